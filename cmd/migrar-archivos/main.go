@@ -0,0 +1,117 @@
+// migrar-archivos copia los archivos subidos que están en el disco local (assets/images, assets/videos y
+// assets/documents) al almacenamiento configurado con ALMACENAMIENTO y las variables S3_*.
+//
+//	go run ./cmd/migrar-archivos -origen assets -simular
+//
+// Es idempotente: los archivos que ya existen en el destino con el mismo tamaño se omiten y los que
+// existen con otro tamaño se informan sin sobrescribirlos.
+package main
+
+import (
+	"ApiEscuela/config"
+	"ApiEscuela/services"
+	"errors"
+	"flag"
+	"log"
+	"mime"
+	"os"
+	"path/filepath"
+)
+
+func main() {
+	origen := flag.String("origen", services.DirectorioArchivos, "directorio local con las carpetas images, videos y documents")
+	simular := flag.Bool("simular", false, "solo informa lo que se copiaría, sin copiar nada")
+	flag.Parse()
+
+	// Misma configuración que la API: entorno, .env y config.env
+	almacenamiento, err := config.CargarAlmacenamiento()
+	if err != nil {
+		log.Fatalf("Configuración de almacenamiento inválida: %v", err)
+	}
+	destino, err := services.NewStorage(almacenamiento)
+	if err != nil {
+		log.Fatalf("Error al configurar el almacenamiento de destino: %v", err)
+	}
+	if local, ok := destino.(*services.StorageLocal); ok && mismoDirectorio(local.Raiz(), *origen) {
+		log.Printf("El almacenamiento configurado es el mismo directorio %s; no hay nada que migrar", *origen)
+		return
+	}
+
+	copiados, omitidos, conflictos, errores := 0, 0, 0, 0
+	for _, carpeta := range services.CarpetasArchivos {
+		entradas, err := os.ReadDir(filepath.Join(*origen, carpeta))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			log.Fatalf("Error al leer %s: %v", filepath.Join(*origen, carpeta), err)
+		}
+
+		for _, entrada := range entradas {
+			// Solo archivos regulares: se ignoran subcarpetas y enlaces simbólicos
+			if !entrada.Type().IsRegular() {
+				continue
+			}
+			clave := services.ClaveArchivo(carpeta, entrada.Name())
+			ruta := filepath.Join(*origen, carpeta, entrada.Name())
+			info, err := entrada.Info()
+			if err != nil {
+				log.Printf("Error: %s: %v", ruta, err)
+				errores++
+				continue
+			}
+
+			if existente, err := destino.Info(clave); err == nil {
+				if existente.Tamano == info.Size() {
+					omitidos++
+				} else {
+					log.Printf("Conflicto: %s ya existe en el destino con %d bytes (local: %d bytes); no se sobrescribe", clave, existente.Tamano, info.Size())
+					conflictos++
+				}
+				continue
+			}
+
+			if *simular {
+				log.Printf("Se copiaría %s (%d bytes)", clave, info.Size())
+				copiados++
+				continue
+			}
+			err = copiar(destino, ruta, clave, info.Size())
+			if errors.Is(err, services.ErrObjetoExistente) {
+				log.Printf("Conflicto: %s apareció en el destino durante la migración; no se sobrescribe", clave)
+				conflictos++
+				continue
+			}
+			if err != nil {
+				log.Printf("Error al copiar %s: %v", clave, err)
+				errores++
+				continue
+			}
+			copiados++
+		}
+	}
+
+	verbo := "Copiados"
+	if *simular {
+		verbo = "Por copiar"
+	}
+	log.Printf("%s: %d, ya migrados: %d, conflictos: %d, errores: %d", verbo, copiados, omitidos, conflictos, errores)
+	if errores > 0 {
+		os.Exit(1)
+	}
+}
+
+func copiar(destino services.Storage, ruta, clave string, tamano int64) error {
+	archivo, err := os.Open(ruta)
+	if err != nil {
+		return err
+	}
+	defer archivo.Close()
+	return destino.Guardar(clave, archivo, tamano, mime.TypeByExtension(filepath.Ext(ruta)))
+}
+
+func mismoDirectorio(a, b string) bool {
+	absA, errA := filepath.Abs(a)
+	absB, errB := filepath.Abs(b)
+	return errA == nil && errB == nil && absA == absB
+}
@@ -0,0 +1,469 @@
+// Package config carga y valida la configuración de la API al iniciar. Las variables se leen del entorno,
+// de .env (desarrollo) y de config.env en el directorio actual o en /etc/secrets/; el entorno tiene prioridad.
+package config
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+	"github.com/spf13/viper"
+)
+
+// secretoJWTInseguro es el valor que la API usaba por defecto; aparece en el historial del repositorio,
+// así que cualquiera podría firmar tokens con él
+const secretoJWTInseguro = "default_secret_for_development_only"
+
+// longitudMinimaSecretoJWT es la longitud exigida en producción (256 bits en ASCII para HS256)
+const longitudMinimaSecretoJWT = 32
+
+// zonaHorariaPorDef es la zona de las visitas si no se indica ZONA_HORARIA; si el sistema no tiene la base
+// de zonas horarias se usa su desfase fijo
+const zonaHorariaPorDef = "America/Guayaquil"
+
+// Config es la configuración tipada de la API
+type Config struct {
+	Ambiente string // APP_ENV: development o production
+	Puerto   string // APP_PORT
+
+	LogNivel   string // LOG_LEVEL
+	LogFormato string // LOG_FORMATO
+
+	DatabaseURL string // DATABASE_URL
+	DB          PoolDB
+
+	JWTSecret           string // JWT_SECRET
+	ArchivosFirmaSecret string // ARCHIVOS_FIRMA_SECRET; si falta se firma con JWT_SECRET
+	SMTP                SMTP
+
+	TrazasExportador   string // OTEL_TRACES_EXPORTER
+	MetricasToken      string // METRICAS_TOKEN
+	SaludVerificarSMTP bool   // SALUD_VERIFICAR_SMTP
+
+	ApagadoTimeout time.Duration // APAGADO_TIMEOUT: plazo para terminar las peticiones y procesos en curso
+	SaludTimeout   time.Duration // SALUD_TIMEOUT: plazo de cada verificación de /health/ready
+
+	Almacenamiento Almacenamiento
+	Archivos       Archivos
+	Antivirus      Antivirus
+	Intervalos     Intervalos
+
+	ZonaHoraria      *time.Location // ZONA_HORARIA: zona de las visitas y de las fechas de los correos
+	EncuestasURLBase string         // ENCUESTAS_URL_BASE: página del frontend que responde las encuestas
+	NoticiasURLBase  string         // NOTICIAS_URL_BASE: sección de noticias del sitio, para los feeds
+
+	advertencias []string
+}
+
+// PoolDB ajusta el pool de conexiones a PostgreSQL
+type PoolDB struct {
+	MaxConexiones             int           // DB_MAX_OPEN_CONNS
+	MaxConexionesInactivas    int           // DB_MAX_IDLE_CONNS
+	VidaMaximaConexion        time.Duration // DB_CONN_MAX_LIFETIME
+	InactividadMaximaConexion time.Duration // DB_CONN_MAX_IDLE_TIME
+}
+
+// SMTP es la cuenta con la que se envían los correos
+type SMTP struct {
+	Host            string // SMTP_HOST
+	Puerto          string // SMTP_PORT
+	Usuario         string // SMTP_USER
+	Clave           string // SMTP_PASS
+	Remitente       string // SMTP_FROM
+	NombreRemitente string // SMTP_FROM_NAME
+}
+
+// Almacenamiento indica dónde se guardan los archivos subidos
+type Almacenamiento struct {
+	Backend    string // ALMACENAMIENTO: local o s3
+	Directorio string // ALMACENAMIENTO_DIRECTORIO: raíz del almacenamiento local
+	S3         S3
+}
+
+// S3 es el bucket del almacenamiento s3 (AWS, MinIO u otro servicio compatible)
+type S3 struct {
+	Endpoint  string // S3_ENDPOINT
+	Region    string // S3_REGION
+	Bucket    string // S3_BUCKET
+	AccessKey string // S3_ACCESS_KEY
+	SecretKey string // S3_SECRET_KEY
+	Prefijo   string // S3_PREFIJO
+	PathStyle bool   // S3_PATH_STYLE: endpoint/bucket/clave en lugar de bucket.endpoint/clave
+}
+
+// Archivos ajusta la validación y el procesamiento de los archivos subidos
+type Archivos struct {
+	LimiteImagenesMB   int              // LIMITE_IMAGENES_MB
+	LimiteVideosMB     int              // LIMITE_VIDEOS_MB
+	LimiteDocumentosMB int              // LIMITE_DOCUMENTOS_MB
+	Variantes          []VarianteImagen // IMAGENES_VARIANTES: "thumb:320,medium:1024"
+	WebP               bool             // IMAGENES_WEBP: generar también variantes WebP
+	Calidad            int              // IMAGENES_CALIDAD: calidad JPEG y WebP de las variantes (1-100)
+	CWebP              string           // IMAGENES_CWEBP: programa que codifica WebP
+	ExpiracionSubidas  time.Duration    // SUBIDAS_EXPIRACION: vida de una subida reanudable sin recibir partes
+	GraciaHuerfanos    time.Duration    // ARCHIVOS_HUERFANOS_GRACIA: antigüedad mínima de un huérfano
+	DuracionCuarentena time.Duration    // ARCHIVOS_CUARENTENA_DURACION: tiempo en cuarentena antes de eliminarlo
+}
+
+// VarianteImagen es una versión reducida de las imágenes subidas; Lado es el máximo en píxeles del lado mayor
+type VarianteImagen struct {
+	Nombre string
+	Lado   int
+}
+
+// Antivirus es el servidor clamd que analiza los archivos subidos; sin dirección no se analizan
+type Antivirus struct {
+	Direccion string        // CLAMD_DIRECCION: host:puerto, tcp://host:puerto o unix:/ruta
+	Timeout   time.Duration // CLAMD_TIMEOUT
+}
+
+// Intervalos son los periodos de los procesos en segundo plano
+type Intervalos struct {
+	MonitorSLA           time.Duration // DUDAS_SLA_INTERVALO
+	Publicador           time.Duration // NOTICIAS_PUBLICADOR_INTERVALO
+	LimpiezaSubidas      time.Duration // SUBIDAS_LIMPIEZA_INTERVALO
+	RecoleccionHuerfanos time.Duration // ARCHIVOS_HUERFANOS_INTERVALO
+}
+
+// Completa indica si están todos los datos necesarios para enviar correos
+func (s SMTP) Completa() bool {
+	return s.Host != "" && s.Puerto != "" && s.Usuario != "" && s.Clave != "" && s.Remitente != "" && s.NombreRemitente != ""
+}
+
+// Direccion devuelve host:puerto del servidor SMTP
+func (s SMTP) Direccion() string {
+	return net.JoinHostPort(s.Host, s.Puerto)
+}
+
+// Cargar lee la configuración y la valida. Devuelve un error con todos los problemas encontrados, para
+// corregirlos de una vez en lugar de arrancar la API varias veces.
+func Cargar() (*Config, error) {
+	v, errores := leer()
+	cfg, erroresFormato := construir(v)
+	errores = append(errores, erroresFormato...)
+	errores = append(errores, cfg.validar()...)
+	if len(errores) > 0 {
+		return nil, errors.Join(errores...)
+	}
+	return cfg, nil
+}
+
+// CargarAlmacenamiento lee solo la configuración del almacenamiento, para las herramientas que mueven
+// archivos sin usar la base de datos
+func CargarAlmacenamiento() (Almacenamiento, error) {
+	v, errores := leer()
+	cfg, erroresFormato := construir(v)
+	errores = append(errores, erroresFormato...)
+	errores = append(errores, cfg.Almacenamiento.validar()...)
+	if len(errores) > 0 {
+		return Almacenamiento{}, errors.Join(errores...)
+	}
+	return cfg.Almacenamiento, nil
+}
+
+// PorDefecto devuelve la configuración con los valores por defecto, sin leer el entorno ni validarla
+func PorDefecto() *Config {
+	cfg, _ := construir(valoresPorDefecto(viper.New()))
+	return cfg
+}
+
+// leer combina el entorno, .env y config.env
+func leer() (*viper.Viper, []error) {
+	// .env es opcional: en producción las variables llegan del entorno
+	_ = godotenv.Load()
+
+	v := valoresPorDefecto(viper.New())
+	v.AutomaticEnv()
+	v.SetConfigName("config")
+	v.SetConfigType("env")
+	v.AddConfigPath(".")
+	v.AddConfigPath("/etc/secrets/")
+
+	var errores []error
+	if err := v.ReadInConfig(); err != nil {
+		var sinArchivo viper.ConfigFileNotFoundError
+		if !errors.As(err, &sinArchivo) {
+			errores = append(errores, fmt.Errorf("config.env: %w", err))
+		}
+	}
+	return v, errores
+}
+
+func valoresPorDefecto(v *viper.Viper) *viper.Viper {
+	v.SetDefault("APP_PORT", "3000")
+	v.SetDefault("APP_ENV", "development")
+	v.SetDefault("LOG_LEVEL", "info")
+	v.SetDefault("LOG_FORMATO", "json")
+	v.SetDefault("OTEL_TRACES_EXPORTER", "none")
+	v.SetDefault("DB_MAX_OPEN_CONNS", 25)
+	v.SetDefault("DB_MAX_IDLE_CONNS", 10)
+	v.SetDefault("DB_CONN_MAX_LIFETIME", "30m")
+	v.SetDefault("DB_CONN_MAX_IDLE_TIME", "5m")
+	v.SetDefault("APAGADO_TIMEOUT", "30s")
+	v.SetDefault("SALUD_TIMEOUT", "2s")
+	v.SetDefault("ALMACENAMIENTO", "local")
+	v.SetDefault("ALMACENAMIENTO_DIRECTORIO", "assets")
+	v.SetDefault("S3_PATH_STYLE", true) // MinIO y la mayoría de servicios compatibles usan endpoint/bucket/clave
+	v.SetDefault("LIMITE_IMAGENES_MB", 10)
+	v.SetDefault("LIMITE_VIDEOS_MB", 50)
+	v.SetDefault("LIMITE_DOCUMENTOS_MB", 20)
+	v.SetDefault("IMAGENES_VARIANTES", "thumb:320,medium:1024")
+	v.SetDefault("IMAGENES_CALIDAD", 85)
+	v.SetDefault("IMAGENES_CWEBP", "cwebp")
+	v.SetDefault("SUBIDAS_EXPIRACION", "24h")
+	v.SetDefault("ARCHIVOS_HUERFANOS_GRACIA", "24h")
+	v.SetDefault("ARCHIVOS_CUARENTENA_DURACION", "168h")
+	v.SetDefault("CLAMD_TIMEOUT", "60s")
+	v.SetDefault("DUDAS_SLA_INTERVALO", "15m")
+	v.SetDefault("NOTICIAS_PUBLICADOR_INTERVALO", "1m")
+	v.SetDefault("SUBIDAS_LIMPIEZA_INTERVALO", "1h")
+	v.SetDefault("ARCHIVOS_HUERFANOS_INTERVALO", "24h")
+	v.SetDefault("ZONA_HORARIA", zonaHorariaPorDef)
+	return v
+}
+
+// construir arma la configuración; los errores son valores con un formato inválido
+func construir(v *viper.Viper) (*Config, []error) {
+	var errores []error
+	entero := func(variable string) int {
+		valor, err := strconv.Atoi(strings.TrimSpace(v.GetString(variable)))
+		if err != nil || valor < 0 {
+			errores = append(errores, fmt.Errorf("%s debe ser un número entero no negativo", variable))
+		}
+		return valor
+	}
+	positivo := func(variable string) int {
+		valor, err := strconv.Atoi(strings.TrimSpace(v.GetString(variable)))
+		if err != nil || valor <= 0 {
+			errores = append(errores, fmt.Errorf("%s debe ser un número entero mayor que cero", variable))
+		}
+		return valor
+	}
+	duracion := func(variable string) time.Duration {
+		valor, err := time.ParseDuration(strings.TrimSpace(v.GetString(variable)))
+		if err != nil || valor < 0 {
+			errores = append(errores, fmt.Errorf("%s debe ser una duración como 30s o 5m", variable))
+		}
+		return valor
+	}
+	plazo := func(variable string) time.Duration {
+		valor, err := time.ParseDuration(strings.TrimSpace(v.GetString(variable)))
+		if err != nil || valor <= 0 {
+			errores = append(errores, fmt.Errorf("%s debe ser una duración mayor que cero, como 30s o 5m", variable))
+		}
+		return valor
+	}
+
+	cfg := &Config{
+		Ambiente:    strings.ToLower(strings.TrimSpace(v.GetString("APP_ENV"))),
+		Puerto:      strings.TrimSpace(v.GetString("APP_PORT")),
+		LogNivel:    v.GetString("LOG_LEVEL"),
+		LogFormato:  v.GetString("LOG_FORMATO"),
+		DatabaseURL: v.GetString("DATABASE_URL"),
+		DB: PoolDB{
+			MaxConexiones:             entero("DB_MAX_OPEN_CONNS"),
+			MaxConexionesInactivas:    entero("DB_MAX_IDLE_CONNS"),
+			VidaMaximaConexion:        duracion("DB_CONN_MAX_LIFETIME"),
+			InactividadMaximaConexion: duracion("DB_CONN_MAX_IDLE_TIME"),
+		},
+		JWTSecret:           v.GetString("JWT_SECRET"),
+		ArchivosFirmaSecret: v.GetString("ARCHIVOS_FIRMA_SECRET"),
+		SMTP: SMTP{
+			Host:            v.GetString("SMTP_HOST"),
+			Puerto:          v.GetString("SMTP_PORT"),
+			Usuario:         v.GetString("SMTP_USER"),
+			Clave:           v.GetString("SMTP_PASS"),
+			Remitente:       v.GetString("SMTP_FROM"),
+			NombreRemitente: v.GetString("SMTP_FROM_NAME"),
+		},
+		TrazasExportador:   v.GetString("OTEL_TRACES_EXPORTER"),
+		MetricasToken:      v.GetString("METRICAS_TOKEN"),
+		SaludVerificarSMTP: v.GetBool("SALUD_VERIFICAR_SMTP"),
+		ApagadoTimeout:     duracion("APAGADO_TIMEOUT"),
+		SaludTimeout:       plazo("SALUD_TIMEOUT"),
+		Almacenamiento: Almacenamiento{
+			Backend:    strings.ToLower(strings.TrimSpace(v.GetString("ALMACENAMIENTO"))),
+			Directorio: v.GetString("ALMACENAMIENTO_DIRECTORIO"),
+			S3: S3{
+				Endpoint:  v.GetString("S3_ENDPOINT"),
+				Region:    v.GetString("S3_REGION"),
+				Bucket:    v.GetString("S3_BUCKET"),
+				AccessKey: v.GetString("S3_ACCESS_KEY"),
+				SecretKey: v.GetString("S3_SECRET_KEY"),
+				Prefijo:   v.GetString("S3_PREFIJO"),
+				PathStyle: v.GetBool("S3_PATH_STYLE"),
+			},
+		},
+		Archivos: Archivos{
+			LimiteImagenesMB:   positivo("LIMITE_IMAGENES_MB"),
+			LimiteVideosMB:     positivo("LIMITE_VIDEOS_MB"),
+			LimiteDocumentosMB: positivo("LIMITE_DOCUMENTOS_MB"),
+			WebP:               v.GetBool("IMAGENES_WEBP"),
+			Calidad:            positivo("IMAGENES_CALIDAD"),
+			CWebP:              v.GetString("IMAGENES_CWEBP"),
+			ExpiracionSubidas:  plazo("SUBIDAS_EXPIRACION"),
+			GraciaHuerfanos:    plazo("ARCHIVOS_HUERFANOS_GRACIA"),
+			DuracionCuarentena: plazo("ARCHIVOS_CUARENTENA_DURACION"),
+		},
+		Antivirus: Antivirus{
+			Direccion: strings.TrimSpace(v.GetString("CLAMD_DIRECCION")),
+			Timeout:   plazo("CLAMD_TIMEOUT"),
+		},
+		Intervalos: Intervalos{
+			MonitorSLA:           plazo("DUDAS_SLA_INTERVALO"),
+			Publicador:           plazo("NOTICIAS_PUBLICADOR_INTERVALO"),
+			LimpiezaSubidas:      plazo("SUBIDAS_LIMPIEZA_INTERVALO"),
+			RecoleccionHuerfanos: plazo("ARCHIVOS_HUERFANOS_INTERVALO"),
+		},
+		EncuestasURLBase: strings.TrimSpace(v.GetString("ENCUESTAS_URL_BASE")),
+		NoticiasURLBase:  strings.TrimSpace(v.GetString("NOTICIAS_URL_BASE")),
+	}
+
+	variantes, err := parseVariantes(v.GetString("IMAGENES_VARIANTES"))
+	if err != nil {
+		errores = append(errores, err)
+	}
+	cfg.Archivos.Variantes = variantes
+
+	nombreZona := strings.TrimSpace(v.GetString("ZONA_HORARIA"))
+	zona, err := time.LoadLocation(nombreZona)
+	switch {
+	case err == nil:
+		cfg.ZonaHoraria = zona
+	case nombreZona == zonaHorariaPorDef:
+		cfg.ZonaHoraria = time.FixedZone("ECT", -5*60*60)
+	default:
+		errores = append(errores, fmt.Errorf("ZONA_HORARIA %q no es una zona horaria conocida", nombreZona))
+	}
+	return cfg, errores
+}
+
+// parseVariantes lee "nombre:lado" separados por comas; los nombres usan minúsculas y dígitos
+func parseVariantes(valor string) ([]VarianteImagen, error) {
+	var variantes []VarianteImagen
+	for _, parte := range strings.Split(valor, ",") {
+		if strings.TrimSpace(parte) == "" {
+			continue
+		}
+		nombre, lado, _ := strings.Cut(strings.TrimSpace(parte), ":")
+		n, err := strconv.Atoi(lado)
+		if err != nil || n <= 0 || !nombreVarianteValido(nombre) {
+			return nil, fmt.Errorf("IMAGENES_VARIANTES: %q no tiene la forma nombre:lado, como thumb:320", parte)
+		}
+		variantes = append(variantes, VarianteImagen{Nombre: nombre, Lado: n})
+	}
+	return variantes, nil
+}
+
+// nombreVarianteValido evita nombres que choquen con el original o armen claves extrañas
+func nombreVarianteValido(nombre string) bool {
+	if nombre == "" || nombre == "original" {
+		return false
+	}
+	for _, c := range nombre {
+		if (c < 'a' || c > 'z') && (c < '0' || c > '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// EsProduccion indica si APP_ENV es production
+func (c *Config) EsProduccion() bool {
+	return c.Ambiente == "production" || c.Ambiente == "produccion"
+}
+
+// Advertencias devuelve los problemas que no impiden arrancar, para registrarlos una vez configurado el log
+func (c *Config) Advertencias() []string {
+	return c.advertencias
+}
+
+// SecretoFirmaArchivos es el secreto de los enlaces firmados de archivos
+func (c *Config) SecretoFirmaArchivos() string {
+	if c.ArchivosFirmaSecret != "" {
+		return c.ArchivosFirmaSecret
+	}
+	return c.JWTSecret
+}
+
+func (c *Config) validar() []error {
+	var errores []error
+	if c.DatabaseURL == "" {
+		errores = append(errores, errors.New("DATABASE_URL no está definida"))
+	}
+	if _, err := strconv.ParseUint(c.Puerto, 10, 16); err != nil {
+		errores = append(errores, fmt.Errorf("APP_PORT %q no es un puerto válido", c.Puerto))
+	}
+	if c.JWTSecret == secretoJWTInseguro {
+		errores = append(errores, errors.New("JWT_SECRET usa el valor por defecto publicado en el repositorio; genere uno nuevo"))
+	}
+	if c.DB.MaxConexiones > 0 && c.DB.MaxConexionesInactivas > c.DB.MaxConexiones {
+		errores = append(errores, errors.New("DB_MAX_IDLE_CONNS no puede ser mayor que DB_MAX_OPEN_CONNS"))
+	}
+	if c.Archivos.Calidad > 100 {
+		errores = append(errores, errors.New("IMAGENES_CALIDAD debe estar entre 1 y 100"))
+	}
+	for variable, valor := range map[string]string{"ENCUESTAS_URL_BASE": c.EncuestasURLBase, "NOTICIAS_URL_BASE": c.NoticiasURLBase} {
+		if valor != "" && !urlHTTPValida(valor) {
+			errores = append(errores, fmt.Errorf("%s no es una URL http(s) válida: %q", variable, valor))
+		}
+	}
+	errores = append(errores, c.Almacenamiento.validar()...)
+
+	if c.EsProduccion() {
+		if len(c.JWTSecret) < longitudMinimaSecretoJWT {
+			errores = append(errores, fmt.Errorf("JWT_SECRET es obligatorio en producción y debe tener al menos %d caracteres", longitudMinimaSecretoJWT))
+		}
+		if !c.SMTP.Completa() {
+			errores = append(errores, errors.New("en producción son obligatorias SMTP_HOST, SMTP_PORT, SMTP_USER, SMTP_PASS, SMTP_FROM y SMTP_FROM_NAME"))
+		}
+		return errores
+	}
+
+	// En desarrollo se tolera la falta de secretos, pero nunca se usa uno conocido
+	if c.JWTSecret == "" {
+		secreto := make([]byte, 32)
+		if _, err := rand.Read(secreto); err != nil {
+			return append(errores, fmt.Errorf("no se pudo generar un JWT_SECRET temporal: %w", err))
+		}
+		c.JWTSecret = hex.EncodeToString(secreto)
+		c.advertencias = append(c.advertencias, "JWT_SECRET no está definida: se usa un secreto aleatorio y los tokens dejarán de valer al reiniciar")
+	}
+	if !c.SMTP.Completa() {
+		c.advertencias = append(c.advertencias, "la configuración SMTP está incompleta: no se enviarán correos")
+	}
+	return errores
+}
+
+func (a Almacenamiento) validar() []error {
+	switch a.Backend {
+	case "local":
+		if a.Directorio == "" {
+			return []error{errors.New("ALMACENAMIENTO_DIRECTORIO no puede estar vacío")}
+		}
+	case "s3":
+		var errores []error
+		if a.S3.Endpoint == "" || a.S3.Bucket == "" || a.S3.AccessKey == "" || a.S3.SecretKey == "" {
+			errores = append(errores, errors.New("el almacenamiento s3 requiere S3_ENDPOINT, S3_BUCKET, S3_ACCESS_KEY y S3_SECRET_KEY"))
+		}
+		if a.S3.Endpoint != "" && !urlHTTPValida(a.S3.Endpoint) {
+			errores = append(errores, fmt.Errorf("S3_ENDPOINT no es una URL válida: %q", a.S3.Endpoint))
+		}
+		return errores
+	default:
+		return []error{fmt.Errorf("ALMACENAMIENTO desconocido: %q (use local o s3)", a.Backend)}
+	}
+	return nil
+}
+
+func urlHTTPValida(valor string) bool {
+	u, err := url.Parse(valor)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
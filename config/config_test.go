@@ -0,0 +1,115 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+// entorno deja solo las variables indicadas y corre Cargar en un directorio sin .env ni config.env
+func entorno(t *testing.T, variables map[string]string) {
+	t.Helper()
+	t.Chdir(t.TempDir())
+	for _, variable := range []string{
+		"APP_ENV", "APP_PORT", "DATABASE_URL", "JWT_SECRET", "ARCHIVOS_FIRMA_SECRET",
+		"SMTP_HOST", "SMTP_PORT", "SMTP_USER", "SMTP_PASS", "SMTP_FROM", "SMTP_FROM_NAME",
+		"DB_MAX_OPEN_CONNS", "DB_MAX_IDLE_CONNS", "DB_CONN_MAX_LIFETIME", "DB_CONN_MAX_IDLE_TIME", "APAGADO_TIMEOUT",
+		"ALMACENAMIENTO", "S3_ENDPOINT", "S3_BUCKET", "S3_ACCESS_KEY", "S3_SECRET_KEY",
+		"IMAGENES_VARIANTES", "DUDAS_SLA_INTERVALO", "ZONA_HORARIA",
+	} {
+		t.Setenv(variable, variables[variable])
+	}
+}
+
+func TestProduccionExigeSecretos(t *testing.T) {
+	entorno(t, map[string]string{"APP_ENV": "production", "APP_PORT": "3000"})
+
+	_, err := Cargar()
+	if err == nil {
+		t.Fatal("se esperaba un error sin DATABASE_URL, JWT_SECRET ni SMTP en producción")
+	}
+	for _, variable := range []string{"DATABASE_URL", "JWT_SECRET", "SMTP_HOST"} {
+		if !strings.Contains(err.Error(), variable) {
+			t.Errorf("el error no menciona %s: %v", variable, err)
+		}
+	}
+}
+
+func TestRechazaSecretoJWTPorDefecto(t *testing.T) {
+	entorno(t, map[string]string{"APP_PORT": "3000", "DATABASE_URL": "postgres://localhost/escuela", "JWT_SECRET": secretoJWTInseguro})
+
+	if _, err := Cargar(); err == nil || !strings.Contains(err.Error(), "JWT_SECRET") {
+		t.Fatalf("se esperaba rechazar el secreto por defecto, error: %v", err)
+	}
+}
+
+func TestDesarrolloGeneraSecretoTemporal(t *testing.T) {
+	entorno(t, map[string]string{
+		"APP_PORT":             "3000",
+		"DATABASE_URL":         "postgres://localhost/escuela",
+		"DB_MAX_OPEN_CONNS":    "5",
+		"DB_MAX_IDLE_CONNS":    "2",
+		"DB_CONN_MAX_LIFETIME": "1h",
+		"APAGADO_TIMEOUT":      "10s",
+	})
+
+	cfg, err := Cargar()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.JWTSecret) < longitudMinimaSecretoJWT {
+		t.Errorf("secreto temporal demasiado corto: %q", cfg.JWTSecret)
+	}
+	if cfg.SecretoFirmaArchivos() != cfg.JWTSecret {
+		t.Error("sin ARCHIVOS_FIRMA_SECRET los enlaces deberían firmarse con JWT_SECRET")
+	}
+	if len(cfg.Advertencias()) != 2 {
+		t.Errorf("se esperaban advertencias por JWT_SECRET y SMTP, hay %v", cfg.Advertencias())
+	}
+	if cfg.DB.MaxConexiones != 5 || cfg.DB.MaxConexionesInactivas != 2 || cfg.DB.VidaMaximaConexion.Hours() != 1 {
+		t.Errorf("pool de conexiones mal leído: %+v", cfg.DB)
+	}
+	if cfg.ApagadoTimeout.Seconds() != 10 {
+		t.Errorf("APAGADO_TIMEOUT mal leído: %v", cfg.ApagadoTimeout)
+	}
+	if cfg.Almacenamiento.Backend != "local" || len(cfg.Archivos.Variantes) != 2 || cfg.Intervalos.MonitorSLA.Minutes() != 15 {
+		t.Errorf("valores por defecto mal aplicados: %+v %+v %+v", cfg.Almacenamiento, cfg.Archivos, cfg.Intervalos)
+	}
+}
+
+func TestS3IncompletoNoArranca(t *testing.T) {
+	entorno(t, map[string]string{
+		"APP_PORT":       "3000",
+		"DATABASE_URL":   "postgres://localhost/escuela",
+		"ALMACENAMIENTO": "s3",
+		"S3_ENDPOINT":    "http://minio:9000",
+		"S3_BUCKET":      "escuela",
+		"S3_ACCESS_KEY":  "clave",
+	})
+
+	if _, err := Cargar(); err == nil || !strings.Contains(err.Error(), "S3_SECRET_KEY") {
+		t.Fatalf("se esperaba un error por la falta de S3_SECRET_KEY, error: %v", err)
+	}
+	if _, err := CargarAlmacenamiento(); err == nil || !strings.Contains(err.Error(), "S3_SECRET_KEY") {
+		t.Fatalf("CargarAlmacenamiento también debería rechazarlo, error: %v", err)
+	}
+}
+
+func TestRechazaValoresMalFormados(t *testing.T) {
+	entorno(t, map[string]string{
+		"APP_PORT":            "3000",
+		"DATABASE_URL":        "postgres://localhost/escuela",
+		"IMAGENES_VARIANTES":  "thumb:320,Grande:x",
+		"DUDAS_SLA_INTERVALO": "0s",
+		"ZONA_HORARIA":        "America/Atlantida",
+	})
+
+	_, err := Cargar()
+	if err == nil {
+		t.Fatal("se esperaba un error por los valores mal formados")
+	}
+	for _, variable := range []string{"IMAGENES_VARIANTES", "DUDAS_SLA_INTERVALO", "ZONA_HORARIA"} {
+		if !strings.Contains(err.Error(), variable) {
+			t.Errorf("el error no menciona %s: %v", variable, err)
+		}
+	}
+}
@@ -41,7 +41,7 @@ func (h *ActividadHandler) CreateActividad(c *fiber.Ctx) error {
 	actividad.Actividad = strings.TrimSpace(actividad.Actividad)
 
 	// Crear actividad
-	if err := h.actividadRepo.CreateActividad(&actividad); err != nil {
+	if err := h.actividadRepo.ConContexto(c.UserContext()).CreateActividad(&actividad); err != nil {
 		return SendError(c, 500, "database_error", "Error interno del servidor", "No se pudo crear la actividad")
 	}
 
@@ -60,7 +60,7 @@ func (h *ActividadHandler) GetActividad(c *fiber.Ctx) error {
 		return SendError(c, 400, "invalid_id", "El ID de la actividad no es válido", "El ID debe ser un número entero positivo")
 	}
 
-	actividad, err := h.actividadRepo.GetActividadByID(uint(id))
+	actividad, err := h.actividadRepo.ConContexto(c.UserContext()).GetActividadByID(uint(id))
 	if err != nil {
 		return SendError(c, 404, "actividad_not_found", "No se encontró la actividad solicitada", "Verifique que el ID sea correcto")
 	}
@@ -70,7 +70,7 @@ func (h *ActividadHandler) GetActividad(c *fiber.Ctx) error {
 
 // GetAllActividades obtiene todas las actividades
 func (h *ActividadHandler) GetAllActividades(c *fiber.Ctx) error {
-	actividades, err := h.actividadRepo.GetAllActividades()
+	actividades, err := h.actividadRepo.ConContexto(c.UserContext()).GetAllActividades()
 	if err != nil {
 		return SendError(c, 500, "database_error", "Error interno del servidor", "No se pudieron obtener las actividades")
 	}
@@ -91,7 +91,7 @@ func (h *ActividadHandler) UpdateActividad(c *fiber.Ctx) error {
 	}
 
 	// Verificar que la actividad existe
-	existingActividad, err := h.actividadRepo.GetActividadByID(uint(id))
+	existingActividad, err := h.actividadRepo.ConContexto(c.UserContext()).GetActividadByID(uint(id))
 	if err != nil {
 		return SendError(c, 404, "actividad_not_found", "No se encontró la actividad solicitada", "Verifique que el ID sea correcto")
 	}
@@ -113,7 +113,7 @@ func (h *ActividadHandler) UpdateActividad(c *fiber.Ctx) error {
 	existingActividad.Duracion = updateData.Duracion
 
 	// Guardar cambios
-	if err := h.actividadRepo.UpdateActividad(existingActividad); err != nil {
+	if err := h.actividadRepo.ConContexto(c.UserContext()).UpdateActividad(existingActividad); err != nil {
 		return SendError(c, 500, "database_error", "Error interno del servidor", "No se pudo actualizar la actividad")
 	}
 
@@ -133,13 +133,13 @@ func (h *ActividadHandler) DeleteActividad(c *fiber.Ctx) error {
 	}
 
 	// Verificar que la actividad existe
-	_, err = h.actividadRepo.GetActividadByID(uint(id))
+	_, err = h.actividadRepo.ConContexto(c.UserContext()).GetActividadByID(uint(id))
 	if err != nil {
 		return SendError(c, 404, "actividad_not_found", "No se encontró la actividad solicitada", "Verifique que el ID sea correcto")
 	}
 
 	// Eliminar actividad
-	if err := h.actividadRepo.DeleteActividad(uint(id)); err != nil {
+	if err := h.actividadRepo.ConContexto(c.UserContext()).DeleteActividad(uint(id)); err != nil {
 		return SendError(c, 500, "database_error", "Error interno del servidor", "No se pudo eliminar la actividad")
 	}
 
@@ -161,7 +161,7 @@ func (h *ActividadHandler) GetActividadesByTematica(c *fiber.Ctx) error {
 		return SendError(c, 400, "invalid_tematica_id", "El ID de la temática no es válido", "El ID debe ser un número entero positivo")
 	}
 
-	actividades, err := h.actividadRepo.GetActividadesByTematica(uint(tematicaID))
+	actividades, err := h.actividadRepo.ConContexto(c.UserContext()).GetActividadesByTematica(uint(tematicaID))
 	if err != nil {
 		return SendError(c, 500, "database_error", "Error interno del servidor", "No se pudieron obtener las actividades")
 	}
@@ -178,7 +178,7 @@ func (h *ActividadHandler) GetActividadesByNombre(c *fiber.Ctx) error {
 		return SendValidationError(c, "Los parámetros de búsqueda no son válidos", validationErrors)
 	}
 
-	actividades, err := h.actividadRepo.GetActividadesByNombre(nombre)
+	actividades, err := h.actividadRepo.ConContexto(c.UserContext()).GetActividadesByNombre(nombre)
 	if err != nil {
 		return SendError(c, 500, "database_error", "Error interno del servidor", "No se pudieron obtener las actividades")
 	}
@@ -206,7 +206,7 @@ func (h *ActividadHandler) GetActividadesByDuracion(c *fiber.Ctx) error {
 		return SendValidationError(c, "Los parámetros de duración no son válidos", validationErrors)
 	}
 
-	actividades, err := h.actividadRepo.GetActividadesByDuracion(duracionMin, duracionMax)
+	actividades, err := h.actividadRepo.ConContexto(c.UserContext()).GetActividadesByDuracion(duracionMin, duracionMax)
 	if err != nil {
 		return SendError(c, 500, "database_error", "Error interno del servidor", "No se pudieron obtener las actividades")
 	}
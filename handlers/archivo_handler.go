@@ -0,0 +1,202 @@
+package handlers
+
+import (
+	"ApiEscuela/services"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type ArchivoHandler struct {
+	archivoService *services.ArchivoService
+}
+
+func NewArchivoHandler(archivoService *services.ArchivoService) *ArchivoHandler {
+	return &ArchivoHandler{archivoService: archivoService}
+}
+
+// GetArchivos lista los archivos del usuario; los administradores ven todos (?usuario_id=&carpeta=)
+func (h *ArchivoHandler) GetArchivos(c *fiber.Ctx) error {
+	var propietarioID *uint
+	if valor := c.Query("usuario_id"); valor != "" {
+		id, err := strconv.Atoi(valor)
+		if err != nil || id <= 0 {
+			return SendError(c, 400, "usuario_id_invalido", "El ID del usuario no es válido", "El ID debe ser un número entero positivo")
+		}
+		propietario := uint(id)
+		propietarioID = &propietario
+	}
+
+	userID, _ := c.Locals("user_id").(uint)
+	archivos, err := h.archivoService.ConContexto(c.UserContext()).Listar(userID, propietarioID, c.Query("carpeta"))
+	if err != nil {
+		return sendArchivoError(c, err, "No se pudieron obtener los archivos")
+	}
+
+	return SendSuccess(c, 200, archivos)
+}
+
+// GetArchivo obtiene los metadatos de un archivo
+func (h *ArchivoHandler) GetArchivo(c *fiber.Ctx) error {
+	id, ok := archivoID(c)
+	if !ok {
+		return nil
+	}
+
+	userID, _ := c.Locals("user_id").(uint)
+	archivo, err := h.archivoService.ConContexto(c.UserContext()).Obtener(id, userID)
+	if err != nil {
+		return sendArchivoError(c, err, "No se pudo obtener el archivo")
+	}
+
+	return SendSuccess(c, 200, archivo)
+}
+
+// UpdateArchivo cambia la visibilidad de un archivo
+func (h *ArchivoHandler) UpdateArchivo(c *fiber.Ctx) error {
+	id, ok := archivoID(c)
+	if !ok {
+		return nil
+	}
+
+	var req struct {
+		Visibilidad string `json:"visibilidad"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return SendError(c, 400, "json_invalido", "No se puede procesar el JSON. Verifique el formato de los datos", err.Error())
+	}
+
+	userID, _ := c.Locals("user_id").(uint)
+	archivo, err := h.archivoService.ConContexto(c.UserContext()).CambiarVisibilidad(id, userID, req.Visibilidad)
+	if err != nil {
+		return sendArchivoError(c, err, "No se pudo actualizar el archivo")
+	}
+
+	return SendSuccess(c, 200, archivo)
+}
+
+// DeleteArchivo elimina un archivo del disco junto con su registro
+func (h *ArchivoHandler) DeleteArchivo(c *fiber.Ctx) error {
+	id, ok := archivoID(c)
+	if !ok {
+		return nil
+	}
+
+	userID, _ := c.Locals("user_id").(uint)
+	if err := h.archivoService.ConContexto(c.UserContext()).Eliminar(id, userID); err != nil {
+		return sendArchivoError(c, err, "No se pudo eliminar el archivo")
+	}
+
+	return SendSuccess(c, 200, fiber.Map{
+		"message": "Archivo eliminado exitosamente",
+		"id":      id,
+	})
+}
+
+// GetPermisos lista los permisos de lectura de un archivo
+func (h *ArchivoHandler) GetPermisos(c *fiber.Ctx) error {
+	id, ok := archivoID(c)
+	if !ok {
+		return nil
+	}
+
+	userID, _ := c.Locals("user_id").(uint)
+	permisos, err := h.archivoService.ConContexto(c.UserContext()).Permisos(id, userID)
+	if err != nil {
+		return sendArchivoError(c, err, "No se pudieron obtener los permisos")
+	}
+
+	return SendSuccess(c, 200, permisos)
+}
+
+// CreatePermiso concede lectura de un archivo a un usuario o a un tipo de usuario
+func (h *ArchivoHandler) CreatePermiso(c *fiber.Ctx) error {
+	id, ok := archivoID(c)
+	if !ok {
+		return nil
+	}
+
+	var req services.PermisoArchivoRequest
+	if err := c.BodyParser(&req); err != nil {
+		return SendError(c, 400, "json_invalido", "No se puede procesar el JSON. Verifique el formato de los datos", err.Error())
+	}
+
+	userID, _ := c.Locals("user_id").(uint)
+	permiso, err := h.archivoService.ConContexto(c.UserContext()).ConcederPermiso(id, userID, req)
+	if err != nil {
+		return sendArchivoError(c, err, "No se pudo conceder el permiso")
+	}
+
+	return SendSuccess(c, 201, permiso)
+}
+
+// DeletePermiso revoca un permiso de lectura de un archivo
+func (h *ArchivoHandler) DeletePermiso(c *fiber.Ctx) error {
+	id, ok := archivoID(c)
+	if !ok {
+		return nil
+	}
+	permisoID, err := strconv.Atoi(c.Params("permiso_id"))
+	if err != nil || permisoID <= 0 {
+		return SendError(c, 400, "permiso_id_invalido", "El ID del permiso no es válido", "El ID debe ser un número entero positivo")
+	}
+
+	userID, _ := c.Locals("user_id").(uint)
+	if err := h.archivoService.ConContexto(c.UserContext()).RevocarPermiso(id, uint(permisoID), userID); err != nil {
+		return sendArchivoError(c, err, "No se pudo revocar el permiso")
+	}
+
+	return SendSuccess(c, 200, fiber.Map{
+		"message": "Permiso revocado exitosamente",
+		"id":      permisoID,
+	})
+}
+
+// GetURLFirmada devuelve un enlace para descargar el archivo; firmado y temporal si es privado (?minutos=)
+func (h *ArchivoHandler) GetURLFirmada(c *fiber.Ctx) error {
+	id, ok := archivoID(c)
+	if !ok {
+		return nil
+	}
+	minutos := 0
+	if valor := c.Query("minutos"); valor != "" {
+		var err error
+		if minutos, err = strconv.Atoi(valor); err != nil || minutos <= 0 {
+			return SendError(c, 400, "minutos_invalidos", "La duración del enlace no es válida", "Los minutos deben ser un número entero positivo (máximo 1440)")
+		}
+	}
+
+	userID, _ := c.Locals("user_id").(uint)
+	enlace, err := h.archivoService.ConContexto(c.UserContext()).EnlaceArchivo(id, userID, c.BaseURL(), time.Duration(minutos)*time.Minute)
+	if err != nil {
+		return sendArchivoError(c, err, "No se pudo generar el enlace del archivo")
+	}
+
+	return SendSuccess(c, 200, enlace)
+}
+
+// archivoID lee el ID del archivo de la ruta; si no es válido ya respondió con el error
+func archivoID(c *fiber.Ctx) (uint, bool) {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil || id <= 0 {
+		SendError(c, 400, "id_invalido", "El ID del archivo no es válido", "El ID debe ser un número entero positivo")
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func sendArchivoError(c *fiber.Ctx, err error, mensaje string) error {
+	switch {
+	case errors.Is(err, services.ErrArchivoNoEncontrado):
+		return SendError(c, 404, "archivo_no_encontrado", "No se encontró el archivo solicitado", "Verifique que el ID sea correcto")
+	case errors.Is(err, services.ErrPermisoNoEncontrado):
+		return SendError(c, 404, "permiso_no_encontrado", "No se encontró el permiso solicitado", "Verifique que el ID sea correcto")
+	case errors.Is(err, services.ErrGestionArchivoDenegada), errors.Is(err, services.ErrSinAccesoArchivo):
+		return SendError(c, 403, "acceso_denegado", mensaje, err.Error())
+	case errors.Is(err, services.ErrArchivoEsPortada):
+		return SendError(c, 409, "archivo_en_uso", mensaje, err.Error())
+	}
+	return SendError(c, 400, "archivo_invalido", mensaje, err.Error())
+}
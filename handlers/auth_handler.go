@@ -50,7 +50,7 @@ func (h *AuthHandler) Login(c *fiber.Ctx) error {
 	}
 
 	// Intentar login
-	response, err := h.authService.Login(loginReq)
+	response, err := h.authService.ConContexto(c.UserContext()).Login(loginReq)
 	if err != nil {
 		// Determinar el código de error específico basado en el mensaje
 		var errorCode string
@@ -93,7 +93,7 @@ func (h *AuthHandler) RecoverPassword(c *fiber.Ctx) error {
 	if strings.TrimSpace(req.Cedula) == "" {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "La cédula es requerida"})
 	}
-	if err := h.authService.RecoverPassword(req.Cedula); err != nil {
+	if err := h.authService.ConContexto(c.UserContext()).RecoverPassword(c.UserContext(), req.Cedula); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 	}
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Si la cédula existe, se envió un correo con la contraseña temporal"})
@@ -134,7 +134,7 @@ func (h *AuthHandler) VerifyCode(c *fiber.Ctx) error {
 	}
 
 	// Verificar código usando el servicio
-	result, err := h.authService.VerifyCodigoWithDetails(strings.TrimSpace(req.Codigo))
+	result, err := h.authService.ConContexto(c.UserContext()).VerifyCodigoWithDetails(strings.TrimSpace(req.Codigo))
 	if err != nil {
 		return SendError(c, 500, "service_error", "Error interno del servidor", "No se pudo verificar el código")
 	}
@@ -184,7 +184,7 @@ func (h *AuthHandler) Register(c *fiber.Ctx) error {
 	}
 
 	// Intentar registro
-	usuario, err := h.authService.Register(registerReq)
+	usuario, err := h.authService.ConContexto(c.UserContext()).Register(registerReq)
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error": err.Error(),
@@ -280,7 +280,7 @@ func (h *AuthHandler) ResetPassword(c *fiber.Ctx) error {
 	clave := strings.TrimSpace(req.Clave)
 
 	// Cambiar contraseña usando el servicio
-	if err := h.authService.ResetPasswordByCodigoID(req.CodigoID, req.UsuarioID, clave); err != nil {
+	if err := h.authService.ConContexto(c.UserContext()).ResetPasswordByCodigoID(req.CodigoID, req.UsuarioID, clave); err != nil {
 		// Manejar diferentes tipos de errores del servicio
 		switch err.Error() {
 		case "código no encontrado":
@@ -334,7 +334,7 @@ func (h *AuthHandler) ChangePassword(c *fiber.Ctx) error {
 	}
 
 	// Cambiar contraseña
-	err := h.authService.ChangePassword(userID, changePasswordReq.OldPassword, changePasswordReq.NewPassword)
+	err := h.authService.ConContexto(c.UserContext()).ChangePassword(userID, changePasswordReq.OldPassword, changePasswordReq.NewPassword)
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error": err.Error(),
@@ -366,7 +366,7 @@ func (h *AuthHandler) RefreshToken(c *fiber.Ctx) error {
 	tipoUsuarioID := c.Locals("tipo_usuario_id").(uint)
 
 	// Generar nuevo token
-	token, err := h.authService.GenerateNewToken(userID, username, tipoUsuarioID)
+	token, err := h.authService.ConContexto(c.UserContext()).GenerateNewToken(userID, username, tipoUsuarioID)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Error al generar nuevo token",
@@ -397,7 +397,7 @@ func (h *AuthHandler) ValidateToken(c *fiber.Ctx) error {
 		})
 	}
 
-	valid, claims := h.authService.ValidateToken(tokenReq.Token)
+	valid, claims := h.authService.ConContexto(c.UserContext()).ValidateToken(tokenReq.Token)
 	if !valid {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 			"valid": false,
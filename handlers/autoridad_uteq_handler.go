@@ -49,12 +49,12 @@ func (h *AutoridadUTEQHandler) CreateAutoridadUTEQ(c *fiber.Ctx) error {
 	}
 
 	// Verificar que la persona existe (validación de relación)
-	if !h.personaExists(autoridad.PersonaID) {
+	if !h.personaExists(c, autoridad.PersonaID) {
 		return SendError(c, 400, "persona_no_existe", "No se encontró la persona con el ID especificado", "Verifique que el persona_id sea correcto y que la persona exista en el sistema")
 	}
 
 	// Crear autoridad
-	if err := h.autoridadRepo.CreateAutoridadUTEQ(&autoridad); err != nil {
+	if err := h.autoridadRepo.ConContexto(c.UserContext()).CreateAutoridadUTEQ(&autoridad); err != nil {
 		// Manejar errores específicos del repositorio
 		switch err.Error() {
 		case "persona no encontrada":
@@ -81,7 +81,7 @@ func (h *AutoridadUTEQHandler) GetAutoridadUTEQ(c *fiber.Ctx) error {
 		return SendError(c, 400, "id_invalido", "El ID de la autoridad UTEQ no es válido", "El ID debe ser un número entero positivo")
 	}
 
-	autoridad, err := h.autoridadRepo.GetAutoridadUTEQByID(uint(id))
+	autoridad, err := h.autoridadRepo.ConContexto(c.UserContext()).ConVisor(visorDe(c)).GetAutoridadUTEQByID(uint(id))
 	if err != nil {
 		return SendError(c, 404, "autoridad_no_encontrada", "No se encontró la autoridad UTEQ solicitada", "Verifique que el ID sea correcto")
 	}
@@ -91,7 +91,7 @@ func (h *AutoridadUTEQHandler) GetAutoridadUTEQ(c *fiber.Ctx) error {
 
 // GetAllAutoridadesUTEQ obtiene todas las autoridades UTEQ activas
 func (h *AutoridadUTEQHandler) GetAllAutoridadesUTEQ(c *fiber.Ctx) error {
-	autoridades, err := h.autoridadRepo.GetAllAutoridadesUTEQ()
+	autoridades, err := h.autoridadRepo.ConContexto(c.UserContext()).ConVisor(visorDe(c)).GetAllAutoridadesUTEQ()
 	if err != nil {
 		return SendError(c, 500, "error_base_datos", "Error interno del servidor", "No se pudieron obtener las autoridades UTEQ")
 	}
@@ -101,7 +101,7 @@ func (h *AutoridadUTEQHandler) GetAllAutoridadesUTEQ(c *fiber.Ctx) error {
 
 // GetAllAutoridadesUTEQIncludingDeleted obtiene todas las autoridades UTEQ incluyendo las eliminadas
 func (h *AutoridadUTEQHandler) GetAllAutoridadesUTEQIncludingDeleted(c *fiber.Ctx) error {
-	autoridades, err := h.autoridadRepo.GetAllAutoridadesUTEQIncludingDeleted()
+	autoridades, err := h.autoridadRepo.ConContexto(c.UserContext()).ConVisor(visorDe(c)).GetAllAutoridadesUTEQIncludingDeleted()
 	if err != nil {
 		return SendError(c, 500, "database_error", "Error interno del servidor", "No se pudieron obtener las autoridades UTEQ")
 	}
@@ -111,7 +111,7 @@ func (h *AutoridadUTEQHandler) GetAllAutoridadesUTEQIncludingDeleted(c *fiber.Ct
 
 // GetDeletedAutoridadesUTEQ obtiene solo las autoridades UTEQ eliminadas
 func (h *AutoridadUTEQHandler) GetDeletedAutoridadesUTEQ(c *fiber.Ctx) error {
-	autoridades, err := h.autoridadRepo.GetDeletedAutoridadesUTEQ()
+	autoridades, err := h.autoridadRepo.ConContexto(c.UserContext()).ConVisor(visorDe(c)).GetDeletedAutoridadesUTEQ()
 	if err != nil {
 		return SendError(c, 500, "database_error", "Error interno del servidor", "No se pudieron obtener las autoridades UTEQ eliminadas")
 	}
@@ -132,7 +132,7 @@ func (h *AutoridadUTEQHandler) UpdateAutoridadUTEQ(c *fiber.Ctx) error {
 	}
 
 	// Verificar que la autoridad existe
-	existingAutoridad, err := h.autoridadRepo.GetAutoridadUTEQByID(uint(id))
+	existingAutoridad, err := h.autoridadRepo.ConContexto(c.UserContext()).ConVisor(visorDe(c)).GetAutoridadUTEQByID(uint(id))
 	if err != nil {
 		return SendError(c, 404, "autoridad_no_encontrada", "No se encontró la autoridad UTEQ solicitada", "Verifique que el ID sea correcto")
 	}
@@ -151,11 +151,11 @@ func (h *AutoridadUTEQHandler) UpdateAutoridadUTEQ(c *fiber.Ctx) error {
 	// Verificar si la nueva persona ya tiene un cargo asignado (si cambia la persona)
 	if updateData.PersonaID != existingAutoridad.PersonaID {
 		// Verificar que la nueva persona existe
-		if !h.personaExists(updateData.PersonaID) {
+		if !h.personaExists(c, updateData.PersonaID) {
 			return SendError(c, 400, "persona_no_existe", "No se encontró la persona con el ID especificado", "Verifique que el persona_id sea correcto y que la persona exista en el sistema")
 		}
 
-		if existingAutoridadByPersona, _ := h.autoridadRepo.GetAutoridadUTEQByPersona(updateData.PersonaID); existingAutoridadByPersona != nil {
+		if existingAutoridadByPersona, _ := h.autoridadRepo.ConContexto(c.UserContext()).GetAutoridadUTEQByPersona(updateData.PersonaID); existingAutoridadByPersona != nil {
 			return SendError(c, 409, "autoridad_duplicada", "La persona ya tiene un cargo asignado", "Una persona solo puede tener un cargo de autoridad")
 		}
 	}
@@ -165,7 +165,7 @@ func (h *AutoridadUTEQHandler) UpdateAutoridadUTEQ(c *fiber.Ctx) error {
 	existingAutoridad.Cargo = strings.TrimSpace(updateData.Cargo)
 
 	// Guardar cambios
-	if err := h.autoridadRepo.UpdateAutoridadUTEQ(existingAutoridad); err != nil {
+	if err := h.autoridadRepo.ConContexto(c.UserContext()).UpdateAutoridadUTEQ(existingAutoridad); err != nil {
 		return SendError(c, 500, "error_base_datos", "Error interno del servidor", "No se pudo actualizar la autoridad UTEQ")
 	}
 
@@ -184,8 +184,8 @@ func (h *AutoridadUTEQHandler) DeleteAutoridadUTEQ(c *fiber.Ctx) error {
 		return SendError(c, 400, "id_invalido", "El ID de la autoridad UTEQ no es válido", "El ID debe ser un número entero positivo")
 	}
 
-	// Verificar que la autoridad existe
-	autoridad, err := h.autoridadRepo.GetAutoridadUTEQByID(uint(id))
+	// Verificar que la autoridad existe; se cuentan todas sus dudas, también las privadas
+	autoridad, err := h.autoridadRepo.ConContexto(c.UserContext()).ConVisor(repositories.VisorSistema).GetAutoridadUTEQByID(uint(id))
 	if err != nil {
 		return SendError(c, 404, "autoridad_no_encontrada", "No se encontró la autoridad UTEQ solicitada", "Verifique que el ID sea correcto")
 	}
@@ -196,7 +196,7 @@ func (h *AutoridadUTEQHandler) DeleteAutoridadUTEQ(c *fiber.Ctx) error {
 	}
 
 	// Eliminar autoridad
-	if err := h.autoridadRepo.DeleteAutoridadUTEQ(uint(id)); err != nil {
+	if err := h.autoridadRepo.ConContexto(c.UserContext()).DeleteAutoridadUTEQ(uint(id)); err != nil {
 		return SendError(c, 500, "error_base_datos", "Error interno del servidor", "No se pudo eliminar la autoridad UTEQ y sus datos relacionados")
 	}
 
@@ -219,7 +219,7 @@ func (h *AutoridadUTEQHandler) RestoreAutoridadUTEQ(c *fiber.Ctx) error {
 	}
 
 	// Restaurar autoridad
-	if err := h.autoridadRepo.RestoreAutoridadUTEQ(uint(id)); err != nil {
+	if err := h.autoridadRepo.ConContexto(c.UserContext()).RestoreAutoridadUTEQ(uint(id)); err != nil {
 		return SendError(c, 500, "database_error", "Error interno del servidor", "No se pudo restaurar la autoridad UTEQ y sus datos relacionados")
 	}
 
@@ -238,7 +238,7 @@ func (h *AutoridadUTEQHandler) GetAutoridadesUTEQByCargo(c *fiber.Ctx) error {
 		return SendValidationError(c, "Los parámetros de búsqueda no son válidos", validationErrors)
 	}
 
-	autoridades, err := h.autoridadRepo.GetAutoridadesUTEQByCargo(cargo)
+	autoridades, err := h.autoridadRepo.ConContexto(c.UserContext()).ConVisor(visorDe(c)).GetAutoridadesUTEQByCargo(cargo)
 	if err != nil {
 		return SendError(c, 500, "error_base_datos", "Error interno del servidor", "No se pudieron obtener las autoridades UTEQ")
 	}
@@ -258,7 +258,7 @@ func (h *AutoridadUTEQHandler) GetAutoridadUTEQByPersona(c *fiber.Ctx) error {
 		return SendError(c, 400, "persona_id_invalido", "El ID de la persona no es válido", "El ID debe ser un número entero positivo")
 	}
 
-	autoridad, err := h.autoridadRepo.GetAutoridadUTEQByPersona(uint(personaID))
+	autoridad, err := h.autoridadRepo.ConContexto(c.UserContext()).ConVisor(visorDe(c)).GetAutoridadUTEQByPersona(uint(personaID))
 	if err != nil {
 		return SendError(c, 404, "autoridad_no_encontrada", "No se encontró la autoridad UTEQ para esta persona", "Verifique que el ID de persona sea correcto")
 	}
@@ -340,12 +340,12 @@ func (h *AutoridadUTEQHandler) validateAutoridadUTEQSearchParams(cargo string) [
 }
 
 // personaExists verifica si una persona existe en la base de datos
-func (h *AutoridadUTEQHandler) personaExists(personaID uint) bool {
+func (h *AutoridadUTEQHandler) personaExists(c *fiber.Ctx, personaID uint) bool {
 	if personaID == 0 {
 		return false
 	}
 
 	var count int64
-	err := h.personaRepo.GetDB().Model(&models.Persona{}).Where("id = ?", personaID).Count(&count).Error
+	err := h.personaRepo.ConContexto(c.UserContext()).GetDB().Model(&models.Persona{}).Where("id = ?", personaID).Count(&count).Error
 	return err == nil && count > 0
 }
@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"ApiEscuela/services"
+	"errors"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	limiteBusquedaPorDef = 10
+	limiteBusquedaMax    = 50
+)
+
+type BusquedaHandler struct {
+	busquedaService *services.BusquedaService
+}
+
+func NewBusquedaHandler(busquedaService *services.BusquedaService) *BusquedaHandler {
+	return &BusquedaHandler{busquedaService: busquedaService}
+}
+
+// Buscar realiza la búsqueda de texto completo unificada (?q=&tipos=noticias,dudas,actividades,instituciones&limite=10)
+func (h *BusquedaHandler) Buscar(c *fiber.Ctx) error {
+	termino := strings.TrimSpace(c.Query("q"))
+	if utf8.RuneCountInString(termino) < 2 {
+		return SendError(c, 400, "termino_invalido", "El término de búsqueda es requerido", "El parámetro q debe tener al menos 2 caracteres")
+	}
+	if utf8.RuneCountInString(termino) > 200 {
+		return SendError(c, 400, "termino_invalido", "El término de búsqueda no es válido", "El parámetro q no puede exceder 200 caracteres")
+	}
+
+	limite := c.QueryInt("limite", limiteBusquedaPorDef)
+	if limite <= 0 || limite > limiteBusquedaMax {
+		return SendError(c, 400, "limite_invalido", "El límite no es válido", "El límite debe estar entre 1 y 50")
+	}
+
+	var tipos []string
+	if valor := strings.TrimSpace(c.Query("tipos")); valor != "" {
+		tipos = strings.Split(valor, ",")
+	}
+
+	userID, _ := c.Locals("user_id").(uint)
+	resultado, err := h.busquedaService.ConContexto(c.UserContext()).Buscar(userID, termino, tipos, limite)
+	if err != nil {
+		if errors.Is(err, services.ErrTipoBusquedaInvalido) {
+			return SendError(c, 400, "tipo_invalido", "El tipo de búsqueda no es válido", err.Error())
+		}
+		return SendError(c, 500, "error_base_datos", "Error interno del servidor", "No se pudo realizar la búsqueda")
+	}
+
+	return SendSuccess(c, 200, resultado)
+}
@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"ApiEscuela/services"
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type CalendarioHandler struct {
+	calendarioService *services.CalendarioService
+}
+
+func NewCalendarioHandler(calendarioService *services.CalendarioService) *CalendarioHandler {
+	return &CalendarioHandler{calendarioService: calendarioService}
+}
+
+// GetToken obtiene (o crea) el token del feed de calendario del usuario autenticado
+func (h *CalendarioHandler) GetToken(c *fiber.Ctx) error {
+	userID, ok := c.Locals("user_id").(uint)
+	if !ok {
+		return SendError(c, 401, "no_autenticado", "Usuario no autenticado", "No se pudo identificar al usuario")
+	}
+
+	token, err := h.calendarioService.ConContexto(c.UserContext()).ObtenerToken(userID)
+	if err != nil {
+		return SendError(c, 500, "error_token_calendario", "Error interno del servidor", "No se pudo obtener el token de calendario")
+	}
+
+	return SendSuccess(c, 200, h.respuestaToken(c, token.Token))
+}
+
+// RegenerarToken invalida el feed anterior y genera un token nuevo
+func (h *CalendarioHandler) RegenerarToken(c *fiber.Ctx) error {
+	userID, ok := c.Locals("user_id").(uint)
+	if !ok {
+		return SendError(c, 401, "no_autenticado", "Usuario no autenticado", "No se pudo identificar al usuario")
+	}
+
+	token, err := h.calendarioService.ConContexto(c.UserContext()).RegenerarToken(userID)
+	if err != nil {
+		return SendError(c, 500, "error_token_calendario", "Error interno del servidor", "No se pudo regenerar el token de calendario")
+	}
+
+	return SendSuccess(c, 200, h.respuestaToken(c, token.Token))
+}
+
+// GetFeed devuelve el calendario iCalendar del dueño del token (ruta pública)
+func (h *CalendarioHandler) GetFeed(c *fiber.Ctx) error {
+	contenido, err := h.calendarioService.ConContexto(c.UserContext()).GenerarFeed(c.Params("token"))
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrTokenCalendarioInvalido):
+			return SendError(c, 404, "calendario_no_encontrado", "Calendario no encontrado", "El token no es válido o fue regenerado")
+		case errors.Is(err, services.ErrSinAsignaciones):
+			return SendError(c, 404, "calendario_no_disponible", "Calendario no disponible", "El usuario no es autoridad ni estudiante universitario")
+		default:
+			return SendError(c, 500, "error_calendario", "Error interno del servidor", "No se pudo generar el calendario")
+		}
+	}
+
+	c.Set(fiber.HeaderContentType, "text/calendar; charset=utf-8")
+	c.Set(fiber.HeaderContentDisposition, `inline; filename="visitas.ics"`)
+	c.Set(fiber.HeaderCacheControl, "private, max-age=300")
+	return c.Send(contenido)
+}
+
+// respuestaToken arma la respuesta con el token y las URLs de suscripción
+func (h *CalendarioHandler) respuestaToken(c *fiber.Ctx, token string) fiber.Map {
+	url := c.BaseURL() + "/calendario/" + token + "/visitas.ics"
+	return fiber.Map{
+		"token":      token,
+		"url":        url,
+		"url_webcal": "webcal://" + c.Hostname() + "/calendario/" + token + "/visitas.ics",
+	}
+}
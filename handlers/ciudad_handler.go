@@ -26,7 +26,7 @@ func (h *CiudadHandler) CreateCiudad(c *fiber.Ctx) error {
 		})
 	}
 
-	if err := h.ciudadRepo.CreateCiudad(&ciudad); err != nil {
+	if err := h.ciudadRepo.ConContexto(c.UserContext()).CreateCiudad(&ciudad); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "No se puede crear la ciudad",
 		})
@@ -44,7 +44,7 @@ func (h *CiudadHandler) GetCiudad(c *fiber.Ctx) error {
 		})
 	}
 
-	ciudad, err := h.ciudadRepo.GetCiudadByID(uint(id))
+	ciudad, err := h.ciudadRepo.ConContexto(c.UserContext()).GetCiudadByID(uint(id))
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 			"error": "Ciudad no encontrada",
@@ -56,7 +56,7 @@ func (h *CiudadHandler) GetCiudad(c *fiber.Ctx) error {
 
 // GetAllCiudades obtiene todas las ciudades
 func (h *CiudadHandler) GetAllCiudades(c *fiber.Ctx) error {
-	ciudades, err := h.ciudadRepo.GetAllCiudades()
+	ciudades, err := h.ciudadRepo.ConContexto(c.UserContext()).GetAllCiudades()
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "No se pueden obtener las ciudades",
@@ -75,7 +75,7 @@ func (h *CiudadHandler) UpdateCiudad(c *fiber.Ctx) error {
 		})
 	}
 
-	ciudad, err := h.ciudadRepo.GetCiudadByID(uint(id))
+	ciudad, err := h.ciudadRepo.ConContexto(c.UserContext()).GetCiudadByID(uint(id))
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 			"error": "Ciudad no encontrada",
@@ -88,7 +88,7 @@ func (h *CiudadHandler) UpdateCiudad(c *fiber.Ctx) error {
 		})
 	}
 
-	if err := h.ciudadRepo.UpdateCiudad(ciudad); err != nil {
+	if err := h.ciudadRepo.ConContexto(c.UserContext()).UpdateCiudad(ciudad); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "No se puede actualizar la ciudad",
 		})
@@ -106,7 +106,7 @@ func (h *CiudadHandler) DeleteCiudad(c *fiber.Ctx) error {
 		})
 	}
 
-	if err := h.ciudadRepo.DeleteCiudad(uint(id)); err != nil {
+	if err := h.ciudadRepo.ConContexto(c.UserContext()).DeleteCiudad(uint(id)); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "No se puede eliminar la ciudad",
 		})
@@ -126,7 +126,7 @@ func (h *CiudadHandler) GetCiudadesByProvincia(c *fiber.Ctx) error {
 		})
 	}
 	
-	ciudades, err := h.ciudadRepo.GetCiudadesByProvincia(uint(provinciaID))
+	ciudades, err := h.ciudadRepo.ConContexto(c.UserContext()).GetCiudadesByProvincia(uint(provinciaID))
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "No se pueden obtener las ciudades",
@@ -140,7 +140,7 @@ func (h *CiudadHandler) GetCiudadesByProvincia(c *fiber.Ctx) error {
 func (h *CiudadHandler) GetCiudadByNombre(c *fiber.Ctx) error {
 	nombre := c.Params("nombre")
 	
-	ciudades, err := h.ciudadRepo.GetCiudadByNombre(nombre)
+	ciudades, err := h.ciudadRepo.ConContexto(c.UserContext()).GetCiudadByNombre(nombre)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "No se pueden obtener las ciudades",
@@ -43,7 +43,7 @@ func (h *CodigoHandler) CreateCodigo(c *fiber.Ctx) error {
 	codigo.Estado = strings.TrimSpace(codigo.Estado)
 
 	// Crear código
-	if err := h.codigoRepo.Crear(codigo.UsuarioID, codigo.Codigo); err != nil {
+	if err := h.codigoRepo.ConContexto(c.UserContext()).Crear(codigo.UsuarioID, codigo.Codigo); err != nil {
 		return SendError(c, 500, "database_error", "Error interno del servidor", "No se pudo crear el código")
 	}
 
@@ -66,7 +66,7 @@ func (h *CodigoHandler) GetCodigo(c *fiber.Ctx) error {
 		return SendError(c, 400, "invalid_id", "El ID del código no es válido", "El ID debe ser un número entero positivo")
 	}
 
-	codigo, err := h.codigoRepo.GetByID(uint(id))
+	codigo, err := h.codigoRepo.ConContexto(c.UserContext()).GetByID(uint(id))
 	if err != nil {
 		return SendError(c, 404, "codigo_not_found", "No se encontró el código solicitado", "Verifique que el ID sea correcto")
 	}
@@ -110,7 +110,7 @@ func (h *CodigoHandler) UpdateCodigo(c *fiber.Ctx) error {
 	}
 
 	// Verificar que el código existe
-	codigo, err := h.codigoRepo.GetByID(uint(id))
+	codigo, err := h.codigoRepo.ConContexto(c.UserContext()).GetByID(uint(id))
 	if err != nil {
 		return SendError(c, 404, "codigo_not_found", "No se encontró el código solicitado", "Verifique que el ID sea correcto")
 	}
@@ -133,7 +133,7 @@ func (h *CodigoHandler) UpdateCodigo(c *fiber.Ctx) error {
 	codigo.ExpiraEn = updateData.ExpiraEn
 
 	// Actualizar en base de datos
-	if err := h.codigoRepo.Update(codigo); err != nil {
+	if err := h.codigoRepo.ConContexto(c.UserContext()).Update(codigo); err != nil {
 		return SendError(c, 500, "database_error", "Error interno del servidor", "No se pudo actualizar el código")
 	}
 
@@ -153,13 +153,13 @@ func (h *CodigoHandler) DeleteCodigo(c *fiber.Ctx) error {
 	}
 
 	// Verificar que el código existe antes de eliminar
-	_, err = h.codigoRepo.GetByID(uint(id))
+	_, err = h.codigoRepo.ConContexto(c.UserContext()).GetByID(uint(id))
 	if err != nil {
 		return SendError(c, 404, "codigo_not_found", "No se encontró el código solicitado", "Verifique que el ID sea correcto")
 	}
 
 	// Los códigos no se eliminan físicamente, solo se marcan como expirados
-	if err := h.codigoRepo.MarcarComoExpirado(uint(id)); err != nil {
+	if err := h.codigoRepo.ConContexto(c.UserContext()).MarcarComoExpirado(uint(id)); err != nil {
 		return SendError(c, 500, "database_error", "Error interno del servidor", "No se pudo marcar el código como expirado")
 	}
 
@@ -186,7 +186,7 @@ func (h *CodigoHandler) VerifyCodigo(c *fiber.Ctx) error {
 	}
 
 	// Buscar código
-	codigo, err := h.codigoRepo.FindLatestByCodigo(strings.TrimSpace(req.Codigo))
+	codigo, err := h.codigoRepo.ConContexto(c.UserContext()).FindLatestByCodigo(strings.TrimSpace(req.Codigo))
 	if err != nil {
 		return SendError(c, 404, "codigo_not_found", "No se encontró el código", "Verifique que el código sea correcto")
 	}
@@ -199,7 +199,7 @@ func (h *CodigoHandler) VerifyCodigo(c *fiber.Ctx) error {
 	// Verificar expiración
 	if codigo.ExpiraEn != nil && codigo.ExpiraEn.Before(time.Now()) {
 		// Marcar como expirado
-		h.codigoRepo.MarcarComoExpirado(codigo.ID)
+		h.codigoRepo.ConContexto(c.UserContext()).MarcarComoExpirado(codigo.ID)
 		return SendError(c, 400, "codigo_expired", "El código ha expirado", "Solicite un nuevo código")
 	}
 
@@ -225,7 +225,7 @@ func (h *CodigoHandler) MarcarComoVerificado(c *fiber.Ctx) error {
 	}
 
 	// Verificar que el código existe
-	codigo, err := h.codigoRepo.GetByID(uint(id))
+	codigo, err := h.codigoRepo.ConContexto(c.UserContext()).GetByID(uint(id))
 	if err != nil {
 		return SendError(c, 404, "codigo_not_found", "No se encontró el código solicitado", "Verifique que el ID sea correcto")
 	}
@@ -236,7 +236,7 @@ func (h *CodigoHandler) MarcarComoVerificado(c *fiber.Ctx) error {
 	}
 
 	// Marcar como verificado
-	if err := h.codigoRepo.MarcarComoVerificado(uint(id)); err != nil {
+	if err := h.codigoRepo.ConContexto(c.UserContext()).MarcarComoVerificado(uint(id)); err != nil {
 		return SendError(c, 500, "database_error", "Error interno del servidor", "No se pudo marcar el código como verificado")
 	}
 
@@ -259,13 +259,13 @@ func (h *CodigoHandler) MarcarComoExpirado(c *fiber.Ctx) error {
 	}
 
 	// Verificar que el código existe
-	_, err = h.codigoRepo.GetByID(uint(id))
+	_, err = h.codigoRepo.ConContexto(c.UserContext()).GetByID(uint(id))
 	if err != nil {
 		return SendError(c, 404, "codigo_not_found", "No se encontró el código solicitado", "Verifique que el ID sea correcto")
 	}
 
 	// Marcar como expirado
-	if err := h.codigoRepo.MarcarComoExpirado(uint(id)); err != nil {
+	if err := h.codigoRepo.ConContexto(c.UserContext()).MarcarComoExpirado(uint(id)); err != nil {
 		return SendError(c, 500, "database_error", "Error interno del servidor", "No se pudo marcar el código como expirado")
 	}
 
@@ -3,17 +3,19 @@ package handlers
 import (
 	"ApiEscuela/models"
 	"ApiEscuela/repositories"
+	"ApiEscuela/services"
 	"strconv"
 
 	"github.com/gofiber/fiber/v2"
 )
 
 type DetalleAutoridadDetallesVisitaHandler struct {
-	detalleRepo *repositories.DetalleAutoridadDetallesVisitaRepository
+	detalleRepo       *repositories.DetalleAutoridadDetallesVisitaRepository
+	calendarioService *services.CalendarioService
 }
 
-func NewDetalleAutoridadDetallesVisitaHandler(detalleRepo *repositories.DetalleAutoridadDetallesVisitaRepository) *DetalleAutoridadDetallesVisitaHandler {
-	return &DetalleAutoridadDetallesVisitaHandler{detalleRepo: detalleRepo}
+func NewDetalleAutoridadDetallesVisitaHandler(detalleRepo *repositories.DetalleAutoridadDetallesVisitaRepository, calendarioService *services.CalendarioService) *DetalleAutoridadDetallesVisitaHandler {
+	return &DetalleAutoridadDetallesVisitaHandler{detalleRepo: detalleRepo, calendarioService: calendarioService}
 }
 
 // CreateDetalleAutoridadDetallesVisita crea un nuevo detalle de autoridad para visita
@@ -27,7 +29,7 @@ func (h *DetalleAutoridadDetallesVisitaHandler) CreateDetalleAutoridadDetallesVi
 	}
 
 	// Verificar si la relación ya existe
-	exists, err := h.detalleRepo.ExistsRelation(detalle.ProgramaVisitaID, detalle.AutoridadUTEQID)
+	exists, err := h.detalleRepo.ConContexto(c.UserContext()).ExistsRelation(detalle.ProgramaVisitaID, detalle.AutoridadUTEQID)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Error al verificar la relación existente",
@@ -40,12 +42,15 @@ func (h *DetalleAutoridadDetallesVisitaHandler) CreateDetalleAutoridadDetallesVi
 		})
 	}
 
-	if err := h.detalleRepo.CreateDetalleAutoridadDetallesVisita(&detalle); err != nil {
+	if err := h.detalleRepo.ConContexto(c.UserContext()).CreateDetalleAutoridadDetallesVisita(&detalle); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "No se puede crear el detalle de autoridad",
 		})
 	}
 
+	// Enviar la invitación de calendario a la autoridad asignada
+	h.calendarioService.ConContexto(c.UserContext()).NotificarAsignacionAutoridad(c.UserContext(), detalle.ID)
+
 	return c.Status(fiber.StatusCreated).JSON(detalle)
 }
 
@@ -58,7 +63,7 @@ func (h *DetalleAutoridadDetallesVisitaHandler) GetDetalleAutoridadDetallesVisit
 		})
 	}
 
-	detalle, err := h.detalleRepo.GetDetalleAutoridadDetallesVisitaByID(uint(id))
+	detalle, err := h.detalleRepo.ConContexto(c.UserContext()).GetDetalleAutoridadDetallesVisitaByID(uint(id))
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 			"error": "Detalle no encontrado",
@@ -70,7 +75,7 @@ func (h *DetalleAutoridadDetallesVisitaHandler) GetDetalleAutoridadDetallesVisit
 
 // GetAllDetalleAutoridadDetallesVisitas obtiene todos los detalles
 func (h *DetalleAutoridadDetallesVisitaHandler) GetAllDetalleAutoridadDetallesVisitas(c *fiber.Ctx) error {
-	detalles, err := h.detalleRepo.GetAllDetalleAutoridadDetallesVisitas()
+	detalles, err := h.detalleRepo.ConContexto(c.UserContext()).GetAllDetalleAutoridadDetallesVisitas()
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "No se pueden obtener los detalles",
@@ -89,7 +94,7 @@ func (h *DetalleAutoridadDetallesVisitaHandler) UpdateDetalleAutoridadDetallesVi
 		})
 	}
 
-	detalle, err := h.detalleRepo.GetDetalleAutoridadDetallesVisitaByID(uint(id))
+	detalle, err := h.detalleRepo.ConContexto(c.UserContext()).GetDetalleAutoridadDetallesVisitaByID(uint(id))
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 			"error": "Detalle no encontrado",
@@ -102,12 +107,14 @@ func (h *DetalleAutoridadDetallesVisitaHandler) UpdateDetalleAutoridadDetallesVi
 		})
 	}
 
-	if err := h.detalleRepo.UpdateDetalleAutoridadDetallesVisita(detalle); err != nil {
+	if err := h.detalleRepo.ConContexto(c.UserContext()).UpdateDetalleAutoridadDetallesVisita(detalle); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "No se puede actualizar el detalle",
 		})
 	}
 
+	h.calendarioService.ConContexto(c.UserContext()).NotificarAsignacionAutoridad(c.UserContext(), detalle.ID)
+
 	return c.JSON(detalle)
 }
 
@@ -120,12 +127,15 @@ func (h *DetalleAutoridadDetallesVisitaHandler) DeleteDetalleAutoridadDetallesVi
 		})
 	}
 
-	if err := h.detalleRepo.DeleteDetalleAutoridadDetallesVisita(uint(id)); err != nil {
+	if err := h.detalleRepo.ConContexto(c.UserContext()).DeleteDetalleAutoridadDetallesVisita(uint(id)); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "No se puede eliminar el detalle",
 		})
 	}
 
+	// Enviar la cancelación para que la visita desaparezca del calendario
+	h.calendarioService.ConContexto(c.UserContext()).NotificarAsignacionAutoridad(c.UserContext(), uint(id))
+
 	return c.JSON(fiber.Map{
 		"message": "Detalle eliminado exitosamente",
 	})
@@ -140,7 +150,7 @@ func (h *DetalleAutoridadDetallesVisitaHandler) GetDetallesByProgramaVisita(c *f
 		})
 	}
 
-	detalles, err := h.detalleRepo.GetDetallesByProgramaVisitaID(uint(programaVisitaID))
+	detalles, err := h.detalleRepo.ConContexto(c.UserContext()).GetDetallesByProgramaVisitaID(uint(programaVisitaID))
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "No se pueden obtener los detalles del programa de visita",
@@ -159,7 +169,7 @@ func (h *DetalleAutoridadDetallesVisitaHandler) GetDetallesByAutoridad(c *fiber.
 		})
 	}
 
-	detalles, err := h.detalleRepo.GetDetallesByAutoridadID(uint(autoridadID))
+	detalles, err := h.detalleRepo.ConContexto(c.UserContext()).GetDetallesByAutoridadID(uint(autoridadID))
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "No se pueden obtener los detalles de la autoridad",
@@ -178,12 +188,19 @@ func (h *DetalleAutoridadDetallesVisitaHandler) DeleteDetallesByProgramaVisita(c
 		})
 	}
 
-	if err := h.detalleRepo.DeleteDetallesByProgramaVisitaID(uint(programaVisitaID)); err != nil {
+	// Guardar las asignaciones antes de eliminarlas para notificar la cancelación
+	eliminados, _ := h.detalleRepo.ConContexto(c.UserContext()).GetDetallesByProgramaVisitaID(uint(programaVisitaID))
+
+	if err := h.detalleRepo.ConContexto(c.UserContext()).DeleteDetallesByProgramaVisitaID(uint(programaVisitaID)); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "No se pueden eliminar los detalles",
 		})
 	}
 
+	for _, detalle := range eliminados {
+		h.calendarioService.ConContexto(c.UserContext()).NotificarAsignacionAutoridad(c.UserContext(), detalle.ID)
+	}
+
 	return c.JSON(fiber.Map{
 		"message": "Todos los detalles del programa de visita eliminados exitosamente",
 	})
@@ -198,12 +215,19 @@ func (h *DetalleAutoridadDetallesVisitaHandler) DeleteDetallesByAutoridad(c *fib
 		})
 	}
 
-	if err := h.detalleRepo.DeleteDetallesByAutoridadID(uint(autoridadID)); err != nil {
+	// Guardar las asignaciones antes de eliminarlas para notificar la cancelación
+	eliminados, _ := h.detalleRepo.ConContexto(c.UserContext()).GetDetallesByAutoridadID(uint(autoridadID))
+
+	if err := h.detalleRepo.ConContexto(c.UserContext()).DeleteDetallesByAutoridadID(uint(autoridadID)); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "No se pueden eliminar los detalles",
 		})
 	}
 
+	for _, detalle := range eliminados {
+		h.calendarioService.ConContexto(c.UserContext()).NotificarAsignacionAutoridad(c.UserContext(), detalle.ID)
+	}
+
 	return c.JSON(fiber.Map{
 		"message": "Todos los detalles de la autoridad eliminados exitosamente",
 	})
@@ -211,7 +235,7 @@ func (h *DetalleAutoridadDetallesVisitaHandler) DeleteDetallesByAutoridad(c *fib
 
 // GetEstadisticasAsignacion obtiene estadísticas de asignación de autoridades
 func (h *DetalleAutoridadDetallesVisitaHandler) GetEstadisticasAsignacion(c *fiber.Ctx) error {
-	estadisticas, err := h.detalleRepo.GetEstadisticasAsignacion()
+	estadisticas, err := h.detalleRepo.ConContexto(c.UserContext()).GetEstadisticasAsignacion()
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "No se pueden obtener las estadísticas",
@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"ApiEscuela/repositories"
+	"ApiEscuela/services"
+	"errors"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type DisponibilidadHandler struct {
+	disponibilidadRepo *repositories.DisponibilidadPersonalRepository
+	asignacionService  *services.AsignacionService
+}
+
+func NewDisponibilidadHandler(disponibilidadRepo *repositories.DisponibilidadPersonalRepository, asignacionService *services.AsignacionService) *DisponibilidadHandler {
+	return &DisponibilidadHandler{
+		disponibilidadRepo: disponibilidadRepo,
+		asignacionService:  asignacionService,
+	}
+}
+
+// CreateDisponibilidad registra una franja de disponibilidad
+func (h *DisponibilidadHandler) CreateDisponibilidad(c *fiber.Ctx) error {
+	var req services.DisponibilidadRequest
+	if err := c.BodyParser(&req); err != nil {
+		return SendError(c, 400, "json_invalido", "No se puede procesar el JSON. Verifique el formato de los datos", err.Error())
+	}
+
+	disponibilidad, err := h.asignacionService.ConContexto(c.UserContext()).CrearDisponibilidad(req)
+	if err != nil {
+		return SendError(c, 400, "disponibilidad_invalida", "No se pudo registrar la disponibilidad", err.Error())
+	}
+
+	return SendSuccess(c, 201, disponibilidad)
+}
+
+// UpdateDisponibilidad actualiza una franja de disponibilidad
+func (h *DisponibilidadHandler) UpdateDisponibilidad(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil || id <= 0 {
+		return SendError(c, 400, "id_invalido", "El ID de la disponibilidad no es válido", "El ID debe ser un número entero positivo")
+	}
+
+	var req services.DisponibilidadRequest
+	if err := c.BodyParser(&req); err != nil {
+		return SendError(c, 400, "json_invalido", "No se puede procesar el JSON. Verifique el formato de los datos", err.Error())
+	}
+
+	disponibilidad, err := h.asignacionService.ConContexto(c.UserContext()).ActualizarDisponibilidad(uint(id), req)
+	if err != nil {
+		if errors.Is(err, services.ErrDisponibilidadNoEncontrada) {
+			return SendError(c, 404, "disponibilidad_no_encontrada", "No se encontró la disponibilidad solicitada", "Verifique que el ID sea correcto")
+		}
+		return SendError(c, 400, "disponibilidad_invalida", "No se pudo actualizar la disponibilidad", err.Error())
+	}
+
+	return SendSuccess(c, 200, disponibilidad)
+}
+
+// DeleteDisponibilidad elimina una franja de disponibilidad
+func (h *DisponibilidadHandler) DeleteDisponibilidad(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil || id <= 0 {
+		return SendError(c, 400, "id_invalido", "El ID de la disponibilidad no es válido", "El ID debe ser un número entero positivo")
+	}
+
+	if _, err := h.disponibilidadRepo.ConContexto(c.UserContext()).GetDisponibilidadByID(uint(id)); err != nil {
+		return SendError(c, 404, "disponibilidad_no_encontrada", "No se encontró la disponibilidad solicitada", "Verifique que el ID sea correcto")
+	}
+
+	if err := h.disponibilidadRepo.ConContexto(c.UserContext()).DeleteDisponibilidad(uint(id)); err != nil {
+		return SendError(c, 500, "error_base_datos", "Error interno del servidor", "No se pudo eliminar la disponibilidad")
+	}
+
+	return SendSuccess(c, 200, fiber.Map{
+		"message": "Disponibilidad eliminada exitosamente",
+		"id":      id,
+	})
+}
+
+// GetDisponibilidadesByAutoridad obtiene las franjas de una autoridad UTEQ
+func (h *DisponibilidadHandler) GetDisponibilidadesByAutoridad(c *fiber.Ctx) error {
+	autoridadID, err := strconv.Atoi(c.Params("autoridad_id"))
+	if err != nil || autoridadID <= 0 {
+		return SendError(c, 400, "autoridad_id_invalido", "El ID de la autoridad no es válido", "El ID debe ser un número entero positivo")
+	}
+
+	disponibilidades, err := h.disponibilidadRepo.ConContexto(c.UserContext()).GetDisponibilidadesByAutoridad(uint(autoridadID))
+	if err != nil {
+		return SendError(c, 500, "error_base_datos", "Error interno del servidor", "No se pudieron obtener las disponibilidades")
+	}
+
+	return SendSuccess(c, 200, disponibilidades)
+}
+
+// GetDisponibilidadesByEstudiante obtiene las franjas de un estudiante universitario
+func (h *DisponibilidadHandler) GetDisponibilidadesByEstudiante(c *fiber.Ctx) error {
+	estudianteID, err := strconv.Atoi(c.Params("estudiante_id"))
+	if err != nil || estudianteID <= 0 {
+		return SendError(c, 400, "estudiante_id_invalido", "El ID del estudiante no es válido", "El ID debe ser un número entero positivo")
+	}
+
+	disponibilidades, err := h.disponibilidadRepo.ConContexto(c.UserContext()).GetDisponibilidadesByEstudiante(uint(estudianteID))
+	if err != nil {
+		return SendError(c, 500, "error_base_datos", "Error interno del servidor", "No se pudieron obtener las disponibilidades")
+	}
+
+	return SendSuccess(c, 200, disponibilidades)
+}
+
+// GetSugerencias lista el personal disponible para un programa de visita (?tipo=autoridad|estudiante&limite=N)
+func (h *DisponibilidadHandler) GetSugerencias(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil || id <= 0 {
+		return SendError(c, 400, "id_invalido", "El ID del programa de visita no es válido", "El ID debe ser un número entero positivo")
+	}
+
+	limite := c.QueryInt("limite", 10)
+	if limite <= 0 {
+		return SendError(c, 400, "limite_invalido", "El límite no es válido", "Use un número entero positivo en 'limite'")
+	}
+
+	sugerencias, err := h.asignacionService.ConContexto(c.UserContext()).SugerirPersonal(uint(id), c.Query("tipo", services.TipoPersonalAutoridad))
+	if err != nil {
+		return h.sendAsignacionError(c, err, "No se pudieron calcular las sugerencias")
+	}
+	if len(sugerencias) > limite {
+		sugerencias = sugerencias[:limite]
+	}
+
+	return SendSuccess(c, 200, sugerencias)
+}
+
+// AutoAsignar asigna automáticamente el personal mejor ubicado a un programa de visita
+func (h *DisponibilidadHandler) AutoAsignar(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil || id <= 0 {
+		return SendError(c, 400, "id_invalido", "El ID del programa de visita no es válido", "El ID debe ser un número entero positivo")
+	}
+
+	var req services.AutoAsignacionRequest
+	if err := c.BodyParser(&req); err != nil {
+		return SendError(c, 400, "json_invalido", "No se puede procesar el JSON. Verifique el formato de los datos", err.Error())
+	}
+
+	resultado, err := h.asignacionService.ConContexto(c.UserContext()).AutoAsignar(c.UserContext(), uint(id), req)
+	if err != nil {
+		return h.sendAsignacionError(c, err, "No se pudo realizar la asignación automática")
+	}
+
+	return SendSuccess(c, 201, resultado)
+}
+
+// sendAsignacionError traduce los errores del servicio de asignación a respuestas HTTP
+func (h *DisponibilidadHandler) sendAsignacionError(c *fiber.Ctx, err error, message string) error {
+	switch {
+	case errors.Is(err, services.ErrProgramaNoEncontrado):
+		return SendError(c, 404, "programa_no_encontrado", "No se encontró el programa de visita", "Verifique que el ID sea correcto")
+	case errors.Is(err, services.ErrTipoPersonalInvalido):
+		return SendError(c, 400, "tipo_invalido", "El tipo de personal no es válido", "Use 'autoridad' o 'estudiante'")
+	default:
+		return SendError(c, 400, "asignacion_invalida", message, err.Error())
+	}
+}
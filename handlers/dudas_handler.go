@@ -3,18 +3,45 @@ package handlers
 import (
 	"ApiEscuela/models"
 	"ApiEscuela/repositories"
+	"ApiEscuela/services"
+	"errors"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 )
 
 type DudasHandler struct {
-	dudasRepo *repositories.DudasRepository
+	dudasRepo    *repositories.DudasRepository
+	dudasService *services.DudasService
+	slaService   *services.SLADudasService
 }
 
-func NewDudasHandler(dudasRepo *repositories.DudasRepository) *DudasHandler {
-	return &DudasHandler{dudasRepo: dudasRepo}
+func NewDudasHandler(dudasRepo *repositories.DudasRepository, dudasService *services.DudasService, slaService *services.SLADudasService) *DudasHandler {
+	return &DudasHandler{dudasRepo: dudasRepo, dudasService: dudasService, slaService: slaService}
+}
+
+// claveVisorDudas es la clave de c.Locals donde IdentificarVisor guarda el visor
+const claveVisorDudas = "visor_dudas"
+
+// IdentificarVisor es un middleware que obtiene una vez por petición las reglas de visibilidad de dudas
+// del usuario autenticado. Se usa en las rutas de dudas y en las que incluyen las dudas de un estudiante
+// o una autoridad.
+func (h *DudasHandler) IdentificarVisor(c *fiber.Ctx) error {
+	userID, _ := c.Locals("user_id").(uint)
+	visor, err := h.dudasService.ConContexto(c.UserContext()).Visor(userID)
+	if err != nil {
+		return SendError(c, 401, "usuario_no_valido", "No se pudo identificar al usuario", "Inicie sesión nuevamente")
+	}
+	c.Locals(claveVisorDudas, visor)
+	return c.Next()
+}
+
+// visorDe devuelve el visor guardado por IdentificarVisor; si la ruta no lo usa, solo se ven las dudas públicas
+func visorDe(c *fiber.Ctx) repositories.VisorDudas {
+	visor, _ := c.Locals(claveVisorDudas).(repositories.VisorDudas)
+	return visor
 }
 
 // CreateDudas crea una nueva duda
@@ -36,8 +63,13 @@ func (h *DudasHandler) CreateDudas(c *fiber.Ctx) error {
 		return SendValidationError(c, "Los datos proporcionados no son válidos", validationErrors)
 	}
 
-	// Crear duda
-	if err := h.dudasRepo.CreateDudas(&duda); err != nil {
+	// Crear duda con su plazo de respuesta y asignación automática
+	if err := h.slaService.ConContexto(c.UserContext()).RegistrarDuda(c.UserContext(), &duda); err != nil {
+		if errors.Is(err, services.ErrTematicaDudaInvalida) {
+			return SendValidationError(c, "Los datos proporcionados no son válidos", []ValidationError{
+				{Field: "tematica_id", Message: err.Error()},
+			})
+		}
 		return SendError(c, 500, "error_base_datos", "Error interno del servidor", "No se pudo crear la duda")
 	}
 
@@ -56,7 +88,9 @@ func (h *DudasHandler) GetDudas(c *fiber.Ctx) error {
 		return SendError(c, 400, "id_invalido", "El ID de la duda no es válido", "El ID debe ser un número entero positivo")
 	}
 
-	duda, err := h.dudasRepo.GetDudasByID(uint(id))
+	visor := visorDe(c)
+
+	duda, err := h.dudasRepo.ConContexto(c.UserContext()).GetDudasByID(visor, uint(id))
 	if err != nil {
 		return SendError(c, 404, "duda_no_encontrada", "No se encontró la duda solicitada", "Verifique que el ID sea correcto")
 	}
@@ -66,7 +100,9 @@ func (h *DudasHandler) GetDudas(c *fiber.Ctx) error {
 
 // GetAllDudas obtiene todas las dudas
 func (h *DudasHandler) GetAllDudas(c *fiber.Ctx) error {
-	dudas, err := h.dudasRepo.GetAllDudas()
+	visor := visorDe(c)
+
+	dudas, err := h.dudasRepo.ConContexto(c.UserContext()).GetAllDudas(visor)
 	if err != nil {
 		return SendError(c, 500, "error_base_datos", "Error interno del servidor", "No se pudieron obtener las dudas")
 	}
@@ -86,8 +122,10 @@ func (h *DudasHandler) UpdateDudas(c *fiber.Ctx) error {
 		return SendError(c, 400, "id_invalido", "El ID de la duda no es válido", "El ID debe ser un número entero positivo")
 	}
 
-	// Verificar que la duda existe
-	existingDuda, err := h.dudasRepo.GetDudasByID(uint(id))
+	visor := visorDe(c)
+
+	// Verificar que la duda existe y es visible para el usuario
+	existingDuda, err := h.dudasRepo.ConContexto(c.UserContext()).GetDudasByID(visor, uint(id))
 	if err != nil {
 		return SendError(c, 404, "duda_no_encontrada", "No se encontró la duda solicitada", "Verifique que el ID sea correcto")
 	}
@@ -98,26 +136,58 @@ func (h *DudasHandler) UpdateDudas(c *fiber.Ctx) error {
 		return SendError(c, 400, "json_invalido", "No se puede procesar el JSON. Verifique el formato de los datos", err.Error())
 	}
 
+	// Solo el estudiante autor y el personal UTEQ editan; reasignar o cambiar la privacidad es solo del personal
+	userID, _ := c.Locals("user_id").(uint)
+	if err := h.dudasService.ConContexto(c.UserContext()).VerificarEdicion(uint(id), userID, &updateData); err != nil {
+		return h.sendHiloError(c, err, "No se pudo actualizar la duda")
+	}
+
 	// Validar datos de actualización
 	if validationErrors := h.validateDudas(&updateData, true); len(validationErrors) > 0 {
 		return SendValidationError(c, "Los datos proporcionados no son válidos", validationErrors)
 	}
 
-	// Actualizar campos
+	// Actualizar campos. La respuesta no se reemplaza aquí: si cambia se agrega al hilo como con /responder
 	existingDuda.Pregunta = updateData.Pregunta
-	existingDuda.Respuesta = updateData.Respuesta
 	existingDuda.Privacidad = updateData.Privacidad
 	existingDuda.EstudianteID = updateData.EstudianteID
+	if !mismaAutoridad(existingDuda.AutoridadUTEQID, updateData.AutoridadUTEQID) {
+		ahora := time.Now()
+		existingDuda.AsignadaEn = &ahora
+		existingDuda.AutoridadUTEQ = nil
+	}
 	existingDuda.AutoridadUTEQID = updateData.AutoridadUTEQID
+	existingDuda.TematicaID = updateData.TematicaID
 
 	// Guardar cambios
-	if err := h.dudasRepo.UpdateDudas(existingDuda); err != nil {
+	if err := h.dudasRepo.ConContexto(c.UserContext()).UpdateDudas(existingDuda); err != nil {
 		return SendError(c, 500, "error_base_datos", "Error interno del servidor", "No se pudo actualizar la duda")
 	}
 
+	if respuesta := respuestaNueva(existingDuda, updateData.Respuesta); respuesta != "" {
+		if _, err := h.dudasService.ConContexto(c.UserContext()).Responder(uint(id), userID, respuesta, existingDuda.AutoridadUTEQID); err != nil {
+			return h.sendHiloError(c, err, "No se pudo responder la duda")
+		}
+		if existingDuda, err = h.dudasRepo.ConContexto(c.UserContext()).GetDudasByID(visor, uint(id)); err != nil {
+			return SendError(c, 500, "error_base_datos", "Error interno del servidor", "No se pudo obtener la duda actualizada")
+		}
+	}
+
 	return SendSuccess(c, 200, existingDuda)
 }
 
+// respuestaNueva devuelve la respuesta enviada al editar una duda si difiere de la última registrada
+func respuestaNueva(duda *models.Dudas, enviada *string) string {
+	if enviada == nil {
+		return ""
+	}
+	respuesta := strings.TrimSpace(*enviada)
+	if duda.Respuesta != nil && strings.TrimSpace(*duda.Respuesta) == respuesta {
+		return ""
+	}
+	return respuesta
+}
+
 // DeleteDudas elimina una duda
 func (h *DudasHandler) DeleteDudas(c *fiber.Ctx) error {
 	idStr := c.Params("id")
@@ -130,14 +200,22 @@ func (h *DudasHandler) DeleteDudas(c *fiber.Ctx) error {
 		return SendError(c, 400, "id_invalido", "El ID de la duda no es válido", "El ID debe ser un número entero positivo")
 	}
 
-	// Verificar que la duda existe
-	_, err = h.dudasRepo.GetDudasByID(uint(id))
+	visor := visorDe(c)
+
+	// Verificar que la duda existe y es visible para el usuario
+	_, err = h.dudasRepo.ConContexto(c.UserContext()).GetDudasByID(visor, uint(id))
 	if err != nil {
 		return SendError(c, 404, "duda_no_encontrada", "No se encontró la duda solicitada", "Verifique que el ID sea correcto")
 	}
 
+	// Solo el estudiante autor y el personal UTEQ pueden eliminarla
+	userID, _ := c.Locals("user_id").(uint)
+	if err := h.dudasService.ConContexto(c.UserContext()).VerificarEliminacion(uint(id), userID); err != nil {
+		return h.sendHiloError(c, err, "No se pudo eliminar la duda")
+	}
+
 	// Eliminar duda
-	if err := h.dudasRepo.DeleteDudas(uint(id)); err != nil {
+	if err := h.dudasRepo.ConContexto(c.UserContext()).DeleteDudas(uint(id)); err != nil {
 		return SendError(c, 500, "error_base_datos", "Error interno del servidor", "No se pudo eliminar la duda")
 	}
 
@@ -159,7 +237,9 @@ func (h *DudasHandler) GetDudasByEstudiante(c *fiber.Ctx) error {
 		return SendError(c, 400, "estudiante_id_invalido", "El ID del estudiante no es válido", "El ID debe ser un número entero positivo")
 	}
 
-	dudas, err := h.dudasRepo.GetDudasByEstudiante(uint(estudianteID))
+	visor := visorDe(c)
+
+	dudas, err := h.dudasRepo.ConContexto(c.UserContext()).GetDudasByEstudiante(visor, uint(estudianteID))
 	if err != nil {
 		return SendError(c, 500, "error_base_datos", "Error interno del servidor", "No se pudieron obtener las dudas")
 	}
@@ -179,7 +259,9 @@ func (h *DudasHandler) GetDudasByAutoridad(c *fiber.Ctx) error {
 		return SendError(c, 400, "autoridad_id_invalido", "El ID de la autoridad no es válido", "El ID debe ser un número entero positivo")
 	}
 
-	dudas, err := h.dudasRepo.GetDudasByAutoridad(uint(autoridadID))
+	visor := visorDe(c)
+
+	dudas, err := h.dudasRepo.ConContexto(c.UserContext()).GetDudasByAutoridad(visor, uint(autoridadID))
 	if err != nil {
 		return SendError(c, 500, "error_base_datos", "Error interno del servidor", "No se pudieron obtener las dudas")
 	}
@@ -189,7 +271,9 @@ func (h *DudasHandler) GetDudasByAutoridad(c *fiber.Ctx) error {
 
 // GetDudasSinResponder obtiene dudas sin respuesta
 func (h *DudasHandler) GetDudasSinResponder(c *fiber.Ctx) error {
-	dudas, err := h.dudasRepo.GetDudasSinResponder()
+	visor := visorDe(c)
+
+	dudas, err := h.dudasRepo.ConContexto(c.UserContext()).GetDudasSinResponder(visor)
 	if err != nil {
 		return SendError(c, 500, "error_base_datos", "Error interno del servidor", "No se pudieron obtener las dudas")
 	}
@@ -199,7 +283,9 @@ func (h *DudasHandler) GetDudasSinResponder(c *fiber.Ctx) error {
 
 // GetDudasRespondidas obtiene dudas con respuesta
 func (h *DudasHandler) GetDudasRespondidas(c *fiber.Ctx) error {
-	dudas, err := h.dudasRepo.GetDudasRespondidas()
+	visor := visorDe(c)
+
+	dudas, err := h.dudasRepo.ConContexto(c.UserContext()).GetDudasRespondidas(visor)
 	if err != nil {
 		return SendError(c, 500, "error_base_datos", "Error interno del servidor", "No se pudieron obtener las dudas")
 	}
@@ -209,7 +295,9 @@ func (h *DudasHandler) GetDudasRespondidas(c *fiber.Ctx) error {
 
 // GetDudasSinAsignar obtiene dudas sin autoridad asignada
 func (h *DudasHandler) GetDudasSinAsignar(c *fiber.Ctx) error {
-	dudas, err := h.dudasRepo.GetDudasSinAsignar()
+	visor := visorDe(c)
+
+	dudas, err := h.dudasRepo.ConContexto(c.UserContext()).GetDudasSinAsignar(visor)
 	if err != nil {
 		return SendError(c, 500, "error_base_datos", "Error interno del servidor", "No se pudieron obtener las dudas")
 	}
@@ -229,7 +317,9 @@ func (h *DudasHandler) BuscarDudasPorPregunta(c *fiber.Ctx) error {
 		return SendValidationError(c, "Los parámetros de búsqueda no son válidos", validationErrors)
 	}
 
-	dudas, err := h.dudasRepo.BuscarDudasPorPregunta(termino)
+	visor := visorDe(c)
+
+	dudas, err := h.dudasRepo.ConContexto(c.UserContext()).BuscarDudasPorPregunta(visor, termino)
 	if err != nil {
 		return SendError(c, 500, "error_base_datos", "Error interno del servidor", "No se pudieron obtener las dudas")
 	}
@@ -249,15 +339,9 @@ func (h *DudasHandler) ResponderDuda(c *fiber.Ctx) error {
 		return SendError(c, 400, "duda_id_invalido", "El ID de la duda no es válido", "El ID debe ser un número entero positivo")
 	}
 
-	// Verificar que la duda existe
-	_, err = h.dudasRepo.GetDudasByID(uint(dudaID))
-	if err != nil {
-		return SendError(c, 404, "duda_no_encontrada", "No se encontró la duda solicitada", "Verifique que el ID sea correcto")
-	}
-
 	var requestData struct {
 		Respuesta       string `json:"respuesta"`
-		AutoridadUTEQID uint   `json:"autoridad_uteq_id"`
+		AutoridadUTEQID *uint  `json:"autoridad_uteq_id"`
 	}
 
 	if err := c.BodyParser(&requestData); err != nil {
@@ -275,23 +359,17 @@ func (h *DudasHandler) ResponderDuda(c *fiber.Ctx) error {
 		})
 	}
 
-	if requestData.AutoridadUTEQID == 0 {
-		return SendValidationError(c, "El ID de la autoridad es requerido", []ValidationError{
-			{
-				Field:   "autoridad_uteq_id",
-				Message: "El ID de la autoridad es requerido",
-				Value:   "0",
-			},
-		})
-	}
-
-	if err := h.dudasRepo.ResponderDuda(uint(dudaID), requestData.Respuesta, requestData.AutoridadUTEQID); err != nil {
-		return SendError(c, 500, "error_base_datos", "Error interno del servidor", "No se pudo responder la duda")
+	userID, _ := c.Locals("user_id").(uint)
+	// La respuesta se agrega al hilo; las respuestas anteriores se conservan
+	mensaje, err := h.dudasService.ConContexto(c.UserContext()).Responder(uint(dudaID), userID, requestData.Respuesta, requestData.AutoridadUTEQID)
+	if err != nil {
+		return h.sendHiloError(c, err, "No se pudo responder la duda")
 	}
 
 	return SendSuccess(c, 200, fiber.Map{
 		"message": "Duda respondida exitosamente",
 		"duda_id": dudaID,
+		"mensaje": mensaje,
 	})
 }
 
@@ -313,7 +391,9 @@ func (h *DudasHandler) GetDudasByPrivacidad(c *fiber.Ctx) error {
 		})
 	}
 
-	dudas, err := h.dudasRepo.GetDudasByPrivacidad(privacidad)
+	visor := visorDe(c)
+
+	dudas, err := h.dudasRepo.ConContexto(c.UserContext()).GetDudasByPrivacidad(visor, privacidad)
 	if err != nil {
 		return SendError(c, 500, "error_base_datos", "Error interno del servidor", "No se pudieron obtener las dudas")
 	}
@@ -321,6 +401,146 @@ func (h *DudasHandler) GetDudasByPrivacidad(c *fiber.Ctx) error {
 	return SendSuccess(c, 200, dudas)
 }
 
+// GetHilo obtiene los mensajes de una duda y la marca como leída
+func (h *DudasHandler) GetHilo(c *fiber.Ctx) error {
+	dudaID, err := strconv.Atoi(c.Params("id"))
+	if err != nil || dudaID <= 0 {
+		return SendError(c, 400, "id_invalido", "El ID de la duda no es válido", "El ID debe ser un número entero positivo")
+	}
+
+	userID, _ := c.Locals("user_id").(uint)
+	hilo, err := h.dudasService.ConContexto(c.UserContext()).ObtenerHilo(uint(dudaID), userID)
+	if err != nil {
+		return h.sendHiloError(c, err, "No se pudo obtener el hilo de la duda")
+	}
+
+	return SendSuccess(c, 200, hilo)
+}
+
+// CreateMensaje agrega un mensaje o una nota interna al hilo de una duda
+func (h *DudasHandler) CreateMensaje(c *fiber.Ctx) error {
+	dudaID, err := strconv.Atoi(c.Params("id"))
+	if err != nil || dudaID <= 0 {
+		return SendError(c, 400, "id_invalido", "El ID de la duda no es válido", "El ID debe ser un número entero positivo")
+	}
+
+	var req services.MensajeDudaRequest
+	if err := c.BodyParser(&req); err != nil {
+		return SendError(c, 400, "json_invalido", "No se puede procesar el JSON. Verifique el formato de los datos", err.Error())
+	}
+
+	userID, _ := c.Locals("user_id").(uint)
+	mensaje, err := h.dudasService.ConContexto(c.UserContext()).AgregarMensaje(uint(dudaID), userID, req)
+	if err != nil {
+		return h.sendHiloError(c, err, "No se pudo agregar el mensaje")
+	}
+
+	return SendSuccess(c, 201, mensaje)
+}
+
+// UpdateMensaje edita un mensaje propio conservando el historial
+func (h *DudasHandler) UpdateMensaje(c *fiber.Ctx) error {
+	dudaID, err := strconv.Atoi(c.Params("id"))
+	if err != nil || dudaID <= 0 {
+		return SendError(c, 400, "id_invalido", "El ID de la duda no es válido", "El ID debe ser un número entero positivo")
+	}
+	mensajeID, err := strconv.Atoi(c.Params("mensaje_id"))
+	if err != nil || mensajeID <= 0 {
+		return SendError(c, 400, "mensaje_id_invalido", "El ID del mensaje no es válido", "El ID debe ser un número entero positivo")
+	}
+
+	var req services.MensajeDudaRequest
+	if err := c.BodyParser(&req); err != nil {
+		return SendError(c, 400, "json_invalido", "No se puede procesar el JSON. Verifique el formato de los datos", err.Error())
+	}
+
+	userID, _ := c.Locals("user_id").(uint)
+	mensaje, err := h.dudasService.ConContexto(c.UserContext()).EditarMensaje(uint(dudaID), uint(mensajeID), userID, req.Contenido)
+	if err != nil {
+		return h.sendHiloError(c, err, "No se pudo editar el mensaje")
+	}
+
+	return SendSuccess(c, 200, mensaje)
+}
+
+// GetHistorialMensaje obtiene un mensaje con sus versiones anteriores
+func (h *DudasHandler) GetHistorialMensaje(c *fiber.Ctx) error {
+	dudaID, err := strconv.Atoi(c.Params("id"))
+	if err != nil || dudaID <= 0 {
+		return SendError(c, 400, "id_invalido", "El ID de la duda no es válido", "El ID debe ser un número entero positivo")
+	}
+	mensajeID, err := strconv.Atoi(c.Params("mensaje_id"))
+	if err != nil || mensajeID <= 0 {
+		return SendError(c, 400, "mensaje_id_invalido", "El ID del mensaje no es válido", "El ID debe ser un número entero positivo")
+	}
+
+	userID, _ := c.Locals("user_id").(uint)
+	mensaje, err := h.dudasService.ConContexto(c.UserContext()).HistorialMensaje(uint(dudaID), uint(mensajeID), userID)
+	if err != nil {
+		return h.sendHiloError(c, err, "No se pudo obtener el historial del mensaje")
+	}
+
+	return SendSuccess(c, 200, mensaje)
+}
+
+// MarcarLeida marca el hilo de una duda como leído
+func (h *DudasHandler) MarcarLeida(c *fiber.Ctx) error {
+	dudaID, err := strconv.Atoi(c.Params("id"))
+	if err != nil || dudaID <= 0 {
+		return SendError(c, 400, "id_invalido", "El ID de la duda no es válido", "El ID debe ser un número entero positivo")
+	}
+
+	userID, _ := c.Locals("user_id").(uint)
+	if err := h.dudasService.ConContexto(c.UserContext()).MarcarLeida(uint(dudaID), userID); err != nil {
+		return h.sendHiloError(c, err, "No se pudo marcar la duda como leída")
+	}
+
+	return SendSuccess(c, 200, fiber.Map{"duda_id": dudaID, "leida": true})
+}
+
+// MarcarNoLeida marca el hilo de una duda como no leído
+func (h *DudasHandler) MarcarNoLeida(c *fiber.Ctx) error {
+	dudaID, err := strconv.Atoi(c.Params("id"))
+	if err != nil || dudaID <= 0 {
+		return SendError(c, 400, "id_invalido", "El ID de la duda no es válido", "El ID debe ser un número entero positivo")
+	}
+
+	userID, _ := c.Locals("user_id").(uint)
+	if err := h.dudasService.ConContexto(c.UserContext()).MarcarNoLeida(uint(dudaID), userID); err != nil {
+		return h.sendHiloError(c, err, "No se pudo marcar la duda como no leída")
+	}
+
+	return SendSuccess(c, 200, fiber.Map{"duda_id": dudaID, "leida": false})
+}
+
+// GetNoLeidas obtiene la cantidad de mensajes no leídos por duda del usuario autenticado
+func (h *DudasHandler) GetNoLeidas(c *fiber.Ctx) error {
+	userID, _ := c.Locals("user_id").(uint)
+	conteos, err := h.dudasService.ConContexto(c.UserContext()).NoLeidas(userID)
+	if err != nil {
+		return SendError(c, 500, "error_base_datos", "Error interno del servidor", "No se pudieron obtener los mensajes no leídos")
+	}
+
+	return SendSuccess(c, 200, conteos)
+}
+
+// sendHiloError traduce los errores del hilo de dudas a respuestas HTTP
+func (h *DudasHandler) sendHiloError(c *fiber.Ctx, err error, message string) error {
+	switch {
+	case errors.Is(err, services.ErrDudaNoEncontrada):
+		return SendError(c, 404, "duda_no_encontrada", "No se encontró la duda solicitada", "Verifique que el ID sea correcto")
+	case errors.Is(err, services.ErrMensajeNoEncontrado):
+		return SendError(c, 404, "mensaje_no_encontrado", "No se encontró el mensaje solicitado", "Verifique que el ID sea correcto")
+	case errors.Is(err, services.ErrSinAccesoDuda):
+		return SendError(c, 403, "sin_acceso_duda", "No tiene acceso a esta duda", err.Error())
+	case errors.Is(err, services.ErrNotaInternaNoPermitida), errors.Is(err, services.ErrEdicionNoPermitida),
+		errors.Is(err, services.ErrReasignacionNoPermitida), errors.Is(err, services.ErrRespuestaNoPermitida):
+		return SendError(c, 403, "accion_no_permitida", message, err.Error())
+	default:
+		return SendError(c, 400, "mensaje_invalido", message, err.Error())
+	}
+}
+
 // validateDudas valida los datos de una duda
 func (h *DudasHandler) validateDudas(duda *models.Dudas, isUpdate bool) []ValidationError {
 	var errors []ValidationError
@@ -430,3 +650,11 @@ func (h *DudasHandler) validateDudasSearchParams(termino string) []ValidationErr
 
 	return errors
 }
+
+// mismaAutoridad compara dos asignaciones opcionales
+func mismaAutoridad(a, b *uint) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
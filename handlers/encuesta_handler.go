@@ -0,0 +1,209 @@
+package handlers
+
+import (
+	"ApiEscuela/repositories"
+	"ApiEscuela/services"
+	"errors"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type EncuestaHandler struct {
+	encuestaRepo    *repositories.EncuestaRepository
+	encuestaService *services.EncuestaService
+}
+
+func NewEncuestaHandler(encuestaRepo *repositories.EncuestaRepository, encuestaService *services.EncuestaService) *EncuestaHandler {
+	return &EncuestaHandler{encuestaRepo: encuestaRepo, encuestaService: encuestaService}
+}
+
+// CreateEncuesta crea una encuesta con sus preguntas
+func (h *EncuestaHandler) CreateEncuesta(c *fiber.Ctx) error {
+	var req services.EncuestaRequest
+	if err := c.BodyParser(&req); err != nil {
+		return SendError(c, 400, "json_invalido", "No se puede procesar el JSON. Verifique el formato de los datos", err.Error())
+	}
+
+	encuesta, err := h.encuestaService.ConContexto(c.UserContext()).CrearEncuesta(req)
+	if err != nil {
+		return SendError(c, 400, "encuesta_invalida", "No se pudo crear la encuesta", err.Error())
+	}
+
+	return SendSuccess(c, 201, encuesta)
+}
+
+// GetAllEncuestas obtiene todas las encuestas
+func (h *EncuestaHandler) GetAllEncuestas(c *fiber.Ctx) error {
+	encuestas, err := h.encuestaRepo.ConContexto(c.UserContext()).GetAllEncuestas()
+	if err != nil {
+		return SendError(c, 500, "error_base_datos", "Error interno del servidor", "No se pudieron obtener las encuestas")
+	}
+
+	return SendSuccess(c, 200, encuestas)
+}
+
+// GetEncuesta obtiene una encuesta por ID
+func (h *EncuestaHandler) GetEncuesta(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil || id <= 0 {
+		return SendError(c, 400, "id_invalido", "El ID de la encuesta no es válido", "El ID debe ser un número entero positivo")
+	}
+
+	encuesta, err := h.encuestaRepo.ConContexto(c.UserContext()).GetEncuestaByID(uint(id))
+	if err != nil {
+		return SendError(c, 404, "encuesta_no_encontrada", "No se encontró la encuesta solicitada", "Verifique que el ID sea correcto")
+	}
+
+	return SendSuccess(c, 200, encuesta)
+}
+
+// UpdateEncuesta actualiza una encuesta
+func (h *EncuestaHandler) UpdateEncuesta(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil || id <= 0 {
+		return SendError(c, 400, "id_invalido", "El ID de la encuesta no es válido", "El ID debe ser un número entero positivo")
+	}
+
+	var req services.EncuestaRequest
+	if err := c.BodyParser(&req); err != nil {
+		return SendError(c, 400, "json_invalido", "No se puede procesar el JSON. Verifique el formato de los datos", err.Error())
+	}
+
+	encuesta, err := h.encuestaService.ConContexto(c.UserContext()).ActualizarEncuesta(uint(id), req)
+	if err != nil {
+		return h.sendEncuestaError(c, err, "No se pudo actualizar la encuesta")
+	}
+
+	return SendSuccess(c, 200, encuesta)
+}
+
+// DeleteEncuesta elimina una encuesta
+func (h *EncuestaHandler) DeleteEncuesta(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil || id <= 0 {
+		return SendError(c, 400, "id_invalido", "El ID de la encuesta no es válido", "El ID debe ser un número entero positivo")
+	}
+
+	if _, err := h.encuestaRepo.ConContexto(c.UserContext()).GetEncuestaByID(uint(id)); err != nil {
+		return SendError(c, 404, "encuesta_no_encontrada", "No se encontró la encuesta solicitada", "Verifique que el ID sea correcto")
+	}
+
+	if err := h.encuestaRepo.ConContexto(c.UserContext()).DeleteEncuesta(uint(id)); err != nil {
+		return SendError(c, 500, "error_base_datos", "Error interno del servidor", "No se pudo eliminar la encuesta")
+	}
+
+	return SendSuccess(c, 200, fiber.Map{
+		"message": "Encuesta eliminada exitosamente",
+		"id":      id,
+	})
+}
+
+// GetResultados devuelve las respuestas agregadas de una encuesta
+func (h *EncuestaHandler) GetResultados(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil || id <= 0 {
+		return SendError(c, 400, "id_invalido", "El ID de la encuesta no es válido", "El ID debe ser un número entero positivo")
+	}
+
+	resultados, err := h.encuestaService.ConContexto(c.UserContext()).Resultados(uint(id))
+	if err != nil {
+		return h.sendEncuestaError(c, err, "No se pudieron obtener los resultados")
+	}
+
+	return SendSuccess(c, 200, resultados)
+}
+
+// GetResultadosPorActividad devuelve la satisfacción promedio por actividad
+func (h *EncuestaHandler) GetResultadosPorActividad(c *fiber.Ctx) error {
+	resumen, err := h.encuestaService.ConContexto(c.UserContext()).ResumenPorActividad()
+	if err != nil {
+		return SendError(c, 500, "error_base_datos", "Error interno del servidor", "No se pudieron obtener los resultados por actividad")
+	}
+
+	return SendSuccess(c, 200, resumen)
+}
+
+// GetResultadosPorTematica devuelve la satisfacción promedio por temática
+func (h *EncuestaHandler) GetResultadosPorTematica(c *fiber.Ctx) error {
+	resumen, err := h.encuestaService.ConContexto(c.UserContext()).ResumenPorTematica()
+	if err != nil {
+		return SendError(c, 500, "error_base_datos", "Error interno del servidor", "No se pudieron obtener los resultados por temática")
+	}
+
+	return SendSuccess(c, 200, resumen)
+}
+
+// CompletarVisita marca un programa de visita como completado y envía las encuestas a los participantes
+func (h *EncuestaHandler) CompletarVisita(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil || id <= 0 {
+		return SendError(c, 400, "id_invalido", "El ID del programa de visita no es válido", "El ID debe ser un número entero positivo")
+	}
+
+	var req services.CompletarVisitaRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return SendError(c, 400, "json_invalido", "No se puede procesar el JSON. Verifique el formato de los datos", err.Error())
+		}
+	}
+
+	resultado, err := h.encuestaService.ConContexto(c.UserContext()).CompletarVisita(c.UserContext(), uint(id), req, c.BaseURL())
+	if err != nil {
+		return h.sendEncuestaError(c, err, "No se pudo completar la visita")
+	}
+
+	return SendSuccess(c, 200, resultado)
+}
+
+// GetEncuestaPublica devuelve el cuestionario de un enlace de encuesta (ruta pública)
+func (h *EncuestaHandler) GetEncuestaPublica(c *fiber.Ctx) error {
+	encuesta, err := h.encuestaService.ConContexto(c.UserContext()).ObtenerEncuestaPorToken(c.Params("token"))
+	if err != nil {
+		return h.sendEncuestaError(c, err, "No se pudo obtener la encuesta")
+	}
+
+	return SendSuccess(c, 200, fiber.Map{
+		"titulo":      encuesta.Titulo,
+		"descripcion": encuesta.Descripcion,
+		"preguntas":   encuesta.Preguntas,
+	})
+}
+
+// ResponderEncuesta guarda la respuesta anónima de un enlace de encuesta (ruta pública)
+func (h *EncuestaHandler) ResponderEncuesta(c *fiber.Ctx) error {
+	var req services.RespuestaEncuestaRequest
+	if err := c.BodyParser(&req); err != nil {
+		return SendError(c, 400, "json_invalido", "No se puede procesar el JSON. Verifique el formato de los datos", err.Error())
+	}
+
+	if err := h.encuestaService.ConContexto(c.UserContext()).Responder(c.Params("token"), req); err != nil {
+		return h.sendEncuestaError(c, err, "No se pudo guardar la respuesta")
+	}
+
+	return SendSuccess(c, 201, fiber.Map{
+		"message": "¡Gracias! Su respuesta fue registrada",
+	})
+}
+
+// sendEncuestaError traduce los errores del servicio de encuestas a respuestas HTTP
+func (h *EncuestaHandler) sendEncuestaError(c *fiber.Ctx, err error, message string) error {
+	switch {
+	case errors.Is(err, services.ErrEncuestaNoEncontrada):
+		return SendError(c, 404, "encuesta_no_encontrada", "No se encontró la encuesta solicitada", "Verifique que el ID sea correcto")
+	case errors.Is(err, services.ErrProgramaNoEncontrado):
+		return SendError(c, 404, "programa_no_encontrado", "No se encontró el programa de visita", "Verifique que el ID sea correcto")
+	case errors.Is(err, services.ErrEncuestaConRespuestas):
+		return SendError(c, 409, "encuesta_con_respuestas", message, err.Error())
+	case errors.Is(err, services.ErrVisitaYaCompletada):
+		return SendError(c, 409, "visita_completada", "La visita ya fue marcada como completada", "Las encuestas solo se envían una vez")
+	case errors.Is(err, services.ErrVisitaNoFinalizada):
+		return SendError(c, 409, "visita_no_finalizada", "La visita aún no ha comenzado", "Solo se pueden completar visitas cuya fecha ya pasó")
+	case errors.Is(err, services.ErrInvitacionInvalida):
+		return SendError(c, 404, "enlace_invalido", "El enlace de la encuesta no es válido o expiró", "Solicite un nuevo enlace a la UTEQ")
+	case errors.Is(err, services.ErrInvitacionUsada):
+		return SendError(c, 409, "encuesta_respondida", "Esta encuesta ya fue respondida", "Cada enlace solo puede usarse una vez")
+	default:
+		return SendError(c, 400, "encuesta_invalida", message, err.Error())
+	}
+}
@@ -1,9 +1,14 @@
 package handlers
 
 import (
+	"ApiEscuela/trazas"
+	"errors"
+	"log/slog"
+	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
 )
 
 // ErrorResponse representa una respuesta de error estandarizada
@@ -16,6 +21,8 @@ type ErrorResponse struct {
 	Timestamp  string   `json:"timestamp"`
 	Path       string   `json:"path"`
 	Method     string   `json:"method"`
+	RequestID  string   `json:"request_id,omitempty"`
+	TraceID    string   `json:"trace_id,omitempty"`
 }
 
 // ValidationError representa un error de validación
@@ -35,6 +42,8 @@ type ValidationResponse struct {
 	Timestamp  string            `json:"timestamp"`
 	Path       string            `json:"path"`
 	Method     string            `json:"method"`
+	RequestID  string            `json:"request_id,omitempty"`
+	TraceID    string            `json:"trace_id,omitempty"`
 }
 
 // NewErrorResponse crea una nueva respuesta de error
@@ -48,6 +57,8 @@ func NewErrorResponse(c *fiber.Ctx, statusCode int, error, message string, detai
 		Timestamp:  time.Now().Format(time.RFC3339),
 		Path:       c.Path(),
 		Method:     c.Method(),
+		RequestID:  requestID(c),
+		TraceID:    trazas.TraceID(c.UserContext()),
 	}
 }
 
@@ -62,6 +73,8 @@ func NewValidationResponse(c *fiber.Ctx, message string, validation []Validation
 		Timestamp:  time.Now().Format(time.RFC3339),
 		Path:       c.Path(),
 		Method:     c.Method(),
+		RequestID:  requestID(c),
+		TraceID:    trazas.TraceID(c.UserContext()),
 	}
 }
 
@@ -86,3 +99,37 @@ func SendSuccess(c *fiber.Ctx, statusCode int, data interface{}) error {
 		"method":      c.Method(),
 	})
 }
+
+// ManejarError es el ErrorHandler de Fiber: responde los errores que ningún handler atendió (rutas
+// inexistentes, cuerpos demasiado grandes) con el formato estándar y registra los errores internos
+// con el identificador de la petición
+func ManejarError(c *fiber.Ctx, err error) error {
+	estado := fiber.StatusInternalServerError
+	var errFiber *fiber.Error
+	if errors.As(err, &errFiber) {
+		estado = errFiber.Code
+	}
+	if estado >= 500 {
+		slog.ErrorContext(c.UserContext(), "error no controlado", "metodo", c.Method(), "ruta", c.Route().Path, "error", err)
+		return SendError(c, estado, "error_interno", "Ocurrió un error interno. Indique el request_id al reportarlo")
+	}
+	return SendError(c, estado, strings.ToLower(strings.ReplaceAll(utils.StatusMessage(estado), " ", "_")), err.Error())
+}
+
+// requestID devuelve el identificador asignado por middleware.RequestID
+func requestID(c *fiber.Ctx) string {
+	id, _ := c.Locals("request_id").(string)
+	return id
+}
+
+// conTraza agrega los identificadores de la petición y de la traza a un cuerpo de error que no usa
+// SendError, como los de las rutas públicas que responden el JSON sin el sobre estándar
+func conTraza(c *fiber.Ctx, cuerpo fiber.Map) fiber.Map {
+	if id := requestID(c); id != "" {
+		cuerpo["request_id"] = id
+	}
+	if id := trazas.TraceID(c.UserContext()); id != "" {
+		cuerpo["trace_id"] = id
+	}
+	return cuerpo
+}
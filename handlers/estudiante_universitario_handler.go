@@ -45,17 +45,17 @@ func (h *EstudianteUniversitarioHandler) CreateEstudianteUniversitario(c *fiber.
 	}
 
 	// Verificar que la persona existe (validación de relación)
-	if !h.personaExists(estudiante.PersonaID) {
+	if !h.personaExists(c, estudiante.PersonaID) {
 		return SendError(c, 400, "persona_no_existe", "No se encontró la persona con el ID especificado", "Verifique que el persona_id sea correcto y que la persona exista en el sistema")
 	}
 
 	// Verificar si la persona ya tiene un registro de estudiante universitario
-	if existingEstudiante, _ := h.estudianteUnivRepo.GetEstudianteUniversitarioByPersona(estudiante.PersonaID); existingEstudiante != nil {
+	if existingEstudiante, _ := h.estudianteUnivRepo.ConContexto(c.UserContext()).GetEstudianteUniversitarioByPersona(estudiante.PersonaID); existingEstudiante != nil {
 		return SendError(c, 409, "estudiante_univ_duplicado", "La persona ya tiene un registro de estudiante universitario", "Una persona solo puede tener un registro de estudiante universitario")
 	}
 
 	// Crear estudiante universitario
-	if err := h.estudianteUnivRepo.CreateEstudianteUniversitario(&estudiante); err != nil {
+	if err := h.estudianteUnivRepo.ConContexto(c.UserContext()).CreateEstudianteUniversitario(&estudiante); err != nil {
 		// Manejar errores específicos del repositorio
 		switch err.Error() {
 		case "estudiante universitario ya existe":
@@ -80,7 +80,7 @@ func (h *EstudianteUniversitarioHandler) GetEstudianteUniversitario(c *fiber.Ctx
 		return SendError(c, 400, "id_invalido", "El ID del estudiante universitario no es válido", "El ID debe ser un número entero positivo")
 	}
 
-	estudiante, err := h.estudianteUnivRepo.GetEstudianteUniversitarioByID(uint(id))
+	estudiante, err := h.estudianteUnivRepo.ConContexto(c.UserContext()).GetEstudianteUniversitarioByID(uint(id))
 	if err != nil {
 		return SendError(c, 404, "estudiante_univ_no_encontrado", "No se encontró el estudiante universitario solicitado", "Verifique que el ID sea correcto")
 	}
@@ -90,7 +90,7 @@ func (h *EstudianteUniversitarioHandler) GetEstudianteUniversitario(c *fiber.Ctx
 
 // GetAllEstudiantesUniversitarios obtiene todos los estudiantes universitarios
 func (h *EstudianteUniversitarioHandler) GetAllEstudiantesUniversitarios(c *fiber.Ctx) error {
-	estudiantes, err := h.estudianteUnivRepo.GetAllEstudiantesUniversitarios()
+	estudiantes, err := h.estudianteUnivRepo.ConContexto(c.UserContext()).GetAllEstudiantesUniversitarios()
 	if err != nil {
 		return SendError(c, 500, "error_base_datos", "Error interno del servidor", "No se pudieron obtener los estudiantes universitarios")
 	}
@@ -111,7 +111,7 @@ func (h *EstudianteUniversitarioHandler) UpdateEstudianteUniversitario(c *fiber.
 	}
 
 	// Verificar que el estudiante existe
-	existingEstudiante, err := h.estudianteUnivRepo.GetEstudianteUniversitarioByID(uint(id))
+	existingEstudiante, err := h.estudianteUnivRepo.ConContexto(c.UserContext()).GetEstudianteUniversitarioByID(uint(id))
 	if err != nil {
 		return SendError(c, 404, "estudiante_univ_no_encontrado", "No se encontró el estudiante universitario solicitado", "Verifique que el ID sea correcto")
 	}
@@ -130,11 +130,11 @@ func (h *EstudianteUniversitarioHandler) UpdateEstudianteUniversitario(c *fiber.
 	// Verificar si la nueva persona ya tiene un registro de estudiante universitario (si cambia la persona)
 	if updateData.PersonaID != existingEstudiante.PersonaID {
 		// Verificar que la nueva persona existe
-		if !h.personaExists(updateData.PersonaID) {
+		if !h.personaExists(c, updateData.PersonaID) {
 			return SendError(c, 400, "persona_no_existe", "No se encontró la persona con el ID especificado", "Verifique que el persona_id sea correcto y que la persona exista en el sistema")
 		}
 
-		if existingEstudianteByPersona, _ := h.estudianteUnivRepo.GetEstudianteUniversitarioByPersona(updateData.PersonaID); existingEstudianteByPersona != nil {
+		if existingEstudianteByPersona, _ := h.estudianteUnivRepo.ConContexto(c.UserContext()).GetEstudianteUniversitarioByPersona(updateData.PersonaID); existingEstudianteByPersona != nil {
 			return SendError(c, 409, "estudiante_univ_duplicado", "La persona ya tiene un registro de estudiante universitario", "Una persona solo puede tener un registro de estudiante universitario")
 		}
 	}
@@ -144,7 +144,7 @@ func (h *EstudianteUniversitarioHandler) UpdateEstudianteUniversitario(c *fiber.
 	existingEstudiante.Semestre = updateData.Semestre
 
 	// Guardar cambios
-	if err := h.estudianteUnivRepo.UpdateEstudianteUniversitario(existingEstudiante); err != nil {
+	if err := h.estudianteUnivRepo.ConContexto(c.UserContext()).UpdateEstudianteUniversitario(existingEstudiante); err != nil {
 		// Manejar errores específicos del repositorio
 		switch err.Error() {
 		case "estudiante universitario ya existe":
@@ -170,7 +170,7 @@ func (h *EstudianteUniversitarioHandler) DeleteEstudianteUniversitario(c *fiber.
 	}
 
 	// Verificar que el estudiante existe
-	estudiante, err := h.estudianteUnivRepo.GetEstudianteUniversitarioByID(uint(id))
+	estudiante, err := h.estudianteUnivRepo.ConContexto(c.UserContext()).GetEstudianteUniversitarioByID(uint(id))
 	if err != nil {
 		return SendError(c, 404, "estudiante_univ_no_encontrado", "No se encontró el estudiante universitario solicitado", "Verifique que el ID sea correcto")
 	}
@@ -181,7 +181,7 @@ func (h *EstudianteUniversitarioHandler) DeleteEstudianteUniversitario(c *fiber.
 	}
 
 	// Eliminar estudiante universitario
-	if err := h.estudianteUnivRepo.DeleteEstudianteUniversitario(uint(id)); err != nil {
+	if err := h.estudianteUnivRepo.ConContexto(c.UserContext()).DeleteEstudianteUniversitario(uint(id)); err != nil {
 		return SendError(c, 500, "error_base_datos", "Error interno del servidor", "No se pudo eliminar el estudiante universitario")
 	}
 
@@ -208,7 +208,7 @@ func (h *EstudianteUniversitarioHandler) GetEstudiantesUniversitariosBySemestre(
 		return SendValidationError(c, "Los parámetros de búsqueda no son válidos", validationErrors)
 	}
 
-	estudiantes, err := h.estudianteUnivRepo.GetEstudiantesUniversitariosBySemestre(semestre)
+	estudiantes, err := h.estudianteUnivRepo.ConContexto(c.UserContext()).GetEstudiantesUniversitariosBySemestre(semestre)
 	if err != nil {
 		return SendError(c, 500, "error_base_datos", "Error interno del servidor", "No se pudieron obtener los estudiantes universitarios")
 	}
@@ -228,7 +228,7 @@ func (h *EstudianteUniversitarioHandler) GetEstudianteUniversitarioByPersona(c *
 		return SendError(c, 400, "persona_id_invalido", "El ID de la persona no es válido", "El ID debe ser un número entero positivo")
 	}
 
-	estudiante, err := h.estudianteUnivRepo.GetEstudianteUniversitarioByPersona(uint(personaID))
+	estudiante, err := h.estudianteUnivRepo.ConContexto(c.UserContext()).GetEstudianteUniversitarioByPersona(uint(personaID))
 	if err != nil {
 		return SendError(c, 404, "estudiante_univ_no_encontrado", "No se encontró el estudiante universitario para esta persona", "Verifique que el ID de persona sea correcto")
 	}
@@ -305,12 +305,12 @@ func (h *EstudianteUniversitarioHandler) validateEstudianteUniversitarioSearchPa
 }
 
 // personaExists verifica si una persona existe en la base de datos
-func (h *EstudianteUniversitarioHandler) personaExists(personaID uint) bool {
+func (h *EstudianteUniversitarioHandler) personaExists(c *fiber.Ctx, personaID uint) bool {
 	if personaID == 0 {
 		return false
 	}
 
 	var count int64
-	err := h.personaRepo.GetDB().Model(&models.Persona{}).Where("id = ?", personaID).Count(&count).Error
+	err := h.personaRepo.ConContexto(c.UserContext()).GetDB().Model(&models.Persona{}).Where("id = ?", personaID).Count(&count).Error
 	return err == nil && count > 0
 }
@@ -0,0 +1,203 @@
+package handlers
+
+import (
+	"ApiEscuela/repositories"
+	"ApiEscuela/services"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// cacheFAQPublico permite a navegadores y proxies reutilizar las respuestas públicas del FAQ por 5 minutos.
+// Estas respuestas se envían sin el sobre de SendSuccess, cuyo timestamp cambiaría el ETag cada segundo.
+const cacheFAQPublico = "public, max-age=300"
+
+type FAQHandler struct {
+	faqRepo    *repositories.PreguntaFrecuenteRepository
+	faqService *services.FAQService
+}
+
+func NewFAQHandler(faqRepo *repositories.PreguntaFrecuenteRepository, faqService *services.FAQService) *FAQHandler {
+	return &FAQHandler{faqRepo: faqRepo, faqService: faqService}
+}
+
+// GetFAQPublico obtiene las preguntas frecuentes publicadas (?categoria=&q=)
+func (h *FAQHandler) GetFAQPublico(c *fiber.Ctx) error {
+	termino := strings.TrimSpace(c.Query("q"))
+	if len(termino) > 100 {
+		return SendError(c, 400, "termino_invalido", "El término de búsqueda no es válido", "El término no puede exceder 100 caracteres")
+	}
+
+	preguntas, err := h.faqRepo.ConContexto(c.UserContext()).GetPreguntasPublicadas(strings.TrimSpace(c.Query("categoria")), termino)
+	if err != nil {
+		return SendError(c, 500, "error_base_datos", "Error interno del servidor", "No se pudieron obtener las preguntas frecuentes")
+	}
+
+	c.Set(fiber.HeaderCacheControl, cacheFAQPublico)
+	return c.JSON(preguntas)
+}
+
+// GetCategoriasPublicas obtiene las categorías del FAQ con entradas publicadas
+func (h *FAQHandler) GetCategoriasPublicas(c *fiber.Ctx) error {
+	categorias, err := h.faqRepo.ConContexto(c.UserContext()).GetCategoriasPublicadas()
+	if err != nil {
+		return SendError(c, 500, "error_base_datos", "Error interno del servidor", "No se pudieron obtener las categorías")
+	}
+
+	c.Set(fiber.HeaderCacheControl, cacheFAQPublico)
+	return c.JSON(categorias)
+}
+
+// GetSugerencias sugiere preguntas frecuentes parecidas al texto de una duda nueva (?q=)
+func (h *FAQHandler) GetSugerencias(c *fiber.Ctx) error {
+	texto := strings.TrimSpace(c.Query("q"))
+	if texto == "" {
+		return SendError(c, 400, "texto_faltante", "El texto de la pregunta es requerido", "Envíe el parámetro q")
+	}
+	if len(texto) > 1000 {
+		return SendError(c, 400, "texto_invalido", "El texto de la pregunta no es válido", "El texto no puede exceder 1000 caracteres")
+	}
+
+	sugerencias, err := h.faqService.ConContexto(c.UserContext()).Sugerencias(texto)
+	if err != nil {
+		return SendError(c, 500, "error_base_datos", "Error interno del servidor", "No se pudieron obtener las sugerencias")
+	}
+
+	c.Set(fiber.HeaderCacheControl, cacheFAQPublico)
+	return c.JSON(sugerencias)
+}
+
+// GetAllPreguntasFrecuentes obtiene todas las entradas del FAQ, incluidas las no publicadas
+func (h *FAQHandler) GetAllPreguntasFrecuentes(c *fiber.Ctx) error {
+	preguntas, err := h.faqRepo.ConContexto(c.UserContext()).GetAllPreguntasFrecuentes()
+	if err != nil {
+		return SendError(c, 500, "error_base_datos", "Error interno del servidor", "No se pudieron obtener las preguntas frecuentes")
+	}
+
+	return SendSuccess(c, 200, preguntas)
+}
+
+// GetPreguntaFrecuente obtiene una entrada del FAQ por ID
+func (h *FAQHandler) GetPreguntaFrecuente(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil || id <= 0 {
+		return SendError(c, 400, "id_invalido", "El ID de la pregunta frecuente no es válido", "El ID debe ser un número entero positivo")
+	}
+
+	pregunta, err := h.faqRepo.ConContexto(c.UserContext()).GetPreguntaFrecuenteByID(uint(id))
+	if err != nil {
+		return SendError(c, 404, "pregunta_frecuente_no_encontrada", "No se encontró la pregunta frecuente solicitada", "Verifique que el ID sea correcto")
+	}
+
+	return SendSuccess(c, 200, pregunta)
+}
+
+// CreatePreguntaFrecuente crea una entrada del FAQ redactada por el personal
+func (h *FAQHandler) CreatePreguntaFrecuente(c *fiber.Ctx) error {
+	var req services.PreguntaFrecuenteRequest
+	if err := c.BodyParser(&req); err != nil {
+		return SendError(c, 400, "json_invalido", "No se puede procesar el JSON. Verifique el formato de los datos", err.Error())
+	}
+
+	userID, _ := c.Locals("user_id").(uint)
+	pregunta, err := h.faqService.ConContexto(c.UserContext()).Crear(userID, req)
+	if err != nil {
+		if errors.Is(err, services.ErrGestionFAQNoPermitida) {
+			return h.sinPermiso(c, err)
+		}
+		return SendError(c, 400, "pregunta_frecuente_invalida", "No se pudo crear la pregunta frecuente", err.Error())
+	}
+
+	return SendSuccess(c, 201, pregunta)
+}
+
+// PromoverDuda crea una entrada del FAQ a partir de una duda pública respondida
+func (h *FAQHandler) PromoverDuda(c *fiber.Ctx) error {
+	dudaID, err := strconv.Atoi(c.Params("duda_id"))
+	if err != nil || dudaID <= 0 {
+		return SendError(c, 400, "duda_id_invalido", "El ID de la duda no es válido", "El ID debe ser un número entero positivo")
+	}
+
+	var req services.PreguntaFrecuenteRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return SendError(c, 400, "json_invalido", "No se puede procesar el JSON. Verifique el formato de los datos", err.Error())
+		}
+	}
+
+	userID, _ := c.Locals("user_id").(uint)
+	pregunta, err := h.faqService.ConContexto(c.UserContext()).Promover(uint(dudaID), userID, req)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrGestionFAQNoPermitida):
+			return h.sinPermiso(c, err)
+		case errors.Is(err, services.ErrDudaNoEncontrada):
+			return SendError(c, 404, "duda_no_encontrada", "No se encontró la duda solicitada", "Verifique que el ID sea correcto")
+		case errors.Is(err, services.ErrDudaYaPromovida):
+			return SendError(c, 409, "duda_ya_promovida", "La duda ya forma parte del FAQ", err.Error())
+		case errors.Is(err, services.ErrDudaNoPromovible):
+			return SendError(c, 409, "duda_no_promovible", "No se puede promover la duda", err.Error())
+		}
+		return SendError(c, 400, "pregunta_frecuente_invalida", "No se pudo promover la duda", err.Error())
+	}
+
+	return SendSuccess(c, 201, pregunta)
+}
+
+// UpdatePreguntaFrecuente actualiza la redacción, categoría, orden o publicación de una entrada
+func (h *FAQHandler) UpdatePreguntaFrecuente(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil || id <= 0 {
+		return SendError(c, 400, "id_invalido", "El ID de la pregunta frecuente no es válido", "El ID debe ser un número entero positivo")
+	}
+
+	var req services.PreguntaFrecuenteRequest
+	if err := c.BodyParser(&req); err != nil {
+		return SendError(c, 400, "json_invalido", "No se puede procesar el JSON. Verifique el formato de los datos", err.Error())
+	}
+
+	userID, _ := c.Locals("user_id").(uint)
+	pregunta, err := h.faqService.ConContexto(c.UserContext()).Actualizar(uint(id), userID, req)
+	if err != nil {
+		if errors.Is(err, services.ErrGestionFAQNoPermitida) {
+			return h.sinPermiso(c, err)
+		}
+		if errors.Is(err, services.ErrPreguntaFrecuenteNoEncontrada) {
+			return SendError(c, 404, "pregunta_frecuente_no_encontrada", "No se encontró la pregunta frecuente solicitada", "Verifique que el ID sea correcto")
+		}
+		return SendError(c, 400, "pregunta_frecuente_invalida", "No se pudo actualizar la pregunta frecuente", err.Error())
+	}
+
+	return SendSuccess(c, 200, pregunta)
+}
+
+// DeletePreguntaFrecuente elimina una entrada del FAQ
+func (h *FAQHandler) DeletePreguntaFrecuente(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil || id <= 0 {
+		return SendError(c, 400, "id_invalido", "El ID de la pregunta frecuente no es válido", "El ID debe ser un número entero positivo")
+	}
+
+	userID, _ := c.Locals("user_id").(uint)
+	if err := h.faqService.ConContexto(c.UserContext()).Eliminar(uint(id), userID); err != nil {
+		switch {
+		case errors.Is(err, services.ErrGestionFAQNoPermitida):
+			return h.sinPermiso(c, err)
+		case errors.Is(err, services.ErrPreguntaFrecuenteNoEncontrada):
+			return SendError(c, 404, "pregunta_frecuente_no_encontrada", "No se encontró la pregunta frecuente solicitada", "Verifique que el ID sea correcto")
+		}
+		return SendError(c, 500, "error_base_datos", "Error interno del servidor", "No se pudo eliminar la pregunta frecuente")
+	}
+
+	return SendSuccess(c, 200, fiber.Map{
+		"message": "Pregunta frecuente eliminada exitosamente",
+		"id":      id,
+	})
+}
+
+// sinPermiso responde 403 a quien intenta gestionar el FAQ sin ser personal de la UTEQ
+func (h *FAQHandler) sinPermiso(c *fiber.Ctx, err error) error {
+	return SendError(c, 403, "gestion_no_permitida", "No tiene permiso para gestionar el FAQ", err.Error())
+}
@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"ApiEscuela/services"
+	"errors"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type HuerfanosHandler struct {
+	huerfanosService *services.HuerfanosService
+}
+
+func NewHuerfanosHandler(huerfanosService *services.HuerfanosService) *HuerfanosHandler {
+	return &HuerfanosHandler{huerfanosService: huerfanosService}
+}
+
+// GetReporte lista los archivos sin referencias que se apartarían en la próxima pasada, sin moverlos
+func (h *HuerfanosHandler) GetReporte(c *fiber.Ctx) error {
+	userID, _ := c.Locals("user_id").(uint)
+	reporte, err := h.huerfanosService.ConContexto(c.UserContext()).Reporte(userID)
+	if err != nil {
+		return sendHuerfanosError(c, err, "No se pudo generar el reporte de archivos huérfanos")
+	}
+
+	return SendSuccess(c, 200, reporte)
+}
+
+// GetCuarentena lista los archivos apartados y cuándo se eliminarán
+func (h *HuerfanosHandler) GetCuarentena(c *fiber.Ctx) error {
+	userID, _ := c.Locals("user_id").(uint)
+	cuarentena, err := h.huerfanosService.ConContexto(c.UserContext()).Cuarentena(userID)
+	if err != nil {
+		return sendHuerfanosError(c, err, "No se pudo obtener la cuarentena")
+	}
+
+	return SendSuccess(c, 200, cuarentena)
+}
+
+// RestaurarCuarentena devuelve un archivo apartado a su carpeta
+func (h *HuerfanosHandler) RestaurarCuarentena(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil || id <= 0 {
+		return SendError(c, 400, "id_invalido", "El ID no es válido", "El ID debe ser un número entero positivo")
+	}
+
+	userID, _ := c.Locals("user_id").(uint)
+	restaurado, err := h.huerfanosService.ConContexto(c.UserContext()).Restaurar(uint(id), userID)
+	if err != nil {
+		return sendHuerfanosError(c, err, "No se pudo restaurar el archivo")
+	}
+
+	return SendSuccess(c, 200, restaurado)
+}
+
+func sendHuerfanosError(c *fiber.Ctx, err error, mensaje string) error {
+	switch {
+	case errors.Is(err, services.ErrGestionHuerfanosDenegada):
+		return SendError(c, 403, "acceso_denegado", mensaje, err.Error())
+	case errors.Is(err, services.ErrCuarentenaNoEncontrada):
+		return SendError(c, 404, "cuarentena_no_encontrada", "No se encontró el archivo en cuarentena", "Verifique que el ID sea correcto")
+	}
+	return SendError(c, 500, "error_interno", mensaje, err.Error())
+}
@@ -26,7 +26,7 @@ func (h *InstitucionHandler) CreateInstitucion(c *fiber.Ctx) error {
 		})
 	}
 
-	if err := h.institucionRepo.CreateInstitucion(&institucion); err != nil {
+	if err := h.institucionRepo.ConContexto(c.UserContext()).CreateInstitucion(&institucion); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "No se puede crear la institución",
 		})
@@ -44,7 +44,7 @@ func (h *InstitucionHandler) GetInstitucion(c *fiber.Ctx) error {
 		})
 	}
 
-	institucion, err := h.institucionRepo.GetInstitucionByID(uint(id))
+	institucion, err := h.institucionRepo.ConContexto(c.UserContext()).GetInstitucionByID(uint(id))
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 			"error": "Institución no encontrada",
@@ -56,7 +56,7 @@ func (h *InstitucionHandler) GetInstitucion(c *fiber.Ctx) error {
 
 // GetAllInstituciones obtiene todas las instituciones
 func (h *InstitucionHandler) GetAllInstituciones(c *fiber.Ctx) error {
-	instituciones, err := h.institucionRepo.GetAllInstituciones()
+	instituciones, err := h.institucionRepo.ConContexto(c.UserContext()).GetAllInstituciones()
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "No se pueden obtener las instituciones",
@@ -75,7 +75,7 @@ func (h *InstitucionHandler) UpdateInstitucion(c *fiber.Ctx) error {
 		})
 	}
 
-	institucion, err := h.institucionRepo.GetInstitucionByID(uint(id))
+	institucion, err := h.institucionRepo.ConContexto(c.UserContext()).GetInstitucionByID(uint(id))
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 			"error": "Institución no encontrada",
@@ -88,7 +88,7 @@ func (h *InstitucionHandler) UpdateInstitucion(c *fiber.Ctx) error {
 		})
 	}
 
-	if err := h.institucionRepo.UpdateInstitucion(institucion); err != nil {
+	if err := h.institucionRepo.ConContexto(c.UserContext()).UpdateInstitucion(institucion); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "No se puede actualizar la institución",
 		})
@@ -106,7 +106,7 @@ func (h *InstitucionHandler) DeleteInstitucion(c *fiber.Ctx) error {
 		})
 	}
 
-	if err := h.institucionRepo.DeleteInstitucion(uint(id)); err != nil {
+	if err := h.institucionRepo.ConContexto(c.UserContext()).DeleteInstitucion(uint(id)); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "No se puede eliminar la institución",
 		})
@@ -121,7 +121,7 @@ func (h *InstitucionHandler) DeleteInstitucion(c *fiber.Ctx) error {
 func (h *InstitucionHandler) GetInstitucionesByNombre(c *fiber.Ctx) error {
 	nombre := c.Params("nombre")
 	
-	instituciones, err := h.institucionRepo.GetInstitucionesByNombre(nombre)
+	instituciones, err := h.institucionRepo.ConContexto(c.UserContext()).GetInstitucionesByNombre(nombre)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "No se pueden obtener las instituciones",
@@ -135,7 +135,7 @@ func (h *InstitucionHandler) GetInstitucionesByNombre(c *fiber.Ctx) error {
 func (h *InstitucionHandler) GetInstitucionesByAutoridad(c *fiber.Ctx) error {
 	autoridad := c.Params("autoridad")
 	
-	instituciones, err := h.institucionRepo.GetInstitucionesByAutoridad(autoridad)
+	instituciones, err := h.institucionRepo.ConContexto(c.UserContext()).GetInstitucionesByAutoridad(autoridad)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "No se pueden obtener las instituciones",
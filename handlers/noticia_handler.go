@@ -3,95 +3,119 @@ package handlers
 import (
 	"ApiEscuela/models"
 	"ApiEscuela/repositories"
+	"ApiEscuela/services"
+	"errors"
+	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 )
 
+// cacheNoticiasPublicas permite reutilizar las respuestas públicas durante 5 minutos
+const cacheNoticiasPublicas = "public, max-age=300"
+
 type NoticiaHandler struct {
-	noticiaRepo *repositories.NoticiaRepository
+	noticiaRepo    *repositories.NoticiaRepository
+	noticiaService *services.NoticiaService
 }
 
-func NewNoticiaHandler(noticiaRepo *repositories.NoticiaRepository) *NoticiaHandler {
-	return &NoticiaHandler{noticiaRepo: noticiaRepo}
+func NewNoticiaHandler(noticiaRepo *repositories.NoticiaRepository, noticiaService *services.NoticiaService) *NoticiaHandler {
+	return &NoticiaHandler{noticiaRepo: noticiaRepo, noticiaService: noticiaService}
 }
 
-// CreateNoticia crea una nueva noticia
+// CreateNoticia crea una nueva noticia como borrador del usuario autenticado
 func (h *NoticiaHandler) CreateNoticia(c *fiber.Ctx) error {
-	var noticia models.Noticia
+	var req services.NoticiaRequest
 
-	if err := c.BodyParser(&noticia); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(conTraza(c, fiber.Map{
 			"error": "No se puede procesar el JSON",
-		})
+		}))
 	}
 
-	if err := h.noticiaRepo.CreateNoticia(&noticia); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "No se puede crear la noticia",
-		})
+	userID, _ := c.Locals("user_id").(uint)
+	noticia, err := h.noticiaService.ConContexto(c.UserContext()).Crear(userID, req)
+	if err != nil {
+		return h.sendNoticiaError(c, err, "No se puede crear la noticia")
 	}
 
 	return c.Status(fiber.StatusCreated).JSON(noticia)
 }
 
-// GetNoticia obtiene una noticia por ID
+// GetNoticia obtiene una noticia por ID; las no publicadas solo las ven su autor y los editores
 func (h *NoticiaHandler) GetNoticia(c *fiber.Ctx) error {
 	id, err := strconv.Atoi(c.Params("id"))
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+		return c.Status(fiber.StatusBadRequest).JSON(conTraza(c, fiber.Map{
 			"error": "ID de noticia inválido",
-		})
+		}))
+	}
+
+	userID, _ := c.Locals("user_id").(uint)
+	noticia, err := h.noticiaService.ConContexto(c.UserContext()).Obtener(uint(id), userID)
+	if err != nil {
+		return h.sendNoticiaError(c, err, "No se puede obtener la noticia")
 	}
 
-	noticia, err := h.noticiaRepo.GetNoticiaByID(uint(id))
+	return c.JSON(noticia)
+}
+
+// GetNoticiaBySlug obtiene una noticia por su slug
+func (h *NoticiaHandler) GetNoticiaBySlug(c *fiber.Ctx) error {
+	userID, _ := c.Locals("user_id").(uint)
+	noticia, err := h.noticiaService.ConContexto(c.UserContext()).ObtenerPorSlug(c.Params("slug"), userID)
 	if err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"error": "Noticia no encontrada",
-		})
+		return h.sendNoticiaError(c, err, "No se puede obtener la noticia")
 	}
 
 	return c.JSON(noticia)
 }
 
-// GetAllNoticias obtiene todas las noticias
+// GetAllNoticias obtiene todas las noticias publicadas (?categoria=slug&etiqueta=slug)
 func (h *NoticiaHandler) GetAllNoticias(c *fiber.Ctx) error {
-	noticias, err := h.noticiaRepo.GetAllNoticias()
+	noticias, err := h.noticiaRepo.ConContexto(c.UserContext()).GetAllNoticias(filtroNoticias(c))
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		return c.Status(fiber.StatusInternalServerError).JSON(conTraza(c, fiber.Map{
 			"error": "No se pueden obtener las noticias",
-		})
+		}))
 	}
 
 	return c.JSON(noticias)
 }
 
-// UpdateNoticia actualiza una noticia
-func (h *NoticiaHandler) UpdateNoticia(c *fiber.Ctx) error {
-	id, err := strconv.Atoi(c.Params("id"))
+// GetNoticiasEditorial lista noticias en cualquier estado: todas para los editores, las propias para el resto
+func (h *NoticiaHandler) GetNoticiasEditorial(c *fiber.Ctx) error {
+	userID, _ := c.Locals("user_id").(uint)
+	noticias, err := h.noticiaService.ConContexto(c.UserContext()).ListarEditorial(userID, c.Query("estado"), filtroNoticias(c))
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "ID de noticia inválido",
-		})
+		return h.sendNoticiaError(c, err, "No se pueden obtener las noticias")
 	}
 
-	noticia, err := h.noticiaRepo.GetNoticiaByID(uint(id))
+	return c.JSON(noticias)
+}
+
+// UpdateNoticia actualiza el contenido de una noticia y guarda una nueva versión
+func (h *NoticiaHandler) UpdateNoticia(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
 	if err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"error": "Noticia no encontrada",
-		})
+		return c.Status(fiber.StatusBadRequest).JSON(conTraza(c, fiber.Map{
+			"error": "ID de noticia inválido",
+		}))
 	}
 
-	if err := c.BodyParser(noticia); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+	var req services.NoticiaRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(conTraza(c, fiber.Map{
 			"error": "No se puede procesar el JSON",
-		})
+		}))
 	}
 
-	if err := h.noticiaRepo.UpdateNoticia(noticia); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "No se puede actualizar la noticia",
-		})
+	userID, _ := c.Locals("user_id").(uint)
+	noticia, err := h.noticiaService.ConContexto(c.UserContext()).Actualizar(uint(id), userID, req)
+	if err != nil {
+		return h.sendNoticiaError(c, err, "No se puede actualizar la noticia")
 	}
 
 	return c.JSON(noticia)
@@ -101,15 +125,14 @@ func (h *NoticiaHandler) UpdateNoticia(c *fiber.Ctx) error {
 func (h *NoticiaHandler) DeleteNoticia(c *fiber.Ctx) error {
 	id, err := strconv.Atoi(c.Params("id"))
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+		return c.Status(fiber.StatusBadRequest).JSON(conTraza(c, fiber.Map{
 			"error": "ID de noticia inválido",
-		})
+		}))
 	}
 
-	if err := h.noticiaRepo.DeleteNoticia(uint(id)); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "No se puede eliminar la noticia",
-		})
+	userID, _ := c.Locals("user_id").(uint)
+	if err := h.noticiaService.ConContexto(c.UserContext()).Eliminar(uint(id), userID); err != nil {
+		return h.sendNoticiaError(c, err, "No se puede eliminar la noticia")
 	}
 
 	return c.JSON(fiber.Map{
@@ -117,20 +140,127 @@ func (h *NoticiaHandler) DeleteNoticia(c *fiber.Ctx) error {
 	})
 }
 
+// EnviarARevision pasa un borrador a revisión
+func (h *NoticiaHandler) EnviarARevision(c *fiber.Ctx) error {
+	return h.transicion(c, "No se puede enviar la noticia a revisión", func(id, userID uint) (*models.Noticia, error) {
+		return h.noticiaService.ConContexto(c.UserContext()).EnviarARevision(id, userID)
+	})
+}
+
+// RechazarNoticia devuelve la noticia a borrador con un comentario para el autor
+func (h *NoticiaHandler) RechazarNoticia(c *fiber.Ctx) error {
+	var req struct {
+		Comentario string `json:"comentario"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(conTraza(c, fiber.Map{
+			"error": "No se puede procesar el JSON",
+		}))
+	}
+	return h.transicion(c, "No se puede rechazar la noticia", func(id, userID uint) (*models.Noticia, error) {
+		return h.noticiaService.ConContexto(c.UserContext()).Rechazar(id, userID, req.Comentario)
+	})
+}
+
+// PublicarNoticia publica la noticia de inmediato o la programa
+func (h *NoticiaHandler) PublicarNoticia(c *fiber.Ctx) error {
+	var req services.PublicacionRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(conTraza(c, fiber.Map{
+				"error": "No se puede procesar el JSON",
+			}))
+		}
+	}
+	return h.transicion(c, "No se puede publicar la noticia", func(id, userID uint) (*models.Noticia, error) {
+		return h.noticiaService.ConContexto(c.UserContext()).Publicar(id, userID, req)
+	})
+}
+
+// ArchivarNoticia retira una noticia publicada o programada
+func (h *NoticiaHandler) ArchivarNoticia(c *fiber.Ctx) error {
+	return h.transicion(c, "No se puede archivar la noticia", func(id, userID uint) (*models.Noticia, error) {
+		return h.noticiaService.ConContexto(c.UserContext()).Archivar(id, userID)
+	})
+}
+
+// GetRevisiones lista las versiones de una noticia
+func (h *NoticiaHandler) GetRevisiones(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(conTraza(c, fiber.Map{
+			"error": "ID de noticia inválido",
+		}))
+	}
+
+	userID, _ := c.Locals("user_id").(uint)
+	revisiones, err := h.noticiaService.ConContexto(c.UserContext()).Revisiones(uint(id), userID)
+	if err != nil {
+		return h.sendNoticiaError(c, err, "No se pueden obtener las revisiones")
+	}
+
+	return c.JSON(revisiones)
+}
+
+// GetDiffRevision compara una versión con otra (?contra=N) o con la versión actual
+func (h *NoticiaHandler) GetDiffRevision(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(conTraza(c, fiber.Map{
+			"error": "ID de noticia inválido",
+		}))
+	}
+	version, err := strconv.Atoi(c.Params("version"))
+	if err != nil || version <= 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(conTraza(c, fiber.Map{
+			"error": "Versión inválida",
+		}))
+	}
+	contra := 0
+	if valor := c.Query("contra"); valor != "" {
+		if contra, err = strconv.Atoi(valor); err != nil || contra <= 0 {
+			return c.Status(fiber.StatusBadRequest).JSON(conTraza(c, fiber.Map{
+				"error": "El parámetro contra debe ser una versión válida",
+			}))
+		}
+	}
+
+	userID, _ := c.Locals("user_id").(uint)
+	diff, err := h.noticiaService.ConContexto(c.UserContext()).Diff(uint(id), userID, version, contra)
+	if err != nil {
+		return h.sendNoticiaError(c, err, "No se puede comparar la revisión")
+	}
+
+	return c.JSON(diff)
+}
+
+// RestaurarRevision vuelve al contenido de una versión anterior
+func (h *NoticiaHandler) RestaurarRevision(c *fiber.Ctx) error {
+	version, err := strconv.Atoi(c.Params("version"))
+	if err != nil || version <= 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(conTraza(c, fiber.Map{
+			"error": "Versión inválida",
+		}))
+	}
+	return h.transicion(c, "No se puede restaurar la revisión", func(id, userID uint) (*models.Noticia, error) {
+		return h.noticiaService.ConContexto(c.UserContext()).Restaurar(id, userID, version)
+	})
+}
+
 // GetNoticiasByUsuario obtiene noticias por usuario
 func (h *NoticiaHandler) GetNoticiasByUsuario(c *fiber.Ctx) error {
 	usuarioID, err := strconv.Atoi(c.Params("usuario_id"))
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+		return c.Status(fiber.StatusBadRequest).JSON(conTraza(c, fiber.Map{
 			"error": "ID de usuario inválido",
-		})
+		}))
 	}
 
-	noticias, err := h.noticiaRepo.GetNoticiasByUsuario(uint(usuarioID))
+	noticias, err := h.noticiaRepo.ConContexto(c.UserContext()).GetNoticiasByUsuario(uint(usuarioID))
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		return c.Status(fiber.StatusInternalServerError).JSON(conTraza(c, fiber.Map{
 			"error": "No se pueden obtener las noticias",
-		})
+		}))
 	}
 
 	return c.JSON(noticias)
@@ -140,11 +270,11 @@ func (h *NoticiaHandler) GetNoticiasByUsuario(c *fiber.Ctx) error {
 func (h *NoticiaHandler) GetNoticiasByTitulo(c *fiber.Ctx) error {
 	titulo := c.Params("titulo")
 
-	noticias, err := h.noticiaRepo.GetNoticiasByTitulo(titulo)
+	noticias, err := h.noticiaRepo.ConContexto(c.UserContext()).GetNoticiasByTitulo(titulo)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		return c.Status(fiber.StatusInternalServerError).JSON(conTraza(c, fiber.Map{
 			"error": "No se pueden obtener las noticias",
-		})
+		}))
 	}
 
 	return c.JSON(noticias)
@@ -154,11 +284,11 @@ func (h *NoticiaHandler) GetNoticiasByTitulo(c *fiber.Ctx) error {
 func (h *NoticiaHandler) GetNoticiasByDescripcion(c *fiber.Ctx) error {
 	descripcion := c.Params("descripcion")
 
-	noticias, err := h.noticiaRepo.GetNoticiasByDescripcion(descripcion)
+	noticias, err := h.noticiaRepo.ConContexto(c.UserContext()).GetNoticiasByDescripcion(descripcion)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		return c.Status(fiber.StatusInternalServerError).JSON(conTraza(c, fiber.Map{
 			"error": "No se pueden obtener las noticias",
-		})
+		}))
 	}
 
 	return c.JSON(noticias)
@@ -168,12 +298,230 @@ func (h *NoticiaHandler) GetNoticiasByDescripcion(c *fiber.Ctx) error {
 func (h *NoticiaHandler) SearchNoticias(c *fiber.Ctx) error {
 	termino := c.Params("termino")
 
-	noticias, err := h.noticiaRepo.SearchNoticias(termino)
+	noticias, err := h.noticiaRepo.ConContexto(c.UserContext()).SearchNoticias(termino)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		return c.Status(fiber.StatusInternalServerError).JSON(conTraza(c, fiber.Map{
 			"error": "No se pueden buscar las noticias",
-		})
+		}))
 	}
 
 	return c.JSON(noticias)
 }
+
+// GetCategorias lista las categorías de noticias
+func (h *NoticiaHandler) GetCategorias(c *fiber.Ctx) error {
+	categorias, err := h.noticiaRepo.ConContexto(c.UserContext()).GetCategorias()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(conTraza(c, fiber.Map{
+			"error": "No se pueden obtener las categorías",
+		}))
+	}
+
+	return c.JSON(categorias)
+}
+
+// CreateCategoria crea una categoría de noticias
+func (h *NoticiaHandler) CreateCategoria(c *fiber.Ctx) error {
+	var req services.CategoriaNoticiaRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(conTraza(c, fiber.Map{
+			"error": "No se puede procesar el JSON",
+		}))
+	}
+
+	userID, _ := c.Locals("user_id").(uint)
+	categoria, err := h.noticiaService.ConContexto(c.UserContext()).CrearCategoria(userID, req)
+	if err != nil {
+		return h.sendNoticiaError(c, err, "No se puede crear la categoría")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(categoria)
+}
+
+// UpdateCategoria actualiza una categoría de noticias
+func (h *NoticiaHandler) UpdateCategoria(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(conTraza(c, fiber.Map{
+			"error": "ID de categoría inválido",
+		}))
+	}
+
+	var req services.CategoriaNoticiaRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(conTraza(c, fiber.Map{
+			"error": "No se puede procesar el JSON",
+		}))
+	}
+
+	userID, _ := c.Locals("user_id").(uint)
+	categoria, err := h.noticiaService.ConContexto(c.UserContext()).ActualizarCategoria(uint(id), userID, req)
+	if err != nil {
+		return h.sendNoticiaError(c, err, "No se puede actualizar la categoría")
+	}
+
+	return c.JSON(categoria)
+}
+
+// DeleteCategoria elimina una categoría de noticias
+func (h *NoticiaHandler) DeleteCategoria(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(conTraza(c, fiber.Map{
+			"error": "ID de categoría inválido",
+		}))
+	}
+
+	userID, _ := c.Locals("user_id").(uint)
+	if err := h.noticiaService.ConContexto(c.UserContext()).EliminarCategoria(uint(id), userID); err != nil {
+		return h.sendNoticiaError(c, err, "No se puede eliminar la categoría")
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Categoría eliminada exitosamente",
+	})
+}
+
+// GetEtiquetas lista las etiquetas usadas por noticias publicadas
+func (h *NoticiaHandler) GetEtiquetas(c *fiber.Ctx) error {
+	etiquetas, err := h.noticiaRepo.ConContexto(c.UserContext()).GetEtiquetas()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(conTraza(c, fiber.Map{
+			"error": "No se pueden obtener las etiquetas",
+		}))
+	}
+
+	return c.JSON(etiquetas)
+}
+
+// GetNoticiasPublicas lista las noticias publicadas sin autenticación (?pagina=1&por_pagina=20&categoria=&etiqueta=)
+func (h *NoticiaHandler) GetNoticiasPublicas(c *fiber.Ctx) error {
+	ultima, err := h.noticiaService.ConContexto(c.UserContext()).UltimaModificacion()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(conTraza(c, fiber.Map{
+			"error": "No se pueden obtener las noticias",
+		}))
+	}
+	if noModificado(c, ultima) {
+		return c.SendStatus(fiber.StatusNotModified)
+	}
+
+	pagina, err := h.noticiaService.ConContexto(c.UserContext()).PaginaPublica(filtroNoticias(c), c.QueryInt("pagina", 1), c.QueryInt("por_pagina", 0), c.BaseURL())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(conTraza(c, fiber.Map{
+			"error": "No se pueden obtener las noticias",
+		}))
+	}
+
+	return c.JSON(pagina)
+}
+
+// GetNoticiaPublica obtiene una noticia publicada por su slug sin autenticación
+func (h *NoticiaHandler) GetNoticiaPublica(c *fiber.Ctx) error {
+	noticia, err := h.noticiaService.ConContexto(c.UserContext()).NoticiaPublicaPorSlug(c.Params("slug"), c.BaseURL())
+	if err != nil {
+		return h.sendNoticiaError(c, err, "No se puede obtener la noticia")
+	}
+	if noModificado(c, &noticia.ActualizadaEn) {
+		return c.SendStatus(fiber.StatusNotModified)
+	}
+
+	return c.JSON(noticia)
+}
+
+// GetFeedRSS genera el feed RSS 2.0 de las noticias publicadas
+func (h *NoticiaHandler) GetFeedRSS(c *fiber.Ctx) error {
+	return h.feed(c, "application/rss+xml; charset=utf-8", h.noticiaService.ConContexto(c.UserContext()).FeedRSS)
+}
+
+// GetFeedAtom genera el feed Atom de las noticias publicadas
+func (h *NoticiaHandler) GetFeedAtom(c *fiber.Ctx) error {
+	return h.feed(c, "application/atom+xml; charset=utf-8", h.noticiaService.ConContexto(c.UserContext()).FeedAtom)
+}
+
+type generadorFeed func(filtro repositories.FiltroNoticias, pagina, porPagina int, urlBase string, feed services.FeedNoticias) ([]byte, error)
+
+func (h *NoticiaHandler) feed(c *fiber.Ctx, tipo string, generar generadorFeed) error {
+	ultima, err := h.noticiaService.ConContexto(c.UserContext()).UltimaModificacion()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(conTraza(c, fiber.Map{
+			"error": "No se puede generar el feed",
+		}))
+	}
+	if noModificado(c, ultima) {
+		return c.SendStatus(fiber.StatusNotModified)
+	}
+
+	contenido, err := generar(filtroNoticias(c), c.QueryInt("pagina", 1), c.QueryInt("por_pagina", 0), c.BaseURL(),
+		services.FeedNoticias{Propio: c.BaseURL() + c.OriginalURL()})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(conTraza(c, fiber.Map{
+			"error": "No se puede generar el feed",
+		}))
+	}
+
+	c.Set(fiber.HeaderContentType, tipo)
+	return c.Send(contenido)
+}
+
+// noModificado responde Cache-Control y Last-Modified, e indica si la copia del cliente sigue vigente según
+// If-Modified-Since. If-None-Match tiene prioridad y lo resuelve el middleware etag.
+func noModificado(c *fiber.Ctx, ultima *time.Time) bool {
+	c.Set(fiber.HeaderCacheControl, cacheNoticiasPublicas)
+	if ultima == nil {
+		return false
+	}
+	c.Set(fiber.HeaderLastModified, ultima.UTC().Format(http.TimeFormat))
+	if c.Get(fiber.HeaderIfNoneMatch) != "" {
+		return false
+	}
+	desde, err := http.ParseTime(c.Get(fiber.HeaderIfModifiedSince))
+	if err != nil {
+		return false
+	}
+	return !ultima.Truncate(time.Second).After(desde)
+}
+
+// filtroNoticias lee los filtros ?categoria= y ?etiqueta= (slugs) de los listados
+func filtroNoticias(c *fiber.Ctx) repositories.FiltroNoticias {
+	return repositories.FiltroNoticias{
+		Categoria: strings.TrimSpace(c.Query("categoria")),
+		Etiqueta:  strings.TrimSpace(c.Query("etiqueta")),
+	}
+}
+
+// transicion ejecuta una acción del flujo editorial sobre la noticia de la ruta
+func (h *NoticiaHandler) transicion(c *fiber.Ctx, message string, accion func(id, userID uint) (*models.Noticia, error)) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(conTraza(c, fiber.Map{
+			"error": "ID de noticia inválido",
+		}))
+	}
+
+	userID, _ := c.Locals("user_id").(uint)
+	noticia, err := accion(uint(id), userID)
+	if err != nil {
+		return h.sendNoticiaError(c, err, message)
+	}
+
+	return c.JSON(noticia)
+}
+
+func (h *NoticiaHandler) sendNoticiaError(c *fiber.Ctx, err error, message string) error {
+	status := fiber.StatusBadRequest
+	switch {
+	case errors.Is(err, services.ErrNoticiaNoEncontrada), errors.Is(err, services.ErrRevisionNoEncontrada),
+		errors.Is(err, services.ErrCategoriaNoEncontrada):
+		status = fiber.StatusNotFound
+	case errors.Is(err, services.ErrPublicacionNoPermitida), errors.Is(err, services.ErrEdicionNoticiaNoPermitida):
+		status = fiber.StatusForbidden
+	case errors.Is(err, services.ErrTransicionNoticiaInvalida), errors.Is(err, services.ErrSlugNoticiaEnUso),
+		errors.Is(err, services.ErrCategoriaDuplicada), errors.Is(err, services.ErrNoticiaModificada):
+		status = fiber.StatusConflict
+	}
+	return c.Status(status).JSON(conTraza(c, fiber.Map{
+		"error":   message,
+		"details": err.Error(),
+	}))
+}
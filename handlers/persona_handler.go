@@ -45,19 +45,19 @@ func (h *PersonaHandler) CreatePersona(c *fiber.Ctx) error {
 	persona.Telefono = strings.TrimSpace(persona.Telefono)
 
 	// Verificar si ya existe una persona con la misma cédula
-	if existingPersona, err := h.personaRepo.GetPersonaByCedula(persona.Cedula); err == nil && existingPersona != nil {
+	if existingPersona, err := h.personaRepo.ConContexto(c.UserContext()).GetPersonaByCedula(persona.Cedula); err == nil && existingPersona != nil {
 		return SendError(c, 409, "duplicate_cedula", "Ya existe una persona con esta cédula", "La cédula debe ser única")
 	}
 
 	// Verificar si ya existe una persona con el mismo correo (si se proporciona)
 	if persona.Correo != "" {
-		if existingPersonas, err := h.personaRepo.GetPersonasByCorreo(persona.Correo); err == nil && len(existingPersonas) > 0 {
+		if existingPersonas, err := h.personaRepo.ConContexto(c.UserContext()).GetPersonasByCorreo(persona.Correo); err == nil && len(existingPersonas) > 0 {
 			return SendError(c, 409, "duplicate_email", "Ya existe una persona con este correo electrónico", "El correo debe ser único")
 		}
 	}
 
 	// Crear persona
-	if err := h.personaRepo.CreatePersona(&persona); err != nil {
+	if err := h.personaRepo.ConContexto(c.UserContext()).CreatePersona(&persona); err != nil {
 		// Manejar errores específicos del repositorio
 		switch err.Error() {
 		case "cedula repetida":
@@ -86,7 +86,7 @@ func (h *PersonaHandler) GetPersona(c *fiber.Ctx) error {
 		return SendError(c, 400, "invalid_id", "El ID de la persona no es válido", "El ID debe ser un número entero positivo")
 	}
 
-	persona, err := h.personaRepo.GetPersonaByID(uint(id))
+	persona, err := h.personaRepo.ConContexto(c.UserContext()).GetPersonaByID(uint(id))
 	if err != nil {
 		return SendError(c, 404, "person_not_found", "No se encontró la persona solicitada", "Verifique que el ID sea correcto")
 	}
@@ -96,7 +96,7 @@ func (h *PersonaHandler) GetPersona(c *fiber.Ctx) error {
 
 // GetAllPersonas obtiene todas las personas
 func (h *PersonaHandler) GetAllPersonas(c *fiber.Ctx) error {
-	personas, err := h.personaRepo.GetAllPersonas()
+	personas, err := h.personaRepo.ConContexto(c.UserContext()).GetAllPersonas()
 	if err != nil {
 		return SendError(c, 500, "database_error", "Error interno del servidor", "No se pudieron obtener las personas")
 	}
@@ -117,7 +117,7 @@ func (h *PersonaHandler) UpdatePersona(c *fiber.Ctx) error {
 	}
 
 	// Verificar que la persona existe
-	existingPersona, err := h.personaRepo.GetPersonaByID(uint(id))
+	existingPersona, err := h.personaRepo.ConContexto(c.UserContext()).GetPersonaByID(uint(id))
 	if err != nil {
 		return SendError(c, 404, "person_not_found", "No se encontró la persona solicitada", "Verifique que el ID sea correcto")
 	}
@@ -143,14 +143,14 @@ func (h *PersonaHandler) UpdatePersona(c *fiber.Ctx) error {
 
 	// Verificar si la nueva cédula ya existe en otra persona
 	if persona.Cedula != existingPersona.Cedula {
-		if existingPersonaByCedula, _ := h.personaRepo.GetPersonaByCedula(persona.Cedula); existingPersonaByCedula != nil {
+		if existingPersonaByCedula, _ := h.personaRepo.ConContexto(c.UserContext()).GetPersonaByCedula(persona.Cedula); existingPersonaByCedula != nil {
 			return SendError(c, 409, "duplicate_cedula", "Ya existe otra persona con esta cédula", "La cédula debe ser única")
 		}
 	}
 
 	// Verificar si el nuevo correo ya existe en otra persona (si se proporciona)
 	if persona.Correo != "" && persona.Correo != existingPersona.Correo {
-		if existingPersonas, _ := h.personaRepo.GetPersonasByCorreo(persona.Correo); len(existingPersonas) > 0 {
+		if existingPersonas, _ := h.personaRepo.ConContexto(c.UserContext()).GetPersonasByCorreo(persona.Correo); len(existingPersonas) > 0 {
 			// Verificar que no sea la misma persona
 			for _, p := range existingPersonas {
 				if p.ID != persona.ID {
@@ -161,7 +161,7 @@ func (h *PersonaHandler) UpdatePersona(c *fiber.Ctx) error {
 	}
 
 	// Actualizar en base de datos
-	if err := h.personaRepo.UpdatePersona(&persona); err != nil {
+	if err := h.personaRepo.ConContexto(c.UserContext()).UpdatePersona(&persona); err != nil {
 		// Manejar errores específicos del repositorio
 		switch err.Error() {
 		case "cedula repetida":
@@ -191,7 +191,7 @@ func (h *PersonaHandler) DeletePersona(c *fiber.Ctx) error {
 	}
 
 	// Verificar que la persona existe antes de eliminar
-	persona, err := h.personaRepo.GetPersonaByID(uint(id))
+	persona, err := h.personaRepo.ConContexto(c.UserContext()).GetPersonaByID(uint(id))
 	if err != nil {
 		return SendError(c, 404, "person_not_found", "No se encontró la persona solicitada", "Verifique que el ID sea correcto")
 	}
@@ -201,7 +201,7 @@ func (h *PersonaHandler) DeletePersona(c *fiber.Ctx) error {
 		return SendError(c, 409, "person_in_use", "No se puede eliminar la persona porque está siendo utilizada", "La persona tiene relaciones activas que impiden su eliminación")
 	}
 
-	if err := h.personaRepo.DeletePersona(uint(id)); err != nil {
+	if err := h.personaRepo.ConContexto(c.UserContext()).DeletePersona(uint(id)); err != nil {
 		return SendError(c, 500, "database_error", "Error interno del servidor", "No se pudo eliminar la persona")
 	}
 
@@ -224,7 +224,7 @@ func (h *PersonaHandler) GetPersonaByCedula(c *fiber.Ctx) error {
 		return SendError(c, 400, "invalid_cedula", "El formato de la cédula no es válido", "La cédula debe tener entre 7 y 10 dígitos numéricos")
 	}
 
-	persona, err := h.personaRepo.GetPersonaByCedula(strings.TrimSpace(cedula))
+	persona, err := h.personaRepo.ConContexto(c.UserContext()).GetPersonaByCedula(strings.TrimSpace(cedula))
 	if err != nil {
 		return SendError(c, 404, "person_not_found", "No se encontró la persona solicitada", "Verifique que la cédula sea correcta")
 	}
@@ -245,7 +245,7 @@ func (h *PersonaHandler) GetPersonasByCorreo(c *fiber.Ctx) error {
 		return SendError(c, 400, "invalid_email", "El formato del correo electrónico no es válido", "Proporcione un correo con formato válido")
 	}
 
-	personas, err := h.personaRepo.GetPersonasByCorreo(strings.TrimSpace(correo))
+	personas, err := h.personaRepo.ConContexto(c.UserContext()).GetPersonasByCorreo(strings.TrimSpace(correo))
 	if err != nil {
 		return SendError(c, 500, "database_error", "Error interno del servidor", "No se pudieron obtener las personas")
 	}
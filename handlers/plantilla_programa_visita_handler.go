@@ -0,0 +1,222 @@
+package handlers
+
+import (
+	"ApiEscuela/repositories"
+	"ApiEscuela/services"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type PlantillaProgramaVisitaHandler struct {
+	plantillaRepo      *repositories.PlantillaProgramaVisitaRepository
+	recurrenciaService *services.RecurrenciaService
+}
+
+func NewPlantillaProgramaVisitaHandler(plantillaRepo *repositories.PlantillaProgramaVisitaRepository, recurrenciaService *services.RecurrenciaService) *PlantillaProgramaVisitaHandler {
+	return &PlantillaProgramaVisitaHandler{plantillaRepo: plantillaRepo, recurrenciaService: recurrenciaService}
+}
+
+// CreatePlantilla crea una plantilla de visitas recurrentes
+func (h *PlantillaProgramaVisitaHandler) CreatePlantilla(c *fiber.Ctx) error {
+	var req services.PlantillaRequest
+	if err := c.BodyParser(&req); err != nil {
+		return SendError(c, 400, "json_invalido", "No se puede procesar el JSON. Verifique el formato de los datos", err.Error())
+	}
+
+	plantilla, err := h.recurrenciaService.ConContexto(c.UserContext()).CrearPlantilla(req)
+	if err != nil {
+		return SendError(c, 400, "plantilla_invalida", "No se pudo crear la plantilla", err.Error())
+	}
+
+	return SendSuccess(c, 201, plantilla)
+}
+
+// GetPlantilla obtiene una plantilla por ID
+func (h *PlantillaProgramaVisitaHandler) GetPlantilla(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil || id <= 0 {
+		return SendError(c, 400, "id_invalido", "El ID de la plantilla no es válido", "El ID debe ser un número entero positivo")
+	}
+
+	plantilla, err := h.recurrenciaService.ConContexto(c.UserContext()).ObtenerPlantilla(uint(id))
+	if err != nil {
+		return SendError(c, 404, "plantilla_no_encontrada", "No se encontró la plantilla solicitada", "Verifique que el ID sea correcto")
+	}
+
+	return SendSuccess(c, 200, plantilla)
+}
+
+// GetAllPlantillas obtiene todas las plantillas
+func (h *PlantillaProgramaVisitaHandler) GetAllPlantillas(c *fiber.Ctx) error {
+	plantillas, err := h.plantillaRepo.ConContexto(c.UserContext()).GetAllPlantillas()
+	if err != nil {
+		return SendError(c, 500, "error_base_datos", "Error interno del servidor", "No se pudieron obtener las plantillas")
+	}
+
+	return SendSuccess(c, 200, plantillas)
+}
+
+// GetPlantillasByInstitucion obtiene las plantillas de una institución
+func (h *PlantillaProgramaVisitaHandler) GetPlantillasByInstitucion(c *fiber.Ctx) error {
+	institucionID, err := strconv.Atoi(c.Params("institucion_id"))
+	if err != nil || institucionID <= 0 {
+		return SendError(c, 400, "institucion_id_invalido", "El ID de la institución no es válido", "El ID debe ser un número entero positivo")
+	}
+
+	plantillas, err := h.plantillaRepo.ConContexto(c.UserContext()).GetPlantillasByInstitucion(uint(institucionID))
+	if err != nil {
+		return SendError(c, 500, "error_base_datos", "Error interno del servidor", "No se pudieron obtener las plantillas")
+	}
+
+	return SendSuccess(c, 200, plantillas)
+}
+
+// DeletePlantilla elimina una plantilla y sus ocurrencias futuras
+func (h *PlantillaProgramaVisitaHandler) DeletePlantilla(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil || id <= 0 {
+		return SendError(c, 400, "id_invalido", "El ID de la plantilla no es válido", "El ID debe ser un número entero positivo")
+	}
+
+	if err := h.recurrenciaService.ConContexto(c.UserContext()).EliminarPlantilla(c.UserContext(), uint(id)); err != nil {
+		return h.sendRecurrenciaError(c, err, "No se pudo eliminar la plantilla")
+	}
+
+	return SendSuccess(c, 200, fiber.Map{
+		"message": "Plantilla eliminada exitosamente",
+		"id":      id,
+	})
+}
+
+// GetOcurrencias calcula las ocurrencias de la serie sin guardarlas (?hasta=YYYY-MM-DD)
+func (h *PlantillaProgramaVisitaHandler) GetOcurrencias(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil || id <= 0 {
+		return SendError(c, 400, "id_invalido", "El ID de la plantilla no es válido", "El ID debe ser un número entero positivo")
+	}
+
+	hasta, err := parseFechaQuery(c.Query("hasta"))
+	if err != nil {
+		return SendError(c, 400, "fecha_invalida", "Formato de fecha inválido", "Use YYYY-MM-DD en el parámetro 'hasta'")
+	}
+
+	fechas, err := h.recurrenciaService.ConContexto(c.UserContext()).PrevisualizarOcurrencias(uint(id), hasta)
+	if err != nil {
+		return h.sendRecurrenciaError(c, err, "No se pudieron calcular las ocurrencias")
+	}
+
+	return SendSuccess(c, 200, fechas)
+}
+
+// GetProgramasPlantilla obtiene los programas de visita ya generados por la plantilla
+func (h *PlantillaProgramaVisitaHandler) GetProgramasPlantilla(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil || id <= 0 {
+		return SendError(c, 400, "id_invalido", "El ID de la plantilla no es válido", "El ID debe ser un número entero positivo")
+	}
+
+	programas, err := h.plantillaRepo.ConContexto(c.UserContext()).GetProgramasByPlantilla(uint(id))
+	if err != nil {
+		return SendError(c, 500, "error_base_datos", "Error interno del servidor", "No se pudieron obtener los programas de visita")
+	}
+
+	return SendSuccess(c, 200, programas)
+}
+
+// MaterializarPlantilla genera los programas de visita pendientes (?hasta=YYYY-MM-DD)
+func (h *PlantillaProgramaVisitaHandler) MaterializarPlantilla(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil || id <= 0 {
+		return SendError(c, 400, "id_invalido", "El ID de la plantilla no es válido", "El ID debe ser un número entero positivo")
+	}
+
+	hasta, err := parseFechaQuery(c.Query("hasta"))
+	if err != nil {
+		return SendError(c, 400, "fecha_invalida", "Formato de fecha inválido", "Use YYYY-MM-DD en el parámetro 'hasta'")
+	}
+
+	programas, err := h.recurrenciaService.ConContexto(c.UserContext()).Materializar(c.UserContext(), uint(id), hasta)
+	if err != nil {
+		return h.sendRecurrenciaError(c, err, "No se pudieron generar los programas de visita")
+	}
+
+	return SendSuccess(c, 201, fiber.Map{
+		"message":   "Programas de visita generados exitosamente",
+		"generados": len(programas),
+		"programas": programas,
+	})
+}
+
+// UpdateOcurrencia edita una ocurrencia (?alcance=esta|futuras|todas)
+func (h *PlantillaProgramaVisitaHandler) UpdateOcurrencia(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil || id <= 0 {
+		return SendError(c, 400, "id_invalido", "El ID de la plantilla no es válido", "El ID debe ser un número entero positivo")
+	}
+	programaID, err := strconv.Atoi(c.Params("programa_id"))
+	if err != nil || programaID <= 0 {
+		return SendError(c, 400, "programa_id_invalido", "El ID del programa de visita no es válido", "El ID debe ser un número entero positivo")
+	}
+
+	var req services.EdicionOcurrenciaRequest
+	if err := c.BodyParser(&req); err != nil {
+		return SendError(c, 400, "json_invalido", "No se puede procesar el JSON. Verifique el formato de los datos", err.Error())
+	}
+
+	plantilla, err := h.recurrenciaService.ConContexto(c.UserContext()).EditarOcurrencia(c.UserContext(), uint(id), uint(programaID), c.Query("alcance", services.AlcanceEsta), req)
+	if err != nil {
+		return h.sendRecurrenciaError(c, err, "No se pudo editar la ocurrencia")
+	}
+
+	return SendSuccess(c, 200, plantilla)
+}
+
+// DeleteOcurrencia elimina una ocurrencia (?alcance=esta|futuras)
+func (h *PlantillaProgramaVisitaHandler) DeleteOcurrencia(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil || id <= 0 {
+		return SendError(c, 400, "id_invalido", "El ID de la plantilla no es válido", "El ID debe ser un número entero positivo")
+	}
+	programaID, err := strconv.Atoi(c.Params("programa_id"))
+	if err != nil || programaID <= 0 {
+		return SendError(c, 400, "programa_id_invalido", "El ID del programa de visita no es válido", "El ID debe ser un número entero positivo")
+	}
+
+	if err := h.recurrenciaService.ConContexto(c.UserContext()).EliminarOcurrencia(c.UserContext(), uint(id), uint(programaID), c.Query("alcance", services.AlcanceEsta)); err != nil {
+		return h.sendRecurrenciaError(c, err, "No se pudo eliminar la ocurrencia")
+	}
+
+	return SendSuccess(c, 200, fiber.Map{
+		"message":     "Ocurrencia eliminada exitosamente",
+		"programa_id": programaID,
+	})
+}
+
+// sendRecurrenciaError traduce los errores del servicio de recurrencia a respuestas HTTP
+func (h *PlantillaProgramaVisitaHandler) sendRecurrenciaError(c *fiber.Ctx, err error, message string) error {
+	switch {
+	case errors.Is(err, services.ErrPlantillaNoEncontrada):
+		return SendError(c, 404, "plantilla_no_encontrada", "No se encontró la plantilla solicitada", "Verifique que el ID sea correcto")
+	case errors.Is(err, services.ErrOcurrenciaNoEncontrada):
+		return SendError(c, 404, "ocurrencia_no_encontrada", "No se encontró la ocurrencia en la plantilla", "Verifique que el programa pertenezca a la plantilla")
+	case errors.Is(err, services.ErrAlcanceInvalido):
+		return SendError(c, 400, "alcance_invalido", "El alcance no es válido", "Use 'esta', 'futuras' o 'todas'")
+	default:
+		return SendError(c, 400, "recurrencia_invalida", message, err.Error())
+	}
+}
+
+// parseFechaQuery interpreta un parámetro opcional YYYY-MM-DD
+func parseFechaQuery(valor string) (*time.Time, error) {
+	if valor == "" {
+		return nil, nil
+	}
+	fecha, err := time.Parse("2006-01-02", valor)
+	if err != nil {
+		return nil, err
+	}
+	return &fecha, nil
+}
@@ -3,6 +3,7 @@ package handlers
 import (
 	"ApiEscuela/models"
 	"ApiEscuela/repositories"
+	"ApiEscuela/services"
 	"strconv"
 	"time"
 
@@ -10,11 +11,12 @@ import (
 )
 
 type ProgramaVisitaHandler struct {
-	programaRepo *repositories.ProgramaVisitaRepository
+	programaRepo      *repositories.ProgramaVisitaRepository
+	calendarioService *services.CalendarioService
 }
 
-func NewProgramaVisitaHandler(programaRepo *repositories.ProgramaVisitaRepository) *ProgramaVisitaHandler {
-	return &ProgramaVisitaHandler{programaRepo: programaRepo}
+func NewProgramaVisitaHandler(programaRepo *repositories.ProgramaVisitaRepository, calendarioService *services.CalendarioService) *ProgramaVisitaHandler {
+	return &ProgramaVisitaHandler{programaRepo: programaRepo, calendarioService: calendarioService}
 }
 
 // CreateProgramaVisita crea un nuevo programa de visita
@@ -27,7 +29,7 @@ func (h *ProgramaVisitaHandler) CreateProgramaVisita(c *fiber.Ctx) error {
 		})
 	}
 
-	if err := h.programaRepo.CreateProgramaVisita(&programa); err != nil {
+	if err := h.programaRepo.ConContexto(c.UserContext()).CreateProgramaVisita(&programa); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "No se puede crear el programa de visita",
 		})
@@ -45,7 +47,7 @@ func (h *ProgramaVisitaHandler) GetProgramaVisita(c *fiber.Ctx) error {
 		})
 	}
 
-	programa, err := h.programaRepo.GetProgramaVisitaByID(uint(id))
+	programa, err := h.programaRepo.ConContexto(c.UserContext()).GetProgramaVisitaByID(uint(id))
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 			"error": "Programa de visita no encontrado",
@@ -57,7 +59,7 @@ func (h *ProgramaVisitaHandler) GetProgramaVisita(c *fiber.Ctx) error {
 
 // GetAllProgramasVisita obtiene todos los programas de visita
 func (h *ProgramaVisitaHandler) GetAllProgramasVisita(c *fiber.Ctx) error {
-	programas, err := h.programaRepo.GetAllProgramasVisita()
+	programas, err := h.programaRepo.ConContexto(c.UserContext()).GetAllProgramasVisita()
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "No se pueden obtener los programas de visita",
@@ -76,7 +78,7 @@ func (h *ProgramaVisitaHandler) UpdateProgramaVisita(c *fiber.Ctx) error {
 		})
 	}
 
-	programa, err := h.programaRepo.GetProgramaVisitaByID(uint(id))
+	programa, err := h.programaRepo.ConContexto(c.UserContext()).GetProgramaVisitaByID(uint(id))
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 			"error": "Programa de visita no encontrado",
@@ -89,12 +91,15 @@ func (h *ProgramaVisitaHandler) UpdateProgramaVisita(c *fiber.Ctx) error {
 		})
 	}
 
-	if err := h.programaRepo.UpdateProgramaVisita(programa); err != nil {
+	if err := h.programaRepo.ConContexto(c.UserContext()).UpdateProgramaVisita(programa); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "No se puede actualizar el programa de visita",
 		})
 	}
 
+	// Reenviar el evento actualizado a los asignados
+	h.calendarioService.ConContexto(c.UserContext()).NotificarCambioPrograma(c.UserContext(), programa.ID)
+
 	return c.JSON(programa)
 }
 
@@ -107,12 +112,15 @@ func (h *ProgramaVisitaHandler) DeleteProgramaVisita(c *fiber.Ctx) error {
 		})
 	}
 
-	if err := h.programaRepo.DeleteProgramaVisita(uint(id)); err != nil {
+	if err := h.programaRepo.ConContexto(c.UserContext()).DeleteProgramaVisita(uint(id)); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "No se puede eliminar el programa de visita",
 		})
 	}
 
+	// Notificar la cancelación a los asignados
+	h.calendarioService.ConContexto(c.UserContext()).NotificarCambioPrograma(c.UserContext(), uint(id))
+
 	return c.JSON(fiber.Map{
 		"message": "Programa de visita eliminado exitosamente",
 	})
@@ -128,7 +136,7 @@ func (h *ProgramaVisitaHandler) GetProgramasVisitaByFecha(c *fiber.Ctx) error {
 		})
 	}
 	
-	programas, err := h.programaRepo.GetProgramasVisitaByFecha(fecha)
+	programas, err := h.programaRepo.ConContexto(c.UserContext()).GetProgramasVisitaByFecha(fecha)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "No se pueden obtener los programas de visita",
@@ -148,7 +156,7 @@ func (h *ProgramaVisitaHandler) GetProgramasVisitaByInstitucion(c *fiber.Ctx) er
 		})
 	}
 	
-	programas, err := h.programaRepo.GetProgramasVisitaByInstitucion(uint(institucionID))
+	programas, err := h.programaRepo.ConContexto(c.UserContext()).GetProgramasVisitaByInstitucion(uint(institucionID))
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "No se pueden obtener los programas de visita",
@@ -183,7 +191,7 @@ func (h *ProgramaVisitaHandler) GetProgramasVisitaByRangoFecha(c *fiber.Ctx) err
 		})
 	}
 	
-	programas, err := h.programaRepo.GetProgramasVisitaByRangoFecha(fechaInicio, fechaFin)
+	programas, err := h.programaRepo.ConContexto(c.UserContext()).GetProgramasVisitaByRangoFecha(fechaInicio, fechaFin)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "No se pueden obtener los programas de visita",
@@ -26,7 +26,7 @@ func (h *ProvinciaHandler) CreateProvincia(c *fiber.Ctx) error {
 		})
 	}
 
-	if err := h.provinciaRepo.CreateProvincia(&provincia); err != nil {
+	if err := h.provinciaRepo.ConContexto(c.UserContext()).CreateProvincia(&provincia); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "No se puede crear la provincia",
 		})
@@ -44,7 +44,7 @@ func (h *ProvinciaHandler) GetProvincia(c *fiber.Ctx) error {
 		})
 	}
 
-	provincia, err := h.provinciaRepo.GetProvinciaByID(uint(id))
+	provincia, err := h.provinciaRepo.ConContexto(c.UserContext()).GetProvinciaByID(uint(id))
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 			"error": "Provincia no encontrada",
@@ -56,7 +56,7 @@ func (h *ProvinciaHandler) GetProvincia(c *fiber.Ctx) error {
 
 // GetAllProvincias obtiene todas las provincias
 func (h *ProvinciaHandler) GetAllProvincias(c *fiber.Ctx) error {
-	provincias, err := h.provinciaRepo.GetAllProvincias()
+	provincias, err := h.provinciaRepo.ConContexto(c.UserContext()).GetAllProvincias()
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "No se pueden obtener las provincias",
@@ -75,7 +75,7 @@ func (h *ProvinciaHandler) UpdateProvincia(c *fiber.Ctx) error {
 		})
 	}
 
-	provincia, err := h.provinciaRepo.GetProvinciaByID(uint(id))
+	provincia, err := h.provinciaRepo.ConContexto(c.UserContext()).GetProvinciaByID(uint(id))
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 			"error": "Provincia no encontrada",
@@ -88,7 +88,7 @@ func (h *ProvinciaHandler) UpdateProvincia(c *fiber.Ctx) error {
 		})
 	}
 
-	if err := h.provinciaRepo.UpdateProvincia(provincia); err != nil {
+	if err := h.provinciaRepo.ConContexto(c.UserContext()).UpdateProvincia(provincia); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "No se puede actualizar la provincia",
 		})
@@ -106,7 +106,7 @@ func (h *ProvinciaHandler) DeleteProvincia(c *fiber.Ctx) error {
 		})
 	}
 
-	if err := h.provinciaRepo.DeleteProvincia(uint(id)); err != nil {
+	if err := h.provinciaRepo.ConContexto(c.UserContext()).DeleteProvincia(uint(id)); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "No se puede eliminar la provincia",
 		})
@@ -121,7 +121,7 @@ func (h *ProvinciaHandler) DeleteProvincia(c *fiber.Ctx) error {
 func (h *ProvinciaHandler) GetProvinciaByNombre(c *fiber.Ctx) error {
 	nombre := c.Params("nombre")
 	
-	provincia, err := h.provinciaRepo.GetProvinciaByNombre(nombre)
+	provincia, err := h.provinciaRepo.ConContexto(c.UserContext()).GetProvinciaByNombre(nombre)
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 			"error": "Provincia no encontrada",
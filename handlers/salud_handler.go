@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"ApiEscuela/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// SaludHandler atiende las sondas de vida y disponibilidad del orquestador. Responden sin el sobre
+// estándar para que cualquier balanceador las entienda: 200 si está bien, 503 si no.
+type SaludHandler struct {
+	saludService *services.SaludService
+}
+
+func NewSaludHandler(saludService *services.SaludService) *SaludHandler {
+	return &SaludHandler{saludService: saludService}
+}
+
+// Vivo responde si el proceso está en marcha (livenessProbe)
+func (h *SaludHandler) Vivo(c *fiber.Ctx) error {
+	c.Set("Cache-Control", "no-store")
+	return c.JSON(h.saludService.Vivo())
+}
+
+// Listo verifica las dependencias (readinessProbe). Responde 503 si falla alguna crítica; si solo falla
+// una opcional responde 200 con status "degradado".
+func (h *SaludHandler) Listo(c *fiber.Ctx) error {
+	c.Set("Cache-Control", "no-store")
+	estado := h.saludService.Listo(c.UserContext())
+	if estado.Estado == services.EstadoSaludError {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(estado)
+	}
+	return c.JSON(estado)
+}
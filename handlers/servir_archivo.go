@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// carpetasArchivos son las únicas carpetas del almacenamiento que se sirven por /api/files
+var carpetasArchivos = map[string]bool{
+	"images":    true,
+	"videos":    true,
+	"documents": true,
+}
+
+// tipoArchivoServido define el Content-Type de cada extensión permitida y si se muestra en el navegador
+type tipoArchivoServido struct {
+	contentType string
+	inline      bool
+}
+
+var tiposArchivoServidos = map[string]tipoArchivoServido{
+	".jpg":  {"image/jpeg", true},
+	".jpeg": {"image/jpeg", true},
+	".png":  {"image/png", true},
+	".gif":  {"image/gif", true},
+	".mp4":  {"video/mp4", true},
+	".avi":  {"video/x-msvideo", true},
+	".mov":  {"video/quicktime", true},
+	".pdf":  {"application/pdf", true},
+	".doc":  {"application/msword", false},
+	".docx": {"application/vnd.openxmlformats-officedocument.wordprocessingml.document", false},
+	".txt":  {"text/plain; charset=utf-8", false},
+}
+
+// tiposVariantes son los formatos de las versiones reducidas de las imágenes
+var tiposVariantes = map[string]tipoArchivoServido{
+	".jpg":  {"image/jpeg", true},
+	".png":  {"image/png", true},
+	".webp": {"image/webp", true},
+}
+
+// nombreArchivoValido acepta nombres simples como los que genera UploadFile: sin rutas, sin archivos
+// ocultos y con una sola extensión
+var nombreArchivoValido = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]*\.[A-Za-z0-9]+$`)
+
+var (
+	errArchivoNoEncontrado = errors.New("archivo no encontrado")
+	errRangoInvalido       = errors.New("rango no satisfacible")
+)
+
+// validarArchivoServido comprueba que la carpeta y el nombre pedidos correspondan a un archivo subido
+// y devuelve cómo servirlo. Los enlaces simbólicos los rechaza el almacenamiento local.
+func validarArchivoServido(carpeta, nombre string) (tipoArchivoServido, error) {
+	tipo, ok := tiposArchivoServidos[strings.ToLower(filepath.Ext(nombre))]
+	if !carpetasArchivos[carpeta] || !nombreArchivoValido.MatchString(nombre) || !ok {
+		return tipo, errArchivoNoEncontrado
+	}
+	return tipo, nil
+}
+
+// parsearRango interpreta un encabezado Range de un solo rango de bytes (bytes=inicio-fin, bytes=inicio-
+// o bytes=-sufijo). Devuelve ok=false si no hay rango o si es de varios rangos, en cuyo caso se envía el
+// archivo completo.
+func parsearRango(encabezado string, tamano int64) (inicio, fin int64, ok bool, err error) {
+	if !strings.HasPrefix(encabezado, "bytes=") {
+		return 0, 0, false, nil
+	}
+	especificacion := strings.TrimSpace(strings.TrimPrefix(encabezado, "bytes="))
+	if strings.Contains(especificacion, ",") {
+		return 0, 0, false, nil
+	}
+	desde, hasta, encontrado := strings.Cut(especificacion, "-")
+	if !encontrado {
+		return 0, 0, false, errRangoInvalido
+	}
+	desde, hasta = strings.TrimSpace(desde), strings.TrimSpace(hasta)
+
+	if desde == "" {
+		// Sufijo: los últimos n bytes
+		n, err := strconv.ParseInt(hasta, 10, 64)
+		if err != nil || n <= 0 || tamano == 0 {
+			return 0, 0, false, errRangoInvalido
+		}
+		if n > tamano {
+			n = tamano
+		}
+		return tamano - n, tamano - 1, true, nil
+	}
+
+	inicio, err = strconv.ParseInt(desde, 10, 64)
+	if err != nil || inicio < 0 || inicio >= tamano {
+		return 0, 0, false, errRangoInvalido
+	}
+	fin = tamano - 1
+	if hasta != "" {
+		if fin, err = strconv.ParseInt(hasta, 10, 64); err != nil || fin < inicio {
+			return 0, 0, false, errRangoInvalido
+		}
+		if fin >= tamano {
+			fin = tamano - 1
+		}
+	}
+	return inicio, fin, true, nil
+}
+
+// contentDisposition arma el encabezado con el nombre del archivo, que ya fue validado como seguro
+func contentDisposition(tipo tipoArchivoServido, nombre string) string {
+	disposicion := "attachment"
+	if tipo.inline {
+		disposicion = "inline"
+	}
+	return fmt.Sprintf(`%s; filename="%s"`, disposicion, nombre)
+}
@@ -0,0 +1,204 @@
+package handlers
+
+import (
+	"ApiEscuela/repositories"
+	"ApiEscuela/services"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// diasMetricasSLAPorDef es el rango de las métricas cuando no se indica desde
+const diasMetricasSLAPorDef = 30
+
+type SLADudasHandler struct {
+	enrutamientoRepo *repositories.EnrutamientoDudasRepository
+	slaService       *services.SLADudasService
+}
+
+func NewSLADudasHandler(enrutamientoRepo *repositories.EnrutamientoDudasRepository, slaService *services.SLADudasService) *SLADudasHandler {
+	return &SLADudasHandler{enrutamientoRepo: enrutamientoRepo, slaService: slaService}
+}
+
+// sinPermiso responde 403 a quien intenta gestionar la asignación o el SLA sin ser administrador
+func (h *SLADudasHandler) sinPermiso(c *fiber.Ctx) error {
+	return SendError(c, 403, "accion_no_permitida", "Solo un administrador puede gestionar la asignación y el SLA de las dudas", "Inicie sesión con una cuenta de administrador")
+}
+
+// CreateRegla crea una regla de asignación automática de dudas
+func (h *SLADudasHandler) CreateRegla(c *fiber.Ctx) error {
+	if !visorDe(c).Administrador {
+		return h.sinPermiso(c)
+	}
+
+	var req services.ReglaAsignacionRequest
+	if err := c.BodyParser(&req); err != nil {
+		return SendError(c, 400, "json_invalido", "No se puede procesar el JSON. Verifique el formato de los datos", err.Error())
+	}
+
+	regla, err := h.slaService.ConContexto(c.UserContext()).CrearRegla(req)
+	if err != nil {
+		return SendError(c, 400, "regla_invalida", "No se pudo crear la regla de asignación", err.Error())
+	}
+
+	return SendSuccess(c, 201, regla)
+}
+
+// GetAllReglas obtiene las reglas de asignación en orden de evaluación
+func (h *SLADudasHandler) GetAllReglas(c *fiber.Ctx) error {
+	reglas, err := h.enrutamientoRepo.ConContexto(c.UserContext()).GetAllReglas()
+	if err != nil {
+		return SendError(c, 500, "error_base_datos", "Error interno del servidor", "No se pudieron obtener las reglas de asignación")
+	}
+
+	return SendSuccess(c, 200, reglas)
+}
+
+// GetRegla obtiene una regla de asignación por ID
+func (h *SLADudasHandler) GetRegla(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil || id <= 0 {
+		return SendError(c, 400, "id_invalido", "El ID de la regla no es válido", "El ID debe ser un número entero positivo")
+	}
+
+	regla, err := h.enrutamientoRepo.ConContexto(c.UserContext()).GetReglaByID(uint(id))
+	if err != nil {
+		return SendError(c, 404, "regla_no_encontrada", "No se encontró la regla solicitada", "Verifique que el ID sea correcto")
+	}
+
+	return SendSuccess(c, 200, regla)
+}
+
+// UpdateRegla actualiza una regla de asignación
+func (h *SLADudasHandler) UpdateRegla(c *fiber.Ctx) error {
+	if !visorDe(c).Administrador {
+		return h.sinPermiso(c)
+	}
+
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil || id <= 0 {
+		return SendError(c, 400, "id_invalido", "El ID de la regla no es válido", "El ID debe ser un número entero positivo")
+	}
+
+	var req services.ReglaAsignacionRequest
+	if err := c.BodyParser(&req); err != nil {
+		return SendError(c, 400, "json_invalido", "No se puede procesar el JSON. Verifique el formato de los datos", err.Error())
+	}
+
+	regla, err := h.slaService.ConContexto(c.UserContext()).ActualizarRegla(uint(id), req)
+	if err != nil {
+		if errors.Is(err, services.ErrReglaNoEncontrada) {
+			return SendError(c, 404, "regla_no_encontrada", "No se encontró la regla solicitada", "Verifique que el ID sea correcto")
+		}
+		return SendError(c, 400, "regla_invalida", "No se pudo actualizar la regla de asignación", err.Error())
+	}
+
+	return SendSuccess(c, 200, regla)
+}
+
+// DeleteRegla elimina una regla de asignación
+func (h *SLADudasHandler) DeleteRegla(c *fiber.Ctx) error {
+	if !visorDe(c).Administrador {
+		return h.sinPermiso(c)
+	}
+
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil || id <= 0 {
+		return SendError(c, 400, "id_invalido", "El ID de la regla no es válido", "El ID debe ser un número entero positivo")
+	}
+
+	if _, err := h.enrutamientoRepo.ConContexto(c.UserContext()).GetReglaByID(uint(id)); err != nil {
+		return SendError(c, 404, "regla_no_encontrada", "No se encontró la regla solicitada", "Verifique que el ID sea correcto")
+	}
+
+	if err := h.enrutamientoRepo.ConContexto(c.UserContext()).DeleteRegla(uint(id)); err != nil {
+		return SendError(c, 500, "error_base_datos", "Error interno del servidor", "No se pudo eliminar la regla de asignación")
+	}
+
+	return SendSuccess(c, 200, fiber.Map{
+		"message": "Regla de asignación eliminada exitosamente",
+		"id":      id,
+	})
+}
+
+// GetConfiguracion obtiene la configuración del SLA de respuesta
+func (h *SLADudasHandler) GetConfiguracion(c *fiber.Ctx) error {
+	configuracion, err := h.enrutamientoRepo.ConContexto(c.UserContext()).GetConfiguracionSLA()
+	if err != nil {
+		return SendError(c, 500, "error_base_datos", "Error interno del servidor", "No se pudo obtener la configuración del SLA")
+	}
+
+	return SendSuccess(c, 200, configuracion)
+}
+
+// UpdateConfiguracion actualiza la configuración del SLA de respuesta
+func (h *SLADudasHandler) UpdateConfiguracion(c *fiber.Ctx) error {
+	if !visorDe(c).Administrador {
+		return h.sinPermiso(c)
+	}
+
+	var req services.ConfiguracionSLARequest
+	if err := c.BodyParser(&req); err != nil {
+		return SendError(c, 400, "json_invalido", "No se puede procesar el JSON. Verifique el formato de los datos", err.Error())
+	}
+
+	configuracion, err := h.slaService.ConContexto(c.UserContext()).ActualizarConfiguracion(req)
+	if err != nil {
+		return SendError(c, 400, "configuracion_invalida", "No se pudo actualizar la configuración del SLA", err.Error())
+	}
+
+	return SendSuccess(c, 200, configuracion)
+}
+
+// GetMetricas obtiene el cumplimiento del SLA (?desde=YYYY-MM-DD&hasta=YYYY-MM-DD, por defecto los últimos 30 días)
+func (h *SLADudasHandler) GetMetricas(c *fiber.Ctx) error {
+	if !visorDe(c).Administrador {
+		return h.sinPermiso(c)
+	}
+
+	hasta := time.Now()
+	if valor := c.Query("hasta"); valor != "" {
+		fecha, err := time.ParseInLocation("2006-01-02", valor, time.Local)
+		if err != nil {
+			return SendError(c, 400, "fecha_invalida", "El parámetro hasta no es válido", "Use el formato YYYY-MM-DD")
+		}
+		hasta = fecha.AddDate(0, 0, 1)
+	}
+	desde := hasta.AddDate(0, 0, -diasMetricasSLAPorDef)
+	if valor := c.Query("desde"); valor != "" {
+		fecha, err := time.ParseInLocation("2006-01-02", valor, time.Local)
+		if err != nil {
+			return SendError(c, 400, "fecha_invalida", "El parámetro desde no es válido", "Use el formato YYYY-MM-DD")
+		}
+		desde = fecha
+	}
+	if !desde.Before(hasta) {
+		return SendError(c, 400, "rango_invalido", "El rango de fechas no es válido", "desde debe ser anterior a hasta")
+	}
+
+	reporte, err := h.slaService.ConContexto(c.UserContext()).Metricas(desde, hasta)
+	if err != nil {
+		return SendError(c, 500, "error_base_datos", "Error interno del servidor", "No se pudieron calcular las métricas del SLA")
+	}
+
+	return SendSuccess(c, 200, reporte)
+}
+
+// RevisarPlazos ejecuta de inmediato la revisión de plazos que hace el monitor en segundo plano
+func (h *SLADudasHandler) RevisarPlazos(c *fiber.Ctx) error {
+	if !visorDe(c).Administrador {
+		return h.sinPermiso(c)
+	}
+
+	recordatorios, escaladas, err := h.slaService.ConContexto(c.UserContext()).RevisarPlazos(time.Now())
+	if err != nil {
+		return SendError(c, 500, "error_base_datos", "Error interno del servidor", "No se pudieron revisar los plazos de las dudas")
+	}
+
+	return SendSuccess(c, 200, fiber.Map{
+		"recordatorios": recordatorios,
+		"escaladas":     escaladas,
+	})
+}
@@ -0,0 +1,180 @@
+package handlers
+
+import (
+	"ApiEscuela/models"
+	"ApiEscuela/repositories"
+	"ApiEscuela/services"
+	"errors"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type SolicitudVisitaHandler struct {
+	solicitudRepo    *repositories.SolicitudVisitaRepository
+	tematicaRepo     *repositories.TematicaRepository
+	solicitudService *services.SolicitudVisitaService
+}
+
+func NewSolicitudVisitaHandler(solicitudRepo *repositories.SolicitudVisitaRepository, tematicaRepo *repositories.TematicaRepository, solicitudService *services.SolicitudVisitaService) *SolicitudVisitaHandler {
+	return &SolicitudVisitaHandler{solicitudRepo: solicitudRepo, tematicaRepo: tematicaRepo, solicitudService: solicitudService}
+}
+
+// CreateSolicitud registra una solicitud de visita desde el formulario público
+func (h *SolicitudVisitaHandler) CreateSolicitud(c *fiber.Ctx) error {
+	var req services.SolicitudVisitaRequest
+	if err := c.BodyParser(&req); err != nil {
+		return SendError(c, 400, "json_invalido", "No se puede procesar el JSON. Verifique el formato de los datos", err.Error())
+	}
+
+	solicitud, err := h.solicitudService.ConContexto(c.UserContext()).CrearSolicitud(c.UserContext(), req)
+	if err != nil {
+		return SendError(c, 400, "solicitud_invalida", "No se pudo registrar la solicitud", err.Error())
+	}
+
+	return SendSuccess(c, 201, fiber.Map{
+		"message":            "Solicitud registrada exitosamente",
+		"codigo_seguimiento": solicitud.CodigoSeguimiento,
+		"estado":             solicitud.Estado,
+	})
+}
+
+// GetTematicasPublicas lista las temáticas que se pueden elegir en el formulario público
+func (h *SolicitudVisitaHandler) GetTematicasPublicas(c *fiber.Ctx) error {
+	tematicas, err := h.tematicaRepo.ConContexto(c.UserContext()).GetAllTematicas()
+	if err != nil {
+		return SendError(c, 500, "error_base_datos", "Error interno del servidor", "No se pudieron obtener las temáticas")
+	}
+
+	resultado := make([]fiber.Map, 0, len(tematicas))
+	for _, t := range tematicas {
+		resultado = append(resultado, fiber.Map{"id": t.ID, "nombre": t.Nombre, "descripcion": t.Descripcion})
+	}
+	return SendSuccess(c, 200, resultado)
+}
+
+// GetEstadoSolicitud permite al contacto consultar su solicitud con el código de seguimiento
+func (h *SolicitudVisitaHandler) GetEstadoSolicitud(c *fiber.Ctx) error {
+	solicitud, err := h.solicitudService.ConContexto(c.UserContext()).ObtenerPorCodigo(c.Params("codigo"))
+	if err != nil {
+		return SendError(c, 404, "solicitud_no_encontrada", "No se encontró la solicitud", "Verifique el código de seguimiento")
+	}
+
+	respuesta := fiber.Map{
+		"codigo_seguimiento": solicitud.CodigoSeguimiento,
+		"estado":             solicitud.Estado,
+		"fecha_preferida":    solicitud.FechaPreferida,
+		"fecha_alternativa":  solicitud.FechaAlternativa,
+		"numero_estudiantes": solicitud.NumeroEstudiantes,
+	}
+	if solicitud.Estado == models.EstadoSolicitudRechazada {
+		respuesta["motivo_rechazo"] = solicitud.MotivoRechazo
+	}
+	if solicitud.ProgramaVisita != nil {
+		respuesta["fecha_visita"] = solicitud.ProgramaVisita.Fecha
+		respuesta["fecha_fin_visita"] = solicitud.ProgramaVisita.Fechafin
+	}
+	return SendSuccess(c, 200, respuesta)
+}
+
+// GetSolicitudes lista las solicitudes para el personal UTEQ (?estado=pendiente|aprobada|rechazada)
+func (h *SolicitudVisitaHandler) GetSolicitudes(c *fiber.Ctx) error {
+	estado := c.Query("estado")
+	switch estado {
+	case "", models.EstadoSolicitudPendiente, models.EstadoSolicitudAprobada, models.EstadoSolicitudRechazada:
+	default:
+		return SendError(c, 400, "estado_invalido", "El estado no es válido", "Use 'pendiente', 'aprobada' o 'rechazada'")
+	}
+
+	userID, _ := c.Locals("user_id").(uint)
+	if err := h.solicitudService.ConContexto(c.UserContext()).VerificarRevisor(userID); err != nil {
+		return h.sendSolicitudError(c, err, "No se pudieron obtener las solicitudes")
+	}
+
+	solicitudes, err := h.solicitudRepo.ConContexto(c.UserContext()).GetSolicitudes(estado)
+	if err != nil {
+		return SendError(c, 500, "error_base_datos", "Error interno del servidor", "No se pudieron obtener las solicitudes")
+	}
+
+	return SendSuccess(c, 200, solicitudes)
+}
+
+// GetSolicitud obtiene una solicitud por ID
+func (h *SolicitudVisitaHandler) GetSolicitud(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil || id <= 0 {
+		return SendError(c, 400, "id_invalido", "El ID de la solicitud no es válido", "El ID debe ser un número entero positivo")
+	}
+
+	userID, _ := c.Locals("user_id").(uint)
+	if err := h.solicitudService.ConContexto(c.UserContext()).VerificarRevisor(userID); err != nil {
+		return h.sendSolicitudError(c, err, "No se pudo obtener la solicitud")
+	}
+
+	solicitud, err := h.solicitudRepo.ConContexto(c.UserContext()).GetSolicitudByID(uint(id))
+	if err != nil {
+		return SendError(c, 404, "solicitud_no_encontrada", "No se encontró la solicitud solicitada", "Verifique que el ID sea correcto")
+	}
+
+	return SendSuccess(c, 200, solicitud)
+}
+
+// AprobarSolicitud crea el programa de visita de la solicitud
+func (h *SolicitudVisitaHandler) AprobarSolicitud(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil || id <= 0 {
+		return SendError(c, 400, "id_invalido", "El ID de la solicitud no es válido", "El ID debe ser un número entero positivo")
+	}
+
+	var req services.AprobacionSolicitudRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return SendError(c, 400, "json_invalido", "No se puede procesar el JSON. Verifique el formato de los datos", err.Error())
+		}
+	}
+
+	userID, _ := c.Locals("user_id").(uint)
+	solicitud, err := h.solicitudService.ConContexto(c.UserContext()).Aprobar(c.UserContext(), uint(id), userID, req)
+	if err != nil {
+		return h.sendSolicitudError(c, err, "No se pudo aprobar la solicitud")
+	}
+
+	return SendSuccess(c, 200, solicitud)
+}
+
+// RechazarSolicitud rechaza una solicitud indicando el motivo
+func (h *SolicitudVisitaHandler) RechazarSolicitud(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil || id <= 0 {
+		return SendError(c, 400, "id_invalido", "El ID de la solicitud no es válido", "El ID debe ser un número entero positivo")
+	}
+
+	var req struct {
+		Motivo string `json:"motivo"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return SendError(c, 400, "json_invalido", "No se puede procesar el JSON. Verifique el formato de los datos", err.Error())
+	}
+
+	userID, _ := c.Locals("user_id").(uint)
+	solicitud, err := h.solicitudService.ConContexto(c.UserContext()).Rechazar(c.UserContext(), uint(id), userID, req.Motivo)
+	if err != nil {
+		return h.sendSolicitudError(c, err, "No se pudo rechazar la solicitud")
+	}
+
+	return SendSuccess(c, 200, solicitud)
+}
+
+// sendSolicitudError traduce los errores del servicio de solicitudes a respuestas HTTP
+func (h *SolicitudVisitaHandler) sendSolicitudError(c *fiber.Ctx, err error, message string) error {
+	switch {
+	case errors.Is(err, services.ErrSolicitudNoEncontrada):
+		return SendError(c, 404, "solicitud_no_encontrada", "No se encontró la solicitud solicitada", "Verifique que el ID sea correcto")
+	case errors.Is(err, services.ErrRevisionNoPermitida):
+		return SendError(c, 403, "revision_no_permitida", "No tiene permiso para consultar ni revisar solicitudes", err.Error())
+	case errors.Is(err, services.ErrSolicitudYaRevisada):
+		return SendError(c, 409, "solicitud_ya_revisada", "La solicitud ya fue revisada", "Solo se pueden aprobar o rechazar solicitudes pendientes")
+	default:
+		return SendError(c, 400, "solicitud_invalida", message, err.Error())
+	}
+}
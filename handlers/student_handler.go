@@ -59,18 +59,18 @@ func (h *EstudianteHandler) CreateEstudiante(c *fiber.Ctx) error {
 	}
 
 	// Verificar que las relaciones existen (validación de relaciones)
-	if !h.personaExists(estudiante.PersonaID) {
+	if !h.personaExists(c, estudiante.PersonaID) {
 		return SendError(c, 400, "persona_no_existe", "No se encontró la persona con el ID especificado", "Verifique que el persona_id sea correcto y que la persona exista en el sistema")
 	}
-	if !h.institucionExists(estudiante.InstitucionID) {
+	if !h.institucionExists(c, estudiante.InstitucionID) {
 		return SendError(c, 400, "institucion_no_existe", "No se encontró la institución con el ID especificado", "Verifique que el institucion_id sea correcto y que la institución exista en el sistema")
 	}
-	if !h.ciudadExists(estudiante.CiudadID) {
+	if !h.ciudadExists(c, estudiante.CiudadID) {
 		return SendError(c, 400, "ciudad_no_existe", "No se encontró la ciudad con el ID especificado", "Verifique que el ciudad_id sea correcto y que la ciudad exista en el sistema")
 	}
 
 	// Crear estudiante
-	if err := h.estudianteRepo.CreateEstudiante(&estudiante); err != nil {
+	if err := h.estudianteRepo.ConContexto(c.UserContext()).CreateEstudiante(&estudiante); err != nil {
 		// Manejar errores específicos del repositorio
 		switch err.Error() {
 		case "estudiante ya existe":
@@ -95,7 +95,7 @@ func (h *EstudianteHandler) GetEstudiante(c *fiber.Ctx) error {
 		return SendError(c, 400, "id_invalido", "El ID del estudiante no es válido", "El ID debe ser un número entero positivo")
 	}
 
-	estudiante, err := h.estudianteRepo.GetEstudianteByID(uint(id))
+	estudiante, err := h.estudianteRepo.ConContexto(c.UserContext()).ConVisor(visorDe(c)).GetEstudianteByID(uint(id))
 	if err != nil {
 		return SendError(c, 404, "estudiante_no_encontrado", "No se encontró el estudiante solicitado", "Verifique que el ID sea correcto")
 	}
@@ -105,7 +105,7 @@ func (h *EstudianteHandler) GetEstudiante(c *fiber.Ctx) error {
 
 // GetAllEstudiantes obtiene todos los estudiantes activos
 func (h *EstudianteHandler) GetAllEstudiantes(c *fiber.Ctx) error {
-	estudiantes, err := h.estudianteRepo.GetAllEstudiantes()
+	estudiantes, err := h.estudianteRepo.ConContexto(c.UserContext()).GetAllEstudiantes()
 	if err != nil {
 		return SendError(c, 500, "error_base_datos", "Error interno del servidor", "No se pudieron obtener los estudiantes")
 	}
@@ -115,7 +115,7 @@ func (h *EstudianteHandler) GetAllEstudiantes(c *fiber.Ctx) error {
 
 // GetAllEstudiantesIncludingDeleted obtiene todos los estudiantes incluyendo los eliminados
 func (h *EstudianteHandler) GetAllEstudiantesIncludingDeleted(c *fiber.Ctx) error {
-	estudiantes, err := h.estudianteRepo.GetAllEstudiantesIncludingDeleted()
+	estudiantes, err := h.estudianteRepo.ConContexto(c.UserContext()).GetAllEstudiantesIncludingDeleted()
 	if err != nil {
 		return SendError(c, 500, "database_error", "Error interno del servidor", "No se pudieron obtener los estudiantes")
 	}
@@ -125,7 +125,7 @@ func (h *EstudianteHandler) GetAllEstudiantesIncludingDeleted(c *fiber.Ctx) erro
 
 // GetDeletedEstudiantes obtiene solo los estudiantes eliminados
 func (h *EstudianteHandler) GetDeletedEstudiantes(c *fiber.Ctx) error {
-	estudiantes, err := h.estudianteRepo.GetDeletedEstudiantes()
+	estudiantes, err := h.estudianteRepo.ConContexto(c.UserContext()).GetDeletedEstudiantes()
 	if err != nil {
 		return SendError(c, 500, "database_error", "Error interno del servidor", "No se pudieron obtener los estudiantes eliminados")
 	}
@@ -146,7 +146,7 @@ func (h *EstudianteHandler) UpdateEstudiante(c *fiber.Ctx) error {
 	}
 
 	// Verificar que el estudiante existe
-	existingEstudiante, err := h.estudianteRepo.GetEstudianteByID(uint(id))
+	existingEstudiante, err := h.estudianteRepo.ConContexto(c.UserContext()).ConVisor(visorDe(c)).GetEstudianteByID(uint(id))
 	if err != nil {
 		return SendError(c, 404, "estudiante_no_encontrado", "No se encontró el estudiante solicitado", "Verifique que el ID sea correcto")
 	}
@@ -165,7 +165,7 @@ func (h *EstudianteHandler) UpdateEstudiante(c *fiber.Ctx) error {
 	// Verificar si la nueva persona ya tiene un registro de estudiante (si cambia la persona)
 	if updateData.PersonaID != existingEstudiante.PersonaID {
 		// Verificar si existe otro estudiante con la nueva persona
-		estudiantes, _ := h.estudianteRepo.GetAllEstudiantes()
+		estudiantes, _ := h.estudianteRepo.ConContexto(c.UserContext()).GetAllEstudiantes()
 		for _, est := range estudiantes {
 			if est.PersonaID == updateData.PersonaID && est.ID != uint(id) {
 				return SendError(c, 409, "estudiante_duplicado", "La persona ya tiene un registro de estudiante", "Una persona solo puede tener un registro de estudiante")
@@ -180,7 +180,7 @@ func (h *EstudianteHandler) UpdateEstudiante(c *fiber.Ctx) error {
 	existingEstudiante.Especialidad = strings.TrimSpace(updateData.Especialidad)
 
 	// Guardar cambios
-	if err := h.estudianteRepo.UpdateEstudiante(existingEstudiante); err != nil {
+	if err := h.estudianteRepo.ConContexto(c.UserContext()).UpdateEstudiante(existingEstudiante); err != nil {
 		// Manejar errores específicos del repositorio
 		switch err.Error() {
 		case "estudiante ya existe":
@@ -205,8 +205,8 @@ func (h *EstudianteHandler) DeleteEstudiante(c *fiber.Ctx) error {
 		return SendError(c, 400, "id_invalido", "El ID del estudiante no es válido", "El ID debe ser un número entero positivo")
 	}
 
-	// Verificar que el estudiante existe
-	estudiante, err := h.estudianteRepo.GetEstudianteByID(uint(id))
+	// Verificar que el estudiante existe; se cuentan todas sus dudas, también las privadas
+	estudiante, err := h.estudianteRepo.ConContexto(c.UserContext()).ConVisor(repositories.VisorSistema).GetEstudianteByID(uint(id))
 	if err != nil {
 		return SendError(c, 404, "estudiante_no_encontrado", "No se encontró el estudiante solicitado", "Verifique que el ID sea correcto")
 	}
@@ -217,7 +217,7 @@ func (h *EstudianteHandler) DeleteEstudiante(c *fiber.Ctx) error {
 	}
 
 	// Eliminar estudiante
-	if err := h.estudianteRepo.DeleteEstudiante(uint(id)); err != nil {
+	if err := h.estudianteRepo.ConContexto(c.UserContext()).DeleteEstudiante(uint(id)); err != nil {
 		return SendError(c, 500, "error_base_datos", "Error interno del servidor", "No se pudo eliminar el estudiante y sus datos relacionados")
 	}
 
@@ -240,7 +240,7 @@ func (h *EstudianteHandler) RestoreEstudiante(c *fiber.Ctx) error {
 	}
 
 	// Restaurar estudiante
-	if err := h.estudianteRepo.RestoreEstudiante(uint(id)); err != nil {
+	if err := h.estudianteRepo.ConContexto(c.UserContext()).RestoreEstudiante(uint(id)); err != nil {
 		return SendError(c, 500, "database_error", "Error interno del servidor", "No se pudo restaurar el estudiante y sus datos relacionados")
 	}
 
@@ -262,7 +262,7 @@ func (h *EstudianteHandler) GetEstudiantesByCity(c *fiber.Ctx) error {
 		return SendError(c, 400, "ciudad_id_invalido", "El ID de la ciudad no es válido", "El ID debe ser un número entero positivo")
 	}
 
-	estudiantes, err := h.estudianteRepo.GetEstudiantesByCity(uint(ciudadID))
+	estudiantes, err := h.estudianteRepo.ConContexto(c.UserContext()).GetEstudiantesByCity(uint(ciudadID))
 	if err != nil {
 		return SendError(c, 500, "error_base_datos", "Error interno del servidor", "No se pudieron obtener los estudiantes")
 	}
@@ -282,7 +282,7 @@ func (h *EstudianteHandler) GetEstudiantesByInstitucion(c *fiber.Ctx) error {
 		return SendError(c, 400, "institucion_id_invalido", "El ID de la institución no es válido", "El ID debe ser un número entero positivo")
 	}
 
-	estudiantes, err := h.estudianteRepo.GetEstudiantesByInstitucion(uint(institucionID))
+	estudiantes, err := h.estudianteRepo.ConContexto(c.UserContext()).GetEstudiantesByInstitucion(uint(institucionID))
 	if err != nil {
 		return SendError(c, 500, "error_base_datos", "Error interno del servidor", "No se pudieron obtener los estudiantes")
 	}
@@ -299,7 +299,7 @@ func (h *EstudianteHandler) GetEstudiantesByEspecialidad(c *fiber.Ctx) error {
 		return SendValidationError(c, "Los parámetros de búsqueda no son válidos", validationErrors)
 	}
 
-	estudiantes, err := h.estudianteRepo.GetEstudiantesByEspecialidad(especialidad)
+	estudiantes, err := h.estudianteRepo.ConContexto(c.UserContext()).GetEstudiantesByEspecialidad(especialidad)
 	if err != nil {
 		return SendError(c, 500, "error_base_datos", "Error interno del servidor", "No se pudieron obtener los estudiantes")
 	}
@@ -408,34 +408,34 @@ func (h *EstudianteHandler) validateEstudianteSearchParams(especialidad string)
 }
 
 // personaExists verifica si una persona existe en la base de datos
-func (h *EstudianteHandler) personaExists(personaID uint) bool {
+func (h *EstudianteHandler) personaExists(c *fiber.Ctx, personaID uint) bool {
 	if personaID == 0 {
 		return false
 	}
 
 	var count int64
-	err := h.personaRepo.GetDB().Model(&models.Persona{}).Where("id = ?", personaID).Count(&count).Error
+	err := h.personaRepo.ConContexto(c.UserContext()).GetDB().Model(&models.Persona{}).Where("id = ?", personaID).Count(&count).Error
 	return err == nil && count > 0
 }
 
 // institucionExists verifica si una institución existe en la base de datos
-func (h *EstudianteHandler) institucionExists(institucionID uint) bool {
+func (h *EstudianteHandler) institucionExists(c *fiber.Ctx, institucionID uint) bool {
 	if institucionID == 0 {
 		return false
 	}
 
 	var count int64
-	err := h.institucionRepo.GetDB().Model(&models.Institucion{}).Where("id = ?", institucionID).Count(&count).Error
+	err := h.institucionRepo.ConContexto(c.UserContext()).GetDB().Model(&models.Institucion{}).Where("id = ?", institucionID).Count(&count).Error
 	return err == nil && count > 0
 }
 
 // ciudadExists verifica si una ciudad existe en la base de datos
-func (h *EstudianteHandler) ciudadExists(ciudadID uint) bool {
+func (h *EstudianteHandler) ciudadExists(c *fiber.Ctx, ciudadID uint) bool {
 	if ciudadID == 0 {
 		return false
 	}
 
 	var count int64
-	err := h.ciudadRepo.GetDB().Model(&models.Ciudad{}).Where("id = ?", ciudadID).Count(&count).Error
+	err := h.ciudadRepo.ConContexto(c.UserContext()).GetDB().Model(&models.Ciudad{}).Where("id = ?", ciudadID).Count(&count).Error
 	return err == nil && count > 0
 }
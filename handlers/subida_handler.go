@@ -0,0 +1,210 @@
+package handlers
+
+import (
+	"ApiEscuela/models"
+	"ApiEscuela/services"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// versionTus es la versión del protocolo de subidas reanudables (https://tus.io/protocols/resumable-upload)
+const versionTus = "1.0.0"
+
+// SubidaHandler implementa el protocolo tus para subir archivos grandes por partes. Cada parte viaja
+// en una petición PATCH, así que debe caber en el BodyLimit de Fiber.
+type SubidaHandler struct {
+	subidaService *services.SubidaService
+}
+
+func NewSubidaHandler(subidaService *services.SubidaService) *SubidaHandler {
+	return &SubidaHandler{subidaService: subidaService}
+}
+
+// VersionTus agrega Tus-Resumable a todas las respuestas y exige que el cliente use la versión
+// soportada. OPTIONS y la consulta de estado con GET no la necesitan.
+func (h *SubidaHandler) VersionTus(c *fiber.Ctx) error {
+	c.Set("Tus-Resumable", versionTus)
+	if c.Method() == fiber.MethodOptions || c.Method() == fiber.MethodGet {
+		return c.Next()
+	}
+	if c.Get("Tus-Resumable") != versionTus {
+		c.Set("Tus-Version", versionTus)
+		return c.Status(fiber.StatusPreconditionFailed).JSON(conTraza(c, fiber.Map{
+			"error": "Versión del protocolo tus no soportada",
+		}))
+	}
+	return c.Next()
+}
+
+// Opciones informa las capacidades del servidor
+func (h *SubidaHandler) Opciones(c *fiber.Ctx) error {
+	c.Set("Tus-Version", versionTus)
+	c.Set("Tus-Extension", "creation,expiration,checksum,termination")
+	c.Set("Tus-Max-Size", strconv.FormatInt(h.subidaService.TamanoMaximoSubida(), 10))
+	c.Set("Tus-Checksum-Algorithm", services.AlgoritmosChecksum)
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// CrearSubida inicia una subida. Upload-Length indica el tamaño total y Upload-Metadata el nombre del
+// archivo (filename o name) y, opcionalmente, visibilidad y sha256 del archivo completo.
+func (h *SubidaHandler) CrearSubida(c *fiber.Ctx) error {
+	tamano, err := strconv.ParseInt(c.Get("Upload-Length"), 10, 64)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(conTraza(c, fiber.Map{
+			"error": "Upload-Length es obligatorio y debe ser un número",
+		}))
+	}
+	metadatos, err := parsearMetadatosTus(c.Get("Upload-Metadata"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(conTraza(c, fiber.Map{
+			"error": "Upload-Metadata no es válido",
+		}))
+	}
+	nombre := metadatos["filename"]
+	if nombre == "" {
+		nombre = metadatos["name"]
+	}
+
+	userID, _ := c.Locals("user_id").(uint)
+	subida, err := h.subidaService.ConContexto(c.UserContext()).Crear(userID, services.CrearSubidaRequest{
+		NombreArchivo: nombre,
+		Tamano:        tamano,
+		Visibilidad:   metadatos["visibilidad"],
+		SHA256:        metadatos["sha256"],
+	})
+	if err != nil {
+		return c.Status(estadoErrorSubidaReanudable(err)).JSON(conTraza(c, fiber.Map{
+			"error":   "No se pudo crear la subida",
+			"details": err.Error(),
+		}))
+	}
+
+	c.Location(c.BaseURL() + "/api/upload/tus/" + subida.Identificador)
+	c.Set("Upload-Expires", subida.ExpiraEn.UTC().Format(http.TimeFormat))
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"identificador": subida.Identificador,
+		"expira_en":     subida.ExpiraEn,
+	})
+}
+
+// EstadoSubida responde a HEAD con los bytes recibidos, para que el cliente sepa desde dónde reanudar
+func (h *SubidaHandler) EstadoSubida(c *fiber.Ctx) error {
+	c.Set("Cache-Control", "no-store")
+	userID, _ := c.Locals("user_id").(uint)
+	subida, err := h.subidaService.ConContexto(c.UserContext()).Obtener(c.Params("id"), userID)
+	if err != nil {
+		return c.SendStatus(estadoErrorSubidaReanudable(err))
+	}
+	cabecerasSubida(c, subida)
+	return c.SendStatus(fiber.StatusOK)
+}
+
+// GetSubida devuelve el estado de la subida en JSON y, cuando se completó, el archivo registrado
+func (h *SubidaHandler) GetSubida(c *fiber.Ctx) error {
+	userID, _ := c.Locals("user_id").(uint)
+	subida, err := h.subidaService.ConContexto(c.UserContext()).Obtener(c.Params("id"), userID)
+	if err != nil {
+		return c.Status(estadoErrorSubidaReanudable(err)).JSON(conTraza(c, fiber.Map{
+			"error": err.Error(),
+		}))
+	}
+	return c.JSON(fiber.Map{
+		"identificador":  subida.Identificador,
+		"nombre_archivo": subida.NombreArchivo,
+		"tamano":         subida.Tamano,
+		"desplazamiento": subida.Desplazamiento,
+		"completa":       subida.ArchivoID != nil,
+		"archivo_id":     subida.ArchivoID,
+		"expira_en":      subida.ExpiraEn,
+	})
+}
+
+// AgregarParte recibe una parte del archivo desde Upload-Offset. La última parte completa la subida:
+// el archivo se inspecciona y se guarda igual que en /api/upload, y sus errores se informan aquí.
+func (h *SubidaHandler) AgregarParte(c *fiber.Ctx) error {
+	if c.Get("Content-Type") != "application/offset+octet-stream" {
+		return c.Status(fiber.StatusUnsupportedMediaType).JSON(conTraza(c, fiber.Map{
+			"error": "Content-Type debe ser application/offset+octet-stream",
+		}))
+	}
+	desplazamiento, err := strconv.ParseInt(c.Get("Upload-Offset"), 10, 64)
+	if err != nil || desplazamiento < 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(conTraza(c, fiber.Map{
+			"error": "Upload-Offset es obligatorio y debe ser un número",
+		}))
+	}
+
+	userID, _ := c.Locals("user_id").(uint)
+	subida, archivo, err := h.subidaService.ConContexto(c.UserContext()).AgregarParte(c.Params("id"), userID, desplazamiento, c.Body(), c.Get("Upload-Checksum"))
+	if err != nil {
+		return c.Status(estadoErrorSubidaReanudable(err)).JSON(conTraza(c, fiber.Map{
+			"error":   "No se pudo guardar la parte",
+			"details": err.Error(),
+		}))
+	}
+
+	cabecerasSubida(c, subida)
+	if archivo != nil {
+		c.Set("Upload-Archivo-Id", strconv.FormatUint(uint64(archivo.ID), 10))
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// CancelarSubida elimina la subida y las partes recibidas
+func (h *SubidaHandler) CancelarSubida(c *fiber.Ctx) error {
+	userID, _ := c.Locals("user_id").(uint)
+	if err := h.subidaService.ConContexto(c.UserContext()).Cancelar(c.Params("id"), userID); err != nil {
+		return c.Status(estadoErrorSubidaReanudable(err)).JSON(conTraza(c, fiber.Map{
+			"error": err.Error(),
+		}))
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func cabecerasSubida(c *fiber.Ctx, subida *models.SubidaReanudable) {
+	c.Set("Upload-Offset", strconv.FormatInt(subida.Desplazamiento, 10))
+	c.Set("Upload-Length", strconv.FormatInt(subida.Tamano, 10))
+	if subida.ArchivoID == nil {
+		c.Set("Upload-Expires", subida.ExpiraEn.UTC().Format(http.TimeFormat))
+	}
+}
+
+// parsearMetadatosTus lee Upload-Metadata: pares "clave valor-en-base64" separados por comas
+func parsearMetadatosTus(valor string) (map[string]string, error) {
+	metadatos := map[string]string{}
+	for _, par := range strings.Split(valor, ",") {
+		par = strings.TrimSpace(par)
+		if par == "" {
+			continue
+		}
+		clave, codificado, _ := strings.Cut(par, " ")
+		decodificado, err := base64.StdEncoding.DecodeString(strings.TrimSpace(codificado))
+		if err != nil {
+			return nil, err
+		}
+		metadatos[clave] = string(decodificado)
+	}
+	return metadatos, nil
+}
+
+// estadoErrorSubidaReanudable agrega los códigos del protocolo tus a los de una subida normal
+func estadoErrorSubidaReanudable(err error) int {
+	switch {
+	case errors.Is(err, services.ErrSubidaNoEncontrada):
+		return fiber.StatusNotFound
+	case errors.Is(err, services.ErrDesplazamientoInvalido):
+		return fiber.StatusConflict
+	case errors.Is(err, services.ErrParteExcedeTamano):
+		return fiber.StatusRequestEntityTooLarge
+	case errors.Is(err, services.ErrChecksumNoCoincide):
+		return 460 // Checksum Mismatch, definido por la extensión checksum de tus
+	case errors.Is(err, services.ErrHashSubidaNoCoincide):
+		return fiber.StatusUnprocessableEntity
+	}
+	return estadoErrorSubida(err)
+}
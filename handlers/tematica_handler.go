@@ -26,7 +26,7 @@ func (h *TematicaHandler) CreateTematica(c *fiber.Ctx) error {
 		})
 	}
 
-	if err := h.tematicaRepo.CreateTematica(&tematica); err != nil {
+	if err := h.tematicaRepo.ConContexto(c.UserContext()).CreateTematica(&tematica); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "No se puede crear la temática",
 		})
@@ -44,7 +44,7 @@ func (h *TematicaHandler) GetTematica(c *fiber.Ctx) error {
 		})
 	}
 
-	tematica, err := h.tematicaRepo.GetTematicaByID(uint(id))
+	tematica, err := h.tematicaRepo.ConContexto(c.UserContext()).GetTematicaByID(uint(id))
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 			"error": "Temática no encontrada",
@@ -56,7 +56,7 @@ func (h *TematicaHandler) GetTematica(c *fiber.Ctx) error {
 
 // GetAllTematicas obtiene todas las temáticas
 func (h *TematicaHandler) GetAllTematicas(c *fiber.Ctx) error {
-	tematicas, err := h.tematicaRepo.GetAllTematicas()
+	tematicas, err := h.tematicaRepo.ConContexto(c.UserContext()).GetAllTematicas()
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "No se pueden obtener las temáticas",
@@ -75,7 +75,7 @@ func (h *TematicaHandler) UpdateTematica(c *fiber.Ctx) error {
 		})
 	}
 
-	tematica, err := h.tematicaRepo.GetTematicaByID(uint(id))
+	tematica, err := h.tematicaRepo.ConContexto(c.UserContext()).GetTematicaByID(uint(id))
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 			"error": "Temática no encontrada",
@@ -88,7 +88,7 @@ func (h *TematicaHandler) UpdateTematica(c *fiber.Ctx) error {
 		})
 	}
 
-	if err := h.tematicaRepo.UpdateTematica(tematica); err != nil {
+	if err := h.tematicaRepo.ConContexto(c.UserContext()).UpdateTematica(tematica); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "No se puede actualizar la temática",
 		})
@@ -106,7 +106,7 @@ func (h *TematicaHandler) DeleteTematica(c *fiber.Ctx) error {
 		})
 	}
 
-	if err := h.tematicaRepo.DeleteTematica(uint(id)); err != nil {
+	if err := h.tematicaRepo.ConContexto(c.UserContext()).DeleteTematica(uint(id)); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "No se puede eliminar la temática",
 		})
@@ -121,7 +121,7 @@ func (h *TematicaHandler) DeleteTematica(c *fiber.Ctx) error {
 func (h *TematicaHandler) GetTematicasByNombre(c *fiber.Ctx) error {
 	nombre := c.Params("nombre")
 	
-	tematicas, err := h.tematicaRepo.GetTematicasByNombre(nombre)
+	tematicas, err := h.tematicaRepo.ConContexto(c.UserContext()).GetTematicasByNombre(nombre)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "No se pueden obtener las temáticas",
@@ -135,7 +135,7 @@ func (h *TematicaHandler) GetTematicasByNombre(c *fiber.Ctx) error {
 func (h *TematicaHandler) GetTematicasByDescripcion(c *fiber.Ctx) error {
 	descripcion := c.Params("descripcion")
 	
-	tematicas, err := h.tematicaRepo.GetTematicasByDescripcion(descripcion)
+	tematicas, err := h.tematicaRepo.ConContexto(c.UserContext()).GetTematicasByDescripcion(descripcion)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "No se pueden obtener las temáticas",
@@ -26,7 +26,7 @@ func (h *TipoUsuarioHandler) CreateTipoUsuario(c *fiber.Ctx) error {
 		})
 	}
 
-	if err := h.tipoUsuarioRepo.CreateTipoUsuario(&tipoUsuario); err != nil {
+	if err := h.tipoUsuarioRepo.ConContexto(c.UserContext()).CreateTipoUsuario(&tipoUsuario); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "No se puede crear el tipo de usuario",
 		})
@@ -44,7 +44,7 @@ func (h *TipoUsuarioHandler) GetTipoUsuario(c *fiber.Ctx) error {
 		})
 	}
 
-	tipoUsuario, err := h.tipoUsuarioRepo.GetTipoUsuarioByID(uint(id))
+	tipoUsuario, err := h.tipoUsuarioRepo.ConContexto(c.UserContext()).GetTipoUsuarioByID(uint(id))
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 			"error": "Tipo de usuario no encontrado",
@@ -56,7 +56,7 @@ func (h *TipoUsuarioHandler) GetTipoUsuario(c *fiber.Ctx) error {
 
 // GetAllTiposUsuario obtiene todos los tipos de usuario
 func (h *TipoUsuarioHandler) GetAllTiposUsuario(c *fiber.Ctx) error {
-	tiposUsuario, err := h.tipoUsuarioRepo.GetAllTiposUsuario()
+	tiposUsuario, err := h.tipoUsuarioRepo.ConContexto(c.UserContext()).GetAllTiposUsuario()
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "No se pueden obtener los tipos de usuario",
@@ -75,7 +75,7 @@ func (h *TipoUsuarioHandler) UpdateTipoUsuario(c *fiber.Ctx) error {
 		})
 	}
 
-	tipoUsuario, err := h.tipoUsuarioRepo.GetTipoUsuarioByID(uint(id))
+	tipoUsuario, err := h.tipoUsuarioRepo.ConContexto(c.UserContext()).GetTipoUsuarioByID(uint(id))
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 			"error": "Tipo de usuario no encontrado",
@@ -88,7 +88,7 @@ func (h *TipoUsuarioHandler) UpdateTipoUsuario(c *fiber.Ctx) error {
 		})
 	}
 
-	if err := h.tipoUsuarioRepo.UpdateTipoUsuario(tipoUsuario); err != nil {
+	if err := h.tipoUsuarioRepo.ConContexto(c.UserContext()).UpdateTipoUsuario(tipoUsuario); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "No se puede actualizar el tipo de usuario",
 		})
@@ -106,7 +106,7 @@ func (h *TipoUsuarioHandler) DeleteTipoUsuario(c *fiber.Ctx) error {
 		})
 	}
 
-	if err := h.tipoUsuarioRepo.DeleteTipoUsuario(uint(id)); err != nil {
+	if err := h.tipoUsuarioRepo.ConContexto(c.UserContext()).DeleteTipoUsuario(uint(id)); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "No se puede eliminar el tipo de usuario",
 		})
@@ -121,7 +121,7 @@ func (h *TipoUsuarioHandler) DeleteTipoUsuario(c *fiber.Ctx) error {
 func (h *TipoUsuarioHandler) GetTipoUsuarioByNombre(c *fiber.Ctx) error {
 	nombre := c.Params("nombre")
 	
-	tipoUsuario, err := h.tipoUsuarioRepo.GetTipoUsuarioByNombre(nombre)
+	tipoUsuario, err := h.tipoUsuarioRepo.ConContexto(c.UserContext()).GetTipoUsuarioByNombre(nombre)
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 			"error": "Tipo de usuario no encontrado",
@@ -1,20 +1,27 @@
 package handlers
 
 import (
+	"ApiEscuela/models"
+	"ApiEscuela/services"
+	"errors"
 	"fmt"
-	"mime/multipart"
-	"os"
+	"net/http"
+	"path"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
 )
 
-type UploadHandler struct{}
+type UploadHandler struct {
+	storage        services.Storage // Almacenamiento donde se guardan los archivos subidos
+	archivoService *services.ArchivoService
+}
 
-func NewUploadHandler() *UploadHandler {
-	return &UploadHandler{}
+func NewUploadHandler(storage services.Storage, archivoService *services.ArchivoService) *UploadHandler {
+	return &UploadHandler{storage: storage, archivoService: archivoService}
 }
 
 // UploadFile maneja la subida de archivos y retorna la URL
@@ -22,140 +29,227 @@ func (h *UploadHandler) UploadFile(c *fiber.Ctx) error {
 	// Obtener el archivo del formulario
 	file, err := c.FormFile("file")
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+		return c.Status(fiber.StatusBadRequest).JSON(conTraza(c, fiber.Map{
 			"error": "No se pudo obtener el archivo",
-		})
+		}))
 	}
 
 	// Verificar que el usuario esté autenticado
 	userID, ok := c.Locals("user_id").(uint)
 	if !ok {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+		return c.Status(fiber.StatusUnauthorized).JSON(conTraza(c, fiber.Map{
 			"error": "Usuario no autenticado",
 			"debug": "user_id no encontrado en locals",
-		})
-	}
-
-	// Debug: Log del userID obtenido
-	fmt.Printf("DEBUG: UserID obtenido del token: %d\n", userID)
-
-	// Validar el archivo
-	if err := h.validarArchivo(file); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": err.Error(),
-		})
+		}))
 	}
 
-	// Determinar el tipo de archivo y carpeta
-	tipoArchivo, carpetaDestino := h.determinarTipoYCarpeta(file.Header.Get("Content-Type"))
-	if carpetaDestino == "" {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Tipo de archivo no soportado",
-		})
+	// Guardar el archivo y registrar sus metadatos. El tipo y la carpeta se deciden por el contenido,
+	// no por el Content-Type que envía el cliente.
+	archivo, err := h.archivoService.ConContexto(c.UserContext()).GuardarArchivo(userID, services.ArchivoDeFormulario(file), c.FormValue("visibilidad"))
+	if err != nil {
+		return c.Status(estadoErrorSubida(err)).JSON(conTraza(c, fiber.Map{
+			"error":   "Error al guardar el archivo",
+			"details": err.Error(),
+		}))
 	}
 
-	// Generar nombre único para el archivo
-	extension := filepath.Ext(file.Filename)
-	nombreArchivo := h.generarNombreArchivo(extension)
-
-	// Crear la ruta completa
-	rutaCompleta := filepath.Join("assets", carpetaDestino, nombreArchivo)
-
-	// Crear el directorio si no existe
-	if err := os.MkdirAll(filepath.Dir(rutaCompleta), 0755); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Error al crear directorio",
-		})
+	// Los archivos públicos se sirven por la ruta abierta; los privados, con un enlace firmado temporal
+	urlArchivo := services.URLPublicaArchivo(c.BaseURL(), archivo)
+	respuesta := fiber.Map{
+		"message":     "Archivo subido exitosamente",
+		"id":          archivo.ID,
+		"tipo":        services.CategoriaPorExtension(archivo.NombreAlmacenado),
+		"tamano":      archivo.Tamano,
+		"sha256":      archivo.SHA256,
+		"visibilidad": archivo.Visibilidad,
 	}
-
-	// Guardar el archivo
-	if err := c.SaveFile(file, rutaCompleta); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Error al guardar el archivo",
-		})
+	if archivo.Visibilidad == models.VisibilidadArchivoPrivado {
+		enlace, err := h.archivoService.ConContexto(c.UserContext()).FirmarURL(archivo, c.BaseURL(), 0)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(conTraza(c, fiber.Map{
+				"error": "No se pudo generar el enlace del archivo",
+			}))
+		}
+		urlArchivo = enlace.URL
+		respuesta["expira_en"] = enlace.ExpiraEn
 	}
+	respuesta["url"] = urlArchivo
 
-	// Generar URL pública
-	baseURL := c.BaseURL()
-	urlArchivo := fmt.Sprintf("%s/api/files/%s/%s", baseURL, carpetaDestino, nombreArchivo)
-
-	return c.JSON(fiber.Map{
-		"message": "Archivo subido exitosamente",
-		"url":     urlArchivo,
-		"tipo":    tipoArchivo,
-		"tamano":  file.Size,
-	})
+	return c.JSON(respuesta)
 }
 
-// GetFile sirve archivos estáticos
+// GetFile sirve un archivo subido. Solo acepta las carpetas conocidas y nombres simples, rechaza enlaces
+// simbólicos y atiende solicitudes Range (necesarias para reproducir y adelantar videos).
 func (h *UploadHandler) GetFile(c *fiber.Ctx) error {
-	// Obtener la ruta del archivo desde los parámetros
 	tipo := c.Params("tipo")
 	nombre := c.Params("nombre")
 
 	if tipo == "" || nombre == "" {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+		return c.Status(fiber.StatusBadRequest).JSON(conTraza(c, fiber.Map{
 			"error": "Ruta de archivo no especificada",
-		})
+		}))
+	}
+
+	tipoArchivo, err := validarArchivoServido(tipo, nombre)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(conTraza(c, fiber.Map{
+			"error": "Archivo no encontrado",
+		}))
+	}
+
+	// Los archivos privados solo se sirven con un enlace firmado
+	if h.archivoService != nil {
+		privado, err := h.archivoService.ConContexto(c.UserContext()).EsPrivado(tipo, nombre)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(conTraza(c, fiber.Map{
+				"error": "Error al consultar el archivo",
+			}))
+		}
+		if privado {
+			return c.Status(fiber.StatusNotFound).JSON(conTraza(c, fiber.Map{
+				"error": "Archivo no encontrado",
+			}))
+		}
 	}
 
-	// Construir la ruta completa del archivo
-	rutaCompleta := filepath.Join("assets", tipo, nombre)
+	return h.enviarVariante(c, tipo, nombre, tipoArchivo)
+}
 
-	// Verificar que el archivo existe físicamente
-	if _, err := os.Stat(rutaCompleta); os.IsNotExist(err) {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+// GetFileFirmado sirve un archivo, público o privado, con un enlace firmado que aún no expiró
+func (h *UploadHandler) GetFileFirmado(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil || id <= 0 {
+		return c.Status(fiber.StatusNotFound).JSON(conTraza(c, fiber.Map{
 			"error": "Archivo no encontrado",
-		})
+		}))
 	}
 
-	// Servir el archivo
-	return c.SendFile(rutaCompleta)
+	archivo, err := h.archivoService.ConContexto(c.UserContext()).ArchivoFirmado(uint(id), c.Query("expira"), c.Query("firma"))
+	if err != nil {
+		status := fiber.StatusNotFound
+		if errors.Is(err, services.ErrFirmaArchivoInvalida) {
+			status = fiber.StatusForbidden
+		}
+		return c.Status(status).JSON(conTraza(c, fiber.Map{
+			"error": err.Error(),
+		}))
+	}
+
+	tipoArchivo, err := validarArchivoServido(archivo.Carpeta, archivo.NombreAlmacenado)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(conTraza(c, fiber.Map{
+			"error": "Archivo no encontrado",
+		}))
+	}
+
+	// El enlace es personal: que no quede en cachés compartidas
+	c.Set(fiber.HeaderCacheControl, "private, no-store")
+	return h.enviarVariante(c, archivo.Carpeta, archivo.NombreAlmacenado, tipoArchivo)
 }
 
-// Funciones auxiliares
+// enviarVariante envía el archivo o, con ?size= (thumb, medium...), una de sus versiones reducidas.
+// Prefiere la versión WebP si el cliente la acepta; si la variante no existe (imágenes más chicas que la
+// variante o subidas antes de generarlas) envía el original.
+func (h *UploadHandler) enviarVariante(c *fiber.Ctx, carpeta, nombre string, tipoArchivo tipoArchivoServido) error {
+	tamano := c.Query("size")
+	if tamano == "" || tamano == "original" {
+		return h.enviarArchivo(c, services.ClaveArchivo(carpeta, nombre), tipoArchivo, nombre)
+	}
+	if carpeta != "images" || !h.archivoService.VarianteConfigurada(tamano) {
+		return c.Status(fiber.StatusBadRequest).JSON(conTraza(c, fiber.Map{
+			"error":   "Tamaño no válido",
+			"details": "Use size=original o uno de los tamaños configurados para imágenes",
+		}))
+	}
 
-func (h *UploadHandler) validarArchivo(file *multipart.FileHeader) error {
-	// Validar tamaño (máximo 50MB)
-	const maxSize = 50 * 1024 * 1024
-	if file.Size > maxSize {
-		return fmt.Errorf("el archivo es demasiado grande (máximo 50MB)")
+	// La respuesta depende de Accept cuando hay variantes WebP
+	c.Vary(fiber.HeaderAccept)
+	extension := "png"
+	if tipoArchivo.contentType == "image/jpeg" {
+		extension = "jpg"
+	}
+	candidatas := []string{tamano + "." + extension}
+	if strings.Contains(c.Get(fiber.HeaderAccept), "image/webp") {
+		candidatas = append([]string{tamano + ".webp"}, candidatas...)
+	}
+	for _, sufijo := range candidatas {
+		clave := services.ClaveVariante(carpeta, nombre, sufijo)
+		if _, err := h.storage.Info(clave); err == nil {
+			return h.enviarArchivo(c, clave, tiposVariantes[filepath.Ext(sufijo)], path.Base(clave))
+		}
 	}
+	return h.enviarArchivo(c, services.ClaveArchivo(carpeta, nombre), tipoArchivo, nombre)
+}
 
-	// Validar extensión
-	extension := strings.ToLower(filepath.Ext(file.Filename))
-	extensionesPermitidas := []string{".jpg", ".jpeg", ".png", ".gif", ".mp4", ".avi", ".mov", ".pdf", ".doc", ".docx", ".txt"}
+// enviarArchivo envía el archivo ya validado con sus encabezados, respetando Range e If-Modified-Since
+func (h *UploadHandler) enviarArchivo(c *fiber.Ctx, clave string, tipoArchivo tipoArchivoServido, nombre string) error {
+	info, err := h.storage.Info(clave)
+	if err != nil {
+		if errors.Is(err, services.ErrObjetoNoEncontrado) {
+			return c.Status(fiber.StatusNotFound).JSON(conTraza(c, fiber.Map{
+				"error": "Archivo no encontrado",
+			}))
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(conTraza(c, fiber.Map{
+			"error": "Error al leer el archivo",
+		}))
+	}
+
+	c.Set(fiber.HeaderContentType, tipoArchivo.contentType)
+	c.Set(fiber.HeaderContentDisposition, contentDisposition(tipoArchivo, nombre))
+	c.Set(fiber.HeaderXContentTypeOptions, "nosniff")
+	c.Set(fiber.HeaderAcceptRanges, "bytes")
+	c.Set(fiber.HeaderLastModified, info.ModificadoEn.UTC().Format(http.TimeFormat))
 
-	permitida := false
-	for _, ext := range extensionesPermitidas {
-		if extension == ext {
-			permitida = true
-			break
+	if desde, err := http.ParseTime(c.Get(fiber.HeaderIfModifiedSince)); err == nil && !info.ModificadoEn.Truncate(time.Second).After(desde) {
+		return c.SendStatus(fiber.StatusNotModified)
+	}
+
+	tamano := info.Tamano
+	inicio, fin := int64(0), tamano-1
+	parcial := false
+	// If-Range: el rango solo vale si el archivo no cambió desde la fecha indicada
+	if rango := c.Get(fiber.HeaderRange); rango != "" && (c.Get(fiber.HeaderIfRange) == "" || c.Get(fiber.HeaderIfRange) == c.GetRespHeader(fiber.HeaderLastModified)) {
+		var ok bool
+		inicio, fin, ok, err = parsearRango(rango, tamano)
+		if err != nil {
+			c.Set(fiber.HeaderContentRange, fmt.Sprintf("bytes */%d", tamano))
+			return c.SendStatus(fiber.StatusRequestedRangeNotSatisfiable)
+		}
+		if ok {
+			parcial = true
+		} else {
+			inicio, fin = 0, tamano-1
 		}
 	}
 
-	if !permitida {
-		return fmt.Errorf("tipo de archivo no permitido")
+	longitud := fin - inicio + 1
+	contenido, err := h.storage.Abrir(clave, inicio, longitud)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(conTraza(c, fiber.Map{
+			"error": "Archivo no encontrado",
+		}))
 	}
 
-	return nil
+	if parcial {
+		c.Status(fiber.StatusPartialContent)
+		c.Set(fiber.HeaderContentRange, fmt.Sprintf("bytes %d-%d/%d", inicio, fin, tamano))
+	}
+	// fasthttp cierra el contenido al terminar de enviar el cuerpo
+	return c.SendStream(contenido, int(longitud))
 }
 
-func (h *UploadHandler) determinarTipoYCarpeta(mimeType string) (string, string) {
+// estadoErrorSubida elige el código HTTP según el motivo del rechazo
+func estadoErrorSubida(err error) int {
 	switch {
-	case strings.HasPrefix(mimeType, "image/"):
-		return "image", "images"
-	case strings.HasPrefix(mimeType, "video/"):
-		return "video", "videos"
-	case strings.HasPrefix(mimeType, "application/") || strings.HasPrefix(mimeType, "text/"):
-		return "document", "documents"
-	default:
-		return "", ""
+	case errors.Is(err, services.ErrArchivoDemasiadoGrande):
+		return fiber.StatusRequestEntityTooLarge
+	case errors.Is(err, services.ErrTipoArchivoNoPermitido), errors.Is(err, services.ErrContenidoNoCoincide):
+		return fiber.StatusUnsupportedMediaType
+	case errors.Is(err, services.ErrArchivoEjecutable), errors.Is(err, services.ErrArchivoInfectado):
+		return fiber.StatusUnprocessableEntity
+	case errors.Is(err, services.ErrAntivirusNoDisponible):
+		return fiber.StatusServiceUnavailable
 	}
-}
-
-func (h *UploadHandler) generarNombreArchivo(extension string) string {
-	timestamp := time.Now().Unix()
-	random := time.Now().UnixNano() % 10000
-	return fmt.Sprintf("%d_%d%s", timestamp, random, extension)
+	return fiber.StatusBadRequest
 }
@@ -0,0 +1,256 @@
+package handlers
+
+import (
+	"ApiEscuela/config"
+	"ApiEscuela/repositories"
+	"ApiEscuela/services"
+	"io"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+const contenidoSecreto = "SECRETO"
+
+// prepararArchivos crea un directorio de subidas con archivos válidos y, fuera de él, un archivo secreto
+// al que apuntan enlaces simbólicos
+func prepararArchivos(t *testing.T) (*fiber.App, string) {
+	t.Helper()
+	base := t.TempDir()
+	raiz := filepath.Join(base, "assets")
+	fuera := filepath.Join(base, "fuera")
+
+	archivos := map[string]string{
+		filepath.Join(raiz, "images", "foto.jpg"):     "JPEG",
+		filepath.Join(raiz, "images", ".oculto.jpg"):  contenidoSecreto,
+		filepath.Join(raiz, "images", "pagina.html"):  contenidoSecreto,
+		filepath.Join(raiz, "videos", "clip.mp4"):     "0123456789",
+		filepath.Join(raiz, "documents", "nota.txt"):  "texto",
+		filepath.Join(raiz, "config", "app.jpg"):      contenidoSecreto,
+		filepath.Join(fuera, "secreto.txt"):           contenidoSecreto,
+		filepath.Join(fuera, "secreto.jpg"):           contenidoSecreto,
+		filepath.Join(fuera, "carpeta", "otro.jpg"):   contenidoSecreto,
+		filepath.Join(base, "secreto.txt"):            contenidoSecreto,
+		filepath.Join(raiz, "documents", "vacio.pdf"): "",
+	}
+	for ruta, contenido := range archivos {
+		if err := os.MkdirAll(filepath.Dir(ruta), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(ruta, []byte(contenido), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	enlaces := map[string]string{
+		filepath.Join(raiz, "images", "enlace.jpg"):    filepath.Join(fuera, "secreto.jpg"),
+		filepath.Join(raiz, "documents", "enlace.txt"): filepath.Join(fuera, "secreto.txt"),
+	}
+	for enlace, destino := range enlaces {
+		if err := os.Symlink(destino, enlace); err != nil {
+			t.Skipf("no se pueden crear enlaces simbólicos: %v", err)
+		}
+	}
+
+	app := fiber.New()
+	// En DryRun ninguna consulta llega a PostgreSQL: ningún archivo figura como privado
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost dbname=escuela"}), &gorm.Config{
+		DryRun:               true,
+		DisableAutomaticPing: true,
+		Logger:               logger.Discard,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	storage := services.NewStorageLocal(raiz)
+	archivoService := services.NewArchivoService(repositories.NewArchivoRepository(db), repositories.NewUsuarioRepository(db), storage, nil, config.PorDefecto().Archivos, "")
+	h := NewUploadHandler(storage, archivoService)
+	app.Get("/api/files/:tipo/:nombre", h.GetFile)
+	return app, base
+}
+
+func pedir(t *testing.T, app *fiber.App, ruta string, encabezados map[string]string) (int, string, map[string]string) {
+	t.Helper()
+	req := httptest.NewRequest("GET", "http://localhost", nil)
+	// app.Test envía RequestURI tal cual, sin que net/url normalice la ruta ni vuelva a codificarla
+	req.RequestURI = ruta
+	for k, v := range encabezados {
+		req.Header.Set(k, v)
+	}
+	res, err := app.Test(req, -1)
+	if err != nil {
+		t.Fatalf("%s: %v", ruta, err)
+	}
+	defer res.Body.Close()
+	cuerpo, _ := io.ReadAll(res.Body)
+	resultado := map[string]string{}
+	for _, k := range []string{"Content-Type", "Content-Disposition", "Content-Range", "X-Content-Type-Options"} {
+		resultado[k] = res.Header.Get(k)
+	}
+	return res.StatusCode, string(cuerpo), resultado
+}
+
+func TestGetFileRechazaRutasHostiles(t *testing.T) {
+	app, _ := prepararArchivos(t)
+
+	rutas := []string{
+		"/api/files/../secreto.txt",
+		"/api/files/images/../../secreto.txt",
+		"/api/files/images/..%2F..%2Fsecreto.txt",
+		"/api/files/images/..%2f..%2ffuera%2fsecreto.txt",
+		"/api/files/images/%2e%2e%2f%2e%2e%2fsecreto.txt",
+		"/api/files/images/..%5C..%5Csecreto.txt",
+		"/api/files/..%2F..%2Ffuera/secreto.txt",
+		"/api/files/..%2Ffuera/secreto.txt",
+		"/api/files/images/%2Ftmp%2Fsecreto.txt",
+		"/api/files/images/..",
+		"/api/files/images/.oculto.jpg",
+		"/api/files/images/enlace.jpg",
+		"/api/files/documents/enlace.txt",
+		"/api/files/images/pagina.html",
+		"/api/files/images/foto.jpg%00.txt",
+		"/api/files/config/app.jpg",
+		"/api/files/fuera/secreto.jpg",
+		"/api/files/images/foto.jpg.",
+	}
+	for _, ruta := range rutas {
+		estado, cuerpo, _ := pedir(t, app, ruta, nil)
+		if estado == fiber.StatusOK || estado == fiber.StatusPartialContent {
+			t.Errorf("%s: estado %d, se esperaba un rechazo", ruta, estado)
+		}
+		if strings.Contains(cuerpo, contenidoSecreto) {
+			t.Errorf("%s: la respuesta expone un archivo fuera de las carpetas permitidas", ruta)
+		}
+	}
+}
+
+func TestGetFileRechazaCarpetaEnlazada(t *testing.T) {
+	app, base := prepararArchivos(t)
+	raiz := filepath.Join(base, "assets")
+	if err := os.RemoveAll(filepath.Join(raiz, "images")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(base, "fuera", "carpeta"), filepath.Join(raiz, "images")); err != nil {
+		t.Skipf("no se pueden crear enlaces simbólicos: %v", err)
+	}
+
+	estado, cuerpo, _ := pedir(t, app, "/api/files/images/otro.jpg", nil)
+	if estado != fiber.StatusNotFound || strings.Contains(cuerpo, contenidoSecreto) {
+		t.Errorf("estado %d: se sirvió un archivo a través de una carpeta enlazada", estado)
+	}
+}
+
+func TestGetFileEncabezados(t *testing.T) {
+	app, _ := prepararArchivos(t)
+
+	casos := []struct {
+		ruta        string
+		contentType string
+		disposicion string
+		cuerpo      string
+	}{
+		{"/api/files/images/foto.jpg", "image/jpeg", `inline; filename="foto.jpg"`, "JPEG"},
+		{"/api/files/documents/nota.txt", "text/plain; charset=utf-8", `attachment; filename="nota.txt"`, "texto"},
+		{"/api/files/documents/vacio.pdf", "application/pdf", `inline; filename="vacio.pdf"`, ""},
+	}
+	for _, caso := range casos {
+		estado, cuerpo, encabezados := pedir(t, app, caso.ruta, nil)
+		if estado != fiber.StatusOK {
+			t.Fatalf("%s: estado %d", caso.ruta, estado)
+		}
+		if encabezados["Content-Type"] != caso.contentType {
+			t.Errorf("%s: Content-Type %q, se esperaba %q", caso.ruta, encabezados["Content-Type"], caso.contentType)
+		}
+		if encabezados["Content-Disposition"] != caso.disposicion {
+			t.Errorf("%s: Content-Disposition %q, se esperaba %q", caso.ruta, encabezados["Content-Disposition"], caso.disposicion)
+		}
+		if encabezados["X-Content-Type-Options"] != "nosniff" {
+			t.Errorf("%s: falta X-Content-Type-Options: nosniff", caso.ruta)
+		}
+		if cuerpo != caso.cuerpo {
+			t.Errorf("%s: cuerpo %q, se esperaba %q", caso.ruta, cuerpo, caso.cuerpo)
+		}
+	}
+}
+
+func TestGetFileRangos(t *testing.T) {
+	app, _ := prepararArchivos(t)
+
+	casos := []struct {
+		rango        string
+		estado       int
+		cuerpo       string
+		contentRange string
+	}{
+		{"bytes=2-5", fiber.StatusPartialContent, "2345", "bytes 2-5/10"},
+		{"bytes=7-", fiber.StatusPartialContent, "789", "bytes 7-9/10"},
+		{"bytes=-3", fiber.StatusPartialContent, "789", "bytes 7-9/10"},
+		{"bytes=8-100", fiber.StatusPartialContent, "89", "bytes 8-9/10"},
+		{"bytes=0-1,4-5", fiber.StatusOK, "0123456789", ""},
+		{"bytes=10-", fiber.StatusRequestedRangeNotSatisfiable, "", "bytes */10"},
+		{"bytes=5-2", fiber.StatusRequestedRangeNotSatisfiable, "", "bytes */10"},
+		{"elementos=0-1", fiber.StatusOK, "0123456789", ""},
+	}
+	for _, caso := range casos {
+		estado, cuerpo, encabezados := pedir(t, app, "/api/files/videos/clip.mp4", map[string]string{"Range": caso.rango})
+		if estado != caso.estado {
+			t.Errorf("%s: estado %d, se esperaba %d", caso.rango, estado, caso.estado)
+			continue
+		}
+		if caso.estado != fiber.StatusRequestedRangeNotSatisfiable && cuerpo != caso.cuerpo {
+			t.Errorf("%s: cuerpo %q, se esperaba %q", caso.rango, cuerpo, caso.cuerpo)
+		}
+		if encabezados["Content-Range"] != caso.contentRange {
+			t.Errorf("%s: Content-Range %q, se esperaba %q", caso.rango, encabezados["Content-Range"], caso.contentRange)
+		}
+		if encabezados["Content-Type"] != "video/mp4" && caso.estado != fiber.StatusRequestedRangeNotSatisfiable {
+			t.Errorf("%s: Content-Type %q", caso.rango, encabezados["Content-Type"])
+		}
+	}
+}
+
+func TestGetFileVariantes(t *testing.T) {
+	app, base := prepararArchivos(t)
+	imagenes := filepath.Join(base, "assets", "images")
+	for nombre, contenido := range map[string]string{"foto.thumb.jpg": "THUMB", "foto.thumb.webp": "WEBP"} {
+		if err := os.WriteFile(filepath.Join(imagenes, nombre), []byte(contenido), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	casos := []struct {
+		ruta        string
+		accept      string
+		estado      int
+		cuerpo      string
+		contentType string
+	}{
+		{"/api/files/images/foto.jpg?size=thumb", "image/jpeg", fiber.StatusOK, "THUMB", "image/jpeg"},
+		{"/api/files/images/foto.jpg?size=thumb", "image/avif,image/webp,*/*", fiber.StatusOK, "WEBP", "image/webp"},
+		{"/api/files/images/foto.jpg?size=medium", "", fiber.StatusOK, "JPEG", "image/jpeg"},
+		{"/api/files/images/foto.jpg?size=original", "", fiber.StatusOK, "JPEG", "image/jpeg"},
+		{"/api/files/images/foto.jpg?size=enorme", "", fiber.StatusBadRequest, "", ""},
+		{"/api/files/videos/clip.mp4?size=thumb", "", fiber.StatusBadRequest, "", ""},
+		// Las variantes no se sirven directamente: solo con ?size= y tras los controles del original
+		{"/api/files/images/foto.thumb.jpg", "", fiber.StatusNotFound, "", ""},
+	}
+	for _, caso := range casos {
+		estado, cuerpo, encabezados := pedir(t, app, caso.ruta, map[string]string{"Accept": caso.accept})
+		if estado != caso.estado {
+			t.Errorf("%s (%s): estado %d, se esperaba %d", caso.ruta, caso.accept, estado, caso.estado)
+			continue
+		}
+		if caso.estado != fiber.StatusOK {
+			continue
+		}
+		if cuerpo != caso.cuerpo || encabezados["Content-Type"] != caso.contentType {
+			t.Errorf("%s (%s): %q %q, se esperaba %q %q", caso.ruta, caso.accept, cuerpo, encabezados["Content-Type"], caso.cuerpo, caso.contentType)
+		}
+	}
+}
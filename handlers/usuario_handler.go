@@ -26,7 +26,7 @@ func (h *UsuarioHandler) CreateUsuario(c *fiber.Ctx) error {
 		})
 	}
 
-	if err := h.usuarioRepo.CreateUsuario(&usuario); err != nil {
+	if err := h.usuarioRepo.ConContexto(c.UserContext()).CreateUsuario(&usuario); err != nil {
 		switch err {
 		case repositories.ErrUsuarioDuplicado:
 			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "usuario repetido"})
@@ -49,7 +49,7 @@ func (h *UsuarioHandler) GetUsuario(c *fiber.Ctx) error {
 		})
 	}
 
-	usuario, err := h.usuarioRepo.GetUsuarioByID(uint(id))
+	usuario, err := h.usuarioRepo.ConContexto(c.UserContext()).GetUsuarioByID(uint(id))
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 			"error": "Usuario no encontrado",
@@ -63,7 +63,7 @@ func (h *UsuarioHandler) GetUsuario(c *fiber.Ctx) error {
 
 // GetAllUsuarios obtiene todos los usuarios
 func (h *UsuarioHandler) GetAllUsuarios(c *fiber.Ctx) error {
-	usuarios, err := h.usuarioRepo.GetAllUsuarios()
+	usuarios, err := h.usuarioRepo.ConContexto(c.UserContext()).GetAllUsuarios()
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "No se pueden obtener los usuarios",
@@ -87,7 +87,7 @@ func (h *UsuarioHandler) UpdateUsuario(c *fiber.Ctx) error {
 		})
 	}
 
-	usuario, err := h.usuarioRepo.GetUsuarioByID(uint(id))
+	usuario, err := h.usuarioRepo.ConContexto(c.UserContext()).GetUsuarioByID(uint(id))
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 			"error": "Usuario no encontrado",
@@ -122,7 +122,7 @@ func (h *UsuarioHandler) UpdateUsuario(c *fiber.Ctx) error {
 		usuario.Verificado = *updateData.Verificado
 	}
 
-	if err := h.usuarioRepo.UpdateUsuario(usuario); err != nil {
+	if err := h.usuarioRepo.ConContexto(c.UserContext()).UpdateUsuario(usuario); err != nil {
 		switch err {
 		case repositories.ErrUsuarioDuplicado:
 			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "usuario repetido"})
@@ -145,7 +145,7 @@ func (h *UsuarioHandler) DeleteUsuario(c *fiber.Ctx) error {
 		})
 	}
 
-	if err := h.usuarioRepo.DeleteUsuario(uint(id)); err != nil {
+	if err := h.usuarioRepo.ConContexto(c.UserContext()).DeleteUsuario(uint(id)); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "No se puede eliminar el usuario",
 		})
@@ -160,7 +160,7 @@ func (h *UsuarioHandler) DeleteUsuario(c *fiber.Ctx) error {
 func (h *UsuarioHandler) GetUsuarioByUsername(c *fiber.Ctx) error {
 	username := c.Params("username")
 	
-	usuario, err := h.usuarioRepo.GetUsuarioByUsername(username)
+	usuario, err := h.usuarioRepo.ConContexto(c.UserContext()).GetUsuarioByUsername(username)
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 			"error": "Usuario no encontrado",
@@ -181,7 +181,7 @@ func (h *UsuarioHandler) GetUsuariosByTipo(c *fiber.Ctx) error {
 		})
 	}
 	
-	usuarios, err := h.usuarioRepo.GetUsuariosByTipo(uint(tipoUsuarioID))
+	usuarios, err := h.usuarioRepo.ConContexto(c.UserContext()).GetUsuariosByTipo(uint(tipoUsuarioID))
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "No se pueden obtener los usuarios",
@@ -205,7 +205,7 @@ func (h *UsuarioHandler) GetUsuariosByPersona(c *fiber.Ctx) error {
 		})
 	}
 	
-	usuarios, err := h.usuarioRepo.GetUsuariosByPersona(uint(personaID))
+	usuarios, err := h.usuarioRepo.ConContexto(c.UserContext()).GetUsuariosByPersona(uint(personaID))
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "No se pueden obtener los usuarios",
@@ -233,7 +233,7 @@ func (h *UsuarioHandler) Login(c *fiber.Ctx) error {
 		})
 	}
 
-	usuario, err := h.usuarioRepo.ValidateLogin(loginData.Usuario, loginData.Contraseña)
+	usuario, err := h.usuarioRepo.ConContexto(c.UserContext()).ValidateLogin(loginData.Usuario, loginData.Contraseña)
 	if err != nil {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 			"error": "Credenciales inválidas",
@@ -250,7 +250,7 @@ func (h *UsuarioHandler) Login(c *fiber.Ctx) error {
 
 // GetAllUsuariosIncludingDeleted obtiene todos los usuarios incluyendo eliminados
 func (h *UsuarioHandler) GetAllUsuariosIncludingDeleted(c *fiber.Ctx) error {
-	usuarios, err := h.usuarioRepo.GetAllUsuariosIncludingDeleted()
+	usuarios, err := h.usuarioRepo.ConContexto(c.UserContext()).GetAllUsuariosIncludingDeleted()
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "No se pueden obtener los usuarios",
@@ -267,7 +267,7 @@ func (h *UsuarioHandler) GetAllUsuariosIncludingDeleted(c *fiber.Ctx) error {
 
 // GetDeletedUsuarios obtiene solo los usuarios eliminados
 func (h *UsuarioHandler) GetDeletedUsuarios(c *fiber.Ctx) error {
-	usuarios, err := h.usuarioRepo.GetDeletedUsuarios()
+	usuarios, err := h.usuarioRepo.ConContexto(c.UserContext()).GetDeletedUsuarios()
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "No se pueden obtener los usuarios eliminados",
@@ -292,7 +292,7 @@ func (h *UsuarioHandler) RestoreUsuario(c *fiber.Ctx) error {
 	}
 
 	// Verificar que el usuario existe (incluyendo eliminados)
-	usuario, err := h.usuarioRepo.GetUsuarioByIDIncludingDeleted(uint(id))
+	usuario, err := h.usuarioRepo.ConContexto(c.UserContext()).GetUsuarioByIDIncludingDeleted(uint(id))
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 			"error": "Usuario no encontrado",
@@ -306,7 +306,7 @@ func (h *UsuarioHandler) RestoreUsuario(c *fiber.Ctx) error {
 		})
 	}
 
-	if err := h.usuarioRepo.RestoreUsuario(uint(id)); err != nil {
+	if err := h.usuarioRepo.ConContexto(c.UserContext()).RestoreUsuario(uint(id)); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "No se puede restaurar el usuario",
 		})
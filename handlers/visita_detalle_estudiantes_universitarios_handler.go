@@ -3,6 +3,7 @@ package handlers
 import (
 	"ApiEscuela/models"
 	"ApiEscuela/repositories"
+	"ApiEscuela/services"
 	"strconv"
 
 	"github.com/gofiber/fiber/v2"
@@ -10,10 +11,11 @@ import (
 
 type VisitaDetalleEstudiantesUniversitariosHandler struct {
 	visitaDetalleEstudiantesRepo *repositories.VisitaDetalleEstudiantesUniversitariosRepository
+	calendarioService            *services.CalendarioService
 }
 
-func NewVisitaDetalleEstudiantesUniversitariosHandler(visitaDetalleEstudiantesRepo *repositories.VisitaDetalleEstudiantesUniversitariosRepository) *VisitaDetalleEstudiantesUniversitariosHandler {
-	return &VisitaDetalleEstudiantesUniversitariosHandler{visitaDetalleEstudiantesRepo: visitaDetalleEstudiantesRepo}
+func NewVisitaDetalleEstudiantesUniversitariosHandler(visitaDetalleEstudiantesRepo *repositories.VisitaDetalleEstudiantesUniversitariosRepository, calendarioService *services.CalendarioService) *VisitaDetalleEstudiantesUniversitariosHandler {
+	return &VisitaDetalleEstudiantesUniversitariosHandler{visitaDetalleEstudiantesRepo: visitaDetalleEstudiantesRepo, calendarioService: calendarioService}
 }
 
 // CreateVisitaDetalleEstudiantesUniversitarios crea una nueva relación entre estudiante universitario y programa de visita
@@ -27,7 +29,7 @@ func (h *VisitaDetalleEstudiantesUniversitariosHandler) CreateVisitaDetalleEstud
 	}
 
 	// Verificar si la relación ya existe
-	exists, err := h.visitaDetalleEstudiantesRepo.ExistsRelation(relacion.EstudianteUniversitarioID, relacion.ProgramaVisitaID)
+	exists, err := h.visitaDetalleEstudiantesRepo.ConContexto(c.UserContext()).ExistsRelation(relacion.EstudianteUniversitarioID, relacion.ProgramaVisitaID)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Error al verificar la relación existente",
@@ -40,12 +42,15 @@ func (h *VisitaDetalleEstudiantesUniversitariosHandler) CreateVisitaDetalleEstud
 		})
 	}
 
-	if err := h.visitaDetalleEstudiantesRepo.CreateVisitaDetalleEstudiantesUniversitarios(&relacion); err != nil {
+	if err := h.visitaDetalleEstudiantesRepo.ConContexto(c.UserContext()).CreateVisitaDetalleEstudiantesUniversitarios(&relacion); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "No se puede crear la relación",
 		})
 	}
 
+	// Enviar la invitación de calendario al estudiante asignado
+	h.calendarioService.ConContexto(c.UserContext()).NotificarAsignacionEstudiante(c.UserContext(), relacion.ID)
+
 	return c.Status(fiber.StatusCreated).JSON(relacion)
 }
 
@@ -58,7 +63,7 @@ func (h *VisitaDetalleEstudiantesUniversitariosHandler) GetVisitaDetalleEstudian
 		})
 	}
 
-	relacion, err := h.visitaDetalleEstudiantesRepo.GetVisitaDetalleEstudiantesUniversitariosByID(uint(id))
+	relacion, err := h.visitaDetalleEstudiantesRepo.ConContexto(c.UserContext()).GetVisitaDetalleEstudiantesUniversitariosByID(uint(id))
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 			"error": "Relación no encontrada",
@@ -70,7 +75,7 @@ func (h *VisitaDetalleEstudiantesUniversitariosHandler) GetVisitaDetalleEstudian
 
 // GetAllVisitaDetalleEstudiantesUniversitarios obtiene todas las relaciones
 func (h *VisitaDetalleEstudiantesUniversitariosHandler) GetAllVisitaDetalleEstudiantesUniversitarios(c *fiber.Ctx) error {
-	relaciones, err := h.visitaDetalleEstudiantesRepo.GetAllVisitaDetalleEstudiantesUniversitarios()
+	relaciones, err := h.visitaDetalleEstudiantesRepo.ConContexto(c.UserContext()).GetAllVisitaDetalleEstudiantesUniversitarios()
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "No se pueden obtener las relaciones",
@@ -89,7 +94,7 @@ func (h *VisitaDetalleEstudiantesUniversitariosHandler) UpdateVisitaDetalleEstud
 		})
 	}
 
-	relacion, err := h.visitaDetalleEstudiantesRepo.GetVisitaDetalleEstudiantesUniversitariosByID(uint(id))
+	relacion, err := h.visitaDetalleEstudiantesRepo.ConContexto(c.UserContext()).GetVisitaDetalleEstudiantesUniversitariosByID(uint(id))
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 			"error": "Relación no encontrada",
@@ -102,12 +107,14 @@ func (h *VisitaDetalleEstudiantesUniversitariosHandler) UpdateVisitaDetalleEstud
 		})
 	}
 
-	if err := h.visitaDetalleEstudiantesRepo.UpdateVisitaDetalleEstudiantesUniversitarios(relacion); err != nil {
+	if err := h.visitaDetalleEstudiantesRepo.ConContexto(c.UserContext()).UpdateVisitaDetalleEstudiantesUniversitarios(relacion); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "No se puede actualizar la relación",
 		})
 	}
 
+	h.calendarioService.ConContexto(c.UserContext()).NotificarAsignacionEstudiante(c.UserContext(), relacion.ID)
+
 	return c.JSON(relacion)
 }
 
@@ -120,12 +127,15 @@ func (h *VisitaDetalleEstudiantesUniversitariosHandler) DeleteVisitaDetalleEstud
 		})
 	}
 
-	if err := h.visitaDetalleEstudiantesRepo.DeleteVisitaDetalleEstudiantesUniversitarios(uint(id)); err != nil {
+	if err := h.visitaDetalleEstudiantesRepo.ConContexto(c.UserContext()).DeleteVisitaDetalleEstudiantesUniversitarios(uint(id)); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "No se puede eliminar la relación",
 		})
 	}
 
+	// Enviar la cancelación para que la visita desaparezca del calendario
+	h.calendarioService.ConContexto(c.UserContext()).NotificarAsignacionEstudiante(c.UserContext(), uint(id))
+
 	return c.JSON(fiber.Map{
 		"message": "Relación eliminada exitosamente",
 	})
@@ -140,7 +150,7 @@ func (h *VisitaDetalleEstudiantesUniversitariosHandler) GetEstudiantesByPrograma
 		})
 	}
 	
-	relaciones, err := h.visitaDetalleEstudiantesRepo.GetEstudiantesByProgramaVisita(uint(programaVisitaID))
+	relaciones, err := h.visitaDetalleEstudiantesRepo.ConContexto(c.UserContext()).GetEstudiantesByProgramaVisita(uint(programaVisitaID))
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "No se pueden obtener los estudiantes",
@@ -159,7 +169,7 @@ func (h *VisitaDetalleEstudiantesUniversitariosHandler) GetProgramasVisitaByEstu
 		})
 	}
 	
-	relaciones, err := h.visitaDetalleEstudiantesRepo.GetProgramasVisitaByEstudiante(uint(estudianteID))
+	relaciones, err := h.visitaDetalleEstudiantesRepo.ConContexto(c.UserContext()).GetProgramasVisitaByEstudiante(uint(estudianteID))
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "No se pueden obtener los programas de visita",
@@ -178,12 +188,19 @@ func (h *VisitaDetalleEstudiantesUniversitariosHandler) DeleteByProgramaVisita(c
 		})
 	}
 
-	if err := h.visitaDetalleEstudiantesRepo.DeleteByProgramaVisita(uint(programaVisitaID)); err != nil {
+	// Guardar las asignaciones antes de eliminarlas para notificar la cancelación
+	eliminadas, _ := h.visitaDetalleEstudiantesRepo.ConContexto(c.UserContext()).GetEstudiantesByProgramaVisita(uint(programaVisitaID))
+
+	if err := h.visitaDetalleEstudiantesRepo.ConContexto(c.UserContext()).DeleteByProgramaVisita(uint(programaVisitaID)); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "No se pueden eliminar las relaciones",
 		})
 	}
 
+	for _, relacion := range eliminadas {
+		h.calendarioService.ConContexto(c.UserContext()).NotificarAsignacionEstudiante(c.UserContext(), relacion.ID)
+	}
+
 	return c.JSON(fiber.Map{
 		"message": "Todas las relaciones del programa de visita eliminadas exitosamente",
 	})
@@ -198,12 +215,19 @@ func (h *VisitaDetalleEstudiantesUniversitariosHandler) DeleteByEstudiante(c *fi
 		})
 	}
 
-	if err := h.visitaDetalleEstudiantesRepo.DeleteByEstudiante(uint(estudianteID)); err != nil {
+	// Guardar las asignaciones antes de eliminarlas para notificar la cancelación
+	eliminadas, _ := h.visitaDetalleEstudiantesRepo.ConContexto(c.UserContext()).GetProgramasVisitaByEstudiante(uint(estudianteID))
+
+	if err := h.visitaDetalleEstudiantesRepo.ConContexto(c.UserContext()).DeleteByEstudiante(uint(estudianteID)); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "No se pueden eliminar las relaciones",
 		})
 	}
 
+	for _, relacion := range eliminadas {
+		h.calendarioService.ConContexto(c.UserContext()).NotificarAsignacionEstudiante(c.UserContext(), relacion.ID)
+	}
+
 	return c.JSON(fiber.Map{
 		"message": "Todas las relaciones del estudiante eliminadas exitosamente",
 	})
@@ -211,7 +235,7 @@ func (h *VisitaDetalleEstudiantesUniversitariosHandler) DeleteByEstudiante(c *fi
 
 // GetEstadisticasParticipacion obtiene estadísticas de participación de estudiantes
 func (h *VisitaDetalleEstudiantesUniversitariosHandler) GetEstadisticasParticipacion(c *fiber.Ctx) error {
-	estadisticas, err := h.visitaDetalleEstudiantesRepo.GetEstadisticasParticipacion()
+	estadisticas, err := h.visitaDetalleEstudiantesRepo.ConContexto(c.UserContext()).GetEstadisticasParticipacion()
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "No se pueden obtener las estadísticas",
@@ -27,7 +27,7 @@ func (h *VisitaDetalleHandler) CreateVisitaDetalle(c *fiber.Ctx) error {
 	}
 
 	// Verificar si la relación ya existe
-	exists, err := h.visitaDetalleRepo.ExistsRelation(detalle.ProgramaVisitaID, detalle.ActividadID)
+	exists, err := h.visitaDetalleRepo.ConContexto(c.UserContext()).ExistsRelation(detalle.ProgramaVisitaID, detalle.ActividadID)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Error al verificar la relación existente",
@@ -40,7 +40,7 @@ func (h *VisitaDetalleHandler) CreateVisitaDetalle(c *fiber.Ctx) error {
 		})
 	}
 
-	if err := h.visitaDetalleRepo.CreateVisitaDetalle(&detalle); err != nil {
+	if err := h.visitaDetalleRepo.ConContexto(c.UserContext()).CreateVisitaDetalle(&detalle); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "No se puede crear el detalle de visita",
 		})
@@ -58,7 +58,7 @@ func (h *VisitaDetalleHandler) GetVisitaDetalle(c *fiber.Ctx) error {
 		})
 	}
 
-	detalle, err := h.visitaDetalleRepo.GetVisitaDetalleByID(uint(id))
+	detalle, err := h.visitaDetalleRepo.ConContexto(c.UserContext()).GetVisitaDetalleByID(uint(id))
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 			"error": "Detalle de visita no encontrado",
@@ -70,7 +70,7 @@ func (h *VisitaDetalleHandler) GetVisitaDetalle(c *fiber.Ctx) error {
 
 // GetAllVisitaDetalles obtiene todos los detalles de visita
 func (h *VisitaDetalleHandler) GetAllVisitaDetalles(c *fiber.Ctx) error {
-	detalles, err := h.visitaDetalleRepo.GetAllVisitaDetalles()
+	detalles, err := h.visitaDetalleRepo.ConContexto(c.UserContext()).GetAllVisitaDetalles()
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "No se pueden obtener los detalles de visita",
@@ -89,7 +89,7 @@ func (h *VisitaDetalleHandler) UpdateVisitaDetalle(c *fiber.Ctx) error {
 		})
 	}
 
-	detalle, err := h.visitaDetalleRepo.GetVisitaDetalleByID(uint(id))
+	detalle, err := h.visitaDetalleRepo.ConContexto(c.UserContext()).GetVisitaDetalleByID(uint(id))
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 			"error": "Detalle de visita no encontrado",
@@ -102,7 +102,7 @@ func (h *VisitaDetalleHandler) UpdateVisitaDetalle(c *fiber.Ctx) error {
 		})
 	}
 
-	if err := h.visitaDetalleRepo.UpdateVisitaDetalle(detalle); err != nil {
+	if err := h.visitaDetalleRepo.ConContexto(c.UserContext()).UpdateVisitaDetalle(detalle); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "No se puede actualizar el detalle de visita",
 		})
@@ -120,7 +120,7 @@ func (h *VisitaDetalleHandler) DeleteVisitaDetalle(c *fiber.Ctx) error {
 		})
 	}
 
-	if err := h.visitaDetalleRepo.DeleteVisitaDetalle(uint(id)); err != nil {
+	if err := h.visitaDetalleRepo.ConContexto(c.UserContext()).DeleteVisitaDetalle(uint(id)); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "No se puede eliminar el detalle de visita",
 		})
@@ -140,7 +140,7 @@ func (h *VisitaDetalleHandler) GetVisitaDetallesByActividad(c *fiber.Ctx) error
 		})
 	}
 	
-	detalles, err := h.visitaDetalleRepo.GetVisitaDetallesByActividad(uint(actividadID))
+	detalles, err := h.visitaDetalleRepo.ConContexto(c.UserContext()).GetVisitaDetallesByActividad(uint(actividadID))
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "No se pueden obtener los detalles de visita",
@@ -159,7 +159,7 @@ func (h *VisitaDetalleHandler) GetVisitaDetallesByPrograma(c *fiber.Ctx) error {
 		})
 	}
 	
-	detalles, err := h.visitaDetalleRepo.GetVisitaDetallesByPrograma(uint(programaID))
+	detalles, err := h.visitaDetalleRepo.ConContexto(c.UserContext()).GetVisitaDetallesByPrograma(uint(programaID))
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "No se pueden obtener los detalles de visita",
@@ -178,7 +178,7 @@ func (h *VisitaDetalleHandler) DeleteVisitaDetallesByPrograma(c *fiber.Ctx) erro
 		})
 	}
 
-	if err := h.visitaDetalleRepo.DeleteVisitaDetallesByPrograma(uint(programaID)); err != nil {
+	if err := h.visitaDetalleRepo.ConContexto(c.UserContext()).DeleteVisitaDetallesByPrograma(uint(programaID)); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "No se pueden eliminar los detalles",
 		})
@@ -198,7 +198,7 @@ func (h *VisitaDetalleHandler) DeleteVisitaDetallesByActividad(c *fiber.Ctx) err
 		})
 	}
 
-	if err := h.visitaDetalleRepo.DeleteVisitaDetallesByActividad(uint(actividadID)); err != nil {
+	if err := h.visitaDetalleRepo.ConContexto(c.UserContext()).DeleteVisitaDetallesByActividad(uint(actividadID)); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "No se pueden eliminar los detalles",
 		})
@@ -211,7 +211,7 @@ func (h *VisitaDetalleHandler) DeleteVisitaDetallesByActividad(c *fiber.Ctx) err
 
 // GetEstadisticasActividades obtiene estadísticas de actividades en visitas
 func (h *VisitaDetalleHandler) GetEstadisticasActividades(c *fiber.Ctx) error {
-	estadisticas, err := h.visitaDetalleRepo.GetEstadisticasActividades()
+	estadisticas, err := h.visitaDetalleRepo.ConContexto(c.UserContext()).GetEstadisticasActividades()
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "No se pueden obtener las estadísticas",
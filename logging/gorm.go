@@ -0,0 +1,101 @@
+package logging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// consultaLentaGORM es la duración a partir de la cual una consulta se registra como advertencia
+const consultaLentaGORM = 200 * time.Millisecond
+
+// LoggerGORM envía los mensajes y las consultas de GORM al log estructurado, con el request_id y la traza
+// de la petición. Las consultas se registran con los marcadores ($1, $2...) y sin los valores, que pueden
+// tener contraseñas, cédulas o correos.
+type LoggerGORM struct {
+	logger *slog.Logger
+	config gormlogger.Config
+}
+
+// NuevoLoggerGORM crea el logger de GORM: los errores y las consultas lentas salen con nivel error y warn,
+// y el resto de las consultas solo con LOG_LEVEL=debug
+func NuevoLoggerGORM(logger *slog.Logger) *LoggerGORM {
+	return &LoggerGORM{
+		logger: logger,
+		config: gormlogger.Config{
+			SlowThreshold:             consultaLentaGORM,
+			LogLevel:                  gormlogger.Info,
+			IgnoreRecordNotFoundError: true,
+			ParameterizedQueries:      true,
+		},
+	}
+}
+
+func (l *LoggerGORM) LogMode(nivel gormlogger.LogLevel) gormlogger.Interface {
+	copia := *l
+	copia.config.LogLevel = nivel
+	return &copia
+}
+
+func (l *LoggerGORM) Info(ctx context.Context, mensaje string, datos ...interface{}) {
+	if l.config.LogLevel >= gormlogger.Info {
+		l.logger.InfoContext(ctx, fmt.Sprintf(mensaje, datos...), "origen", "gorm")
+	}
+}
+
+func (l *LoggerGORM) Warn(ctx context.Context, mensaje string, datos ...interface{}) {
+	if l.config.LogLevel >= gormlogger.Warn {
+		l.logger.WarnContext(ctx, fmt.Sprintf(mensaje, datos...), "origen", "gorm")
+	}
+}
+
+func (l *LoggerGORM) Error(ctx context.Context, mensaje string, datos ...interface{}) {
+	if l.config.LogLevel >= gormlogger.Error {
+		l.logger.ErrorContext(ctx, fmt.Sprintf(mensaje, datos...), "origen", "gorm")
+	}
+}
+
+// Trace registra una consulta terminada; fc arma el SQL solo si el registro se va a escribir
+func (l *LoggerGORM) Trace(ctx context.Context, inicio time.Time, fc func() (string, int64), err error) {
+	if l.config.LogLevel <= gormlogger.Silent {
+		return
+	}
+	duracion := time.Since(inicio)
+	var nivel slog.Level
+	var mensaje string
+	switch {
+	case err != nil && l.config.LogLevel >= gormlogger.Error && !(l.config.IgnoreRecordNotFoundError && errors.Is(err, gormlogger.ErrRecordNotFound)):
+		nivel, mensaje = slog.LevelError, "error en la consulta"
+	case l.config.SlowThreshold > 0 && duracion > l.config.SlowThreshold && l.config.LogLevel >= gormlogger.Warn:
+		nivel, mensaje = slog.LevelWarn, "consulta lenta"
+	case l.config.LogLevel >= gormlogger.Info:
+		nivel, mensaje = slog.LevelDebug, "consulta"
+	default:
+		return
+	}
+	if !l.logger.Enabled(ctx, nivel) {
+		return
+	}
+
+	sql, filas := fc()
+	atributos := []any{"sql", sql, "duracion_ms", float64(duracion.Microseconds()) / 1000}
+	if filas >= 0 {
+		atributos = append(atributos, "filas", filas)
+	}
+	if nivel == slog.LevelError {
+		atributos = append(atributos, "error", err)
+	}
+	l.logger.Log(ctx, nivel, mensaje, atributos...)
+}
+
+// ParamsFilter hace que GORM arme el SQL del log sin los valores de los parámetros
+func (l *LoggerGORM) ParamsFilter(ctx context.Context, sql string, params ...interface{}) (string, []interface{}) {
+	if l.config.ParameterizedQueries {
+		return sql, nil
+	}
+	return sql, params
+}
@@ -0,0 +1,77 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+type ciudadPrueba struct {
+	ID     uint
+	Nombre string
+}
+
+// dbConLogger construye las consultas sin ejecutarlas y las registra con LoggerGORM en salida
+func dbConLogger(t *testing.T, salida *bytes.Buffer, nivel string) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost dbname=escuela"}), &gorm.Config{
+		DryRun:               true,
+		DisableAutomaticPing: true,
+		Logger:               NuevoLoggerGORM(Nuevo(salida, nivel, "json")),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestLoggerGORMOmiteLosParametros(t *testing.T) {
+	var salida bytes.Buffer
+	db := dbConLogger(t, &salida, "debug")
+
+	ctx := ConRequestID(context.Background(), "abc-123")
+	db.WithContext(ctx).Where("nombre = ?", "Quevedo").Find(&[]ciudadPrueba{})
+
+	var registro map[string]any
+	if err := json.Unmarshal(salida.Bytes(), &registro); err != nil {
+		t.Fatalf("%v: %s", err, salida.String())
+	}
+	if registro["level"] != "DEBUG" || registro["request_id"] != "abc-123" {
+		t.Errorf("registro inesperado: %v", registro)
+	}
+	sql, _ := registro["sql"].(string)
+	if !strings.Contains(sql, "nombre = $1") || strings.Contains(salida.String(), "Quevedo") {
+		t.Errorf("la consulta debería registrarse con el marcador y sin el valor: %s", salida.String())
+	}
+}
+
+func TestLoggerGORMRegistraErroresSinDebug(t *testing.T) {
+	var salida bytes.Buffer
+	db := dbConLogger(t, &salida, "info")
+
+	db.Find(&[]ciudadPrueba{})
+	if salida.Len() != 0 {
+		t.Fatalf("con nivel info no se esperaban las consultas correctas: %s", salida.String())
+	}
+
+	err := db.Callback().Query().After("gorm:query").Register("prueba:error", func(d *gorm.DB) {
+		d.AddError(errors.New("relation \"ciudad_pruebas\" does not exist"))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.Find(&[]ciudadPrueba{})
+	var registro map[string]any
+	if err := json.Unmarshal(salida.Bytes(), &registro); err != nil {
+		t.Fatalf("%v: %s", err, salida.String())
+	}
+	if registro["level"] != "ERROR" || !strings.Contains(registro["error"].(string), "does not exist") {
+		t.Errorf("registro inesperado: %v", registro)
+	}
+}
@@ -0,0 +1,61 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestOcultarDatosSensibles(t *testing.T) {
+	var salida bytes.Buffer
+	logger := Nuevo(&salida, "info", "json")
+
+	logger.Info("no se pudo enviar a ana@escuela.edu.ec",
+		"smtp_pass", "secreto123",
+		"cedula", "0102030405",
+		"destino", "ana@escuela.edu.ec",
+		"error", errors.New("dial postgres://admin:clave123@db:5432/escuela"),
+		"asunto", "Código 0912345678 para Bearer abc.def",
+	)
+
+	linea := salida.String()
+	for _, filtrado := range []string{"secreto123", "0102030405", "ana@escuela.edu.ec", "clave123", "0912345678", "abc.def"} {
+		if strings.Contains(linea, filtrado) {
+			t.Errorf("el log contiene %q: %s", filtrado, linea)
+		}
+	}
+	if !strings.Contains(linea, "postgres://admin:"+Redactado+"@db:5432") {
+		t.Errorf("la URL de la base de datos debería conservar el host: %s", linea)
+	}
+}
+
+func TestRequestIDEnElLog(t *testing.T) {
+	var salida bytes.Buffer
+	logger := Nuevo(&salida, "warn", "json")
+
+	logger.InfoContext(ConRequestID(context.Background(), "abc-123"), "se descarta por el nivel")
+	if salida.Len() != 0 {
+		t.Fatalf("con nivel warn no se esperaban registros info: %s", salida.String())
+	}
+
+	logger.WarnContext(ConRequestID(context.Background(), "abc-123"), "petición lenta")
+	var registro map[string]any
+	if err := json.Unmarshal(salida.Bytes(), &registro); err != nil {
+		t.Fatal(err)
+	}
+	if registro["request_id"] != "abc-123" || registro["level"] != "WARN" {
+		t.Errorf("registro inesperado: %v", registro)
+	}
+}
+
+func TestParsearNivel(t *testing.T) {
+	if ParsearNivel("DEBUG").String() != "DEBUG" || ParsearNivel("error").String() != "ERROR" {
+		t.Error("no se reconocieron los niveles")
+	}
+	if ParsearNivel("verboso").String() != "INFO" {
+		t.Error("un nivel desconocido debería equivaler a info")
+	}
+}
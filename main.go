@@ -1,36 +1,70 @@
 package main
 
 import (
+	"ApiEscuela/config"
 	"ApiEscuela/handlers"
+	"ApiEscuela/logging"
+	"ApiEscuela/metricas"
+	"ApiEscuela/middleware"
 	"ApiEscuela/models"
 	"ApiEscuela/repositories"
 	"ApiEscuela/routers"
 	"ApiEscuela/services"
-	"log"
+	"ApiEscuela/trazas"
+	"context"
+	"log/slog"
 	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
-	"github.com/joho/godotenv"
-	"github.com/spf13/viper"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
 
 func main() {
-	// Cargar variables de entorno desde .env (opcional para desarrollo)
-	err := godotenv.Load()
+	// Configuración tipada: se valida completa antes de abrir conexiones y, si falla, la API no arranca
+	cfg, err := config.Cargar()
 	if err != nil {
-		log.Printf("Info: No se encontró archivo .env, usando variables de entorno del sistema")
+		logging.Configurar("info", "json")
+		slog.Error("configuración inválida", "error", err)
+		os.Exit(1)
+	}
+
+	// Logs estructurados: el nivel y el formato se pueden cambiar sin recompilar
+	logger := logging.Configurar(cfg.LogNivel, cfg.LogFormato)
+	for _, advertencia := range cfg.Advertencias() {
+		slog.Warn(advertencia)
+	}
+	middleware.ConfigurarJWT(cfg.JWTSecret)
+
+	// Trazas de OpenTelemetry (otlp, stdout o none); al apagar se envían los spans pendientes
+	detenerTrazas, err := trazas.Configurar(context.Background(), cfg.TrazasExportador)
+	if err != nil {
+		slog.Error("error al configurar las trazas", "error", err)
+		os.Exit(1)
 	}
 
 	// Inicializar Fiber
 	app := fiber.New(fiber.Config{
 		AppName: "ApiEscuela v1.0",
 		// Configurar para aceptar JSON automáticamente
-		BodyLimit: 4 * 1024 * 1024, // 4MB
+		BodyLimit:    4 * 1024 * 1024, // 4MB
+		ErrorHandler: handlers.ManejarError,
 	})
 
+	// Identificador de petición y log de acceso; va primero para cubrir a todos los demás
+	app.Use(middleware.RequestID())
+
+	// Métricas de Prometheus por ruta registrada, publicadas en /metrics
+	app.Use(middleware.Metricas())
+
+	// Un span por petición; continúa la traza del cliente si envía traceparent
+	app.Use(middleware.Trazas())
+
 	// Middleware para detectar JSON automáticamente
 	app.Use(func(c *fiber.Ctx) error {
 		// Si el body parece JSON pero no tiene Content-Type, lo establecemos
@@ -46,74 +80,46 @@ func main() {
 
 	// Configurar CORS
 	app.Use(cors.New(cors.Config{
-		AllowOrigins: "*",
-		AllowMethods: "GET,POST,HEAD,PUT,DELETE,PATCH,OPTIONS",
-		AllowHeaders: "Origin, Content-Type, Accept, Authorization",
+		AllowOrigins:  "*",
+		AllowMethods:  "GET,POST,HEAD,PUT,DELETE,PATCH,OPTIONS",
+		AllowHeaders:  "Origin, Content-Type, Accept, Authorization, X-Request-ID, traceparent, tracestate, Tus-Resumable, Upload-Length, Upload-Offset, Upload-Metadata, Upload-Checksum",
+		ExposeHeaders: "Location, X-Request-ID, X-Trace-ID, Tus-Resumable, Tus-Version, Tus-Extension, Tus-Max-Size, Tus-Checksum-Algorithm, Upload-Offset, Upload-Length, Upload-Expires, Upload-Archivo-Id",
 	}))
 
-	// Configurar Viper
-	config := viper.New()
-	config.AutomaticEnv()
-	config.SetDefault("APP_PORT", "3000")
-	config.SetDefault("APP_ENV", "development")
-
-	config.SetConfigName("config")
-	config.SetConfigType("env")
-	config.AddConfigPath(".")
-	config.AddConfigPath("/etc/secrets/")
-
-	if err := config.ReadInConfig(); err != nil {
-		log.Printf("Advertencia: No se pudo leer el archivo de configuración. %v", err)
+	// Configurar conexión a la base de datos; GORM registra en el log estructurado, sin los valores de las consultas
+	db, err := gorm.Open(postgres.Open(cfg.DatabaseURL), &gorm.Config{Logger: logging.NuevoLoggerGORM(logger)})
+	if err != nil {
+		slog.Error("error al conectar con la base de datos", "error", err)
+		os.Exit(1)
 	}
 
-	// Configurar conexión a la base de datos
-	// Obtener configuración de base de datos desde variables de entorno
-	databaseURL := os.Getenv("DATABASE_URL")
-	if databaseURL == "" {
-		log.Fatal("DATABASE_URL no está definida. Configure la variable de entorno DATABASE_URL en su archivo .env")
+	// Medir la duración y los errores de las consultas y el estado del pool de conexiones, y trazar
+	// las consultas hechas con el contexto de una petición
+	if err := db.Use(metricas.PluginGORM{}); err != nil {
+		slog.Error("error al instalar las métricas de la base de datos", "error", err)
+		os.Exit(1)
 	}
-
-	// Debug: Mostrar la URL (sin la contraseña por seguridad)
-	debugURL := databaseURL
-	if len(debugURL) > 50 {
-		debugURL = debugURL[:50] + "..."
+	if err := db.Use(trazas.PluginGORM{}); err != nil {
+		slog.Error("error al instalar las trazas de la base de datos", "error", err)
+		os.Exit(1)
 	}
-	log.Printf("Usando DATABASE_URL para conexión a la base de datos: %s", debugURL)
-
-	// Debug: Verificar otras variables de entorno
-	log.Printf("Debug - Variables de entorno:")
-	log.Printf("  DATABASE_URL length: %d", len(databaseURL))
-	log.Printf("  DB_HOST: %s", os.Getenv("DB_HOST"))
-	log.Printf("  DB_USER: %s", os.Getenv("DB_USER"))
-	log.Printf("  DB_NAME: %s", os.Getenv("DB_NAME"))
-
-	db, err := gorm.Open(postgres.Open(databaseURL), &gorm.Config{})
+	sqlDB, err := db.DB()
 	if err != nil {
-		log.Fatalf("Error al conectar con la base de datos: %v", err)
+		slog.Error("error al obtener el pool de conexiones", "error", err)
+		os.Exit(1)
 	}
+	metricas.RegistrarPool(sqlDB, "postgres")
+
+	// Ajustar el pool: DB_MAX_OPEN_CONNS en 0 deja las conexiones sin límite
+	sqlDB.SetMaxOpenConns(cfg.DB.MaxConexiones)
+	sqlDB.SetMaxIdleConns(cfg.DB.MaxConexionesInactivas)
+	sqlDB.SetConnMaxLifetime(cfg.DB.VidaMaximaConexion)
+	sqlDB.SetConnMaxIdleTime(cfg.DB.InactividadMaximaConexion)
 
 	// Automigración de todos los modelos
-	if err := db.AutoMigrate(
-		&models.Provincia{},
-		&models.Ciudad{},
-		&models.Persona{},
-		&models.TipoUsuario{},
-		&models.Usuario{},
-		&models.Institucion{},
-		&models.Estudiante{},
-		&models.EstudianteUniversitario{},
-		&models.AutoridadUTEQ{},
-		&models.Tematica{},
-		&models.Actividad{},
-		&models.ProgramaVisita{},
-		&models.DetalleAutoridadDetallesVisita{},
-		&models.VisitaDetalle{},
-		&models.Dudas{},
-		&models.VisitaDetalleEstudiantesUniversitarios{},
-		&models.CodigoUsuario{},
-		&models.Noticia{},
-	); err != nil {
-		log.Fatalf("Error en la automigración: %v", err)
+	if err := db.AutoMigrate(models.Modelos()...); err != nil {
+		slog.Error("error en la automigración", "error", err)
+		os.Exit(1)
 	}
 
 	// Inicializar repositorios
@@ -129,6 +135,7 @@ func main() {
 	tematicaRepo := repositories.NewTematicaRepository(db)
 	actividadRepo := repositories.NewActividadRepository(db)
 	programaVisitaRepo := repositories.NewProgramaVisitaRepository(db)
+	plantillaProgramaVisitaRepo := repositories.NewPlantillaProgramaVisitaRepository(db)
 	detalleAutoridadDetallesVisitaRepo := repositories.NewDetalleAutoridadDetallesVisitaRepository(db)
 	visitaDetalleRepo := repositories.NewVisitaDetalleRepository(db)
 	dudasRepo := repositories.NewDudasRepository(db)
@@ -137,12 +144,70 @@ func main() {
 
 	// Ejecutar migración manual para la tabla de códigos
 	if err := codigoUsuarioRepo.MigrarColumnaExpiraEn(); err != nil {
-		log.Printf("Advertencia: Error al migrar tabla de códigos: %v", err)
+		slog.Warn("error al migrar tabla de códigos", "error", err)
 	} else {
-		log.Printf("Migración de tabla de códigos completada exitosamente")
+		slog.Info("migración de tabla de códigos completada")
+	}
+
+	// Copiar al hilo las respuestas de las dudas anteriores a los mensajes
+	if copiadas, err := dudasRepo.MigrarRespuestasAMensajes(); err != nil {
+		slog.Warn("error al copiar las respuestas de dudas al hilo", "error", err)
+	} else if copiadas > 0 {
+		slog.Info("respuestas de dudas copiadas al hilo", "cantidad", copiadas)
 	}
 
 	noticiaRepo := repositories.NewNoticiaRepository(db)
+	tokenCalendarioRepo := repositories.NewTokenCalendarioRepository(db)
+	disponibilidadPersonalRepo := repositories.NewDisponibilidadPersonalRepository(db)
+	solicitudVisitaRepo := repositories.NewSolicitudVisitaRepository(db)
+	encuestaRepo := repositories.NewEncuestaRepository(db)
+	enrutamientoDudasRepo := repositories.NewEnrutamientoDudasRepository(db)
+	preguntaFrecuenteRepo := repositories.NewPreguntaFrecuenteRepository(db)
+	busquedaRepo := repositories.NewBusquedaRepository(db)
+	archivoRepo := repositories.NewArchivoRepository(db)
+	subidaRepo := repositories.NewSubidaRepository(db)
+	huerfanosRepo := repositories.NewHuerfanosRepository(db)
+	saludRepo := repositories.NewSaludRepository(db)
+
+	// Preparar la búsqueda de texto completo (unaccent, columnas tsvector e índices GIN)
+	if err := busquedaRepo.PrepararBusqueda(); err != nil {
+		slog.Warn("error al preparar la búsqueda de texto completo", "error", err)
+	}
+
+	// Almacenamiento de los archivos subidos (disco local o S3, según ALMACENAMIENTO)
+	storage, err := services.NewStorage(cfg.Almacenamiento)
+	if err != nil {
+		slog.Error("error al configurar el almacenamiento de archivos", "error", err)
+		os.Exit(1)
+	}
+
+	// Inicializar servicios de dominio
+	emailService := services.NewEmailService(cfg.SMTP)
+	calendarioService := services.NewCalendarioService(tokenCalendarioRepo, usuarioRepo, autoridadRepo, estudianteUnivRepo, detalleAutoridadDetallesVisitaRepo, visitaDetalleEstudiantesUniversitariosRepo, emailService)
+	recurrenciaService := services.NewRecurrenciaService(plantillaProgramaVisitaRepo, calendarioService, cfg.ZonaHoraria)
+	asignacionService := services.NewAsignacionService(disponibilidadPersonalRepo, programaVisitaRepo, detalleAutoridadDetallesVisitaRepo, visitaDetalleEstudiantesUniversitariosRepo, calendarioService, cfg.ZonaHoraria)
+	solicitudVisitaService := services.NewSolicitudVisitaService(solicitudVisitaRepo, institucionRepo, tematicaRepo, usuarioRepo, autoridadRepo, emailService)
+	dudasService := services.NewDudasService(dudasRepo, usuarioRepo, estudianteRepo, autoridadRepo)
+	slaDudasService := services.NewSLADudasService(dudasRepo, enrutamientoDudasRepo, estudianteRepo, autoridadRepo, tematicaRepo, institucionRepo, emailService, cfg.ZonaHoraria, cfg.Intervalos.MonitorSLA)
+	faqService := services.NewFAQService(preguntaFrecuenteRepo, dudasRepo, usuarioRepo, autoridadRepo)
+	busquedaService := services.NewBusquedaService(busquedaRepo, dudasService)
+	noticiaService := services.NewNoticiaService(noticiaRepo, usuarioRepo, archivoRepo, cfg.NoticiasURLBase, cfg.Intervalos.Publicador)
+	archivoService := services.NewArchivoService(archivoRepo, usuarioRepo, storage, services.NewAntivirus(cfg.Antivirus), cfg.Archivos, cfg.SecretoFirmaArchivos())
+	subidaService := services.NewSubidaService(subidaRepo, archivoService, storage, cfg.Archivos, cfg.Intervalos.LimpiezaSubidas)
+	huerfanosService := services.NewHuerfanosService(huerfanosRepo, archivoRepo, usuarioRepo, storage, cfg.Archivos, cfg.Intervalos.RecoleccionHuerfanos)
+	direccionSMTP := ""
+	if cfg.SaludVerificarSMTP {
+		direccionSMTP = cfg.SMTP.Direccion()
+	}
+	saludService := services.NewSaludService(saludRepo, storage, models.Modelos(), direccionSMTP, cfg.SaludTimeout)
+
+	// Generar el slug de las noticias creadas antes de que existieran
+	if asignados, err := noticiaService.AsignarSlugsFaltantes(); err != nil {
+		slog.Warn("error al generar slugs de noticias", "error", err)
+	} else if asignados > 0 {
+		slog.Info("slugs generados para noticias", "cantidad", asignados)
+	}
+	encuestaService := services.NewEncuestaService(encuestaRepo, programaVisitaRepo, visitaDetalleRepo, estudianteRepo, actividadRepo, emailService, cfg.EncuestasURLBase)
 
 	// Inicializar handlers
 	estudianteHandler := handlers.NewEstudianteHandler(estudianteRepo, personaRepo, institucionRepo, ciudadRepo)
@@ -156,17 +221,29 @@ func main() {
 	autoridadHandler := handlers.NewAutoridadUTEQHandler(autoridadRepo, personaRepo)
 	tematicaHandler := handlers.NewTematicaHandler(tematicaRepo)
 	actividadHandler := handlers.NewActividadHandler(actividadRepo)
-	programaVisitaHandler := handlers.NewProgramaVisitaHandler(programaVisitaRepo)
-	detalleAutoridadDetallesVisitaHandler := handlers.NewDetalleAutoridadDetallesVisitaHandler(detalleAutoridadDetallesVisitaRepo)
+	programaVisitaHandler := handlers.NewProgramaVisitaHandler(programaVisitaRepo, calendarioService)
+	plantillaProgramaVisitaHandler := handlers.NewPlantillaProgramaVisitaHandler(plantillaProgramaVisitaRepo, recurrenciaService)
+	detalleAutoridadDetallesVisitaHandler := handlers.NewDetalleAutoridadDetallesVisitaHandler(detalleAutoridadDetallesVisitaRepo, calendarioService)
 	visitaDetalleHandler := handlers.NewVisitaDetalleHandler(visitaDetalleRepo)
-	dudasHandler := handlers.NewDudasHandler(dudasRepo)
-	visitaDetalleEstudiantesUniversitariosHandler := handlers.NewVisitaDetalleEstudiantesUniversitariosHandler(visitaDetalleEstudiantesUniversitariosRepo)
-	noticiaHandler := handlers.NewNoticiaHandler(noticiaRepo)
-	uploadHandler := handlers.NewUploadHandler()
+	dudasHandler := handlers.NewDudasHandler(dudasRepo, dudasService, slaDudasService)
+	visitaDetalleEstudiantesUniversitariosHandler := handlers.NewVisitaDetalleEstudiantesUniversitariosHandler(visitaDetalleEstudiantesUniversitariosRepo, calendarioService)
+	noticiaHandler := handlers.NewNoticiaHandler(noticiaRepo, noticiaService)
+	uploadHandler := handlers.NewUploadHandler(storage, archivoService)
 	codigoHandler := handlers.NewCodigoHandler(codigoUsuarioRepo)
+	calendarioHandler := handlers.NewCalendarioHandler(calendarioService)
+	disponibilidadHandler := handlers.NewDisponibilidadHandler(disponibilidadPersonalRepo, asignacionService)
+	solicitudVisitaHandler := handlers.NewSolicitudVisitaHandler(solicitudVisitaRepo, tematicaRepo, solicitudVisitaService)
+	encuestaHandler := handlers.NewEncuestaHandler(encuestaRepo, encuestaService)
+	slaDudasHandler := handlers.NewSLADudasHandler(enrutamientoDudasRepo, slaDudasService)
+	faqHandler := handlers.NewFAQHandler(preguntaFrecuenteRepo, faqService)
+	busquedaHandler := handlers.NewBusquedaHandler(busquedaService)
+	archivoHandler := handlers.NewArchivoHandler(archivoService)
+	subidaHandler := handlers.NewSubidaHandler(subidaService)
+	huerfanosHandler := handlers.NewHuerfanosHandler(huerfanosService)
+	saludHandler := handlers.NewSaludHandler(saludService)
 
 	// Inicializar servicios
-	authService := services.NewAuthService(usuarioRepo, personaRepo, codigoUsuarioRepo)
+	authService := services.NewAuthService(usuarioRepo, personaRepo, codigoUsuarioRepo, emailService)
 
 	// Inicializar handler de autenticación
 	authHandler := handlers.NewAuthHandler(authService)
@@ -185,6 +262,7 @@ func main() {
 		tematicaHandler,
 		actividadHandler,
 		programaVisitaHandler,
+		plantillaProgramaVisitaHandler,
 		detalleAutoridadDetallesVisitaHandler,
 		visitaDetalleHandler,
 		dudasHandler,
@@ -193,8 +271,31 @@ func main() {
 		uploadHandler,
 		authHandler,
 		codigoHandler,
+		calendarioHandler,
+		disponibilidadHandler,
+		solicitudVisitaHandler,
+		encuestaHandler,
+		slaDudasHandler,
+		faqHandler,
+		busquedaHandler,
+		archivoHandler,
+		subidaHandler,
+		huerfanosHandler,
+		saludHandler,
 	)
 
+	// Revisar en segundo plano los plazos de respuesta de las dudas
+	detenerMonitorSLA := slaDudasService.IniciarMonitor()
+
+	// Publicar las noticias programadas y archivar las expiradas en segundo plano
+	detenerPublicador := noticiaService.IniciarPublicador()
+
+	// Eliminar las subidas reanudables vencidas y sus partes en segundo plano
+	detenerLimpiezaSubidas := subidaService.IniciarLimpieza()
+
+	// Apartar en cuarentena los archivos subidos que nada referencia y eliminarlos al vencer el plazo
+	detenerRecolector := huerfanosService.IniciarRecoleccion()
+
 	// Configurar todas las rutas
 	routers.SetupAllRoutes(app, allHandlers)
 
@@ -207,21 +308,76 @@ func main() {
 		})
 	})
 
-	// Ruta de salud
-	app.Get("/health", func(c *fiber.Ctx) error {
-		return c.JSON(fiber.Map{
-			"status":   "healthy",
-			"database": "connected",
-		})
-	})
+	// Métricas para Prometheus; con METRICAS_TOKEN se exige Authorization: Bearer <token>
+	app.Get("/metrics", metricas.Handler(cfg.MetricasToken))
 
-	// Iniciar servidor
-	port := config.GetString("APP_PORT")
-	log.Printf("Servidor ApiEscuela iniciado en el puerto %s", port)
-	log.Printf("Ambiente: %s", config.GetString("APP_ENV"))
-	log.Printf("Conectado a la base de datos exitosamente")
+	// Iniciar servidor; SIGINT o SIGTERM inician el apagado ordenado
+	ctx, detenerSenales := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer detenerSenales()
 
-	if err := app.Listen(":" + port); err != nil {
-		log.Fatalf("Error al iniciar el servidor: %v", err)
+	errServidor := make(chan error, 1)
+	go func() {
+		slog.Info("servidor ApiEscuela iniciado", "puerto", cfg.Puerto, "ambiente", cfg.Ambiente)
+		errServidor <- app.Listen(":" + cfg.Puerto)
+	}()
+
+	select {
+	case err := <-errServidor:
+		if err != nil {
+			slog.Error("error al iniciar el servidor", "error", err)
+			os.Exit(1)
+		}
+		return
+	case <-ctx.Done():
+	}
+	detenerSenales() // Una segunda señal termina el proceso sin esperar
+	slog.Info("señal recibida, apagando el servidor", "plazo", cfg.ApagadoTimeout)
+	limite := time.Now().Add(cfg.ApagadoTimeout)
+
+	// 1. Dejar de aceptar conexiones y esperar a que terminen las peticiones en curso
+	if err := app.ShutdownWithTimeout(cfg.ApagadoTimeout); err != nil {
+		slog.Warn("quedaron peticiones sin terminar al apagar el servidor", "error", err)
+	}
+
+	// 2. Detener los procesos en segundo plano y esperar los correos pendientes, dentro del mismo plazo
+	procesos := []struct {
+		nombre  string
+		detener func()
+	}{
+		{"monitor de SLA de dudas", detenerMonitorSLA},
+		{"publicador de noticias", detenerPublicador},
+		{"limpieza de subidas", detenerLimpiezaSubidas},
+		{"recolector de huérfanos", detenerRecolector},
+		{"envío de correos", emailService.Esperar},
+	}
+	var grupo sync.WaitGroup
+	for _, proceso := range procesos {
+		grupo.Add(1)
+		go func() {
+			defer grupo.Done()
+			proceso.detener()
+			slog.Debug("proceso detenido", "proceso", proceso.nombre)
+		}()
+	}
+	terminados := make(chan struct{})
+	go func() {
+		grupo.Wait()
+		close(terminados)
+	}()
+	select {
+	case <-terminados:
+	case <-time.After(time.Until(limite)):
+		slog.Warn("se agotó el plazo de apagado con procesos en segundo plano en curso")
+	}
+
+	// 3. Cerrar la base de datos y enviar las trazas pendientes
+	if err := sqlDB.Close(); err != nil {
+		slog.Warn("error al cerrar la conexión a la base de datos", "error", err)
+	}
+	ctxTrazas, cancelar := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancelar()
+	if err := detenerTrazas(ctxTrazas); err != nil {
+		slog.Warn("no se pudieron enviar las trazas pendientes", "error", err)
 	}
+	slog.Info("servidor detenido")
 }
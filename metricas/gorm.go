@@ -0,0 +1,56 @@
+package metricas
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const claveInicio = "metricas:inicio"
+
+// PluginGORM mide la duración y los errores de cada consulta. Se instala con db.Use(metricas.PluginGORM{}).
+type PluginGORM struct{}
+
+func (PluginGORM) Name() string {
+	return "metricas"
+}
+
+// Initialize registra un callback antes y otro después de cada tipo de operación
+func (PluginGORM) Initialize(db *gorm.DB) error {
+	callbacks := db.Callback()
+	return errors.Join(
+		callbacks.Create().Before("gorm:create").Register("metricas:antes_create", iniciarConsulta),
+		callbacks.Create().After("gorm:create").Register("metricas:despues_create", terminarConsulta("create")),
+		callbacks.Query().Before("gorm:query").Register("metricas:antes_query", iniciarConsulta),
+		callbacks.Query().After("gorm:query").Register("metricas:despues_query", terminarConsulta("query")),
+		callbacks.Update().Before("gorm:update").Register("metricas:antes_update", iniciarConsulta),
+		callbacks.Update().After("gorm:update").Register("metricas:despues_update", terminarConsulta("update")),
+		callbacks.Delete().Before("gorm:delete").Register("metricas:antes_delete", iniciarConsulta),
+		callbacks.Delete().After("gorm:delete").Register("metricas:despues_delete", terminarConsulta("delete")),
+		callbacks.Row().Before("gorm:row").Register("metricas:antes_row", iniciarConsulta),
+		callbacks.Row().After("gorm:row").Register("metricas:despues_row", terminarConsulta("row")),
+		callbacks.Raw().Before("gorm:raw").Register("metricas:antes_raw", iniciarConsulta),
+		callbacks.Raw().After("gorm:raw").Register("metricas:despues_raw", terminarConsulta("raw")),
+	)
+}
+
+func iniciarConsulta(db *gorm.DB) {
+	db.InstanceSet(claveInicio, time.Now())
+}
+
+func terminarConsulta(operacion string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		valor, ok := db.InstanceGet(claveInicio)
+		inicio, esTiempo := valor.(time.Time)
+		if !ok || !esTiempo {
+			return
+		}
+		tabla := db.Statement.Table
+		if tabla == "" {
+			tabla = "sin_tabla"
+		}
+		fallida := db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound)
+		RegistrarConsulta(operacion, tabla, time.Since(inicio), fallida)
+	}
+}
@@ -0,0 +1,131 @@
+// Package metricas define las métricas de Prometheus de la API y el endpoint /metrics que las expone.
+// Usa un registro propio para publicar solo las métricas de ApiEscuela, las del runtime de Go y las del proceso.
+package metricas
+
+import (
+	"crypto/subtle"
+	"database/sql"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const espacio = "apiescuela"
+
+// Registro contiene todas las métricas que publica /metrics
+var Registro = prometheus.NewRegistry()
+
+var registrar = promauto.With(Registro)
+
+var (
+	peticiones = registrar.NewCounterVec(prometheus.CounterOpts{
+		Namespace: espacio,
+		Name:      "http_peticiones_total",
+		Help:      "Peticiones HTTP atendidas por método, ruta registrada y estado.",
+	}, []string{"metodo", "ruta", "estado"})
+
+	duracionPeticiones = registrar.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: espacio,
+		Name:      "http_duracion_segundos",
+		Help:      "Duración de las peticiones HTTP por método y ruta registrada.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"metodo", "ruta"})
+
+	duracionConsultas = registrar.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: espacio,
+		Name:      "db_consulta_duracion_segundos",
+		Help:      "Duración de las consultas de GORM por operación y tabla.",
+		Buckets:   prometheus.ExponentialBuckets(0.0005, 2, 14), // 0,5 ms a 4 s
+	}, []string{"operacion", "tabla"})
+
+	erroresConsultas = registrar.NewCounterVec(prometheus.CounterOpts{
+		Namespace: espacio,
+		Name:      "db_consulta_errores_total",
+		Help:      "Consultas de GORM fallidas por operación y tabla (sin contar registros no encontrados).",
+	}, []string{"operacion", "tabla"})
+
+	logins = registrar.NewCounterVec(prometheus.CounterOpts{
+		Namespace: espacio,
+		Name:      "login_total",
+		Help:      "Intentos de inicio de sesión por resultado (exito, fallo) y motivo del fallo.",
+	}, []string{"resultado", "motivo"})
+
+	correos = registrar.NewCounterVec(prometheus.CounterOpts{
+		Namespace: espacio,
+		Name:      "correos_total",
+		Help:      "Correos enviados por resultado (enviado, error, sin_configuracion).",
+	}, []string{"resultado"})
+
+	tamanoSubidas = registrar.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: espacio,
+		Name:      "subida_tamano_bytes",
+		Help:      "Tamaño de los archivos guardados por categoría.",
+		Buckets:   prometheus.ExponentialBuckets(10*1024, 4, 8), // 10 KB a 160 MB
+	}, []string{"categoria"})
+)
+
+func init() {
+	Registro.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+// RegistrarPeticion cuenta una petición HTTP. ruta debe ser el patrón registrado (/api/noticias/:id),
+// nunca la URL, para que la cantidad de series no crezca con cada identificador.
+func RegistrarPeticion(metodo, ruta string, estado int, duracion time.Duration) {
+	peticiones.WithLabelValues(metodo, ruta, strconv.Itoa(estado)).Inc()
+	duracionPeticiones.WithLabelValues(metodo, ruta).Observe(duracion.Seconds())
+}
+
+// RegistrarConsulta mide una consulta a la base de datos
+func RegistrarConsulta(operacion, tabla string, duracion time.Duration, fallida bool) {
+	duracionConsultas.WithLabelValues(operacion, tabla).Observe(duracion.Seconds())
+	if fallida {
+		erroresConsultas.WithLabelValues(operacion, tabla).Inc()
+	}
+}
+
+// RegistrarLogin cuenta un intento de inicio de sesión; motivo queda vacío cuando tuvo éxito
+func RegistrarLogin(exito bool, motivo string) {
+	resultado := "exito"
+	if !exito {
+		resultado = "fallo"
+	}
+	logins.WithLabelValues(resultado, motivo).Inc()
+}
+
+// RegistrarCorreo cuenta el resultado de un envío de correo
+func RegistrarCorreo(resultado string) {
+	correos.WithLabelValues(resultado).Inc()
+}
+
+// RegistrarSubida mide el tamaño de un archivo guardado
+func RegistrarSubida(categoria string, tamano int64) {
+	tamanoSubidas.WithLabelValues(categoria).Observe(float64(tamano))
+}
+
+// RegistrarPool publica las estadísticas del pool de conexiones (conexiones abiertas, en uso, esperas)
+func RegistrarPool(db *sql.DB, nombre string) {
+	Registro.MustRegister(collectors.NewDBStatsCollector(db, nombre))
+}
+
+// Handler expone las métricas en el formato de Prometheus. Si token no está vacío se exige
+// "Authorization: Bearer <token>", para no publicar el tráfico de la API a cualquiera.
+func Handler(token string) fiber.Handler {
+	exponer := adaptor.HTTPHandler(promhttp.HandlerFor(Registro, promhttp.HandlerOpts{}))
+	return func(c *fiber.Ctx) error {
+		if token != "" && subtle.ConstantTimeCompare([]byte(c.Get(fiber.HeaderAuthorization)), []byte("Bearer "+token)) != 1 {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Token de métricas inválido",
+			})
+		}
+		return exponer(c)
+	}
+}
@@ -0,0 +1,57 @@
+package metricas
+
+import (
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestHandlerExponeMetricas(t *testing.T) {
+	RegistrarPeticion("GET", "/api/noticias/:id", 200, 15*time.Millisecond)
+	RegistrarLogin(false, "contrasena_incorrecta")
+
+	app := fiber.New()
+	app.Get("/metrics", Handler(""))
+	resp, err := app.Test(httptest.NewRequest("GET", "/metrics", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cuerpo, _ := io.ReadAll(resp.Body)
+	texto := string(cuerpo)
+	for _, esperado := range []string{
+		`apiescuela_http_peticiones_total{estado="200",metodo="GET",ruta="/api/noticias/:id"} 1`,
+		`apiescuela_login_total{motivo="contrasena_incorrecta",resultado="fallo"} 1`,
+		"go_goroutines",
+	} {
+		if !strings.Contains(texto, esperado) {
+			t.Errorf("falta %q en /metrics", esperado)
+		}
+	}
+}
+
+func TestHandlerConToken(t *testing.T) {
+	app := fiber.New()
+	app.Get("/metrics", Handler("secreto"))
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/metrics", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusUnauthorized {
+		t.Errorf("sin token: estado %d, se esperaba 401", resp.StatusCode)
+	}
+
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	req.Header.Set("Authorization", "Bearer secreto")
+	resp, err = app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusOK {
+		t.Errorf("con token: estado %d, se esperaba 200", resp.StatusCode)
+	}
+}
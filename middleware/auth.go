@@ -1,8 +1,8 @@
 package middleware
 
 import (
+	"ApiEscuela/trazas"
 	"errors"
-	"os"
 	"strings"
 	"time"
 
@@ -18,14 +18,24 @@ type JWTClaims struct {
 	jwt.RegisteredClaims
 }
 
-// getJWTSecret obtiene la clave secreta desde variables de entorno
-func getJWTSecret() []byte {
-	secret := os.Getenv("JWT_SECRET")
-	if secret == "" {
-		// Fallback para desarrollo (NO usar en producción)
-		return []byte("default_secret_for_development_only")
+// ErrSecretoJWTNoConfigurado indica que no se llamó a ConfigurarJWT
+var ErrSecretoJWTNoConfigurado = errors.New("el secreto JWT no está configurado")
+
+// secretoJWT firma y valida los tokens; lo fija ConfigurarJWT al iniciar
+var secretoJWT []byte
+
+// ConfigurarJWT fija el secreto con el que se firman y validan los tokens. config.Cargar ya rechazó
+// los secretos vacíos o conocidos.
+func ConfigurarJWT(secreto string) {
+	secretoJWT = []byte(secreto)
+}
+
+// getJWTSecret devuelve el secreto configurado, o un error si todavía no hay uno
+func getJWTSecret() ([]byte, error) {
+	if len(secretoJWT) == 0 {
+		return nil, ErrSecretoJWTNoConfigurado
 	}
-	return []byte(secret)
+	return secretoJWT, nil
 }
 
 const loginRedirectPath = "/auth/login"
@@ -45,14 +55,18 @@ func GenerateJWT(userID uint, username string, tipoUsuarioID uint) (string, erro
 		},
 	}
 
+	secreto, err := getJWTSecret()
+	if err != nil {
+		return "", err
+	}
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(getJWTSecret())
+	return token.SignedString(secreto)
 }
 
 // ValidateJWT valida un token JWT
 func ValidateJWT(tokenString string) (*JWTClaims, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &JWTClaims{}, func(token *jwt.Token) (interface{}, error) {
-		return getJWTSecret(), nil
+		return getJWTSecret()
 	})
 
 	if err != nil {
@@ -76,6 +90,14 @@ type ErrorResponse struct {
 	Path       string `json:"path"`
 	Method     string `json:"method"`
 	RedirectTo string `json:"redirect_to,omitempty"`
+	RequestID  string `json:"request_id,omitempty"`
+	TraceID    string `json:"trace_id,omitempty"`
+}
+
+// requestIDDe devuelve el identificador asignado por RequestID
+func requestIDDe(c *fiber.Ctx) string {
+	id, _ := c.Locals("request_id").(string)
+	return id
 }
 
 // JWTMiddleware es el middleware que protege las rutas
@@ -92,6 +114,8 @@ func JWTMiddleware() fiber.Handler {
 				Timestamp:  time.Now().Format(time.RFC3339),
 				Path:       c.Path(),
 				Method:     c.Method(),
+				RequestID:  requestIDDe(c),
+				TraceID:    trazas.TraceID(c.UserContext()),
 			})
 		}
 
@@ -106,6 +130,8 @@ func JWTMiddleware() fiber.Handler {
 				Timestamp:  time.Now().Format(time.RFC3339),
 				Path:       c.Path(),
 				Method:     c.Method(),
+				RequestID:  requestIDDe(c),
+				TraceID:    trazas.TraceID(c.UserContext()),
 			})
 		}
 
@@ -118,6 +144,8 @@ func JWTMiddleware() fiber.Handler {
 				Timestamp:  time.Now().Format(time.RFC3339),
 				Path:       c.Path(),
 				Method:     c.Method(),
+				RequestID:  requestIDDe(c),
+				TraceID:    trazas.TraceID(c.UserContext()),
 			})
 		}
 
@@ -131,6 +159,8 @@ func JWTMiddleware() fiber.Handler {
 				Timestamp:  time.Now().Format(time.RFC3339),
 				Path:       c.Path(),
 				Method:     c.Method(),
+				RequestID:  requestIDDe(c),
+				TraceID:    trazas.TraceID(c.UserContext()),
 			})
 		}
 
@@ -170,6 +200,8 @@ func JWTMiddleware() fiber.Handler {
 				Timestamp:  time.Now().Format(time.RFC3339),
 				Path:       c.Path(),
 				Method:     c.Method(),
+				RequestID:  requestIDDe(c),
+				TraceID:    trazas.TraceID(c.UserContext()),
 				RedirectTo: redirectTo,
 			})
 		}
@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"ApiEscuela/metricas"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Metricas cuenta cada petición y mide su duración por ruta registrada. Las peticiones a rutas
+// inexistentes se agrupan en "sin_ruta" para que no aparezca una serie por cada URL inventada.
+func Metricas() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		inicio := time.Now()
+		escribirError(c, c.Next())
+		metricas.RegistrarPeticion(c.Method(), rutaRegistrada(c), c.Response().StatusCode(), time.Since(inicio))
+		return nil
+	}
+}
+
+// claveSinRuta marca en Locals las peticiones que ningún handler atendió, porque el error que lo indica
+// solo lo ve el primer middleware que lo recibe
+const claveSinRuta = "sin_ruta"
+
+// escribirError deja escrita la respuesta de error con el ErrorHandler de la app, para que los
+// middlewares que miden la petición vean el estado real. Después de llamarla el middleware devuelve nil,
+// así Fiber no vuelve a manejar el mismo error.
+func escribirError(c *fiber.Ctx, err error) {
+	if err == nil {
+		return
+	}
+	if esRutaInexistente(err) {
+		c.Locals(claveSinRuta, true)
+	}
+	if errManejador := c.App().ErrorHandler(c, err); errManejador != nil {
+		c.Status(fiber.StatusInternalServerError)
+	}
+}
+
+// rutaRegistrada es la ruta que atendió la petición, o "sin_ruta" si no existía, para que no aparezca
+// una serie o un nombre de span por cada URL inventada
+func rutaRegistrada(c *fiber.Ctx) string {
+	if sinRuta, _ := c.Locals(claveSinRuta).(bool); sinRuta {
+		return "sin_ruta"
+	}
+	return c.Route().Path
+}
+
+// esRutaInexistente reconoce el 404 que Fiber devuelve cuando ningún handler atiende la ruta
+func esRutaInexistente(err error) bool {
+	var errFiber *fiber.Error
+	return errors.As(err, &errFiber) && errFiber.Code == fiber.StatusNotFound && strings.HasPrefix(errFiber.Message, "Cannot ")
+}
@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestRutaInexistenteDetrasDeTrazas(t *testing.T) {
+	var rutas []string
+	app := fiber.New()
+	// Igual que Metricas: mide después de que Trazas ya escribió el error y devolvió nil
+	app.Use(func(c *fiber.Ctx) error {
+		escribirError(c, c.Next())
+		rutas = append(rutas, rutaRegistrada(c))
+		return nil
+	})
+	app.Use(Trazas())
+	app.Get("/api/noticias/:id", func(c *fiber.Ctx) error {
+		return fiber.ErrNotFound
+	})
+
+	for _, caso := range []struct{ url, ruta string }{
+		{"/no/existe", "sin_ruta"},
+		{"/api/noticias/7", "/api/noticias/:id"}, // Un 404 del handler no es una ruta inexistente
+	} {
+		rutas = nil
+		resp, err := app.Test(httptest.NewRequest("GET", caso.url, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != fiber.StatusNotFound {
+			t.Errorf("%s: estado %d, se esperaba 404", caso.url, resp.StatusCode)
+		}
+		if len(rutas) != 1 || rutas[0] != caso.ruta {
+			t.Errorf("%s: ruta %v, se esperaba %q", caso.url, rutas, caso.ruta)
+		}
+	}
+}
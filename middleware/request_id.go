@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"ApiEscuela/logging"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"regexp"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// EncabezadoRequestID es el encabezado con el que se recibe y se devuelve el identificador de la petición
+const EncabezadoRequestID = "X-Request-ID"
+
+// requestIDValido acepta los identificadores que envían los balanceadores y proxies habituales
+var requestIDValido = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// RequestID asigna un identificador a cada petición: el de X-Request-ID si el cliente o el proxy lo envía,
+// o uno nuevo. Se devuelve en la respuesta, se guarda en c.Locals("request_id") y en el contexto de la
+// petición para que los logs lo incluyan. Al terminar registra la petición con su estado y duración.
+func RequestID() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id := c.Get(EncabezadoRequestID)
+		if !requestIDValido.MatchString(id) {
+			id = nuevoRequestID()
+		}
+		c.Locals("request_id", id)
+		c.Set(EncabezadoRequestID, id)
+		c.SetUserContext(logging.ConRequestID(c.UserContext(), id))
+
+		inicio := time.Now()
+		err := c.Next()
+		escribirError(c, err)
+
+		estado := c.Response().StatusCode()
+		nivel := slog.LevelInfo
+		switch {
+		case estado >= 500:
+			nivel = slog.LevelError
+		case estado >= 400:
+			nivel = slog.LevelWarn
+		}
+		atributos := []slog.Attr{
+			slog.String("metodo", c.Method()),
+			slog.String("ruta", rutaRegistrada(c)), // La ruta registrada, sin los tokens que viajan en la URL
+			slog.Int("estado", estado),
+			slog.Duration("duracion", time.Since(inicio)),
+			slog.String("ip", c.IP()),
+		}
+		if usuarioID, ok := c.Locals("user_id").(uint); ok {
+			atributos = append(atributos, slog.Uint64("usuario_id", uint64(usuarioID)))
+		}
+		slog.LogAttrs(c.UserContext(), nivel, "petición", atributos...)
+		return nil
+	}
+}
+
+func nuevoRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
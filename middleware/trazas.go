@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"ApiEscuela/trazas"
+
+	"github.com/gofiber/fiber/v2"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// EncabezadoTraceID devuelve el trace_id de la petición en todas las respuestas, también en las de error
+// que no usan el formato estándar
+const EncabezadoTraceID = "X-Trace-ID"
+
+// Trazas abre un span por petición, continuando la traza del cliente si envía traceparent, y lo deja
+// en c.UserContext() para que los logs, las consultas y las respuestas de error lleven el trace_id
+func Trazas() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx := otel.GetTextMapPropagator().Extract(c.UserContext(), encabezadosPeticion{c})
+		ctx, span := trazas.Tracer().Start(ctx, c.Method(), trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(attribute.String("http.request.method", c.Method())))
+		defer span.End()
+		c.SetUserContext(ctx)
+		if id := trazas.TraceID(ctx); id != "" {
+			c.Set(EncabezadoTraceID, id)
+		}
+
+		err := c.Next()
+		escribirError(c, err)
+
+		// El nombre usa la ruta registrada, no la URL, igual que las métricas y el log de acceso
+		ruta := rutaRegistrada(c)
+		estado := c.Response().StatusCode()
+		span.SetName(c.Method() + " " + ruta)
+		span.SetAttributes(
+			attribute.String("http.route", ruta),
+			attribute.Int("http.response.status_code", estado),
+		)
+		if estado >= 500 {
+			span.SetStatus(codes.Error, "")
+		}
+		return nil
+	}
+}
+
+// encabezadosPeticion adapta los encabezados de Fiber para leer traceparent y baggage
+type encabezadosPeticion struct {
+	c *fiber.Ctx
+}
+
+func (e encabezadosPeticion) Get(clave string) string {
+	return e.c.Get(clave)
+}
+
+func (e encabezadosPeticion) Set(clave, valor string) {
+	e.c.Request().Header.Set(clave, valor)
+}
+
+func (e encabezadosPeticion) Keys() []string {
+	var claves []string
+	e.c.Request().Header.VisitAll(func(clave, _ []byte) {
+		claves = append(claves, string(clave))
+	})
+	return claves
+}
+
+var _ propagation.TextMapCarrier = encabezadosPeticion{}
@@ -0,0 +1,30 @@
+package models
+
+import "gorm.io/gorm"
+
+// Visibilidad de un archivo subido
+const (
+	VisibilidadArchivoPublico = "publico"
+	VisibilidadArchivoPrivado = "privado"
+)
+
+// Archivo registra un archivo subido con /api/upload
+type Archivo struct {
+	gorm.Model
+	UsuarioID        uint   `json:"usuario_id" gorm:"not null;index"` // Propietario
+	NombreOriginal   string `json:"nombre_original" gorm:"not null"`
+	Carpeta          string `json:"carpeta" gorm:"size:20;not null;uniqueIndex:idx_archivo_ubicacion"`
+	NombreAlmacenado string `json:"nombre_almacenado" gorm:"not null;uniqueIndex:idx_archivo_ubicacion"`
+	MIME             string `json:"mime" gorm:"not null"`
+	Tamano           int64  `json:"tamano" gorm:"not null"`
+	SHA256           string `json:"sha256" gorm:"size:64;not null;index"`
+	Visibilidad      string `json:"visibilidad" gorm:"size:10;not null;default:'publico'"`
+	Variantes        string `json:"variantes,omitempty"` // Sufijos de las versiones reducidas de una imagen, separados por comas (thumb.jpg,medium.jpg)
+
+	// Relaciones
+	Permisos []PermisoArchivo `json:"permisos,omitempty" gorm:"foreignKey:ArchivoID;constraint:OnDelete:CASCADE"`
+}
+
+func (Archivo) TableName() string {
+	return "archivos"
+}
@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ArchivoCuarentena es un archivo subido que ningún registro referencia. Se aparta de su carpeta a
+// cuarentena/<clave> junto con sus variantes y se elimina cuando vence EliminarEn, salvo que se restaure.
+type ArchivoCuarentena struct {
+	gorm.Model
+	Clave        string    `json:"clave" gorm:"not null;uniqueIndex"` // Clave original: carpeta/nombre
+	Variantes    string    `json:"variantes,omitempty"`               // Claves originales de las variantes, separadas por comas
+	Tamano       int64     `json:"tamano" gorm:"not null"`            // Tamaño del original y sus variantes
+	ArchivoID    *uint     `json:"archivo_id,omitempty" gorm:"index"` // Registro en archivos, si lo tiene
+	ModificadoEn time.Time `json:"modificado_en"`                     // Última modificación del archivo antes de apartarlo
+	EliminarEn   time.Time `json:"eliminar_en" gorm:"not null;index"`
+}
+
+func (ArchivoCuarentena) TableName() string {
+	return "archivos_cuarentena"
+}
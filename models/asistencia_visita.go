@@ -0,0 +1,17 @@
+package models
+
+import "gorm.io/gorm"
+
+// AsistenciaVisita registra a un estudiante de la institución que asistió a un programa de visita.
+// Se guarda al completar la visita y determina quiénes reciben las encuestas.
+type AsistenciaVisita struct {
+	gorm.Model
+	ProgramaVisitaID uint `json:"programa_visita_id" gorm:"not null;uniqueIndex:idx_asistencia_visita_estudiante"`
+	EstudianteID     uint `json:"estudiante_id" gorm:"not null;uniqueIndex:idx_asistencia_visita_estudiante;index"`
+
+	// Relaciones
+	Estudiante Estudiante `json:"estudiante,omitempty" gorm:"foreignKey:EstudianteID"`
+}
+
+// TableName fija el nombre de la tabla
+func (AsistenciaVisita) TableName() string { return "asistencias_visita" }
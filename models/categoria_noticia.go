@@ -0,0 +1,15 @@
+package models
+
+import "gorm.io/gorm"
+
+// CategoriaNoticia agrupa las noticias por sección (Admisión, Eventos, Becas, etc.)
+type CategoriaNoticia struct {
+	gorm.Model
+	Nombre      string `json:"nombre" gorm:"not null;uniqueIndex"`
+	Slug        string `json:"slug" gorm:"not null;uniqueIndex"`
+	Descripcion string `json:"descripcion"`
+}
+
+func (CategoriaNoticia) TableName() string {
+	return "categorias_noticia"
+}
@@ -0,0 +1,18 @@
+package models
+
+import "gorm.io/gorm"
+
+// ConfiguracionSLADudas define el plazo de respuesta de las dudas. Existe un único registro.
+type ConfiguracionSLADudas struct {
+	gorm.Model
+	HorasRespuesta    int   `json:"horas_respuesta" gorm:"not null;default:48"`
+	HorasRecordatorio int   `json:"horas_recordatorio" gorm:"not null;default:12"` // Antes del vencimiento
+	SupervisorID      *uint `json:"supervisor_id,omitempty"`
+
+	// Relaciones
+	Supervisor *AutoridadUTEQ `json:"supervisor,omitempty" gorm:"foreignKey:SupervisorID"`
+}
+
+func (ConfiguracionSLADudas) TableName() string {
+	return "configuracion_sla_dudas"
+}
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// DisponibilidadPersonal representa una franja semanal en la que una autoridad UTEQ
+// o un estudiante universitario puede acompañar visitas
+type DisponibilidadPersonal struct {
+	gorm.Model
+	AutoridadUTEQID           *uint      `json:"autoridad_uteq_id,omitempty" gorm:"index"`
+	EstudianteUniversitarioID *uint      `json:"estudiante_universitario_id,omitempty" gorm:"index"`
+	DiaSemana                 int        `json:"dia_semana" gorm:"not null"`         // 0 = domingo ... 6 = sábado
+	HoraInicio                string     `json:"hora_inicio" gorm:"size:5;not null"` // HH:MM
+	HoraFin                   string     `json:"hora_fin" gorm:"size:5;not null"`    // HH:MM
+	VigenteDesde              *time.Time `json:"vigente_desde,omitempty"`
+	VigenteHasta              *time.Time `json:"vigente_hasta,omitempty"`
+
+	// Relaciones
+	AutoridadUTEQ           *AutoridadUTEQ           `json:"autoridad_uteq,omitempty" gorm:"foreignKey:AutoridadUTEQID"`
+	EstudianteUniversitario *EstudianteUniversitario `json:"estudiante_universitario,omitempty" gorm:"foreignKey:EstudianteUniversitarioID"`
+}
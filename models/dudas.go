@@ -10,13 +10,23 @@ type Dudas struct {
 	gorm.Model
 	Pregunta         string     `json:"pregunta" gorm:"not null"`
 	FechaPregunta    time.Time  `json:"fecha_pregunta" gorm:"not null;default:CURRENT_TIMESTAMP"`
-	Respuesta        *string    `json:"respuesta,omitempty"`        // Opcional - puntero para permitir null
+	Respuesta        *string    `json:"respuesta,omitempty"`        // Última respuesta pública; el hilo completo está en Mensajes
 	FechaRespuesta   *time.Time `json:"fecha_respuesta,omitempty"`  // Opcional - se establece cuando se responde
 	Privacidad       string     `json:"privacidad" gorm:"not null;default:'publico';check:privacidad IN ('privado','publico')"`
 	EstudianteID     uint       `json:"estudiante_id" gorm:"not null"`
 	AutoridadUTEQID  *uint      `json:"autoridad_uteq_id,omitempty"` // Opcional - puede no estar asignada
+	TematicaID       *uint      `json:"tematica_id,omitempty"`
+	AsignadaEn       *time.Time `json:"asignada_en,omitempty"`
+	VenceEn          *time.Time `json:"vence_en,omitempty"`             // Plazo de primera respuesta (SLA)
+	PrimeraRespuestaEn *time.Time `json:"primera_respuesta_en,omitempty"`
+	RecordatorioEn   *time.Time `json:"recordatorio_en,omitempty"`
+	EscaladaEn       *time.Time `json:"escalada_en,omitempty"`
+	EscaladaAID      *uint      `json:"escalada_a_id,omitempty"`        // Supervisor que recibió la escalación
 	
 	// Relaciones
 	Estudiante    Estudiante     `json:"estudiante,omitempty" gorm:"foreignKey:EstudianteID"`
 	AutoridadUTEQ *AutoridadUTEQ `json:"autoridad_uteq,omitempty" gorm:"foreignKey:AutoridadUTEQID"`
+	Tematica      *Tematica      `json:"tematica,omitempty" gorm:"foreignKey:TematicaID"`
+	EscaladaA     *AutoridadUTEQ `json:"escalada_a,omitempty" gorm:"foreignKey:EscaladaAID"`
+	Mensajes      []MensajeDuda  `json:"mensajes,omitempty" gorm:"foreignKey:DudaID"`
 }
\ No newline at end of file
@@ -0,0 +1,15 @@
+package models
+
+import "gorm.io/gorm"
+
+// EdicionMensajeDuda guarda el contenido anterior de un mensaje cada vez que se edita
+type EdicionMensajeDuda struct {
+	gorm.Model
+	MensajeDudaID     uint   `json:"mensaje_duda_id" gorm:"not null;index"`
+	ContenidoAnterior string `json:"contenido_anterior" gorm:"type:text;not null"`
+	EditadoPorID      uint   `json:"editado_por_id" gorm:"not null"`
+}
+
+func (EdicionMensajeDuda) TableName() string {
+	return "ediciones_mensaje_duda"
+}
@@ -0,0 +1,21 @@
+package models
+
+import "gorm.io/gorm"
+
+// Encuesta representa un cuestionario de satisfacción asociado a un programa de visita o a una actividad
+type Encuesta struct {
+	gorm.Model
+	Titulo           string `json:"titulo" gorm:"not null"`
+	Descripcion      string `json:"descripcion"`
+	ProgramaVisitaID *uint  `json:"programa_visita_id,omitempty" gorm:"index"`
+	ActividadID      *uint  `json:"actividad_id,omitempty" gorm:"index"`
+	Activa           bool   `json:"activa" gorm:"default:true"`
+
+	// Relaciones
+	ProgramaVisita *ProgramaVisita    `json:"programa_visita,omitempty" gorm:"foreignKey:ProgramaVisitaID"`
+	Actividad      *Actividad         `json:"actividad,omitempty" gorm:"foreignKey:ActividadID"`
+	Preguntas      []PreguntaEncuesta `json:"preguntas,omitempty" gorm:"foreignKey:EncuestaID"`
+}
+
+// TableName fija el nombre de la tabla usado en las consultas de resultados
+func (Encuesta) TableName() string { return "encuestas" }
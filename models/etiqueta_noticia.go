@@ -0,0 +1,14 @@
+package models
+
+import "gorm.io/gorm"
+
+// EtiquetaNoticia es una etiqueta libre; se crea la primera vez que se usa en una noticia
+type EtiquetaNoticia struct {
+	gorm.Model
+	Nombre string `json:"nombre" gorm:"not null"`
+	Slug   string `json:"slug" gorm:"not null;uniqueIndex"`
+}
+
+func (EtiquetaNoticia) TableName() string {
+	return "etiquetas_noticia"
+}
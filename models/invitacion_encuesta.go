@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// InvitacionEncuesta representa un enlace de un solo uso para responder una encuesta.
+// No guarda el destinatario: solo el hash del token, para que las respuestas sean anónimas.
+type InvitacionEncuesta struct {
+	gorm.Model
+	EncuestaID       uint       `json:"encuesta_id" gorm:"not null;index"`
+	ProgramaVisitaID uint       `json:"programa_visita_id" gorm:"not null;index"`
+	TokenHash        string     `json:"-" gorm:"uniqueIndex;size:64;not null"`
+	ExpiraEn         time.Time  `json:"expira_en" gorm:"not null"`
+	RespondidaEn     *time.Time `json:"respondida_en,omitempty"`
+
+	// Relaciones
+	Encuesta Encuesta `json:"encuesta,omitempty" gorm:"foreignKey:EncuestaID"`
+}
+
+// TableName fija el nombre de la tabla
+func (InvitacionEncuesta) TableName() string { return "invitaciones_encuesta" }
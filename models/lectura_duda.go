@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// LecturaDuda registra hasta cuándo leyó un participante el hilo de una duda
+type LecturaDuda struct {
+	gorm.Model
+	DudaID          uint      `json:"duda_id" gorm:"not null;uniqueIndex:idx_lectura_duda_usuario"`
+	UsuarioID       uint      `json:"usuario_id" gorm:"not null;uniqueIndex:idx_lectura_duda_usuario"`
+	UltimaLecturaEn time.Time `json:"ultima_lectura_en" gorm:"not null"`
+}
+
+func (LecturaDuda) TableName() string {
+	return "lecturas_duda"
+}
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Tipos de mensaje de una duda
+const (
+	TipoMensajeDudaMensaje     = "mensaje"
+	TipoMensajeDudaNotaInterna = "nota_interna"
+)
+
+// Roles del autor de un mensaje
+const (
+	RolDudaEstudiante = "estudiante"
+	RolDudaPersonal   = "personal"
+)
+
+// MensajeDuda es un mensaje del hilo de conversación de una duda.
+// Las notas internas solo las ve el personal UTEQ.
+type MensajeDuda struct {
+	gorm.Model
+	DudaID    uint       `json:"duda_id" gorm:"not null;index"`
+	UsuarioID uint       `json:"usuario_id" gorm:"not null;index"`
+	PersonaID uint       `json:"persona_id" gorm:"not null"`
+	RolAutor  string     `json:"rol_autor" gorm:"not null;check:rol_autor IN ('estudiante','personal')"`
+	Tipo      string     `json:"tipo" gorm:"not null;default:'mensaje';check:tipo IN ('mensaje','nota_interna')"`
+	Contenido string     `json:"contenido" gorm:"type:text;not null"`
+	EditadoEn *time.Time `json:"editado_en,omitempty"`
+
+	// Relaciones
+	Autor     Persona              `json:"autor,omitempty" gorm:"foreignKey:PersonaID"`
+	Ediciones []EdicionMensajeDuda `json:"ediciones,omitempty" gorm:"foreignKey:MensajeDudaID"`
+}
+
+func (MensajeDuda) TableName() string {
+	return "mensajes_duda"
+}
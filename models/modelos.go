@@ -0,0 +1,54 @@
+package models
+
+// Modelos lista los modelos que se migran al iniciar. La verificación de disponibilidad (/health/ready)
+// comprueba que existan sus tablas, así que un modelo nuevo se agrega aquí y no directamente en main.go.
+func Modelos() []interface{} {
+	return []interface{}{
+		&Provincia{},
+		&Ciudad{},
+		&Persona{},
+		&TipoUsuario{},
+		&Usuario{},
+		&Institucion{},
+		&Estudiante{},
+		&EstudianteUniversitario{},
+		&AutoridadUTEQ{},
+		&Tematica{},
+		&Actividad{},
+		&ProgramaVisita{},
+		&PlantillaProgramaVisita{},
+		&PlantillaVisitaActividad{},
+		&PlantillaVisitaAutoridad{},
+		&PlantillaVisitaEstudiante{},
+		&DetalleAutoridadDetallesVisita{},
+		&VisitaDetalle{},
+		&Dudas{},
+		&MensajeDuda{},
+		&EdicionMensajeDuda{},
+		&LecturaDuda{},
+		&ReglaAsignacionDuda{},
+		&ConfiguracionSLADudas{},
+		&PreguntaFrecuente{},
+		&VisitaDetalleEstudiantesUniversitarios{},
+		&AsistenciaVisita{},
+		&CodigoUsuario{},
+		&CategoriaNoticia{},
+		&EtiquetaNoticia{},
+		&Noticia{},
+		&RevisionNoticia{},
+		&TokenCalendario{},
+		&DisponibilidadPersonal{},
+		&SolicitudVisita{},
+		&SolicitudVisitaTematica{},
+		&Encuesta{},
+		&PreguntaEncuesta{},
+		&InvitacionEncuesta{},
+		&RespuestaEncuesta{},
+		&RespuestaPregunta{},
+		&Archivo{},
+		&PermisoArchivo{},
+		&SubidaReanudable{},
+		&ParteSubida{},
+		&ArchivoCuarentena{},
+	}
+}
@@ -1,6 +1,19 @@
 package models
 
-import "gorm.io/gorm"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Estados del flujo editorial de una noticia
+const (
+	EstadoNoticiaBorrador   = "borrador"
+	EstadoNoticiaEnRevision = "en_revision"
+	EstadoNoticiaProgramada = "programada"
+	EstadoNoticiaPublicada  = "publicada"
+	EstadoNoticiaArchivada  = "archivada"
+)
 
 // Noticia representa una noticia del sistema
 type Noticia struct {
@@ -10,6 +23,25 @@ type Noticia struct {
 	URLNoticia  string `json:"url_noticia"`
 	UsuarioID   uint   `json:"usuario_id" gorm:"not null"`
 
+	// Slug para URLs amigables; las noticias anteriores lo reciben al iniciar la API
+	Slug            string `json:"slug" gorm:"size:200;uniqueIndex:idx_noticia_slug,where:slug <> ''"`
+	CategoriaID     *uint  `json:"categoria_id,omitempty" gorm:"index"`
+	ImagenPortadaID *uint  `json:"imagen_portada_id,omitempty" gorm:"index"` // Imagen pública subida con /api/upload
+
+	// Flujo editorial. El valor por defecto de la columna mantiene publicadas las noticias anteriores al flujo;
+	// las noticias nuevas siempre empiezan como borrador.
+	Estado             string     `json:"estado" gorm:"size:20;not null;default:'publicada';index"`
+	PublicarEn         *time.Time `json:"publicar_en,omitempty" gorm:"index"`
+	ExpiraEn           *time.Time `json:"expira_en,omitempty" gorm:"index"`
+	PublicadaEn        *time.Time `json:"publicada_en,omitempty"`
+	PublicadaPorID     *uint      `json:"publicada_por_id,omitempty"`
+	ComentarioRevision string     `json:"comentario_revision,omitempty"` // Motivo del último rechazo
+	Version            int        `json:"version" gorm:"not null;default:1"`
+
 	// Relaciones
-	Usuario Usuario `json:"usuario,omitempty" gorm:"foreignKey:UsuarioID"`
+	Usuario       Usuario           `json:"usuario,omitempty" gorm:"foreignKey:UsuarioID"`
+	Categoria     *CategoriaNoticia `json:"categoria,omitempty" gorm:"foreignKey:CategoriaID"`
+	ImagenPortada *Archivo          `json:"imagen_portada,omitempty" gorm:"foreignKey:ImagenPortadaID;constraint:OnDelete:SET NULL"`
+	Etiquetas     []EtiquetaNoticia `json:"etiquetas,omitempty" gorm:"many2many:noticia_etiquetas"`
+	Revisiones    []RevisionNoticia `json:"revisiones,omitempty" gorm:"foreignKey:NoticiaID"`
 }
@@ -0,0 +1,16 @@
+package models
+
+import "gorm.io/gorm"
+
+// PermisoArchivo concede lectura de un archivo privado a un usuario o a todos los usuarios de un tipo
+type PermisoArchivo struct {
+	gorm.Model
+	ArchivoID     uint  `json:"archivo_id" gorm:"not null;index"`
+	UsuarioID     *uint `json:"usuario_id,omitempty" gorm:"index"`
+	TipoUsuarioID *uint `json:"tipo_usuario_id,omitempty" gorm:"index"`
+	OtorgadoPorID uint  `json:"otorgado_por_id" gorm:"not null"`
+}
+
+func (PermisoArchivo) TableName() string {
+	return "permisos_archivo"
+}
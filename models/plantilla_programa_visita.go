@@ -0,0 +1,54 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// PlantillaProgramaVisita representa una plantilla de visitas recurrentes de una institución
+// Regla: subconjunto de RRULE (RFC 5545): FREQ=WEEKLY|MONTHLY, INTERVAL, COUNT, UNTIL, BYDAY, BYMONTHDAY
+// Excepciones: fechas YYYY-MM-DD separadas por coma que no generan ocurrencia (EXDATE)
+type PlantillaProgramaVisita struct {
+	gorm.Model
+	InstitucionID     uint      `json:"institucion_id" gorm:"not null;index"`
+	FechaInicio       time.Time `json:"fecha_inicio" gorm:"not null"`
+	DuracionMinutos   int       `json:"duracion_minutos"`
+	Regla             string    `json:"regla" gorm:"not null"`
+	Excepciones       string    `json:"excepciones"`
+	PlantillaOrigenID *uint     `json:"plantilla_origen_id,omitempty"` // Serie de la que se separó ("esta y futuras")
+
+	// Relaciones
+	Institucion Institucion                 `json:"institucion,omitempty" gorm:"foreignKey:InstitucionID"`
+	Actividades []PlantillaVisitaActividad  `json:"actividades,omitempty" gorm:"foreignKey:PlantillaProgramaVisitaID"`
+	Autoridades []PlantillaVisitaAutoridad  `json:"autoridades,omitempty" gorm:"foreignKey:PlantillaProgramaVisitaID"`
+	Estudiantes []PlantillaVisitaEstudiante `json:"estudiantes,omitempty" gorm:"foreignKey:PlantillaProgramaVisitaID"`
+	Programas   []ProgramaVisita            `json:"programas,omitempty" gorm:"foreignKey:PlantillaID"`
+}
+
+// ActividadIDs devuelve los IDs de las actividades de la plantilla
+func (p *PlantillaProgramaVisita) ActividadIDs() []uint {
+	ids := make([]uint, 0, len(p.Actividades))
+	for _, a := range p.Actividades {
+		ids = append(ids, a.ActividadID)
+	}
+	return ids
+}
+
+// AutoridadIDs devuelve los IDs de las autoridades asignadas en la plantilla
+func (p *PlantillaProgramaVisita) AutoridadIDs() []uint {
+	ids := make([]uint, 0, len(p.Autoridades))
+	for _, a := range p.Autoridades {
+		ids = append(ids, a.AutoridadUTEQID)
+	}
+	return ids
+}
+
+// EstudianteIDs devuelve los IDs de los estudiantes universitarios asignados en la plantilla
+func (p *PlantillaProgramaVisita) EstudianteIDs() []uint {
+	ids := make([]uint, 0, len(p.Estudiantes))
+	for _, e := range p.Estudiantes {
+		ids = append(ids, e.EstudianteUniversitarioID)
+	}
+	return ids
+}
@@ -0,0 +1,13 @@
+package models
+
+import "gorm.io/gorm"
+
+// PlantillaVisitaActividad representa una actividad que se copia en cada ocurrencia de la plantilla
+type PlantillaVisitaActividad struct {
+	gorm.Model
+	PlantillaProgramaVisitaID uint `json:"plantilla_programa_visita_id" gorm:"not null;index"`
+	ActividadID               uint `json:"actividad_id" gorm:"not null"`
+
+	// Relaciones
+	Actividad Actividad `json:"actividad,omitempty" gorm:"foreignKey:ActividadID"`
+}
@@ -0,0 +1,13 @@
+package models
+
+import "gorm.io/gorm"
+
+// PlantillaVisitaAutoridad representa una autoridad UTEQ asignada a cada ocurrencia de la plantilla
+type PlantillaVisitaAutoridad struct {
+	gorm.Model
+	PlantillaProgramaVisitaID uint `json:"plantilla_programa_visita_id" gorm:"not null;index"`
+	AutoridadUTEQID           uint `json:"autoridad_uteq_id" gorm:"not null"`
+
+	// Relaciones
+	AutoridadUTEQ AutoridadUTEQ `json:"autoridad_uteq,omitempty" gorm:"foreignKey:AutoridadUTEQID"`
+}
@@ -0,0 +1,13 @@
+package models
+
+import "gorm.io/gorm"
+
+// PlantillaVisitaEstudiante representa un estudiante universitario asignado a cada ocurrencia de la plantilla
+type PlantillaVisitaEstudiante struct {
+	gorm.Model
+	PlantillaProgramaVisitaID uint `json:"plantilla_programa_visita_id" gorm:"not null;index"`
+	EstudianteUniversitarioID uint `json:"estudiante_universitario_id" gorm:"not null"`
+
+	// Relaciones
+	EstudianteUniversitario EstudianteUniversitario `json:"estudiante_universitario,omitempty" gorm:"foreignKey:EstudianteUniversitarioID"`
+}
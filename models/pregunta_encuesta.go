@@ -0,0 +1,24 @@
+package models
+
+import "gorm.io/gorm"
+
+// Tipos de pregunta de una encuesta
+const (
+	TipoPreguntaLikert         = "likert"          // Escala de 1 a 5
+	TipoPreguntaOpcionMultiple = "opcion_multiple" // Una opción de la lista
+	TipoPreguntaTexto          = "texto"           // Respuesta libre
+)
+
+// PreguntaEncuesta representa una pregunta de una encuesta
+type PreguntaEncuesta struct {
+	gorm.Model
+	EncuestaID uint     `json:"encuesta_id" gorm:"not null;index"`
+	Orden      int      `json:"orden"`
+	Texto      string   `json:"texto" gorm:"not null"`
+	Tipo       string   `json:"tipo" gorm:"not null;size:20"`
+	Opciones   []string `json:"opciones,omitempty" gorm:"serializer:json"` // Solo para opcion_multiple
+	Requerida  bool     `json:"requerida" gorm:"default:true"`
+}
+
+// TableName fija el nombre de la tabla usado en las consultas de resultados
+func (PreguntaEncuesta) TableName() string { return "preguntas_encuesta" }
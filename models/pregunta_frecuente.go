@@ -0,0 +1,19 @@
+package models
+
+import "gorm.io/gorm"
+
+// PreguntaFrecuente es una entrada del FAQ público, normalmente promovida desde una duda pública respondida
+type PreguntaFrecuente struct {
+	gorm.Model
+	DudaID      *uint  `json:"duda_id,omitempty" gorm:"uniqueIndex"` // Duda de origen, si fue promovida
+	Pregunta    string `json:"pregunta" gorm:"type:text;not null"`
+	Respuesta   string `json:"respuesta" gorm:"type:text;not null"`
+	Categoria   string `json:"categoria" gorm:"not null;default:'General';index"`
+	Orden       int    `json:"orden" gorm:"not null;default:0"`
+	Publicada   bool   `json:"publicada" gorm:"not null;default:true"`
+	CreadaPorID uint   `json:"creada_por_id"`
+}
+
+func (PreguntaFrecuente) TableName() string {
+	return "preguntas_frecuentes"
+}
@@ -11,7 +11,15 @@ type ProgramaVisita struct {
 	Fecha         time.Time   `json:"fecha" gorm:"not null"`
 	Fechafin         time.Time   `json:"fechafin" gorm:"null"`
 	InstitucionID uint        `json:"institucion_id" gorm:"not null"`
+
+	// Recurrencia (solo para programas generados desde una plantilla)
+	PlantillaID     *uint      `json:"plantilla_id,omitempty" gorm:"index"`
+	FechaOcurrencia *time.Time `json:"fecha_ocurrencia,omitempty"` // Fecha original de la ocurrencia (RECURRENCE-ID)
+	Excepcion       bool       `json:"excepcion" gorm:"default:false"` // Editada individualmente ("solo esta")
+
+	// Cierre de la visita (habilita el envío de encuestas)
+	CompletadaEn *time.Time `json:"completada_en,omitempty"`
 	
 	// Relaciones
 	Institucion   Institucion   `json:"institucion,omitempty" gorm:"foreignKey:InstitucionID"`
-}
\ No newline at end of file
+}
@@ -0,0 +1,25 @@
+package models
+
+import "gorm.io/gorm"
+
+// ReglaAsignacionDuda asigna automáticamente una autoridad a las dudas nuevas que cumplen todos sus criterios.
+// Los criterios vacíos no se evalúan; las reglas se prueban por prioridad ascendente.
+type ReglaAsignacionDuda struct {
+	gorm.Model
+	Nombre          string `json:"nombre" gorm:"not null"`
+	Prioridad       int    `json:"prioridad" gorm:"not null;default:100"`
+	TematicaID      *uint  `json:"tematica_id,omitempty"`
+	InstitucionID   *uint  `json:"institucion_id,omitempty"`
+	PalabraClave    string `json:"palabra_clave"`
+	AutoridadUTEQID uint   `json:"autoridad_uteq_id" gorm:"not null"`
+	Activa          bool   `json:"activa" gorm:"not null;default:true"`
+
+	// Relaciones
+	Tematica      *Tematica      `json:"tematica,omitempty" gorm:"foreignKey:TematicaID"`
+	Institucion   *Institucion   `json:"institucion,omitempty" gorm:"foreignKey:InstitucionID"`
+	AutoridadUTEQ *AutoridadUTEQ `json:"autoridad_uteq,omitempty" gorm:"foreignKey:AutoridadUTEQID"`
+}
+
+func (ReglaAsignacionDuda) TableName() string {
+	return "reglas_asignacion_duda"
+}
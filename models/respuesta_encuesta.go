@@ -0,0 +1,16 @@
+package models
+
+import "gorm.io/gorm"
+
+// RespuestaEncuesta representa una respuesta anónima a una encuesta
+type RespuestaEncuesta struct {
+	gorm.Model
+	EncuestaID       uint `json:"encuesta_id" gorm:"not null;index"`
+	ProgramaVisitaID uint `json:"programa_visita_id" gorm:"not null;index"`
+
+	// Relaciones
+	Respuestas []RespuestaPregunta `json:"respuestas,omitempty" gorm:"foreignKey:RespuestaEncuestaID"`
+}
+
+// TableName fija el nombre de la tabla usado en las consultas de resultados
+func (RespuestaEncuesta) TableName() string { return "respuestas_encuesta" }
@@ -0,0 +1,16 @@
+package models
+
+import "gorm.io/gorm"
+
+// RespuestaPregunta representa la respuesta a una pregunta dentro de una respuesta de encuesta
+type RespuestaPregunta struct {
+	gorm.Model
+	RespuestaEncuestaID uint   `json:"respuesta_encuesta_id" gorm:"not null;index"`
+	PreguntaEncuestaID  uint   `json:"pregunta_encuesta_id" gorm:"not null;index"`
+	Valor               *int   `json:"valor,omitempty"`  // likert
+	Opcion              string `json:"opcion,omitempty"` // opcion_multiple
+	Texto               string `json:"texto,omitempty"`  // texto
+}
+
+// TableName fija el nombre de la tabla usado en las consultas de resultados
+func (RespuestaPregunta) TableName() string { return "respuestas_pregunta" }
@@ -0,0 +1,22 @@
+package models
+
+import "gorm.io/gorm"
+
+// RevisionNoticia guarda el contenido de una noticia en cada versión
+type RevisionNoticia struct {
+	gorm.Model
+	NoticiaID       uint   `json:"noticia_id" gorm:"not null;uniqueIndex:idx_revision_noticia_version"`
+	Version         int    `json:"version" gorm:"not null;uniqueIndex:idx_revision_noticia_version"`
+	Titulo          string `json:"titulo" gorm:"not null"`
+	Descripcion     string `json:"descripcion" gorm:"type:text"`
+	URLNoticia      string `json:"url_noticia"`
+	ImagenPortadaID *uint  `json:"imagen_portada_id"` // Sin clave foránea: el historial conserva el ID aunque el archivo se elimine
+	CategoriaID     *uint  `json:"categoria_id"`
+	Etiquetas       string `json:"etiquetas"`                  // Nombres separados por coma
+	UsuarioID       uint   `json:"usuario_id" gorm:"not null"` // Usuario que hizo el cambio
+	Motivo          string `json:"motivo"`
+}
+
+func (RevisionNoticia) TableName() string {
+	return "revisiones_noticia"
+}
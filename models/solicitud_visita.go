@@ -0,0 +1,50 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Estados de una solicitud de visita
+const (
+	EstadoSolicitudPendiente = "pendiente"
+	EstadoSolicitudAprobada  = "aprobada"
+	EstadoSolicitudRechazada = "rechazada"
+)
+
+// SolicitudVisita representa el pedido de visita enviado por una institución desde el formulario público
+type SolicitudVisita struct {
+	gorm.Model
+	CodigoSeguimiento string     `json:"codigo_seguimiento" gorm:"uniqueIndex;size:32;not null"`
+	InstitucionID     *uint      `json:"institucion_id,omitempty" gorm:"index"`
+	NombreInstitucion string     `json:"nombre_institucion"`
+	NombreContacto    string     `json:"nombre_contacto" gorm:"not null"`
+	CorreoContacto    string     `json:"correo_contacto" gorm:"not null"`
+	TelefonoContacto  string     `json:"telefono_contacto"`
+	FechaPreferida    time.Time  `json:"fecha_preferida" gorm:"not null"`
+	FechaAlternativa  *time.Time `json:"fecha_alternativa,omitempty"`
+	NumeroEstudiantes int        `json:"numero_estudiantes" gorm:"not null"`
+	Comentarios       string     `json:"comentarios"`
+
+	// Revisión por el personal UTEQ
+	Estado           string     `json:"estado" gorm:"not null;default:'pendiente';size:20;index"`
+	MotivoRechazo    string     `json:"motivo_rechazo,omitempty"`
+	RevisadoPorID    *uint      `json:"revisado_por_id,omitempty"`
+	FechaRevision    *time.Time `json:"fecha_revision,omitempty"`
+	ProgramaVisitaID *uint      `json:"programa_visita_id,omitempty"`
+
+	// Relaciones
+	Institucion    *Institucion              `json:"institucion,omitempty" gorm:"foreignKey:InstitucionID"`
+	ProgramaVisita *ProgramaVisita           `json:"programa_visita,omitempty" gorm:"foreignKey:ProgramaVisitaID"`
+	Tematicas      []SolicitudVisitaTematica `json:"tematicas,omitempty" gorm:"foreignKey:SolicitudVisitaID"`
+}
+
+// TematicaIDs devuelve los IDs de las temáticas preferidas
+func (s *SolicitudVisita) TematicaIDs() []uint {
+	ids := make([]uint, 0, len(s.Tematicas))
+	for _, t := range s.Tematicas {
+		ids = append(ids, t.TematicaID)
+	}
+	return ids
+}
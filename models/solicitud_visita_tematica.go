@@ -0,0 +1,13 @@
+package models
+
+import "gorm.io/gorm"
+
+// SolicitudVisitaTematica representa una temática preferida en una solicitud de visita
+type SolicitudVisitaTematica struct {
+	gorm.Model
+	SolicitudVisitaID uint `json:"solicitud_visita_id" gorm:"not null;index"`
+	TematicaID        uint `json:"tematica_id" gorm:"not null"`
+
+	// Relaciones
+	Tematica Tematica `json:"tematica,omitempty" gorm:"foreignKey:TematicaID"`
+}
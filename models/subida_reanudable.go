@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// SubidaReanudable es una subida por partes (protocolo tus) que se completa en varias peticiones.
+// Las partes se guardan en el almacenamiento bajo subidas/<identificador>/.
+type SubidaReanudable struct {
+	gorm.Model
+	Identificador  string    `json:"identificador" gorm:"size:32;not null;uniqueIndex"`
+	UsuarioID      uint      `json:"usuario_id" gorm:"not null;index"`
+	NombreArchivo  string    `json:"nombre_archivo" gorm:"not null"`
+	Visibilidad    string    `json:"visibilidad" gorm:"size:10;not null;default:'publico'"`
+	Tamano         int64     `json:"tamano" gorm:"not null"`                   // Tamaño total declarado al crear la subida
+	Desplazamiento int64     `json:"desplazamiento" gorm:"not null;default:0"` // Bytes recibidos hasta ahora
+	SHA256         string    `json:"sha256,omitempty" gorm:"size:64"`          // Hash esperado del archivo completo (opcional)
+	ExpiraEn       time.Time `json:"expira_en" gorm:"not null;index"`
+	ArchivoID      *uint     `json:"archivo_id,omitempty"` // Archivo registrado al completar la subida
+
+	// Relaciones
+	Partes []ParteSubida `json:"-" gorm:"foreignKey:SubidaID;constraint:OnDelete:CASCADE"`
+}
+
+func (SubidaReanudable) TableName() string {
+	return "subidas_reanudables"
+}
+
+// ParteSubida es un fragmento recibido de una subida reanudable
+type ParteSubida struct {
+	ID             uint   `json:"id" gorm:"primaryKey"`
+	SubidaID       uint   `json:"subida_id" gorm:"not null;uniqueIndex:idx_parte_subida"`
+	Desplazamiento int64  `json:"desplazamiento" gorm:"not null;uniqueIndex:idx_parte_subida"`
+	Tamano         int64  `json:"tamano" gorm:"not null"`
+	Clave          string `json:"-" gorm:"not null"` // Clave de la parte en el almacenamiento
+}
+
+func (ParteSubida) TableName() string {
+	return "partes_subida"
+}
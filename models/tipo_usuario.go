@@ -1,6 +1,16 @@
 package models
 
-import "gorm.io/gorm"
+import (
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// NombreTipoUsuarioAdministrador es el tipo de usuario con acceso a todas las dudas, incluidas las privadas
+const NombreTipoUsuarioAdministrador = "Administrador"
+
+// NombreTipoUsuarioEditor es el tipo de usuario que revisa y publica noticias
+const NombreTipoUsuarioEditor = "Editor"
 
 // TipoUsuario representa los diferentes tipos de usuarios del sistema
 type TipoUsuario struct {
@@ -10,4 +20,14 @@ type TipoUsuario struct {
 	
 	// Relaciones
 	Usuarios []Usuario `json:"usuarios,omitempty" gorm:"foreignKey:TipoUsuarioID"`
-}
\ No newline at end of file
+}
+
+// EsAdministrador indica si el tipo corresponde a un administrador del sistema
+func (t TipoUsuario) EsAdministrador() bool {
+	return strings.EqualFold(strings.TrimSpace(t.Nombre), NombreTipoUsuarioAdministrador)
+}
+
+// PuedePublicarNoticias indica si el tipo puede aprobar, programar, publicar y archivar noticias
+func (t TipoUsuario) PuedePublicarNoticias() bool {
+	return t.EsAdministrador() || strings.EqualFold(strings.TrimSpace(t.Nombre), NombreTipoUsuarioEditor)
+}
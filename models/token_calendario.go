@@ -0,0 +1,13 @@
+package models
+
+import "gorm.io/gorm"
+
+// TokenCalendario representa el token secreto con el que un usuario suscribe su feed iCalendar
+type TokenCalendario struct {
+	gorm.Model
+	UsuarioID uint   `json:"usuario_id" gorm:"not null;uniqueIndex"`
+	Token     string `json:"token" gorm:"not null;uniqueIndex;size:64"`
+
+	// Relaciones
+	Usuario Usuario `json:"usuario,omitempty" gorm:"foreignKey:UsuarioID"`
+}
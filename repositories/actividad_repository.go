@@ -2,6 +2,7 @@ package repositories
 
 import (
 	"ApiEscuela/models"
+	"context"
 	"gorm.io/gorm"
 )
 
@@ -13,6 +14,12 @@ func NewActividadRepository(db *gorm.DB) *ActividadRepository {
 	return &ActividadRepository{db: db}
 }
 
+// ConContexto devuelve una copia del repositorio cuyas consultas usan ctx, para que queden dentro de la
+// traza de la petición
+func (r *ActividadRepository) ConContexto(ctx context.Context) *ActividadRepository {
+	return &ActividadRepository{db: r.db.WithContext(ctx)}
+}
+
 // CreateActividad crea una nueva actividad
 func (r *ActividadRepository) CreateActividad(actividad *models.Actividad) error {
 	return r.db.Create(actividad).Error
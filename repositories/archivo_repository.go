@@ -0,0 +1,112 @@
+package repositories
+
+import (
+	"ApiEscuela/models"
+	"context"
+
+	"gorm.io/gorm"
+)
+
+type ArchivoRepository struct {
+	db *gorm.DB
+}
+
+func NewArchivoRepository(db *gorm.DB) *ArchivoRepository {
+	return &ArchivoRepository{db: db}
+}
+
+// ConContexto devuelve una copia del repositorio cuyas consultas usan ctx, para que queden dentro de la
+// traza de la petición
+func (r *ArchivoRepository) ConContexto(ctx context.Context) *ArchivoRepository {
+	return &ArchivoRepository{db: r.db.WithContext(ctx)}
+}
+
+// CreateArchivo registra un archivo subido
+func (r *ArchivoRepository) CreateArchivo(archivo *models.Archivo) error {
+	return r.db.Create(archivo).Error
+}
+
+// GetArchivoByID obtiene un archivo con sus permisos
+func (r *ArchivoRepository) GetArchivoByID(id uint) (*models.Archivo, error) {
+	var archivo models.Archivo
+	if err := r.db.Preload("Permisos").First(&archivo, id).Error; err != nil {
+		return nil, err
+	}
+	return &archivo, nil
+}
+
+// GetArchivoByUbicacion obtiene el registro de un archivo por su carpeta y nombre en disco
+func (r *ArchivoRepository) GetArchivoByUbicacion(carpeta, nombre string) (*models.Archivo, error) {
+	var archivo models.Archivo
+	err := r.db.Where("carpeta = ? AND nombre_almacenado = ?", carpeta, nombre).First(&archivo).Error
+	if err != nil {
+		return nil, err
+	}
+	return &archivo, nil
+}
+
+// GetArchivos lista archivos, del más reciente al más antiguo; usuarioID y carpeta son filtros opcionales
+func (r *ArchivoRepository) GetArchivos(usuarioID *uint, carpeta string) ([]models.Archivo, error) {
+	var archivos []models.Archivo
+	query := r.db.Order("id DESC")
+	if usuarioID != nil {
+		query = query.Where("usuario_id = ?", *usuarioID)
+	}
+	if carpeta != "" {
+		query = query.Where("carpeta = ?", carpeta)
+	}
+	err := query.Find(&archivos).Error
+	return archivos, err
+}
+
+// EsPortadaDeNoticia indica si alguna noticia usa el archivo como imagen de portada
+func (r *ArchivoRepository) EsPortadaDeNoticia(id uint) (bool, error) {
+	var total int64
+	err := r.db.Model(&models.Noticia{}).Where("imagen_portada_id = ?", id).Count(&total).Error
+	return total > 0, err
+}
+
+// UpdateArchivo actualiza los datos de un archivo
+func (r *ArchivoRepository) UpdateArchivo(archivo *models.Archivo) error {
+	return r.db.Omit("Permisos").Save(archivo).Error
+}
+
+// DeleteArchivo elimina definitivamente el registro de un archivo y sus permisos
+func (r *ArchivoRepository) DeleteArchivo(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("archivo_id = ?", id).Delete(&models.PermisoArchivo{}).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Delete(&models.Archivo{}, id).Error
+	})
+}
+
+// CreatePermiso concede un permiso de lectura
+func (r *ArchivoRepository) CreatePermiso(permiso *models.PermisoArchivo) error {
+	return r.db.Create(permiso).Error
+}
+
+// ExistePermiso indica si el archivo ya tiene un permiso igual
+func (r *ArchivoRepository) ExistePermiso(archivoID uint, usuarioID, tipoUsuarioID *uint) (bool, error) {
+	query := r.db.Model(&models.PermisoArchivo{}).Where("archivo_id = ?", archivoID)
+	if usuarioID != nil {
+		query = query.Where("usuario_id = ?", *usuarioID)
+	} else {
+		query = query.Where("tipo_usuario_id = ?", *tipoUsuarioID)
+	}
+	var total int64
+	err := query.Count(&total).Error
+	return total > 0, err
+}
+
+// DeletePermiso revoca un permiso del archivo
+func (r *ArchivoRepository) DeletePermiso(archivoID, permisoID uint) error {
+	res := r.db.Unscoped().Where("archivo_id = ?", archivoID).Delete(&models.PermisoArchivo{}, permisoID)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
@@ -2,6 +2,7 @@ package repositories
 
 import (
 	"ApiEscuela/models"
+	"context"
 	"errors"
 	"strings"
 
@@ -9,7 +10,19 @@ import (
 )
 
 type AutoridadUTEQRepository struct {
-	db *gorm.DB
+	db    *gorm.DB
+	visor VisorDudas // Filtra las dudas incluidas; sin ConVisor, solo las públicas
+}
+
+// ConContexto devuelve una copia del repositorio cuyas consultas usan ctx, para que queden dentro de la
+// traza de la petición (incluidas las de cada Preload)
+func (r *AutoridadUTEQRepository) ConContexto(ctx context.Context) *AutoridadUTEQRepository {
+	return &AutoridadUTEQRepository{db: r.db.WithContext(ctx), visor: r.visor}
+}
+
+// ConVisor devuelve una copia del repositorio que incluye las dudas que visor puede ver
+func (r *AutoridadUTEQRepository) ConVisor(visor VisorDudas) *AutoridadUTEQRepository {
+	return &AutoridadUTEQRepository{db: r.db, visor: visor}
 }
 
 var (
@@ -64,7 +77,7 @@ func (r *AutoridadUTEQRepository) CreateAutoridadUTEQ(autoridad *models.Autorida
 func (r *AutoridadUTEQRepository) GetAutoridadUTEQByID(id uint) (*models.AutoridadUTEQ, error) {
 	var autoridad models.AutoridadUTEQ
 	err := r.db.Preload("Persona").Preload("DetalleAutoridadDetallesVisitas").
-		Preload("Dudas").First(&autoridad, id).Error
+		Preload("Dudas", r.visor.filtrar).First(&autoridad, id).Error
 	if err != nil {
 		return nil, err
 	}
@@ -75,7 +88,7 @@ func (r *AutoridadUTEQRepository) GetAutoridadUTEQByID(id uint) (*models.Autorid
 func (r *AutoridadUTEQRepository) GetAllAutoridadesUTEQ() ([]models.AutoridadUTEQ, error) {
 	var autoridades []models.AutoridadUTEQ
 	err := r.db.Preload("Persona").Preload("DetalleAutoridadDetallesVisitas").
-		Preload("Dudas").Find(&autoridades).Error
+		Preload("Dudas", r.visor.filtrar).Find(&autoridades).Error
 	return autoridades, err
 }
 
@@ -183,7 +196,7 @@ func (r *AutoridadUTEQRepository) RestoreAutoridadUTEQ(id uint) error {
 func (r *AutoridadUTEQRepository) GetAllAutoridadesUTEQIncludingDeleted() ([]models.AutoridadUTEQ, error) {
 	var autoridades []models.AutoridadUTEQ
 	err := r.db.Unscoped().Preload("Persona").Preload("DetalleAutoridadDetallesVisitas").
-		Preload("Dudas").Find(&autoridades).Error
+		Preload("Dudas", r.visor.filtrar).Find(&autoridades).Error
 	return autoridades, err
 }
 
@@ -192,7 +205,7 @@ func (r *AutoridadUTEQRepository) GetDeletedAutoridadesUTEQ() ([]models.Autorida
 	var autoridades []models.AutoridadUTEQ
 	err := r.db.Unscoped().Where("deleted_at IS NOT NULL").
 		Preload("Persona").Preload("DetalleAutoridadDetallesVisitas").
-		Preload("Dudas").Find(&autoridades).Error
+		Preload("Dudas", r.visor.filtrar).Find(&autoridades).Error
 	return autoridades, err
 }
 
@@ -201,7 +214,7 @@ func (r *AutoridadUTEQRepository) GetAutoridadesUTEQByCargo(cargo string) ([]mod
 	var autoridades []models.AutoridadUTEQ
 	err := r.db.Where("cargo ILIKE ?", "%"+cargo+"%").
 		Preload("Persona").Preload("DetalleAutoridadDetallesVisitas").
-		Preload("Dudas").Find(&autoridades).Error
+		Preload("Dudas", r.visor.filtrar).Find(&autoridades).Error
 	return autoridades, err
 }
 
@@ -210,7 +223,7 @@ func (r *AutoridadUTEQRepository) GetAutoridadUTEQByPersona(personaID uint) (*mo
 	var autoridad models.AutoridadUTEQ
 	err := r.db.Where("persona_id = ?", personaID).
 		Preload("Persona").Preload("DetalleAutoridadDetallesVisitas").
-		Preload("Dudas").First(&autoridad).Error
+		Preload("Dudas", r.visor.filtrar).First(&autoridad).Error
 	if err != nil {
 		return nil, err
 	}
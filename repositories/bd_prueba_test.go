@@ -0,0 +1,72 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// consultaGenerada es una sentencia construida por GORM con sus parámetros
+type consultaGenerada struct {
+	sql  string
+	vars []interface{}
+}
+
+// dbSinConexion construye las consultas de PostgreSQL sin ejecutarlas y guarda las sentencias generadas
+func dbSinConexion(t *testing.T) (*gorm.DB, *[]consultaGenerada) {
+	t.Helper()
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost dbname=escuela"}), &gorm.Config{
+		DryRun:                 true,
+		DisableAutomaticPing:   true,
+		SkipDefaultTransaction: true,
+		Logger:                 logger.Discard,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.ConnPool = &conexionSimulada{}
+	db.Statement.ConnPool = db.ConnPool
+	var consultas []consultaGenerada
+	err = db.Callback().Query().After("gorm:query").Register("prueba:sql", func(d *gorm.DB) {
+		consultas = append(consultas, consultaGenerada{sql: d.Statement.SQL.String(), vars: d.Statement.Vars})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db, &consultas
+}
+
+// conexionSimulada permite abrir transacciones en DryRun, donde ninguna sentencia llega a ejecutarse
+type conexionSimulada struct{}
+
+func (*conexionSimulada) PrepareContext(context.Context, string) (*sql.Stmt, error) {
+	return nil, sql.ErrConnDone
+}
+
+func (*conexionSimulada) ExecContext(context.Context, string, ...interface{}) (sql.Result, error) {
+	return nil, sql.ErrConnDone
+}
+
+func (*conexionSimulada) QueryContext(context.Context, string, ...interface{}) (*sql.Rows, error) {
+	return nil, sql.ErrConnDone
+}
+
+func (*conexionSimulada) QueryRowContext(context.Context, string, ...interface{}) *sql.Row {
+	return nil
+}
+
+func (c *conexionSimulada) BeginTx(context.Context, *sql.TxOptions) (gorm.ConnPool, error) {
+	return c, nil
+}
+
+func (*conexionSimulada) Commit() error {
+	return nil
+}
+
+func (*conexionSimulada) Rollback() error {
+	return nil
+}
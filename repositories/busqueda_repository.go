@@ -0,0 +1,163 @@
+package repositories
+
+import (
+	"ApiEscuela/models"
+	"context"
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// configuracionBusqueda es la configuración de texto completo en español que además ignora las tildes
+const configuracionBusqueda = "es_unaccent"
+
+// opcionesFragmento controla los fragmentos resaltados que devuelve ts_headline
+const opcionesFragmento = "StartSel=<mark>, StopSel=</mark>, MaxWords=30, MinWords=10, MaxFragments=2, FragmentDelimiter= … "
+
+// Tipos de resultado de la búsqueda unificada
+const (
+	TipoBusquedaNoticia     = "noticia"
+	TipoBusquedaDuda        = "duda"
+	TipoBusquedaActividad   = "actividad"
+	TipoBusquedaInstitucion = "institucion"
+)
+
+// ResultadoBusqueda es un resultado de la búsqueda de texto completo
+type ResultadoBusqueda struct {
+	Tipo       string  `json:"tipo"`
+	ID         uint    `json:"id"`
+	Titulo     string  `json:"titulo"`
+	Fragmento  string  `json:"fragmento"`
+	Relevancia float64 `json:"relevancia"`
+}
+
+// columnaBusqueda describe la columna tsvector generada de una tabla y los campos que la componen con su peso
+type columnaBusqueda struct {
+	modelo interface{}
+	campos []campoBusqueda
+}
+
+type campoBusqueda struct {
+	columna string
+	peso    string
+}
+
+var columnasBusqueda = []columnaBusqueda{
+	{&models.Noticia{}, []campoBusqueda{{"titulo", "A"}, {"descripcion", "B"}}},
+	{&models.Dudas{}, []campoBusqueda{{"pregunta", "A"}, {"respuesta", "B"}}},
+	{&models.Actividad{}, []campoBusqueda{{"actividad", "A"}}},
+	{&models.Institucion{}, []campoBusqueda{{"nombre", "A"}, {"autoridad", "B"}, {"direccion", "C"}}},
+}
+
+type BusquedaRepository struct {
+	db *gorm.DB
+}
+
+func NewBusquedaRepository(db *gorm.DB) *BusquedaRepository {
+	return &BusquedaRepository{db: db}
+}
+
+// ConContexto devuelve una copia del repositorio cuyas consultas usan ctx, para que queden dentro de la
+// traza de la petición
+func (r *BusquedaRepository) ConContexto(ctx context.Context) *BusquedaRepository {
+	return &BusquedaRepository{db: r.db.WithContext(ctx)}
+}
+
+// PrepararBusqueda instala unaccent, crea la configuración es_unaccent y agrega las columnas tsvector
+// generadas con sus índices GIN. Es idempotente.
+func (r *BusquedaRepository) PrepararBusqueda() error {
+	sentencias := []string{
+		"CREATE EXTENSION IF NOT EXISTS unaccent",
+		`DO $$
+		BEGIN
+			IF NOT EXISTS (SELECT 1 FROM pg_ts_config WHERE cfgname = '` + configuracionBusqueda + `') THEN
+				CREATE TEXT SEARCH CONFIGURATION ` + configuracionBusqueda + ` (COPY = spanish);
+				ALTER TEXT SEARCH CONFIGURATION ` + configuracionBusqueda + `
+					ALTER MAPPING FOR hword, hword_part, word WITH unaccent, spanish_stem;
+			END IF;
+		END $$`,
+	}
+	for _, sentencia := range sentencias {
+		if err := r.db.Exec(sentencia).Error; err != nil {
+			return err
+		}
+	}
+
+	for _, c := range columnasBusqueda {
+		tabla, err := nombreTabla(r.db, c.modelo)
+		if err != nil {
+			return err
+		}
+		expresion := ""
+		for i, campo := range c.campos {
+			if i > 0 {
+				expresion += " || "
+			}
+			expresion += fmt.Sprintf("setweight(to_tsvector('%s', coalesce(%s, '')), '%s')", configuracionBusqueda, campo.columna, campo.peso)
+		}
+		if err := r.db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN IF NOT EXISTS busqueda tsvector GENERATED ALWAYS AS (%s) STORED", tabla, expresion)).Error; err != nil {
+			return err
+		}
+		if err := r.db.Exec(fmt.Sprintf("CREATE INDEX IF NOT EXISTS idx_%s_busqueda ON %s USING GIN (busqueda)", tabla, tabla)).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// BuscarNoticias busca noticias publicadas por relevancia
+func (r *BusquedaRepository) BuscarNoticias(termino string, limite int) ([]ResultadoBusqueda, error) {
+	return r.buscar(r.db.Scopes(noticiaVisible), &models.Noticia{}, TipoBusquedaNoticia, "titulo", "coalesce(nullif(descripcion, ''), titulo)", termino, limite)
+}
+
+// BuscarDudas busca dudas por relevancia respetando su privacidad
+func (r *BusquedaRepository) BuscarDudas(visor VisorDudas, termino string, limite int) ([]ResultadoBusqueda, error) {
+	return r.buscar(r.db.Scopes(visor.filtrar), &models.Dudas{}, TipoBusquedaDuda, "pregunta", "pregunta || ' ' || coalesce(respuesta, '')", termino, limite)
+}
+
+// BuscarActividades busca actividades por relevancia
+func (r *BusquedaRepository) BuscarActividades(termino string, limite int) ([]ResultadoBusqueda, error) {
+	return r.buscar(r.db, &models.Actividad{}, TipoBusquedaActividad, "actividad", "actividad", termino, limite)
+}
+
+// BuscarInstituciones busca instituciones por relevancia
+func (r *BusquedaRepository) BuscarInstituciones(termino string, limite int) ([]ResultadoBusqueda, error) {
+	return r.buscar(r.db, &models.Institucion{}, TipoBusquedaInstitucion, "nombre", "concat_ws(' - ', nombre, nullif(autoridad, ''), nullif(direccion, ''))", termino, limite)
+}
+
+func (r *BusquedaRepository) buscar(db *gorm.DB, modelo interface{}, tipo, titulo, texto, termino string, limite int) ([]ResultadoBusqueda, error) {
+	var resultados []ResultadoBusqueda
+	err := db.Model(modelo).
+		Select(fmt.Sprintf("? AS tipo, id, %s AS titulo, ts_headline(?, %s, websearch_to_tsquery(?, ?), ?) AS fragmento, ts_rank_cd(busqueda, websearch_to_tsquery(?, ?)) AS relevancia", titulo, escaparHTMLSQL(texto)),
+			tipo, configuracionBusqueda, configuracionBusqueda, termino, opcionesFragmento, configuracionBusqueda, termino).
+		Where(coincideBusqueda(termino)).
+		Order("relevancia DESC, id DESC").
+		Limit(limite).
+		Scan(&resultados).Error
+	return resultados, err
+}
+
+// coincideBusqueda filtra las filas cuya columna tsvector coincide con el término
+func coincideBusqueda(termino string) clause.Expr {
+	return gorm.Expr("busqueda @@ websearch_to_tsquery(?, ?)", configuracionBusqueda, termino)
+}
+
+// ordenRelevancia ordena por relevancia respecto al término
+func ordenRelevancia(termino string) clause.OrderBy {
+	return clause.OrderBy{Expression: gorm.Expr("ts_rank_cd(busqueda, websearch_to_tsquery(?, ?)) DESC", configuracionBusqueda, termino)}
+}
+
+// escaparHTMLSQL escapa el texto antes de resaltarlo, para que solo las marcas <mark> sean HTML
+func escaparHTMLSQL(expresion string) string {
+	return fmt.Sprintf("replace(replace(replace(%s, '&', '&amp;'), '<', '&lt;'), '>', '&gt;')", expresion)
+}
+
+// nombreTabla obtiene el nombre de tabla que GORM usa para un modelo
+func nombreTabla(db *gorm.DB, modelo interface{}) (string, error) {
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(modelo); err != nil {
+		return "", err
+	}
+	return stmt.Schema.Table, nil
+}
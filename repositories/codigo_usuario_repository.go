@@ -2,6 +2,7 @@ package repositories
 
 import (
 	"ApiEscuela/models"
+	"context"
 	"time"
 
 	"gorm.io/gorm"
@@ -22,6 +23,12 @@ func NewCodigoUsuarioRepository(db *gorm.DB) *CodigoUsuarioRepository {
 	return &CodigoUsuarioRepository{db: db}
 }
 
+// ConContexto devuelve una copia del repositorio cuyas consultas usan ctx, para que queden dentro de la
+// traza de la petición
+func (r *CodigoUsuarioRepository) ConContexto(ctx context.Context) *CodigoUsuarioRepository {
+	return &CodigoUsuarioRepository{db: r.db.WithContext(ctx)}
+}
+
 // Crear inserta un nuevo código para un usuario con expiración de 3 minutos
 func (r *CodigoUsuarioRepository) Crear(usuarioID uint, codigo string) error {
 	expiraEn := time.Now().Add(3 * time.Minute)
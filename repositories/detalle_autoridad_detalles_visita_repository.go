@@ -2,6 +2,9 @@ package repositories
 
 import (
 	"ApiEscuela/models"
+	"context"
+	"time"
+
 	"gorm.io/gorm"
 )
 
@@ -13,6 +16,12 @@ func NewDetalleAutoridadDetallesVisitaRepository(db *gorm.DB) *DetalleAutoridadD
 	return &DetalleAutoridadDetallesVisitaRepository{db: db}
 }
 
+// ConContexto devuelve una copia del repositorio cuyas consultas usan ctx, para que queden dentro de la
+// traza de la petición
+func (r *DetalleAutoridadDetallesVisitaRepository) ConContexto(ctx context.Context) *DetalleAutoridadDetallesVisitaRepository {
+	return &DetalleAutoridadDetallesVisitaRepository{db: r.db.WithContext(ctx)}
+}
+
 // CreateDetalleAutoridadDetallesVisita crea un nuevo detalle de autoridad para visita
 func (r *DetalleAutoridadDetallesVisitaRepository) CreateDetalleAutoridadDetallesVisita(detalle *models.DetalleAutoridadDetallesVisita) error {
 	return r.db.Create(detalle).Error
@@ -59,6 +68,18 @@ func (r *DetalleAutoridadDetallesVisitaRepository) GetDetallesByProgramaVisitaID
 	return detalles, err
 }
 
+// GetDetallesParaNotificar obtiene las asignaciones vigentes de un programa y, si el programa fue
+// eliminado, las que se eliminaron junto con él (mismo deleted_at)
+func (r *DetalleAutoridadDetallesVisitaRepository) GetDetallesParaNotificar(programaVisitaID uint) ([]models.DetalleAutoridadDetallesVisita, error) {
+	var detalles []models.DetalleAutoridadDetallesVisita
+	eliminacionPrograma := r.db.Unscoped().Model(&models.ProgramaVisita{}).Select("deleted_at").Where("id = ?", programaVisitaID)
+	err := r.db.Unscoped().
+		Where("programa_visita_id = ?", programaVisitaID).
+		Where("deleted_at IS NULL OR deleted_at = (?)", eliminacionPrograma).
+		Find(&detalles).Error
+	return detalles, err
+}
+
 // GetDetallesByAutoridadID obtiene todos los detalles de una autoridad específica
 func (r *DetalleAutoridadDetallesVisitaRepository) GetDetallesByAutoridadID(autoridadID uint) ([]models.DetalleAutoridadDetallesVisita, error) {
 	var detalles []models.DetalleAutoridadDetallesVisita
@@ -121,4 +142,41 @@ func (r *DetalleAutoridadDetallesVisitaRepository) GetEstadisticasAsignacion() (
 		"total_programas_con_autoridades":  totalProgramasUnicos,
 		"promedio_autoridades_por_programa": promedioAutoridadesPorPrograma,
 	}, nil
-}
\ No newline at end of file
+}
+
+// GetDetallesCalendarioByAutoridad obtiene las asignaciones de una autoridad para su calendario,
+// incluyendo las eliminadas después de eliminadosDesde para poder publicarlas como canceladas
+func (r *DetalleAutoridadDetallesVisitaRepository) GetDetallesCalendarioByAutoridad(autoridadID uint, eliminadosDesde time.Time) ([]models.DetalleAutoridadDetallesVisita, error) {
+	var detalles []models.DetalleAutoridadDetallesVisita
+	err := r.db.Unscoped().
+		Where("autoridad_uteq_id = ?", autoridadID).
+		Where("deleted_at IS NULL OR deleted_at > ?", eliminadosDesde).
+		Preload("ProgramaVisita", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).
+		Preload("ProgramaVisita.Institucion").
+		Find(&detalles).Error
+	return detalles, err
+}
+
+// GetDetalleByIDIncludingDeleted obtiene un detalle por ID incluyendo los eliminados
+func (r *DetalleAutoridadDetallesVisitaRepository) GetDetalleByIDIncludingDeleted(id uint) (*models.DetalleAutoridadDetallesVisita, error) {
+	var detalle models.DetalleAutoridadDetallesVisita
+	err := r.db.Unscoped().
+		Preload("ProgramaVisita", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).
+		Preload("ProgramaVisita.Institucion").
+		Preload("AutoridadUTEQ").
+		Preload("AutoridadUTEQ.Persona").
+		First(&detalle, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &detalle, nil
+}
+
+// GetDetallesEnRango obtiene las asignaciones de autoridades cuyos programas inician entre desde y hasta
+func (r *DetalleAutoridadDetallesVisitaRepository) GetDetallesEnRango(desde, hasta time.Time) ([]models.DetalleAutoridadDetallesVisita, error) {
+	var detalles []models.DetalleAutoridadDetallesVisita
+	programas := r.db.Model(&models.ProgramaVisita{}).Select("id").Where("fecha BETWEEN ? AND ?", desde, hasta)
+	err := r.db.Where("programa_visita_id IN (?)", programas).
+		Preload("ProgramaVisita").Find(&detalles).Error
+	return detalles, err
+}
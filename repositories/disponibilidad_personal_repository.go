@@ -0,0 +1,151 @@
+package repositories
+
+import (
+	"ApiEscuela/models"
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type DisponibilidadPersonalRepository struct {
+	db *gorm.DB
+}
+
+func NewDisponibilidadPersonalRepository(db *gorm.DB) *DisponibilidadPersonalRepository {
+	return &DisponibilidadPersonalRepository{db: db}
+}
+
+// ConContexto devuelve una copia del repositorio cuyas consultas usan ctx, para que queden dentro de la
+// traza de la petición
+func (r *DisponibilidadPersonalRepository) ConContexto(ctx context.Context) *DisponibilidadPersonalRepository {
+	return &DisponibilidadPersonalRepository{db: r.db.WithContext(ctx)}
+}
+
+// CreateDisponibilidad crea una nueva franja de disponibilidad
+func (r *DisponibilidadPersonalRepository) CreateDisponibilidad(disponibilidad *models.DisponibilidadPersonal) error {
+	return r.db.Create(disponibilidad).Error
+}
+
+// GetDisponibilidadByID obtiene una franja de disponibilidad por ID
+func (r *DisponibilidadPersonalRepository) GetDisponibilidadByID(id uint) (*models.DisponibilidadPersonal, error) {
+	var disponibilidad models.DisponibilidadPersonal
+	err := r.db.First(&disponibilidad, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &disponibilidad, nil
+}
+
+// UpdateDisponibilidad actualiza una franja de disponibilidad
+func (r *DisponibilidadPersonalRepository) UpdateDisponibilidad(disponibilidad *models.DisponibilidadPersonal) error {
+	return r.db.Omit("AutoridadUTEQ", "EstudianteUniversitario").Save(disponibilidad).Error
+}
+
+// DeleteDisponibilidad elimina una franja de disponibilidad
+func (r *DisponibilidadPersonalRepository) DeleteDisponibilidad(id uint) error {
+	return r.db.Delete(&models.DisponibilidadPersonal{}, id).Error
+}
+
+// GetDisponibilidadesByAutoridad obtiene las franjas de una autoridad
+func (r *DisponibilidadPersonalRepository) GetDisponibilidadesByAutoridad(autoridadID uint) ([]models.DisponibilidadPersonal, error) {
+	var disponibilidades []models.DisponibilidadPersonal
+	err := r.db.Where("autoridad_uteq_id = ?", autoridadID).
+		Order("dia_semana, hora_inicio").Find(&disponibilidades).Error
+	return disponibilidades, err
+}
+
+// GetDisponibilidadesByEstudiante obtiene las franjas de un estudiante universitario
+func (r *DisponibilidadPersonalRepository) GetDisponibilidadesByEstudiante(estudianteID uint) ([]models.DisponibilidadPersonal, error) {
+	var disponibilidades []models.DisponibilidadPersonal
+	err := r.db.Where("estudiante_universitario_id = ?", estudianteID).
+		Order("dia_semana, hora_inicio").Find(&disponibilidades).Error
+	return disponibilidades, err
+}
+
+// GetDisponibilidadesAutoridadesByDia obtiene las franjas de todas las autoridades para un día de la semana
+func (r *DisponibilidadPersonalRepository) GetDisponibilidadesAutoridadesByDia(diaSemana int) ([]models.DisponibilidadPersonal, error) {
+	var disponibilidades []models.DisponibilidadPersonal
+	err := r.db.Where("autoridad_uteq_id IS NOT NULL AND dia_semana = ?", diaSemana).
+		Preload("AutoridadUTEQ").Preload("AutoridadUTEQ.Persona").
+		Find(&disponibilidades).Error
+	return disponibilidades, err
+}
+
+// GetDisponibilidadesEstudiantesByDia obtiene las franjas de todos los estudiantes universitarios para un día de la semana
+func (r *DisponibilidadPersonalRepository) GetDisponibilidadesEstudiantesByDia(diaSemana int) ([]models.DisponibilidadPersonal, error) {
+	var disponibilidades []models.DisponibilidadPersonal
+	err := r.db.Where("estudiante_universitario_id IS NOT NULL AND dia_semana = ?", diaSemana).
+		Preload("EstudianteUniversitario").Preload("EstudianteUniversitario.Persona").
+		Find(&disponibilidades).Error
+	return disponibilidades, err
+}
+
+// condicionOcupado reconoce una asignación al mismo programa o a otro que se superpone con [inicio, fin).
+// Los programas sin fechafin válida duran una hora, como en el cálculo de sugerencias.
+const condicionOcupado = `"ProgramaVisita"."id" = ? OR ("ProgramaVisita"."fecha" < ? AND
+	(CASE WHEN "ProgramaVisita"."fechafin" > "ProgramaVisita"."fecha" THEN "ProgramaVisita"."fechafin"
+	ELSE "ProgramaVisita"."fecha" + INTERVAL '1 hour' END) > ?)`
+
+// AsignarPersonal asigna autoridades y estudiantes a un programa de visita en una sola transacción.
+// Cada persona se bloquea y se vuelve a comprobar que no tenga ya este programa ni otra visita superpuesta,
+// para que dos asignaciones simultáneas no la pongan en dos visitas a la vez; las ocupadas se omiten.
+func (r *DisponibilidadPersonalRepository) AsignarPersonal(programaVisitaID uint, inicio, fin time.Time, autoridadIDs, estudianteIDs []uint) ([]models.DetalleAutoridadDetallesVisita, []models.VisitaDetalleEstudiantesUniversitarios, error) {
+	var detalles []models.DetalleAutoridadDetallesVisita
+	var relaciones []models.VisitaDetalleEstudiantesUniversitarios
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		for _, id := range autoridadIDs {
+			libre, err := personaLibre(tx, &models.AutoridadUTEQ{}, &models.DetalleAutoridadDetallesVisita{}, "autoridad_uteq_id", id, programaVisitaID, inicio, fin)
+			if err != nil {
+				return err
+			}
+			if !libre {
+				continue
+			}
+			detalle := models.DetalleAutoridadDetallesVisita{ProgramaVisitaID: programaVisitaID, AutoridadUTEQID: id}
+			if err := tx.Create(&detalle).Error; err != nil {
+				return err
+			}
+			detalles = append(detalles, detalle)
+		}
+		for _, id := range estudianteIDs {
+			libre, err := personaLibre(tx, &models.EstudianteUniversitario{}, &models.VisitaDetalleEstudiantesUniversitarios{}, "estudiante_universitario_id", id, programaVisitaID, inicio, fin)
+			if err != nil {
+				return err
+			}
+			if !libre {
+				continue
+			}
+			relacion := models.VisitaDetalleEstudiantesUniversitarios{ProgramaVisitaID: programaVisitaID, EstudianteUniversitarioID: id}
+			if err := tx.Create(&relacion).Error; err != nil {
+				return err
+			}
+			relaciones = append(relaciones, relacion)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return detalles, relaciones, nil
+}
+
+// personaLibre bloquea la fila de la persona (persona es su modelo y asignacion el de sus asignaciones)
+// y comprueba que siga libre para el programa
+func personaLibre(tx *gorm.DB, persona, asignacion interface{}, columna string, id, programaVisitaID uint, inicio, fin time.Time) (bool, error) {
+	if err := tx.Model(persona).Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").
+		Where("id = ?", id).Take(persona).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	var count int64
+	err := tx.Model(asignacion).Joins("ProgramaVisita").
+		Where(columna+" = ?", id).
+		Where(condicionOcupado, programaVisitaID, fin, inicio).
+		Count(&count).Error
+	return count == 0, err
+}
@@ -0,0 +1,56 @@
+package repositories
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func TestAsignarPersonalOmiteAQuienYaEstaOcupado(t *testing.T) {
+	db, consultas := dbSinConexion(t)
+	ocupada := uint(4)
+	// DryRun no devuelve filas: la autoridad 4 simula tener otra visita superpuesta
+	err := db.Callback().Query().After("gorm:query").Register("prueba:ocupada", func(d *gorm.DB) {
+		conteo, esConteo := d.Statement.Dest.(*int64)
+		if esConteo && d.Statement.Table == "detalle_autoridad_detalles_visita" && d.Statement.Vars[0] == ocupada {
+			*conteo, d.RowsAffected = 1, 1
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	inicio := time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC)
+	fin := inicio.Add(2 * time.Hour)
+	detalles, relaciones, err := NewDisponibilidadPersonalRepository(db).AsignarPersonal(9, inicio, fin, []uint{3, ocupada}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(detalles) != 1 || detalles[0].AutoridadUTEQID != 3 || detalles[0].ProgramaVisitaID != 9 || len(relaciones) != 0 {
+		t.Fatalf("asignaciones %+v %+v, se esperaba solo la autoridad 3", detalles, relaciones)
+	}
+
+	var bloqueos, comprobaciones int
+	for _, c := range *consultas {
+		switch {
+		case strings.Contains(c.sql, `FROM "autoridad_uteqs"`):
+			if !strings.HasSuffix(c.sql, "FOR UPDATE") {
+				t.Errorf("la autoridad no se bloquea antes de comprobarla: %s", c.sql)
+			}
+			bloqueos++
+		case strings.Contains(c.sql, `FROM "detalle_autoridad_detalles_visita"`):
+			if !strings.Contains(c.sql, `JOIN "programa_visita" "ProgramaVisita"`) || !strings.Contains(c.sql, `"ProgramaVisita"."fecha" < $`) {
+				t.Errorf("la comprobación no considera las visitas superpuestas: %s", c.sql)
+			}
+			if c.vars[1] != uint(9) || c.vars[2] != fin || c.vars[3] != inicio {
+				t.Errorf("parámetros de la comprobación %v", c.vars)
+			}
+			comprobaciones++
+		}
+	}
+	if bloqueos != 2 || comprobaciones != 2 {
+		t.Errorf("se esperaban 2 bloqueos y 2 comprobaciones, hubo %d y %d", bloqueos, comprobaciones)
+	}
+}
@@ -2,10 +2,41 @@ package repositories
 
 import (
 	"ApiEscuela/models"
+	"context"
 	"time"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// VisorDudas identifica a quien consulta las dudas. Las dudas privadas solo las ven su estudiante autor,
+// la autoridad asignada (o el supervisor al que se escaló) y los administradores; las públicas, todos.
+type VisorDudas struct {
+	EstudianteID  *uint
+	AutoridadID   *uint
+	Administrador bool
+}
+
+// VisorSistema lo usan los procesos internos que no actúan en nombre de un usuario
+var VisorSistema = VisorDudas{Administrador: true}
+
+// filtrar aplica las reglas de visibilidad a una consulta sobre dudas
+func (v VisorDudas) filtrar(db *gorm.DB) *gorm.DB {
+	if v.Administrador {
+		return db
+	}
+	condicion := "privacidad = ?"
+	args := []interface{}{"publico"}
+	if v.EstudianteID != nil {
+		condicion += " OR estudiante_id = ?"
+		args = append(args, *v.EstudianteID)
+	}
+	if v.AutoridadID != nil {
+		condicion += " OR autoridad_uteq_id = ? OR escalada_a_id = ?"
+		args = append(args, *v.AutoridadID, *v.AutoridadID)
+	}
+	return db.Where("("+condicion+")", args...)
+}
+
 type DudasRepository struct {
 	db *gorm.DB
 }
@@ -14,6 +45,12 @@ func NewDudasRepository(db *gorm.DB) *DudasRepository {
 	return &DudasRepository{db: db}
 }
 
+// ConContexto devuelve una copia del repositorio cuyas consultas usan ctx, para que queden dentro de la
+// traza de la petición
+func (r *DudasRepository) ConContexto(ctx context.Context) *DudasRepository {
+	return &DudasRepository{db: r.db.WithContext(ctx)}
+}
+
 // CreateDudas crea una nueva duda
 func (r *DudasRepository) CreateDudas(duda *models.Dudas) error {
 	// Establecer la fecha de pregunta automáticamente si no está establecida
@@ -24,9 +61,9 @@ func (r *DudasRepository) CreateDudas(duda *models.Dudas) error {
 }
 
 // GetDudasByID obtiene una duda por ID
-func (r *DudasRepository) GetDudasByID(id uint) (*models.Dudas, error) {
+func (r *DudasRepository) GetDudasByID(visor VisorDudas, id uint) (*models.Dudas, error) {
 	var duda models.Dudas
-	err := r.db.Preload("Estudiante").Preload("Estudiante.Persona").
+	err := r.db.Scopes(visor.filtrar).Preload("Estudiante").Preload("Estudiante.Persona").
 		Preload("Estudiante.Institucion").Preload("Estudiante.Ciudad").
 		Preload("AutoridadUTEQ").Preload("AutoridadUTEQ.Persona").
 		First(&duda, id).Error
@@ -37,9 +74,9 @@ func (r *DudasRepository) GetDudasByID(id uint) (*models.Dudas, error) {
 }
 
 // GetAllDudas obtiene todas las dudas
-func (r *DudasRepository) GetAllDudas() ([]models.Dudas, error) {
+func (r *DudasRepository) GetAllDudas(visor VisorDudas) ([]models.Dudas, error) {
 	var dudas []models.Dudas
-	err := r.db.Preload("Estudiante").Preload("Estudiante.Persona").
+	err := r.db.Scopes(visor.filtrar).Preload("Estudiante").Preload("Estudiante.Persona").
 		Preload("Estudiante.Institucion").Preload("Estudiante.Ciudad").
 		Preload("AutoridadUTEQ").Preload("AutoridadUTEQ.Persona").
 		Find(&dudas).Error
@@ -57,9 +94,9 @@ func (r *DudasRepository) DeleteDudas(id uint) error {
 }
 
 // GetDudasByEstudiante obtiene dudas por estudiante
-func (r *DudasRepository) GetDudasByEstudiante(estudianteID uint) ([]models.Dudas, error) {
+func (r *DudasRepository) GetDudasByEstudiante(visor VisorDudas, estudianteID uint) ([]models.Dudas, error) {
 	var dudas []models.Dudas
-	err := r.db.Where("estudiante_id = ?", estudianteID).
+	err := r.db.Scopes(visor.filtrar).Where("estudiante_id = ?", estudianteID).
 		Preload("Estudiante").Preload("Estudiante.Persona").
 		Preload("Estudiante.Institucion").Preload("Estudiante.Ciudad").
 		Preload("AutoridadUTEQ").Preload("AutoridadUTEQ.Persona").
@@ -68,9 +105,9 @@ func (r *DudasRepository) GetDudasByEstudiante(estudianteID uint) ([]models.Duda
 }
 
 // GetDudasByAutoridad obtiene dudas asignadas a una autoridad
-func (r *DudasRepository) GetDudasByAutoridad(autoridadID uint) ([]models.Dudas, error) {
+func (r *DudasRepository) GetDudasByAutoridad(visor VisorDudas, autoridadID uint) ([]models.Dudas, error) {
 	var dudas []models.Dudas
-	err := r.db.Where("autoridad_uteq_id = ?", autoridadID).
+	err := r.db.Scopes(visor.filtrar).Where("autoridad_uteq_id = ?", autoridadID).
 		Preload("Estudiante").Preload("Estudiante.Persona").
 		Preload("Estudiante.Institucion").Preload("Estudiante.Ciudad").
 		Preload("AutoridadUTEQ").Preload("AutoridadUTEQ.Persona").
@@ -79,9 +116,9 @@ func (r *DudasRepository) GetDudasByAutoridad(autoridadID uint) ([]models.Dudas,
 }
 
 // GetDudasSinResponder obtiene dudas sin respuesta
-func (r *DudasRepository) GetDudasSinResponder() ([]models.Dudas, error) {
+func (r *DudasRepository) GetDudasSinResponder(visor VisorDudas) ([]models.Dudas, error) {
 	var dudas []models.Dudas
-	err := r.db.Where("respuesta IS NULL OR respuesta = ''").
+	err := r.db.Scopes(visor.filtrar).Where("respuesta IS NULL OR respuesta = ''").
 		Preload("Estudiante").Preload("Estudiante.Persona").
 		Preload("Estudiante.Institucion").Preload("Estudiante.Ciudad").
 		Preload("AutoridadUTEQ").Preload("AutoridadUTEQ.Persona").
@@ -90,9 +127,9 @@ func (r *DudasRepository) GetDudasSinResponder() ([]models.Dudas, error) {
 }
 
 // GetDudasRespondidas obtiene dudas con respuesta
-func (r *DudasRepository) GetDudasRespondidas() ([]models.Dudas, error) {
+func (r *DudasRepository) GetDudasRespondidas(visor VisorDudas) ([]models.Dudas, error) {
 	var dudas []models.Dudas
-	err := r.db.Where("respuesta IS NOT NULL AND respuesta != ''").
+	err := r.db.Scopes(visor.filtrar).Where("respuesta IS NOT NULL AND respuesta != ''").
 		Preload("Estudiante").Preload("Estudiante.Persona").
 		Preload("Estudiante.Institucion").Preload("Estudiante.Ciudad").
 		Preload("AutoridadUTEQ").Preload("AutoridadUTEQ.Persona").
@@ -101,19 +138,19 @@ func (r *DudasRepository) GetDudasRespondidas() ([]models.Dudas, error) {
 }
 
 // GetDudasSinAsignar obtiene dudas sin autoridad asignada
-func (r *DudasRepository) GetDudasSinAsignar() ([]models.Dudas, error) {
+func (r *DudasRepository) GetDudasSinAsignar(visor VisorDudas) ([]models.Dudas, error) {
 	var dudas []models.Dudas
-	err := r.db.Where("autoridad_uteq_id IS NULL").
+	err := r.db.Scopes(visor.filtrar).Where("autoridad_uteq_id IS NULL").
 		Preload("Estudiante").Preload("Estudiante.Persona").
 		Preload("Estudiante.Institucion").Preload("Estudiante.Ciudad").
 		Find(&dudas).Error
 	return dudas, err
 }
 
-// BuscarDudasPorPregunta busca dudas por contenido de la pregunta
-func (r *DudasRepository) BuscarDudasPorPregunta(termino string) ([]models.Dudas, error) {
+// BuscarDudasPorPregunta busca dudas por pregunta y respuesta con búsqueda de texto completo, de la más relevante a la menos
+func (r *DudasRepository) BuscarDudasPorPregunta(visor VisorDudas, termino string) ([]models.Dudas, error) {
 	var dudas []models.Dudas
-	err := r.db.Where("pregunta ILIKE ?", "%"+termino+"%").
+	err := r.db.Scopes(visor.filtrar).Where(coincideBusqueda(termino)).Order(ordenRelevancia(termino)).
 		Preload("Estudiante").Preload("Estudiante.Persona").
 		Preload("Estudiante.Institucion").Preload("Estudiante.Ciudad").
 		Preload("AutoridadUTEQ").Preload("AutoridadUTEQ.Persona").
@@ -122,24 +159,210 @@ func (r *DudasRepository) BuscarDudasPorPregunta(termino string) ([]models.Dudas
 }
 
 
-// ResponderDuda actualiza la respuesta de una duda
-func (r *DudasRepository) ResponderDuda(dudaID uint, respuesta string,autoridadID uint) error {
-	now := time.Now()
-	return r.db.Model(&models.Dudas{}).Where("id = ?", dudaID).
-		Updates(map[string]interface{}{
-			"respuesta":        &respuesta,
-			"fecha_respuesta":  &now,
-			"autoridad_uteq_id":  &autoridadID,
-		}).Error
-}
-
 // GetDudasByPrivacidad obtiene dudas por tipo de privacidad
-func (r *DudasRepository) GetDudasByPrivacidad(privacidad string) ([]models.Dudas, error) {
+func (r *DudasRepository) GetDudasByPrivacidad(visor VisorDudas, privacidad string) ([]models.Dudas, error) {
 	var dudas []models.Dudas
-	err := r.db.Where("privacidad = ?", privacidad).
+	err := r.db.Scopes(visor.filtrar).Where("privacidad = ?", privacidad).
 		Preload("Estudiante").Preload("Estudiante.Persona").
 		Preload("Estudiante.Institucion").Preload("Estudiante.Ciudad").
 		Preload("AutoridadUTEQ").Preload("AutoridadUTEQ.Persona").
 		Find(&dudas).Error
 	return dudas, err
-}
\ No newline at end of file
+}
+
+// CreateMensaje agrega un mensaje al hilo de una duda. Si es una respuesta pública del personal
+// también actualiza la última respuesta de la duda, en la misma transacción.
+func (r *DudasRepository) CreateMensaje(mensaje *models.MensajeDuda, autoridadID *uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(mensaje).Error; err != nil {
+			return err
+		}
+		if mensaje.RolAutor != models.RolDudaPersonal || mensaje.Tipo != models.TipoMensajeDudaMensaje {
+			return nil
+		}
+		campos := map[string]interface{}{
+			"respuesta":            mensaje.Contenido,
+			"fecha_respuesta":      mensaje.CreatedAt,
+			"primera_respuesta_en": gorm.Expr("COALESCE(primera_respuesta_en, ?)", mensaje.CreatedAt),
+		}
+		if autoridadID != nil {
+			campos["autoridad_uteq_id"] = *autoridadID
+			campos["asignada_en"] = gorm.Expr("CASE WHEN autoridad_uteq_id IS DISTINCT FROM ? THEN ? ELSE asignada_en END", *autoridadID, mensaje.CreatedAt)
+		}
+		return tx.Model(&models.Dudas{}).Where("id = ?", mensaje.DudaID).Updates(campos).Error
+	})
+}
+
+// MigrarRespuestasAMensajes copia al hilo la respuesta de las dudas respondidas antes de que existieran
+// los mensajes. El mensaje queda a nombre de la autoridad asignada; las dudas sin autoridad no se pueden
+// atribuir y se omiten. Solo se copian en dudas sin respuestas del personal, así que se puede repetir.
+func (r *DudasRepository) MigrarRespuestasAMensajes() (int64, error) {
+	resultado := r.db.Exec(`INSERT INTO mensajes_duda (created_at, updated_at, duda_id, usuario_id, persona_id, rol_autor, tipo, contenido)
+		SELECT COALESCE(d.fecha_respuesta, d.updated_at), COALESCE(d.fecha_respuesta, d.updated_at), d.id,
+			COALESCE((SELECT MIN(u.id) FROM usuarios u WHERE u.persona_id = a.persona_id AND u.deleted_at IS NULL), 0),
+			a.persona_id, ?, ?, d.respuesta
+		FROM dudas d
+		JOIN autoridad_uteqs a ON a.id = d.autoridad_uteq_id
+		WHERE d.respuesta IS NOT NULL AND d.respuesta <> ''
+			AND NOT EXISTS (SELECT 1 FROM mensajes_duda m WHERE m.duda_id = d.id AND m.rol_autor = ?)`,
+		models.RolDudaPersonal, models.TipoMensajeDudaMensaje, models.RolDudaPersonal)
+	return resultado.RowsAffected, resultado.Error
+}
+
+// GetMensajesByDuda obtiene el hilo de una duda en orden cronológico; las notas internas solo si se piden
+func (r *DudasRepository) GetMensajesByDuda(dudaID uint, incluirNotas bool) ([]models.MensajeDuda, error) {
+	var mensajes []models.MensajeDuda
+	query := r.db.Where("duda_id = ?", dudaID)
+	if !incluirNotas {
+		query = query.Where("tipo = ?", models.TipoMensajeDudaMensaje)
+	}
+	err := query.Preload("Autor").Order("created_at, id").Find(&mensajes).Error
+	return mensajes, err
+}
+
+// GetMensajeByID obtiene un mensaje con su historial de ediciones
+func (r *DudasRepository) GetMensajeByID(id uint) (*models.MensajeDuda, error) {
+	var mensaje models.MensajeDuda
+	err := r.db.Preload("Autor").
+		Preload("Ediciones", func(db *gorm.DB) *gorm.DB { return db.Order("created_at DESC, id DESC") }).
+		First(&mensaje, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &mensaje, nil
+}
+
+// EditarMensaje guarda el contenido anterior en el historial y actualiza el mensaje
+func (r *DudasRepository) EditarMensaje(mensaje *models.MensajeDuda, edicion *models.EdicionMensajeDuda) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(edicion).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(mensaje).Updates(map[string]interface{}{
+			"contenido":  mensaje.Contenido,
+			"editado_en": mensaje.EditadoEn,
+		}).Error; err != nil {
+			return err
+		}
+		if mensaje.RolAutor != models.RolDudaPersonal || mensaje.Tipo != models.TipoMensajeDudaMensaje {
+			return nil
+		}
+		// Si el mensaje editado es la última respuesta pública, mantener la duda sincronizada
+		var ultimo models.MensajeDuda
+		err := tx.Where("duda_id = ? AND rol_autor = ? AND tipo = ?", mensaje.DudaID, models.RolDudaPersonal, models.TipoMensajeDudaMensaje).
+			Order("created_at DESC, id DESC").First(&ultimo).Error
+		if err != nil || ultimo.ID != mensaje.ID {
+			return nil
+		}
+		return tx.Model(&models.Dudas{}).Where("id = ?", mensaje.DudaID).Update("respuesta", mensaje.Contenido).Error
+	})
+}
+
+// MarcarLeida registra que el usuario leyó el hilo hasta el momento indicado
+func (r *DudasRepository) MarcarLeida(dudaID, usuarioID uint, leidaEn time.Time) error {
+	lectura := models.LecturaDuda{DudaID: dudaID, UsuarioID: usuarioID, UltimaLecturaEn: leidaEn}
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "duda_id"}, {Name: "usuario_id"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{"ultima_lectura_en": leidaEn, "updated_at": time.Now(), "deleted_at": nil}),
+	}).Create(&lectura).Error
+}
+
+// MarcarNoLeida elimina la marca de lectura para que todo el hilo vuelva a contarse como no leído
+func (r *DudasRepository) MarcarNoLeida(dudaID, usuarioID uint) error {
+	return r.db.Unscoped().Where("duda_id = ? AND usuario_id = ?", dudaID, usuarioID).Delete(&models.LecturaDuda{}).Error
+}
+
+// ConteoNoLeidos es la cantidad de mensajes no leídos de una duda
+type ConteoNoLeidos struct {
+	DudaID   uint  `json:"duda_id"`
+	NoLeidos int64 `json:"no_leidos"`
+}
+
+// ContarNoLeidos cuenta, por duda, los mensajes de otros participantes posteriores a la última lectura del usuario
+func (r *DudasRepository) ContarNoLeidos(usuarioID uint, dudaIDs []uint, incluirNotas bool) ([]ConteoNoLeidos, error) {
+	var conteos []ConteoNoLeidos
+	if len(dudaIDs) == 0 {
+		return conteos, nil
+	}
+	query := r.db.Table("mensajes_duda AS m").
+		Select("m.duda_id, COUNT(*) AS no_leidos").
+		Joins("LEFT JOIN lecturas_duda AS l ON l.duda_id = m.duda_id AND l.usuario_id = ? AND l.deleted_at IS NULL", usuarioID).
+		Where("m.deleted_at IS NULL AND m.duda_id IN ? AND m.usuario_id <> ?", dudaIDs, usuarioID).
+		Where("l.id IS NULL OR m.created_at > l.ultima_lectura_en")
+	if !incluirNotas {
+		query = query.Where("m.tipo = ?", models.TipoMensajeDudaMensaje)
+	}
+	err := query.Group("m.duda_id").Scan(&conteos).Error
+	return conteos, err
+}
+
+// GetDudasPorRecordar obtiene las dudas asignadas, sin respuesta ni recordatorio, que vencen antes de limite
+func (r *DudasRepository) GetDudasPorRecordar(visor VisorDudas, ahora, limite time.Time) ([]models.Dudas, error) {
+	var dudas []models.Dudas
+	err := r.db.Scopes(visor.filtrar).Where("primera_respuesta_en IS NULL AND escalada_en IS NULL AND recordatorio_en IS NULL").
+		Where("autoridad_uteq_id IS NOT NULL AND vence_en > ? AND vence_en <= ?", ahora, limite).
+		Preload("AutoridadUTEQ").Preload("AutoridadUTEQ.Persona").
+		Find(&dudas).Error
+	return dudas, err
+}
+
+// MarcarRecordatorio registra el envío del recordatorio; devuelve false si otro proceso ya lo registró
+func (r *DudasRepository) MarcarRecordatorio(dudaID uint, enviadoEn time.Time) (bool, error) {
+	resultado := r.db.Model(&models.Dudas{}).
+		Where("id = ? AND recordatorio_en IS NULL", dudaID).
+		Update("recordatorio_en", enviadoEn)
+	return resultado.RowsAffected > 0, resultado.Error
+}
+
+// GetDudasVencidas obtiene las dudas sin respuesta cuyo plazo venció y que aún no se escalaron
+func (r *DudasRepository) GetDudasVencidas(visor VisorDudas, ahora time.Time) ([]models.Dudas, error) {
+	var dudas []models.Dudas
+	err := r.db.Scopes(visor.filtrar).Where("primera_respuesta_en IS NULL AND escalada_en IS NULL AND vence_en <= ?", ahora).
+		Preload("Estudiante").Preload("Estudiante.Persona").
+		Preload("AutoridadUTEQ").Preload("AutoridadUTEQ.Persona").
+		Find(&dudas).Error
+	return dudas, err
+}
+
+// MarcarEscalada registra la escalación al supervisor; devuelve false si otro proceso ya la registró
+func (r *DudasRepository) MarcarEscalada(dudaID, supervisorID uint, escaladaEn time.Time) (bool, error) {
+	resultado := r.db.Model(&models.Dudas{}).
+		Where("id = ? AND escalada_en IS NULL", dudaID).
+		Updates(map[string]interface{}{
+			"escalada_en":   escaladaEn,
+			"escalada_a_id": supervisorID,
+		})
+	return resultado.RowsAffected > 0, resultado.Error
+}
+
+// MetricasSLA agrega el cumplimiento del plazo de respuesta de un grupo de dudas
+type MetricasSLA struct {
+	AutoridadUTEQID        *uint    `json:"autoridad_uteq_id,omitempty"`
+	Total                  int64    `json:"total"`
+	Respondidas            int64    `json:"respondidas"`
+	RespondidasATiempo     int64    `json:"respondidas_a_tiempo"`
+	VencidasSinRespuesta   int64    `json:"vencidas_sin_respuesta"`
+	Escaladas              int64    `json:"escaladas"`
+	HorasPromedioRespuesta float64  `json:"horas_promedio_respuesta"`
+	Cumplimiento           *float64 `json:"cumplimiento" gorm:"-"` // Porcentaje respondido a tiempo sobre las dudas ya resueltas o vencidas
+}
+
+// GetMetricasSLA calcula las métricas de las dudas creadas en el rango; si porAutoridad agrupa por autoridad asignada
+func (r *DudasRepository) GetMetricasSLA(desde, hasta, ahora time.Time, porAutoridad bool) ([]MetricasSLA, error) {
+	var metricas []MetricasSLA
+	columnas := `COUNT(*) AS total,
+		COUNT(primera_respuesta_en) AS respondidas,
+		COUNT(*) FILTER (WHERE primera_respuesta_en <= vence_en) AS respondidas_a_tiempo,
+		COUNT(*) FILTER (WHERE primera_respuesta_en IS NULL AND vence_en <= ?) AS vencidas_sin_respuesta,
+		COUNT(escalada_en) AS escaladas,
+		COALESCE(AVG(EXTRACT(EPOCH FROM (primera_respuesta_en - fecha_pregunta)) / 3600), 0) AS horas_promedio_respuesta`
+	query := r.db.Model(&models.Dudas{}).
+		Where("vence_en IS NOT NULL AND fecha_pregunta >= ? AND fecha_pregunta < ?", desde, hasta)
+	if porAutoridad {
+		query = query.Select("autoridad_uteq_id, "+columnas, ahora).Group("autoridad_uteq_id").Order("autoridad_uteq_id")
+	} else {
+		query = query.Select(columnas, ahora)
+	}
+	err := query.Scan(&metricas).Error
+	return metricas, err
+}
@@ -0,0 +1,104 @@
+package repositories
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+var condicionVisor = regexp.MustCompile(`WHERE \(+(.+?)\)+ AND "dudas"\."deleted_at" IS NULL`)
+
+// visibleSegun evalúa la condición generada por filtrar ("columna = $n" unidas por OR) sobre una duda
+func visibleSegun(t *testing.T, consulta consultaGenerada, duda map[string]interface{}) bool {
+	t.Helper()
+	partes := condicionVisor.FindStringSubmatch(consulta.sql)
+	if partes == nil {
+		return true // Sin condición: el visor ve todas las dudas
+	}
+	for _, comparacion := range strings.Split(partes[1], " OR ") {
+		columna, marcador, ok := strings.Cut(comparacion, " = $")
+		if !ok {
+			t.Fatalf("condición no reconocida: %s", partes[1])
+		}
+		indice, _ := strconv.Atoi(marcador)
+		if duda[columna] == consulta.vars[indice-1] {
+			return true
+		}
+	}
+	return false
+}
+
+func TestVisibilidadDeDudas(t *testing.T) {
+	estudiante, otroEstudiante, autoridad, supervisor := uint(7), uint(9), uint(3), uint(4)
+	dudas := map[string]map[string]interface{}{
+		"pública de otro":           {"privacidad": "publico", "estudiante_id": uint(8), "autoridad_uteq_id": uint(5), "escalada_a_id": nil},
+		"privada propia asignada":   {"privacidad": "privado", "estudiante_id": estudiante, "autoridad_uteq_id": autoridad, "escalada_a_id": nil},
+		"privada escalada":          {"privacidad": "privado", "estudiante_id": uint(8), "autoridad_uteq_id": uint(5), "escalada_a_id": supervisor},
+		"privada ajena sin asignar": {"privacidad": "privado", "estudiante_id": uint(8), "autoridad_uteq_id": nil, "escalada_a_id": nil},
+	}
+	casos := []struct {
+		nombre   string
+		visor    VisorDudas
+		visibles []string
+	}{
+		{"estudiante autor", VisorDudas{EstudianteID: &estudiante}, []string{"pública de otro", "privada propia asignada"}},
+		{"otro estudiante", VisorDudas{EstudianteID: &otroEstudiante}, []string{"pública de otro"}},
+		{"autoridad asignada", VisorDudas{AutoridadID: &autoridad}, []string{"pública de otro", "privada propia asignada"}},
+		{"supervisor de la escalada", VisorDudas{AutoridadID: &supervisor}, []string{"pública de otro", "privada escalada"}},
+		{"administrador", VisorDudas{Administrador: true}, []string{"pública de otro", "privada propia asignada", "privada escalada", "privada ajena sin asignar"}},
+		{"sin rol", VisorDudas{}, []string{"pública de otro"}},
+	}
+	for _, caso := range casos {
+		t.Run(caso.nombre, func(t *testing.T) {
+			db, consultas := dbSinConexion(t)
+			if _, err := NewDudasRepository(db).GetAllDudas(caso.visor); err != nil {
+				t.Fatal(err)
+			}
+			esperadas := map[string]bool{}
+			for _, nombre := range caso.visibles {
+				esperadas[nombre] = true
+			}
+			for nombre, duda := range dudas {
+				if visible := visibleSegun(t, (*consultas)[0], duda); visible != esperadas[nombre] {
+					t.Errorf("duda %q: visible=%v, se esperaba %v (%s)", nombre, visible, esperadas[nombre], (*consultas)[0].sql)
+				}
+			}
+		})
+	}
+}
+
+func TestDudasIncluidasSiguenAlVisor(t *testing.T) {
+	estudiante := uint(7)
+	db, consultas := dbSinConexion(t)
+
+	// DryRun no devuelve filas: se simula que se encontró el estudiante para que corra la precarga
+	err := db.Callback().Query().Before("gorm:preload").Register("prueba:fila", func(d *gorm.DB) {
+		if d.Statement.Table == "estudiantes" {
+			d.Statement.ReflectValue.FieldByName("ID").SetUint(uint64(estudiante))
+			d.RowsAffected = 1
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repo := NewEstudianteRepository(db).ConVisor(VisorDudas{EstudianteID: &estudiante})
+	if _, err := repo.GetEstudianteByID(estudiante); err != nil {
+		t.Fatal(err)
+	}
+	var precarga *consultaGenerada
+	for i, consulta := range *consultas {
+		if strings.Contains(consulta.sql, `FROM "dudas"`) {
+			precarga = &(*consultas)[i]
+		}
+	}
+	if precarga == nil {
+		t.Fatal("no se precargaron las dudas del estudiante")
+	}
+	if !strings.Contains(precarga.sql, "privacidad = $1 OR estudiante_id = $2") {
+		t.Errorf("la precarga no aplica el visor: %s", precarga.sql)
+	}
+}
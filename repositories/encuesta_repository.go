@@ -0,0 +1,233 @@
+package repositories
+
+import (
+	"ApiEscuela/models"
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrInvitacionYaRespondida indica que el enlace de la encuesta ya fue usado
+var ErrInvitacionYaRespondida = errors.New("la encuesta ya fue respondida con este enlace")
+
+type EncuestaRepository struct {
+	db *gorm.DB
+}
+
+func NewEncuestaRepository(db *gorm.DB) *EncuestaRepository {
+	return &EncuestaRepository{db: db}
+}
+
+// ConContexto devuelve una copia del repositorio cuyas consultas usan ctx, para que queden dentro de la
+// traza de la petición
+func (r *EncuestaRepository) ConContexto(ctx context.Context) *EncuestaRepository {
+	return &EncuestaRepository{db: r.db.WithContext(ctx)}
+}
+
+// ConteoOpcion es la cantidad de respuestas con un mismo valor u opción para una pregunta
+type ConteoOpcion struct {
+	PreguntaEncuestaID uint
+	Valor              *int
+	Opcion             string
+	Total              int64
+}
+
+// TextoRespuesta es una respuesta libre a una pregunta
+type TextoRespuesta struct {
+	PreguntaEncuestaID uint
+	Texto              string
+}
+
+// SatisfaccionActividad acumula las respuestas likert de las encuestas de una actividad
+type SatisfaccionActividad struct {
+	ActividadID uint
+	Respuestas  int64
+	Suma        float64
+	Valores     int64
+}
+
+// CreateEncuesta crea una encuesta con sus preguntas
+func (r *EncuestaRepository) CreateEncuesta(encuesta *models.Encuesta) error {
+	return r.db.Create(encuesta).Error
+}
+
+// GetEncuestaByID obtiene una encuesta con sus preguntas ordenadas
+func (r *EncuestaRepository) GetEncuestaByID(id uint) (*models.Encuesta, error) {
+	var encuesta models.Encuesta
+	err := r.db.Preload("Preguntas", func(db *gorm.DB) *gorm.DB { return db.Order("orden, id") }).
+		Preload("Actividad").
+		Preload("ProgramaVisita").
+		First(&encuesta, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &encuesta, nil
+}
+
+// GetAllEncuestas obtiene todas las encuestas
+func (r *EncuestaRepository) GetAllEncuestas() ([]models.Encuesta, error) {
+	var encuestas []models.Encuesta
+	err := r.db.Preload("Preguntas", func(db *gorm.DB) *gorm.DB { return db.Order("orden, id") }).
+		Preload("Actividad").
+		Order("created_at DESC").
+		Find(&encuestas).Error
+	return encuestas, err
+}
+
+// UpdateEncuesta actualiza una encuesta; si preguntas no es nil reemplaza las preguntas en la misma transacción
+func (r *EncuestaRepository) UpdateEncuesta(encuesta *models.Encuesta, preguntas []models.PreguntaEncuesta) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit(clause.Associations).Save(encuesta).Error; err != nil {
+			return err
+		}
+		if preguntas == nil {
+			return nil
+		}
+		if err := tx.Where("encuesta_id = ?", encuesta.ID).Delete(&models.PreguntaEncuesta{}).Error; err != nil {
+			return err
+		}
+		for i := range preguntas {
+			preguntas[i].EncuestaID = encuesta.ID
+		}
+		if len(preguntas) > 0 {
+			if err := tx.Create(&preguntas).Error; err != nil {
+				return err
+			}
+		}
+		encuesta.Preguntas = preguntas
+		return nil
+	})
+}
+
+// DeleteEncuesta elimina una encuesta y sus preguntas
+func (r *EncuestaRepository) DeleteEncuesta(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("encuesta_id = ?", id).Delete(&models.PreguntaEncuesta{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.Encuesta{}, id).Error
+	})
+}
+
+// CountRespuestas cuenta las respuestas recibidas por una encuesta
+func (r *EncuestaRepository) CountRespuestas(encuestaID uint) (int64, error) {
+	var total int64
+	err := r.db.Model(&models.RespuestaEncuesta{}).Where("encuesta_id = ?", encuestaID).Count(&total).Error
+	return total, err
+}
+
+// GetEncuestasActivasParaPrograma obtiene las encuestas activas del programa o de sus actividades
+func (r *EncuestaRepository) GetEncuestasActivasParaPrograma(programaVisitaID uint, actividadIDs []uint) ([]models.Encuesta, error) {
+	var encuestas []models.Encuesta
+	query := r.db.Where("activa = ?", true)
+	if len(actividadIDs) > 0 {
+		query = query.Where("programa_visita_id = ? OR actividad_id IN ?", programaVisitaID, actividadIDs)
+	} else {
+		query = query.Where("programa_visita_id = ?", programaVisitaID)
+	}
+	err := query.Preload("Actividad").Find(&encuestas).Error
+	return encuestas, err
+}
+
+// GetEstudiantesUniversitariosDelPrograma obtiene, con sus datos personales, los estudiantes universitarios
+// asignados al programa de visita
+func (r *EncuestaRepository) GetEstudiantesUniversitariosDelPrograma(programaVisitaID uint) ([]models.EstudianteUniversitario, error) {
+	var estudiantes []models.EstudianteUniversitario
+	asignados := r.db.Model(&models.VisitaDetalleEstudiantesUniversitarios{}).
+		Select("estudiante_universitario_id").Where("programa_visita_id = ?", programaVisitaID)
+	err := r.db.Where("id IN (?)", asignados).Preload("Persona").Find(&estudiantes).Error
+	return estudiantes, err
+}
+
+// CompletarPrograma marca el programa como completado y guarda la asistencia y las invitaciones en una
+// sola transacción. Falla si el programa ya estaba completado.
+func (r *EncuestaRepository) CompletarPrograma(programaVisitaID uint, completadaEn time.Time, asistencias []models.AsistenciaVisita, invitaciones []models.InvitacionEncuesta) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		resultado := tx.Model(&models.ProgramaVisita{}).
+			Where("id = ? AND completada_en IS NULL", programaVisitaID).
+			Update("completada_en", completadaEn)
+		if resultado.Error != nil {
+			return resultado.Error
+		}
+		if resultado.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		if len(asistencias) > 0 {
+			if err := tx.Create(&asistencias).Error; err != nil {
+				return err
+			}
+		}
+		if len(invitaciones) > 0 {
+			return tx.Create(&invitaciones).Error
+		}
+		return nil
+	})
+}
+
+// GetInvitacionByTokenHash obtiene una invitación por el hash de su token
+func (r *EncuestaRepository) GetInvitacionByTokenHash(tokenHash string) (*models.InvitacionEncuesta, error) {
+	var invitacion models.InvitacionEncuesta
+	err := r.db.Where("token_hash = ?", tokenHash).First(&invitacion).Error
+	if err != nil {
+		return nil, err
+	}
+	return &invitacion, nil
+}
+
+// GuardarRespuesta marca la invitación como usada y guarda la respuesta anónima en una sola transacción
+func (r *EncuestaRepository) GuardarRespuesta(invitacionID uint, respuesta *models.RespuestaEncuesta) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		ahora := time.Now()
+		resultado := tx.Model(&models.InvitacionEncuesta{}).
+			Where("id = ? AND respondida_en IS NULL", invitacionID).
+			Update("respondida_en", ahora)
+		if resultado.Error != nil {
+			return resultado.Error
+		}
+		if resultado.RowsAffected == 0 {
+			return ErrInvitacionYaRespondida
+		}
+		return tx.Create(respuesta).Error
+	})
+}
+
+// GetConteosRespuestas agrupa las respuestas likert y de opción múltiple de una encuesta
+func (r *EncuestaRepository) GetConteosRespuestas(encuestaID uint) ([]ConteoOpcion, error) {
+	var conteos []ConteoOpcion
+	err := r.db.Table("respuestas_pregunta AS rp").
+		Select("rp.pregunta_encuesta_id, rp.valor, rp.opcion, COUNT(*) AS total").
+		Joins("JOIN respuestas_encuesta AS re ON re.id = rp.respuesta_encuesta_id AND re.deleted_at IS NULL").
+		Where("re.encuesta_id = ? AND rp.deleted_at IS NULL", encuestaID).
+		Where("rp.valor IS NOT NULL OR rp.opcion <> ''").
+		Group("rp.pregunta_encuesta_id, rp.valor, rp.opcion").
+		Scan(&conteos).Error
+	return conteos, err
+}
+
+// GetTextosRespuestas obtiene las respuestas libres de una encuesta, de la más reciente a la más antigua
+func (r *EncuestaRepository) GetTextosRespuestas(encuestaID uint) ([]TextoRespuesta, error) {
+	var textos []TextoRespuesta
+	err := r.db.Table("respuestas_pregunta AS rp").
+		Select("rp.pregunta_encuesta_id, rp.texto").
+		Joins("JOIN respuestas_encuesta AS re ON re.id = rp.respuesta_encuesta_id AND re.deleted_at IS NULL").
+		Where("re.encuesta_id = ? AND rp.deleted_at IS NULL AND rp.texto <> ''", encuestaID).
+		Order("rp.created_at DESC").
+		Scan(&textos).Error
+	return textos, err
+}
+
+// GetSatisfaccionPorActividad acumula las respuestas likert de las encuestas asociadas a cada actividad
+func (r *EncuestaRepository) GetSatisfaccionPorActividad() ([]SatisfaccionActividad, error) {
+	var resultados []SatisfaccionActividad
+	err := r.db.Table("encuestas AS e").
+		Select("e.actividad_id, COUNT(DISTINCT re.id) AS respuestas, COALESCE(SUM(rp.valor), 0) AS suma, COUNT(rp.valor) AS valores").
+		Joins("JOIN respuestas_encuesta AS re ON re.encuesta_id = e.id AND re.deleted_at IS NULL").
+		Joins("LEFT JOIN respuestas_pregunta AS rp ON rp.respuesta_encuesta_id = re.id AND rp.deleted_at IS NULL").
+		Where("e.actividad_id IS NOT NULL AND e.deleted_at IS NULL").
+		Group("e.actividad_id").
+		Scan(&resultados).Error
+	return resultados, err
+}
@@ -0,0 +1,89 @@
+package repositories
+
+import (
+	"ApiEscuela/models"
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+// idConfiguracionSLADudas es el ID del único registro de configuración del SLA
+const idConfiguracionSLADudas = 1
+
+type EnrutamientoDudasRepository struct {
+	db *gorm.DB
+}
+
+func NewEnrutamientoDudasRepository(db *gorm.DB) *EnrutamientoDudasRepository {
+	return &EnrutamientoDudasRepository{db: db}
+}
+
+// ConContexto devuelve una copia del repositorio cuyas consultas usan ctx, para que queden dentro de la
+// traza de la petición
+func (r *EnrutamientoDudasRepository) ConContexto(ctx context.Context) *EnrutamientoDudasRepository {
+	return &EnrutamientoDudasRepository{db: r.db.WithContext(ctx)}
+}
+
+// CreateRegla crea una regla de asignación
+func (r *EnrutamientoDudasRepository) CreateRegla(regla *models.ReglaAsignacionDuda) error {
+	return r.db.Create(regla).Error
+}
+
+// GetReglaByID obtiene una regla de asignación por ID
+func (r *EnrutamientoDudasRepository) GetReglaByID(id uint) (*models.ReglaAsignacionDuda, error) {
+	var regla models.ReglaAsignacionDuda
+	err := r.db.Preload("Tematica").Preload("Institucion").
+		Preload("AutoridadUTEQ").Preload("AutoridadUTEQ.Persona").
+		First(&regla, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &regla, nil
+}
+
+// GetAllReglas obtiene todas las reglas en el orden en que se evalúan
+func (r *EnrutamientoDudasRepository) GetAllReglas() ([]models.ReglaAsignacionDuda, error) {
+	var reglas []models.ReglaAsignacionDuda
+	err := r.db.Preload("Tematica").Preload("Institucion").
+		Preload("AutoridadUTEQ").Preload("AutoridadUTEQ.Persona").
+		Order("prioridad, id").Find(&reglas).Error
+	return reglas, err
+}
+
+// GetReglasActivas obtiene las reglas activas en el orden en que se evalúan
+func (r *EnrutamientoDudasRepository) GetReglasActivas() ([]models.ReglaAsignacionDuda, error) {
+	var reglas []models.ReglaAsignacionDuda
+	err := r.db.Where("activa = ?", true).Order("prioridad, id").Find(&reglas).Error
+	return reglas, err
+}
+
+// UpdateRegla actualiza una regla de asignación
+func (r *EnrutamientoDudasRepository) UpdateRegla(regla *models.ReglaAsignacionDuda) error {
+	return r.db.Omit("Tematica", "Institucion", "AutoridadUTEQ").Save(regla).Error
+}
+
+// DeleteRegla elimina una regla de asignación
+func (r *EnrutamientoDudasRepository) DeleteRegla(id uint) error {
+	return r.db.Delete(&models.ReglaAsignacionDuda{}, id).Error
+}
+
+// GetConfiguracionSLA obtiene la configuración del SLA; si aún no existe devuelve los valores por defecto
+func (r *EnrutamientoDudasRepository) GetConfiguracionSLA() (*models.ConfiguracionSLADudas, error) {
+	var configuracion models.ConfiguracionSLADudas
+	err := r.db.Preload("Supervisor").Preload("Supervisor.Persona").
+		First(&configuracion, idConfiguracionSLADudas).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &models.ConfiguracionSLADudas{HorasRespuesta: 48, HorasRecordatorio: 12}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &configuracion, nil
+}
+
+// GuardarConfiguracionSLA crea o actualiza el registro de configuración del SLA
+func (r *EnrutamientoDudasRepository) GuardarConfiguracionSLA(configuracion *models.ConfiguracionSLADudas) error {
+	configuracion.ID = idConfiguracionSLADudas
+	return r.db.Omit("Supervisor").Save(configuracion).Error
+}
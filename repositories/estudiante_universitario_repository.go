@@ -2,6 +2,7 @@ package repositories
 
 import (
 	"ApiEscuela/models"
+	"context"
 	"errors"
 	"strings"
 	"gorm.io/gorm"
@@ -33,6 +34,12 @@ func NewEstudianteUniversitarioRepository(db *gorm.DB) *EstudianteUniversitarioR
 	return &EstudianteUniversitarioRepository{db: db}
 }
 
+// ConContexto devuelve una copia del repositorio cuyas consultas usan ctx, para que queden dentro de la
+// traza de la petición
+func (r *EstudianteUniversitarioRepository) ConContexto(ctx context.Context) *EstudianteUniversitarioRepository {
+	return &EstudianteUniversitarioRepository{db: r.db.WithContext(ctx)}
+}
+
 // CreateEstudianteUniversitario crea un nuevo estudiante universitario
 func (r *EstudianteUniversitarioRepository) CreateEstudianteUniversitario(estudiante *models.EstudianteUniversitario) error {
 	// Prevalidar por persona
@@ -0,0 +1,130 @@
+package repositories
+
+import (
+	"ApiEscuela/models"
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ColumnaConArchivos es una columna de texto que puede contener enlaces a archivos subidos. La tabla se
+// obtiene del modelo para no depender de cómo GORM nombra cada una.
+type ColumnaConArchivos struct {
+	Modelo  interface{}
+	Columna string
+}
+
+// ColumnasConArchivos son las columnas donde se buscan referencias a archivos antes de considerarlos
+// huérfanos. Al agregar un modelo que guarde URLs de /api/upload hay que sumarlo aquí.
+var ColumnasConArchivos = []ColumnaConArchivos{
+	{&models.Noticia{}, "url_noticia"},
+	{&models.Noticia{}, "descripcion"},
+	{&models.RevisionNoticia{}, "url_noticia"},
+	{&models.RevisionNoticia{}, "descripcion"},
+	{&models.Dudas{}, "pregunta"},
+	{&models.Dudas{}, "respuesta"},
+	{&models.MensajeDuda{}, "contenido"},
+	{&models.EdicionMensajeDuda{}, "contenido_anterior"},
+	{&models.PreguntaFrecuente{}, "respuesta"},
+}
+
+// ColumnasConIDArchivo son las columnas que guardan el ID de un archivo subido en lugar de su URL
+var ColumnasConIDArchivo = []ColumnaConArchivos{
+	{&models.Noticia{}, "imagen_portada_id"},
+	{&models.RevisionNoticia{}, "imagen_portada_id"},
+}
+
+type HuerfanosRepository struct {
+	db *gorm.DB
+}
+
+func NewHuerfanosRepository(db *gorm.DB) *HuerfanosRepository {
+	return &HuerfanosRepository{db: db}
+}
+
+// ConContexto devuelve una copia del repositorio cuyas consultas usan ctx, para que queden dentro de la
+// traza de la petición
+func (r *HuerfanosRepository) ConContexto(ctx context.Context) *HuerfanosRepository {
+	return &HuerfanosRepository{db: r.db.WithContext(ctx)}
+}
+
+// GetTextosConArchivos devuelve los valores de ColumnasConArchivos que mencionan una carpeta de archivos.
+// Incluye los registros eliminados lógicamente, porque se pueden restaurar.
+func (r *HuerfanosRepository) GetTextosConArchivos() ([]string, error) {
+	var textos []string
+	for _, c := range ColumnasConArchivos {
+		tabla, err := nombreTabla(r.db, c.Modelo)
+		if err != nil {
+			return nil, err
+		}
+		var valores []string
+		err = r.db.Table(tabla).
+			Where(c.Columna+" LIKE ? OR "+c.Columna+" LIKE ? OR "+c.Columna+" LIKE ? OR "+c.Columna+" LIKE ?",
+				"%images/%", "%videos/%", "%documents/%", "%/api/files/firmados/%").
+			Pluck(c.Columna, &valores).Error
+		if err != nil {
+			return nil, err
+		}
+		textos = append(textos, valores...)
+	}
+	return textos, nil
+}
+
+// GetIDsArchivosReferenciados devuelve los IDs guardados en ColumnasConIDArchivo, incluidos los de
+// registros eliminados lógicamente
+func (r *HuerfanosRepository) GetIDsArchivosReferenciados() ([]uint, error) {
+	var ids []uint
+	for _, c := range ColumnasConIDArchivo {
+		tabla, err := nombreTabla(r.db, c.Modelo)
+		if err != nil {
+			return nil, err
+		}
+		var valores []uint
+		if err := r.db.Table(tabla).Where(c.Columna+" IS NOT NULL").Distinct().Pluck(c.Columna, &valores).Error; err != nil {
+			return nil, err
+		}
+		ids = append(ids, valores...)
+	}
+	return ids, nil
+}
+
+// GetArchivosRegistrados lista todos los registros de archivos con sus permisos
+func (r *HuerfanosRepository) GetArchivosRegistrados() ([]models.Archivo, error) {
+	var archivos []models.Archivo
+	err := r.db.Preload("Permisos").Find(&archivos).Error
+	return archivos, err
+}
+
+// CreateCuarentena registra un archivo apartado
+func (r *HuerfanosRepository) CreateCuarentena(cuarentena *models.ArchivoCuarentena) error {
+	return r.db.Create(cuarentena).Error
+}
+
+// GetCuarentenas lista los archivos en cuarentena, los que vencen antes primero
+func (r *HuerfanosRepository) GetCuarentenas() ([]models.ArchivoCuarentena, error) {
+	var cuarentenas []models.ArchivoCuarentena
+	err := r.db.Order("eliminar_en ASC").Find(&cuarentenas).Error
+	return cuarentenas, err
+}
+
+// GetCuarentenaByID obtiene un archivo en cuarentena
+func (r *HuerfanosRepository) GetCuarentenaByID(id uint) (*models.ArchivoCuarentena, error) {
+	var cuarentena models.ArchivoCuarentena
+	if err := r.db.First(&cuarentena, id).Error; err != nil {
+		return nil, err
+	}
+	return &cuarentena, nil
+}
+
+// GetCuarentenasVencidas lista los archivos cuyo plazo de cuarentena terminó
+func (r *HuerfanosRepository) GetCuarentenasVencidas(ahora time.Time) ([]models.ArchivoCuarentena, error) {
+	var cuarentenas []models.ArchivoCuarentena
+	err := r.db.Where("eliminar_en <= ?", ahora).Find(&cuarentenas).Error
+	return cuarentenas, err
+}
+
+// DeleteCuarentena elimina definitivamente el registro de cuarentena
+func (r *HuerfanosRepository) DeleteCuarentena(id uint) error {
+	return r.db.Unscoped().Delete(&models.ArchivoCuarentena{}, id).Error
+}
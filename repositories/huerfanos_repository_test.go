@@ -0,0 +1,58 @@
+package repositories
+
+import (
+	"strings"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+func TestTextosConArchivosConsultaLasTablasDeLosModelos(t *testing.T) {
+	db, consultas := dbSinConexion(t)
+
+	if _, err := NewHuerfanosRepository(db).GetTextosConArchivos(); err != nil {
+		t.Fatal(err)
+	}
+	if len(*consultas) != len(ColumnasConArchivos) {
+		t.Fatalf("se esperaban %d consultas, hubo %d", len(ColumnasConArchivos), len(*consultas))
+	}
+	for i, c := range ColumnasConArchivos {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(c.Modelo); err != nil {
+			t.Fatal(err)
+		}
+		if stmt.Schema.LookUpField(c.Columna) == nil {
+			t.Errorf("la tabla %s no tiene la columna %s", stmt.Schema.Table, c.Columna)
+		}
+		if !strings.Contains((*consultas)[i].sql, `FROM "`+stmt.Schema.Table+`"`) {
+			t.Errorf("consulta %q, se esperaba la tabla %s", (*consultas)[i].sql, stmt.Schema.Table)
+		}
+	}
+	// GORM nombra la tabla de Noticia en singular; la versión anterior consultaba "noticias"
+	if !strings.Contains((*consultas)[0].sql, `FROM "noticia" `) {
+		t.Errorf("la primera consulta debería leer la tabla noticia: %s", (*consultas)[0].sql)
+	}
+}
+
+func TestIDsArchivosReferenciadosConsultaLasColumnasDeLosModelos(t *testing.T) {
+	db, consultas := dbSinConexion(t)
+
+	if _, err := NewHuerfanosRepository(db).GetIDsArchivosReferenciados(); err != nil {
+		t.Fatal(err)
+	}
+	if len(*consultas) != len(ColumnasConIDArchivo) {
+		t.Fatalf("se esperaban %d consultas, hubo %d", len(ColumnasConIDArchivo), len(*consultas))
+	}
+	for i, c := range ColumnasConIDArchivo {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(c.Modelo); err != nil {
+			t.Fatal(err)
+		}
+		if stmt.Schema.LookUpField(c.Columna) == nil {
+			t.Errorf("la tabla %s no tiene la columna %s", stmt.Schema.Table, c.Columna)
+		}
+		if !strings.Contains((*consultas)[i].sql, `FROM "`+stmt.Schema.Table+`"`) {
+			t.Errorf("consulta %q, se esperaba la tabla %s", (*consultas)[i].sql, stmt.Schema.Table)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package repositories
 
 import (
 	"ApiEscuela/models"
+	"context"
 
 	"gorm.io/gorm"
 )
@@ -14,6 +15,12 @@ func NewInstitucionRepository(db *gorm.DB) *InstitucionRepository {
 	return &InstitucionRepository{db: db}
 }
 
+// ConContexto devuelve una copia del repositorio cuyas consultas usan ctx, para que queden dentro de la
+// traza de la petición
+func (r *InstitucionRepository) ConContexto(ctx context.Context) *InstitucionRepository {
+	return &InstitucionRepository{db: r.db.WithContext(ctx)}
+}
+
 // GetDB retorna la instancia de la base de datos para uso interno
 func (r *InstitucionRepository) GetDB() *gorm.DB {
 	return r.db
@@ -2,8 +2,23 @@ package repositories
 
 import (
 	"ApiEscuela/models"
+	"context"
+	"errors"
+	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrNoticiaModificada indica que la noticia cambió desde que se leyó: otra edición guardó una versión
+// nueva o su estado cambió (por ejemplo, el proceso de publicación programada la publicó)
+var ErrNoticiaModificada = errors.New("la noticia fue modificada por otra petición")
+
+// columnasContenidoNoticia son las que cambian las ediciones y restauraciones; columnasEstadoNoticia, las del
+// flujo editorial. Cada operación actualiza solo las suyas para no revertir lo que guardó la otra.
+var (
+	columnasContenidoNoticia = []string{"titulo", "descripcion", "url_noticia", "slug", "categoria_id", "imagen_portada_id", "version", "updated_at"}
+	columnasEstadoNoticia    = []string{"estado", "publicar_en", "expira_en", "publicada_en", "publicada_por_id", "comentario_revision", "updated_at"}
 )
 
 type NoticiaRepository struct {
@@ -14,33 +29,183 @@ func NewNoticiaRepository(db *gorm.DB) *NoticiaRepository {
 	return &NoticiaRepository{db: db}
 }
 
-// CreateNoticia crea una nueva noticia
-func (r *NoticiaRepository) CreateNoticia(noticia *models.Noticia) error {
-	return r.db.Create(noticia).Error
+// ConContexto devuelve una copia del repositorio cuyas consultas usan ctx, para que queden dentro de la
+// traza de la petición
+func (r *NoticiaRepository) ConContexto(ctx context.Context) *NoticiaRepository {
+	return &NoticiaRepository{db: r.db.WithContext(ctx)}
+}
+
+// FiltroNoticias filtra los listados por slug de categoría y de etiqueta; los campos vacíos no filtran
+type FiltroNoticias struct {
+	Categoria string
+	Etiqueta  string
+}
+
+func (f FiltroNoticias) aplicar(db *gorm.DB) *gorm.DB {
+	if f.Categoria != "" {
+		db = db.Where("noticia.categoria_id IN (SELECT id FROM categorias_noticia WHERE slug = ? AND deleted_at IS NULL)", f.Categoria)
+	}
+	if f.Etiqueta != "" {
+		db = db.Where(`noticia.id IN (SELECT ne.noticia_id FROM noticia_etiquetas ne
+			JOIN etiquetas_noticia e ON e.id = ne.etiqueta_noticia_id WHERE e.slug = ? AND e.deleted_at IS NULL)`, f.Etiqueta)
+	}
+	return db
+}
+
+// preloadNoticia carga las relaciones que se devuelven con cada noticia
+func preloadNoticia(db *gorm.DB) *gorm.DB {
+	return db.Preload("Usuario").Preload("Usuario.Persona").Preload("Categoria").Preload("ImagenPortada").Preload("Etiquetas")
 }
 
-// GetNoticiaByID obtiene una noticia por ID
+// noticiaVisible limita la consulta a las noticias publicadas y no expiradas
+func noticiaVisible(db *gorm.DB) *gorm.DB {
+	return db.Where("noticia.estado = ? AND (noticia.expira_en IS NULL OR noticia.expira_en > ?)", models.EstadoNoticiaPublicada, time.Now())
+}
+
+// CreateNoticia crea una nueva noticia junto con su primera revisión
+func (r *NoticiaRepository) CreateNoticia(noticia *models.Noticia, revision *models.RevisionNoticia) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit(clause.Associations).Create(noticia).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(noticia).Association("Etiquetas").Replace(noticia.Etiquetas); err != nil {
+			return err
+		}
+		revision.NoticiaID = noticia.ID
+		revision.Version = noticia.Version
+		return tx.Create(revision).Error
+	})
+}
+
+// GetNoticiaByID obtiene una noticia por ID, en cualquier estado
 func (r *NoticiaRepository) GetNoticiaByID(id uint) (*models.Noticia, error) {
 	var noticia models.Noticia
-	err := r.db.Preload("Usuario").Preload("Usuario.Persona").
-		First(&noticia, id).Error
+	err := r.db.Scopes(preloadNoticia).First(&noticia, id).Error
 	if err != nil {
 		return nil, err
 	}
 	return &noticia, nil
 }
 
-// GetAllNoticias obtiene todas las noticias
-func (r *NoticiaRepository) GetAllNoticias() ([]models.Noticia, error) {
+// GetNoticiaBySlug obtiene una noticia por su slug, en cualquier estado
+func (r *NoticiaRepository) GetNoticiaBySlug(slug string) (*models.Noticia, error) {
+	var noticia models.Noticia
+	err := r.db.Scopes(preloadNoticia).Where("slug = ?", slug).First(&noticia).Error
+	if err != nil {
+		return nil, err
+	}
+	return &noticia, nil
+}
+
+// SlugEnUso indica si otra noticia, incluso eliminada, ya usa el slug
+func (r *NoticiaRepository) SlugEnUso(slug string, exceptoID uint) (bool, error) {
+	var total int64
+	err := r.db.Unscoped().Model(&models.Noticia{}).Where("slug = ? AND id <> ?", slug, exceptoID).Count(&total).Error
+	return total > 0, err
+}
+
+// GetNoticiasSinSlug obtiene las noticias creadas antes de que existieran los slugs
+func (r *NoticiaRepository) GetNoticiasSinSlug() ([]models.Noticia, error) {
+	var noticias []models.Noticia
+	err := r.db.Where("slug IS NULL OR slug = ''").Order("id").Find(&noticias).Error
+	return noticias, err
+}
+
+// AsignarSlug guarda el slug de una noticia sin modificar nada más
+func (r *NoticiaRepository) AsignarSlug(id uint, slug string) error {
+	return r.db.Model(&models.Noticia{}).Where("id = ?", id).UpdateColumn("slug", slug).Error
+}
+
+// GetAllNoticias obtiene todas las noticias publicadas
+func (r *NoticiaRepository) GetAllNoticias(filtro FiltroNoticias) ([]models.Noticia, error) {
 	var noticias []models.Noticia
-	err := r.db.Preload("Usuario").Preload("Usuario.Persona").
+	err := r.db.Scopes(noticiaVisible, filtro.aplicar, preloadNoticia).
+		Order("publicada_en DESC NULLS LAST, id DESC").
 		Find(&noticias).Error
 	return noticias, err
 }
 
-// UpdateNoticia actualiza una noticia
-func (r *NoticiaRepository) UpdateNoticia(noticia *models.Noticia) error {
-	return r.db.Save(noticia).Error
+// GetNoticiasPublicadasPaginadas obtiene una página de noticias publicadas, de la más reciente a la más antigua
+func (r *NoticiaRepository) GetNoticiasPublicadasPaginadas(filtro FiltroNoticias, offset, limite int) ([]models.Noticia, int64, error) {
+	var total int64
+	if err := r.db.Model(&models.Noticia{}).Scopes(noticiaVisible, filtro.aplicar).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var noticias []models.Noticia
+	err := r.db.Scopes(noticiaVisible, filtro.aplicar).Preload("Categoria").Preload("ImagenPortada").Preload("Etiquetas").
+		Order("publicada_en DESC NULLS LAST, id DESC").
+		Offset(offset).Limit(limite).
+		Find(&noticias).Error
+	return noticias, total, err
+}
+
+// UltimaModificacionNoticias devuelve la fecha del último cambio en noticias o categorías, incluidas las
+// eliminaciones, para responder Last-Modified en los listados públicos. Es nil si nunca hubo noticias.
+func (r *NoticiaRepository) UltimaModificacionNoticias() (*time.Time, error) {
+	var resultado struct {
+		Ultima *time.Time
+	}
+	err := r.db.Raw(`SELECT GREATEST(
+			(SELECT MAX(GREATEST(updated_at, COALESCE(deleted_at, updated_at))) FROM noticia),
+			(SELECT MAX(updated_at) FROM categorias_noticia)
+		) AS ultima`).Scan(&resultado).Error
+	return resultado.Ultima, err
+}
+
+// GetNoticiasEditorial obtiene noticias en cualquier estado; estado y usuarioID son filtros opcionales
+func (r *NoticiaRepository) GetNoticiasEditorial(estado string, usuarioID *uint, filtro FiltroNoticias) ([]models.Noticia, error) {
+	var noticias []models.Noticia
+	query := r.db.Scopes(filtro.aplicar, preloadNoticia)
+	if estado != "" {
+		query = query.Where("estado = ?", estado)
+	}
+	if usuarioID != nil {
+		query = query.Where("usuario_id = ?", *usuarioID)
+	}
+	err := query.Order("updated_at DESC").Find(&noticias).Error
+	return noticias, err
+}
+
+// GuardarContenido actualiza el contenido y las etiquetas de una noticia; si revision no es nil lo guarda
+// como una nueva versión. Falla con ErrNoticiaModificada si otra edición guardó una versión desde que se leyó.
+func (r *NoticiaRepository) GuardarContenido(noticia *models.Noticia, revision *models.RevisionNoticia) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		leida := noticia.Version
+		if revision != nil {
+			noticia.Version++
+		}
+		res := tx.Model(noticia).Where("version = ?", leida).
+			Select(columnasContenidoNoticia).Omit(clause.Associations).Updates(noticia)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrNoticiaModificada
+		}
+		if err := tx.Model(noticia).Association("Etiquetas").Replace(noticia.Etiquetas); err != nil {
+			return err
+		}
+		if revision == nil {
+			return nil
+		}
+		revision.NoticiaID = noticia.ID
+		revision.Version = noticia.Version
+		return tx.Create(revision).Error
+	})
+}
+
+// CambiarEstado guarda el estado editorial y sus fechas si la noticia sigue en estadoLeido, para que una
+// transición no pise otra simultánea ni la publicación programada
+func (r *NoticiaRepository) CambiarEstado(noticia *models.Noticia, estadoLeido string) error {
+	res := r.db.Model(noticia).Where("estado = ?", estadoLeido).
+		Select(columnasEstadoNoticia).Omit(clause.Associations).Updates(noticia)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrNoticiaModificada
+	}
+	return nil
 }
 
 // DeleteNoticia elimina una noticia
@@ -48,38 +213,158 @@ func (r *NoticiaRepository) DeleteNoticia(id uint) error {
 	return r.db.Delete(&models.Noticia{}, id).Error
 }
 
-// GetNoticiasByUsuario obtiene noticias por usuario
+// GetNoticiasByUsuario obtiene las noticias publicadas de un usuario
 func (r *NoticiaRepository) GetNoticiasByUsuario(usuarioID uint) ([]models.Noticia, error) {
 	var noticias []models.Noticia
-	err := r.db.Where("usuario_id = ?", usuarioID).
-		Preload("Usuario").Preload("Usuario.Persona").
+	err := r.db.Scopes(noticiaVisible).Where("usuario_id = ?", usuarioID).
+		Scopes(preloadNoticia).
 		Find(&noticias).Error
 	return noticias, err
 }
 
-// GetNoticiasByTitulo busca noticias por título
+// GetNoticiasByTitulo busca noticias publicadas por título
 func (r *NoticiaRepository) GetNoticiasByTitulo(titulo string) ([]models.Noticia, error) {
 	var noticias []models.Noticia
-	err := r.db.Where("titulo ILIKE ?", "%"+titulo+"%").
-		Preload("Usuario").Preload("Usuario.Persona").
+	err := r.db.Scopes(noticiaVisible).Where("titulo ILIKE ?", "%"+titulo+"%").
+		Scopes(preloadNoticia).
 		Find(&noticias).Error
 	return noticias, err
 }
 
-// GetNoticiasByDescripcion busca noticias por descripción
+// GetNoticiasByDescripcion busca noticias publicadas por descripción
 func (r *NoticiaRepository) GetNoticiasByDescripcion(descripcion string) ([]models.Noticia, error) {
 	var noticias []models.Noticia
-	err := r.db.Where("descripcion ILIKE ?", "%"+descripcion+"%").
-		Preload("Usuario").Preload("Usuario.Persona").
+	err := r.db.Scopes(noticiaVisible).Where("descripcion ILIKE ?", "%"+descripcion+"%").
+		Scopes(preloadNoticia).
 		Find(&noticias).Error
 	return noticias, err
 }
 
-// SearchNoticias busca noticias por título o descripción
+// SearchNoticias busca noticias publicadas por título o descripción con búsqueda de texto completo, de la más relevante a la menos
 func (r *NoticiaRepository) SearchNoticias(termino string) ([]models.Noticia, error) {
 	var noticias []models.Noticia
-	err := r.db.Where("titulo ILIKE ? OR descripcion ILIKE ?", "%"+termino+"%", "%"+termino+"%").
-		Preload("Usuario").Preload("Usuario.Persona").
+	err := r.db.Scopes(noticiaVisible).Where(coincideBusqueda(termino)).Order(ordenRelevancia(termino)).
+		Scopes(preloadNoticia).
 		Find(&noticias).Error
 	return noticias, err
 }
+
+// CreateRevision guarda una revisión sin cambiar la noticia
+func (r *NoticiaRepository) CreateRevision(revision *models.RevisionNoticia) error {
+	return r.db.Create(revision).Error
+}
+
+// GetRevisiones obtiene las versiones de una noticia, de la más reciente a la más antigua
+func (r *NoticiaRepository) GetRevisiones(noticiaID uint) ([]models.RevisionNoticia, error) {
+	var revisiones []models.RevisionNoticia
+	err := r.db.Where("noticia_id = ?", noticiaID).Order("version DESC").Find(&revisiones).Error
+	return revisiones, err
+}
+
+// GetRevision obtiene una versión concreta de una noticia
+func (r *NoticiaRepository) GetRevision(noticiaID uint, version int) (*models.RevisionNoticia, error) {
+	var revision models.RevisionNoticia
+	err := r.db.Where("noticia_id = ? AND version = ?", noticiaID, version).First(&revision).Error
+	if err != nil {
+		return nil, err
+	}
+	return &revision, nil
+}
+
+// PublicarProgramadas publica las noticias programadas cuya fecha de publicación ya llegó
+func (r *NoticiaRepository) PublicarProgramadas(ahora time.Time) (int64, error) {
+	res := r.db.Model(&models.Noticia{}).
+		Where("estado = ? AND publicar_en <= ?", models.EstadoNoticiaProgramada, ahora).
+		Updates(map[string]interface{}{"estado": models.EstadoNoticiaPublicada, "publicada_en": gorm.Expr("publicar_en")})
+	return res.RowsAffected, res.Error
+}
+
+// ArchivarExpiradas archiva las noticias publicadas cuya fecha de expiración ya pasó
+func (r *NoticiaRepository) ArchivarExpiradas(ahora time.Time) (int64, error) {
+	res := r.db.Model(&models.Noticia{}).
+		Where("estado = ? AND expira_en <= ?", models.EstadoNoticiaPublicada, ahora).
+		Update("estado", models.EstadoNoticiaArchivada)
+	return res.RowsAffected, res.Error
+}
+
+// GetCategorias obtiene las categorías de noticias ordenadas por nombre
+func (r *NoticiaRepository) GetCategorias() ([]models.CategoriaNoticia, error) {
+	var categorias []models.CategoriaNoticia
+	err := r.db.Order("nombre").Find(&categorias).Error
+	return categorias, err
+}
+
+// GetCategoriaByID obtiene una categoría por ID
+func (r *NoticiaRepository) GetCategoriaByID(id uint) (*models.CategoriaNoticia, error) {
+	var categoria models.CategoriaNoticia
+	if err := r.db.First(&categoria, id).Error; err != nil {
+		return nil, err
+	}
+	return &categoria, nil
+}
+
+// CategoriaEnUso indica si otra categoría ya usa el nombre o el slug
+func (r *NoticiaRepository) CategoriaEnUso(nombre, slug string, exceptoID uint) (bool, error) {
+	var total int64
+	err := r.db.Model(&models.CategoriaNoticia{}).
+		Where("(lower(nombre) = lower(?) OR slug = ?) AND id <> ?", nombre, slug, exceptoID).
+		Count(&total).Error
+	return total > 0, err
+}
+
+// CreateCategoria crea una categoría
+func (r *NoticiaRepository) CreateCategoria(categoria *models.CategoriaNoticia) error {
+	return r.db.Create(categoria).Error
+}
+
+// UpdateCategoria actualiza una categoría
+func (r *NoticiaRepository) UpdateCategoria(categoria *models.CategoriaNoticia) error {
+	return r.db.Save(categoria).Error
+}
+
+// DeleteCategoria elimina definitivamente una categoría y deja sin categoría a sus noticias
+func (r *NoticiaRepository) DeleteCategoria(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Noticia{}).Where("categoria_id = ?", id).Update("categoria_id", nil).Error; err != nil {
+			return err
+		}
+		res := tx.Unscoped().Delete(&models.CategoriaNoticia{}, id)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+}
+
+// GetEtiquetas obtiene las etiquetas usadas por al menos una noticia publicada
+func (r *NoticiaRepository) GetEtiquetas() ([]models.EtiquetaNoticia, error) {
+	var etiquetas []models.EtiquetaNoticia
+	err := r.db.Where(`id IN (SELECT ne.etiqueta_noticia_id FROM noticia_etiquetas ne
+		JOIN noticia ON noticia.id = ne.noticia_id WHERE noticia.deleted_at IS NULL AND noticia.estado = ?
+		AND (noticia.expira_en IS NULL OR noticia.expira_en > ?))`, models.EstadoNoticiaPublicada, time.Now()).
+		Order("nombre").Find(&etiquetas).Error
+	return etiquetas, err
+}
+
+// ObtenerOCrearEtiquetas devuelve las etiquetas con los slugs indicados, creando las que no existan.
+// nombres y slugs deben tener la misma longitud.
+func (r *NoticiaRepository) ObtenerOCrearEtiquetas(nombres, slugs []string) ([]models.EtiquetaNoticia, error) {
+	if len(nombres) != len(slugs) {
+		return nil, errors.New("nombres y slugs de etiquetas no coinciden")
+	}
+	etiquetas := make([]models.EtiquetaNoticia, 0, len(slugs))
+	for i, slug := range slugs {
+		var etiqueta models.EtiquetaNoticia
+		err := r.db.Where(models.EtiquetaNoticia{Slug: slug}).
+			Attrs(models.EtiquetaNoticia{Nombre: nombres[i]}).
+			FirstOrCreate(&etiqueta).Error
+		if err != nil {
+			return nil, err
+		}
+		etiquetas = append(etiquetas, etiqueta)
+	}
+	return etiquetas, nil
+}
@@ -2,6 +2,7 @@ package repositories
 
 import (
 	"ApiEscuela/models"
+	"context"
 	"errors"
 	"strings"
 
@@ -40,6 +41,12 @@ func NewPersonaRepository(db *gorm.DB) *PersonaRepository {
 	return &PersonaRepository{db: db}
 }
 
+// ConContexto devuelve una copia del repositorio cuyas consultas usan ctx, para que queden dentro de la
+// traza de la petición
+func (r *PersonaRepository) ConContexto(ctx context.Context) *PersonaRepository {
+	return &PersonaRepository{db: r.db.WithContext(ctx)}
+}
+
 // GetDB retorna la instancia de la base de datos para uso interno
 func (r *PersonaRepository) GetDB() *gorm.DB {
 	return r.db
@@ -0,0 +1,326 @@
+package repositories
+
+import (
+	"ApiEscuela/models"
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type PlantillaProgramaVisitaRepository struct {
+	db *gorm.DB
+}
+
+func NewPlantillaProgramaVisitaRepository(db *gorm.DB) *PlantillaProgramaVisitaRepository {
+	return &PlantillaProgramaVisitaRepository{db: db}
+}
+
+// ConContexto devuelve una copia del repositorio cuyas consultas usan ctx, para que queden dentro de la
+// traza de la petición
+func (r *PlantillaProgramaVisitaRepository) ConContexto(ctx context.Context) *PlantillaProgramaVisitaRepository {
+	return &PlantillaProgramaVisitaRepository{db: r.db.WithContext(ctx)}
+}
+
+// CreatePlantilla crea una plantilla junto con sus actividades y asignaciones
+func (r *PlantillaProgramaVisitaRepository) CreatePlantilla(plantilla *models.PlantillaProgramaVisita) error {
+	return r.db.Create(plantilla).Error
+}
+
+// GetPlantillaByID obtiene una plantilla por ID con sus relaciones
+func (r *PlantillaProgramaVisitaRepository) GetPlantillaByID(id uint) (*models.PlantillaProgramaVisita, error) {
+	var plantilla models.PlantillaProgramaVisita
+	err := r.db.Preload("Institucion").
+		Preload("Actividades").Preload("Actividades.Actividad").
+		Preload("Autoridades").Preload("Autoridades.AutoridadUTEQ").
+		Preload("Estudiantes").Preload("Estudiantes.EstudianteUniversitario").
+		First(&plantilla, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &plantilla, nil
+}
+
+// GetAllPlantillas obtiene todas las plantillas
+func (r *PlantillaProgramaVisitaRepository) GetAllPlantillas() ([]models.PlantillaProgramaVisita, error) {
+	var plantillas []models.PlantillaProgramaVisita
+	err := r.db.Preload("Institucion").
+		Preload("Actividades").Preload("Autoridades").Preload("Estudiantes").
+		Find(&plantillas).Error
+	return plantillas, err
+}
+
+// GetPlantillasByInstitucion obtiene las plantillas de una institución
+func (r *PlantillaProgramaVisitaRepository) GetPlantillasByInstitucion(institucionID uint) ([]models.PlantillaProgramaVisita, error) {
+	var plantillas []models.PlantillaProgramaVisita
+	err := r.db.Where("institucion_id = ?", institucionID).
+		Preload("Institucion").
+		Preload("Actividades").Preload("Autoridades").Preload("Estudiantes").
+		Find(&plantillas).Error
+	return plantillas, err
+}
+
+// UpdatePlantilla actualiza los campos de la plantilla sin tocar sus relaciones
+func (r *PlantillaProgramaVisitaRepository) UpdatePlantilla(plantilla *models.PlantillaProgramaVisita) error {
+	return r.db.Omit(clause.Associations).Save(plantilla).Error
+}
+
+// GetFechasOcurrencia obtiene las fechas de ocurrencia ya materializadas. Incluye las eliminadas, para
+// no regenerar una visita que alguien borró; las reemplazadas por ReemplazarSerie ya no tienen fecha.
+func (r *PlantillaProgramaVisitaRepository) GetFechasOcurrencia(plantillaID uint) ([]time.Time, error) {
+	var fechas []time.Time
+	err := r.db.Unscoped().Model(&models.ProgramaVisita{}).
+		Where("plantilla_id = ? AND fecha_ocurrencia IS NOT NULL", plantillaID).
+		Pluck("fecha_ocurrencia", &fechas).Error
+	return fechas, err
+}
+
+// GetProgramasByPlantilla obtiene los programas generados por una plantilla
+func (r *PlantillaProgramaVisitaRepository) GetProgramasByPlantilla(plantillaID uint) ([]models.ProgramaVisita, error) {
+	var programas []models.ProgramaVisita
+	err := r.db.Where("plantilla_id = ?", plantillaID).
+		Preload("Institucion").
+		Order("fecha_ocurrencia ASC").
+		Find(&programas).Error
+	return programas, err
+}
+
+// GetProgramaDePlantilla obtiene un programa verificando que pertenezca a la plantilla
+func (r *PlantillaProgramaVisitaRepository) GetProgramaDePlantilla(plantillaID, programaID uint) (*models.ProgramaVisita, error) {
+	var programa models.ProgramaVisita
+	err := r.db.Where("id = ? AND plantilla_id = ?", programaID, plantillaID).First(&programa).Error
+	if err != nil {
+		return nil, err
+	}
+	return &programa, nil
+}
+
+// MaterializarOcurrencias crea en una transacción los programas de visita de las fechas dadas,
+// con sus actividades (VisitaDetalle) y asignaciones de autoridades y estudiantes
+func (r *PlantillaProgramaVisitaRepository) MaterializarOcurrencias(plantilla *models.PlantillaProgramaVisita, fechas []time.Time) ([]models.ProgramaVisita, error) {
+	programas := make([]models.ProgramaVisita, 0, len(fechas))
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		for _, fecha := range fechas {
+			ocurrencia := fecha
+			programa := models.ProgramaVisita{
+				Fecha:           fecha,
+				Fechafin:        fecha.Add(time.Duration(plantilla.DuracionMinutos) * time.Minute),
+				InstitucionID:   plantilla.InstitucionID,
+				PlantillaID:     &plantilla.ID,
+				FechaOcurrencia: &ocurrencia,
+			}
+			if err := tx.Create(&programa).Error; err != nil {
+				return err
+			}
+			if err := crearAsignacionesPrograma(tx, programa.ID,
+				plantilla.ActividadIDs(), plantilla.AutoridadIDs(), plantilla.EstudianteIDs()); err != nil {
+				return err
+			}
+			programas = append(programas, programa)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return programas, nil
+}
+
+// ReemplazarAsignacionesPrograma reemplaza las actividades y asignaciones de un único programa.
+// Un slice nil conserva las asignaciones actuales de ese tipo.
+func (r *PlantillaProgramaVisitaRepository) ReemplazarAsignacionesPrograma(programa *models.ProgramaVisita, actividadIDs, autoridadIDs, estudianteIDs []uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit(clause.Associations).Save(programa).Error; err != nil {
+			return err
+		}
+		if actividadIDs != nil {
+			if err := tx.Where("programa_visita_id = ?", programa.ID).Delete(&models.VisitaDetalle{}).Error; err != nil {
+				return err
+			}
+		}
+		if autoridadIDs != nil {
+			if err := tx.Where("programa_visita_id = ?", programa.ID).Delete(&models.DetalleAutoridadDetallesVisita{}).Error; err != nil {
+				return err
+			}
+		}
+		if estudianteIDs != nil {
+			if err := tx.Where("programa_visita_id = ?", programa.ID).Delete(&models.VisitaDetalleEstudiantesUniversitarios{}).Error; err != nil {
+				return err
+			}
+		}
+		return crearAsignacionesPrograma(tx, programa.ID, actividadIDs, autoridadIDs, estudianteIDs)
+	})
+}
+
+// DividirSerie cierra la serie original, crea la nueva serie ("esta y futuras") y elimina
+// los programas de la serie original desde la fecha indicada. Devuelve los programas eliminados.
+func (r *PlantillaProgramaVisitaRepository) DividirSerie(original, nueva *models.PlantillaProgramaVisita, desde time.Time) ([]uint, error) {
+	var eliminados []uint
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit(clause.Associations).Save(original).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(nueva).Error; err != nil {
+			return err
+		}
+		var err error
+		eliminados, err = eliminarProgramasDesde(tx, original.ID, desde)
+		return err
+	})
+	return eliminados, err
+}
+
+// ReemplazarSerie actualiza la plantilla completa y elimina sus programas desde la fecha indicada para
+// regenerarlos. Las ocurrencias editadas individualmente (excepciones) se conservan. Las demás se
+// eliminan lógicamente, para que el calendario publique su cancelación, y pierden su fecha de
+// ocurrencia para poder regenerarlas. Devuelve los programas eliminados.
+func (r *PlantillaProgramaVisitaRepository) ReemplazarSerie(plantilla *models.PlantillaProgramaVisita, desde time.Time, actividadIDs, autoridadIDs, estudianteIDs []uint) ([]uint, error) {
+	var ids []uint
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit(clause.Associations).Save(plantilla).Error; err != nil {
+			return err
+		}
+		if err := reemplazarAsignacionesPlantilla(tx, plantilla.ID, actividadIDs, autoridadIDs, estudianteIDs); err != nil {
+			return err
+		}
+		if err := tx.Model(&models.ProgramaVisita{}).
+			Where("plantilla_id = ? AND fecha_ocurrencia >= ? AND excepcion = ?", plantilla.ID, desde, false).
+			Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+		if err := tx.Model(&models.ProgramaVisita{}).Where("id IN ?", ids).Update("fecha_ocurrencia", nil).Error; err != nil {
+			return err
+		}
+		return eliminarProgramas(tx, ids)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// TruncarSerie guarda la plantilla con su regla ya terminada antes de desde y elimina sus programas
+// desde esa fecha de ocurrencia. Devuelve los programas eliminados.
+func (r *PlantillaProgramaVisitaRepository) TruncarSerie(plantilla *models.PlantillaProgramaVisita, desde time.Time) ([]uint, error) {
+	var eliminados []uint
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit(clause.Associations).Save(plantilla).Error; err != nil {
+			return err
+		}
+		var err error
+		eliminados, err = eliminarProgramasDesde(tx, plantilla.ID, desde)
+		return err
+	})
+	return eliminados, err
+}
+
+// EliminarOcurrencia agrega la excepción a la plantilla y elimina el programa de esa ocurrencia
+func (r *PlantillaProgramaVisitaRepository) EliminarOcurrencia(plantilla *models.PlantillaProgramaVisita, programaID uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit(clause.Associations).Save(plantilla).Error; err != nil {
+			return err
+		}
+		return eliminarProgramas(tx, []uint{programaID})
+	})
+}
+
+// DeletePlantilla elimina la plantilla, sus relaciones y los programas futuros generados.
+// Devuelve los programas eliminados.
+func (r *PlantillaProgramaVisitaRepository) DeletePlantilla(id uint) ([]uint, error) {
+	var eliminados []uint
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if eliminados, err = eliminarProgramasDesde(tx, id, time.Now()); err != nil {
+			return err
+		}
+		if err := reemplazarAsignacionesPlantilla(tx, id, []uint{}, []uint{}, []uint{}); err != nil {
+			return err
+		}
+		return tx.Delete(&models.PlantillaProgramaVisita{}, id).Error
+	})
+	return eliminados, err
+}
+
+// Funciones auxiliares
+
+func eliminarProgramasDesde(tx *gorm.DB, plantillaID uint, desde time.Time) ([]uint, error) {
+	var ids []uint
+	if err := tx.Model(&models.ProgramaVisita{}).
+		Where("plantilla_id = ? AND fecha_ocurrencia >= ?", plantillaID, desde).
+		Pluck("id", &ids).Error; err != nil {
+		return nil, err
+	}
+	return ids, eliminarProgramas(tx, ids)
+}
+
+// eliminarProgramas elimina lógicamente los programas y sus asignaciones con el mismo deleted_at,
+// que es lo que permite al calendario reconocer las asignaciones canceladas junto con el programa
+func eliminarProgramas(tx *gorm.DB, ids []uint) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	eliminado := tx.NowFunc()
+	for _, asignacion := range []interface{}{&models.VisitaDetalle{}, &models.DetalleAutoridadDetallesVisita{}, &models.VisitaDetalleEstudiantesUniversitarios{}} {
+		if err := tx.Model(asignacion).Where("programa_visita_id IN ?", ids).Update("deleted_at", eliminado).Error; err != nil {
+			return err
+		}
+	}
+	return tx.Model(&models.ProgramaVisita{}).Where("id IN ?", ids).Update("deleted_at", eliminado).Error
+}
+
+func crearAsignacionesPrograma(tx *gorm.DB, programaID uint, actividadIDs, autoridadIDs, estudianteIDs []uint) error {
+	for _, id := range actividadIDs {
+		if err := tx.Create(&models.VisitaDetalle{ProgramaVisitaID: programaID, ActividadID: id}).Error; err != nil {
+			return err
+		}
+	}
+	for _, id := range autoridadIDs {
+		if err := tx.Create(&models.DetalleAutoridadDetallesVisita{ProgramaVisitaID: programaID, AutoridadUTEQID: id}).Error; err != nil {
+			return err
+		}
+	}
+	for _, id := range estudianteIDs {
+		if err := tx.Create(&models.VisitaDetalleEstudiantesUniversitarios{ProgramaVisitaID: programaID, EstudianteUniversitarioID: id}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func reemplazarAsignacionesPlantilla(tx *gorm.DB, plantillaID uint, actividadIDs, autoridadIDs, estudianteIDs []uint) error {
+	if actividadIDs != nil {
+		if err := tx.Where("plantilla_programa_visita_id = ?", plantillaID).Delete(&models.PlantillaVisitaActividad{}).Error; err != nil {
+			return err
+		}
+		for _, id := range actividadIDs {
+			if err := tx.Create(&models.PlantillaVisitaActividad{PlantillaProgramaVisitaID: plantillaID, ActividadID: id}).Error; err != nil {
+				return err
+			}
+		}
+	}
+	if autoridadIDs != nil {
+		if err := tx.Where("plantilla_programa_visita_id = ?", plantillaID).Delete(&models.PlantillaVisitaAutoridad{}).Error; err != nil {
+			return err
+		}
+		for _, id := range autoridadIDs {
+			if err := tx.Create(&models.PlantillaVisitaAutoridad{PlantillaProgramaVisitaID: plantillaID, AutoridadUTEQID: id}).Error; err != nil {
+				return err
+			}
+		}
+	}
+	if estudianteIDs != nil {
+		if err := tx.Where("plantilla_programa_visita_id = ?", plantillaID).Delete(&models.PlantillaVisitaEstudiante{}).Error; err != nil {
+			return err
+		}
+		for _, id := range estudianteIDs {
+			if err := tx.Create(&models.PlantillaVisitaEstudiante{PlantillaProgramaVisitaID: plantillaID, EstudianteUniversitarioID: id}).Error; err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package repositories
+
+import (
+	"ApiEscuela/models"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func TestEliminarOcurrenciaMarcaProgramaYAsignacionesConLaMismaFecha(t *testing.T) {
+	db, _ := dbSinConexion(t)
+	var eliminaciones []consultaGenerada
+	err := db.Callback().Update().After("gorm:update").Register("prueba:eliminacion", func(d *gorm.DB) {
+		if sql := d.Statement.SQL.String(); strings.Contains(sql, `SET "deleted_at"`) {
+			eliminaciones = append(eliminaciones, consultaGenerada{sql: sql, vars: d.Statement.Vars})
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plantilla := &models.PlantillaProgramaVisita{}
+	plantilla.ID = 1
+	if err := NewPlantillaProgramaVisitaRepository(db).EliminarOcurrencia(plantilla, 7); err != nil {
+		t.Fatal(err)
+	}
+
+	tablas := []string{"visita_detalles", "detalle_autoridad_detalles_visita", "visita_detalle_estudiantes_universitarios", "programa_visita"}
+	if len(eliminaciones) != len(tablas) {
+		t.Fatalf("se esperaban %d eliminaciones, hubo %d", len(tablas), len(eliminaciones))
+	}
+	// El calendario encuentra las asignaciones de un programa cancelado por su deleted_at
+	fecha, ok := eliminaciones[0].vars[0].(time.Time)
+	if !ok {
+		t.Fatalf("el primer parámetro no es la fecha de eliminación: %v", eliminaciones[0].vars)
+	}
+	for i, e := range eliminaciones {
+		if !strings.Contains(e.sql, `"`+tablas[i]) {
+			t.Errorf("consulta %q, se esperaba la tabla %s", e.sql, tablas[i])
+		}
+		if f, _ := e.vars[0].(time.Time); !f.Equal(fecha) {
+			t.Errorf("%s se eliminó con %v y el resto con %v", tablas[i], f, fecha)
+		}
+	}
+}
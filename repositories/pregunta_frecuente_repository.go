@@ -0,0 +1,93 @@
+package repositories
+
+import (
+	"ApiEscuela/models"
+	"context"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+type PreguntaFrecuenteRepository struct {
+	db *gorm.DB
+}
+
+func NewPreguntaFrecuenteRepository(db *gorm.DB) *PreguntaFrecuenteRepository {
+	return &PreguntaFrecuenteRepository{db: db}
+}
+
+// ConContexto devuelve una copia del repositorio cuyas consultas usan ctx, para que queden dentro de la
+// traza de la petición
+func (r *PreguntaFrecuenteRepository) ConContexto(ctx context.Context) *PreguntaFrecuenteRepository {
+	return &PreguntaFrecuenteRepository{db: r.db.WithContext(ctx)}
+}
+
+// CreatePreguntaFrecuente crea una entrada del FAQ
+func (r *PreguntaFrecuenteRepository) CreatePreguntaFrecuente(pregunta *models.PreguntaFrecuente) error {
+	return r.db.Create(pregunta).Error
+}
+
+// GetPreguntaFrecuenteByID obtiene una entrada del FAQ por ID
+func (r *PreguntaFrecuenteRepository) GetPreguntaFrecuenteByID(id uint) (*models.PreguntaFrecuente, error) {
+	var pregunta models.PreguntaFrecuente
+	if err := r.db.First(&pregunta, id).Error; err != nil {
+		return nil, err
+	}
+	return &pregunta, nil
+}
+
+// GetPreguntaFrecuenteByDuda obtiene la entrada promovida desde una duda
+func (r *PreguntaFrecuenteRepository) GetPreguntaFrecuenteByDuda(dudaID uint) (*models.PreguntaFrecuente, error) {
+	var pregunta models.PreguntaFrecuente
+	if err := r.db.Where("duda_id = ?", dudaID).First(&pregunta).Error; err != nil {
+		return nil, err
+	}
+	return &pregunta, nil
+}
+
+// GetAllPreguntasFrecuentes obtiene todas las entradas, incluidas las no publicadas
+func (r *PreguntaFrecuenteRepository) GetAllPreguntasFrecuentes() ([]models.PreguntaFrecuente, error) {
+	var preguntas []models.PreguntaFrecuente
+	err := r.db.Order("categoria, orden, id").Find(&preguntas).Error
+	return preguntas, err
+}
+
+// GetPreguntasPublicadas obtiene las entradas publicadas, opcionalmente filtradas por categoría y término
+func (r *PreguntaFrecuenteRepository) GetPreguntasPublicadas(categoria, termino string) ([]models.PreguntaFrecuente, error) {
+	var preguntas []models.PreguntaFrecuente
+	query := r.db.Where("publicada = ?", true)
+	if categoria != "" {
+		query = query.Where("categoria = ?", categoria)
+	}
+	if termino != "" {
+		patron := "%" + escaparLike(termino) + "%"
+		query = query.Where("pregunta ILIKE ? OR respuesta ILIKE ?", patron, patron)
+	}
+	err := query.Order("categoria, orden, id").Find(&preguntas).Error
+	return preguntas, err
+}
+
+// escaparLike hace que %, _ y \ del término se busquen literalmente en un LIKE o ILIKE
+func escaparLike(termino string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(termino)
+}
+
+// GetCategoriasPublicadas obtiene las categorías que tienen entradas publicadas
+func (r *PreguntaFrecuenteRepository) GetCategoriasPublicadas() ([]string, error) {
+	var categorias []string
+	err := r.db.Model(&models.PreguntaFrecuente{}).
+		Where("publicada = ?", true).
+		Distinct("categoria").Order("categoria").
+		Pluck("categoria", &categorias).Error
+	return categorias, err
+}
+
+// UpdatePreguntaFrecuente actualiza una entrada del FAQ
+func (r *PreguntaFrecuenteRepository) UpdatePreguntaFrecuente(pregunta *models.PreguntaFrecuente) error {
+	return r.db.Save(pregunta).Error
+}
+
+// DeletePreguntaFrecuente elimina una entrada del FAQ. Se borra definitivamente para que la duda pueda volver a promoverse.
+func (r *PreguntaFrecuenteRepository) DeletePreguntaFrecuente(id uint) error {
+	return r.db.Unscoped().Delete(&models.PreguntaFrecuente{}, id).Error
+}
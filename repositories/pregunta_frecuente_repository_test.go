@@ -0,0 +1,23 @@
+package repositories
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGetPreguntasPublicadasBuscaElTerminoLiteral(t *testing.T) {
+	db, consultas := dbSinConexion(t)
+	if _, err := NewPreguntaFrecuenteRepository(db).GetPreguntasPublicadas("", `100%_seguro\`); err != nil {
+		t.Fatal(err)
+	}
+	if len(*consultas) != 1 {
+		t.Fatalf("se esperaba una consulta, hubo %d", len(*consultas))
+	}
+	consulta := (*consultas)[0]
+	if !strings.Contains(consulta.sql, "pregunta ILIKE $2 OR respuesta ILIKE $3") {
+		t.Errorf("consulta inesperada: %s", consulta.sql)
+	}
+	if patron := `%100\%\_seguro\\%`; consulta.vars[1] != patron || consulta.vars[2] != patron {
+		t.Errorf("patrones %v, se esperaba %q", consulta.vars[1:], patron)
+	}
+}
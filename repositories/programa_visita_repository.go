@@ -2,6 +2,7 @@ package repositories
 
 import (
 	"ApiEscuela/models"
+	"context"
 	"time"
 	"gorm.io/gorm"
 )
@@ -14,6 +15,12 @@ func NewProgramaVisitaRepository(db *gorm.DB) *ProgramaVisitaRepository {
 	return &ProgramaVisitaRepository{db: db}
 }
 
+// ConContexto devuelve una copia del repositorio cuyas consultas usan ctx, para que queden dentro de la
+// traza de la petición
+func (r *ProgramaVisitaRepository) ConContexto(ctx context.Context) *ProgramaVisitaRepository {
+	return &ProgramaVisitaRepository{db: r.db.WithContext(ctx)}
+}
+
 // CreateProgramaVisita crea un nuevo programa de visita
 func (r *ProgramaVisitaRepository) CreateProgramaVisita(programa *models.ProgramaVisita) error {
 	return r.db.Create(programa).Error
@@ -2,6 +2,7 @@ package repositories
 
 import (
 	"ApiEscuela/models"
+	"context"
 	"gorm.io/gorm"
 )
 
@@ -13,6 +14,12 @@ func NewProvinciaRepository(db *gorm.DB) *ProvinciaRepository {
 	return &ProvinciaRepository{db: db}
 }
 
+// ConContexto devuelve una copia del repositorio cuyas consultas usan ctx, para que queden dentro de la
+// traza de la petición
+func (r *ProvinciaRepository) ConContexto(ctx context.Context) *ProvinciaRepository {
+	return &ProvinciaRepository{db: r.db.WithContext(ctx)}
+}
+
 // CreateProvincia crea una nueva provincia
 func (r *ProvinciaRepository) CreateProvincia(provincia *models.Provincia) error {
 	return r.db.Create(provincia).Error
@@ -0,0 +1,53 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// SaludRepository hace las consultas de la verificación de disponibilidad
+type SaludRepository struct {
+	db *gorm.DB
+}
+
+func NewSaludRepository(db *gorm.DB) *SaludRepository {
+	return &SaludRepository{db: db}
+}
+
+// Ping comprueba que PostgreSQL responda
+func (r *SaludRepository) Ping(ctx context.Context) error {
+	sqlDB, err := r.db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
+
+// TablasFaltantes devuelve las tablas de los modelos que no existen en el esquema actual, es decir,
+// las que la migración todavía no creó
+func (r *SaludRepository) TablasFaltantes(ctx context.Context, modelos []interface{}) ([]string, error) {
+	var existentes []string
+	if err := r.db.WithContext(ctx).
+		Raw("SELECT table_name FROM information_schema.tables WHERE table_schema = CURRENT_SCHEMA()").
+		Scan(&existentes).Error; err != nil {
+		return nil, err
+	}
+	existe := make(map[string]bool, len(existentes))
+	for _, tabla := range existentes {
+		existe[tabla] = true
+	}
+
+	var faltantes []string
+	for _, modelo := range modelos {
+		stmt := &gorm.Statement{DB: r.db}
+		if err := stmt.Parse(modelo); err != nil {
+			return nil, fmt.Errorf("modelo %T: %w", modelo, err)
+		}
+		if !existe[stmt.Schema.Table] {
+			faltantes = append(faltantes, stmt.Schema.Table)
+		}
+	}
+	return faltantes, nil
+}
@@ -0,0 +1,119 @@
+package repositories
+
+import (
+	"ApiEscuela/models"
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+// ErrSolicitudNoPendiente indica que la solicitud ya fue aprobada o rechazada por otro revisor
+var ErrSolicitudNoPendiente = errors.New("la solicitud ya no está pendiente")
+
+type SolicitudVisitaRepository struct {
+	db *gorm.DB
+}
+
+func NewSolicitudVisitaRepository(db *gorm.DB) *SolicitudVisitaRepository {
+	return &SolicitudVisitaRepository{db: db}
+}
+
+// ConContexto devuelve una copia del repositorio cuyas consultas usan ctx, para que queden dentro de la
+// traza de la petición
+func (r *SolicitudVisitaRepository) ConContexto(ctx context.Context) *SolicitudVisitaRepository {
+	return &SolicitudVisitaRepository{db: r.db.WithContext(ctx)}
+}
+
+// CreateSolicitud crea una solicitud de visita con sus temáticas preferidas
+func (r *SolicitudVisitaRepository) CreateSolicitud(solicitud *models.SolicitudVisita) error {
+	return r.db.Create(solicitud).Error
+}
+
+// GetSolicitudByID obtiene una solicitud por ID
+func (r *SolicitudVisitaRepository) GetSolicitudByID(id uint) (*models.SolicitudVisita, error) {
+	var solicitud models.SolicitudVisita
+	err := r.preloads(r.db).First(&solicitud, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &solicitud, nil
+}
+
+// GetSolicitudByCodigo obtiene una solicitud por su código de seguimiento
+func (r *SolicitudVisitaRepository) GetSolicitudByCodigo(codigo string) (*models.SolicitudVisita, error) {
+	var solicitud models.SolicitudVisita
+	err := r.preloads(r.db).Where("codigo_seguimiento = ?", codigo).First(&solicitud).Error
+	if err != nil {
+		return nil, err
+	}
+	return &solicitud, nil
+}
+
+// GetSolicitudes obtiene las solicitudes, opcionalmente filtradas por estado
+func (r *SolicitudVisitaRepository) GetSolicitudes(estado string) ([]models.SolicitudVisita, error) {
+	var solicitudes []models.SolicitudVisita
+	query := r.preloads(r.db)
+	if estado != "" {
+		query = query.Where("estado = ?", estado)
+	}
+	err := query.Order("created_at DESC").Find(&solicitudes).Error
+	return solicitudes, err
+}
+
+// marcarRevisada cambia el estado de la solicitud solo si sigue pendiente, para que dos revisores
+// simultáneos no la aprueben o rechacen dos veces
+func marcarRevisada(tx *gorm.DB, solicitud *models.SolicitudVisita) error {
+	res := tx.Model(&models.SolicitudVisita{}).
+		Where("id = ? AND estado = ?", solicitud.ID, models.EstadoSolicitudPendiente).
+		Updates(map[string]interface{}{
+			"estado":          solicitud.Estado,
+			"motivo_rechazo":  solicitud.MotivoRechazo,
+			"revisado_por_id": solicitud.RevisadoPorID,
+			"fecha_revision":  solicitud.FechaRevision,
+		})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrSolicitudNoPendiente
+	}
+	return nil
+}
+
+// RechazarSolicitud guarda el rechazo de una solicitud que sigue pendiente
+func (r *SolicitudVisitaRepository) RechazarSolicitud(solicitud *models.SolicitudVisita) error {
+	return marcarRevisada(r.db, solicitud)
+}
+
+// AprobarSolicitud marca la solicitud como aprobada y crea la institución (si es nueva) y el programa
+// de visita, todo en una sola transacción. Falla con ErrSolicitudNoPendiente si otro revisor ya la revisó.
+func (r *SolicitudVisitaRepository) AprobarSolicitud(solicitud *models.SolicitudVisita, institucion *models.Institucion, programa *models.ProgramaVisita) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := marcarRevisada(tx, solicitud); err != nil {
+			return err
+		}
+		if institucion.ID == 0 {
+			if err := tx.Create(institucion).Error; err != nil {
+				return err
+			}
+		}
+		programa.InstitucionID = institucion.ID
+		if err := tx.Create(programa).Error; err != nil {
+			return err
+		}
+		solicitud.InstitucionID = &institucion.ID
+		solicitud.ProgramaVisitaID = &programa.ID
+		return tx.Model(&models.SolicitudVisita{}).Where("id = ?", solicitud.ID).Updates(map[string]interface{}{
+			"institucion_id":     solicitud.InstitucionID,
+			"programa_visita_id": solicitud.ProgramaVisitaID,
+		}).Error
+	})
+}
+
+func (r *SolicitudVisitaRepository) preloads(db *gorm.DB) *gorm.DB {
+	return db.Preload("Institucion").
+		Preload("ProgramaVisita").
+		Preload("Tematicas").
+		Preload("Tematicas.Tematica")
+}
@@ -0,0 +1,33 @@
+package repositories
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"ApiEscuela/models"
+
+	"gorm.io/gorm"
+)
+
+func TestRevisionSoloCambiaSolicitudesPendientes(t *testing.T) {
+	db, _ := dbSinConexion(t)
+	var actualizaciones []string
+	err := db.Callback().Update().After("gorm:update").Register("prueba:sql", func(d *gorm.DB) {
+		actualizaciones = append(actualizaciones, d.Statement.SQL.String())
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// DryRun no afecta filas: equivale a que otro revisor ya cambió el estado
+	solicitud := &models.SolicitudVisita{Estado: models.EstadoSolicitudRechazada, MotivoRechazo: "sin cupo"}
+	solicitud.ID = 5
+	err = NewSolicitudVisitaRepository(db).RechazarSolicitud(solicitud)
+	if !errors.Is(err, ErrSolicitudNoPendiente) {
+		t.Fatalf("error = %v, se esperaba ErrSolicitudNoPendiente", err)
+	}
+	if len(actualizaciones) != 1 || !strings.Contains(actualizaciones[0], "WHERE (id = $") || !strings.Contains(actualizaciones[0], "AND estado = $") {
+		t.Errorf("la actualización no condiciona el estado pendiente: %v", actualizaciones)
+	}
+}
@@ -2,13 +2,26 @@ package repositories
 
 import (
 	"ApiEscuela/models"
+	"context"
 	"errors"
 	"strings"
 	"gorm.io/gorm"
 )
 
 type EstudianteRepository struct {
-	db *gorm.DB
+	db    *gorm.DB
+	visor VisorDudas // Filtra las dudas incluidas; sin ConVisor, solo las públicas
+}
+
+// ConContexto devuelve una copia del repositorio cuyas consultas usan ctx, para que queden dentro de la
+// traza de la petición (incluidas las de cada Preload)
+func (r *EstudianteRepository) ConContexto(ctx context.Context) *EstudianteRepository {
+	return &EstudianteRepository{db: r.db.WithContext(ctx), visor: r.visor}
+}
+
+// ConVisor devuelve una copia del repositorio que incluye las dudas que visor puede ver
+func (r *EstudianteRepository) ConVisor(visor VisorDudas) *EstudianteRepository {
+	return &EstudianteRepository{db: r.db, visor: visor}
 }
 
 var (
@@ -54,7 +67,17 @@ func (r *EstudianteRepository) GetEstudianteByID(id uint) (*models.Estudiante, e
 	var estudiante models.Estudiante
 	err := r.db.Preload("Persona").Preload("Institucion").
 		Preload("Ciudad").Preload("Ciudad.Provincia").
-		Preload("Dudas").First(&estudiante, id).Error
+		Preload("Dudas", r.visor.filtrar).First(&estudiante, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &estudiante, nil
+}
+
+// GetEstudianteByPersona obtiene el estudiante asociado a una persona
+func (r *EstudianteRepository) GetEstudianteByPersona(personaID uint) (*models.Estudiante, error) {
+	var estudiante models.Estudiante
+	err := r.db.Where("persona_id = ?", personaID).First(&estudiante).Error
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,91 @@
+package repositories
+
+import (
+	"ApiEscuela/models"
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrDesplazamientoCambiado indica que otra petición avanzó la subida al mismo tiempo
+var ErrDesplazamientoCambiado = errors.New("el desplazamiento de la subida cambió")
+
+type SubidaRepository struct {
+	db *gorm.DB
+}
+
+func NewSubidaRepository(db *gorm.DB) *SubidaRepository {
+	return &SubidaRepository{db: db}
+}
+
+// ConContexto devuelve una copia del repositorio cuyas consultas usan ctx, para que queden dentro de la
+// traza de la petición
+func (r *SubidaRepository) ConContexto(ctx context.Context) *SubidaRepository {
+	return &SubidaRepository{db: r.db.WithContext(ctx)}
+}
+
+// CreateSubida registra una subida reanudable
+func (r *SubidaRepository) CreateSubida(subida *models.SubidaReanudable) error {
+	return r.db.Create(subida).Error
+}
+
+// GetSubidaByIdentificador obtiene una subida con sus partes ordenadas por desplazamiento
+func (r *SubidaRepository) GetSubidaByIdentificador(identificador string) (*models.SubidaReanudable, error) {
+	var subida models.SubidaReanudable
+	err := r.db.Preload("Partes", func(db *gorm.DB) *gorm.DB {
+		return db.Order("desplazamiento ASC")
+	}).Where("identificador = ?", identificador).First(&subida).Error
+	if err != nil {
+		return nil, err
+	}
+	return &subida, nil
+}
+
+// AgregarParte registra una parte y avanza el desplazamiento de la subida, solo si sigue siendo el
+// esperado. Si otra petición lo cambió primero devuelve ErrDesplazamientoCambiado.
+func (r *SubidaRepository) AgregarParte(subida *models.SubidaReanudable, parte *models.ParteSubida, expiraEn time.Time) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		resultado := tx.Model(&models.SubidaReanudable{}).
+			Where("id = ? AND desplazamiento = ?", subida.ID, parte.Desplazamiento).
+			Updates(map[string]interface{}{
+				"desplazamiento": parte.Desplazamiento + parte.Tamano,
+				"expira_en":      expiraEn,
+			})
+		if resultado.Error != nil {
+			return resultado.Error
+		}
+		if resultado.RowsAffected == 0 {
+			return ErrDesplazamientoCambiado
+		}
+		return tx.Create(parte).Error
+	})
+}
+
+// CompletarSubida asocia el archivo registrado y elimina las partes, que ya no se necesitan
+func (r *SubidaRepository) CompletarSubida(subidaID, archivoID uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("subida_id = ?", subidaID).Delete(&models.ParteSubida{}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.SubidaReanudable{}).Where("id = ?", subidaID).Update("archivo_id", archivoID).Error
+	})
+}
+
+// DeleteSubida elimina definitivamente una subida y sus partes
+func (r *SubidaRepository) DeleteSubida(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("subida_id = ?", id).Delete(&models.ParteSubida{}).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Delete(&models.SubidaReanudable{}, id).Error
+	})
+}
+
+// GetSubidasExpiradas lista las subidas vencidas, completas o no, con sus partes
+func (r *SubidaRepository) GetSubidasExpiradas(ahora time.Time) ([]models.SubidaReanudable, error) {
+	var subidas []models.SubidaReanudable
+	err := r.db.Preload("Partes").Where("expira_en <= ?", ahora).Find(&subidas).Error
+	return subidas, err
+}
@@ -2,6 +2,7 @@ package repositories
 
 import (
 	"ApiEscuela/models"
+	"context"
 	"gorm.io/gorm"
 )
 
@@ -13,6 +14,12 @@ func NewTematicaRepository(db *gorm.DB) *TematicaRepository {
 	return &TematicaRepository{db: db}
 }
 
+// ConContexto devuelve una copia del repositorio cuyas consultas usan ctx, para que queden dentro de la
+// traza de la petición
+func (r *TematicaRepository) ConContexto(ctx context.Context) *TematicaRepository {
+	return &TematicaRepository{db: r.db.WithContext(ctx)}
+}
+
 // CreateTematica crea una nueva temática
 func (r *TematicaRepository) CreateTematica(tematica *models.Tematica) error {
 	return r.db.Create(tematica).Error
@@ -2,6 +2,7 @@ package repositories
 
 import (
 	"ApiEscuela/models"
+	"context"
 	"gorm.io/gorm"
 )
 
@@ -13,6 +14,12 @@ func NewTipoUsuarioRepository(db *gorm.DB) *TipoUsuarioRepository {
 	return &TipoUsuarioRepository{db: db}
 }
 
+// ConContexto devuelve una copia del repositorio cuyas consultas usan ctx, para que queden dentro de la
+// traza de la petición
+func (r *TipoUsuarioRepository) ConContexto(ctx context.Context) *TipoUsuarioRepository {
+	return &TipoUsuarioRepository{db: r.db.WithContext(ctx)}
+}
+
 // CreateTipoUsuario crea un nuevo tipo de usuario
 func (r *TipoUsuarioRepository) CreateTipoUsuario(tipoUsuario *models.TipoUsuario) error {
 	return r.db.Create(tipoUsuario).Error
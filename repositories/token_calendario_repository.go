@@ -0,0 +1,58 @@
+package repositories
+
+import (
+	"ApiEscuela/models"
+	"context"
+
+	"gorm.io/gorm"
+)
+
+type TokenCalendarioRepository struct {
+	db *gorm.DB
+}
+
+func NewTokenCalendarioRepository(db *gorm.DB) *TokenCalendarioRepository {
+	return &TokenCalendarioRepository{db: db}
+}
+
+// ConContexto devuelve una copia del repositorio cuyas consultas usan ctx, para que queden dentro de la
+// traza de la petición
+func (r *TokenCalendarioRepository) ConContexto(ctx context.Context) *TokenCalendarioRepository {
+	return &TokenCalendarioRepository{db: r.db.WithContext(ctx)}
+}
+
+// GetTokenByUsuario obtiene el token de calendario de un usuario
+func (r *TokenCalendarioRepository) GetTokenByUsuario(usuarioID uint) (*models.TokenCalendario, error) {
+	var token models.TokenCalendario
+	err := r.db.Where("usuario_id = ?", usuarioID).First(&token).Error
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// GetTokenByValor obtiene un token de calendario por su valor
+func (r *TokenCalendarioRepository) GetTokenByValor(valor string) (*models.TokenCalendario, error) {
+	var token models.TokenCalendario
+	err := r.db.Where("token = ?", valor).First(&token).Error
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// GuardarToken crea o reemplaza el token de calendario de un usuario
+func (r *TokenCalendarioRepository) GuardarToken(usuarioID uint, valor string) (*models.TokenCalendario, error) {
+	var token models.TokenCalendario
+	err := r.db.Unscoped().Where("usuario_id = ?", usuarioID).First(&token).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+	token.UsuarioID = usuarioID
+	token.Token = valor
+	token.DeletedAt = gorm.DeletedAt{}
+	if err := r.db.Unscoped().Save(&token).Error; err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
@@ -2,6 +2,7 @@ package repositories
 
 import (
 	"ApiEscuela/models"
+	"context"
 	"errors"
 	"strings"
 
@@ -34,6 +35,12 @@ func NewUsuarioRepository(db *gorm.DB) *UsuarioRepository {
 	return &UsuarioRepository{db: db}
 }
 
+// ConContexto devuelve una copia del repositorio cuyas consultas usan ctx, para que queden dentro de la
+// traza de la petición
+func (r *UsuarioRepository) ConContexto(ctx context.Context) *UsuarioRepository {
+	return &UsuarioRepository{db: r.db.WithContext(ctx)}
+}
+
 // CreateUsuario crea un nuevo usuario
 func (r *UsuarioRepository) CreateUsuario(usuario *models.Usuario) error {
 	if err := r.db.Create(usuario).Error; err != nil {
@@ -2,6 +2,9 @@ package repositories
 
 import (
 	"ApiEscuela/models"
+	"context"
+	"time"
+
 	"gorm.io/gorm"
 )
 
@@ -13,6 +16,12 @@ func NewVisitaDetalleEstudiantesUniversitariosRepository(db *gorm.DB) *VisitaDet
 	return &VisitaDetalleEstudiantesUniversitariosRepository{db: db}
 }
 
+// ConContexto devuelve una copia del repositorio cuyas consultas usan ctx, para que queden dentro de la
+// traza de la petición
+func (r *VisitaDetalleEstudiantesUniversitariosRepository) ConContexto(ctx context.Context) *VisitaDetalleEstudiantesUniversitariosRepository {
+	return &VisitaDetalleEstudiantesUniversitariosRepository{db: r.db.WithContext(ctx)}
+}
+
 // CreateVisitaDetalleEstudiantesUniversitarios crea una nueva relación entre estudiante universitario y programa de visita
 func (r *VisitaDetalleEstudiantesUniversitariosRepository) CreateVisitaDetalleEstudiantesUniversitarios(relacion *models.VisitaDetalleEstudiantesUniversitarios) error {
 	return r.db.Create(relacion).Error
@@ -127,4 +136,53 @@ func (r *VisitaDetalleEstudiantesUniversitariosRepository) GetEstadisticasPartic
 		"total_programas_con_estudiantes":   totalProgramasUnicos,
 		"promedio_estudiantes_por_programa": promedioEstudiantesPorPrograma,
 	}, nil
-}
\ No newline at end of file
+}
+
+// GetRelacionesCalendarioByEstudiante obtiene las asignaciones de un estudiante para su calendario,
+// incluyendo las eliminadas después de eliminadosDesde para poder publicarlas como canceladas
+func (r *VisitaDetalleEstudiantesUniversitariosRepository) GetRelacionesCalendarioByEstudiante(estudianteID uint, eliminadosDesde time.Time) ([]models.VisitaDetalleEstudiantesUniversitarios, error) {
+	var relaciones []models.VisitaDetalleEstudiantesUniversitarios
+	err := r.db.Unscoped().
+		Where("estudiante_universitario_id = ?", estudianteID).
+		Where("deleted_at IS NULL OR deleted_at > ?", eliminadosDesde).
+		Preload("ProgramaVisita", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).
+		Preload("ProgramaVisita.Institucion").
+		Find(&relaciones).Error
+	return relaciones, err
+}
+
+// GetRelacionByIDIncludingDeleted obtiene una relación por ID incluyendo las eliminadas
+func (r *VisitaDetalleEstudiantesUniversitariosRepository) GetRelacionByIDIncludingDeleted(id uint) (*models.VisitaDetalleEstudiantesUniversitarios, error) {
+	var relacion models.VisitaDetalleEstudiantesUniversitarios
+	err := r.db.Unscoped().
+		Preload("ProgramaVisita", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).
+		Preload("ProgramaVisita.Institucion").
+		Preload("EstudianteUniversitario").
+		Preload("EstudianteUniversitario.Persona").
+		First(&relacion, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &relacion, nil
+}
+
+// GetRelacionesParaNotificar obtiene las asignaciones vigentes de un programa y, si el programa fue
+// eliminado, las que se eliminaron junto con él (mismo deleted_at)
+func (r *VisitaDetalleEstudiantesUniversitariosRepository) GetRelacionesParaNotificar(programaVisitaID uint) ([]models.VisitaDetalleEstudiantesUniversitarios, error) {
+	var relaciones []models.VisitaDetalleEstudiantesUniversitarios
+	eliminacionPrograma := r.db.Unscoped().Model(&models.ProgramaVisita{}).Select("deleted_at").Where("id = ?", programaVisitaID)
+	err := r.db.Unscoped().
+		Where("programa_visita_id = ?", programaVisitaID).
+		Where("deleted_at IS NULL OR deleted_at = (?)", eliminacionPrograma).
+		Find(&relaciones).Error
+	return relaciones, err
+}
+
+// GetRelacionesEnRango obtiene las asignaciones de estudiantes cuyos programas inician entre desde y hasta
+func (r *VisitaDetalleEstudiantesUniversitariosRepository) GetRelacionesEnRango(desde, hasta time.Time) ([]models.VisitaDetalleEstudiantesUniversitarios, error) {
+	var relaciones []models.VisitaDetalleEstudiantesUniversitarios
+	programas := r.db.Model(&models.ProgramaVisita{}).Select("id").Where("fecha BETWEEN ? AND ?", desde, hasta)
+	err := r.db.Where("programa_visita_id IN (?)", programas).
+		Preload("ProgramaVisita").Find(&relaciones).Error
+	return relaciones, err
+}
@@ -2,6 +2,7 @@ package repositories
 
 import (
 	"ApiEscuela/models"
+	"context"
 	"gorm.io/gorm"
 )
 
@@ -13,6 +14,12 @@ func NewVisitaDetalleRepository(db *gorm.DB) *VisitaDetalleRepository {
 	return &VisitaDetalleRepository{db: db}
 }
 
+// ConContexto devuelve una copia del repositorio cuyas consultas usan ctx, para que queden dentro de la
+// traza de la petición
+func (r *VisitaDetalleRepository) ConContexto(ctx context.Context) *VisitaDetalleRepository {
+	return &VisitaDetalleRepository{db: r.db.WithContext(ctx)}
+}
+
 // CreateVisitaDetalle crea un nuevo detalle de visita
 func (r *VisitaDetalleRepository) CreateVisitaDetalle(detalle *models.VisitaDetalle) error {
 	return r.db.Create(detalle).Error
@@ -3,13 +3,21 @@ package routers
 import (
 	"ApiEscuela/handlers"
 	"ApiEscuela/middleware"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/etag"
+	"github.com/gofiber/fiber/v2/middleware/limiter"
 )
 
 // SetupAllRoutes configura todas las rutas de la aplicación
 func SetupAllRoutes(app *fiber.App, handlers *AllHandlers) {
 	// ==================== RUTAS PÚBLICAS (SIN AUTENTICACIÓN) ====================
+	// Sondas de vida y disponibilidad; /health se mantiene como alias de la de disponibilidad
+	app.Get("/health", handlers.SaludHandler.Listo)
+	app.Get("/health/live", handlers.SaludHandler.Vivo)
+	app.Get("/health/ready", handlers.SaludHandler.Listo)
+
 	// Rutas de autenticación
 	auth := app.Group("/auth")
 	auth.Post("/login", handlers.AuthHandler.Login)
@@ -20,8 +28,44 @@ func SetupAllRoutes(app *fiber.App, handlers *AllHandlers) {
 	auth.Post("/reset-password", handlers.AuthHandler.ResetPassword)
 
 	// ==================== SERVIR ARCHIVOS ESTÁTICOS (PÚBLICO) ====================
+	// Los archivos privados solo se sirven con un enlace firmado y temporal (?expira=&firma=)
+	app.Get("/api/files/firmados/:id", handlers.UploadHandler.GetFileFirmado)
 	app.Get("/api/files/:tipo/:nombre", handlers.UploadHandler.GetFile)
 
+	// ==================== FEEDS DE CALENDARIO (PÚBLICO, PROTEGIDO POR TOKEN) ====================
+	app.Get("/calendario/:token/visitas.ics", handlers.CalendarioHandler.GetFeed)
+
+	// ==================== SOLICITUDES DE VISITA (FORMULARIO PÚBLICO) ====================
+	solicitudesPublicas := app.Group("/solicitudes-visita")
+	solicitudesPublicas.Post("/", limiter.New(limiter.Config{
+		Max:        5,
+		Expiration: time.Hour,
+		LimitReached: func(c *fiber.Ctx) error {
+			return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+				"error": "Demasiadas solicitudes enviadas. Intente más tarde",
+			})
+		},
+	}), handlers.SolicitudVisitaHandler.CreateSolicitud)
+	solicitudesPublicas.Get("/tematicas", handlers.SolicitudVisitaHandler.GetTematicasPublicas)
+	solicitudesPublicas.Get("/:codigo", handlers.SolicitudVisitaHandler.GetEstadoSolicitud)
+
+	// ==================== PREGUNTAS FRECUENTES (PÚBLICO) ====================
+	faqPublico := app.Group("/faq", etag.New())
+	faqPublico.Get("/", handlers.FAQHandler.GetFAQPublico)
+	faqPublico.Get("/categorias", handlers.FAQHandler.GetCategoriasPublicas)
+	faqPublico.Get("/sugerencias", handlers.FAQHandler.GetSugerencias)
+
+	// ==================== NOTICIAS (PÚBLICO, SOLO LECTURA) ====================
+	noticiasPublicas := app.Group("/noticias", etag.New())
+	noticiasPublicas.Get("/", handlers.NoticiaHandler.GetNoticiasPublicas)
+	noticiasPublicas.Get("/rss.xml", handlers.NoticiaHandler.GetFeedRSS)
+	noticiasPublicas.Get("/atom.xml", handlers.NoticiaHandler.GetFeedAtom)
+	noticiasPublicas.Get("/:slug", handlers.NoticiaHandler.GetNoticiaPublica)
+
+	// ==================== ENCUESTAS (ENLACE PÚBLICO CON TOKEN) ====================
+	app.Get("/encuestas/:token", handlers.EncuestaHandler.GetEncuestaPublica)
+	app.Post("/encuestas/:token", handlers.EncuestaHandler.ResponderEncuesta)
+
 	// ==================== RUTAS PROTEGIDAS (CON AUTENTICACIÓN JWT) ====================
 	// Aplicar middleware JWT a todas las rutas protegidas
 	protected := app.Group("/api", middleware.JWTMiddleware())
@@ -39,6 +83,15 @@ func SetupAllRoutes(app *fiber.App, handlers *AllHandlers) {
 		})
 	})
 
+	// Subidas reanudables por partes (protocolo tus) para archivos grandes
+	tus := upload.Group("/tus", handlers.SubidaHandler.VersionTus)
+	tus.Options("/", handlers.SubidaHandler.Opciones)
+	tus.Post("/", handlers.SubidaHandler.CrearSubida)
+	tus.Head("/:id", handlers.SubidaHandler.EstadoSubida)
+	tus.Get("/:id", handlers.SubidaHandler.GetSubida)
+	tus.Patch("/:id", handlers.SubidaHandler.AgregarParte)
+	tus.Delete("/:id", handlers.SubidaHandler.CancelarSubida)
+
 	// Rutas de autenticación protegidas
 	authProtected := protected.Group("/auth")
 	authProtected.Get("/profile", handlers.AuthHandler.GetProfile)
@@ -51,8 +104,8 @@ func SetupAllRoutes(app *fiber.App, handlers *AllHandlers) {
 	estudiantes.Get("/", handlers.EstudianteHandler.GetAllEstudiantes)
 	estudiantes.Get("/all-including-deleted", handlers.EstudianteHandler.GetAllEstudiantesIncludingDeleted)
 	estudiantes.Get("/deleted", handlers.EstudianteHandler.GetDeletedEstudiantes)
-	estudiantes.Get("/:id", handlers.EstudianteHandler.GetEstudiante)
-	estudiantes.Put("/:id", handlers.EstudianteHandler.UpdateEstudiante)
+	estudiantes.Get("/:id", handlers.DudasHandler.IdentificarVisor, handlers.EstudianteHandler.GetEstudiante)
+	estudiantes.Put("/:id", handlers.DudasHandler.IdentificarVisor, handlers.EstudianteHandler.UpdateEstudiante)
 	estudiantes.Delete("/:id", handlers.EstudianteHandler.DeleteEstudiante)
 	estudiantes.Put("/:id/restore", handlers.EstudianteHandler.RestoreEstudiante)
 	estudiantes.Get("/ciudad/:ciudad_id", handlers.EstudianteHandler.GetEstudiantesByCity)
@@ -134,15 +187,15 @@ func SetupAllRoutes(app *fiber.App, handlers *AllHandlers) {
 	// ==================== AUTORIDADES UTEQ ====================
 	autoridades := protected.Group("/autoridades-uteq")
 	autoridades.Post("/", handlers.AutoridadHandler.CreateAutoridadUTEQ)
-	autoridades.Get("/", handlers.AutoridadHandler.GetAllAutoridadesUTEQ)
-	autoridades.Get("/all-including-deleted", handlers.AutoridadHandler.GetAllAutoridadesUTEQIncludingDeleted)
-	autoridades.Get("/deleted", handlers.AutoridadHandler.GetDeletedAutoridadesUTEQ)
-	autoridades.Get("/:id", handlers.AutoridadHandler.GetAutoridadUTEQ)
-	autoridades.Put("/:id", handlers.AutoridadHandler.UpdateAutoridadUTEQ)
+	autoridades.Get("/", handlers.DudasHandler.IdentificarVisor, handlers.AutoridadHandler.GetAllAutoridadesUTEQ)
+	autoridades.Get("/all-including-deleted", handlers.DudasHandler.IdentificarVisor, handlers.AutoridadHandler.GetAllAutoridadesUTEQIncludingDeleted)
+	autoridades.Get("/deleted", handlers.DudasHandler.IdentificarVisor, handlers.AutoridadHandler.GetDeletedAutoridadesUTEQ)
+	autoridades.Get("/:id", handlers.DudasHandler.IdentificarVisor, handlers.AutoridadHandler.GetAutoridadUTEQ)
+	autoridades.Put("/:id", handlers.DudasHandler.IdentificarVisor, handlers.AutoridadHandler.UpdateAutoridadUTEQ)
 	autoridades.Delete("/:id", handlers.AutoridadHandler.DeleteAutoridadUTEQ)
 	autoridades.Put("/:id/restore", handlers.AutoridadHandler.RestoreAutoridadUTEQ)
-	autoridades.Get("/cargo/:cargo", handlers.AutoridadHandler.GetAutoridadesUTEQByCargo)
-	autoridades.Get("/persona/:persona_id", handlers.AutoridadHandler.GetAutoridadUTEQByPersona)
+	autoridades.Get("/cargo/:cargo", handlers.DudasHandler.IdentificarVisor, handlers.AutoridadHandler.GetAutoridadesUTEQByCargo)
+	autoridades.Get("/persona/:persona_id", handlers.DudasHandler.IdentificarVisor, handlers.AutoridadHandler.GetAutoridadUTEQByPersona)
 
 	// ==================== TEMÁTICAS ====================
 	tematicas := protected.Group("/tematicas")
@@ -175,6 +228,75 @@ func SetupAllRoutes(app *fiber.App, handlers *AllHandlers) {
 	programas.Get("/fecha/:fecha", handlers.ProgramaVisitaHandler.GetProgramasVisitaByFecha) // YYYY-MM-DD
 	programas.Get("/institucion/:institucion_id", handlers.ProgramaVisitaHandler.GetProgramasVisitaByInstitucion)
 	programas.Get("/rango-fecha", handlers.ProgramaVisitaHandler.GetProgramasVisitaByRangoFecha) // ?inicio=2024-01-01&fin=2024-12-31
+	programas.Get("/:id/sugerencias", handlers.DisponibilidadHandler.GetSugerencias) // ?tipo=autoridad|estudiante&limite=10
+	programas.Post("/:id/auto-asignar", handlers.DisponibilidadHandler.AutoAsignar)
+	programas.Post("/:id/completar", handlers.EncuestaHandler.CompletarVisita)
+
+	// ==================== BÚSQUEDA ====================
+	protected.Get("/buscar", handlers.BusquedaHandler.Buscar)
+
+	// ==================== ARCHIVOS ====================
+	archivos := protected.Group("/archivos")
+	archivos.Get("/", handlers.ArchivoHandler.GetArchivos) // ?usuario_id=&carpeta=
+	// Archivos sin referencias (solo administradores); antes de /:id para que no se tomen como ID
+	archivos.Get("/huerfanos", handlers.HuerfanosHandler.GetReporte)
+	archivos.Get("/cuarentena", handlers.HuerfanosHandler.GetCuarentena)
+	archivos.Post("/cuarentena/:id/restaurar", handlers.HuerfanosHandler.RestaurarCuarentena)
+	archivos.Get("/:id", handlers.ArchivoHandler.GetArchivo)
+	archivos.Put("/:id", handlers.ArchivoHandler.UpdateArchivo)
+	archivos.Delete("/:id", handlers.ArchivoHandler.DeleteArchivo)
+	archivos.Get("/:id/url", handlers.ArchivoHandler.GetURLFirmada) // ?minutos=15
+	archivos.Get("/:id/permisos", handlers.ArchivoHandler.GetPermisos)
+	archivos.Post("/:id/permisos", handlers.ArchivoHandler.CreatePermiso)
+	archivos.Delete("/:id/permisos/:permiso_id", handlers.ArchivoHandler.DeletePermiso)
+
+	// ==================== PREGUNTAS FRECUENTES (GESTIÓN) ====================
+	faq := protected.Group("/faq")
+	faq.Get("/", handlers.FAQHandler.GetAllPreguntasFrecuentes)
+	faq.Post("/", handlers.FAQHandler.CreatePreguntaFrecuente)
+	faq.Post("/promover/:duda_id", handlers.FAQHandler.PromoverDuda)
+	faq.Get("/:id", handlers.FAQHandler.GetPreguntaFrecuente)
+	faq.Put("/:id", handlers.FAQHandler.UpdatePreguntaFrecuente)
+	faq.Delete("/:id", handlers.FAQHandler.DeletePreguntaFrecuente)
+
+	// ==================== ENCUESTAS DE SATISFACCIÓN ====================
+	encuestas := protected.Group("/encuestas")
+	encuestas.Post("/", handlers.EncuestaHandler.CreateEncuesta)
+	encuestas.Get("/", handlers.EncuestaHandler.GetAllEncuestas)
+	encuestas.Get("/resultados/actividades", handlers.EncuestaHandler.GetResultadosPorActividad)
+	encuestas.Get("/resultados/tematicas", handlers.EncuestaHandler.GetResultadosPorTematica)
+	encuestas.Get("/:id", handlers.EncuestaHandler.GetEncuesta)
+	encuestas.Put("/:id", handlers.EncuestaHandler.UpdateEncuesta)
+	encuestas.Delete("/:id", handlers.EncuestaHandler.DeleteEncuesta)
+	encuestas.Get("/:id/resultados", handlers.EncuestaHandler.GetResultados)
+
+	// ==================== SOLICITUDES DE VISITA (REVISIÓN) ====================
+	solicitudes := protected.Group("/solicitudes-visita")
+	solicitudes.Get("/", handlers.SolicitudVisitaHandler.GetSolicitudes) // ?estado=pendiente|aprobada|rechazada
+	solicitudes.Get("/:id", handlers.SolicitudVisitaHandler.GetSolicitud)
+	solicitudes.Post("/:id/aprobar", handlers.SolicitudVisitaHandler.AprobarSolicitud)
+	solicitudes.Post("/:id/rechazar", handlers.SolicitudVisitaHandler.RechazarSolicitud)
+
+	// ==================== DISPONIBILIDAD DEL PERSONAL ====================
+	disponibilidades := protected.Group("/disponibilidades")
+	disponibilidades.Post("/", handlers.DisponibilidadHandler.CreateDisponibilidad)
+	disponibilidades.Put("/:id", handlers.DisponibilidadHandler.UpdateDisponibilidad)
+	disponibilidades.Delete("/:id", handlers.DisponibilidadHandler.DeleteDisponibilidad)
+	disponibilidades.Get("/autoridad/:autoridad_id", handlers.DisponibilidadHandler.GetDisponibilidadesByAutoridad)
+	disponibilidades.Get("/estudiante/:estudiante_id", handlers.DisponibilidadHandler.GetDisponibilidadesByEstudiante)
+
+	// ==================== PLANTILLAS DE VISITA (RECURRENTES) ====================
+	plantillas := protected.Group("/plantillas-visita")
+	plantillas.Post("/", handlers.PlantillaProgramaVisitaHandler.CreatePlantilla)
+	plantillas.Get("/", handlers.PlantillaProgramaVisitaHandler.GetAllPlantillas)
+	plantillas.Get("/institucion/:institucion_id", handlers.PlantillaProgramaVisitaHandler.GetPlantillasByInstitucion)
+	plantillas.Get("/:id", handlers.PlantillaProgramaVisitaHandler.GetPlantilla)
+	plantillas.Delete("/:id", handlers.PlantillaProgramaVisitaHandler.DeletePlantilla)
+	plantillas.Get("/:id/ocurrencias", handlers.PlantillaProgramaVisitaHandler.GetOcurrencias) // ?hasta=2025-12-31
+	plantillas.Get("/:id/programas", handlers.PlantillaProgramaVisitaHandler.GetProgramasPlantilla)
+	plantillas.Post("/:id/materializar", handlers.PlantillaProgramaVisitaHandler.MaterializarPlantilla) // ?hasta=2025-12-31
+	plantillas.Put("/:id/ocurrencias/:programa_id", handlers.PlantillaProgramaVisitaHandler.UpdateOcurrencia)    // ?alcance=esta|futuras|todas
+	plantillas.Delete("/:id/ocurrencias/:programa_id", handlers.PlantillaProgramaVisitaHandler.DeleteOcurrencia) // ?alcance=esta|futuras
 
 	// ==================== DETALLE AUTORIDAD DETALLES VISITA ====================
 	detalleAutoridad := protected.Group("/detalle-autoridad-detalles-visita")
@@ -204,8 +326,19 @@ func SetupAllRoutes(app *fiber.App, handlers *AllHandlers) {
 
 	// ==================== DUDAS ====================
 	dudas := protected.Group("/dudas")
+	dudas.Use(handlers.DudasHandler.IdentificarVisor)
 	dudas.Post("/", handlers.DudasHandler.CreateDudas)
 	dudas.Get("/", handlers.DudasHandler.GetAllDudas)
+	dudas.Get("/no-leidas", handlers.DudasHandler.GetNoLeidas)
+	dudas.Get("/reglas", handlers.SLADudasHandler.GetAllReglas)
+	dudas.Post("/reglas", handlers.SLADudasHandler.CreateRegla)
+	dudas.Get("/reglas/:id", handlers.SLADudasHandler.GetRegla)
+	dudas.Put("/reglas/:id", handlers.SLADudasHandler.UpdateRegla)
+	dudas.Delete("/reglas/:id", handlers.SLADudasHandler.DeleteRegla)
+	dudas.Get("/sla/configuracion", handlers.SLADudasHandler.GetConfiguracion)
+	dudas.Put("/sla/configuracion", handlers.SLADudasHandler.UpdateConfiguracion)
+	dudas.Get("/sla/metricas", handlers.SLADudasHandler.GetMetricas)
+	dudas.Post("/sla/revisar", handlers.SLADudasHandler.RevisarPlazos)
 	dudas.Get("/:id", handlers.DudasHandler.GetDudas)
 	dudas.Put("/:id", handlers.DudasHandler.UpdateDudas)
 	dudas.Delete("/:id", handlers.DudasHandler.DeleteDudas)
@@ -217,6 +350,12 @@ func SetupAllRoutes(app *fiber.App, handlers *AllHandlers) {
 	dudas.Get("/privacidad/:privacidad", handlers.DudasHandler.GetDudasByPrivacidad)
 	dudas.Get("/buscar/:termino", handlers.DudasHandler.BuscarDudasPorPregunta)
 	dudas.Put("/:duda_id/responder", handlers.DudasHandler.ResponderDuda)
+	dudas.Get("/:id/mensajes", handlers.DudasHandler.GetHilo)
+	dudas.Post("/:id/mensajes", handlers.DudasHandler.CreateMensaje)
+	dudas.Put("/:id/mensajes/:mensaje_id", handlers.DudasHandler.UpdateMensaje)
+	dudas.Get("/:id/mensajes/:mensaje_id/historial", handlers.DudasHandler.GetHistorialMensaje)
+	dudas.Post("/:id/leida", handlers.DudasHandler.MarcarLeida)
+	dudas.Delete("/:id/leida", handlers.DudasHandler.MarcarNoLeida)
 
 	// ==================== VISITA DETALLE ESTUDIANTES UNIVERSITARIOS ====================
 	visitaDetalleEstudiantes := protected.Group("/visita-detalle-estudiantes-universitarios")
@@ -235,6 +374,13 @@ func SetupAllRoutes(app *fiber.App, handlers *AllHandlers) {
 	noticias := protected.Group("/noticias")
 	noticias.Post("/", handlers.NoticiaHandler.CreateNoticia)
 	noticias.Get("/", handlers.NoticiaHandler.GetAllNoticias)
+	noticias.Get("/editorial", handlers.NoticiaHandler.GetNoticiasEditorial)
+	noticias.Get("/categorias", handlers.NoticiaHandler.GetCategorias)
+	noticias.Post("/categorias", handlers.NoticiaHandler.CreateCategoria)
+	noticias.Put("/categorias/:id", handlers.NoticiaHandler.UpdateCategoria)
+	noticias.Delete("/categorias/:id", handlers.NoticiaHandler.DeleteCategoria)
+	noticias.Get("/etiquetas", handlers.NoticiaHandler.GetEtiquetas)
+	noticias.Get("/slug/:slug", handlers.NoticiaHandler.GetNoticiaBySlug)
 	noticias.Get("/:id", handlers.NoticiaHandler.GetNoticia)
 	noticias.Put("/:id", handlers.NoticiaHandler.UpdateNoticia)
 	noticias.Delete("/:id", handlers.NoticiaHandler.DeleteNoticia)
@@ -242,6 +388,13 @@ func SetupAllRoutes(app *fiber.App, handlers *AllHandlers) {
 	noticias.Get("/titulo/:titulo", handlers.NoticiaHandler.GetNoticiasByTitulo)
 	noticias.Get("/descripcion/:descripcion", handlers.NoticiaHandler.GetNoticiasByDescripcion)
 	noticias.Get("/buscar/:termino", handlers.NoticiaHandler.SearchNoticias)
+	noticias.Post("/:id/enviar-revision", handlers.NoticiaHandler.EnviarARevision)
+	noticias.Post("/:id/rechazar", handlers.NoticiaHandler.RechazarNoticia)
+	noticias.Post("/:id/publicar", handlers.NoticiaHandler.PublicarNoticia)
+	noticias.Post("/:id/archivar", handlers.NoticiaHandler.ArchivarNoticia)
+	noticias.Get("/:id/revisiones", handlers.NoticiaHandler.GetRevisiones)
+	noticias.Get("/:id/revisiones/:version/diff", handlers.NoticiaHandler.GetDiffRevision)
+	noticias.Post("/:id/revisiones/:version/restaurar", handlers.NoticiaHandler.RestaurarRevision)
 
 	// ==================== CÓDIGOS ====================
 	codigos := protected.Group("/codigos")
@@ -253,6 +406,11 @@ func SetupAllRoutes(app *fiber.App, handlers *AllHandlers) {
 	codigos.Put("/:id/verificar", handlers.CodigoHandler.MarcarComoVerificado)
 	codigos.Put("/:id/expirado", handlers.CodigoHandler.MarcarComoExpirado)
 
+	// ==================== CALENDARIO ====================
+	calendario := protected.Group("/calendario")
+	calendario.Get("/token", handlers.CalendarioHandler.GetToken)
+	calendario.Post("/token/regenerar", handlers.CalendarioHandler.RegenerarToken)
+
 }
 
 // AllHandlers contiene todos los handlers de la aplicación
@@ -269,6 +427,7 @@ type AllHandlers struct {
 	TematicaHandler                               *handlers.TematicaHandler
 	ActividadHandler                              *handlers.ActividadHandler
 	ProgramaVisitaHandler                         *handlers.ProgramaVisitaHandler
+	PlantillaProgramaVisitaHandler                *handlers.PlantillaProgramaVisitaHandler
 	DetalleAutoridadDetallesVisitaHandler         *handlers.DetalleAutoridadDetallesVisitaHandler
 	VisitaDetalleHandler                          *handlers.VisitaDetalleHandler
 	DudasHandler                                  *handlers.DudasHandler
@@ -277,6 +436,17 @@ type AllHandlers struct {
 	UploadHandler                                 *handlers.UploadHandler
 	AuthHandler                                   *handlers.AuthHandler
 	CodigoHandler                                 *handlers.CodigoHandler
+	CalendarioHandler                             *handlers.CalendarioHandler
+	DisponibilidadHandler                         *handlers.DisponibilidadHandler
+	SolicitudVisitaHandler                        *handlers.SolicitudVisitaHandler
+	EncuestaHandler                               *handlers.EncuestaHandler
+	SLADudasHandler                               *handlers.SLADudasHandler
+	FAQHandler                                    *handlers.FAQHandler
+	BusquedaHandler                               *handlers.BusquedaHandler
+	ArchivoHandler                                *handlers.ArchivoHandler
+	SubidaHandler                                 *handlers.SubidaHandler
+	HuerfanosHandler                              *handlers.HuerfanosHandler
+	SaludHandler                                  *handlers.SaludHandler
 }
 
 // NewAllHandlers crea una instancia con todos los handlers
@@ -293,6 +463,7 @@ func NewAllHandlers(
 	tematicaHandler *handlers.TematicaHandler,
 	actividadHandler *handlers.ActividadHandler,
 	programaVisitaHandler *handlers.ProgramaVisitaHandler,
+	plantillaProgramaVisitaHandler *handlers.PlantillaProgramaVisitaHandler,
 	detalleAutoridadDetallesVisitaHandler *handlers.DetalleAutoridadDetallesVisitaHandler,
 	visitaDetalleHandler *handlers.VisitaDetalleHandler,
 	dudasHandler *handlers.DudasHandler,
@@ -301,6 +472,17 @@ func NewAllHandlers(
 	uploadHandler *handlers.UploadHandler,
 	authHandler *handlers.AuthHandler,
 	codigoHandler *handlers.CodigoHandler,
+	calendarioHandler *handlers.CalendarioHandler,
+	disponibilidadHandler *handlers.DisponibilidadHandler,
+	solicitudVisitaHandler *handlers.SolicitudVisitaHandler,
+	encuestaHandler *handlers.EncuestaHandler,
+	slaDudasHandler *handlers.SLADudasHandler,
+	faqHandler *handlers.FAQHandler,
+	busquedaHandler *handlers.BusquedaHandler,
+	archivoHandler *handlers.ArchivoHandler,
+	subidaHandler *handlers.SubidaHandler,
+	huerfanosHandler *handlers.HuerfanosHandler,
+	saludHandler *handlers.SaludHandler,
 ) *AllHandlers {
 	return &AllHandlers{
 		EstudianteHandler:                     estudianteHandler,
@@ -315,6 +497,7 @@ func NewAllHandlers(
 		TematicaHandler:                       tematicaHandler,
 		ActividadHandler:                      actividadHandler,
 		ProgramaVisitaHandler:                 programaVisitaHandler,
+		PlantillaProgramaVisitaHandler:        plantillaProgramaVisitaHandler,
 		DetalleAutoridadDetallesVisitaHandler: detalleAutoridadDetallesVisitaHandler,
 		VisitaDetalleHandler:                  visitaDetalleHandler,
 		DudasHandler:                          dudasHandler,
@@ -323,5 +506,16 @@ func NewAllHandlers(
 		UploadHandler:  uploadHandler,
 		AuthHandler:    authHandler,
 		CodigoHandler:  codigoHandler,
+		CalendarioHandler: calendarioHandler,
+		DisponibilidadHandler: disponibilidadHandler,
+		SolicitudVisitaHandler: solicitudVisitaHandler,
+		EncuestaHandler: encuestaHandler,
+		SLADudasHandler: slaDudasHandler,
+		FAQHandler:      faqHandler,
+		BusquedaHandler: busquedaHandler,
+		ArchivoHandler:  archivoHandler,
+		SubidaHandler:   subidaHandler,
+		HuerfanosHandler: huerfanosHandler,
+		SaludHandler:     saludHandler,
 	}
 }
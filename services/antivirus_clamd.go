@@ -0,0 +1,119 @@
+package services
+
+import (
+	"ApiEscuela/config"
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+var (
+	ErrArchivoInfectado      = errors.New("el archivo contiene malware")
+	ErrAntivirusNoDisponible = errors.New("no se pudo analizar el archivo con el antivirus")
+)
+
+const (
+	tamanoFragmentoClamd = 32 * 1024
+	timeoutClamdPorDef   = 60 * time.Second
+)
+
+// Antivirus analiza el contenido de un archivo antes de guardarlo
+type Antivirus interface {
+	// Analizar devuelve nil si el contenido está limpio, ErrArchivoInfectado con la firma detectada o
+	// ErrAntivirusNoDisponible si no se pudo completar el análisis
+	Analizar(contenido io.Reader) error
+}
+
+// ClienteClamd habla el protocolo de clamd (comando INSTREAM) por TCP o por socket Unix
+type ClienteClamd struct {
+	red       string
+	direccion string
+	timeout   time.Duration
+}
+
+// NewClienteClamd acepta direcciones "tcp://host:3310", "host:3310" o "unix:/ruta/clamd.sock"
+func NewClienteClamd(direccion string, timeout time.Duration) *ClienteClamd {
+	red := "tcp"
+	if ruta, ok := strings.CutPrefix(direccion, "unix:"); ok {
+		red, direccion = "unix", ruta
+	}
+	direccion = strings.TrimPrefix(direccion, "tcp://")
+	if timeout <= 0 {
+		timeout = timeoutClamdPorDef
+	}
+	return &ClienteClamd{red: red, direccion: direccion, timeout: timeout}
+}
+
+// NewAntivirus crea el cliente de clamd si CLAMD_DIRECCION está definida; si no, devuelve nil y los
+// archivos se guardan sin análisis antivirus
+func NewAntivirus(cfg config.Antivirus) Antivirus {
+	if cfg.Direccion == "" {
+		return nil
+	}
+	return NewClienteClamd(cfg.Direccion, cfg.Timeout)
+}
+
+// Analizar envía el contenido a clamd en fragmentos con INSTREAM y lee el veredicto
+func (c *ClienteClamd) Analizar(contenido io.Reader) error {
+	conexion, err := net.DialTimeout(c.red, c.direccion, c.timeout)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrAntivirusNoDisponible, err)
+	}
+	defer conexion.Close()
+	conexion.SetDeadline(time.Now().Add(c.timeout))
+
+	if err := enviarInstream(conexion, contenido); err != nil {
+		return fmt.Errorf("%w: %v", ErrAntivirusNoDisponible, err)
+	}
+	respuesta, err := bufio.NewReader(conexion).ReadString(0)
+	if err != nil && !(errors.Is(err, io.EOF) && respuesta != "") {
+		return fmt.Errorf("%w: %v", ErrAntivirusNoDisponible, err)
+	}
+	return interpretarRespuestaClamd(strings.TrimRight(respuesta, "\x00\n"))
+}
+
+func enviarInstream(conexion net.Conn, contenido io.Reader) error {
+	if _, err := conexion.Write([]byte("zINSTREAM\x00")); err != nil {
+		return err
+	}
+	fragmento := make([]byte, tamanoFragmentoClamd)
+	longitud := make([]byte, 4)
+	for {
+		n, err := contenido.Read(fragmento)
+		if n > 0 {
+			binary.BigEndian.PutUint32(longitud, uint32(n))
+			if _, errEscritura := conexion.Write(append(longitud, fragmento[:n]...)); errEscritura != nil {
+				return errEscritura
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	// Un fragmento de longitud cero indica el fin del flujo
+	_, err := conexion.Write([]byte{0, 0, 0, 0})
+	return err
+}
+
+// interpretarRespuestaClamd traduce "stream: OK", "stream: <firma> FOUND" o "... ERROR"
+func interpretarRespuestaClamd(respuesta string) error {
+	resultado := strings.TrimSpace(respuesta[strings.LastIndex(respuesta, ":")+1:])
+	switch {
+	case resultado == "OK":
+		return nil
+	case strings.HasSuffix(resultado, " FOUND"):
+		return fmt.Errorf("%w (%s)", ErrArchivoInfectado, strings.TrimSuffix(resultado, " FOUND"))
+	}
+	if respuesta == "" {
+		respuesta = "respuesta vacía"
+	}
+	return fmt.Errorf("%w: %s", ErrAntivirusNoDisponible, respuesta)
+}
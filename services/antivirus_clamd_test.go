@@ -0,0 +1,95 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+const firmaEicar = "EICAR-STANDARD-ANTIVIRUS-TEST-FILE"
+
+// servidorClamd imita clamd: acepta zINSTREAM, lee los fragmentos y responde FOUND si encuentra la firma EICAR
+func servidorClamd(t *testing.T, responder func(contenido []byte) string) string {
+	t.Helper()
+	oyente, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { oyente.Close() })
+
+	go func() {
+		for {
+			conexion, err := oyente.Accept()
+			if err != nil {
+				return
+			}
+			go func(conexion net.Conn) {
+				defer conexion.Close()
+				lector := bufio.NewReader(conexion)
+				comando, err := lector.ReadString(0)
+				if err != nil || comando != "zINSTREAM\x00" {
+					conexion.Write([]byte("UNKNOWN COMMAND\x00"))
+					return
+				}
+				var contenido bytes.Buffer
+				for {
+					var longitud uint32
+					if err := binary.Read(lector, binary.BigEndian, &longitud); err != nil {
+						return
+					}
+					if longitud == 0 {
+						break
+					}
+					if _, err := io.CopyN(&contenido, lector, int64(longitud)); err != nil {
+						return
+					}
+				}
+				conexion.Write([]byte(responder(contenido.Bytes()) + "\x00"))
+			}(conexion)
+		}
+	}()
+	return oyente.Addr().String()
+}
+
+func veredictoEicar(contenido []byte) string {
+	if bytes.Contains(contenido, []byte(firmaEicar)) {
+		return "stream: Eicar-Test-Signature FOUND"
+	}
+	return "stream: OK"
+}
+
+func TestClienteClamdAnalizar(t *testing.T) {
+	direccion := servidorClamd(t, veredictoEicar)
+	cliente := NewClienteClamd("tcp://"+direccion, 5*time.Second)
+
+	if err := cliente.Analizar(strings.NewReader("contenido limpio")); err != nil {
+		t.Errorf("archivo limpio: %v", err)
+	}
+
+	// La firma queda partida entre dos fragmentos de INSTREAM
+	grande := strings.Repeat("a", tamanoFragmentoClamd-10) + firmaEicar
+	err := cliente.Analizar(strings.NewReader(grande))
+	if !errors.Is(err, ErrArchivoInfectado) || !strings.Contains(err.Error(), "Eicar-Test-Signature") {
+		t.Errorf("archivo infectado: %v, se esperaba ErrArchivoInfectado con la firma", err)
+	}
+}
+
+func TestClienteClamdErrores(t *testing.T) {
+	direccion := servidorClamd(t, func([]byte) string { return "INSTREAM size limit exceeded. ERROR" })
+	if err := NewClienteClamd(direccion, 5*time.Second).Analizar(strings.NewReader("x")); !errors.Is(err, ErrAntivirusNoDisponible) {
+		t.Errorf("respuesta de error: %v, se esperaba ErrAntivirusNoDisponible", err)
+	}
+
+	oyente, _ := net.Listen("tcp", "127.0.0.1:0")
+	cerrado := oyente.Addr().String()
+	oyente.Close()
+	if err := NewClienteClamd(cerrado, time.Second).Analizar(strings.NewReader("x")); !errors.Is(err, ErrAntivirusNoDisponible) {
+		t.Errorf("clamd caído: %v, se esperaba ErrAntivirusNoDisponible", err)
+	}
+}
@@ -0,0 +1,436 @@
+package services
+
+import (
+	"ApiEscuela/config"
+	"ApiEscuela/metricas"
+	"ApiEscuela/models"
+	"ApiEscuela/repositories"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// DirectorioArchivos es la carpeta local donde se guardan los archivos subidos si no se configura otra
+const DirectorioArchivos = "assets"
+
+const (
+	duracionURLFirmadaPorDef = 15 * time.Minute
+	maxDuracionURLFirmada    = 24 * time.Hour
+)
+
+var (
+	ErrArchivoNoEncontrado    = errors.New("archivo no encontrado")
+	ErrPermisoNoEncontrado    = errors.New("permiso no encontrado")
+	ErrSinAccesoArchivo       = errors.New("no tiene acceso a este archivo")
+	ErrGestionArchivoDenegada = errors.New("solo el propietario o un administrador puede gestionar el archivo")
+	ErrFirmaArchivoInvalida   = errors.New("el enlace no es válido o expiró")
+	ErrArchivoEsPortada       = errors.New("el archivo es la portada de una noticia y debe seguir siendo público")
+)
+
+type ArchivoService struct {
+	archivoRepo  *repositories.ArchivoRepository
+	usuarioRepo  *repositories.UsuarioRepository
+	storage      Storage
+	antivirus    Antivirus       // nil si no hay antivirus configurado
+	archivos     config.Archivos // Límites de subida y variantes de imagen
+	secretoFirma string          // Firma los enlaces temporales a archivos privados
+}
+
+func NewArchivoService(archivoRepo *repositories.ArchivoRepository, usuarioRepo *repositories.UsuarioRepository, storage Storage, antivirus Antivirus, archivos config.Archivos, secretoFirma string) *ArchivoService {
+	return &ArchivoService{archivoRepo: archivoRepo, usuarioRepo: usuarioRepo, storage: storage, antivirus: antivirus, archivos: archivos, secretoFirma: secretoFirma}
+}
+
+// ConContexto devuelve una copia del servicio cuyos repositorios usan ctx, para que las consultas queden
+// dentro de la traza de la petición
+func (s *ArchivoService) ConContexto(ctx context.Context) *ArchivoService {
+	copia := *s
+	copia.archivoRepo = s.archivoRepo.ConContexto(ctx)
+	copia.usuarioRepo = s.usuarioRepo.ConContexto(ctx)
+	return &copia
+}
+
+// VarianteConfigurada indica si nombre es una de las variantes de imagen configuradas en IMAGENES_VARIANTES
+func (s *ArchivoService) VarianteConfigurada(nombre string) bool {
+	for _, variante := range s.archivos.Variantes {
+		if variante.Nombre == nombre {
+			return true
+		}
+	}
+	return false
+}
+
+// PermisoArchivoRequest concede lectura a un usuario o a un tipo de usuario (solo uno de los dos)
+type PermisoArchivoRequest struct {
+	UsuarioID     *uint `json:"usuario_id"`
+	TipoUsuarioID *uint `json:"tipo_usuario_id"`
+}
+
+// URLFirmada es un enlace temporal a un archivo privado
+type URLFirmada struct {
+	URL      string    `json:"url"`
+	ExpiraEn time.Time `json:"expira_en"`
+}
+
+// GuardarArchivo inspecciona el contenido del archivo subido, lo analiza con el antivirus si está
+// configurado, lo guarda en la carpeta de su categoría calculando su SHA-256 y registra sus metadatos.
+// A las imágenes se les quitan los metadatos y se les generan las variantes configuradas.
+func (s *ArchivoService) GuardarArchivo(usuarioID uint, archivo ArchivoEntrante, visibilidad string) (*models.Archivo, error) {
+	visibilidad, err := validarVisibilidadArchivo(visibilidad)
+	if err != nil {
+		return nil, err
+	}
+	inspeccion, err := InspeccionarArchivo(archivo, s.archivos)
+	if err != nil {
+		return nil, err
+	}
+	if s.antivirus != nil {
+		if err := s.analizar(archivo); err != nil {
+			return nil, err
+		}
+	}
+	carpeta, mime := inspeccion.Carpeta, inspeccion.MIME
+
+	sufijo, err := generarTokenHex(8)
+	if err != nil {
+		return nil, err
+	}
+	nombre := fmt.Sprintf("%d_%s%s", time.Now().Unix(), sufijo, strings.ToLower(filepath.Ext(archivo.Nombre)))
+	clave := ClaveArchivo(carpeta, nombre)
+
+	origen, err := archivo.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer origen.Close()
+	var contenido io.Reader = origen
+	tamano := archivo.Tamano
+
+	// Las imágenes se guardan sin metadatos y con sus versiones reducidas
+	var variantes []string
+	if inspeccion.Categoria == CategoriaImagen {
+		datos, err := io.ReadAll(origen)
+		if err != nil {
+			return nil, err
+		}
+		procesada, err := ProcesarImagen(datos, mime, s.archivos)
+		if err != nil {
+			return nil, err
+		}
+		for _, variante := range procesada.Variantes {
+			claveVariante := ClaveVariante(carpeta, nombre, variante.Sufijo)
+			if err := s.storage.Guardar(claveVariante, bytes.NewReader(variante.Contenido), int64(len(variante.Contenido)), variante.MIME); err != nil {
+				s.eliminarVariantes(carpeta, nombre, variantes)
+				return nil, err
+			}
+			variantes = append(variantes, variante.Sufijo)
+		}
+		contenido, tamano = bytes.NewReader(procesada.Original), int64(len(procesada.Original))
+	}
+
+	hash := sha256.New()
+	if err := s.storage.Guardar(clave, io.TeeReader(contenido, hash), tamano, mime); err != nil {
+		s.eliminarVariantes(carpeta, nombre, variantes)
+		return nil, err
+	}
+
+	registro := &models.Archivo{
+		UsuarioID:        usuarioID,
+		NombreOriginal:   filepath.Base(archivo.Nombre),
+		Carpeta:          carpeta,
+		NombreAlmacenado: nombre,
+		MIME:             mime,
+		Tamano:           tamano,
+		SHA256:           hex.EncodeToString(hash.Sum(nil)),
+		Visibilidad:      visibilidad,
+		Variantes:        strings.Join(variantes, ","),
+	}
+	if err := s.archivoRepo.CreateArchivo(registro); err != nil {
+		s.storage.Eliminar(clave)
+		s.eliminarVariantes(carpeta, nombre, variantes)
+		return nil, err
+	}
+	metricas.RegistrarSubida(inspeccion.Categoria, tamano)
+	return registro, nil
+}
+
+// Listar devuelve los archivos del usuario; los administradores pueden ver todos o filtrar por propietario
+func (s *ArchivoService) Listar(usuarioID uint, propietarioID *uint, carpeta string) ([]models.Archivo, error) {
+	usuario, err := s.usuarioRepo.GetUsuarioByID(usuarioID)
+	if err != nil {
+		return nil, ErrSinAccesoArchivo
+	}
+	if !usuario.TipoUsuario.EsAdministrador() {
+		propietarioID = &usuarioID
+	}
+	return s.archivoRepo.GetArchivos(propietarioID, carpeta)
+}
+
+// Obtener devuelve un archivo si el usuario puede leerlo. Los permisos solo se incluyen para quien lo gestiona.
+func (s *ArchivoService) Obtener(id, usuarioID uint) (*models.Archivo, error) {
+	archivo, usuario, err := s.archivoYUsuario(id, usuarioID)
+	if err != nil {
+		return nil, err
+	}
+	if !puedeLeerArchivo(archivo, usuario) {
+		return nil, ErrArchivoNoEncontrado
+	}
+	if !puedeGestionarArchivo(archivo, usuario) {
+		archivo.Permisos = nil
+	}
+	return archivo, nil
+}
+
+// CambiarVisibilidad cambia un archivo entre público y privado. Las portadas de noticias no pueden
+// hacerse privadas mientras se usen.
+func (s *ArchivoService) CambiarVisibilidad(id, usuarioID uint, visibilidad string) (*models.Archivo, error) {
+	archivo, err := s.archivoGestionable(id, usuarioID)
+	if err != nil {
+		return nil, err
+	}
+	if archivo.Visibilidad, err = validarVisibilidadArchivo(visibilidad); err != nil {
+		return nil, err
+	}
+	if archivo.Visibilidad == models.VisibilidadArchivoPrivado {
+		esPortada, err := s.archivoRepo.EsPortadaDeNoticia(archivo.ID)
+		if err != nil {
+			return nil, err
+		}
+		if esPortada {
+			return nil, ErrArchivoEsPortada
+		}
+	}
+	if err := s.archivoRepo.UpdateArchivo(archivo); err != nil {
+		return nil, err
+	}
+	return archivo, nil
+}
+
+// Eliminar borra el archivo del almacenamiento y su registro
+func (s *ArchivoService) Eliminar(id, usuarioID uint) error {
+	archivo, err := s.archivoGestionable(id, usuarioID)
+	if err != nil {
+		return err
+	}
+	if err := s.storage.Eliminar(ClaveArchivo(archivo.Carpeta, archivo.NombreAlmacenado)); err != nil {
+		return err
+	}
+	if archivo.Variantes != "" {
+		s.eliminarVariantes(archivo.Carpeta, archivo.NombreAlmacenado, strings.Split(archivo.Variantes, ","))
+	}
+	return s.archivoRepo.DeleteArchivo(archivo.ID)
+}
+
+// Permisos lista los permisos de un archivo
+func (s *ArchivoService) Permisos(id, usuarioID uint) ([]models.PermisoArchivo, error) {
+	archivo, err := s.archivoGestionable(id, usuarioID)
+	if err != nil {
+		return nil, err
+	}
+	return archivo.Permisos, nil
+}
+
+// ConcederPermiso da lectura del archivo a un usuario o a un tipo de usuario
+func (s *ArchivoService) ConcederPermiso(id, usuarioID uint, req PermisoArchivoRequest) (*models.PermisoArchivo, error) {
+	archivo, err := s.archivoGestionable(id, usuarioID)
+	if err != nil {
+		return nil, err
+	}
+	if (req.UsuarioID == nil) == (req.TipoUsuarioID == nil) {
+		return nil, errors.New("indique usuario_id o tipo_usuario_id, pero no ambos")
+	}
+	if req.UsuarioID != nil {
+		if _, err := s.usuarioRepo.GetUsuarioByID(*req.UsuarioID); err != nil {
+			return nil, errors.New("el usuario indicado no existe")
+		}
+	}
+	existe, err := s.archivoRepo.ExistePermiso(archivo.ID, req.UsuarioID, req.TipoUsuarioID)
+	if err != nil {
+		return nil, err
+	}
+	if existe {
+		return nil, errors.New("el permiso ya existe")
+	}
+
+	permiso := &models.PermisoArchivo{
+		ArchivoID:     archivo.ID,
+		UsuarioID:     req.UsuarioID,
+		TipoUsuarioID: req.TipoUsuarioID,
+		OtorgadoPorID: usuarioID,
+	}
+	if err := s.archivoRepo.CreatePermiso(permiso); err != nil {
+		return nil, err
+	}
+	return permiso, nil
+}
+
+// RevocarPermiso elimina un permiso del archivo
+func (s *ArchivoService) RevocarPermiso(id, permisoID, usuarioID uint) error {
+	if _, err := s.archivoGestionable(id, usuarioID); err != nil {
+		return err
+	}
+	if err := s.archivoRepo.DeletePermiso(id, permisoID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrPermisoNoEncontrado
+		}
+		return err
+	}
+	return nil
+}
+
+// EnlaceArchivo devuelve la URL para descargar el archivo: la ruta pública si es público o un enlace
+// firmado que vence tras duracion (0 = 15 minutos, máximo 24 horas) si es privado
+func (s *ArchivoService) EnlaceArchivo(id, usuarioID uint, urlBase string, duracion time.Duration) (*URLFirmada, error) {
+	archivo, err := s.Obtener(id, usuarioID)
+	if err != nil {
+		return nil, err
+	}
+	if archivo.Visibilidad == models.VisibilidadArchivoPublico {
+		return &URLFirmada{URL: URLPublicaArchivo(urlBase, archivo)}, nil
+	}
+	return s.FirmarURL(archivo, urlBase, duracion)
+}
+
+// FirmarURL genera un enlace temporal a un archivo privado
+func (s *ArchivoService) FirmarURL(archivo *models.Archivo, urlBase string, duracion time.Duration) (*URLFirmada, error) {
+	if duracion <= 0 {
+		duracion = duracionURLFirmadaPorDef
+	}
+	if duracion > maxDuracionURLFirmada {
+		duracion = maxDuracionURLFirmada
+	}
+	expira := time.Now().Add(duracion).Truncate(time.Second)
+	firma, err := s.firmaArchivo(archivo.ID, expira.Unix())
+	if err != nil {
+		return nil, err
+	}
+	url := fmt.Sprintf("%s/api/files/firmados/%d?expira=%d&firma=%s", strings.TrimRight(urlBase, "/"), archivo.ID, expira.Unix(), firma)
+	return &URLFirmada{URL: url, ExpiraEn: expira}, nil
+}
+
+// ArchivoFirmado valida un enlace firmado y devuelve el archivo al que da acceso
+func (s *ArchivoService) ArchivoFirmado(id uint, expira, firma string) (*models.Archivo, error) {
+	segundos, err := strconv.ParseInt(expira, 10, 64)
+	if err != nil || time.Now().Unix() > segundos {
+		return nil, ErrFirmaArchivoInvalida
+	}
+	esperada, err := s.firmaArchivo(id, segundos)
+	if err != nil || !hmac.Equal([]byte(esperada), []byte(firma)) {
+		return nil, ErrFirmaArchivoInvalida
+	}
+	archivo, err := s.archivoRepo.GetArchivoByID(id)
+	if err != nil {
+		return nil, ErrArchivoNoEncontrado
+	}
+	return archivo, nil
+}
+
+// EsPrivado indica si el archivo guardado en carpeta/nombre está registrado como privado.
+// Los archivos sin registro (subidos antes de existir los metadatos) se consideran públicos.
+func (s *ArchivoService) EsPrivado(carpeta, nombre string) (bool, error) {
+	archivo, err := s.archivoRepo.GetArchivoByUbicacion(carpeta, nombre)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return archivo.Visibilidad == models.VisibilidadArchivoPrivado, nil
+}
+
+// URLPublicaArchivo es la ruta abierta de un archivo público
+func URLPublicaArchivo(urlBase string, archivo *models.Archivo) string {
+	return fmt.Sprintf("%s/api/files/%s/%s", strings.TrimRight(urlBase, "/"), archivo.Carpeta, archivo.NombreAlmacenado)
+}
+
+// eliminarVariantes borra las versiones reducidas de una imagen; los errores se ignoran porque el
+// original ya no se puede servir sin su registro
+func (s *ArchivoService) eliminarVariantes(carpeta, nombre string, sufijos []string) {
+	for _, sufijo := range sufijos {
+		s.storage.Eliminar(ClaveVariante(carpeta, nombre, sufijo))
+	}
+}
+
+func (s *ArchivoService) analizar(archivo ArchivoEntrante) error {
+	contenido, err := archivo.Open()
+	if err != nil {
+		return err
+	}
+	defer contenido.Close()
+	return s.antivirus.Analizar(contenido)
+}
+
+func (s *ArchivoService) archivoYUsuario(id, usuarioID uint) (*models.Archivo, *models.Usuario, error) {
+	usuario, err := s.usuarioRepo.GetUsuarioByID(usuarioID)
+	if err != nil {
+		return nil, nil, ErrSinAccesoArchivo
+	}
+	archivo, err := s.archivoRepo.GetArchivoByID(id)
+	if err != nil {
+		return nil, nil, ErrArchivoNoEncontrado
+	}
+	return archivo, usuario, nil
+}
+
+// archivoGestionable carga el archivo si el usuario es su propietario o administrador. A quien ni
+// siquiera puede leerlo se le responde que no existe.
+func (s *ArchivoService) archivoGestionable(id, usuarioID uint) (*models.Archivo, error) {
+	archivo, usuario, err := s.archivoYUsuario(id, usuarioID)
+	if err != nil {
+		return nil, err
+	}
+	if puedeGestionarArchivo(archivo, usuario) {
+		return archivo, nil
+	}
+	if puedeLeerArchivo(archivo, usuario) {
+		return nil, ErrGestionArchivoDenegada
+	}
+	return nil, ErrArchivoNoEncontrado
+}
+
+func puedeGestionarArchivo(archivo *models.Archivo, usuario *models.Usuario) bool {
+	return archivo.UsuarioID == usuario.ID || usuario.TipoUsuario.EsAdministrador()
+}
+
+func puedeLeerArchivo(archivo *models.Archivo, usuario *models.Usuario) bool {
+	if archivo.Visibilidad == models.VisibilidadArchivoPublico || puedeGestionarArchivo(archivo, usuario) {
+		return true
+	}
+	for _, p := range archivo.Permisos {
+		if (p.UsuarioID != nil && *p.UsuarioID == usuario.ID) ||
+			(p.TipoUsuarioID != nil && *p.TipoUsuarioID == usuario.TipoUsuarioID) {
+			return true
+		}
+	}
+	return false
+}
+
+func validarVisibilidadArchivo(visibilidad string) (string, error) {
+	switch strings.TrimSpace(visibilidad) {
+	case "", models.VisibilidadArchivoPublico:
+		return models.VisibilidadArchivoPublico, nil
+	case models.VisibilidadArchivoPrivado:
+		return models.VisibilidadArchivoPrivado, nil
+	}
+	return "", errors.New("la visibilidad debe ser 'publico' o 'privado'")
+}
+
+// firmaArchivo es el HMAC-SHA256 de id:expira con ARCHIVOS_FIRMA_SECRET (o JWT_SECRET si no está definida)
+func (s *ArchivoService) firmaArchivo(id uint, expira int64) (string, error) {
+	if s.secretoFirma == "" {
+		return "", errors.New("no hay un secreto configurado para firmar enlaces de archivos")
+	}
+	mac := hmac.New(sha256.New, []byte(s.secretoFirma))
+	fmt.Fprintf(mac, "%d:%d", id, expira)
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
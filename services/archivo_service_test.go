@@ -0,0 +1,154 @@
+package services
+
+import (
+	"ApiEscuela/config"
+	"ApiEscuela/models"
+	"ApiEscuela/repositories"
+	"errors"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func servicioArchivosSimulado(t *testing.T, archivo *models.Archivo, secreto string) *ArchivoService {
+	t.Helper()
+	filas := map[string]interface{}{}
+	if archivo != nil {
+		filas["Archivo"] = archivo
+	}
+	db := bdSimulada(t, filas)
+	return NewArchivoService(repositories.NewArchivoRepository(db), repositories.NewUsuarioRepository(db), nil, nil, config.PorDefecto().Archivos, secreto)
+}
+
+func TestEnlaceFirmadoDeArchivo(t *testing.T) {
+	archivo := &models.Archivo{Carpeta: "documents", NombreAlmacenado: "1700000000_ab12.pdf", Visibilidad: models.VisibilidadArchivoPrivado}
+	archivo.ID = 8
+	servicio := servicioArchivosSimulado(t, archivo, "secreto")
+
+	enlace, err := servicio.FirmarURL(archivo, "https://api.uteq.edu.ec/", 10*time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := url.Parse(enlace.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Path != "/api/files/firmados/8" {
+		t.Fatalf("ruta del enlace = %s", u.Path)
+	}
+	expira, firma := u.Query().Get("expira"), u.Query().Get("firma")
+	if obtenido, err := servicio.ArchivoFirmado(8, expira, firma); err != nil || obtenido.ID != 8 {
+		t.Fatalf("el enlace recién firmado no es válido: %v", err)
+	}
+
+	segundosVencido := time.Now().Add(-time.Minute).Unix()
+	firmaVencida, err := servicio.firmaArchivo(8, segundosVencido)
+	if err != nil {
+		t.Fatal(err)
+	}
+	vencido := strconv.FormatInt(segundosVencido, 10)
+	alterada := []byte(firma)
+	if alterada[0] == '0' {
+		alterada[0] = '1'
+	} else {
+		alterada[0] = '0'
+	}
+	masTarde := strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10)
+
+	casos := []struct {
+		nombre string
+		id     uint
+		expira string
+		firma  string
+	}{
+		{"firma alterada", 8, expira, string(alterada)},
+		{"firma vacía", 8, expira, ""},
+		{"otro archivo", 9, expira, firma},
+		{"expiración extendida", 8, masTarde, firma},
+		{"expiración mal formada", 8, "mañana", firma},
+		{"enlace vencido con firma válida", 8, vencido, firmaVencida},
+	}
+	for _, caso := range casos {
+		t.Run(caso.nombre, func(t *testing.T) {
+			if _, err := servicio.ArchivoFirmado(caso.id, caso.expira, caso.firma); !errors.Is(err, ErrFirmaArchivoInvalida) {
+				t.Errorf("error = %v, se esperaba ErrFirmaArchivoInvalida", err)
+			}
+		})
+	}
+
+	t.Run("archivo eliminado", func(t *testing.T) {
+		eliminado := servicioArchivosSimulado(t, nil, "secreto")
+		if _, err := eliminado.ArchivoFirmado(8, expira, firma); !errors.Is(err, ErrArchivoNoEncontrado) {
+			t.Errorf("error = %v, se esperaba ErrArchivoNoEncontrado", err)
+		}
+	})
+
+	t.Run("otro secreto", func(t *testing.T) {
+		otro := servicioArchivosSimulado(t, archivo, "otro secreto")
+		if _, err := otro.ArchivoFirmado(8, expira, firma); !errors.Is(err, ErrFirmaArchivoInvalida) {
+			t.Errorf("error = %v, se esperaba ErrFirmaArchivoInvalida", err)
+		}
+	})
+
+	t.Run("sin secreto no se firma", func(t *testing.T) {
+		sinSecreto := servicioArchivosSimulado(t, archivo, "")
+		if _, err := sinSecreto.FirmarURL(archivo, "https://api.uteq.edu.ec", 0); err == nil {
+			t.Error("se firmó un enlace sin secreto")
+		}
+		if _, err := sinSecreto.ArchivoFirmado(8, expira, firma); !errors.Is(err, ErrFirmaArchivoInvalida) {
+			t.Errorf("error = %v, se esperaba ErrFirmaArchivoInvalida", err)
+		}
+	})
+}
+
+func TestPuedeLeerArchivo(t *testing.T) {
+	id := func(v uint) *uint { return &v }
+	usuario := func(id, tipo uint, nombreTipo string) *models.Usuario {
+		u := &models.Usuario{TipoUsuarioID: tipo, TipoUsuario: models.TipoUsuario{Nombre: nombreTipo}}
+		u.ID = id
+		return u
+	}
+	privado := func(permisos ...models.PermisoArchivo) *models.Archivo {
+		return &models.Archivo{UsuarioID: 1, Visibilidad: models.VisibilidadArchivoPrivado, Permisos: permisos}
+	}
+
+	casos := []struct {
+		nombre    string
+		archivo   *models.Archivo
+		usuario   *models.Usuario
+		leer      bool
+		gestionar bool
+	}{
+		{"público", &models.Archivo{UsuarioID: 1, Visibilidad: models.VisibilidadArchivoPublico}, usuario(2, 3, "Estudiante"), true, false},
+		{"privado del propietario", privado(), usuario(1, 3, "Estudiante"), true, true},
+		{"privado y administrador", privado(), usuario(2, 1, models.NombreTipoUsuarioAdministrador), true, true},
+		{"privado sin permisos", privado(), usuario(2, 3, "Estudiante"), false, false},
+		{"permiso al usuario", privado(models.PermisoArchivo{UsuarioID: id(2)}), usuario(2, 3, "Estudiante"), true, false},
+		{"permiso a otro usuario", privado(models.PermisoArchivo{UsuarioID: id(4)}), usuario(2, 3, "Estudiante"), false, false},
+		{"permiso al tipo de usuario", privado(models.PermisoArchivo{TipoUsuarioID: id(3)}), usuario(2, 3, "Estudiante"), true, false},
+		{"permiso a otro tipo de usuario", privado(models.PermisoArchivo{TipoUsuarioID: id(5)}), usuario(2, 3, "Estudiante"), false, false},
+	}
+	for _, caso := range casos {
+		t.Run(caso.nombre, func(t *testing.T) {
+			if got := puedeLeerArchivo(caso.archivo, caso.usuario); got != caso.leer {
+				t.Errorf("puedeLeerArchivo = %v, se esperaba %v", got, caso.leer)
+			}
+			if got := puedeGestionarArchivo(caso.archivo, caso.usuario); got != caso.gestionar {
+				t.Errorf("puedeGestionarArchivo = %v, se esperaba %v", got, caso.gestionar)
+			}
+		})
+	}
+}
+
+func TestEsPrivadoConArchivosSinRegistro(t *testing.T) {
+	// Los archivos subidos antes de existir la tabla archivos no tienen registro y siguen siendo públicos
+	if privado, err := servicioArchivosSimulado(t, nil, "secreto").EsPrivado("images", "1600000000_antiguo.jpg"); err != nil || privado {
+		t.Errorf("EsPrivado = %v, %v; se esperaba false sin error", privado, err)
+	}
+
+	archivo := &models.Archivo{Carpeta: "images", NombreAlmacenado: "1700000000_ab12.jpg", Visibilidad: models.VisibilidadArchivoPrivado}
+	if privado, err := servicioArchivosSimulado(t, archivo, "secreto").EsPrivado("images", "1700000000_ab12.jpg"); err != nil || !privado {
+		t.Errorf("EsPrivado = %v, %v; se esperaba true sin error", privado, err)
+	}
+}
@@ -0,0 +1,359 @@
+package services
+
+import (
+	"ApiEscuela/models"
+	"ApiEscuela/repositories"
+	"context"
+	"errors"
+	"sort"
+	"time"
+)
+
+// Tipos de personal asignable a una visita
+const (
+	TipoPersonalAutoridad  = "autoridad"
+	TipoPersonalEstudiante = "estudiante"
+)
+
+// ventanaCarga es el rango alrededor de la visita usado para medir la carga de cada persona
+const ventanaCarga = 14 * 24 * time.Hour
+
+var (
+	ErrProgramaNoEncontrado          = errors.New("programa de visita no encontrado")
+	ErrDisponibilidadNoEncontrada    = errors.New("disponibilidad no encontrada")
+	ErrTipoPersonalInvalido          = errors.New("tipo de personal inválido")
+	ErrDisponibilidadPersonaInvalida = errors.New("indique autoridad_uteq_id o estudiante_universitario_id, no ambos")
+)
+
+type AsignacionService struct {
+	disponibilidadRepo *repositories.DisponibilidadPersonalRepository
+	programaRepo       *repositories.ProgramaVisitaRepository
+	detalleRepo        *repositories.DetalleAutoridadDetallesVisitaRepository
+	visitaEstRepo      *repositories.VisitaDetalleEstudiantesUniversitariosRepository
+	calendarioService  *CalendarioService
+	zona               *time.Location
+}
+
+func NewAsignacionService(
+	disponibilidadRepo *repositories.DisponibilidadPersonalRepository,
+	programaRepo *repositories.ProgramaVisitaRepository,
+	detalleRepo *repositories.DetalleAutoridadDetallesVisitaRepository,
+	visitaEstRepo *repositories.VisitaDetalleEstudiantesUniversitariosRepository,
+	calendarioService *CalendarioService,
+	zona *time.Location,
+) *AsignacionService {
+	return &AsignacionService{
+		disponibilidadRepo: disponibilidadRepo,
+		programaRepo:       programaRepo,
+		detalleRepo:        detalleRepo,
+		visitaEstRepo:      visitaEstRepo,
+		calendarioService:  calendarioService,
+		zona:               zona,
+	}
+}
+
+// ConContexto devuelve una copia del servicio cuyos repositorios usan ctx, para que las consultas queden
+// dentro de la traza de la petición
+func (s *AsignacionService) ConContexto(ctx context.Context) *AsignacionService {
+	copia := *s
+	copia.disponibilidadRepo = s.disponibilidadRepo.ConContexto(ctx)
+	copia.programaRepo = s.programaRepo.ConContexto(ctx)
+	copia.detalleRepo = s.detalleRepo.ConContexto(ctx)
+	copia.visitaEstRepo = s.visitaEstRepo.ConContexto(ctx)
+	copia.calendarioService = s.calendarioService.ConContexto(ctx)
+	return &copia
+}
+
+// DisponibilidadRequest representa los datos de una franja de disponibilidad
+type DisponibilidadRequest struct {
+	AutoridadUTEQID           *uint      `json:"autoridad_uteq_id"`
+	EstudianteUniversitarioID *uint      `json:"estudiante_universitario_id"`
+	DiaSemana                 int        `json:"dia_semana"`
+	HoraInicio                string     `json:"hora_inicio"`
+	HoraFin                   string     `json:"hora_fin"`
+	VigenteDesde              *time.Time `json:"vigente_desde"`
+	VigenteHasta              *time.Time `json:"vigente_hasta"`
+}
+
+// SugerenciaPersonal es una persona disponible para un programa de visita, ordenada por carga
+type SugerenciaPersonal struct {
+	Tipo      string `json:"tipo"`
+	ID        uint   `json:"id"`
+	PersonaID uint   `json:"persona_id"`
+	Nombre    string `json:"nombre"`
+	Carga     int    `json:"carga"` // Visitas asignadas en los 14 días previos y posteriores
+}
+
+// AutoAsignacionRequest indica cuántas personas de cada tipo se deben asignar
+type AutoAsignacionRequest struct {
+	Autoridades int `json:"autoridades"`
+	Estudiantes int `json:"estudiantes"`
+}
+
+// ResultadoAutoAsignacion contiene las asignaciones creadas y las que no se pudieron cubrir
+type ResultadoAutoAsignacion struct {
+	Autoridades          []models.DetalleAutoridadDetallesVisita         `json:"autoridades"`
+	Estudiantes          []models.VisitaDetalleEstudiantesUniversitarios `json:"estudiantes"`
+	AutoridadesFaltantes int                                             `json:"autoridades_faltantes"`
+	EstudiantesFaltantes int                                             `json:"estudiantes_faltantes"`
+}
+
+// CrearDisponibilidad valida y guarda una franja de disponibilidad
+func (s *AsignacionService) CrearDisponibilidad(req DisponibilidadRequest) (*models.DisponibilidadPersonal, error) {
+	disponibilidad := &models.DisponibilidadPersonal{}
+	if err := aplicarDisponibilidad(disponibilidad, req); err != nil {
+		return nil, err
+	}
+	if err := s.disponibilidadRepo.CreateDisponibilidad(disponibilidad); err != nil {
+		return nil, err
+	}
+	return disponibilidad, nil
+}
+
+// ActualizarDisponibilidad reemplaza los datos de una franja existente
+func (s *AsignacionService) ActualizarDisponibilidad(id uint, req DisponibilidadRequest) (*models.DisponibilidadPersonal, error) {
+	disponibilidad, err := s.disponibilidadRepo.GetDisponibilidadByID(id)
+	if err != nil {
+		return nil, ErrDisponibilidadNoEncontrada
+	}
+	if err := aplicarDisponibilidad(disponibilidad, req); err != nil {
+		return nil, err
+	}
+	if err := s.disponibilidadRepo.UpdateDisponibilidad(disponibilidad); err != nil {
+		return nil, err
+	}
+	return disponibilidad, nil
+}
+
+// SugerirPersonal lista las personas del tipo indicado que pueden acompañar el programa:
+// con una franja que cubre la visita, sin otra visita superpuesta y sin estar ya asignadas.
+// Se ordenan de menor a mayor carga.
+func (s *AsignacionService) SugerirPersonal(programaID uint, tipo string) ([]SugerenciaPersonal, error) {
+	programa, err := s.programaRepo.GetProgramaVisitaByID(programaID)
+	if err != nil {
+		return nil, ErrProgramaNoEncontrado
+	}
+
+	inicio := programa.Fecha.In(s.zona)
+	fin := finPrograma(*programa).In(s.zona)
+	dia := int(inicio.Weekday())
+
+	candidatos := map[uint]*SugerenciaPersonal{}
+	asignados := map[uint]bool{}
+	conflictos := map[uint]bool{}
+	carga := map[uint]int{}
+
+	switch tipo {
+	case TipoPersonalAutoridad:
+		disponibilidades, err := s.disponibilidadRepo.GetDisponibilidadesAutoridadesByDia(dia)
+		if err != nil {
+			return nil, err
+		}
+		for _, d := range disponibilidades {
+			if d.AutoridadUTEQ == nil || d.AutoridadUTEQ.ID == 0 || !franjaCubre(d, inicio, fin) {
+				continue
+			}
+			candidatos[d.AutoridadUTEQ.ID] = &SugerenciaPersonal{
+				Tipo: tipo, ID: d.AutoridadUTEQ.ID,
+				PersonaID: d.AutoridadUTEQ.PersonaID, Nombre: d.AutoridadUTEQ.Persona.Nombre,
+			}
+		}
+		detalles, err := s.detalleRepo.GetDetallesEnRango(inicio.Add(-ventanaCarga), fin.Add(ventanaCarga))
+		if err != nil {
+			return nil, err
+		}
+		for _, d := range detalles {
+			registrarCarga(d.AutoridadUTEQID, d.ProgramaVisita, programa.ID, inicio, fin, asignados, conflictos, carga)
+		}
+	case TipoPersonalEstudiante:
+		disponibilidades, err := s.disponibilidadRepo.GetDisponibilidadesEstudiantesByDia(dia)
+		if err != nil {
+			return nil, err
+		}
+		for _, d := range disponibilidades {
+			if d.EstudianteUniversitario == nil || d.EstudianteUniversitario.ID == 0 || !franjaCubre(d, inicio, fin) {
+				continue
+			}
+			candidatos[d.EstudianteUniversitario.ID] = &SugerenciaPersonal{
+				Tipo: tipo, ID: d.EstudianteUniversitario.ID,
+				PersonaID: d.EstudianteUniversitario.PersonaID, Nombre: d.EstudianteUniversitario.Persona.Nombre,
+			}
+		}
+		relaciones, err := s.visitaEstRepo.GetRelacionesEnRango(inicio.Add(-ventanaCarga), fin.Add(ventanaCarga))
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range relaciones {
+			registrarCarga(r.EstudianteUniversitarioID, r.ProgramaVisita, programa.ID, inicio, fin, asignados, conflictos, carga)
+		}
+	default:
+		return nil, ErrTipoPersonalInvalido
+	}
+
+	sugerencias := make([]SugerenciaPersonal, 0, len(candidatos))
+	for id, candidato := range candidatos {
+		if asignados[id] || conflictos[id] {
+			continue
+		}
+		candidato.Carga = carga[id]
+		sugerencias = append(sugerencias, *candidato)
+	}
+	sort.Slice(sugerencias, func(i, j int) bool {
+		if sugerencias[i].Carga != sugerencias[j].Carga {
+			return sugerencias[i].Carga < sugerencias[j].Carga
+		}
+		return sugerencias[i].ID < sugerencias[j].ID
+	})
+	return sugerencias, nil
+}
+
+// AutoAsignar asigna al programa las personas mejor ubicadas en las sugerencias y les envía la invitación
+// de calendario. Las que otra asignación ocupó mientras tanto se omiten y cuentan como faltantes.
+func (s *AsignacionService) AutoAsignar(ctx context.Context, programaID uint, req AutoAsignacionRequest) (*ResultadoAutoAsignacion, error) {
+	if req.Autoridades < 0 || req.Estudiantes < 0 {
+		return nil, errors.New("las cantidades no pueden ser negativas")
+	}
+	if req.Autoridades == 0 && req.Estudiantes == 0 {
+		return nil, errors.New("indique cuántas autoridades o estudiantes asignar")
+	}
+
+	var autoridadIDs, estudianteIDs []uint
+	if req.Autoridades > 0 {
+		sugerencias, err := s.SugerirPersonal(programaID, TipoPersonalAutoridad)
+		if err != nil {
+			return nil, err
+		}
+		autoridadIDs = primerasSugerencias(sugerencias, req.Autoridades)
+	}
+	if req.Estudiantes > 0 {
+		sugerencias, err := s.SugerirPersonal(programaID, TipoPersonalEstudiante)
+		if err != nil {
+			return nil, err
+		}
+		estudianteIDs = primerasSugerencias(sugerencias, req.Estudiantes)
+	}
+
+	programa, err := s.programaRepo.GetProgramaVisitaByID(programaID)
+	if err != nil {
+		return nil, ErrProgramaNoEncontrado
+	}
+	detalles, relaciones, err := s.disponibilidadRepo.AsignarPersonal(programaID, programa.Fecha, finPrograma(*programa), autoridadIDs, estudianteIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, detalle := range detalles {
+		s.calendarioService.NotificarAsignacionAutoridad(ctx, detalle.ID)
+	}
+	for _, relacion := range relaciones {
+		s.calendarioService.NotificarAsignacionEstudiante(ctx, relacion.ID)
+	}
+	return &ResultadoAutoAsignacion{
+		Autoridades:          detalles,
+		Estudiantes:          relaciones,
+		AutoridadesFaltantes: req.Autoridades - len(detalles),
+		EstudiantesFaltantes: req.Estudiantes - len(relaciones),
+	}, nil
+}
+
+// aplicarDisponibilidad valida la solicitud y copia sus datos en el modelo
+func aplicarDisponibilidad(disponibilidad *models.DisponibilidadPersonal, req DisponibilidadRequest) error {
+	if (req.AutoridadUTEQID == nil) == (req.EstudianteUniversitarioID == nil) {
+		return ErrDisponibilidadPersonaInvalida
+	}
+	if req.DiaSemana < 0 || req.DiaSemana > 6 {
+		return errors.New("dia_semana debe estar entre 0 (domingo) y 6 (sábado)")
+	}
+	inicio, err := minutosDelDia(req.HoraInicio)
+	if err != nil {
+		return errors.New("hora_inicio debe tener el formato HH:MM")
+	}
+	fin, err := minutosDelDia(req.HoraFin)
+	if err != nil {
+		return errors.New("hora_fin debe tener el formato HH:MM")
+	}
+	if fin <= inicio {
+		return errors.New("hora_fin debe ser posterior a hora_inicio")
+	}
+	if req.VigenteDesde != nil && req.VigenteHasta != nil && req.VigenteHasta.Before(*req.VigenteDesde) {
+		return errors.New("vigente_hasta debe ser posterior a vigente_desde")
+	}
+
+	disponibilidad.AutoridadUTEQID = req.AutoridadUTEQID
+	disponibilidad.EstudianteUniversitarioID = req.EstudianteUniversitarioID
+	disponibilidad.DiaSemana = req.DiaSemana
+	disponibilidad.HoraInicio = req.HoraInicio
+	disponibilidad.HoraFin = req.HoraFin
+	disponibilidad.VigenteDesde = req.VigenteDesde
+	disponibilidad.VigenteHasta = req.VigenteHasta
+	return nil
+}
+
+// franjaCubre indica si la franja contiene por completo la visita (inicio y fin en la zona local)
+func franjaCubre(d models.DisponibilidadPersonal, inicio, fin time.Time) bool {
+	if inicio.YearDay() != fin.YearDay() || inicio.Year() != fin.Year() {
+		return false
+	}
+	fecha := time.Date(inicio.Year(), inicio.Month(), inicio.Day(), 0, 0, 0, 0, time.UTC)
+	if d.VigenteDesde != nil && fecha.Before(truncarDia(*d.VigenteDesde)) {
+		return false
+	}
+	if d.VigenteHasta != nil && fecha.After(truncarDia(*d.VigenteHasta)) {
+		return false
+	}
+	desde, err := minutosDelDia(d.HoraInicio)
+	if err != nil {
+		return false
+	}
+	hasta, err := minutosDelDia(d.HoraFin)
+	if err != nil {
+		return false
+	}
+	return inicio.Hour()*60+inicio.Minute() >= desde && fin.Hour()*60+fin.Minute() <= hasta
+}
+
+// registrarCarga acumula la carga de una persona y marca si ya está asignada o tiene una visita superpuesta
+func registrarCarga(personaID uint, otro models.ProgramaVisita, programaID uint, inicio, fin time.Time, asignados, conflictos map[uint]bool, carga map[uint]int) {
+	if otro.ID == 0 {
+		return
+	}
+	if otro.ID == programaID {
+		asignados[personaID] = true
+		return
+	}
+	carga[personaID]++
+	if otro.Fecha.Before(fin) && finPrograma(otro).After(inicio) {
+		conflictos[personaID] = true
+	}
+}
+
+func primerasSugerencias(sugerencias []SugerenciaPersonal, n int) []uint {
+	if n > len(sugerencias) {
+		n = len(sugerencias)
+	}
+	ids := make([]uint, 0, n)
+	for _, s := range sugerencias[:n] {
+		ids = append(ids, s.ID)
+	}
+	return ids
+}
+
+// minutosDelDia convierte "HH:MM" en minutos desde la medianoche
+func minutosDelDia(hora string) (int, error) {
+	t, err := time.Parse("15:04", hora)
+	if err != nil {
+		return 0, err
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+func truncarDia(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// finPrograma devuelve el fin de una visita; si no tiene fecha de fin se asume una hora de duración
+func finPrograma(programa models.ProgramaVisita) time.Time {
+	if programa.Fechafin.IsZero() || !programa.Fechafin.After(programa.Fecha) {
+		return programa.Fecha.Add(time.Hour)
+	}
+	return programa.Fechafin
+}
@@ -1,16 +1,17 @@
 package services
 
 import (
+	"ApiEscuela/metricas"
 	"ApiEscuela/middleware"
 	"ApiEscuela/models"
 	"ApiEscuela/repositories"
+	"context"
 	"crypto/rand"
 	"errors"
 	"fmt"
+	"log/slog"
 	"math/big"
 	mrand "math/rand"
-	"net/smtp"
-	"os"
 	"strings"
 	"time"
 	"unicode"
@@ -22,18 +23,30 @@ type AuthService struct {
 	usuarioRepo       *repositories.UsuarioRepository
 	personaRepo       *repositories.PersonaRepository
 	codigoUsuarioRepo *repositories.CodigoUsuarioRepository
+	emailService      *EmailService
 }
 
 var ErrPersonaNoEncontrada = errors.New("persona no encontrada")
 
-func NewAuthService(usuarioRepo *repositories.UsuarioRepository, personaRepo *repositories.PersonaRepository, codigoUsuarioRepo *repositories.CodigoUsuarioRepository) *AuthService {
+func NewAuthService(usuarioRepo *repositories.UsuarioRepository, personaRepo *repositories.PersonaRepository, codigoUsuarioRepo *repositories.CodigoUsuarioRepository, emailService *EmailService) *AuthService {
 	return &AuthService{
 		usuarioRepo:       usuarioRepo,
 		personaRepo:       personaRepo,
 		codigoUsuarioRepo: codigoUsuarioRepo,
+		emailService:      emailService,
 	}
 }
 
+// ConContexto devuelve una copia del servicio cuyos repositorios usan ctx, para que las consultas queden
+// dentro de la traza de la petición
+func (s *AuthService) ConContexto(ctx context.Context) *AuthService {
+	copia := *s
+	copia.usuarioRepo = s.usuarioRepo.ConContexto(ctx)
+	copia.personaRepo = s.personaRepo.ConContexto(ctx)
+	copia.codigoUsuarioRepo = s.codigoUsuarioRepo.ConContexto(ctx)
+	return &copia
+}
+
 // LoginRequest representa la estructura de datos para el login
 type LoginRequest struct {
 	Usuario    string `json:"usuario" validate:"required"`
@@ -77,9 +90,11 @@ func (s *AuthService) Login(loginReq LoginRequest) (*LoginResponse, error) {
 		usuarioDeleted, errDeleted := s.usuarioRepo.GetUsuarioByUsernameIncludingDeleted(loginReq.Usuario)
 		if errDeleted == nil && usuarioDeleted != nil {
 			// El usuario existe pero está eliminado
+			metricas.RegistrarLogin(false, "usuario_eliminado")
 			return nil, errors.New("usuario eliminado - contacte al administrador")
 		}
 		// Usuario no existe
+		metricas.RegistrarLogin(false, "usuario_no_encontrado")
 		return nil, errors.New("usuario no encontrado")
 	}
 
@@ -87,11 +102,13 @@ func (s *AuthService) Login(loginReq LoginRequest) (*LoginResponse, error) {
 	if len(usuario.Contraseña) < 60 {
 		// Contraseña no está encriptada, comparar directamente
 		if loginReq.Contraseña != usuario.Contraseña {
+			metricas.RegistrarLogin(false, "contrasena_incorrecta")
 			return nil, errors.New("contraseña incorrecta (texto plano)")
 		}
 	} else {
 		// Verificar contraseña encriptada
 		if !s.CheckPassword(loginReq.Contraseña, usuario.Contraseña) {
+			metricas.RegistrarLogin(false, "contrasena_incorrecta")
 			return nil, errors.New("contraseña incorrecta (hash bcrypt)")
 		}
 	}
@@ -99,8 +116,10 @@ func (s *AuthService) Login(loginReq LoginRequest) (*LoginResponse, error) {
 	// Generar token JWT
 	token, err := middleware.GenerateJWT(usuario.ID, usuario.Usuario, usuario.TipoUsuarioID)
 	if err != nil {
+		metricas.RegistrarLogin(false, "error_token")
 		return nil, errors.New("error al generar token")
 	}
+	metricas.RegistrarLogin(true, "")
 
 	// Limpiar la contraseña antes de devolver el usuario
 	usuario.Contraseña = ""
@@ -205,7 +224,7 @@ func (s *AuthService) GenerateNewToken(userID uint, username string, tipoUsuario
 }
 
 // RecoverPassword genera una contraseña temporal y la envía por correo
-func (s *AuthService) RecoverPassword(cedula string) error {
+func (s *AuthService) RecoverPassword(ctx context.Context, cedula string) error {
 	if strings.TrimSpace(cedula) == "" {
 		return errors.New("la cédula es requerida")
 	}
@@ -240,7 +259,7 @@ func (s *AuthService) RecoverPassword(cedula string) error {
 		// Marcar códigos expirados como expirado
 		for _, codigo := range codigosExpirados {
 			if err := s.codigoUsuarioRepo.MarcarComoExpirado(codigo.ID); err != nil {
-				fmt.Printf("DEBUG: Error al marcar código como expirado: %v\n", err)
+				slog.Warn("no se pudo marcar el código como expirado", "usuario_id", u.ID, "error", err)
 			}
 		}
 
@@ -276,56 +295,12 @@ func (s *AuthService) RecoverPassword(cedula string) error {
 		persona.Nombre, otp, strings.Join(usernames, ", "),
 	)
 
-	return s.sendEmail(persona.Correo, subject, body)
+	return s.sendEmail(ctx, persona.Correo, subject, body)
 }
 
 // sendEmail envía un correo usando SMTP con contenido HTML básico
-func (s *AuthService) sendEmail(to, subject, htmlBody string) error {
-	// Obtener configuración SMTP desde variables de entorno
-	host := os.Getenv("SMTP_HOST")
-	port := os.Getenv("SMTP_PORT")
-	user := os.Getenv("SMTP_USER")
-	pass := os.Getenv("SMTP_PASS")
-	from := os.Getenv("SMTP_FROM")
-	fromName := os.Getenv("SMTP_FROM_NAME")
-
-	// Debug: Mostrar configuración SMTP (sin contraseña)
-	fmt.Printf("DEBUG SMTP - Host: %s, Port: %s, User: %s, From: %s, FromName: %s\n", host, port, user, from, fromName)
-	fmt.Printf("DEBUG SMTP - Pass length: %d\n", len(pass))
-
-	// Verificar que todas las variables estén definidas
-	if host == "" || port == "" || user == "" || pass == "" || from == "" || fromName == "" {
-		return fmt.Errorf("configuración SMTP incompleta. Verifique las variables de entorno: SMTP_HOST, SMTP_PORT, SMTP_USER, SMTP_PASS, SMTP_FROM, SMTP_FROM_NAME")
-	}
-
-	addr := host + ":" + port
-	auth := smtp.PlainAuth("", user, pass, host)
-
-	fmt.Printf("DEBUG SMTP - Conectando a: %s\n", addr)
-
-	// Mensaje MIME
-	headers := make(map[string]string)
-	headers["From"] = fmt.Sprintf("%s <%s>", fromName, from)
-	headers["To"] = to
-	headers["Subject"] = subject
-	headers["MIME-Version"] = "1.0"
-	headers["Content-Type"] = "text/html; charset=\"UTF-8\""
-
-	var msgBuilder strings.Builder
-	for k, v := range headers {
-		msgBuilder.WriteString(fmt.Sprintf("%s: %s\r\n", k, v))
-	}
-	msgBuilder.WriteString("\r\n")
-	msgBuilder.WriteString(htmlBody)
-
-	fmt.Printf("DEBUG SMTP - Enviando correo a: %s\n", to)
-	err := smtp.SendMail(addr, auth, from, []string{to}, []byte(msgBuilder.String()))
-	if err != nil {
-		fmt.Printf("DEBUG SMTP - Error al enviar correo: %v\n", err)
-	} else {
-		fmt.Printf("DEBUG SMTP - Correo enviado exitosamente\n")
-	}
-	return err
+func (s *AuthService) sendEmail(ctx context.Context, to, subject, htmlBody string) error {
+	return s.emailService.Enviar(ctx, to, subject, htmlBody)
 }
 
 // generateRandomPassword crea una contraseña aleatoria alfanumérica
@@ -481,7 +456,7 @@ func (s *AuthService) ResetPasswordByCodigoID(codigoID uint, usuarioID uint, nue
 
 	// Marcar el código como verificado después de cambiar la contraseña
 	if err := s.codigoUsuarioRepo.MarcarComoVerificado(codigoID); err != nil {
-		fmt.Printf("DEBUG: Error al marcar código como verificado: %v\n", err)
+		slog.Warn("no se pudo marcar el código como verificado", "usuario_id", usuarioID, "error", err)
 		// No retornar error, la contraseña ya fue cambiada
 	}
 
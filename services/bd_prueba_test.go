@@ -0,0 +1,85 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+	"testing"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// bdSimulada responde las consultas sin conectarse a PostgreSQL: cada First o Find sobre un modelo
+// devuelve el registro de filas con el nombre de ese modelo (por ejemplo "Usuario"). Si no hay uno,
+// First falla con gorm.ErrRecordNotFound, como en la base real. Un Find sobre un slice devuelve el
+// slice registrado con ese nombre.
+func bdSimulada(t *testing.T, filas map[string]interface{}) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost dbname=escuela"}), &gorm.Config{
+		DryRun:               true,
+		DisableAutomaticPing: true,
+		Logger:               logger.Discard,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.ConnPool = &conexionSimulada{}
+	db.Statement.ConnPool = db.ConnPool
+	err = db.Callback().Query().After("gorm:preload").Register("prueba:filas", func(d *gorm.DB) {
+		if d.Statement.Schema == nil {
+			return
+		}
+		fila, ok := filas[d.Statement.Schema.Name]
+		destino := d.Statement.ReflectValue
+		if ok && destino.Kind() == reflect.Slice && reflect.TypeOf(fila) == destino.Type() {
+			destino.Set(reflect.ValueOf(fila))
+			d.RowsAffected = int64(destino.Len())
+			return
+		}
+		if !ok || destino.Kind() != reflect.Struct {
+			if d.Statement.RaiseErrorOnNotFound {
+				d.AddError(gorm.ErrRecordNotFound)
+			}
+			return
+		}
+		destino.Set(reflect.ValueOf(fila).Elem())
+		d.RowsAffected = 1
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// conexionSimulada permite abrir transacciones en DryRun, donde ninguna sentencia llega a ejecutarse
+type conexionSimulada struct{}
+
+func (*conexionSimulada) PrepareContext(context.Context, string) (*sql.Stmt, error) {
+	return nil, sql.ErrConnDone
+}
+
+func (*conexionSimulada) ExecContext(context.Context, string, ...interface{}) (sql.Result, error) {
+	return nil, sql.ErrConnDone
+}
+
+func (*conexionSimulada) QueryContext(context.Context, string, ...interface{}) (*sql.Rows, error) {
+	return nil, sql.ErrConnDone
+}
+
+func (*conexionSimulada) QueryRowContext(context.Context, string, ...interface{}) *sql.Row {
+	return nil
+}
+
+func (c *conexionSimulada) BeginTx(context.Context, *sql.TxOptions) (gorm.ConnPool, error) {
+	return c, nil
+}
+
+func (*conexionSimulada) Commit() error {
+	return nil
+}
+
+func (*conexionSimulada) Rollback() error {
+	return nil
+}
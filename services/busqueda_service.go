@@ -0,0 +1,75 @@
+package services
+
+import (
+	"ApiEscuela/repositories"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Tipos aceptados por el parámetro tipos de la búsqueda unificada
+var tiposBusqueda = []string{"noticias", "dudas", "actividades", "instituciones"}
+
+var ErrTipoBusquedaInvalido = errors.New("tipo de búsqueda inválido")
+
+type BusquedaService struct {
+	busquedaRepo *repositories.BusquedaRepository
+	dudasService *DudasService
+}
+
+func NewBusquedaService(busquedaRepo *repositories.BusquedaRepository, dudasService *DudasService) *BusquedaService {
+	return &BusquedaService{busquedaRepo: busquedaRepo, dudasService: dudasService}
+}
+
+// ConContexto devuelve una copia del servicio cuyos repositorios usan ctx, para que las consultas queden
+// dentro de la traza de la petición
+func (s *BusquedaService) ConContexto(ctx context.Context) *BusquedaService {
+	return &BusquedaService{busquedaRepo: s.busquedaRepo.ConContexto(ctx), dudasService: s.dudasService.ConContexto(ctx)}
+}
+
+// ResultadoBusquedaUnificada agrupa los resultados por tipo, cada grupo ordenado por relevancia
+type ResultadoBusquedaUnificada struct {
+	Consulta   string                                      `json:"consulta"`
+	Total      int                                         `json:"total"`
+	Resultados map[string][]repositories.ResultadoBusqueda `json:"resultados"`
+}
+
+// Buscar busca el término en noticias, dudas, actividades e instituciones (o solo en los tipos indicados).
+// Las dudas privadas solo aparecen para quien puede verlas.
+func (s *BusquedaService) Buscar(usuarioID uint, termino string, tipos []string, limite int) (*ResultadoBusquedaUnificada, error) {
+	if len(tipos) == 0 {
+		tipos = tiposBusqueda
+	}
+	resultado := &ResultadoBusquedaUnificada{Consulta: termino, Resultados: map[string][]repositories.ResultadoBusqueda{}}
+
+	for _, tipo := range tipos {
+		var encontrados []repositories.ResultadoBusqueda
+		var err error
+		switch strings.TrimSpace(tipo) {
+		case "noticias":
+			encontrados, err = s.busquedaRepo.BuscarNoticias(termino, limite)
+		case "dudas":
+			visor, errVisor := s.dudasService.Visor(usuarioID)
+			if errVisor != nil {
+				return nil, errVisor
+			}
+			encontrados, err = s.busquedaRepo.BuscarDudas(visor, termino, limite)
+		case "actividades":
+			encontrados, err = s.busquedaRepo.BuscarActividades(termino, limite)
+		case "instituciones":
+			encontrados, err = s.busquedaRepo.BuscarInstituciones(termino, limite)
+		default:
+			return nil, fmt.Errorf("%w %q; use %s", ErrTipoBusquedaInvalido, tipo, strings.Join(tiposBusqueda, ", "))
+		}
+		if err != nil {
+			return nil, err
+		}
+		if encontrados == nil {
+			encontrados = []repositories.ResultadoBusqueda{}
+		}
+		resultado.Resultados[strings.TrimSpace(tipo)] = encontrados
+		resultado.Total += len(encontrados)
+	}
+	return resultado, nil
+}
@@ -0,0 +1,242 @@
+package services
+
+import (
+	"ApiEscuela/models"
+	"ApiEscuela/repositories"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// retencionCancelados es el tiempo durante el cual una asignación eliminada sigue
+// publicándose como STATUS:CANCELLED para que los clientes la retiren
+const retencionCancelados = 30 * 24 * time.Hour
+
+var (
+	ErrTokenCalendarioInvalido = errors.New("token de calendario inválido")
+	ErrSinAsignaciones         = errors.New("el usuario no es autoridad ni estudiante universitario")
+)
+
+type CalendarioService struct {
+	tokenRepo          *repositories.TokenCalendarioRepository
+	usuarioRepo        *repositories.UsuarioRepository
+	autoridadRepo      *repositories.AutoridadUTEQRepository
+	estudianteUnivRepo *repositories.EstudianteUniversitarioRepository
+	detalleRepo        *repositories.DetalleAutoridadDetallesVisitaRepository
+	visitaEstRepo      *repositories.VisitaDetalleEstudiantesUniversitariosRepository
+	emailService       *EmailService
+}
+
+func NewCalendarioService(
+	tokenRepo *repositories.TokenCalendarioRepository,
+	usuarioRepo *repositories.UsuarioRepository,
+	autoridadRepo *repositories.AutoridadUTEQRepository,
+	estudianteUnivRepo *repositories.EstudianteUniversitarioRepository,
+	detalleRepo *repositories.DetalleAutoridadDetallesVisitaRepository,
+	visitaEstRepo *repositories.VisitaDetalleEstudiantesUniversitariosRepository,
+	emailService *EmailService,
+) *CalendarioService {
+	return &CalendarioService{
+		tokenRepo:          tokenRepo,
+		usuarioRepo:        usuarioRepo,
+		autoridadRepo:      autoridadRepo,
+		estudianteUnivRepo: estudianteUnivRepo,
+		detalleRepo:        detalleRepo,
+		visitaEstRepo:      visitaEstRepo,
+		emailService:       emailService,
+	}
+}
+
+// ConContexto devuelve una copia del servicio cuyos repositorios usan ctx, para que las consultas queden
+// dentro de la traza de la petición
+func (s *CalendarioService) ConContexto(ctx context.Context) *CalendarioService {
+	copia := *s
+	copia.tokenRepo = s.tokenRepo.ConContexto(ctx)
+	copia.usuarioRepo = s.usuarioRepo.ConContexto(ctx)
+	copia.autoridadRepo = s.autoridadRepo.ConContexto(ctx)
+	copia.estudianteUnivRepo = s.estudianteUnivRepo.ConContexto(ctx)
+	copia.detalleRepo = s.detalleRepo.ConContexto(ctx)
+	copia.visitaEstRepo = s.visitaEstRepo.ConContexto(ctx)
+	return &copia
+}
+
+// ObtenerToken devuelve el token de calendario del usuario, creándolo si no existe
+func (s *CalendarioService) ObtenerToken(usuarioID uint) (*models.TokenCalendario, error) {
+	if token, err := s.tokenRepo.GetTokenByUsuario(usuarioID); err == nil {
+		return token, nil
+	}
+	return s.RegenerarToken(usuarioID)
+}
+
+// RegenerarToken reemplaza el token del usuario invalidando las suscripciones anteriores
+func (s *CalendarioService) RegenerarToken(usuarioID uint) (*models.TokenCalendario, error) {
+	valor, err := generarTokenHex(32)
+	if err != nil {
+		return nil, err
+	}
+	return s.tokenRepo.GuardarToken(usuarioID, valor)
+}
+
+// GenerarFeed construye el calendario iCalendar del usuario dueño del token
+func (s *CalendarioService) GenerarFeed(valor string) ([]byte, error) {
+	token, err := s.tokenRepo.GetTokenByValor(valor)
+	if err != nil {
+		return nil, ErrTokenCalendarioInvalido
+	}
+	usuario, err := s.usuarioRepo.GetUsuarioByID(token.UsuarioID)
+	if err != nil {
+		return nil, ErrTokenCalendarioInvalido
+	}
+
+	eliminadosDesde := time.Now().Add(-retencionCancelados)
+	var eventos []EventoCalendario
+	encontrado := false
+
+	if autoridad, err := s.autoridadRepo.GetAutoridadUTEQByPersona(usuario.PersonaID); err == nil {
+		encontrado = true
+		detalles, err := s.detalleRepo.GetDetallesCalendarioByAutoridad(autoridad.ID, eliminadosDesde)
+		if err != nil {
+			return nil, err
+		}
+		for _, d := range detalles {
+			if evento, ok := eventoAsignacion("asignacion-autoridad", d.ID, d.Model, d.ProgramaVisita, eliminadosDesde); ok {
+				eventos = append(eventos, evento)
+			}
+		}
+	}
+
+	if estudiante, err := s.estudianteUnivRepo.GetEstudianteUniversitarioByPersona(usuario.PersonaID); err == nil {
+		encontrado = true
+		relaciones, err := s.visitaEstRepo.GetRelacionesCalendarioByEstudiante(estudiante.ID, eliminadosDesde)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range relaciones {
+			if evento, ok := eventoAsignacion("asignacion-estudiante", r.ID, r.Model, r.ProgramaVisita, eliminadosDesde); ok {
+				eventos = append(eventos, evento)
+			}
+		}
+	}
+
+	if !encontrado {
+		return nil, ErrSinAsignaciones
+	}
+
+	return GenerarICS("Visitas UTEQ", MetodoICSPublicar, eventos), nil
+}
+
+// NotificarAsignacionAutoridad envía a la autoridad el .ics de su asignación (REQUEST o CANCEL según su estado)
+func (s *CalendarioService) NotificarAsignacionAutoridad(ctx context.Context, detalleID uint) {
+	detalle, err := s.detalleRepo.GetDetalleByIDIncludingDeleted(detalleID)
+	if err != nil {
+		slog.WarnContext(ctx, "calendario: no se pudo cargar la asignación de autoridad", "detalle_id", detalleID, "error", err)
+		return
+	}
+	s.notificar(ctx, "asignacion-autoridad", detalle.ID, detalle.Model, detalle.ProgramaVisita, detalle.AutoridadUTEQ.Persona)
+}
+
+// NotificarAsignacionEstudiante envía al estudiante el .ics de su asignación (REQUEST o CANCEL según su estado)
+func (s *CalendarioService) NotificarAsignacionEstudiante(ctx context.Context, relacionID uint) {
+	relacion, err := s.visitaEstRepo.GetRelacionByIDIncludingDeleted(relacionID)
+	if err != nil {
+		slog.WarnContext(ctx, "calendario: no se pudo cargar la asignación de estudiante", "relacion_id", relacionID, "error", err)
+		return
+	}
+	s.notificar(ctx, "asignacion-estudiante", relacion.ID, relacion.Model, relacion.ProgramaVisita, relacion.EstudianteUniversitario.Persona)
+}
+
+// NotificarCambioPrograma reenvía el .ics a todos los asignados de un programa de visita
+// después de crearlo, modificarlo o eliminarlo
+func (s *CalendarioService) NotificarCambioPrograma(ctx context.Context, programaVisitaID uint) {
+	detalles, err := s.detalleRepo.GetDetallesParaNotificar(programaVisitaID)
+	if err != nil {
+		slog.WarnContext(ctx, "calendario: no se pudieron cargar las autoridades del programa", "programa_visita_id", programaVisitaID, "error", err)
+	}
+	for _, d := range detalles {
+		s.NotificarAsignacionAutoridad(ctx, d.ID)
+	}
+
+	relaciones, err := s.visitaEstRepo.GetRelacionesParaNotificar(programaVisitaID)
+	if err != nil {
+		slog.WarnContext(ctx, "calendario: no se pudieron cargar los estudiantes del programa", "programa_visita_id", programaVisitaID, "error", err)
+	}
+	for _, r := range relaciones {
+		s.NotificarAsignacionEstudiante(ctx, r.ID)
+	}
+}
+
+// notificar arma el evento y envía la invitación en segundo plano
+func (s *CalendarioService) notificar(ctx context.Context, tipo string, id uint, asignacion gorm.Model, programa models.ProgramaVisita, persona models.Persona) {
+	if persona.Correo == "" {
+		return
+	}
+
+	evento := construirEvento(tipo, id, asignacion, programa)
+	evento.Asistente = persona.Correo
+	evento.Organizador = s.emailService.Remitente()
+
+	metodo := MetodoICSSolicitud
+	asunto := "Asignación a visita: " + evento.Resumen
+	mensaje := "Ha sido asignado a la siguiente visita"
+	if evento.Cancelado {
+		metodo = MetodoICSCancelar
+		asunto = "Visita cancelada: " + evento.Resumen
+		mensaje = "La siguiente visita ha sido cancelada"
+	}
+
+	cuerpo := fmt.Sprintf(`<p>Hola %s,</p><p>%s:</p><p><strong>%s</strong><br>%s - %s</p><p>Se adjunta el evento para su calendario.</p>`,
+		persona.Nombre, mensaje, evento.Resumen,
+		programa.Fecha.Format("02/01/2006 15:04"), evento.Fin.Format("02/01/2006 15:04"))
+	adjunto := AdjuntoCorreo{
+		Nombre:      "visita.ics",
+		ContentType: "text/calendar; charset=UTF-8; method=" + metodo,
+		Contenido:   GenerarICS("", metodo, []EventoCalendario{evento}),
+	}
+
+	s.emailService.EnviarEnSegundoPlano(ctx, persona.Correo, asunto, cuerpo, adjunto)
+}
+
+// eventoAsignacion construye el evento del feed y descarta cancelaciones antiguas
+func eventoAsignacion(tipo string, id uint, asignacion gorm.Model, programa models.ProgramaVisita, eliminadosDesde time.Time) (EventoCalendario, bool) {
+	if programa.ID == 0 {
+		return EventoCalendario{}, false
+	}
+	if programa.DeletedAt.Valid && programa.DeletedAt.Time.Before(eliminadosDesde) {
+		return EventoCalendario{}, false
+	}
+	return construirEvento(tipo, id, asignacion, programa), true
+}
+
+// construirEvento convierte una asignación en un VEVENT con UID estable por asignación
+func construirEvento(tipo string, id uint, asignacion gorm.Model, programa models.ProgramaVisita) EventoCalendario {
+	modificado := asignacion.UpdatedAt
+	if programa.UpdatedAt.After(modificado) {
+		modificado = programa.UpdatedAt
+	}
+	if asignacion.DeletedAt.Valid && asignacion.DeletedAt.Time.After(modificado) {
+		modificado = asignacion.DeletedAt.Time
+	}
+	if programa.DeletedAt.Valid && programa.DeletedAt.Time.After(modificado) {
+		modificado = programa.DeletedAt.Time
+	}
+
+	resumen := "Visita programada"
+	if programa.Institucion.Nombre != "" {
+		resumen = "Visita a " + programa.Institucion.Nombre
+	}
+
+	return EventoCalendario{
+		UID:         UIDCalendario(tipo, id),
+		Inicio:      programa.Fecha,
+		Fin:         finPrograma(programa),
+		Resumen:     resumen,
+		Descripcion: fmt.Sprintf("Programa de visita #%d", programa.ID),
+		Ubicacion:   programa.Institucion.Direccion,
+		Modificado:  modificado,
+		Cancelado:   asignacion.DeletedAt.Valid || programa.DeletedAt.Valid,
+	}
+}
@@ -0,0 +1,83 @@
+package services
+
+import "strings"
+
+// Tipos de línea de un diff
+const (
+	DiffIgual     = "igual"
+	DiffAgregado  = "agregado"
+	DiffEliminado = "eliminado"
+)
+
+// maxCeldasDiff limita el tamaño de la tabla LCS; por encima se muestra el texto completo como reemplazado
+const maxCeldasDiff = 4_000_000
+
+// LineaDiff es una línea del diff entre dos textos
+type LineaDiff struct {
+	Tipo  string `json:"tipo"`
+	Texto string `json:"texto"`
+}
+
+// diffLineas compara dos textos línea a línea usando la subsecuencia común más larga
+func diffLineas(antes, despues string) []LineaDiff {
+	a := dividirLineas(antes)
+	b := dividirLineas(despues)
+	if len(a)*len(b) > maxCeldasDiff {
+		diff := make([]LineaDiff, 0, len(a)+len(b))
+		for _, l := range a {
+			diff = append(diff, LineaDiff{Tipo: DiffEliminado, Texto: l})
+		}
+		for _, l := range b {
+			diff = append(diff, LineaDiff{Tipo: DiffAgregado, Texto: l})
+		}
+		return diff
+	}
+
+	// lcs[i][j] = longitud de la subsecuencia común más larga de a[i:] y b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	diff := make([]LineaDiff, 0, len(a)+len(b))
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			diff = append(diff, LineaDiff{Tipo: DiffIgual, Texto: a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			diff = append(diff, LineaDiff{Tipo: DiffEliminado, Texto: a[i]})
+			i++
+		default:
+			diff = append(diff, LineaDiff{Tipo: DiffAgregado, Texto: b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		diff = append(diff, LineaDiff{Tipo: DiffEliminado, Texto: a[i]})
+	}
+	for ; j < len(b); j++ {
+		diff = append(diff, LineaDiff{Tipo: DiffAgregado, Texto: b[j]})
+	}
+	return diff
+}
+
+func dividirLineas(texto string) []string {
+	if texto == "" {
+		return nil
+	}
+	return strings.Split(strings.ReplaceAll(texto, "\r\n", "\n"), "\n")
+}
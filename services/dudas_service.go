@@ -0,0 +1,350 @@
+package services
+
+import (
+	"ApiEscuela/models"
+	"ApiEscuela/repositories"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// maxLongitudMensajeDuda limita el contenido de cada mensaje del hilo
+const maxLongitudMensajeDuda = 2000
+
+var (
+	ErrDudaNoEncontrada        = errors.New("duda no encontrada")
+	ErrMensajeNoEncontrado     = errors.New("mensaje no encontrado")
+	ErrSinAccesoDuda           = errors.New("no participa en esta duda")
+	ErrNotaInternaNoPermitida  = errors.New("solo las autoridades UTEQ y los administradores pueden crear notas internas")
+	ErrEdicionNoPermitida      = errors.New("solo el autor puede editar el mensaje")
+	ErrReasignacionNoPermitida = errors.New("solo el personal UTEQ puede cambiar el estudiante, la autoridad asignada o la privacidad de una duda")
+	ErrRespuestaNoPermitida    = errors.New("solo el personal UTEQ puede responder una duda")
+)
+
+type DudasService struct {
+	dudasRepo      *repositories.DudasRepository
+	usuarioRepo    *repositories.UsuarioRepository
+	estudianteRepo *repositories.EstudianteRepository
+	autoridadRepo  *repositories.AutoridadUTEQRepository
+}
+
+func NewDudasService(
+	dudasRepo *repositories.DudasRepository,
+	usuarioRepo *repositories.UsuarioRepository,
+	estudianteRepo *repositories.EstudianteRepository,
+	autoridadRepo *repositories.AutoridadUTEQRepository,
+) *DudasService {
+	return &DudasService{
+		dudasRepo:      dudasRepo,
+		usuarioRepo:    usuarioRepo,
+		estudianteRepo: estudianteRepo,
+		autoridadRepo:  autoridadRepo,
+	}
+}
+
+// ConContexto devuelve una copia del servicio cuyos repositorios usan ctx, para que las consultas queden
+// dentro de la traza de la petición
+func (s *DudasService) ConContexto(ctx context.Context) *DudasService {
+	return &DudasService{
+		dudasRepo:      s.dudasRepo.ConContexto(ctx),
+		usuarioRepo:    s.usuarioRepo.ConContexto(ctx),
+		estudianteRepo: s.estudianteRepo.ConContexto(ctx),
+		autoridadRepo:  s.autoridadRepo.ConContexto(ctx),
+	}
+}
+
+// MensajeDudaRequest representa un mensaje nuevo o editado del hilo
+type MensajeDudaRequest struct {
+	Contenido string `json:"contenido"`
+	Tipo      string `json:"tipo"`
+}
+
+// HiloDuda es la duda con los mensajes visibles para quien la consulta
+type HiloDuda struct {
+	Duda     *models.Dudas        `json:"duda"`
+	Rol      string               `json:"rol"`
+	Mensajes []models.MensajeDuda `json:"mensajes"`
+}
+
+// participanteDuda identifica a un usuario dentro del hilo de una duda
+type participanteDuda struct {
+	usuario     *models.Usuario
+	rol         string
+	autoridadID *uint
+}
+
+// ObtenerHilo devuelve el hilo de la duda y lo marca como leído para el usuario.
+// Las notas internas se omiten para el estudiante.
+func (s *DudasService) ObtenerHilo(dudaID, usuarioID uint) (*HiloDuda, error) {
+	duda, p, err := s.dudaYParticipante(dudaID, usuarioID)
+	if err != nil {
+		return nil, err
+	}
+	mensajes, err := s.dudasRepo.GetMensajesByDuda(dudaID, p.rol == models.RolDudaPersonal)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.dudasRepo.MarcarLeida(dudaID, usuarioID, time.Now()); err != nil {
+		return nil, err
+	}
+	return &HiloDuda{Duda: duda, Rol: p.rol, Mensajes: mensajes}, nil
+}
+
+// AgregarMensaje agrega un mensaje o una nota interna al hilo
+func (s *DudasService) AgregarMensaje(dudaID, usuarioID uint, req MensajeDudaRequest) (*models.MensajeDuda, error) {
+	_, p, err := s.dudaYParticipante(dudaID, usuarioID)
+	if err != nil {
+		return nil, err
+	}
+	return s.crearMensaje(dudaID, p, req, p.autoridadID)
+}
+
+// Responder agrega una respuesta pública del personal sin reemplazar las anteriores.
+// autoridadID indica la autoridad a la que queda asignada la duda; si es nil se usa la de quien responde.
+func (s *DudasService) Responder(dudaID, usuarioID uint, respuesta string, autoridadID *uint) (*models.MensajeDuda, error) {
+	_, p, err := s.dudaYParticipante(dudaID, usuarioID)
+	if err != nil {
+		return nil, err
+	}
+	if p.rol != models.RolDudaPersonal {
+		return nil, ErrSinAccesoDuda
+	}
+	if autoridadID != nil {
+		if _, err := s.autoridadRepo.GetAutoridadUTEQByID(*autoridadID); err != nil {
+			return nil, errors.New("la autoridad indicada no existe")
+		}
+	} else {
+		autoridadID = p.autoridadID
+	}
+	return s.crearMensaje(dudaID, p, MensajeDudaRequest{Contenido: respuesta, Tipo: models.TipoMensajeDudaMensaje}, autoridadID)
+}
+
+// VerificarEdicion comprueba que el usuario pueda aplicar cambios a una duda. El personal UTEQ puede
+// cambiarlo todo. El estudiante autor solo cambia el contenido: el estudiante, la autoridad y la privacidad
+// que omite conservan su valor actual y, si los cambia, recibe ErrReasignacionNoPermitida; tampoco puede
+// cambiar la respuesta. Los demás usuarios no pueden editarla.
+func (s *DudasService) VerificarEdicion(dudaID, usuarioID uint, cambios *models.Dudas) error {
+	duda, p, err := s.dudaYParticipante(dudaID, usuarioID)
+	if err != nil {
+		return err
+	}
+	if p.rol == models.RolDudaPersonal {
+		return nil
+	}
+	if cambios.EstudianteID == 0 {
+		cambios.EstudianteID = duda.EstudianteID
+	}
+	if cambios.AutoridadUTEQID == nil {
+		cambios.AutoridadUTEQID = duda.AutoridadUTEQID
+	}
+	if strings.TrimSpace(cambios.Privacidad) == "" {
+		cambios.Privacidad = duda.Privacidad
+	}
+	if cambios.EstudianteID != duda.EstudianteID || !mismoID(cambios.AutoridadUTEQID, duda.AutoridadUTEQID) ||
+		cambios.Privacidad != duda.Privacidad {
+		return ErrReasignacionNoPermitida
+	}
+	if cambios.Respuesta != nil && strings.TrimSpace(*cambios.Respuesta) != "" &&
+		(duda.Respuesta == nil || strings.TrimSpace(*cambios.Respuesta) != strings.TrimSpace(*duda.Respuesta)) {
+		return ErrRespuestaNoPermitida
+	}
+	return nil
+}
+
+// VerificarEliminacion comprueba que el usuario sea el estudiante autor de la duda o personal UTEQ
+func (s *DudasService) VerificarEliminacion(dudaID, usuarioID uint) error {
+	_, _, err := s.dudaYParticipante(dudaID, usuarioID)
+	return err
+}
+
+// EditarMensaje cambia el contenido de un mensaje propio y guarda la versión anterior en el historial
+func (s *DudasService) EditarMensaje(dudaID, mensajeID, usuarioID uint, contenido string) (*models.MensajeDuda, error) {
+	mensaje, _, err := s.mensajeVisible(dudaID, mensajeID, usuarioID)
+	if err != nil {
+		return nil, err
+	}
+	if mensaje.UsuarioID != usuarioID {
+		return nil, ErrEdicionNoPermitida
+	}
+	contenido, err = validarContenidoMensaje(contenido)
+	if err != nil {
+		return nil, err
+	}
+	if contenido == mensaje.Contenido {
+		return mensaje, nil
+	}
+
+	edicion := &models.EdicionMensajeDuda{
+		MensajeDudaID:     mensaje.ID,
+		ContenidoAnterior: mensaje.Contenido,
+		EditadoPorID:      usuarioID,
+	}
+	ahora := time.Now()
+	mensaje.Contenido = contenido
+	mensaje.EditadoEn = &ahora
+	if err := s.dudasRepo.EditarMensaje(mensaje, edicion); err != nil {
+		return nil, err
+	}
+	return s.dudasRepo.GetMensajeByID(mensaje.ID)
+}
+
+// HistorialMensaje devuelve un mensaje con sus versiones anteriores
+func (s *DudasService) HistorialMensaje(dudaID, mensajeID, usuarioID uint) (*models.MensajeDuda, error) {
+	mensaje, _, err := s.mensajeVisible(dudaID, mensajeID, usuarioID)
+	return mensaje, err
+}
+
+// MarcarLeida marca todo el hilo como leído para el usuario
+func (s *DudasService) MarcarLeida(dudaID, usuarioID uint) error {
+	if _, _, err := s.dudaYParticipante(dudaID, usuarioID); err != nil {
+		return err
+	}
+	return s.dudasRepo.MarcarLeida(dudaID, usuarioID, time.Now())
+}
+
+// MarcarNoLeida vuelve a marcar el hilo como no leído para el usuario
+func (s *DudasService) MarcarNoLeida(dudaID, usuarioID uint) error {
+	if _, _, err := s.dudaYParticipante(dudaID, usuarioID); err != nil {
+		return err
+	}
+	return s.dudasRepo.MarcarNoLeida(dudaID, usuarioID)
+}
+
+// NoLeidas cuenta los mensajes no leídos de las dudas propias (estudiante) o asignadas (autoridad)
+func (s *DudasService) NoLeidas(usuarioID uint) ([]repositories.ConteoNoLeidos, error) {
+	usuario, err := s.usuarioRepo.GetUsuarioByID(usuarioID)
+	if err != nil {
+		return nil, err
+	}
+	visor := s.visorDe(usuario)
+
+	var dudas []models.Dudas
+	incluirNotas := false
+	if visor.EstudianteID != nil {
+		if dudas, err = s.dudasRepo.GetDudasByEstudiante(visor, *visor.EstudianteID); err != nil {
+			return nil, err
+		}
+	} else if visor.AutoridadID != nil {
+		if dudas, err = s.dudasRepo.GetDudasByAutoridad(visor, *visor.AutoridadID); err != nil {
+			return nil, err
+		}
+		incluirNotas = true
+	}
+
+	ids := make([]uint, 0, len(dudas))
+	for _, d := range dudas {
+		ids = append(ids, d.ID)
+	}
+	return s.dudasRepo.ContarNoLeidos(usuarioID, ids, incluirNotas)
+}
+
+// Visor devuelve las reglas de visibilidad de dudas que corresponden a un usuario
+func (s *DudasService) Visor(usuarioID uint) (repositories.VisorDudas, error) {
+	usuario, err := s.usuarioRepo.GetUsuarioByID(usuarioID)
+	if err != nil {
+		return repositories.VisorDudas{}, ErrSinAccesoDuda
+	}
+	return s.visorDe(usuario), nil
+}
+
+func (s *DudasService) visorDe(usuario *models.Usuario) repositories.VisorDudas {
+	visor := repositories.VisorDudas{Administrador: usuario.TipoUsuario.EsAdministrador()}
+	if estudiante, err := s.estudianteRepo.GetEstudianteByPersona(usuario.PersonaID); err == nil {
+		visor.EstudianteID = &estudiante.ID
+	}
+	if autoridad, err := s.autoridadRepo.GetAutoridadUTEQByPersona(usuario.PersonaID); err == nil {
+		visor.AutoridadID = &autoridad.ID
+	}
+	return visor
+}
+
+func (s *DudasService) crearMensaje(dudaID uint, p *participanteDuda, req MensajeDudaRequest, autoridadID *uint) (*models.MensajeDuda, error) {
+	contenido, err := validarContenidoMensaje(req.Contenido)
+	if err != nil {
+		return nil, err
+	}
+	tipo := strings.TrimSpace(req.Tipo)
+	switch tipo {
+	case "":
+		tipo = models.TipoMensajeDudaMensaje
+	case models.TipoMensajeDudaMensaje:
+	case models.TipoMensajeDudaNotaInterna:
+		if p.rol != models.RolDudaPersonal {
+			return nil, ErrNotaInternaNoPermitida
+		}
+	default:
+		return nil, errors.New("el tipo debe ser 'mensaje' o 'nota_interna'")
+	}
+
+	mensaje := &models.MensajeDuda{
+		DudaID:    dudaID,
+		UsuarioID: p.usuario.ID,
+		PersonaID: p.usuario.PersonaID,
+		RolAutor:  p.rol,
+		Tipo:      tipo,
+		Contenido: contenido,
+	}
+	if err := s.dudasRepo.CreateMensaje(mensaje, autoridadID); err != nil {
+		return nil, err
+	}
+	// Quien escribe ya leyó el hilo hasta su propio mensaje
+	if err := s.dudasRepo.MarcarLeida(dudaID, p.usuario.ID, mensaje.CreatedAt); err != nil {
+		return nil, err
+	}
+	return s.dudasRepo.GetMensajeByID(mensaje.ID)
+}
+
+// dudaYParticipante carga la duda si el usuario puede verla y determina su rol: el estudiante que la creó,
+// o personal UTEQ (autoridades y administradores). Los demás usuarios no participan en el hilo.
+func (s *DudasService) dudaYParticipante(dudaID, usuarioID uint) (*models.Dudas, *participanteDuda, error) {
+	usuario, err := s.usuarioRepo.GetUsuarioByID(usuarioID)
+	if err != nil {
+		return nil, nil, ErrSinAccesoDuda
+	}
+	visor := s.visorDe(usuario)
+	duda, err := s.dudasRepo.GetDudasByID(visor, dudaID)
+	if err != nil {
+		return nil, nil, ErrDudaNoEncontrada
+	}
+
+	p := &participanteDuda{usuario: usuario, autoridadID: visor.AutoridadID}
+	if visor.EstudianteID != nil && *visor.EstudianteID == duda.EstudianteID {
+		p.rol = models.RolDudaEstudiante
+		return duda, p, nil
+	}
+	if visor.EstudianteID != nil || (visor.AutoridadID == nil && !visor.Administrador) {
+		return nil, nil, ErrSinAccesoDuda
+	}
+	p.rol = models.RolDudaPersonal
+	return duda, p, nil
+}
+
+// mensajeVisible obtiene un mensaje de la duda; las notas internas no existen para el estudiante
+func (s *DudasService) mensajeVisible(dudaID, mensajeID, usuarioID uint) (*models.MensajeDuda, *participanteDuda, error) {
+	_, p, err := s.dudaYParticipante(dudaID, usuarioID)
+	if err != nil {
+		return nil, nil, err
+	}
+	mensaje, err := s.dudasRepo.GetMensajeByID(mensajeID)
+	if err != nil || mensaje.DudaID != dudaID {
+		return nil, nil, ErrMensajeNoEncontrado
+	}
+	if mensaje.Tipo == models.TipoMensajeDudaNotaInterna && p.rol != models.RolDudaPersonal {
+		return nil, nil, ErrMensajeNoEncontrado
+	}
+	return mensaje, p, nil
+}
+
+func validarContenidoMensaje(contenido string) (string, error) {
+	contenido = strings.TrimSpace(contenido)
+	if contenido == "" {
+		return "", errors.New("el contenido del mensaje es requerido")
+	}
+	if utf8.RuneCountInString(contenido) > maxLongitudMensajeDuda {
+		return "", fmt.Errorf("el mensaje no puede exceder %d caracteres", maxLongitudMensajeDuda)
+	}
+	return contenido, nil
+}
@@ -0,0 +1,181 @@
+package services
+
+import (
+	"ApiEscuela/models"
+	"ApiEscuela/repositories"
+	"testing"
+)
+
+func TestVisorSegunElUsuario(t *testing.T) {
+	estudiante := &models.Estudiante{PersonaID: 10}
+	estudiante.ID = 7
+	autoridad := &models.AutoridadUTEQ{PersonaID: 10}
+	autoridad.ID = 3
+	usuario := func(tipo string) *models.Usuario {
+		u := &models.Usuario{PersonaID: 10, TipoUsuario: models.TipoUsuario{Nombre: tipo}}
+		u.ID = 1
+		return u
+	}
+
+	casos := []struct {
+		nombre        string
+		filas         map[string]interface{}
+		estudianteID  uint
+		autoridadID   uint
+		administrador bool
+	}{
+		{"estudiante autor", map[string]interface{}{"Usuario": usuario("Estudiante"), "Estudiante": estudiante}, 7, 0, false},
+		{"autoridad asignada", map[string]interface{}{"Usuario": usuario("Autoridad"), "AutoridadUTEQ": autoridad}, 0, 3, false},
+		{"administrador", map[string]interface{}{"Usuario": usuario(models.NombreTipoUsuarioAdministrador)}, 0, 0, true},
+		{"usuario sin estudiante ni autoridad", map[string]interface{}{"Usuario": usuario("Estudiante universitario")}, 0, 0, false},
+	}
+	for _, caso := range casos {
+		t.Run(caso.nombre, func(t *testing.T) {
+			db := bdSimulada(t, caso.filas)
+			servicio := NewDudasService(repositories.NewDudasRepository(db), repositories.NewUsuarioRepository(db),
+				repositories.NewEstudianteRepository(db), repositories.NewAutoridadUTEQRepository(db))
+
+			visor, err := servicio.Visor(1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if visor.Administrador != caso.administrador {
+				t.Errorf("administrador=%v, se esperaba %v", visor.Administrador, caso.administrador)
+			}
+			if id := valorID(visor.EstudianteID); id != caso.estudianteID {
+				t.Errorf("estudiante %d, se esperaba %d", id, caso.estudianteID)
+			}
+			if id := valorID(visor.AutoridadID); id != caso.autoridadID {
+				t.Errorf("autoridad %d, se esperaba %d", id, caso.autoridadID)
+			}
+		})
+	}
+
+	t.Run("usuario inexistente", func(t *testing.T) {
+		db := bdSimulada(t, nil)
+		servicio := NewDudasService(repositories.NewDudasRepository(db), repositories.NewUsuarioRepository(db),
+			repositories.NewEstudianteRepository(db), repositories.NewAutoridadUTEQRepository(db))
+		if _, err := servicio.Visor(1); err != ErrSinAccesoDuda {
+			t.Errorf("error %v, se esperaba ErrSinAccesoDuda", err)
+		}
+	})
+}
+
+func valorID(id *uint) uint {
+	if id == nil {
+		return 0
+	}
+	return *id
+}
+
+func TestParticipantesDelHilo(t *testing.T) {
+	duda := &models.Dudas{EstudianteID: 7, Privacidad: "publico"}
+	duda.ID = 20
+	estudiante := func(id uint) *models.Estudiante {
+		e := &models.Estudiante{PersonaID: 10}
+		e.ID = id
+		return e
+	}
+	autoridad := &models.AutoridadUTEQ{PersonaID: 10}
+	autoridad.ID = 3
+	usuario := func(tipo string) *models.Usuario {
+		u := &models.Usuario{PersonaID: 10, TipoUsuario: models.TipoUsuario{Nombre: tipo}}
+		u.ID = 1
+		return u
+	}
+
+	casos := []struct {
+		nombre string
+		filas  map[string]interface{}
+		rol    string // Vacío si no participa
+	}{
+		{"estudiante autor", map[string]interface{}{"Usuario": usuario("Estudiante"), "Estudiante": estudiante(7)}, models.RolDudaEstudiante},
+		{"otro estudiante", map[string]interface{}{"Usuario": usuario("Estudiante"), "Estudiante": estudiante(8)}, ""},
+		{"autoridad", map[string]interface{}{"Usuario": usuario("Autoridad"), "AutoridadUTEQ": autoridad}, models.RolDudaPersonal},
+		{"administrador", map[string]interface{}{"Usuario": usuario(models.NombreTipoUsuarioAdministrador)}, models.RolDudaPersonal},
+		{"estudiante universitario", map[string]interface{}{"Usuario": usuario("Estudiante universitario")}, ""},
+	}
+	for _, caso := range casos {
+		t.Run(caso.nombre, func(t *testing.T) {
+			caso.filas["Dudas"] = duda
+			db := bdSimulada(t, caso.filas)
+			servicio := NewDudasService(repositories.NewDudasRepository(db), repositories.NewUsuarioRepository(db),
+				repositories.NewEstudianteRepository(db), repositories.NewAutoridadUTEQRepository(db))
+
+			_, p, err := servicio.dudaYParticipante(20, 1)
+			if caso.rol == "" {
+				if err != ErrSinAccesoDuda {
+					t.Errorf("error %v, se esperaba ErrSinAccesoDuda", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if p.rol != caso.rol {
+				t.Errorf("rol %q, se esperaba %q", p.rol, caso.rol)
+			}
+		})
+	}
+}
+
+func TestVerificarEdicionDeDudas(t *testing.T) {
+	id := func(v uint) *uint { return &v }
+	texto := func(v string) *string { return &v }
+	duda := &models.Dudas{EstudianteID: 7, Privacidad: "publico", AutoridadUTEQID: id(3)}
+	duda.ID = 20
+	estudiante := func(id uint) *models.Estudiante {
+		e := &models.Estudiante{PersonaID: 10}
+		e.ID = id
+		return e
+	}
+	usuario := func(tipo string) *models.Usuario {
+		u := &models.Usuario{PersonaID: 10, TipoUsuario: models.TipoUsuario{Nombre: tipo}}
+		u.ID = 1
+		return u
+	}
+	autor := map[string]interface{}{"Usuario": usuario("Estudiante"), "Estudiante": estudiante(7)}
+	otro := map[string]interface{}{"Usuario": usuario("Estudiante"), "Estudiante": estudiante(8)}
+	admin := map[string]interface{}{"Usuario": usuario(models.NombreTipoUsuarioAdministrador)}
+
+	casos := []struct {
+		nombre  string
+		filas   map[string]interface{}
+		cambios models.Dudas
+		err     error
+	}{
+		{"autor cambia la pregunta", autor, models.Dudas{Pregunta: "¿Cuándo abren las inscripciones?"}, nil},
+		{"autor se reasigna la autoridad", autor, models.Dudas{AutoridadUTEQID: id(4)}, ErrReasignacionNoPermitida},
+		{"autor cambia la privacidad", autor, models.Dudas{Privacidad: "privado"}, ErrReasignacionNoPermitida},
+		{"autor cambia el estudiante", autor, models.Dudas{EstudianteID: 8}, ErrReasignacionNoPermitida},
+		{"autor responde", autor, models.Dudas{Respuesta: texto("Ya está resuelto")}, ErrRespuestaNoPermitida},
+		{"otro estudiante", otro, models.Dudas{Pregunta: "Pregunta cambiada por otro"}, ErrSinAccesoDuda},
+		{"administrador reasigna", admin, models.Dudas{EstudianteID: 8, AutoridadUTEQID: id(4), Privacidad: "privado"}, nil},
+	}
+	for _, caso := range casos {
+		t.Run(caso.nombre, func(t *testing.T) {
+			caso.filas["Dudas"] = duda
+			db := bdSimulada(t, caso.filas)
+			servicio := NewDudasService(repositories.NewDudasRepository(db), repositories.NewUsuarioRepository(db),
+				repositories.NewEstudianteRepository(db), repositories.NewAutoridadUTEQRepository(db))
+
+			cambios := caso.cambios
+			if err := servicio.VerificarEdicion(20, 1, &cambios); err != caso.err {
+				t.Fatalf("error %v, se esperaba %v", err, caso.err)
+			}
+		})
+	}
+
+	t.Run("lo que el autor omite conserva su valor", func(t *testing.T) {
+		db := bdSimulada(t, map[string]interface{}{"Usuario": usuario("Estudiante"), "Estudiante": estudiante(7), "Dudas": duda})
+		servicio := NewDudasService(repositories.NewDudasRepository(db), repositories.NewUsuarioRepository(db),
+			repositories.NewEstudianteRepository(db), repositories.NewAutoridadUTEQRepository(db))
+		cambios := models.Dudas{Pregunta: "¿Cuándo abren las inscripciones?"}
+		if err := servicio.VerificarEdicion(20, 1, &cambios); err != nil {
+			t.Fatal(err)
+		}
+		if cambios.EstudianteID != 7 || valorID(cambios.AutoridadUTEQID) != 3 || cambios.Privacidad != "publico" {
+			t.Errorf("cambios = %+v, se esperaban los valores actuales de la duda", cambios)
+		}
+	})
+}
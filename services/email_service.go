@@ -0,0 +1,135 @@
+package services
+
+import (
+	"ApiEscuela/config"
+	"ApiEscuela/logging"
+	"ApiEscuela/metricas"
+	"ApiEscuela/trazas"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"log/slog"
+	"net/smtp"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// AdjuntoCorreo representa un archivo adjunto de un correo
+type AdjuntoCorreo struct {
+	Nombre      string
+	ContentType string
+	Contenido   []byte
+}
+
+type EmailService struct {
+	smtp   config.SMTP
+	envios sync.WaitGroup
+}
+
+func NewEmailService(smtp config.SMTP) *EmailService {
+	return &EmailService{smtp: smtp}
+}
+
+// Remitente es la dirección desde la que se envían los correos
+func (s *EmailService) Remitente() string {
+	return s.smtp.Remitente
+}
+
+// Enviar envía un correo usando SMTP con contenido HTML y adjuntos opcionales. El span del envío
+// queda dentro de la traza de ctx.
+func (s *EmailService) Enviar(ctx context.Context, to, subject, htmlBody string, adjuntos ...AdjuntoCorreo) error {
+	host, port, from, fromName := s.smtp.Host, s.smtp.Puerto, s.smtp.Remitente, s.smtp.NombreRemitente
+
+	// Verificar que todas las variables estén definidas
+	if !s.smtp.Completa() {
+		metricas.RegistrarCorreo("sin_configuracion")
+		return fmt.Errorf("configuración SMTP incompleta. Verifique las variables de entorno: SMTP_HOST, SMTP_PORT, SMTP_USER, SMTP_PASS, SMTP_FROM, SMTP_FROM_NAME")
+	}
+
+	addr := s.smtp.Direccion()
+	auth := smtp.PlainAuth("", s.smtp.Usuario, s.smtp.Clave, host)
+
+	// Mensaje MIME
+	headers := make(map[string]string)
+	headers["From"] = fmt.Sprintf("%s <%s>", fromName, from)
+	headers["To"] = to
+	headers["Subject"] = subject
+	headers["MIME-Version"] = "1.0"
+
+	var msgBuilder strings.Builder
+	if len(adjuntos) == 0 {
+		headers["Content-Type"] = "text/html; charset=\"UTF-8\""
+		for k, v := range headers {
+			msgBuilder.WriteString(fmt.Sprintf("%s: %s\r\n", k, v))
+		}
+		msgBuilder.WriteString("\r\n")
+		msgBuilder.WriteString(htmlBody)
+	} else {
+		boundary := fmt.Sprintf("apiescuela-%d", time.Now().UnixNano())
+		headers["Content-Type"] = fmt.Sprintf("multipart/mixed; boundary=\"%s\"", boundary)
+		for k, v := range headers {
+			msgBuilder.WriteString(fmt.Sprintf("%s: %s\r\n", k, v))
+		}
+		msgBuilder.WriteString("\r\n")
+
+		msgBuilder.WriteString("--" + boundary + "\r\n")
+		msgBuilder.WriteString("Content-Type: text/html; charset=\"UTF-8\"\r\n\r\n")
+		msgBuilder.WriteString(htmlBody + "\r\n")
+
+		for _, adjunto := range adjuntos {
+			msgBuilder.WriteString("--" + boundary + "\r\n")
+			msgBuilder.WriteString(fmt.Sprintf("Content-Type: %s; name=\"%s\"\r\n", adjunto.ContentType, adjunto.Nombre))
+			msgBuilder.WriteString(fmt.Sprintf("Content-Disposition: attachment; filename=\"%s\"\r\n", adjunto.Nombre))
+			msgBuilder.WriteString("Content-Transfer-Encoding: base64\r\n\r\n")
+			codificado := base64.StdEncoding.EncodeToString(adjunto.Contenido)
+			for len(codificado) > 76 {
+				msgBuilder.WriteString(codificado[:76] + "\r\n")
+				codificado = codificado[76:]
+			}
+			msgBuilder.WriteString(codificado + "\r\n")
+		}
+		msgBuilder.WriteString("--" + boundary + "--\r\n")
+	}
+
+	slog.Debug("enviando correo", "servidor", addr, "asunto", subject)
+	// No se registra el destinatario: es un dato personal
+	_, span := trazas.Tracer().Start(ctx, "smtp.enviar", trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("server.address", host),
+			attribute.String("server.port", port),
+			attribute.Int("smtp.adjuntos", len(adjuntos)),
+			attribute.Int("smtp.tamano_bytes", msgBuilder.Len()),
+		))
+	defer span.End()
+	err := smtp.SendMail(addr, auth, from, []string{to}, []byte(msgBuilder.String()))
+	if err != nil {
+		span.SetStatus(codes.Error, logging.OcultarTexto(err.Error()))
+		metricas.RegistrarCorreo("error")
+	} else {
+		metricas.RegistrarCorreo("enviado")
+	}
+	return err
+}
+
+// EnviarEnSegundoPlano envía el correo sin bloquear la petición; los errores solo se registran en el log.
+// El envío sigue en la traza de ctx pero no se cancela cuando termina la petición.
+func (s *EmailService) EnviarEnSegundoPlano(ctx context.Context, to, subject, htmlBody string, adjuntos ...AdjuntoCorreo) {
+	ctx = context.WithoutCancel(ctx)
+	s.envios.Add(1)
+	go func() {
+		defer s.envios.Done()
+		if err := s.Enviar(ctx, to, subject, htmlBody, adjuntos...); err != nil {
+			slog.ErrorContext(ctx, "no se pudo enviar el correo", "asunto", subject, "correo", to, "error", err)
+		}
+	}()
+}
+
+// Esperar bloquea hasta que terminen los correos enviados en segundo plano
+func (s *EmailService) Esperar() {
+	s.envios.Wait()
+}
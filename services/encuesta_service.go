@@ -0,0 +1,668 @@
+package services
+
+import (
+	"ApiEscuela/models"
+	"ApiEscuela/repositories"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"html"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Escala de las preguntas likert
+const (
+	likertMin = 1
+	likertMax = 5
+)
+
+// vigenciaInvitacionEncuesta es el tiempo durante el cual el enlace de la encuesta puede usarse
+const vigenciaInvitacionEncuesta = 30 * 24 * time.Hour
+
+// maxLongitudTextoEncuesta limita las respuestas libres
+const maxLongitudTextoEncuesta = 2000
+
+var (
+	ErrEncuestaNoEncontrada  = errors.New("encuesta no encontrada")
+	ErrEncuestaConRespuestas = errors.New("la encuesta ya tiene respuestas; no se pueden cambiar sus preguntas")
+	ErrVisitaYaCompletada    = errors.New("la visita ya fue marcada como completada")
+	ErrVisitaNoFinalizada    = errors.New("la visita aún no ha comenzado")
+	ErrInvitacionInvalida    = errors.New("el enlace de la encuesta no es válido o expiró")
+	ErrInvitacionUsada       = errors.New("la encuesta ya fue respondida con este enlace")
+)
+
+type EncuestaService struct {
+	encuestaRepo      *repositories.EncuestaRepository
+	programaRepo      *repositories.ProgramaVisitaRepository
+	visitaDetalleRepo *repositories.VisitaDetalleRepository
+	estudianteRepo    *repositories.EstudianteRepository
+	actividadRepo     *repositories.ActividadRepository
+	emailService      *EmailService
+	urlEncuestas      string // ENCUESTAS_URL_BASE; vacía si los enlaces apuntan a la API
+}
+
+func NewEncuestaService(
+	encuestaRepo *repositories.EncuestaRepository,
+	programaRepo *repositories.ProgramaVisitaRepository,
+	visitaDetalleRepo *repositories.VisitaDetalleRepository,
+	estudianteRepo *repositories.EstudianteRepository,
+	actividadRepo *repositories.ActividadRepository,
+	emailService *EmailService,
+	urlEncuestas string,
+) *EncuestaService {
+	return &EncuestaService{
+		encuestaRepo:      encuestaRepo,
+		programaRepo:      programaRepo,
+		visitaDetalleRepo: visitaDetalleRepo,
+		estudianteRepo:    estudianteRepo,
+		actividadRepo:     actividadRepo,
+		emailService:      emailService,
+		urlEncuestas:      urlEncuestas,
+	}
+}
+
+// ConContexto devuelve una copia del servicio cuyos repositorios usan ctx, para que las consultas queden
+// dentro de la traza de la petición
+func (s *EncuestaService) ConContexto(ctx context.Context) *EncuestaService {
+	copia := *s
+	copia.encuestaRepo = s.encuestaRepo.ConContexto(ctx)
+	copia.programaRepo = s.programaRepo.ConContexto(ctx)
+	copia.visitaDetalleRepo = s.visitaDetalleRepo.ConContexto(ctx)
+	copia.estudianteRepo = s.estudianteRepo.ConContexto(ctx)
+	copia.actividadRepo = s.actividadRepo.ConContexto(ctx)
+	return &copia
+}
+
+// PreguntaRequest representa una pregunta al crear o editar una encuesta
+type PreguntaRequest struct {
+	Texto     string   `json:"texto"`
+	Tipo      string   `json:"tipo"`
+	Opciones  []string `json:"opciones"`
+	Requerida *bool    `json:"requerida"`
+}
+
+// EncuestaRequest representa los datos de una encuesta
+type EncuestaRequest struct {
+	Titulo           string            `json:"titulo"`
+	Descripcion      string            `json:"descripcion"`
+	ProgramaVisitaID *uint             `json:"programa_visita_id"`
+	ActividadID      *uint             `json:"actividad_id"`
+	Activa           *bool             `json:"activa"`
+	Preguntas        []PreguntaRequest `json:"preguntas"`
+}
+
+// CompletarVisitaRequest indica qué estudiantes de la institución asistieron a la visita y, opcionalmente,
+// correos de otros participantes
+type CompletarVisitaRequest struct {
+	Asistentes []uint   `json:"asistentes"`
+	Correos    []string `json:"correos"`
+}
+
+// ResultadoCompletarVisita resume las invitaciones enviadas al completar una visita
+type ResultadoCompletarVisita struct {
+	ProgramaVisitaID uint      `json:"programa_visita_id"`
+	CompletadaEn     time.Time `json:"completada_en"`
+	Asistentes       int       `json:"asistentes"`
+	Encuestas        int       `json:"encuestas"`
+	Participantes    int       `json:"participantes"`
+}
+
+// RespuestaItemRequest es la respuesta a una pregunta
+type RespuestaItemRequest struct {
+	PreguntaID uint   `json:"pregunta_id"`
+	Valor      *int   `json:"valor"`
+	Opcion     string `json:"opcion"`
+	Texto      string `json:"texto"`
+}
+
+// RespuestaEncuestaRequest contiene las respuestas de un participante
+type RespuestaEncuestaRequest struct {
+	Respuestas []RespuestaItemRequest `json:"respuestas"`
+}
+
+// ResultadoPregunta agrega las respuestas de una pregunta
+type ResultadoPregunta struct {
+	PreguntaID   uint             `json:"pregunta_id"`
+	Texto        string           `json:"texto"`
+	Tipo         string           `json:"tipo"`
+	Respuestas   int64            `json:"respuestas"`
+	Promedio     *float64         `json:"promedio,omitempty"`
+	Distribucion map[string]int64 `json:"distribucion,omitempty"`
+	Textos       []string         `json:"textos,omitempty"`
+}
+
+// ResultadoEncuesta agrega todas las respuestas de una encuesta
+type ResultadoEncuesta struct {
+	EncuestaID      uint                `json:"encuesta_id"`
+	Titulo          string              `json:"titulo"`
+	TotalRespuestas int64               `json:"total_respuestas"`
+	Preguntas       []ResultadoPregunta `json:"preguntas"`
+}
+
+// ResumenSatisfaccion es el promedio likert de las encuestas de una actividad o temática
+type ResumenSatisfaccion struct {
+	ID         uint     `json:"id"`
+	Nombre     string   `json:"nombre"`
+	TematicaID uint     `json:"tematica_id,omitempty"`
+	Respuestas int64    `json:"respuestas"`
+	Promedio   *float64 `json:"promedio"`
+}
+
+// CrearEncuesta valida y guarda una encuesta con sus preguntas
+func (s *EncuestaService) CrearEncuesta(req EncuestaRequest) (*models.Encuesta, error) {
+	encuesta := &models.Encuesta{Activa: true}
+	if err := aplicarEncuesta(encuesta, req); err != nil {
+		return nil, err
+	}
+	preguntas, err := construirPreguntas(req.Preguntas)
+	if err != nil {
+		return nil, err
+	}
+	if len(preguntas) == 0 {
+		return nil, errors.New("la encuesta debe tener al menos una pregunta")
+	}
+	encuesta.Preguntas = preguntas
+	if err := s.encuestaRepo.CreateEncuesta(encuesta); err != nil {
+		return nil, err
+	}
+	return s.encuestaRepo.GetEncuestaByID(encuesta.ID)
+}
+
+// ActualizarEncuesta modifica una encuesta. Las preguntas solo se reemplazan si se envían
+// y la encuesta aún no tiene respuestas.
+func (s *EncuestaService) ActualizarEncuesta(id uint, req EncuestaRequest) (*models.Encuesta, error) {
+	encuesta, err := s.encuestaRepo.GetEncuestaByID(id)
+	if err != nil {
+		return nil, ErrEncuestaNoEncontrada
+	}
+	if err := aplicarEncuesta(encuesta, req); err != nil {
+		return nil, err
+	}
+
+	var preguntas []models.PreguntaEncuesta
+	if req.Preguntas != nil {
+		total, err := s.encuestaRepo.CountRespuestas(id)
+		if err != nil {
+			return nil, err
+		}
+		if total > 0 {
+			return nil, ErrEncuestaConRespuestas
+		}
+		if preguntas, err = construirPreguntas(req.Preguntas); err != nil {
+			return nil, err
+		}
+		if len(preguntas) == 0 {
+			return nil, errors.New("la encuesta debe tener al menos una pregunta")
+		}
+	}
+
+	if err := s.encuestaRepo.UpdateEncuesta(encuesta, preguntas); err != nil {
+		return nil, err
+	}
+	return s.encuestaRepo.GetEncuestaByID(id)
+}
+
+// CompletarVisita marca la visita como completada y envía a cada participante los enlaces
+// de las encuestas activas del programa y de sus actividades
+func (s *EncuestaService) CompletarVisita(ctx context.Context, programaID uint, req CompletarVisitaRequest, urlBase string) (*ResultadoCompletarVisita, error) {
+	programa, err := s.programaRepo.GetProgramaVisitaByID(programaID)
+	if err != nil {
+		return nil, ErrProgramaNoEncontrado
+	}
+	if programa.CompletadaEn != nil {
+		return nil, ErrVisitaYaCompletada
+	}
+	if programa.Fecha.After(time.Now()) {
+		return nil, ErrVisitaNoFinalizada
+	}
+
+	detalles, err := s.visitaDetalleRepo.GetVisitaDetallesByPrograma(programaID)
+	if err != nil {
+		return nil, err
+	}
+	actividadIDs := make([]uint, 0, len(detalles))
+	for _, d := range detalles {
+		actividadIDs = append(actividadIDs, d.ActividadID)
+	}
+	encuestas, err := s.encuestaRepo.GetEncuestasActivasParaPrograma(programaID, actividadIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	asistencias, correos, err := s.participantes(programa, req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Un token por participante y encuesta; solo se guarda su hash
+	ahora := time.Now()
+	enlaces := make(map[string][]string, len(correos))
+	var invitaciones []models.InvitacionEncuesta
+	for _, correo := range correos {
+		for _, encuesta := range encuestas {
+			token, err := generarTokenHex(32)
+			if err != nil {
+				return nil, err
+			}
+			invitaciones = append(invitaciones, models.InvitacionEncuesta{
+				EncuestaID:       encuesta.ID,
+				ProgramaVisitaID: programaID,
+				TokenHash:        hashTokenEncuesta(token),
+				ExpiraEn:         ahora.Add(vigenciaInvitacionEncuesta),
+			})
+			enlaces[correo] = append(enlaces[correo], fmt.Sprintf(`<li><a href="%s%s">%s</a></li>`,
+				urlEncuestas(urlBase, s.urlEncuestas), token, html.EscapeString(encuesta.Titulo)))
+		}
+	}
+
+	if err := s.encuestaRepo.CompletarPrograma(programaID, ahora, asistencias, invitaciones); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrVisitaYaCompletada
+		}
+		return nil, err
+	}
+
+	for correo, lista := range enlaces {
+		s.emailService.EnviarEnSegundoPlano(ctx, correo, "Cuéntanos cómo fue tu visita a la UTEQ",
+			fmt.Sprintf(`<p>Gracias por participar en la visita a la UTEQ del %s.</p><p>Nos ayudaría mucho conocer tu opinión. Las respuestas son anónimas:</p><ul>%s</ul><p>Los enlaces vencen en 30 días.</p>`,
+				programa.Fecha.Format("02/01/2006"), strings.Join(lista, "")))
+	}
+
+	participantes := len(correos)
+	if len(encuestas) == 0 {
+		participantes = 0
+	}
+	return &ResultadoCompletarVisita{
+		ProgramaVisitaID: programaID,
+		CompletadaEn:     ahora,
+		Asistentes:       len(asistencias),
+		Encuestas:        len(encuestas),
+		Participantes:    participantes,
+	}, nil
+}
+
+// ObtenerEncuestaPorToken devuelve la encuesta asociada a un enlace vigente y no usado
+func (s *EncuestaService) ObtenerEncuestaPorToken(token string) (*models.Encuesta, error) {
+	invitacion, err := s.invitacionVigente(token)
+	if err != nil {
+		return nil, err
+	}
+	encuesta, err := s.encuestaRepo.GetEncuestaByID(invitacion.EncuestaID)
+	if err != nil {
+		return nil, ErrInvitacionInvalida
+	}
+	return encuesta, nil
+}
+
+// Responder valida y guarda la respuesta anónima de un enlace
+func (s *EncuestaService) Responder(token string, req RespuestaEncuestaRequest) error {
+	invitacion, err := s.invitacionVigente(token)
+	if err != nil {
+		return err
+	}
+	encuesta, err := s.encuestaRepo.GetEncuestaByID(invitacion.EncuestaID)
+	if err != nil {
+		return ErrInvitacionInvalida
+	}
+
+	recibidas := make(map[uint]RespuestaItemRequest, len(req.Respuestas))
+	for _, r := range req.Respuestas {
+		recibidas[r.PreguntaID] = r
+	}
+
+	respuesta := &models.RespuestaEncuesta{EncuestaID: encuesta.ID, ProgramaVisitaID: invitacion.ProgramaVisitaID}
+	for _, pregunta := range encuesta.Preguntas {
+		item, ok := recibidas[pregunta.ID]
+		delete(recibidas, pregunta.ID)
+		valor, err := validarRespuesta(pregunta, item, ok)
+		if err != nil {
+			return err
+		}
+		if valor != nil {
+			valor.PreguntaEncuestaID = pregunta.ID
+			respuesta.Respuestas = append(respuesta.Respuestas, *valor)
+		}
+	}
+	if len(recibidas) > 0 {
+		return errors.New("hay respuestas para preguntas que no pertenecen a la encuesta")
+	}
+
+	if err := s.encuestaRepo.GuardarRespuesta(invitacion.ID, respuesta); err != nil {
+		if errors.Is(err, repositories.ErrInvitacionYaRespondida) {
+			return ErrInvitacionUsada
+		}
+		return err
+	}
+	return nil
+}
+
+// Resultados agrega las respuestas de una encuesta por pregunta
+func (s *EncuestaService) Resultados(encuestaID uint) (*ResultadoEncuesta, error) {
+	encuesta, err := s.encuestaRepo.GetEncuestaByID(encuestaID)
+	if err != nil {
+		return nil, ErrEncuestaNoEncontrada
+	}
+	total, err := s.encuestaRepo.CountRespuestas(encuestaID)
+	if err != nil {
+		return nil, err
+	}
+	conteos, err := s.encuestaRepo.GetConteosRespuestas(encuestaID)
+	if err != nil {
+		return nil, err
+	}
+	textos, err := s.encuestaRepo.GetTextosRespuestas(encuestaID)
+	if err != nil {
+		return nil, err
+	}
+
+	resultados := make(map[uint]*ResultadoPregunta, len(encuesta.Preguntas))
+	orden := make([]uint, 0, len(encuesta.Preguntas))
+	for _, p := range encuesta.Preguntas {
+		resultados[p.ID] = &ResultadoPregunta{PreguntaID: p.ID, Texto: p.Texto, Tipo: p.Tipo}
+		orden = append(orden, p.ID)
+	}
+
+	sumas := map[uint]float64{}
+	for _, c := range conteos {
+		r, ok := resultados[c.PreguntaEncuestaID]
+		if !ok {
+			continue
+		}
+		if r.Distribucion == nil {
+			r.Distribucion = map[string]int64{}
+		}
+		r.Respuestas += c.Total
+		if c.Valor != nil {
+			r.Distribucion[strconv.Itoa(*c.Valor)] += c.Total
+			sumas[c.PreguntaEncuestaID] += float64(*c.Valor) * float64(c.Total)
+		} else {
+			r.Distribucion[c.Opcion] += c.Total
+		}
+	}
+	for _, t := range textos {
+		if r, ok := resultados[t.PreguntaEncuestaID]; ok {
+			r.Textos = append(r.Textos, t.Texto)
+			r.Respuestas++
+		}
+	}
+
+	salida := &ResultadoEncuesta{EncuestaID: encuesta.ID, Titulo: encuesta.Titulo, TotalRespuestas: total}
+	for _, id := range orden {
+		r := resultados[id]
+		if r.Tipo == models.TipoPreguntaLikert && r.Respuestas > 0 {
+			promedio := redondear(sumas[id] / float64(r.Respuestas))
+			r.Promedio = &promedio
+		}
+		salida.Preguntas = append(salida.Preguntas, *r)
+	}
+	return salida, nil
+}
+
+// ResumenPorActividad devuelve el promedio likert de las encuestas de cada actividad
+func (s *EncuestaService) ResumenPorActividad() ([]ResumenSatisfaccion, error) {
+	datos, actividades, err := s.satisfaccionActividades()
+	if err != nil {
+		return nil, err
+	}
+	resumen := make([]ResumenSatisfaccion, 0, len(datos))
+	for _, d := range datos {
+		actividad := actividades[d.ActividadID]
+		resumen = append(resumen, ResumenSatisfaccion{
+			ID:         d.ActividadID,
+			Nombre:     actividad.Actividad,
+			TematicaID: actividad.TematicaID,
+			Respuestas: d.Respuestas,
+			Promedio:   promedioSatisfaccion(d.Suma, d.Valores),
+		})
+	}
+	sort.Slice(resumen, func(i, j int) bool { return resumen[i].ID < resumen[j].ID })
+	return resumen, nil
+}
+
+// ResumenPorTematica agrupa la satisfacción de las actividades por temática
+func (s *EncuestaService) ResumenPorTematica() ([]ResumenSatisfaccion, error) {
+	datos, actividades, err := s.satisfaccionActividades()
+	if err != nil {
+		return nil, err
+	}
+	acumulado := map[uint]*repositories.SatisfaccionActividad{}
+	nombres := map[uint]string{}
+	for _, d := range datos {
+		actividad, ok := actividades[d.ActividadID]
+		if !ok {
+			continue
+		}
+		a, ok := acumulado[actividad.TematicaID]
+		if !ok {
+			a = &repositories.SatisfaccionActividad{}
+			acumulado[actividad.TematicaID] = a
+			nombres[actividad.TematicaID] = actividad.Tematica.Nombre
+		}
+		a.Respuestas += d.Respuestas
+		a.Suma += d.Suma
+		a.Valores += d.Valores
+	}
+
+	resumen := make([]ResumenSatisfaccion, 0, len(acumulado))
+	for id, a := range acumulado {
+		resumen = append(resumen, ResumenSatisfaccion{
+			ID:         id,
+			Nombre:     nombres[id],
+			Respuestas: a.Respuestas,
+			Promedio:   promedioSatisfaccion(a.Suma, a.Valores),
+		})
+	}
+	sort.Slice(resumen, func(i, j int) bool { return resumen[i].ID < resumen[j].ID })
+	return resumen, nil
+}
+
+func (s *EncuestaService) satisfaccionActividades() ([]repositories.SatisfaccionActividad, map[uint]models.Actividad, error) {
+	datos, err := s.encuestaRepo.GetSatisfaccionPorActividad()
+	if err != nil {
+		return nil, nil, err
+	}
+	lista, err := s.actividadRepo.GetAllActividades()
+	if err != nil {
+		return nil, nil, err
+	}
+	actividades := make(map[uint]models.Actividad, len(lista))
+	for _, a := range lista {
+		actividades[a.ID] = a
+	}
+	return datos, actividades, nil
+}
+
+// participantes reúne, sin duplicados, los correos de quienes estuvieron en la visita: los estudiantes de la
+// institución registrados como asistentes, los estudiantes universitarios asignados al programa y los
+// correos adicionales. Devuelve también los registros de asistencia que se guardan al completarla.
+func (s *EncuestaService) participantes(programa *models.ProgramaVisita, req CompletarVisitaRequest) ([]models.AsistenciaVisita, []string, error) {
+	vistos := map[string]bool{}
+	var correos []string
+	agregar := func(correo string) {
+		correo = strings.ToLower(strings.TrimSpace(correo))
+		if correo == "" || vistos[correo] || !correoValido(correo) {
+			return
+		}
+		vistos[correo] = true
+		correos = append(correos, correo)
+	}
+
+	var asistencias []models.AsistenciaVisita
+	if len(req.Asistentes) > 0 {
+		estudiantes, err := s.estudianteRepo.GetEstudiantesByInstitucion(programa.InstitucionID)
+		if err != nil {
+			return nil, nil, err
+		}
+		deLaInstitucion := make(map[uint]*models.Estudiante, len(estudiantes))
+		for i := range estudiantes {
+			deLaInstitucion[estudiantes[i].ID] = &estudiantes[i]
+		}
+		registrados := map[uint]bool{}
+		for _, id := range req.Asistentes {
+			estudiante, ok := deLaInstitucion[id]
+			if !ok {
+				return nil, nil, fmt.Errorf("el estudiante %d no pertenece a la institución de la visita", id)
+			}
+			if registrados[id] {
+				continue
+			}
+			registrados[id] = true
+			asistencias = append(asistencias, models.AsistenciaVisita{ProgramaVisitaID: programa.ID, EstudianteID: id})
+			agregar(estudiante.Persona.Correo)
+		}
+	}
+
+	universitarios, err := s.encuestaRepo.GetEstudiantesUniversitariosDelPrograma(programa.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, e := range universitarios {
+		agregar(e.Persona.Correo)
+	}
+
+	for _, c := range req.Correos {
+		if !correoValido(strings.TrimSpace(c)) {
+			return nil, nil, fmt.Errorf("el correo %q no es válido", c)
+		}
+		agregar(c)
+	}
+	return asistencias, correos, nil
+}
+
+func (s *EncuestaService) invitacionVigente(token string) (*models.InvitacionEncuesta, error) {
+	invitacion, err := s.encuestaRepo.GetInvitacionByTokenHash(hashTokenEncuesta(token))
+	if err != nil || time.Now().After(invitacion.ExpiraEn) {
+		return nil, ErrInvitacionInvalida
+	}
+	if invitacion.RespondidaEn != nil {
+		return nil, ErrInvitacionUsada
+	}
+	return invitacion, nil
+}
+
+// aplicarEncuesta valida los datos generales y los copia en el modelo
+func aplicarEncuesta(encuesta *models.Encuesta, req EncuestaRequest) error {
+	req.Titulo = strings.TrimSpace(req.Titulo)
+	if req.Titulo == "" {
+		return errors.New("el título es requerido")
+	}
+	if (req.ProgramaVisitaID == nil) == (req.ActividadID == nil) {
+		return errors.New("indique programa_visita_id o actividad_id, no ambos")
+	}
+	encuesta.Titulo = req.Titulo
+	encuesta.Descripcion = strings.TrimSpace(req.Descripcion)
+	encuesta.ProgramaVisitaID = req.ProgramaVisitaID
+	encuesta.ActividadID = req.ActividadID
+	if req.Activa != nil {
+		encuesta.Activa = *req.Activa
+	}
+	return nil
+}
+
+func construirPreguntas(reqs []PreguntaRequest) ([]models.PreguntaEncuesta, error) {
+	preguntas := make([]models.PreguntaEncuesta, 0, len(reqs))
+	for i, p := range reqs {
+		texto := strings.TrimSpace(p.Texto)
+		if texto == "" {
+			return nil, fmt.Errorf("la pregunta %d no tiene texto", i+1)
+		}
+		pregunta := models.PreguntaEncuesta{Orden: i + 1, Texto: texto, Tipo: p.Tipo, Requerida: true}
+		if p.Requerida != nil {
+			pregunta.Requerida = *p.Requerida
+		}
+		switch p.Tipo {
+		case models.TipoPreguntaLikert, models.TipoPreguntaTexto:
+		case models.TipoPreguntaOpcionMultiple:
+			vistas := map[string]bool{}
+			for _, o := range p.Opciones {
+				o = strings.TrimSpace(o)
+				if o == "" || vistas[o] {
+					continue
+				}
+				vistas[o] = true
+				pregunta.Opciones = append(pregunta.Opciones, o)
+			}
+			if len(pregunta.Opciones) < 2 {
+				return nil, fmt.Errorf("la pregunta %d debe tener al menos dos opciones", i+1)
+			}
+		default:
+			return nil, fmt.Errorf("la pregunta %d tiene un tipo inválido; use likert, opcion_multiple o texto", i+1)
+		}
+		preguntas = append(preguntas, pregunta)
+	}
+	return preguntas, nil
+}
+
+// validarRespuesta verifica la respuesta a una pregunta; devuelve nil si se omitió una pregunta opcional
+func validarRespuesta(pregunta models.PreguntaEncuesta, item RespuestaItemRequest, recibida bool) (*models.RespuestaPregunta, error) {
+	vacia := !recibida
+	switch pregunta.Tipo {
+	case models.TipoPreguntaLikert:
+		vacia = vacia || item.Valor == nil
+	case models.TipoPreguntaOpcionMultiple:
+		vacia = vacia || strings.TrimSpace(item.Opcion) == ""
+	default:
+		vacia = vacia || strings.TrimSpace(item.Texto) == ""
+	}
+	if vacia {
+		if pregunta.Requerida {
+			return nil, fmt.Errorf("la pregunta \"%s\" es requerida", pregunta.Texto)
+		}
+		return nil, nil
+	}
+
+	switch pregunta.Tipo {
+	case models.TipoPreguntaLikert:
+		if *item.Valor < likertMin || *item.Valor > likertMax {
+			return nil, fmt.Errorf("la respuesta a \"%s\" debe estar entre %d y %d", pregunta.Texto, likertMin, likertMax)
+		}
+		return &models.RespuestaPregunta{Valor: item.Valor}, nil
+	case models.TipoPreguntaOpcionMultiple:
+		opcion := strings.TrimSpace(item.Opcion)
+		for _, o := range pregunta.Opciones {
+			if o == opcion {
+				return &models.RespuestaPregunta{Opcion: opcion}, nil
+			}
+		}
+		return nil, fmt.Errorf("la opción elegida en \"%s\" no es válida", pregunta.Texto)
+	default:
+		texto := strings.TrimSpace(item.Texto)
+		if len([]rune(texto)) > maxLongitudTextoEncuesta {
+			return nil, fmt.Errorf("la respuesta a \"%s\" supera los %d caracteres", pregunta.Texto, maxLongitudTextoEncuesta)
+		}
+		return &models.RespuestaPregunta{Texto: texto}, nil
+	}
+}
+
+// urlEncuestas usa base (ENCUESTAS_URL_BASE, p. ej. la página del frontend) o, si no está definida, la ruta
+// pública de la API
+func urlEncuestas(urlBase, base string) string {
+	if base != "" {
+		return strings.TrimRight(base, "/") + "/"
+	}
+	return strings.TrimRight(urlBase, "/") + "/encuestas/"
+}
+
+func hashTokenEncuesta(token string) string {
+	suma := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(suma[:])
+}
+
+func promedioSatisfaccion(suma float64, valores int64) *float64 {
+	if valores == 0 {
+		return nil
+	}
+	promedio := redondear(suma / float64(valores))
+	return &promedio
+}
+
+func redondear(valor float64) float64 {
+	return float64(int64(valor*100+0.5)) / 100
+}
@@ -0,0 +1,45 @@
+package services
+
+import (
+	"ApiEscuela/models"
+	"ApiEscuela/repositories"
+	"reflect"
+	"testing"
+)
+
+func TestParticipantesDeLaVisita(t *testing.T) {
+	estudiante := func(id uint, correo string) models.Estudiante {
+		e := models.Estudiante{InstitucionID: 4, Persona: models.Persona{Correo: correo}}
+		e.ID = id
+		return e
+	}
+	guia := models.EstudianteUniversitario{Persona: models.Persona{Correo: "guia@uteq.edu.ec"}}
+	guia.ID = 3
+	db := bdSimulada(t, map[string]interface{}{
+		"Estudiante":              []models.Estudiante{estudiante(1, "ana@colegio.edu.ec"), estudiante(2, "Luis@colegio.edu.ec")},
+		"EstudianteUniversitario": []models.EstudianteUniversitario{guia},
+	})
+	servicio := NewEncuestaService(repositories.NewEncuestaRepository(db), nil, nil, repositories.NewEstudianteRepository(db), nil, nil, "")
+	programa := &models.ProgramaVisita{InstitucionID: 4}
+	programa.ID = 7
+
+	asistencias, correos, err := servicio.participantes(programa, CompletarVisitaRequest{
+		Asistentes: []uint{2, 2},
+		Correos:    []string{"docente@colegio.edu.ec", "GUIA@uteq.edu.ec"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Quien no asistió (ana) no recibe la encuesta aunque sea de la institución
+	esperados := []string{"luis@colegio.edu.ec", "guia@uteq.edu.ec", "docente@colegio.edu.ec"}
+	if !reflect.DeepEqual(correos, esperados) {
+		t.Errorf("correos = %v, se esperaba %v", correos, esperados)
+	}
+	if len(asistencias) != 1 || asistencias[0].EstudianteID != 2 || asistencias[0].ProgramaVisitaID != 7 {
+		t.Errorf("asistencias = %+v, se esperaba solo el estudiante 2 en el programa 7", asistencias)
+	}
+
+	if _, _, err := servicio.participantes(programa, CompletarVisitaRequest{Asistentes: []uint{9}}); err == nil {
+		t.Error("se aceptó como asistente a un estudiante de otra institución")
+	}
+}
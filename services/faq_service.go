@@ -0,0 +1,253 @@
+package services
+
+import (
+	"ApiEscuela/models"
+	"ApiEscuela/repositories"
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+const (
+	// umbralSimilitudFAQ es la similitud mínima (coeficiente de Dice sobre palabras) para sugerir una entrada
+	umbralSimilitudFAQ = 0.35
+	maxSugerenciasFAQ  = 5
+	categoriaFAQPorDef = "General"
+)
+
+var (
+	ErrPreguntaFrecuenteNoEncontrada = errors.New("pregunta frecuente no encontrada")
+	ErrDudaNoPromovible              = errors.New("solo se pueden promover dudas públicas que ya tienen respuesta")
+	ErrDudaYaPromovida               = errors.New("la duda ya fue promovida al FAQ")
+	ErrGestionFAQNoPermitida         = errors.New("solo el personal de la UTEQ puede gestionar el FAQ")
+)
+
+// palabrasVaciasFAQ no aportan a la similitud entre preguntas
+var palabrasVaciasFAQ = map[string]bool{
+	"que": true, "como": true, "cual": true, "cuales": true, "cuando": true, "donde": true, "para": true,
+	"por": true, "los": true, "las": true, "del": true, "una": true, "uno": true, "unos": true, "unas": true,
+	"con": true, "sin": true, "hay": true, "puedo": true, "puede": true, "este": true, "esta": true,
+	"eso": true, "esa": true, "ese": true, "mas": true, "muy": true, "son": true, "sus": true, "mis": true,
+	"quien": true, "tengo": true, "tiene": true, "hacer": true, "sobre": true, "entre": true, "cuanto": true,
+}
+
+var reemplazoTildes = strings.NewReplacer("á", "a", "é", "e", "í", "i", "ó", "o", "ú", "u", "ü", "u", "ñ", "n")
+
+type FAQService struct {
+	faqRepo       *repositories.PreguntaFrecuenteRepository
+	dudasRepo     *repositories.DudasRepository
+	usuarioRepo   *repositories.UsuarioRepository
+	autoridadRepo *repositories.AutoridadUTEQRepository
+}
+
+func NewFAQService(
+	faqRepo *repositories.PreguntaFrecuenteRepository,
+	dudasRepo *repositories.DudasRepository,
+	usuarioRepo *repositories.UsuarioRepository,
+	autoridadRepo *repositories.AutoridadUTEQRepository,
+) *FAQService {
+	return &FAQService{faqRepo: faqRepo, dudasRepo: dudasRepo, usuarioRepo: usuarioRepo, autoridadRepo: autoridadRepo}
+}
+
+// ConContexto devuelve una copia del servicio cuyos repositorios usan ctx, para que las consultas queden
+// dentro de la traza de la petición
+func (s *FAQService) ConContexto(ctx context.Context) *FAQService {
+	copia := *s
+	copia.faqRepo = s.faqRepo.ConContexto(ctx)
+	copia.dudasRepo = s.dudasRepo.ConContexto(ctx)
+	copia.usuarioRepo = s.usuarioRepo.ConContexto(ctx)
+	copia.autoridadRepo = s.autoridadRepo.ConContexto(ctx)
+	return &copia
+}
+
+// PreguntaFrecuenteRequest representa los datos editables de una entrada del FAQ
+type PreguntaFrecuenteRequest struct {
+	Pregunta  string `json:"pregunta"`
+	Respuesta string `json:"respuesta"`
+	Categoria string `json:"categoria"`
+	Orden     *int   `json:"orden"`
+	Publicada *bool  `json:"publicada"`
+}
+
+// SugerenciaFAQ es una entrada del FAQ parecida a una pregunta nueva
+type SugerenciaFAQ struct {
+	models.PreguntaFrecuente
+	Similitud float64 `json:"similitud"`
+}
+
+// Promover crea una entrada del FAQ a partir de una duda pública respondida.
+// La redacción puede ajustarse; si no se envía se usan la pregunta y la última respuesta de la duda.
+func (s *FAQService) Promover(dudaID, usuarioID uint, req PreguntaFrecuenteRequest) (*models.PreguntaFrecuente, error) {
+	if err := s.verificarPersonal(usuarioID); err != nil {
+		return nil, err
+	}
+	duda, err := s.dudasRepo.GetDudasByID(repositories.VisorSistema, dudaID)
+	if err != nil {
+		return nil, ErrDudaNoEncontrada
+	}
+	if duda.Privacidad != "publico" || duda.Respuesta == nil || strings.TrimSpace(*duda.Respuesta) == "" {
+		return nil, ErrDudaNoPromovible
+	}
+	if _, err := s.faqRepo.GetPreguntaFrecuenteByDuda(dudaID); err == nil {
+		return nil, ErrDudaYaPromovida
+	}
+
+	if strings.TrimSpace(req.Pregunta) == "" {
+		req.Pregunta = duda.Pregunta
+	}
+	if strings.TrimSpace(req.Respuesta) == "" {
+		req.Respuesta = *duda.Respuesta
+	}
+	pregunta := &models.PreguntaFrecuente{DudaID: &duda.ID, Publicada: true, CreadaPorID: usuarioID}
+	if err := aplicarPreguntaFrecuente(pregunta, req); err != nil {
+		return nil, err
+	}
+	if err := s.faqRepo.CreatePreguntaFrecuente(pregunta); err != nil {
+		return nil, err
+	}
+	return pregunta, nil
+}
+
+// Crear agrega una entrada del FAQ redactada directamente por el personal
+func (s *FAQService) Crear(usuarioID uint, req PreguntaFrecuenteRequest) (*models.PreguntaFrecuente, error) {
+	if err := s.verificarPersonal(usuarioID); err != nil {
+		return nil, err
+	}
+	pregunta := &models.PreguntaFrecuente{Publicada: true, CreadaPorID: usuarioID}
+	if err := aplicarPreguntaFrecuente(pregunta, req); err != nil {
+		return nil, err
+	}
+	if err := s.faqRepo.CreatePreguntaFrecuente(pregunta); err != nil {
+		return nil, err
+	}
+	return pregunta, nil
+}
+
+// Actualizar cambia la redacción, categoría, orden o publicación de una entrada
+func (s *FAQService) Actualizar(id, usuarioID uint, req PreguntaFrecuenteRequest) (*models.PreguntaFrecuente, error) {
+	if err := s.verificarPersonal(usuarioID); err != nil {
+		return nil, err
+	}
+	pregunta, err := s.faqRepo.GetPreguntaFrecuenteByID(id)
+	if err != nil {
+		return nil, ErrPreguntaFrecuenteNoEncontrada
+	}
+	if strings.TrimSpace(req.Pregunta) == "" {
+		req.Pregunta = pregunta.Pregunta
+	}
+	if strings.TrimSpace(req.Respuesta) == "" {
+		req.Respuesta = pregunta.Respuesta
+	}
+	if strings.TrimSpace(req.Categoria) == "" {
+		req.Categoria = pregunta.Categoria
+	}
+	if err := aplicarPreguntaFrecuente(pregunta, req); err != nil {
+		return nil, err
+	}
+	if err := s.faqRepo.UpdatePreguntaFrecuente(pregunta); err != nil {
+		return nil, err
+	}
+	return pregunta, nil
+}
+
+// Eliminar borra una entrada del FAQ
+func (s *FAQService) Eliminar(id, usuarioID uint) error {
+	if err := s.verificarPersonal(usuarioID); err != nil {
+		return err
+	}
+	if _, err := s.faqRepo.GetPreguntaFrecuenteByID(id); err != nil {
+		return ErrPreguntaFrecuenteNoEncontrada
+	}
+	return s.faqRepo.DeletePreguntaFrecuente(id)
+}
+
+// verificarPersonal comprueba que el usuario sea personal de la UTEQ: administrador o autoridad
+func (s *FAQService) verificarPersonal(usuarioID uint) error {
+	if !esPersonalUTEQ(s.usuarioRepo, s.autoridadRepo, usuarioID) {
+		return ErrGestionFAQNoPermitida
+	}
+	return nil
+}
+
+// Sugerencias devuelve las entradas publicadas más parecidas al texto de una pregunta nueva
+func (s *FAQService) Sugerencias(texto string) ([]SugerenciaFAQ, error) {
+	palabras := palabrasClaveFAQ(texto)
+	sugerencias := []SugerenciaFAQ{}
+	if len(palabras) == 0 {
+		return sugerencias, nil
+	}
+	publicadas, err := s.faqRepo.GetPreguntasPublicadas("", "")
+	if err != nil {
+		return nil, err
+	}
+
+	for _, p := range publicadas {
+		similitud := similitudDice(palabras, palabrasClaveFAQ(p.Pregunta))
+		if similitud >= umbralSimilitudFAQ {
+			sugerencias = append(sugerencias, SugerenciaFAQ{PreguntaFrecuente: p, Similitud: similitud})
+		}
+	}
+	sort.SliceStable(sugerencias, func(i, j int) bool { return sugerencias[i].Similitud > sugerencias[j].Similitud })
+	if len(sugerencias) > maxSugerenciasFAQ {
+		sugerencias = sugerencias[:maxSugerenciasFAQ]
+	}
+	return sugerencias, nil
+}
+
+func aplicarPreguntaFrecuente(pregunta *models.PreguntaFrecuente, req PreguntaFrecuenteRequest) error {
+	req.Pregunta = strings.TrimSpace(req.Pregunta)
+	req.Respuesta = strings.TrimSpace(req.Respuesta)
+	req.Categoria = strings.TrimSpace(req.Categoria)
+	if req.Pregunta == "" {
+		return errors.New("la pregunta es requerida")
+	}
+	if req.Respuesta == "" {
+		return errors.New("la respuesta es requerida")
+	}
+	if req.Categoria == "" {
+		req.Categoria = categoriaFAQPorDef
+	}
+	if len(req.Categoria) > 100 {
+		return errors.New("la categoría no puede exceder 100 caracteres")
+	}
+
+	pregunta.Pregunta = req.Pregunta
+	pregunta.Respuesta = req.Respuesta
+	pregunta.Categoria = req.Categoria
+	if req.Orden != nil {
+		pregunta.Orden = *req.Orden
+	}
+	if req.Publicada != nil {
+		pregunta.Publicada = *req.Publicada
+	}
+	return nil
+}
+
+// palabrasClaveFAQ normaliza el texto (minúsculas, sin tildes) y devuelve sus palabras significativas
+func palabrasClaveFAQ(texto string) map[string]bool {
+	texto = reemplazoTildes.Replace(strings.ToLower(texto))
+	palabras := map[string]bool{}
+	for _, palabra := range strings.FieldsFunc(texto, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) }) {
+		if len(palabra) < 3 || palabrasVaciasFAQ[palabra] {
+			continue
+		}
+		palabras[palabra] = true
+	}
+	return palabras
+}
+
+// similitudDice es 2|A∩B| / (|A|+|B|)
+func similitudDice(a, b map[string]bool) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	comunes := 0
+	for palabra := range a {
+		if b[palabra] {
+			comunes++
+		}
+	}
+	return 2 * float64(comunes) / float64(len(a)+len(b))
+}
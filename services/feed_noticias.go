@@ -0,0 +1,245 @@
+package services
+
+import (
+	"ApiEscuela/models"
+	"encoding/xml"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	tituloFeedNoticias      = "Noticias"
+	descripcionFeedNoticias = "Noticias publicadas"
+	autorFeedNoticias       = "ApiEscuela"
+	idiomaFeedNoticias      = "es"
+)
+
+// CategoriaPublica es la categoría de una noticia tal como se muestra en el sitio público
+type CategoriaPublica struct {
+	Nombre string `json:"nombre"`
+	Slug   string `json:"slug"`
+}
+
+// NoticiaPublica es la vista pública de una noticia publicada, sin datos del autor ni del flujo editorial
+type NoticiaPublica struct {
+	ID            uint               `json:"id"`
+	Slug          string             `json:"slug"`
+	Titulo        string             `json:"titulo"`
+	Descripcion   string             `json:"descripcion"`
+	URLNoticia    string             `json:"url_noticia,omitempty"`
+	ImagenPortada string             `json:"imagen_portada,omitempty"`
+	Enlace        string             `json:"enlace"`
+	Categoria     *CategoriaPublica  `json:"categoria,omitempty"`
+	Etiquetas     []CategoriaPublica `json:"etiquetas"`
+	PublicadaEn   time.Time          `json:"publicada_en"`
+	ActualizadaEn time.Time          `json:"actualizada_en"`
+}
+
+// PaginaNoticias es una página del listado público de noticias
+type PaginaNoticias struct {
+	Noticias     []NoticiaPublica `json:"noticias"`
+	Pagina       int              `json:"pagina"`
+	PorPagina    int              `json:"por_pagina"`
+	Total        int64            `json:"total"`
+	TotalPaginas int              `json:"total_paginas"`
+}
+
+// FeedNoticias describe el canal de un feed; los enlaces son absolutos
+type FeedNoticias struct {
+	Enlace    string // Página del listado de noticias
+	Propio    string // URL de este feed
+	Siguiente string // URL de la página siguiente del feed, si existe
+	Anterior  string // URL de la página anterior del feed, si existe
+}
+
+func noticiaPublica(n *models.Noticia, urlBase, urlNoticias string) NoticiaPublica {
+	publica := NoticiaPublica{
+		ID:            n.ID,
+		Slug:          n.Slug,
+		Titulo:        n.Titulo,
+		Descripcion:   n.Descripcion,
+		URLNoticia:    n.URLNoticia,
+		Enlace:        urlListadoNoticias(urlBase, urlNoticias) + n.Slug,
+		Etiquetas:     make([]CategoriaPublica, 0, len(n.Etiquetas)),
+		PublicadaEn:   n.CreatedAt,
+		ActualizadaEn: n.UpdatedAt,
+	}
+	if n.PublicadaEn != nil {
+		publica.PublicadaEn = *n.PublicadaEn
+	}
+	if n.ImagenPortada != nil {
+		publica.ImagenPortada = strings.TrimRight(urlBase, "/") + prefijoImagenesSubidas + n.ImagenPortada.NombreAlmacenado
+	}
+	if n.Categoria != nil {
+		publica.Categoria = &CategoriaPublica{Nombre: n.Categoria.Nombre, Slug: n.Categoria.Slug}
+	}
+	for _, e := range n.Etiquetas {
+		publica.Etiquetas = append(publica.Etiquetas, CategoriaPublica{Nombre: e.Nombre, Slug: e.Slug})
+	}
+	return publica
+}
+
+// urlListadoNoticias usa urlNoticias (NOTICIAS_URL_BASE, p. ej. la sección de noticias del sitio) o, si no
+// está definida, la ruta pública de la API
+func urlListadoNoticias(urlBase, urlNoticias string) string {
+	if urlNoticias != "" {
+		return strings.TrimRight(urlNoticias, "/") + "/"
+	}
+	return strings.TrimRight(urlBase, "/") + "/noticias/"
+}
+
+type rssDocumento struct {
+	XMLName xml.Name `xml:"rss"`
+	Version string   `xml:"version,attr"`
+	Atom    string   `xml:"xmlns:atom,attr"`
+	Canal   rssCanal `xml:"channel"`
+}
+
+type rssCanal struct {
+	Titulo      string        `xml:"title"`
+	Enlace      string        `xml:"link"`
+	Descripcion string        `xml:"description"`
+	Idioma      string        `xml:"language"`
+	Construido  string        `xml:"lastBuildDate,omitempty"`
+	Enlaces     []enlaceAtom  `xml:"atom:link"`
+	Items       []rssElemento `xml:"item"`
+}
+
+type rssElemento struct {
+	Titulo      string   `xml:"title"`
+	Enlace      string   `xml:"link"`
+	Descripcion string   `xml:"description"`
+	GUID        rssGUID  `xml:"guid"`
+	Publicado   string   `xml:"pubDate"`
+	Categorias  []string `xml:"category"`
+}
+
+type rssGUID struct {
+	EsEnlace bool   `xml:"isPermaLink,attr"`
+	Valor    string `xml:",chardata"`
+}
+
+type enlaceAtom struct {
+	Rel  string `xml:"rel,attr"`
+	Href string `xml:"href,attr"`
+	Tipo string `xml:"type,attr,omitempty"`
+}
+
+// generarRSS genera un documento RSS 2.0; la paginación se anuncia con enlaces atom:link (RFC 5005)
+func generarRSS(feed FeedNoticias, noticias []NoticiaPublica, actualizado time.Time) ([]byte, error) {
+	canal := rssCanal{
+		Titulo:      tituloFeedNoticias,
+		Enlace:      feed.Enlace,
+		Descripcion: descripcionFeedNoticias,
+		Idioma:      idiomaFeedNoticias,
+		Enlaces:     enlacesFeed(feed, "application/rss+xml"),
+	}
+	if !actualizado.IsZero() {
+		canal.Construido = actualizado.UTC().Format(time.RFC1123Z)
+	}
+	for _, n := range noticias {
+		elemento := rssElemento{
+			Titulo:      n.Titulo,
+			Enlace:      n.Enlace,
+			Descripcion: n.Descripcion,
+			GUID:        rssGUID{Valor: idNoticiaFeed(n)},
+			Publicado:   n.PublicadaEn.UTC().Format(time.RFC1123Z),
+		}
+		if n.Categoria != nil {
+			elemento.Categorias = append(elemento.Categorias, n.Categoria.Nombre)
+		}
+		for _, e := range n.Etiquetas {
+			elemento.Categorias = append(elemento.Categorias, e.Nombre)
+		}
+		canal.Items = append(canal.Items, elemento)
+	}
+	return serializarXML(rssDocumento{Version: "2.0", Atom: "http://www.w3.org/2005/Atom", Canal: canal})
+}
+
+type atomDocumento struct {
+	XMLName     xml.Name      `xml:"http://www.w3.org/2005/Atom feed"`
+	Titulo      string        `xml:"title"`
+	Subtitulo   string        `xml:"subtitle"`
+	ID          string        `xml:"id"`
+	Actualizado string        `xml:"updated"`
+	Autor       atomAutor     `xml:"author"`
+	Enlaces     []enlaceAtom  `xml:"link"`
+	Entradas    []atomEntrada `xml:"entry"`
+}
+
+type atomAutor struct {
+	Nombre string `xml:"name"`
+}
+
+type atomEntrada struct {
+	Titulo      string          `xml:"title"`
+	ID          string          `xml:"id"`
+	Enlace      enlaceAtom      `xml:"link"`
+	Publicado   string          `xml:"published"`
+	Actualizado string          `xml:"updated"`
+	Resumen     string          `xml:"summary"`
+	Categorias  []atomCategoria `xml:"category"`
+}
+
+type atomCategoria struct {
+	Termino  string `xml:"term,attr"`
+	Etiqueta string `xml:"label,attr"`
+}
+
+// generarAtom genera un documento Atom (RFC 4287) con enlaces de paginación (RFC 5005)
+func generarAtom(feed FeedNoticias, noticias []NoticiaPublica, actualizado time.Time) ([]byte, error) {
+	if actualizado.IsZero() {
+		actualizado = time.Now()
+	}
+	doc := atomDocumento{
+		Titulo:      tituloFeedNoticias,
+		Subtitulo:   descripcionFeedNoticias,
+		ID:          feed.Propio,
+		Actualizado: actualizado.UTC().Format(time.RFC3339),
+		Autor:       atomAutor{Nombre: autorFeedNoticias},
+		Enlaces:     append(enlacesFeed(feed, "application/atom+xml"), enlaceAtom{Rel: "alternate", Href: feed.Enlace}),
+	}
+	for _, n := range noticias {
+		entrada := atomEntrada{
+			Titulo:      n.Titulo,
+			ID:          idNoticiaFeed(n),
+			Enlace:      enlaceAtom{Rel: "alternate", Href: n.Enlace},
+			Publicado:   n.PublicadaEn.UTC().Format(time.RFC3339),
+			Actualizado: n.ActualizadaEn.UTC().Format(time.RFC3339),
+			Resumen:     n.Descripcion,
+		}
+		if n.Categoria != nil {
+			entrada.Categorias = append(entrada.Categorias, atomCategoria{Termino: n.Categoria.Slug, Etiqueta: n.Categoria.Nombre})
+		}
+		for _, e := range n.Etiquetas {
+			entrada.Categorias = append(entrada.Categorias, atomCategoria{Termino: e.Slug, Etiqueta: e.Nombre})
+		}
+		doc.Entradas = append(doc.Entradas, entrada)
+	}
+	return serializarXML(doc)
+}
+
+func enlacesFeed(feed FeedNoticias, tipo string) []enlaceAtom {
+	enlaces := []enlaceAtom{{Rel: "self", Href: feed.Propio, Tipo: tipo}}
+	if feed.Anterior != "" {
+		enlaces = append(enlaces, enlaceAtom{Rel: "previous", Href: feed.Anterior, Tipo: tipo})
+	}
+	if feed.Siguiente != "" {
+		enlaces = append(enlaces, enlaceAtom{Rel: "next", Href: feed.Siguiente, Tipo: tipo})
+	}
+	return enlaces
+}
+
+// idNoticiaFeed es un identificador estable aunque cambien el slug o la URL del sitio
+func idNoticiaFeed(n NoticiaPublica) string {
+	return fmt.Sprintf("tag:%s,2024:noticia/%d", dominioUIDCalendario, n.ID)
+}
+
+func serializarXML(doc interface{}) ([]byte, error) {
+	contenido, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), contenido...), nil
+}
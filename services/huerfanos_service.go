@@ -0,0 +1,421 @@
+package services
+
+import (
+	"ApiEscuela/config"
+	"ApiEscuela/models"
+	"ApiEscuela/repositories"
+	"context"
+	"errors"
+	"log/slog"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const carpetaCuarentena = "cuarentena"
+
+var (
+	ErrCuarentenaNoEncontrada   = errors.New("archivo en cuarentena no encontrado")
+	ErrGestionHuerfanosDenegada = errors.New("solo un administrador puede gestionar los archivos huérfanos")
+)
+
+var (
+	// enlaceArchivo reconoce carpeta/nombre en URLs como /api/files/images/1700000000_ab12.jpg
+	enlaceArchivo = regexp.MustCompile(`(images|videos|documents)/([A-Za-z0-9][A-Za-z0-9_-]*\.[A-Za-z0-9]+)`)
+	// enlaceFirmado reconoce los enlaces firmados /api/files/firmados/<id>
+	enlaceFirmado = regexp.MustCompile(`/api/files/firmados/(\d+)`)
+)
+
+// HuerfanosService busca los archivos subidos que ningún registro referencia, los aparta en cuarentena
+// y los elimina cuando vence el plazo
+type HuerfanosService struct {
+	huerfanosRepo *repositories.HuerfanosRepository
+	archivoRepo   *repositories.ArchivoRepository
+	usuarioRepo   *repositories.UsuarioRepository
+	storage       Storage
+	archivos      config.Archivos // Plazos de gracia y cuarentena, y variantes de imagen
+	intervalo     time.Duration   // Periodo de la recolección
+}
+
+func NewHuerfanosService(huerfanosRepo *repositories.HuerfanosRepository, archivoRepo *repositories.ArchivoRepository, usuarioRepo *repositories.UsuarioRepository, storage Storage, archivos config.Archivos, intervalo time.Duration) *HuerfanosService {
+	return &HuerfanosService{huerfanosRepo: huerfanosRepo, archivoRepo: archivoRepo, usuarioRepo: usuarioRepo, storage: storage, archivos: archivos, intervalo: intervalo}
+}
+
+// ConContexto devuelve una copia del servicio cuyos repositorios usan ctx, para que las consultas queden
+// dentro de la traza de la petición
+func (s *HuerfanosService) ConContexto(ctx context.Context) *HuerfanosService {
+	copia := *s
+	copia.huerfanosRepo = s.huerfanosRepo.ConContexto(ctx)
+	copia.archivoRepo = s.archivoRepo.ConContexto(ctx)
+	copia.usuarioRepo = s.usuarioRepo.ConContexto(ctx)
+	return &copia
+}
+
+// ArchivoHuerfano es un archivo sin referencias, con sus variantes
+type ArchivoHuerfano struct {
+	Clave        string    `json:"clave"`
+	Variantes    []string  `json:"variantes,omitempty"`
+	Tamano       int64     `json:"tamano"`
+	ModificadoEn time.Time `json:"modificado_en"`
+	ArchivoID    *uint     `json:"archivo_id,omitempty"`
+}
+
+// ReporteHuerfanos es el resultado de una búsqueda sin cambios
+type ReporteHuerfanos struct {
+	Huerfanos   []ArchivoHuerfano `json:"huerfanos"`
+	Total       int               `json:"total"`
+	TamanoTotal int64             `json:"tamano_total"`
+	Revisados   int               `json:"revisados"` // Archivos revisados, sin contar variantes
+	EnGracia    int               `json:"en_gracia"` // Sin referencias, pero subidos hace menos que el plazo de gracia
+	Gracia      string            `json:"gracia"`
+}
+
+// ResultadoRecoleccion resume una pasada del recolector
+type ResultadoRecoleccion struct {
+	Apartados   int `json:"apartados"`
+	Restaurados int `json:"restaurados"`
+	Eliminados  int `json:"eliminados"`
+}
+
+// referenciasArchivos son las claves y los IDs de archivos que algún registro usa
+type referenciasArchivos struct {
+	claves    map[string]bool
+	ids       map[uint]bool
+	registros map[string]*models.Archivo // Por clave
+	variantes map[string]string          // Clave de variante registrada -> clave del original
+}
+
+func (r *referenciasArchivos) usado(clave string, archivoID *uint) bool {
+	return r.claves[clave] || (archivoID != nil && r.ids[*archivoID])
+}
+
+// Reporte lista los archivos que se apartarían en la próxima pasada, sin moverlos
+func (s *HuerfanosService) Reporte(usuarioID uint) (*ReporteHuerfanos, error) {
+	if err := s.verificarAdministrador(usuarioID); err != nil {
+		return nil, err
+	}
+	referencias, err := s.referencias()
+	if err != nil {
+		return nil, err
+	}
+	return s.buscarHuerfanos(referencias, time.Now())
+}
+
+// Cuarentena lista los archivos apartados
+func (s *HuerfanosService) Cuarentena(usuarioID uint) ([]models.ArchivoCuarentena, error) {
+	if err := s.verificarAdministrador(usuarioID); err != nil {
+		return nil, err
+	}
+	return s.huerfanosRepo.GetCuarentenas()
+}
+
+// Restaurar devuelve un archivo en cuarentena a su carpeta
+func (s *HuerfanosService) Restaurar(id, usuarioID uint) (*models.ArchivoCuarentena, error) {
+	if err := s.verificarAdministrador(usuarioID); err != nil {
+		return nil, err
+	}
+	cuarentena, err := s.huerfanosRepo.GetCuarentenaByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCuarentenaNoEncontrada
+		}
+		return nil, err
+	}
+	if err := s.restaurar(cuarentena); err != nil {
+		return nil, err
+	}
+	return cuarentena, nil
+}
+
+// Recolectar aparta en cuarentena los huérfanos nuevos y elimina los que cumplieron el plazo. Antes de
+// eliminar vuelve a buscar referencias: si un archivo apartado volvió a usarse, se restaura.
+func (s *HuerfanosService) Recolectar(ahora time.Time) (*ResultadoRecoleccion, error) {
+	referencias, err := s.referencias()
+	if err != nil {
+		return nil, err
+	}
+	resultado := &ResultadoRecoleccion{}
+
+	vencidas, err := s.huerfanosRepo.GetCuarentenasVencidas(ahora)
+	if err != nil {
+		return nil, err
+	}
+	for i := range vencidas {
+		cuarentena := &vencidas[i]
+		if referencias.usado(cuarentena.Clave, cuarentena.ArchivoID) {
+			if err := s.restaurar(cuarentena); err != nil {
+				slog.Warn("no se pudo restaurar un archivo de la cuarentena", "archivo", cuarentena.Clave, "error", err)
+				continue
+			}
+			resultado.Restaurados++
+			continue
+		}
+		if err := s.eliminar(cuarentena); err != nil {
+			slog.Warn("no se pudo eliminar un archivo de la cuarentena", "archivo", cuarentena.Clave, "error", err)
+			continue
+		}
+		resultado.Eliminados++
+	}
+
+	reporte, err := s.buscarHuerfanos(referencias, ahora)
+	if err != nil {
+		return resultado, err
+	}
+	eliminarEn := ahora.Add(s.archivos.DuracionCuarentena)
+	for _, huerfano := range reporte.Huerfanos {
+		if err := s.apartar(huerfano, eliminarEn); err != nil {
+			slog.Warn("no se pudo apartar un archivo en cuarentena", "archivo", huerfano.Clave, "error", err)
+			continue
+		}
+		resultado.Apartados++
+	}
+	return resultado, nil
+}
+
+// IniciarRecoleccion ejecuta Recolectar periódicamente en segundo plano
+// (ARCHIVOS_HUERFANOS_INTERVALO, por defecto 24h). Devuelve una función que detiene el recolector;
+// si hay una recolección en curso, espera a que termine para no dejar archivos a medio mover.
+func (s *HuerfanosService) IniciarRecoleccion() func() {
+	detener := make(chan struct{})
+	terminado := make(chan struct{})
+	go func() {
+		defer close(terminado)
+		ticker := time.NewTicker(s.intervalo)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				resultado, err := s.Recolectar(time.Now())
+				if err != nil {
+					slog.Error("error al recolectar archivos huérfanos", "error", err)
+				} else if resultado.Apartados > 0 || resultado.Restaurados > 0 || resultado.Eliminados > 0 {
+					slog.Info("archivos huérfanos recolectados", "apartados", resultado.Apartados, "restaurados", resultado.Restaurados, "eliminados", resultado.Eliminados)
+				}
+			case <-detener:
+				return
+			}
+		}
+	}()
+	return func() {
+		close(detener)
+		<-terminado
+	}
+}
+
+// referencias reúne los archivos mencionados en ColumnasConArchivos y los apuntados por
+// ColumnasConIDArchivo. Los archivos privados o con permisos se gestionan desde /archivos y también
+// cuentan como usados.
+func (s *HuerfanosService) referencias() (*referenciasArchivos, error) {
+	textos, err := s.huerfanosRepo.GetTextosConArchivos()
+	if err != nil {
+		return nil, err
+	}
+	ids, err := s.huerfanosRepo.GetIDsArchivosReferenciados()
+	if err != nil {
+		return nil, err
+	}
+	archivos, err := s.huerfanosRepo.GetArchivosRegistrados()
+	if err != nil {
+		return nil, err
+	}
+	referencias := extraerReferencias(textos)
+	for _, id := range ids {
+		referencias.ids[id] = true
+	}
+	referencias.registros = map[string]*models.Archivo{}
+	referencias.variantes = map[string]string{}
+	for i := range archivos {
+		archivo := &archivos[i]
+		clave := ClaveArchivo(archivo.Carpeta, archivo.NombreAlmacenado)
+		referencias.registros[clave] = archivo
+		if archivo.Visibilidad == models.VisibilidadArchivoPrivado || len(archivo.Permisos) > 0 {
+			referencias.ids[archivo.ID] = true
+		}
+		if archivo.Variantes != "" {
+			for _, sufijo := range strings.Split(archivo.Variantes, ",") {
+				referencias.variantes[ClaveVariante(archivo.Carpeta, archivo.NombreAlmacenado, sufijo)] = clave
+			}
+		}
+	}
+	return referencias, nil
+}
+
+// extraerReferencias busca enlaces a archivos en los textos
+func extraerReferencias(textos []string) *referenciasArchivos {
+	referencias := &referenciasArchivos{claves: map[string]bool{}, ids: map[uint]bool{}}
+	for _, texto := range textos {
+		for _, coincidencia := range enlaceArchivo.FindAllStringSubmatch(texto, -1) {
+			referencias.claves[ClaveArchivo(coincidencia[1], coincidencia[2])] = true
+		}
+		for _, coincidencia := range enlaceFirmado.FindAllStringSubmatch(texto, -1) {
+			if id, err := strconv.ParseUint(coincidencia[1], 10, 64); err == nil {
+				referencias.ids[uint(id)] = true
+			}
+		}
+	}
+	return referencias
+}
+
+// buscarHuerfanos recorre las carpetas de archivos agrupando cada original con sus variantes
+func (s *HuerfanosService) buscarHuerfanos(referencias *referenciasArchivos, ahora time.Time) (*ReporteHuerfanos, error) {
+	gracia := s.archivos.GraciaHuerfanos
+	reporte := &ReporteHuerfanos{Huerfanos: []ArchivoHuerfano{}, Gracia: gracia.String()}
+
+	// Cada original se agrupa con sus variantes por el nombre sin extensión (images/1700000000_ab12)
+	grupos := map[string]*ArchivoHuerfano{}
+	var orden []string
+	for _, carpeta := range CarpetasArchivos {
+		objetos, err := s.storage.Listar(carpeta)
+		if err != nil {
+			return nil, err
+		}
+		for _, objeto := range objetos {
+			id, esVariante := grupoDeArchivo(objeto.Clave, referencias.variantes, s.archivos.Variantes)
+			grupo, existe := grupos[id]
+			if !existe {
+				grupo = &ArchivoHuerfano{}
+				grupos[id] = grupo
+				orden = append(orden, id)
+			}
+			if esVariante {
+				grupo.Variantes = append(grupo.Variantes, objeto.Clave)
+			} else {
+				grupo.Clave = objeto.Clave
+			}
+			grupo.Tamano += objeto.Tamano
+			if objeto.ModificadoEn.After(grupo.ModificadoEn) {
+				grupo.ModificadoEn = objeto.ModificadoEn
+			}
+		}
+	}
+
+	sort.Strings(orden)
+	for _, id := range orden {
+		grupo := grupos[id]
+		sort.Strings(grupo.Variantes)
+		if grupo.Clave == "" {
+			// Variantes cuyo original ya no existe
+			grupo.Clave, grupo.Variantes = grupo.Variantes[0], grupo.Variantes[1:]
+		}
+		clave := grupo.Clave
+		reporte.Revisados++
+		if archivo, ok := referencias.registros[clave]; ok {
+			grupo.ArchivoID = &archivo.ID
+		}
+		if referencias.usado(clave, grupo.ArchivoID) {
+			continue
+		}
+		if ahora.Sub(grupo.ModificadoEn) < gracia {
+			reporte.EnGracia++
+			continue
+		}
+		reporte.Huerfanos = append(reporte.Huerfanos, *grupo)
+		reporte.TamanoTotal += grupo.Tamano
+	}
+	reporte.Total = len(reporte.Huerfanos)
+	return reporte, nil
+}
+
+// grupoDeArchivo devuelve la clave sin extensión del original al que pertenece el archivo e indica si
+// es una variante. Las variantes se reconocen por el registro del original o, en las imágenes
+// anteriores a los registros, por los nombres de IMAGENES_VARIANTES (configuradas).
+func grupoDeArchivo(clave string, registradas map[string]string, configuradas []config.VarianteImagen) (string, bool) {
+	sinExtension := func(c string) string { return strings.TrimSuffix(c, filepath.Ext(c)) }
+	if original, ok := registradas[clave]; ok {
+		return sinExtension(original), true
+	}
+	base := sinExtension(clave)
+	if sufijo := filepath.Ext(base); sufijo != "" && !strings.HasSuffix(base, "/"+sufijo) {
+		for _, variante := range configuradas {
+			if sufijo == "."+variante.Nombre {
+				return sinExtension(base), true
+			}
+		}
+	}
+	return base, false
+}
+
+// apartar mueve el archivo y sus variantes a cuarentena/. Si algo falla, devuelve a su lugar lo movido.
+func (s *HuerfanosService) apartar(huerfano ArchivoHuerfano, eliminarEn time.Time) error {
+	claves := append([]string{huerfano.Clave}, huerfano.Variantes...)
+	var movidas []string
+	for _, clave := range claves {
+		err := MoverObjeto(s.storage, clave, path.Join(carpetaCuarentena, clave))
+		if errors.Is(err, ErrObjetoNoEncontrado) {
+			continue // Variantes huérfanas sin original
+		}
+		if err != nil {
+			s.devolver(movidas)
+			return err
+		}
+		movidas = append(movidas, clave)
+	}
+	cuarentena := &models.ArchivoCuarentena{
+		Clave:        huerfano.Clave,
+		Variantes:    strings.Join(huerfano.Variantes, ","),
+		Tamano:       huerfano.Tamano,
+		ArchivoID:    huerfano.ArchivoID,
+		ModificadoEn: huerfano.ModificadoEn,
+		EliminarEn:   eliminarEn,
+	}
+	if err := s.huerfanosRepo.CreateCuarentena(cuarentena); err != nil {
+		s.devolver(movidas)
+		return err
+	}
+	return nil
+}
+
+func (s *HuerfanosService) restaurar(cuarentena *models.ArchivoCuarentena) error {
+	for _, clave := range clavesCuarentena(cuarentena) {
+		err := MoverObjeto(s.storage, path.Join(carpetaCuarentena, clave), clave)
+		if err != nil && !errors.Is(err, ErrObjetoNoEncontrado) {
+			return err
+		}
+	}
+	return s.huerfanosRepo.DeleteCuarentena(cuarentena.ID)
+}
+
+func (s *HuerfanosService) eliminar(cuarentena *models.ArchivoCuarentena) error {
+	for _, clave := range clavesCuarentena(cuarentena) {
+		if err := s.storage.Eliminar(path.Join(carpetaCuarentena, clave)); err != nil {
+			return err
+		}
+	}
+	if cuarentena.ArchivoID != nil {
+		if err := s.archivoRepo.DeleteArchivo(*cuarentena.ArchivoID); err != nil {
+			return err
+		}
+	}
+	return s.huerfanosRepo.DeleteCuarentena(cuarentena.ID)
+}
+
+// devolver deshace un apartado a medias; los errores solo se registran
+func (s *HuerfanosService) devolver(claves []string) {
+	for _, clave := range claves {
+		if err := MoverObjeto(s.storage, path.Join(carpetaCuarentena, clave), clave); err != nil {
+			slog.Warn("no se pudo devolver un archivo desde la cuarentena", "archivo", clave, "error", err)
+		}
+	}
+}
+
+func (s *HuerfanosService) verificarAdministrador(usuarioID uint) error {
+	usuario, err := s.usuarioRepo.GetUsuarioByID(usuarioID)
+	if err != nil || !usuario.TipoUsuario.EsAdministrador() {
+		return ErrGestionHuerfanosDenegada
+	}
+	return nil
+}
+
+func clavesCuarentena(cuarentena *models.ArchivoCuarentena) []string {
+	claves := []string{cuarentena.Clave}
+	if cuarentena.Variantes != "" {
+		claves = append(claves, strings.Split(cuarentena.Variantes, ",")...)
+	}
+	return claves
+}
@@ -0,0 +1,90 @@
+package services
+
+import (
+	"ApiEscuela/config"
+	"ApiEscuela/models"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExtraerReferencias(t *testing.T) {
+	referencias := extraerReferencias([]string{
+		"https://api.uteq.edu.ec/api/files/images/1700000000_ab12.jpg",
+		`Ver <a href="/api/files/documents/1700000001_cd34.pdf">el acta</a> y /api/files/firmados/15?expira=1&firma=x`,
+	})
+	for _, clave := range []string{"images/1700000000_ab12.jpg", "documents/1700000001_cd34.pdf"} {
+		if !referencias.claves[clave] {
+			t.Errorf("no se encontró la referencia a %s", clave)
+		}
+	}
+	if !referencias.ids[15] {
+		t.Error("no se encontró la referencia al enlace firmado 15")
+	}
+}
+
+func TestBuscarHuerfanos(t *testing.T) {
+	cfg := config.PorDefecto().Archivos
+	cfg.GraciaHuerfanos = 24 * time.Hour
+	cfg.Variantes = []config.VarianteImagen{{Nombre: "thumb", Lado: 320}}
+	raiz := t.TempDir()
+	storage := NewStorageLocal(raiz)
+	ahora := time.Now()
+	antiguo := ahora.Add(-48 * time.Hour)
+
+	archivos := map[string]time.Time{
+		"images/100_usada.jpg":          antiguo,
+		"images/100_usada.thumb.jpg":    antiguo,
+		"images/200_huerfana.png":       antiguo,
+		"images/200_huerfana.thumb.png": antiguo,
+		"images/300_reciente.png":       ahora,
+		"documents/400_privado.pdf":     antiguo,
+		"videos/500_firmado.mp4":        antiguo,
+		"images/600_sola.thumb.jpg":     antiguo,
+	}
+	for clave, modificado := range archivos {
+		if err := storage.Guardar(clave, strings.NewReader("x"), 1, ""); err != nil {
+			t.Fatal(err)
+		}
+		os.Chtimes(filepath.Join(raiz, filepath.FromSlash(clave)), modificado, modificado)
+	}
+
+	referencias := extraerReferencias([]string{"/api/files/images/100_usada.jpg", "/api/files/firmados/5"})
+	referencias.registros = map[string]*models.Archivo{
+		"documents/400_privado.pdf": {Carpeta: "documents", NombreAlmacenado: "400_privado.pdf", Visibilidad: models.VisibilidadArchivoPrivado},
+		"videos/500_firmado.mp4":    {Carpeta: "videos", NombreAlmacenado: "500_firmado.mp4"},
+	}
+	referencias.registros["documents/400_privado.pdf"].ID = 4
+	referencias.registros["videos/500_firmado.mp4"].ID = 5
+	referencias.ids[4] = true
+	referencias.variantes = map[string]string{}
+
+	servicio := &HuerfanosService{storage: storage, archivos: cfg}
+	reporte, err := servicio.buscarHuerfanos(referencias, ahora)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reporte.Total != 2 || reporte.EnGracia != 1 || reporte.Revisados != 6 {
+		t.Fatalf("reporte %+v, se esperaban 2 huérfanos, 1 en gracia y 6 revisados", reporte)
+	}
+	huerfana := reporte.Huerfanos[0]
+	if huerfana.Clave != "images/200_huerfana.png" || len(huerfana.Variantes) != 1 || huerfana.Variantes[0] != "images/200_huerfana.thumb.png" || huerfana.Tamano != 2 {
+		t.Errorf("primer huérfano %+v, se esperaba images/200_huerfana.png con su miniatura", huerfana)
+	}
+	if sola := reporte.Huerfanos[1]; sola.Clave != "images/600_sola.thumb.jpg" || len(sola.Variantes) != 0 {
+		t.Errorf("segundo huérfano %+v, se esperaba la miniatura sin original", sola)
+	}
+
+	// Apartar mueve el original y sus variantes a cuarentena/ sin dejar nada en la carpeta
+	if err := MoverObjeto(storage, huerfana.Clave, "cuarentena/"+huerfana.Clave); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := storage.Info(huerfana.Clave); err == nil {
+		t.Error("el original sigue en su carpeta después de moverlo")
+	}
+	if _, err := storage.Info("cuarentena/" + huerfana.Clave); err != nil {
+		t.Errorf("el original no está en cuarentena: %v", err)
+	}
+}
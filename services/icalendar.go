@@ -0,0 +1,128 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Métodos iTIP (RFC 5546) usados en los adjuntos de correo
+const (
+	MetodoICSPublicar  = "PUBLISH"
+	MetodoICSSolicitud = "REQUEST"
+	MetodoICSCancelar  = "CANCEL"
+)
+
+// dominioUIDCalendario garantiza UIDs globalmente únicos y estables entre el feed y los correos
+const dominioUIDCalendario = "apiescuela.uteq.edu.ec"
+
+// epochSecuenciaICS es la referencia para calcular SEQUENCE a partir de la última modificación
+var epochSecuenciaICS = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// EventoCalendario representa un VEVENT de iCalendar (RFC 5545)
+type EventoCalendario struct {
+	UID         string
+	Inicio      time.Time
+	Fin         time.Time
+	Resumen     string
+	Descripcion string
+	Ubicacion   string
+	Organizador string
+	Asistente   string
+	Modificado  time.Time
+	Cancelado   bool
+}
+
+// Secuencia deriva SEQUENCE de la fecha de modificación para que siempre aumente con cada cambio
+func (e EventoCalendario) Secuencia() int64 {
+	segundos := int64(e.Modificado.Sub(epochSecuenciaICS).Seconds())
+	if segundos < 0 {
+		return 0
+	}
+	return segundos
+}
+
+// UIDCalendario construye un UID estable para un recurso
+func UIDCalendario(tipo string, id uint) string {
+	return fmt.Sprintf("%s-%d@%s", tipo, id, dominioUIDCalendario)
+}
+
+// GenerarICS genera un VCALENDAR con los eventos indicados
+func GenerarICS(nombre, metodo string, eventos []EventoCalendario) []byte {
+	var b strings.Builder
+	linea := func(contenido string) {
+		b.WriteString(plegarLineaICS(contenido))
+		b.WriteString("\r\n")
+	}
+
+	ahora := time.Now().UTC().Format("20060102T150405Z")
+	linea("BEGIN:VCALENDAR")
+	linea("VERSION:2.0")
+	linea("PRODID:-//UTEQ//ApiEscuela//ES")
+	linea("CALSCALE:GREGORIAN")
+	if metodo != "" {
+		linea("METHOD:" + metodo)
+	}
+	if nombre != "" {
+		linea("X-WR-CALNAME:" + escaparTextoICS(nombre))
+	}
+	for _, e := range eventos {
+		linea("BEGIN:VEVENT")
+		linea("UID:" + e.UID)
+		linea("DTSTAMP:" + ahora)
+		linea("DTSTART:" + e.Inicio.UTC().Format("20060102T150405Z"))
+		linea("DTEND:" + e.Fin.UTC().Format("20060102T150405Z"))
+		linea(fmt.Sprintf("SEQUENCE:%d", e.Secuencia()))
+		if !e.Modificado.IsZero() {
+			linea("LAST-MODIFIED:" + e.Modificado.UTC().Format("20060102T150405Z"))
+		}
+		linea("SUMMARY:" + escaparTextoICS(e.Resumen))
+		if e.Descripcion != "" {
+			linea("DESCRIPTION:" + escaparTextoICS(e.Descripcion))
+		}
+		if e.Ubicacion != "" {
+			linea("LOCATION:" + escaparTextoICS(e.Ubicacion))
+		}
+		if e.Organizador != "" {
+			linea("ORGANIZER:mailto:" + e.Organizador)
+		}
+		if e.Asistente != "" {
+			linea("ATTENDEE;ROLE=REQ-PARTICIPANT;RSVP=FALSE:mailto:" + e.Asistente)
+		}
+		if e.Cancelado {
+			linea("STATUS:CANCELLED")
+		} else {
+			linea("STATUS:CONFIRMED")
+		}
+		linea("END:VEVENT")
+	}
+	linea("END:VCALENDAR")
+	return []byte(b.String())
+}
+
+// escaparTextoICS escapa los caracteres especiales de valores TEXT
+func escaparTextoICS(texto string) string {
+	r := strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+	return r.Replace(texto)
+}
+
+// plegarLineaICS divide líneas de más de 75 octetos sin cortar caracteres UTF-8
+func plegarLineaICS(contenido string) string {
+	if len(contenido) <= 75 {
+		return contenido
+	}
+	var b strings.Builder
+	limite := 75
+	actual := 0
+	for _, r := range contenido {
+		n := len(string(r))
+		if actual+n > limite {
+			b.WriteString("\r\n ")
+			actual = 1
+			limite = 75
+		}
+		b.WriteRune(r)
+		actual += n
+	}
+	return b.String()
+}
@@ -0,0 +1,319 @@
+package services
+
+import (
+	"ApiEscuela/config"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif" // registra el decodificador GIF para image.Decode
+	"image/jpeg"
+	"image/png"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/image/draw"
+)
+
+// ErrImagenInvalida indica que la imagen no se pudo decodificar o tiene demasiados píxeles
+var ErrImagenInvalida = errors.New("la imagen no es válida o es demasiado grande")
+
+const (
+	// maxPixelesImagen evita decodificar imágenes enormes (bombas de descompresión)
+	maxPixelesImagen = 50_000_000
+	// calidadOriginalJPEG se usa cuando hay que volver a codificar el original para aplicar la orientación EXIF
+	calidadOriginalJPEG = 92
+)
+
+// ImagenProcesada es el original sin metadatos y las variantes generadas a partir de él
+type ImagenProcesada struct {
+	Original  []byte
+	Variantes []ArchivoVariante
+}
+
+// ArchivoVariante es una variante codificada; Sufijo identifica la variante y su formato (thumb.jpg, medium.webp)
+type ArchivoVariante struct {
+	Sufijo    string
+	Contenido []byte
+	MIME      string
+}
+
+// ClaveVariante es la clave de una variante, junto al original: images/<nombre sin extensión>.<sufijo>.
+// La ruta abierta /api/files/:tipo/:nombre no acepta nombres con dos extensiones, así que las variantes
+// solo se sirven con ?size=, después de los mismos controles de acceso que el original.
+func ClaveVariante(carpeta, nombre, sufijo string) string {
+	return ClaveArchivo(carpeta, strings.TrimSuffix(nombre, filepath.Ext(nombre))+"."+sufijo)
+}
+
+// ProcesarImagen quita los metadatos (EXIF con GPS, XMP, IPTC, comentarios) del original y genera las
+// variantes de archivos.Variantes. Las variantes de JPEG se codifican en JPEG y las de PNG y GIF en PNG; con
+// IMAGENES_WEBP=true también se genera una versión WebP de cada variante usando cwebp.
+func ProcesarImagen(contenido []byte, mime string, archivos config.Archivos) (*ImagenProcesada, error) {
+	configuracion, _, err := image.DecodeConfig(bytes.NewReader(contenido))
+	if err != nil || configuracion.Width*configuracion.Height > maxPixelesImagen {
+		return nil, ErrImagenInvalida
+	}
+
+	procesada := &ImagenProcesada{Original: contenido}
+	orientacion := 1
+	switch mime {
+	case "image/jpeg":
+		if procesada.Original, orientacion, err = quitarMetadatosJPEG(contenido); err != nil {
+			return nil, ErrImagenInvalida
+		}
+	case "image/png":
+		if procesada.Original, err = quitarMetadatosPNG(contenido); err != nil {
+			return nil, ErrImagenInvalida
+		}
+	}
+
+	imagen, _, err := image.Decode(bytes.NewReader(procesada.Original))
+	if err != nil {
+		return nil, ErrImagenInvalida
+	}
+	if orientacion > 1 {
+		// Sin la etiqueta EXIF los visores ya no rotarían la foto: se aplica la orientación a los píxeles
+		imagen = orientarImagen(imagen, orientacion)
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, imagen, &jpeg.Options{Quality: calidadOriginalJPEG}); err != nil {
+			return nil, err
+		}
+		procesada.Original = buf.Bytes()
+	}
+
+	for _, variante := range archivos.Variantes {
+		reducida := redimensionar(imagen, variante.Lado)
+		if reducida == nil {
+			// La imagen ya es más chica que la variante: se sirve el original
+			continue
+		}
+		var buf bytes.Buffer
+		extension, mimeVariante := "png", "image/png"
+		if mime == "image/jpeg" {
+			extension, mimeVariante = "jpg", "image/jpeg"
+			err = jpeg.Encode(&buf, reducida, &jpeg.Options{Quality: archivos.Calidad})
+		} else {
+			err = png.Encode(&buf, reducida)
+		}
+		if err != nil {
+			return nil, err
+		}
+		procesada.Variantes = append(procesada.Variantes, ArchivoVariante{Sufijo: variante.Nombre + "." + extension, Contenido: buf.Bytes(), MIME: mimeVariante})
+
+		if archivos.WebP {
+			contenidoWebP, err := codificarWebP(reducida, archivos)
+			if err != nil {
+				return nil, fmt.Errorf("no se pudo generar la variante WebP: %w", err)
+			}
+			procesada.Variantes = append(procesada.Variantes, ArchivoVariante{Sufijo: variante.Nombre + ".webp", Contenido: contenidoWebP, MIME: "image/webp"})
+		}
+	}
+	return procesada, nil
+}
+
+// redimensionar reduce la imagen para que su lado mayor mida lado píxeles; nil si ya es más chica
+func redimensionar(imagen image.Image, lado int) image.Image {
+	ancho, alto := imagen.Bounds().Dx(), imagen.Bounds().Dy()
+	if ancho <= lado && alto <= lado {
+		return nil
+	}
+	nuevoAncho, nuevoAlto := lado, alto*lado/ancho
+	if alto > ancho {
+		nuevoAncho, nuevoAlto = ancho*lado/alto, lado
+	}
+	destino := image.NewRGBA(image.Rect(0, 0, max(nuevoAncho, 1), max(nuevoAlto, 1)))
+	draw.CatmullRom.Scale(destino, destino.Bounds(), imagen, imagen.Bounds(), draw.Src, nil)
+	return destino
+}
+
+// segmentosJPEGConservados son los APPn que no llevan datos personales: JFIF (APP0), perfil ICC (APP2)
+// y Adobe (APP14, necesario para interpretar los colores CMYK)
+var segmentosJPEGConservados = map[byte]bool{0xE0: true, 0xE2: true, 0xEE: true}
+
+// quitarMetadatosJPEG elimina los segmentos EXIF/XMP (APP1), IPTC (APP13), el resto de APPn y los
+// comentarios sin volver a comprimir la imagen. Devuelve la orientación EXIF que tenía (1 si no tenía).
+func quitarMetadatosJPEG(contenido []byte) ([]byte, int, error) {
+	if len(contenido) < 4 || contenido[0] != 0xFF || contenido[1] != 0xD8 {
+		return nil, 0, ErrImagenInvalida
+	}
+	salida := bytes.NewBuffer(make([]byte, 0, len(contenido)))
+	salida.Write(contenido[:2])
+	orientacion := 1
+
+	i := 2
+	for i+4 <= len(contenido) {
+		if contenido[i] != 0xFF {
+			return nil, 0, ErrImagenInvalida
+		}
+		marcador := contenido[i+1]
+		if marcador == 0xFF {
+			// Relleno entre segmentos
+			i++
+			continue
+		}
+		if marcador == 0xDA {
+			// Inicio de los datos comprimidos: el resto se copia tal cual
+			salida.Write(contenido[i:])
+			return salida.Bytes(), orientacion, nil
+		}
+		longitud := int(binary.BigEndian.Uint16(contenido[i+2:]))
+		fin := i + 2 + longitud
+		if longitud < 2 || fin > len(contenido) {
+			return nil, 0, ErrImagenInvalida
+		}
+		esMetadato := marcador == 0xFE || (marcador >= 0xE0 && marcador <= 0xEF && !segmentosJPEGConservados[marcador])
+		if marcador == 0xE1 {
+			if o := orientacionEXIF(contenido[i+4 : fin]); o > 0 {
+				orientacion = o
+			}
+		}
+		if !esMetadato {
+			salida.Write(contenido[i:fin])
+		}
+		i = fin
+	}
+	return nil, 0, ErrImagenInvalida
+}
+
+// orientacionEXIF lee la etiqueta Orientation (0x0112) del IFD0 de un segmento APP1 Exif; 0 si no está
+func orientacionEXIF(segmento []byte) int {
+	tiff, ok := bytes.CutPrefix(segmento, []byte("Exif\x00\x00"))
+	if !ok || len(tiff) < 8 {
+		return 0
+	}
+	var orden binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		orden = binary.LittleEndian
+	case "MM":
+		orden = binary.BigEndian
+	default:
+		return 0
+	}
+	ifd := int(orden.Uint32(tiff[4:]))
+	if ifd+2 > len(tiff) {
+		return 0
+	}
+	entradas := int(orden.Uint16(tiff[ifd:]))
+	for e := 0; e < entradas; e++ {
+		inicio := ifd + 2 + e*12
+		if inicio+12 > len(tiff) {
+			return 0
+		}
+		if orden.Uint16(tiff[inicio:]) == 0x0112 {
+			valor := int(orden.Uint16(tiff[inicio+8:]))
+			if valor >= 1 && valor <= 8 {
+				return valor
+			}
+			return 0
+		}
+	}
+	return 0
+}
+
+// orientarImagen aplica a los píxeles una orientación EXIF (2 a 8)
+func orientarImagen(imagen image.Image, orientacion int) image.Image {
+	b := imagen.Bounds()
+	ancho, alto := b.Dx(), b.Dy()
+	transpuesta := orientacion >= 5
+	destinoAncho, destinoAlto := ancho, alto
+	if transpuesta {
+		destinoAncho, destinoAlto = alto, ancho
+	}
+	destino := image.NewRGBA(image.Rect(0, 0, destinoAncho, destinoAlto))
+	for y := 0; y < alto; y++ {
+		for x := 0; x < ancho; x++ {
+			var dx, dy int
+			switch orientacion {
+			case 2:
+				dx, dy = ancho-1-x, y
+			case 3:
+				dx, dy = ancho-1-x, alto-1-y
+			case 4:
+				dx, dy = x, alto-1-y
+			case 5:
+				dx, dy = y, x
+			case 6:
+				dx, dy = alto-1-y, x
+			case 7:
+				dx, dy = alto-1-y, ancho-1-x
+			case 8:
+				dx, dy = y, ancho-1-x
+			default:
+				dx, dy = x, y
+			}
+			destino.Set(dx, dy, imagen.At(b.Min.X+x, b.Min.Y+y))
+		}
+	}
+	return destino
+}
+
+// fragmentosPNGEliminados llevan texto libre, EXIF o la fecha de modificación
+var fragmentosPNGEliminados = map[string]bool{"tEXt": true, "zTXt": true, "iTXt": true, "eXIf": true, "tIME": true}
+
+// quitarMetadatosPNG elimina los fragmentos de texto y EXIF sin volver a comprimir la imagen
+func quitarMetadatosPNG(contenido []byte) ([]byte, error) {
+	const firma = "\x89PNG\r\n\x1a\n"
+	if !bytes.HasPrefix(contenido, []byte(firma)) {
+		return nil, ErrImagenInvalida
+	}
+	salida := bytes.NewBuffer(make([]byte, 0, len(contenido)))
+	salida.WriteString(firma)
+	for i := len(firma); i < len(contenido); {
+		if i+8 > len(contenido) {
+			return nil, ErrImagenInvalida
+		}
+		longitud := int(binary.BigEndian.Uint32(contenido[i:]))
+		fin := i + 12 + longitud
+		if longitud < 0 || fin > len(contenido) {
+			return nil, ErrImagenInvalida
+		}
+		tipo := string(contenido[i+4 : i+8])
+		if !fragmentosPNGEliminados[tipo] {
+			salida.Write(contenido[i:fin])
+		}
+		i = fin
+		if tipo == "IEND" {
+			break
+		}
+	}
+	return salida.Bytes(), nil
+}
+
+// codificarWebP usa el programa cwebp (IMAGENES_CWEBP, "cwebp" por defecto), porque la biblioteca
+// estándar no incluye un codificador WebP
+func codificarWebP(imagen image.Image, archivos config.Archivos) ([]byte, error) {
+	directorio, err := os.MkdirTemp("", "webp")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(directorio)
+	entrada := filepath.Join(directorio, "entrada.png")
+	salida := filepath.Join(directorio, "salida.webp")
+
+	archivo, err := os.Create(entrada)
+	if err != nil {
+		return nil, err
+	}
+	err = png.Encode(archivo, imagen)
+	if cerrarErr := archivo.Close(); err == nil {
+		err = cerrarErr
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancelar := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancelar()
+	comando := exec.CommandContext(ctx, archivos.CWebP, "-quiet", "-q", strconv.Itoa(archivos.Calidad), entrada, "-o", salida)
+	if detalle, err := comando.CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("%v: %s", err, strings.TrimSpace(string(detalle)))
+	}
+	return os.ReadFile(salida)
+}
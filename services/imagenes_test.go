@@ -0,0 +1,137 @@
+package services
+
+import (
+	"ApiEscuela/config"
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+func imagenPrueba(ancho, alto int) image.Image {
+	imagen := image.NewRGBA(image.Rect(0, 0, ancho, alto))
+	for y := 0; y < alto; y++ {
+		for x := 0; x < ancho; x++ {
+			imagen.Set(x, y, color.RGBA{uint8(x), uint8(y), 128, 255})
+		}
+	}
+	return imagen
+}
+
+// segmentoEXIF arma un APP1 Exif big-endian con la orientación y un texto que simula la posición GPS
+func segmentoEXIF(orientacion uint16) []byte {
+	var tiff bytes.Buffer
+	tiff.WriteString("MM\x00\x2a")
+	binary.Write(&tiff, binary.BigEndian, uint32(8))
+	binary.Write(&tiff, binary.BigEndian, uint16(1))
+	binary.Write(&tiff, binary.BigEndian, []uint16{0x0112, 3})
+	binary.Write(&tiff, binary.BigEndian, uint32(1))
+	binary.Write(&tiff, binary.BigEndian, []uint16{orientacion, 0})
+	binary.Write(&tiff, binary.BigEndian, uint32(0))
+	tiff.WriteString("GPS -1.0123,-79.4567")
+
+	datos := append([]byte("Exif\x00\x00"), tiff.Bytes()...)
+	segmento := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(segmento[2:], uint16(len(datos)+2))
+	return append(segmento, datos...)
+}
+
+func jpegConMetadatos(t *testing.T, ancho, alto int, orientacion uint16) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, imagenPrueba(ancho, alto), nil); err != nil {
+		t.Fatal(err)
+	}
+	original := buf.Bytes()
+	comentario := []byte{0xFF, 0xFE, 0, 12}
+	comentario = append(comentario, "Autor Juan"...)
+	var resultado []byte
+	resultado = append(resultado, original[:2]...)
+	resultado = append(resultado, segmentoEXIF(orientacion)...)
+	resultado = append(resultado, comentario...)
+	return append(resultado, original[2:]...)
+}
+
+func TestProcesarImagenJPEG(t *testing.T) {
+	contenido := jpegConMetadatos(t, 800, 400, 6)
+
+	procesada, err := ProcesarImagen(contenido, "image/jpeg", config.PorDefecto().Archivos)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, rastro := range []string{"Exif", "GPS", "Autor Juan"} {
+		if bytes.Contains(procesada.Original, []byte(rastro)) {
+			t.Errorf("el original conserva %q", rastro)
+		}
+	}
+
+	// Orientación 6: la foto se guarda girada 90° para que se vea igual sin la etiqueta EXIF
+	configuracion, err := jpeg.DecodeConfig(bytes.NewReader(procesada.Original))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if configuracion.Width != 400 || configuracion.Height != 800 {
+		t.Errorf("original %dx%d, se esperaba 400x800", configuracion.Width, configuracion.Height)
+	}
+
+	// medium (1024) es más grande que la foto: no se genera
+	if len(procesada.Variantes) != 1 || procesada.Variantes[0].Sufijo != "thumb.jpg" {
+		t.Fatalf("variantes %+v, se esperaba solo thumb.jpg", procesada.Variantes)
+	}
+	miniatura, err := jpeg.DecodeConfig(bytes.NewReader(procesada.Variantes[0].Contenido))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if miniatura.Width != 160 || miniatura.Height != 320 {
+		t.Errorf("miniatura %dx%d, se esperaba 160x320", miniatura.Width, miniatura.Height)
+	}
+}
+
+func TestProcesarImagenJPEGSinRotacionNoRecomprime(t *testing.T) {
+	contenido := jpegConMetadatos(t, 100, 50, 1)
+	procesada, err := ProcesarImagen(contenido, "image/jpeg", config.PorDefecto().Archivos)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var esperado bytes.Buffer
+	jpeg.Encode(&esperado, imagenPrueba(100, 50), nil)
+	if !bytes.Equal(procesada.Original, esperado.Bytes()) {
+		t.Error("sin orientación, el original debería ser el mismo JPEG sin los segmentos de metadatos")
+	}
+}
+
+func TestProcesarImagenPNG(t *testing.T) {
+	archivos := config.PorDefecto().Archivos
+	archivos.Variantes = []config.VarianteImagen{{Nombre: "thumb", Lado: 64}}
+	var buf bytes.Buffer
+	png.Encode(&buf, imagenPrueba(200, 100))
+	original := buf.Bytes()
+
+	// Fragmento tEXt insertado después de IHDR (8 bytes de firma + 25 del IHDR)
+	datos := []byte("Comment\x00GPS -1.0123,-79.4567")
+	texto := make([]byte, 8, 12+len(datos))
+	binary.BigEndian.PutUint32(texto, uint32(len(datos)))
+	copy(texto[4:], "tEXt")
+	texto = append(texto, datos...)
+	texto = binary.BigEndian.AppendUint32(texto, crc32.ChecksumIEEE(texto[4:]))
+	contenido := append(append(append([]byte{}, original[:33]...), texto...), original[33:]...)
+
+	procesada, err := ProcesarImagen(contenido, "image/png", archivos)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(procesada.Original, original) {
+		t.Error("el PNG debería quedar igual al original sin el fragmento tEXt")
+	}
+	if len(procesada.Variantes) != 1 || procesada.Variantes[0].Sufijo != "thumb.png" {
+		t.Fatalf("variantes %+v, se esperaba thumb.png", procesada.Variantes)
+	}
+	miniatura, err := png.DecodeConfig(bytes.NewReader(procesada.Variantes[0].Contenido))
+	if err != nil || miniatura.Width != 64 || miniatura.Height != 32 {
+		t.Errorf("miniatura %+v (%v), se esperaba 64x32", miniatura, err)
+	}
+}
@@ -0,0 +1,347 @@
+package services
+
+import (
+	"ApiEscuela/config"
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+var (
+	ErrTipoArchivoNoPermitido = errors.New("tipo de archivo no permitido")
+	ErrContenidoNoCoincide    = errors.New("el contenido del archivo no corresponde a su extensión")
+	ErrArchivoEjecutable      = errors.New("no se permiten archivos ejecutables ni con macros")
+	ErrArchivoDemasiadoGrande = errors.New("el archivo es demasiado grande")
+)
+
+// Categorías de archivo; cada una se guarda en su carpeta del almacenamiento
+const (
+	CategoriaImagen    = "image"
+	CategoriaVideo     = "video"
+	CategoriaDocumento = "document"
+)
+
+// tipoPermitido describe una extensión aceptada: su categoría, el MIME que se registra y cómo
+// reconocer su contenido a partir de los primeros bytes
+type tipoPermitido struct {
+	categoria string
+	mime      string
+	coincide  func(cabecera []byte) bool
+}
+
+var tiposPermitidos = map[string]tipoPermitido{
+	".jpg":  {CategoriaImagen, "image/jpeg", detectado("image/jpeg")},
+	".jpeg": {CategoriaImagen, "image/jpeg", detectado("image/jpeg")},
+	".png":  {CategoriaImagen, "image/png", detectado("image/png")},
+	".gif":  {CategoriaImagen, "image/gif", detectado("image/gif")},
+	".mp4":  {CategoriaVideo, "video/mp4", esCajaISOBMFF("ftyp")},
+	".mov":  {CategoriaVideo, "video/quicktime", esCajaISOBMFF("ftyp", "moov", "mdat", "wide", "free", "skip")},
+	".avi":  {CategoriaVideo, "video/x-msvideo", detectado("video/avi")},
+	".pdf":  {CategoriaDocumento, "application/pdf", detectado("application/pdf")},
+	".doc":  {CategoriaDocumento, "application/msword", empiezaCon(firmaOLE)},
+	".docx": {CategoriaDocumento, "application/vnd.openxmlformats-officedocument.wordprocessingml.document", empiezaCon("PK\x03\x04")},
+	".txt":  {CategoriaDocumento, "text/plain; charset=utf-8", detectado("text/plain; charset=utf-8")},
+}
+
+// firmasEjecutables son los encabezados de binarios y scripts que se rechazan aunque la extensión sea válida
+var firmasEjecutables = []string{
+	"MZ",               // Windows PE / DOS
+	"\x7fELF",          // Linux
+	"\xFE\xED\xFA\xCE", // Mach-O 32 bits
+	"\xFE\xED\xFA\xCF", // Mach-O 64 bits
+	"\xCE\xFA\xED\xFE",
+	"\xCF\xFA\xED\xFE",
+	"\xCA\xFE\xBA\xBE", // Mach-O universal / clases Java
+	"#!",               // scripts con shebang
+}
+
+// firmaOLE es el encabezado de los archivos compuestos OLE (.doc)
+const firmaOLE = "\xD0\xCF\x11\xE0\xA1\xB1\x1A\xE1"
+
+// sectorMaximoOLE es el mayor número de sector válido; los valores siguientes marcan el fin de una cadena
+// o sectores libres
+const sectorMaximoOLE = 0xFFFFFFFA
+
+// almacenamientosConMacros son los nombres con los que Office guarda el proyecto VBA dentro de un archivo OLE
+var almacenamientosConMacros = []string{"Macros", "VBA", "_VBA_PROJECT_CUR"}
+
+// categoriasArchivo son las categorías de archivos que se aceptan
+var categoriasArchivo = []string{CategoriaImagen, CategoriaVideo, CategoriaDocumento}
+
+// ArchivoEntrante es un archivo recibido, ya sea de un formulario o de una subida reanudable completa
+type ArchivoEntrante struct {
+	Nombre string
+	Tamano int64
+	abrir  func() (multipart.File, error)
+}
+
+// ArchivoDeFormulario toma el archivo de un formulario multipart
+func ArchivoDeFormulario(archivo *multipart.FileHeader) ArchivoEntrante {
+	return ArchivoEntrante{Nombre: archivo.Filename, Tamano: archivo.Size, abrir: archivo.Open}
+}
+
+// ArchivoEnDisco toma un archivo temporal del disco, que se presenta con el nombre original indicado
+func ArchivoEnDisco(ruta, nombre string) (ArchivoEntrante, error) {
+	info, err := os.Stat(ruta)
+	if err != nil {
+		return ArchivoEntrante{}, err
+	}
+	return ArchivoEntrante{Nombre: nombre, Tamano: info.Size(), abrir: func() (multipart.File, error) { return os.Open(ruta) }}, nil
+}
+
+// Open abre el contenido para leerlo desde el principio
+func (a ArchivoEntrante) Open() (multipart.File, error) {
+	return a.abrir()
+}
+
+// ArchivoInspeccionado es el resultado de examinar el contenido de un archivo subido
+type ArchivoInspeccionado struct {
+	Categoria string
+	Carpeta   string
+	MIME      string
+}
+
+// InspeccionarArchivo determina el tipo real del archivo a partir de su contenido, sin confiar en el
+// Content-Type enviado por el cliente. Rechaza extensiones no permitidas, contenido que no corresponde
+// a la extensión, ejecutables y archivos que superan el límite de su categoría en archivos.
+func InspeccionarArchivo(archivo ArchivoEntrante, archivos config.Archivos) (*ArchivoInspeccionado, error) {
+	extension := strings.ToLower(filepath.Ext(archivo.Nombre))
+	tipo, ok := tiposPermitidos[extension]
+	if !ok {
+		return nil, ErrTipoArchivoNoPermitido
+	}
+	if limite := LimiteSubida(archivos, tipo.categoria); archivo.Tamano > limite {
+		return nil, fmt.Errorf("%w (máximo %d MB para %s)", ErrArchivoDemasiadoGrande, limite/(1024*1024), carpetaCategoria(tipo.categoria))
+	}
+
+	contenido, err := archivo.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer contenido.Close()
+	cabecera := make([]byte, 512)
+	n, err := io.ReadFull(contenido, cabecera)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	cabecera = cabecera[:n]
+
+	for _, firma := range firmasEjecutables {
+		if bytes.HasPrefix(cabecera, []byte(firma)) {
+			return nil, ErrArchivoEjecutable
+		}
+	}
+	if !tipo.coincide(cabecera) {
+		return nil, ErrContenidoNoCoincide
+	}
+
+	switch extension {
+	case ".txt":
+		if err := validarTextoPlano(contenido, cabecera); err != nil {
+			return nil, err
+		}
+	case ".doc":
+		if err := validarDoc(contenido, archivo.Tamano); err != nil {
+			return nil, err
+		}
+	case ".docx":
+		if err := validarDocx(contenido, archivo.Tamano); err != nil {
+			return nil, err
+		}
+	}
+
+	return &ArchivoInspeccionado{Categoria: tipo.categoria, Carpeta: carpetaCategoria(tipo.categoria), MIME: tipo.mime}, nil
+}
+
+// LimiteSubida es el tamaño máximo en bytes de un archivo de la categoría (LIMITE_IMAGENES_MB,
+// LIMITE_VIDEOS_MB y LIMITE_DOCUMENTOS_MB)
+func LimiteSubida(archivos config.Archivos, categoria string) int64 {
+	var mb int
+	switch categoria {
+	case CategoriaImagen:
+		mb = archivos.LimiteImagenesMB
+	case CategoriaVideo:
+		mb = archivos.LimiteVideosMB
+	case CategoriaDocumento:
+		mb = archivos.LimiteDocumentosMB
+	}
+	return int64(mb) * 1024 * 1024
+}
+
+// CategoriaPorExtension devuelve la categoría que corresponde a la extensión del nombre, o "" si no se permite
+func CategoriaPorExtension(nombre string) string {
+	return tiposPermitidos[strings.ToLower(filepath.Ext(nombre))].categoria
+}
+
+func carpetaCategoria(categoria string) string {
+	switch categoria {
+	case CategoriaImagen:
+		return "images"
+	case CategoriaVideo:
+		return "videos"
+	}
+	return "documents"
+}
+
+// validarTextoPlano exige UTF-8 válido y sin bytes nulos en todo el archivo
+func validarTextoPlano(contenido io.Reader, cabecera []byte) error {
+	resto, err := io.ReadAll(contenido)
+	if err != nil {
+		return err
+	}
+	texto := append(cabecera, resto...)
+	if !utf8.Valid(texto) || bytes.IndexByte(texto, 0) >= 0 {
+		return ErrContenidoNoCoincide
+	}
+	return nil
+}
+
+// validarDocx comprueba que el ZIP sea un documento de Word y que no traiga macros
+func validarDocx(contenido multipart.File, tamano int64) error {
+	lector, err := zip.NewReader(contenido, tamano)
+	if err != nil {
+		return ErrContenidoNoCoincide
+	}
+	documento := false
+	for _, f := range lector.File {
+		nombre := strings.ToLower(f.Name)
+		if strings.HasSuffix(nombre, "vbaproject.bin") {
+			return ErrArchivoEjecutable
+		}
+		if nombre == "word/document.xml" {
+			documento = true
+		}
+	}
+	if !documento {
+		return ErrContenidoNoCoincide
+	}
+	return nil
+}
+
+// validarDoc recorre el directorio del archivo compuesto OLE y rechaza los documentos con un proyecto VBA.
+// Un archivo cuya estructura no se puede recorrer no es un .doc válido.
+func validarDoc(contenido io.ReaderAt, tamano int64) error {
+	cabecera := make([]byte, 512)
+	if _, err := contenido.ReadAt(cabecera, 0); err != nil {
+		return ErrContenidoNoCoincide
+	}
+	desplazamiento := binary.LittleEndian.Uint16(cabecera[0x1E:])
+	if desplazamiento != 9 && desplazamiento != 12 {
+		return ErrContenidoNoCoincide
+	}
+	tamSector := int64(1) << desplazamiento
+	totalSectores := (tamano+tamSector-1)/tamSector - 1
+	// Algunos programas no completan el último sector; lo que falta se lee como ceros
+	leerSector := func(numero uint32) ([]byte, error) {
+		if int64(numero) >= totalSectores {
+			return nil, ErrContenidoNoCoincide
+		}
+		sector := make([]byte, tamSector)
+		if n, err := contenido.ReadAt(sector, (int64(numero)+1)*tamSector); n == 0 || (err != nil && !errors.Is(err, io.EOF)) {
+			return nil, ErrContenidoNoCoincide
+		}
+		return sector, nil
+	}
+
+	// Los sectores de la FAT se listan en la cabecera y, en archivos grandes, en la cadena DIFAT
+	var sectoresFAT []uint32
+	for i := 0; i < 109; i++ {
+		if s := binary.LittleEndian.Uint32(cabecera[0x4C+4*i:]); s <= sectorMaximoOLE {
+			sectoresFAT = append(sectoresFAT, s)
+		}
+	}
+	difat := binary.LittleEndian.Uint32(cabecera[0x44:])
+	for leidos := int64(0); difat <= sectorMaximoOLE; leidos++ {
+		if leidos >= totalSectores {
+			return ErrContenidoNoCoincide
+		}
+		sector, err := leerSector(difat)
+		if err != nil {
+			return err
+		}
+		for i := int64(0); i < tamSector/4-1; i++ {
+			if s := binary.LittleEndian.Uint32(sector[4*i:]); s <= sectorMaximoOLE {
+				sectoresFAT = append(sectoresFAT, s)
+			}
+		}
+		difat = binary.LittleEndian.Uint32(sector[tamSector-4:])
+	}
+	var fat []uint32
+	for _, s := range sectoresFAT {
+		sector, err := leerSector(s)
+		if err != nil {
+			return err
+		}
+		for i := int64(0); i < tamSector/4; i++ {
+			fat = append(fat, binary.LittleEndian.Uint32(sector[4*i:]))
+		}
+	}
+
+	// Cada entrada del directorio ocupa 128 bytes y empieza con su nombre en UTF-16
+	directorio := binary.LittleEndian.Uint32(cabecera[0x30:])
+	for leidos := 0; directorio <= sectorMaximoOLE; leidos++ {
+		if leidos > len(fat) || int(directorio) >= len(fat) {
+			return ErrContenidoNoCoincide
+		}
+		sector, err := leerSector(directorio)
+		if err != nil {
+			return err
+		}
+		for inicio := int64(0); inicio+128 <= tamSector; inicio += 128 {
+			entrada := sector[inicio : inicio+128]
+			largo := int(binary.LittleEndian.Uint16(entrada[0x40:]))
+			if entrada[0x42] == 0 || largo < 2 || largo > 64 {
+				continue // Entrada sin usar
+			}
+			unidades := make([]uint16, (largo-2)/2)
+			for i := range unidades {
+				unidades[i] = binary.LittleEndian.Uint16(entrada[2*i:])
+			}
+			nombre := string(utf16.Decode(unidades))
+			for _, macros := range almacenamientosConMacros {
+				if strings.EqualFold(nombre, macros) {
+					return ErrArchivoEjecutable
+				}
+			}
+		}
+		directorio = fat[directorio]
+	}
+	return nil
+}
+
+func detectado(mime string) func([]byte) bool {
+	return func(cabecera []byte) bool {
+		return http.DetectContentType(cabecera) == mime
+	}
+}
+
+func empiezaCon(firma string) func([]byte) bool {
+	return func(cabecera []byte) bool {
+		return bytes.HasPrefix(cabecera, []byte(firma))
+	}
+}
+
+// esCajaISOBMFF reconoce los contenedores MP4/QuickTime por el tipo de la primera caja (bytes 4 a 8)
+func esCajaISOBMFF(tipos ...string) func([]byte) bool {
+	return func(cabecera []byte) bool {
+		if len(cabecera) < 8 {
+			return false
+		}
+		for _, tipo := range tipos {
+			if string(cabecera[4:8]) == tipo {
+				return true
+			}
+		}
+		return false
+	}
+}
@@ -0,0 +1,152 @@
+package services
+
+import (
+	"ApiEscuela/config"
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"mime/multipart"
+	"strings"
+	"testing"
+	"unicode/utf16"
+)
+
+// archivoSubido arma un archivo como el que entrega Fiber para un formulario
+func archivoSubido(t *testing.T, nombre string, contenido []byte) ArchivoEntrante {
+	t.Helper()
+	var cuerpo bytes.Buffer
+	escritor := multipart.NewWriter(&cuerpo)
+	parte, err := escritor.CreateFormFile("file", nombre)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parte.Write(contenido)
+	escritor.Close()
+
+	formulario, err := multipart.NewReader(&cuerpo, escritor.Boundary()).ReadForm(1 << 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { formulario.RemoveAll() })
+	return ArchivoDeFormulario(formulario.File["file"][0])
+}
+
+func zipConArchivos(t *testing.T, nombres ...string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	escritor := zip.NewWriter(&buf)
+	for _, nombre := range nombres {
+		f, err := escritor.Create(nombre)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Write([]byte("<xml/>"))
+	}
+	escritor.Close()
+	return buf.Bytes()
+}
+
+// docOLE arma un archivo compuesto OLE mínimo (sectores de 512 bytes: cabecera, FAT y directorio) con las
+// entradas indicadas en el directorio
+func docOLE(nombres ...string) []byte {
+	archivo := make([]byte, 3*512)
+	cabecera, fat, directorio := archivo[:512], archivo[512:1024], archivo[1024:]
+	copy(cabecera, firmaOLE)
+	binary.LittleEndian.PutUint16(cabecera[0x18:], 0x3E)
+	binary.LittleEndian.PutUint16(cabecera[0x1A:], 3)
+	binary.LittleEndian.PutUint16(cabecera[0x1C:], 0xFFFE)
+	binary.LittleEndian.PutUint16(cabecera[0x1E:], 9)
+	binary.LittleEndian.PutUint16(cabecera[0x20:], 6)
+	binary.LittleEndian.PutUint32(cabecera[0x2C:], 1) // Un sector de FAT
+	binary.LittleEndian.PutUint32(cabecera[0x30:], 1) // El directorio empieza en el sector 1
+	binary.LittleEndian.PutUint32(cabecera[0x38:], 4096)
+	binary.LittleEndian.PutUint32(cabecera[0x3C:], 0xFFFFFFFE)
+	binary.LittleEndian.PutUint32(cabecera[0x44:], 0xFFFFFFFE)
+	for i := 0; i < 109; i++ {
+		binary.LittleEndian.PutUint32(cabecera[0x4C+4*i:], 0xFFFFFFFF)
+	}
+	binary.LittleEndian.PutUint32(cabecera[0x4C:], 0) // La FAT está en el sector 0
+
+	for i := 0; i < 128; i++ {
+		binary.LittleEndian.PutUint32(fat[4*i:], 0xFFFFFFFF)
+	}
+	binary.LittleEndian.PutUint32(fat[0:], 0xFFFFFFFD) // Sector de FAT
+	binary.LittleEndian.PutUint32(fat[4:], 0xFFFFFFFE) // Fin de la cadena del directorio
+
+	for i, nombre := range append([]string{"Root Entry"}, nombres...) {
+		entrada := directorio[128*i : 128*(i+1)]
+		unidades := utf16.Encode([]rune(nombre))
+		for j, u := range unidades {
+			binary.LittleEndian.PutUint16(entrada[2*j:], u)
+		}
+		binary.LittleEndian.PutUint16(entrada[0x40:], uint16(2*len(unidades)+2))
+		entrada[0x42] = 2 // Flujo
+	}
+	directorio[0x42] = 5 // Raíz
+	return archivo
+}
+
+func TestInspeccionarArchivo(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	mp4 := []byte("\x00\x00\x00\x18ftypmp42\x00\x00\x00\x00mp42isom")
+	mov := []byte("\x00\x00\x00\x14ftypqt  \x00\x00\x00\x00qt  ")
+
+	casos := []struct {
+		nombre    string
+		contenido []byte
+		carpeta   string
+		err       error
+	}{
+		{"foto.PNG", png, "images", nil},
+		{"foto.jpg", []byte("\xFF\xD8\xFF\xE0\x00\x10JFIF"), "images", nil},
+		{"clip.mp4", mp4, "videos", nil},
+		{"clip.mov", mov, "videos", nil},
+		{"acta.pdf", []byte("%PDF-1.7\n"), "documents", nil},
+		{"nota.txt", []byte("Reunión el martes"), "documents", nil},
+		{"informe.docx", zipConArchivos(t, "[Content_Types].xml", "word/document.xml"), "documents", nil},
+		{"informe.doc", docOLE("WordDocument", "1Table"), "documents", nil},
+
+		{"foto.exe", png, "", ErrTipoArchivoNoPermitido},
+		{"foto.jpg", png, "", ErrContenidoNoCoincide},
+		{"clip.mp4", []byte("no es un video"), "", ErrContenidoNoCoincide},
+		{"nota.txt", []byte("<html><script>alert(1)</script>"), "", ErrContenidoNoCoincide},
+		{"nota.txt", []byte("texto\x00binario"), "", ErrContenidoNoCoincide},
+		{"informe.docx", zipConArchivos(t, "otro.txt"), "", ErrContenidoNoCoincide},
+		{"informe.docx", zipConArchivos(t, "word/document.xml", "word/vbaProject.bin"), "", ErrArchivoEjecutable},
+		{"informe.doc", docOLE("WordDocument", "Macros"), "", ErrArchivoEjecutable},
+		{"informe.doc", docOLE("WordDocument", "_VBA_PROJECT_CUR"), "", ErrArchivoEjecutable},
+		{"informe.doc", []byte(firmaOLE + "truncado"), "", ErrContenidoNoCoincide},
+		{"foto.jpg", []byte("MZ\x90\x00\x03\x00\x00\x00"), "", ErrArchivoEjecutable},
+		{"nota.txt", []byte("#!/bin/sh\nrm -rf /\n"), "", ErrArchivoEjecutable},
+		{"acta.pdf", []byte("\x7fELF\x02\x01\x01"), "", ErrArchivoEjecutable},
+	}
+	for _, caso := range casos {
+		resultado, err := InspeccionarArchivo(archivoSubido(t, caso.nombre, caso.contenido), config.PorDefecto().Archivos)
+		if caso.err != nil {
+			if !errors.Is(err, caso.err) {
+				t.Errorf("%s %q: error %v, se esperaba %v", caso.nombre, caso.contenido[:min(len(caso.contenido), 12)], err, caso.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", caso.nombre, err)
+			continue
+		}
+		if resultado.Carpeta != caso.carpeta {
+			t.Errorf("%s: carpeta %q, se esperaba %q", caso.nombre, resultado.Carpeta, caso.carpeta)
+		}
+	}
+}
+
+func TestInspeccionarArchivoLimitePorCategoria(t *testing.T) {
+	archivos := config.PorDefecto().Archivos
+	archivos.LimiteDocumentosMB = 1
+	texto := []byte(strings.Repeat("a", 1024*1024+1))
+	if _, err := InspeccionarArchivo(archivoSubido(t, "nota.txt", texto), archivos); !errors.Is(err, ErrArchivoDemasiadoGrande) {
+		t.Errorf("documento de más de 1 MB: %v, se esperaba ErrArchivoDemasiadoGrande", err)
+	}
+	if _, err := InspeccionarArchivo(archivoSubido(t, "nota.txt", texto[:1024*1024]), archivos); err != nil {
+		t.Errorf("documento de 1 MB: %v", err)
+	}
+}
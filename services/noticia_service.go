@@ -0,0 +1,876 @@
+package services
+
+import (
+	"ApiEscuela/models"
+	"ApiEscuela/repositories"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+)
+
+const (
+	porPaginaNoticiasPorDef = 20
+	maxPorPaginaNoticias    = 50
+)
+
+const (
+	maxEtiquetasNoticia        = 10
+	maxLongitudEtiquetaNoticia = 40
+	// prefijoImagenesSubidas es la ruta con la que /api/upload devuelve las imágenes
+	prefijoImagenesSubidas = "/api/files/images/"
+)
+
+var (
+	ErrNoticiaNoEncontrada       = errors.New("noticia no encontrada")
+	ErrRevisionNoEncontrada      = errors.New("revisión no encontrada")
+	ErrPublicacionNoPermitida    = errors.New("solo los editores pueden aprobar, publicar o archivar noticias")
+	ErrEdicionNoticiaNoPermitida = errors.New("solo el autor puede modificar la noticia mientras no esté programada o publicada; después, solo los editores")
+	ErrTransicionNoticiaInvalida = errors.New("la noticia no admite esta acción en su estado actual")
+	ErrSlugNoticiaEnUso          = errors.New("el slug ya lo usa otra noticia")
+	ErrCategoriaNoEncontrada     = errors.New("categoría no encontrada")
+	ErrCategoriaDuplicada        = errors.New("ya existe una categoría con ese nombre")
+	ErrImagenPortadaInvalida     = errors.New("imagen_portada_id debe ser una imagen pública subida con /api/upload")
+	ErrNoticiaModificada         = errors.New("la noticia cambió mientras se editaba; vuelva a cargarla")
+)
+
+type NoticiaService struct {
+	noticiaRepo *repositories.NoticiaRepository
+	usuarioRepo *repositories.UsuarioRepository
+	archivoRepo *repositories.ArchivoRepository
+	urlNoticias string        // NOTICIAS_URL_BASE; vacía si los enlaces apuntan a la API
+	intervalo   time.Duration // Periodo del publicador de noticias programadas
+}
+
+func NewNoticiaService(noticiaRepo *repositories.NoticiaRepository, usuarioRepo *repositories.UsuarioRepository, archivoRepo *repositories.ArchivoRepository, urlNoticias string, intervalo time.Duration) *NoticiaService {
+	return &NoticiaService{noticiaRepo: noticiaRepo, usuarioRepo: usuarioRepo, archivoRepo: archivoRepo, urlNoticias: urlNoticias, intervalo: intervalo}
+}
+
+// ConContexto devuelve una copia del servicio cuyos repositorios usan ctx, para que las consultas queden
+// dentro de la traza de la petición
+func (s *NoticiaService) ConContexto(ctx context.Context) *NoticiaService {
+	copia := *s
+	copia.noticiaRepo = s.noticiaRepo.ConContexto(ctx)
+	copia.usuarioRepo = s.usuarioRepo.ConContexto(ctx)
+	copia.archivoRepo = s.archivoRepo.ConContexto(ctx)
+	return &copia
+}
+
+// NoticiaRequest representa el contenido de una noticia; en la edición los campos nil no se modifican
+type NoticiaRequest struct {
+	Titulo          *string   `json:"titulo"`
+	Descripcion     *string   `json:"descripcion"`
+	URLNoticia      *string   `json:"url_noticia"`
+	Slug            *string   `json:"slug"`
+	CategoriaID     *uint     `json:"categoria_id"` // 0 quita la categoría
+	Etiquetas       *[]string `json:"etiquetas"`
+	ImagenPortadaID *uint     `json:"imagen_portada_id"` // ID de un archivo de /api/upload; 0 quita la portada
+}
+
+// CategoriaNoticiaRequest representa una categoría nueva o editada
+type CategoriaNoticiaRequest struct {
+	Nombre      string `json:"nombre"`
+	Descripcion string `json:"descripcion"`
+}
+
+// PublicacionRequest indica cuándo publicar una noticia y, opcionalmente, cuándo retirarla
+type PublicacionRequest struct {
+	PublicarEn *time.Time `json:"publicar_en"` // nil o pasada = publicar de inmediato
+	ExpiraEn   *time.Time `json:"expira_en"`
+}
+
+// DiffCampoNoticia es el diff de un campo entre dos versiones
+type DiffCampoNoticia struct {
+	Campo   string      `json:"campo"`
+	Cambios []LineaDiff `json:"cambios"`
+}
+
+// DiffNoticia compara dos versiones de una noticia
+type DiffNoticia struct {
+	NoticiaID uint               `json:"noticia_id"`
+	Desde     int                `json:"desde"`
+	Hasta     int                `json:"hasta"`
+	Campos    []DiffCampoNoticia `json:"campos"`
+}
+
+// Crear crea una noticia como borrador del usuario
+func (s *NoticiaService) Crear(usuarioID uint, req NoticiaRequest) (*models.Noticia, error) {
+	noticia := &models.Noticia{
+		UsuarioID: usuarioID,
+		Estado:    models.EstadoNoticiaBorrador,
+		Version:   1,
+	}
+	if err := s.aplicarNoticiaRequest(noticia, req); err != nil {
+		return nil, err
+	}
+	if noticia.Slug == "" {
+		slug, err := s.slugDisponible(generarSlug(noticia.Titulo), 0)
+		if err != nil {
+			return nil, err
+		}
+		noticia.Slug = slug
+	}
+	if err := s.noticiaRepo.CreateNoticia(noticia, revisionDe(noticia, usuarioID, "Creación")); err != nil {
+		return nil, err
+	}
+	return s.noticiaRepo.GetNoticiaByID(noticia.ID)
+}
+
+// Obtener devuelve una noticia; las no publicadas solo las ven su autor y los editores
+func (s *NoticiaService) Obtener(id, usuarioID uint) (*models.Noticia, error) {
+	noticia, err := s.noticiaRepo.GetNoticiaByID(id)
+	if err != nil {
+		return nil, ErrNoticiaNoEncontrada
+	}
+	if noticiaVisible(noticia, time.Now()) {
+		return noticia, nil
+	}
+	if noticia.UsuarioID == usuarioID {
+		return noticia, nil
+	}
+	if editor, err := s.esEditor(usuarioID); err != nil || !editor {
+		return nil, ErrNoticiaNoEncontrada
+	}
+	return noticia, nil
+}
+
+// ObtenerPorSlug devuelve una noticia por su slug con las mismas reglas de visibilidad que Obtener
+func (s *NoticiaService) ObtenerPorSlug(slug string, usuarioID uint) (*models.Noticia, error) {
+	noticia, err := s.noticiaRepo.GetNoticiaBySlug(slug)
+	if err != nil {
+		return nil, ErrNoticiaNoEncontrada
+	}
+	return s.Obtener(noticia.ID, usuarioID)
+}
+
+// PaginaPublica devuelve una página de noticias publicadas en su vista pública.
+// pagina empieza en 1; porPagina fuera de rango usa el valor por defecto.
+func (s *NoticiaService) PaginaPublica(filtro repositories.FiltroNoticias, pagina, porPagina int, urlBase string) (*PaginaNoticias, error) {
+	pagina, porPagina = normalizarPaginacion(pagina, porPagina)
+	noticias, total, err := s.noticiaRepo.GetNoticiasPublicadasPaginadas(filtro, (pagina-1)*porPagina, porPagina)
+	if err != nil {
+		return nil, err
+	}
+	resultado := &PaginaNoticias{
+		Noticias:     make([]NoticiaPublica, 0, len(noticias)),
+		Pagina:       pagina,
+		PorPagina:    porPagina,
+		Total:        total,
+		TotalPaginas: int((total + int64(porPagina) - 1) / int64(porPagina)),
+	}
+	for i := range noticias {
+		resultado.Noticias = append(resultado.Noticias, noticiaPublica(&noticias[i], urlBase, s.urlNoticias))
+	}
+	return resultado, nil
+}
+
+// NoticiaPublicaPorSlug devuelve la vista pública de una noticia publicada
+func (s *NoticiaService) NoticiaPublicaPorSlug(slug, urlBase string) (*NoticiaPublica, error) {
+	noticia, err := s.noticiaRepo.GetNoticiaBySlug(slug)
+	if err != nil || !noticiaVisible(noticia, time.Now()) {
+		return nil, ErrNoticiaNoEncontrada
+	}
+	publica := noticiaPublica(noticia, urlBase, s.urlNoticias)
+	return &publica, nil
+}
+
+// UltimaModificacion es la fecha del último cambio que puede alterar los listados públicos
+func (s *NoticiaService) UltimaModificacion() (*time.Time, error) {
+	return s.noticiaRepo.UltimaModificacionNoticias()
+}
+
+// FeedRSS genera el feed RSS 2.0 de una página de noticias publicadas
+func (s *NoticiaService) FeedRSS(filtro repositories.FiltroNoticias, pagina, porPagina int, urlBase string, feed FeedNoticias) ([]byte, error) {
+	resultado, actualizado, err := s.paginaFeed(filtro, pagina, porPagina, urlBase, &feed)
+	if err != nil {
+		return nil, err
+	}
+	return generarRSS(feed, resultado.Noticias, actualizado)
+}
+
+// FeedAtom genera el feed Atom de una página de noticias publicadas
+func (s *NoticiaService) FeedAtom(filtro repositories.FiltroNoticias, pagina, porPagina int, urlBase string, feed FeedNoticias) ([]byte, error) {
+	resultado, actualizado, err := s.paginaFeed(filtro, pagina, porPagina, urlBase, &feed)
+	if err != nil {
+		return nil, err
+	}
+	return generarAtom(feed, resultado.Noticias, actualizado)
+}
+
+// paginaFeed obtiene la página del feed y completa el enlace al listado y los de paginación a partir de feed.Propio
+func (s *NoticiaService) paginaFeed(filtro repositories.FiltroNoticias, pagina, porPagina int, urlBase string, feed *FeedNoticias) (*PaginaNoticias, time.Time, error) {
+	resultado, err := s.PaginaPublica(filtro, pagina, porPagina, urlBase)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	var actualizado time.Time
+	for _, n := range resultado.Noticias {
+		if n.ActualizadaEn.After(actualizado) {
+			actualizado = n.ActualizadaEn
+		}
+	}
+	feed.Enlace = urlListadoNoticias(urlBase, s.urlNoticias)
+	if resultado.Pagina > 1 {
+		feed.Anterior = conPagina(feed.Propio, resultado.Pagina-1)
+	}
+	if resultado.Pagina < resultado.TotalPaginas {
+		feed.Siguiente = conPagina(feed.Propio, resultado.Pagina+1)
+	}
+	return resultado, actualizado, nil
+}
+
+// ListarEditorial lista noticias en cualquier estado: todas para los editores, las propias para el resto
+func (s *NoticiaService) ListarEditorial(usuarioID uint, estado string, filtro repositories.FiltroNoticias) ([]models.Noticia, error) {
+	if estado != "" && !estadoNoticiaValido(estado) {
+		return nil, fmt.Errorf("estado inválido %q", estado)
+	}
+	editor, err := s.esEditor(usuarioID)
+	if err != nil {
+		return nil, err
+	}
+	if editor {
+		return s.noticiaRepo.GetNoticiasEditorial(estado, nil, filtro)
+	}
+	return s.noticiaRepo.GetNoticiasEditorial(estado, &usuarioID, filtro)
+}
+
+// Actualizar cambia el contenido y guarda una nueva versión si hubo cambios
+func (s *NoticiaService) Actualizar(id, usuarioID uint, req NoticiaRequest) (*models.Noticia, error) {
+	noticia, err := s.noticiaEditable(id, usuarioID)
+	if err != nil {
+		return nil, err
+	}
+	anterior := *noticia
+	if err := s.aplicarNoticiaRequest(noticia, req); err != nil {
+		return nil, err
+	}
+	if mismoContenido(&anterior, noticia) {
+		if noticia.Slug != anterior.Slug {
+			return s.guardarContenido(noticia, nil)
+		}
+		return noticia, nil
+	}
+	return s.guardarVersion(&anterior, noticia, usuarioID, "Edición")
+}
+
+// Eliminar elimina una noticia; el autor solo puede eliminar sus borradores
+func (s *NoticiaService) Eliminar(id, usuarioID uint) error {
+	noticia, err := s.noticiaRepo.GetNoticiaByID(id)
+	if err != nil {
+		return ErrNoticiaNoEncontrada
+	}
+	editor, err := s.esEditor(usuarioID)
+	if err != nil {
+		return err
+	}
+	if !editor && (noticia.UsuarioID != usuarioID || noticia.Estado != models.EstadoNoticiaBorrador) {
+		return ErrEdicionNoticiaNoPermitida
+	}
+	return s.noticiaRepo.DeleteNoticia(id)
+}
+
+// EnviarARevision pasa un borrador a revisión
+func (s *NoticiaService) EnviarARevision(id, usuarioID uint) (*models.Noticia, error) {
+	noticia, err := s.noticiaEditable(id, usuarioID)
+	if err != nil {
+		return nil, err
+	}
+	if noticia.Estado != models.EstadoNoticiaBorrador {
+		return nil, ErrTransicionNoticiaInvalida
+	}
+	leido := noticia.Estado
+	noticia.Estado = models.EstadoNoticiaEnRevision
+	return s.guardarEstado(noticia, leido)
+}
+
+// Rechazar devuelve a borrador una noticia en revisión o programada, con el motivo para el autor
+func (s *NoticiaService) Rechazar(id, usuarioID uint, comentario string) (*models.Noticia, error) {
+	noticia, err := s.noticiaDeEditor(id, usuarioID)
+	if err != nil {
+		return nil, err
+	}
+	if noticia.Estado != models.EstadoNoticiaEnRevision && noticia.Estado != models.EstadoNoticiaProgramada {
+		return nil, ErrTransicionNoticiaInvalida
+	}
+	comentario = strings.TrimSpace(comentario)
+	if comentario == "" {
+		return nil, errors.New("el comentario es requerido para rechazar la noticia")
+	}
+	leido := noticia.Estado
+	noticia.Estado = models.EstadoNoticiaBorrador
+	noticia.ComentarioRevision = comentario
+	noticia.PublicarEn = nil
+	return s.guardarEstado(noticia, leido)
+}
+
+// Publicar publica la noticia de inmediato o la programa si publicar_en es futura
+func (s *NoticiaService) Publicar(id, usuarioID uint, req PublicacionRequest) (*models.Noticia, error) {
+	noticia, err := s.noticiaDeEditor(id, usuarioID)
+	if err != nil {
+		return nil, err
+	}
+	if noticia.Estado == models.EstadoNoticiaPublicada {
+		return nil, ErrTransicionNoticiaInvalida
+	}
+
+	ahora := time.Now()
+	inicio := ahora
+	if req.PublicarEn != nil && req.PublicarEn.After(ahora) {
+		inicio = *req.PublicarEn
+	}
+	if req.ExpiraEn != nil && !req.ExpiraEn.After(inicio) {
+		return nil, errors.New("expira_en debe ser posterior a la fecha de publicación")
+	}
+
+	leido := noticia.Estado
+	noticia.ExpiraEn = req.ExpiraEn
+	noticia.PublicadaPorID = &usuarioID
+	noticia.ComentarioRevision = ""
+	if inicio.After(ahora) {
+		noticia.Estado = models.EstadoNoticiaProgramada
+		noticia.PublicarEn = &inicio
+		noticia.PublicadaEn = nil
+	} else {
+		noticia.Estado = models.EstadoNoticiaPublicada
+		noticia.PublicarEn = nil
+		noticia.PublicadaEn = &ahora
+	}
+	return s.guardarEstado(noticia, leido)
+}
+
+// Archivar retira una noticia publicada o cancela una programada
+func (s *NoticiaService) Archivar(id, usuarioID uint) (*models.Noticia, error) {
+	noticia, err := s.noticiaDeEditor(id, usuarioID)
+	if err != nil {
+		return nil, err
+	}
+	if noticia.Estado != models.EstadoNoticiaPublicada && noticia.Estado != models.EstadoNoticiaProgramada {
+		return nil, ErrTransicionNoticiaInvalida
+	}
+	leido := noticia.Estado
+	noticia.Estado = models.EstadoNoticiaArchivada
+	noticia.PublicarEn = nil
+	return s.guardarEstado(noticia, leido)
+}
+
+// Revisiones lista las versiones de una noticia para quien puede verla
+func (s *NoticiaService) Revisiones(id, usuarioID uint) ([]models.RevisionNoticia, error) {
+	if _, err := s.Obtener(id, usuarioID); err != nil {
+		return nil, err
+	}
+	return s.noticiaRepo.GetRevisiones(id)
+}
+
+// Diff compara la versión indicada con otra; si contra es 0 se compara con la versión actual
+func (s *NoticiaService) Diff(id, usuarioID uint, version, contra int) (*DiffNoticia, error) {
+	noticia, err := s.Obtener(id, usuarioID)
+	if err != nil {
+		return nil, err
+	}
+	desde, err := s.revision(id, version)
+	if err != nil {
+		return nil, err
+	}
+	hasta := revisionDe(noticia, noticia.UsuarioID, "")
+	hasta.Version = noticia.Version
+	if contra != 0 {
+		if hasta, err = s.revision(id, contra); err != nil {
+			return nil, err
+		}
+	}
+
+	return &DiffNoticia{
+		NoticiaID: id,
+		Desde:     desde.Version,
+		Hasta:     hasta.Version,
+		Campos: []DiffCampoNoticia{
+			{Campo: "titulo", Cambios: diffLineas(desde.Titulo, hasta.Titulo)},
+			{Campo: "descripcion", Cambios: diffLineas(desde.Descripcion, hasta.Descripcion)},
+			{Campo: "url_noticia", Cambios: diffLineas(desde.URLNoticia, hasta.URLNoticia)},
+			{Campo: "imagen_portada", Cambios: diffLineas(s.nombrePortada(desde.ImagenPortadaID), s.nombrePortada(hasta.ImagenPortadaID))},
+			{Campo: "categoria", Cambios: diffLineas(s.nombreCategoria(desde.CategoriaID), s.nombreCategoria(hasta.CategoriaID))},
+			{Campo: "etiquetas", Cambios: diffLineas(strings.ReplaceAll(desde.Etiquetas, ", ", "\n"), strings.ReplaceAll(hasta.Etiquetas, ", ", "\n"))},
+		},
+	}, nil
+}
+
+// Restaurar vuelve al contenido de una versión anterior guardándolo como una versión nueva
+func (s *NoticiaService) Restaurar(id, usuarioID uint, version int) (*models.Noticia, error) {
+	noticia, err := s.noticiaEditable(id, usuarioID)
+	if err != nil {
+		return nil, err
+	}
+	revision, err := s.revision(id, version)
+	if err != nil {
+		return nil, err
+	}
+	anterior := *noticia
+	noticia.Titulo = revision.Titulo
+	noticia.Descripcion = revision.Descripcion
+	noticia.URLNoticia = revision.URLNoticia
+	noticia.ImagenPortadaID, noticia.ImagenPortada = nil, nil
+	if revision.ImagenPortadaID != nil {
+		// Si la imagen se eliminó o ya no es pública, la noticia queda sin portada
+		if imagen, err := s.imagenPortada(*revision.ImagenPortadaID); err == nil {
+			noticia.ImagenPortadaID, noticia.ImagenPortada = &imagen.ID, imagen
+		}
+	}
+	noticia.CategoriaID = nil
+	if revision.CategoriaID != nil {
+		// Si la categoría se eliminó después, la noticia queda sin categoría
+		if _, err := s.noticiaRepo.GetCategoriaByID(*revision.CategoriaID); err == nil {
+			noticia.CategoriaID = revision.CategoriaID
+		}
+	}
+	nombres := []string{}
+	if revision.Etiquetas != "" {
+		nombres = strings.Split(revision.Etiquetas, ", ")
+	}
+	if noticia.Etiquetas, err = s.etiquetas(nombres); err != nil {
+		return nil, err
+	}
+	if mismoContenido(&anterior, noticia) {
+		return noticia, nil
+	}
+	return s.guardarVersion(&anterior, noticia, usuarioID, fmt.Sprintf("Restauración de la versión %d", version))
+}
+
+// AsignarSlugsFaltantes genera el slug de las noticias creadas antes de que existieran
+func (s *NoticiaService) AsignarSlugsFaltantes() (int, error) {
+	noticias, err := s.noticiaRepo.GetNoticiasSinSlug()
+	if err != nil {
+		return 0, err
+	}
+	for i := range noticias {
+		slug, err := s.slugDisponible(generarSlug(noticias[i].Titulo), noticias[i].ID)
+		if err != nil {
+			return i, err
+		}
+		if err := s.noticiaRepo.AsignarSlug(noticias[i].ID, slug); err != nil {
+			return i, err
+		}
+	}
+	return len(noticias), nil
+}
+
+// CrearCategoria crea una categoría de noticias (solo editores)
+func (s *NoticiaService) CrearCategoria(usuarioID uint, req CategoriaNoticiaRequest) (*models.CategoriaNoticia, error) {
+	if err := s.exigirEditor(usuarioID); err != nil {
+		return nil, err
+	}
+	categoria := &models.CategoriaNoticia{}
+	if err := s.aplicarCategoria(categoria, req); err != nil {
+		return nil, err
+	}
+	if err := s.noticiaRepo.CreateCategoria(categoria); err != nil {
+		return nil, err
+	}
+	return categoria, nil
+}
+
+// ActualizarCategoria cambia el nombre o la descripción de una categoría (solo editores).
+// El slug se regenera con el nombre, así que cambian los enlaces filtrados por la categoría.
+func (s *NoticiaService) ActualizarCategoria(id, usuarioID uint, req CategoriaNoticiaRequest) (*models.CategoriaNoticia, error) {
+	if err := s.exigirEditor(usuarioID); err != nil {
+		return nil, err
+	}
+	categoria, err := s.noticiaRepo.GetCategoriaByID(id)
+	if err != nil {
+		return nil, ErrCategoriaNoEncontrada
+	}
+	if err := s.aplicarCategoria(categoria, req); err != nil {
+		return nil, err
+	}
+	if err := s.noticiaRepo.UpdateCategoria(categoria); err != nil {
+		return nil, err
+	}
+	return categoria, nil
+}
+
+// EliminarCategoria elimina una categoría; sus noticias quedan sin categoría (solo editores)
+func (s *NoticiaService) EliminarCategoria(id, usuarioID uint) error {
+	if err := s.exigirEditor(usuarioID); err != nil {
+		return err
+	}
+	if err := s.noticiaRepo.DeleteCategoria(id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrCategoriaNoEncontrada
+		}
+		return err
+	}
+	return nil
+}
+
+// PublicarPendientes publica las noticias programadas que llegaron a su fecha y archiva las expiradas
+func (s *NoticiaService) PublicarPendientes(ahora time.Time) (publicadas, archivadas int64, err error) {
+	if publicadas, err = s.noticiaRepo.PublicarProgramadas(ahora); err != nil {
+		return 0, 0, err
+	}
+	if archivadas, err = s.noticiaRepo.ArchivarExpiradas(ahora); err != nil {
+		return publicadas, 0, err
+	}
+	return publicadas, archivadas, nil
+}
+
+// IniciarPublicador publica y archiva noticias periódicamente en segundo plano
+// (NOTICIAS_PUBLICADOR_INTERVALO, por defecto 1m). Devuelve una función que detiene el publicador
+// después de la pasada en curso.
+func (s *NoticiaService) IniciarPublicador() func() {
+	detener := make(chan struct{})
+	terminado := make(chan struct{})
+	go func() {
+		defer close(terminado)
+		ticker := time.NewTicker(s.intervalo)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				publicadas, archivadas, err := s.PublicarPendientes(time.Now())
+				if err != nil {
+					slog.Error("error al publicar noticias programadas", "error", err)
+				} else if publicadas > 0 || archivadas > 0 {
+					slog.Info("noticias programadas procesadas", "publicadas", publicadas, "archivadas", archivadas)
+				}
+			case <-detener:
+				return
+			}
+		}
+	}()
+	return func() {
+		close(detener)
+		<-terminado
+	}
+}
+
+func (s *NoticiaService) esEditor(usuarioID uint) (bool, error) {
+	usuario, err := s.usuarioRepo.GetUsuarioByID(usuarioID)
+	if err != nil {
+		return false, ErrPublicacionNoPermitida
+	}
+	return usuario.TipoUsuario.PuedePublicarNoticias(), nil
+}
+
+// noticiaEditable carga la noticia si el usuario puede modificar su contenido
+func (s *NoticiaService) noticiaEditable(id, usuarioID uint) (*models.Noticia, error) {
+	noticia, err := s.Obtener(id, usuarioID)
+	if err != nil {
+		return nil, err
+	}
+	editor, err := s.esEditor(usuarioID)
+	if err != nil {
+		return nil, err
+	}
+	if editor {
+		return noticia, nil
+	}
+	if noticia.UsuarioID != usuarioID ||
+		(noticia.Estado != models.EstadoNoticiaBorrador && noticia.Estado != models.EstadoNoticiaEnRevision) {
+		return nil, ErrEdicionNoticiaNoPermitida
+	}
+	return noticia, nil
+}
+
+func (s *NoticiaService) exigirEditor(usuarioID uint) error {
+	editor, err := s.esEditor(usuarioID)
+	if err != nil {
+		return err
+	}
+	if !editor {
+		return ErrPublicacionNoPermitida
+	}
+	return nil
+}
+
+// noticiaDeEditor carga la noticia si el usuario es editor
+func (s *NoticiaService) noticiaDeEditor(id, usuarioID uint) (*models.Noticia, error) {
+	if err := s.exigirEditor(usuarioID); err != nil {
+		return nil, err
+	}
+	noticia, err := s.noticiaRepo.GetNoticiaByID(id)
+	if err != nil {
+		return nil, ErrNoticiaNoEncontrada
+	}
+	return noticia, nil
+}
+
+func (s *NoticiaService) revision(id uint, version int) (*models.RevisionNoticia, error) {
+	revision, err := s.noticiaRepo.GetRevision(id, version)
+	if err != nil {
+		return nil, ErrRevisionNoEncontrada
+	}
+	return revision, nil
+}
+
+// guardarVersion guarda el nuevo contenido como versión siguiente. Las noticias creadas antes del
+// historial no tienen su versión actual guardada, así que primero se guarda el contenido anterior.
+func (s *NoticiaService) guardarVersion(anterior, noticia *models.Noticia, usuarioID uint, motivo string) (*models.Noticia, error) {
+	if _, err := s.noticiaRepo.GetRevision(anterior.ID, anterior.Version); errors.Is(err, gorm.ErrRecordNotFound) {
+		base := revisionDe(anterior, anterior.UsuarioID, "Versión anterior al historial")
+		base.NoticiaID = anterior.ID
+		base.Version = anterior.Version
+		if err := s.noticiaRepo.CreateRevision(base); err != nil {
+			return nil, err
+		}
+	} else if err != nil {
+		return nil, err
+	}
+	return s.guardarContenido(noticia, revisionDe(noticia, usuarioID, motivo))
+}
+
+// guardarContenido guarda solo las columnas de contenido, sin tocar el estado editorial
+func (s *NoticiaService) guardarContenido(noticia *models.Noticia, revision *models.RevisionNoticia) (*models.Noticia, error) {
+	if err := s.noticiaRepo.GuardarContenido(noticia, revision); err != nil {
+		if errors.Is(err, repositories.ErrNoticiaModificada) {
+			return nil, ErrNoticiaModificada
+		}
+		return nil, err
+	}
+	return s.noticiaRepo.GetNoticiaByID(noticia.ID)
+}
+
+// guardarEstado guarda la transición si la noticia sigue en el estado en que se leyó
+func (s *NoticiaService) guardarEstado(noticia *models.Noticia, estadoLeido string) (*models.Noticia, error) {
+	if err := s.noticiaRepo.CambiarEstado(noticia, estadoLeido); err != nil {
+		if errors.Is(err, repositories.ErrNoticiaModificada) {
+			return nil, ErrNoticiaModificada
+		}
+		return nil, err
+	}
+	return s.noticiaRepo.GetNoticiaByID(noticia.ID)
+}
+
+func (s *NoticiaService) aplicarNoticiaRequest(noticia *models.Noticia, req NoticiaRequest) error {
+	if req.Titulo != nil {
+		noticia.Titulo = strings.TrimSpace(*req.Titulo)
+	}
+	if req.Descripcion != nil {
+		noticia.Descripcion = strings.TrimSpace(*req.Descripcion)
+	}
+	if req.URLNoticia != nil {
+		noticia.URLNoticia = strings.TrimSpace(*req.URLNoticia)
+	}
+	if noticia.Titulo == "" {
+		return errors.New("el título es requerido")
+	}
+
+	if req.Slug != nil {
+		slug := generarSlug(*req.Slug)
+		if slug == "" {
+			return errors.New("el slug debe contener al menos una letra o número")
+		}
+		enUso, err := s.noticiaRepo.SlugEnUso(slug, noticia.ID)
+		if err != nil {
+			return err
+		}
+		if enUso {
+			return ErrSlugNoticiaEnUso
+		}
+		noticia.Slug = slug
+	}
+
+	if req.CategoriaID != nil {
+		if *req.CategoriaID == 0 {
+			noticia.CategoriaID = nil
+		} else {
+			if _, err := s.noticiaRepo.GetCategoriaByID(*req.CategoriaID); err != nil {
+				return errors.New("la categoría indicada no existe")
+			}
+			id := *req.CategoriaID
+			noticia.CategoriaID = &id
+		}
+		noticia.Categoria = nil
+	}
+
+	if req.Etiquetas != nil {
+		etiquetas, err := s.etiquetas(*req.Etiquetas)
+		if err != nil {
+			return err
+		}
+		noticia.Etiquetas = etiquetas
+	}
+
+	if req.ImagenPortadaID != nil {
+		if *req.ImagenPortadaID == 0 {
+			noticia.ImagenPortadaID, noticia.ImagenPortada = nil, nil
+		} else {
+			imagen, err := s.imagenPortada(*req.ImagenPortadaID)
+			if err != nil {
+				return err
+			}
+			noticia.ImagenPortadaID, noticia.ImagenPortada = &imagen.ID, imagen
+		}
+	}
+	return nil
+}
+
+// etiquetas normaliza los nombres (sin repetidos por slug) y obtiene o crea las etiquetas
+func (s *NoticiaService) etiquetas(nombres []string) ([]models.EtiquetaNoticia, error) {
+	var limpios, slugs []string
+	vistos := map[string]bool{}
+	for _, nombre := range nombres {
+		// Las comas separan las etiquetas en el historial de revisiones
+		nombre = strings.Join(strings.Fields(strings.ReplaceAll(nombre, ",", " ")), " ")
+		slug := generarSlug(nombre)
+		if slug == "" || vistos[slug] {
+			continue
+		}
+		if utf8.RuneCountInString(nombre) > maxLongitudEtiquetaNoticia {
+			return nil, fmt.Errorf("cada etiqueta puede tener como máximo %d caracteres", maxLongitudEtiquetaNoticia)
+		}
+		vistos[slug] = true
+		limpios = append(limpios, nombre)
+		slugs = append(slugs, slug)
+	}
+	if len(slugs) > maxEtiquetasNoticia {
+		return nil, fmt.Errorf("una noticia puede tener como máximo %d etiquetas", maxEtiquetasNoticia)
+	}
+	if len(slugs) == 0 {
+		return []models.EtiquetaNoticia{}, nil
+	}
+	return s.noticiaRepo.ObtenerOCrearEtiquetas(limpios, slugs)
+}
+
+// slugDisponible agrega un sufijo numérico al slug base hasta que ninguna otra noticia lo use
+func (s *NoticiaService) slugDisponible(base string, noticiaID uint) (string, error) {
+	if base == "" {
+		base = "noticia"
+	}
+	slug := base
+	for i := 2; ; i++ {
+		enUso, err := s.noticiaRepo.SlugEnUso(slug, noticiaID)
+		if err != nil {
+			return "", err
+		}
+		if !enUso {
+			return slug, nil
+		}
+		slug = base + "-" + strconv.Itoa(i)
+	}
+}
+
+func (s *NoticiaService) aplicarCategoria(categoria *models.CategoriaNoticia, req CategoriaNoticiaRequest) error {
+	nombre := strings.Join(strings.Fields(req.Nombre), " ")
+	slug := generarSlug(nombre)
+	if slug == "" {
+		return errors.New("el nombre de la categoría es requerido")
+	}
+	enUso, err := s.noticiaRepo.CategoriaEnUso(nombre, slug, categoria.ID)
+	if err != nil {
+		return err
+	}
+	if enUso {
+		return ErrCategoriaDuplicada
+	}
+	categoria.Nombre = nombre
+	categoria.Slug = slug
+	categoria.Descripcion = strings.TrimSpace(req.Descripcion)
+	return nil
+}
+
+func (s *NoticiaService) nombreCategoria(id *uint) string {
+	if id == nil {
+		return ""
+	}
+	if categoria, err := s.noticiaRepo.GetCategoriaByID(*id); err == nil {
+		return categoria.Nombre
+	}
+	return fmt.Sprintf("(categoría eliminada #%d)", *id)
+}
+
+func (s *NoticiaService) nombrePortada(id *uint) string {
+	if id == nil {
+		return ""
+	}
+	if archivo, err := s.archivoRepo.GetArchivoByID(*id); err == nil {
+		return archivo.NombreOriginal
+	}
+	return fmt.Sprintf("(imagen eliminada #%d)", *id)
+}
+
+// imagenPortada obtiene el archivo de la portada. Solo se aceptan imágenes públicas, porque la portada se
+// muestra en el sitio y en los feeds sin autenticación.
+func (s *NoticiaService) imagenPortada(id uint) (*models.Archivo, error) {
+	archivo, err := s.archivoRepo.GetArchivoByID(id)
+	if err != nil {
+		return nil, ErrImagenPortadaInvalida
+	}
+	if archivo.Carpeta != "images" || archivo.Visibilidad != models.VisibilidadArchivoPublico {
+		return nil, ErrImagenPortadaInvalida
+	}
+	return archivo, nil
+}
+
+func revisionDe(noticia *models.Noticia, usuarioID uint, motivo string) *models.RevisionNoticia {
+	return &models.RevisionNoticia{
+		Titulo:          noticia.Titulo,
+		Descripcion:     noticia.Descripcion,
+		URLNoticia:      noticia.URLNoticia,
+		ImagenPortadaID: noticia.ImagenPortadaID,
+		CategoriaID:     noticia.CategoriaID,
+		Etiquetas:       nombresEtiquetas(noticia.Etiquetas),
+		UsuarioID:       usuarioID,
+		Motivo:          motivo,
+	}
+}
+
+func nombresEtiquetas(etiquetas []models.EtiquetaNoticia) string {
+	nombres := make([]string, 0, len(etiquetas))
+	for _, e := range etiquetas {
+		nombres = append(nombres, e.Nombre)
+	}
+	sort.Strings(nombres)
+	return strings.Join(nombres, ", ")
+}
+
+func mismoContenido(a, b *models.Noticia) bool {
+	return a.Titulo == b.Titulo && a.Descripcion == b.Descripcion && a.URLNoticia == b.URLNoticia &&
+		mismoID(a.ImagenPortadaID, b.ImagenPortadaID) && mismoID(a.CategoriaID, b.CategoriaID) &&
+		nombresEtiquetas(a.Etiquetas) == nombresEtiquetas(b.Etiquetas)
+}
+
+func mismoID(a, b *uint) bool {
+	return (a == nil && b == nil) || (a != nil && b != nil && *a == *b)
+}
+
+func noticiaVisible(noticia *models.Noticia, ahora time.Time) bool {
+	return noticia.Estado == models.EstadoNoticiaPublicada && (noticia.ExpiraEn == nil || noticia.ExpiraEn.After(ahora))
+}
+
+// conPagina reemplaza el parámetro pagina de una URL
+func conPagina(enlace string, pagina int) string {
+	u, err := url.Parse(enlace)
+	if err != nil {
+		return ""
+	}
+	q := u.Query()
+	q.Set("pagina", strconv.Itoa(pagina))
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+func normalizarPaginacion(pagina, porPagina int) (int, int) {
+	if pagina < 1 {
+		pagina = 1
+	}
+	if porPagina < 1 || porPagina > maxPorPaginaNoticias {
+		porPagina = porPaginaNoticiasPorDef
+	}
+	return pagina, porPagina
+}
+
+func estadoNoticiaValido(estado string) bool {
+	switch estado {
+	case models.EstadoNoticiaBorrador, models.EstadoNoticiaEnRevision, models.EstadoNoticiaProgramada,
+		models.EstadoNoticiaPublicada, models.EstadoNoticiaArchivada:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,268 @@
+package services
+
+import (
+	"ApiEscuela/models"
+	"ApiEscuela/repositories"
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var asignacionSQL = regexp.MustCompile(`"(\w+)"=\$(\d+)`)
+
+// actualizacionNoticia es un UPDATE sobre noticia: las columnas del SET y la condición del WHERE
+type actualizacionNoticia struct {
+	columnas map[string]interface{}
+	where    string
+	vars     []interface{}
+}
+
+// servicioNoticiasSimulado devuelve noticia y un editor, y registra los UPDATE y las revisiones creadas.
+// Si filasAfectadas es 0 el UPDATE se comporta como si otra petición hubiera cambiado la noticia.
+func servicioNoticiasSimulado(t *testing.T, noticia *models.Noticia, revision *models.RevisionNoticia, filasAfectadas int64) (*NoticiaService, *[]actualizacionNoticia, *[]models.RevisionNoticia) {
+	t.Helper()
+	editor := &models.Usuario{TipoUsuario: models.TipoUsuario{Nombre: models.NombreTipoUsuarioEditor}}
+	editor.ID = 1
+	filas := map[string]interface{}{"Noticia": noticia, "Usuario": editor}
+	if revision != nil {
+		filas["RevisionNoticia"] = revision
+	}
+	db := bdSimulada(t, filas)
+
+	var actualizaciones []actualizacionNoticia
+	var revisiones []models.RevisionNoticia
+	err := errors.Join(
+		db.Callback().Update().After("gorm:update").Register("prueba:update", func(d *gorm.DB) {
+			if d.Statement.Table != "noticia" {
+				return
+			}
+			sql := d.Statement.SQL.String()
+			set, where, _ := strings.Cut(sql, " WHERE ")
+			a := actualizacionNoticia{columnas: map[string]interface{}{}, where: where, vars: d.Statement.Vars}
+			for _, m := range asignacionSQL.FindAllStringSubmatch(set, -1) {
+				i, _ := strconv.Atoi(m[2])
+				a.columnas[m[1]] = d.Statement.Vars[i-1]
+			}
+			if _, soloFecha := a.columnas["updated_at"]; soloFecha && len(a.columnas) == 1 {
+				return // Association("Etiquetas").Replace solo toca updated_at
+			}
+			actualizaciones = append(actualizaciones, a)
+			d.RowsAffected = filasAfectadas
+		}),
+		db.Callback().Create().After("gorm:create").Register("prueba:create", func(d *gorm.DB) {
+			if r, ok := d.Statement.Dest.(*models.RevisionNoticia); ok {
+				revisiones = append(revisiones, *r)
+			}
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	servicio := NewNoticiaService(repositories.NewNoticiaRepository(db), repositories.NewUsuarioRepository(db), repositories.NewArchivoRepository(db), "", time.Minute)
+	return servicio, &actualizaciones, &revisiones
+}
+
+func noticiaEn(estado string) *models.Noticia {
+	noticia := &models.Noticia{Titulo: "Casa abierta", UsuarioID: 2, Estado: estado, Version: 3}
+	noticia.ID = 5
+	return noticia
+}
+
+func TestTransicionesDeNoticia(t *testing.T) {
+	manana := time.Now().Add(24 * time.Hour)
+	casos := []struct {
+		nombre   string
+		estado   string
+		accion   func(*NoticiaService) (*models.Noticia, error)
+		esperado string // Estado guardado; vacío si la transición no se permite
+	}{
+		{"borrador a revisión", models.EstadoNoticiaBorrador,
+			func(s *NoticiaService) (*models.Noticia, error) { return s.EnviarARevision(5, 1) }, models.EstadoNoticiaEnRevision},
+		{"revisión no se reenvía", models.EstadoNoticiaEnRevision,
+			func(s *NoticiaService) (*models.Noticia, error) { return s.EnviarARevision(5, 1) }, ""},
+		{"rechazo de una noticia en revisión", models.EstadoNoticiaEnRevision,
+			func(s *NoticiaService) (*models.Noticia, error) { return s.Rechazar(5, 1, "falta la foto") }, models.EstadoNoticiaBorrador},
+		{"un borrador no se rechaza", models.EstadoNoticiaBorrador,
+			func(s *NoticiaService) (*models.Noticia, error) { return s.Rechazar(5, 1, "falta la foto") }, ""},
+		{"publicación inmediata", models.EstadoNoticiaEnRevision,
+			func(s *NoticiaService) (*models.Noticia, error) { return s.Publicar(5, 1, PublicacionRequest{}) }, models.EstadoNoticiaPublicada},
+		{"publicación programada", models.EstadoNoticiaBorrador,
+			func(s *NoticiaService) (*models.Noticia, error) {
+				return s.Publicar(5, 1, PublicacionRequest{PublicarEn: &manana})
+			}, models.EstadoNoticiaProgramada},
+		{"una publicada no se vuelve a publicar", models.EstadoNoticiaPublicada,
+			func(s *NoticiaService) (*models.Noticia, error) { return s.Publicar(5, 1, PublicacionRequest{}) }, ""},
+		{"archivo de una publicada", models.EstadoNoticiaPublicada,
+			func(s *NoticiaService) (*models.Noticia, error) { return s.Archivar(5, 1) }, models.EstadoNoticiaArchivada},
+		{"un borrador no se archiva", models.EstadoNoticiaBorrador,
+			func(s *NoticiaService) (*models.Noticia, error) { return s.Archivar(5, 1) }, ""},
+	}
+	for _, caso := range casos {
+		t.Run(caso.nombre, func(t *testing.T) {
+			servicio, actualizaciones, _ := servicioNoticiasSimulado(t, noticiaEn(caso.estado), nil, 1)
+			_, err := caso.accion(servicio)
+			if caso.esperado == "" {
+				if !errors.Is(err, ErrTransicionNoticiaInvalida) {
+					t.Fatalf("error = %v, se esperaba ErrTransicionNoticiaInvalida", err)
+				}
+				if len(*actualizaciones) != 0 {
+					t.Errorf("una transición inválida no debe guardar nada: %v", *actualizaciones)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(*actualizaciones) != 1 {
+				t.Fatalf("se esperaba un UPDATE, hubo %d", len(*actualizaciones))
+			}
+			a := (*actualizaciones)[0]
+			if a.columnas["estado"] != caso.esperado {
+				t.Errorf("estado guardado %v, se esperaba %s", a.columnas["estado"], caso.esperado)
+			}
+			// Solo se guardan las columnas del flujo editorial y condicionadas al estado leído
+			if _, ok := a.columnas["titulo"]; ok {
+				t.Errorf("la transición no debe guardar el contenido: %v", a.columnas)
+			}
+			if !strings.Contains(a.where, "estado = $") || !contieneValor(a.vars, caso.estado) {
+				t.Errorf("el UPDATE no se condiciona al estado %s: %s %v", caso.estado, a.where, a.vars)
+			}
+		})
+	}
+
+	t.Run("la noticia cambió de estado mientras tanto", func(t *testing.T) {
+		servicio, _, _ := servicioNoticiasSimulado(t, noticiaEn(models.EstadoNoticiaProgramada), nil, 0)
+		if _, err := servicio.Archivar(5, 1); !errors.Is(err, ErrNoticiaModificada) {
+			t.Errorf("error = %v, se esperaba ErrNoticiaModificada", err)
+		}
+	})
+}
+
+func TestRestaurarVersionDeNoticia(t *testing.T) {
+	noticia := noticiaEn(models.EstadoNoticiaPublicada)
+	revision := &models.RevisionNoticia{NoticiaID: 5, Version: 1, Titulo: "Casa abierta 2025", Descripcion: "Primera versión"}
+
+	servicio, actualizaciones, revisiones := servicioNoticiasSimulado(t, noticia, revision, 1)
+	if _, err := servicio.Restaurar(5, 1, 1); err != nil {
+		t.Fatal(err)
+	}
+	if len(*actualizaciones) != 1 {
+		t.Fatalf("se esperaba un UPDATE, hubo %d", len(*actualizaciones))
+	}
+	a := (*actualizaciones)[0]
+	if a.columnas["titulo"] != "Casa abierta 2025" || a.columnas["descripcion"] != "Primera versión" || a.columnas["version"] != 4 {
+		t.Errorf("contenido restaurado incorrecto: %v", a.columnas)
+	}
+	// Restaurar no debe revertir el estado ni las fechas de publicación
+	for _, columna := range []string{"estado", "publicar_en", "publicada_en"} {
+		if _, ok := a.columnas[columna]; ok {
+			t.Errorf("la restauración guardó %s: %v", columna, a.columnas)
+		}
+	}
+	if !strings.Contains(a.where, "version = $") || !contieneValor(a.vars, 3) {
+		t.Errorf("el UPDATE no se condiciona a la versión leída: %s %v", a.where, a.vars)
+	}
+	if len(*revisiones) != 1 || (*revisiones)[0].Version != 4 || (*revisiones)[0].Motivo != "Restauración de la versión 1" {
+		t.Errorf("revisión guardada %+v", *revisiones)
+	}
+
+	t.Run("otra edición guardó una versión", func(t *testing.T) {
+		servicio, _, revisiones := servicioNoticiasSimulado(t, noticiaEn(models.EstadoNoticiaBorrador), revision, 0)
+		if _, err := servicio.Restaurar(5, 1, 1); !errors.Is(err, ErrNoticiaModificada) {
+			t.Fatalf("error = %v, se esperaba ErrNoticiaModificada", err)
+		}
+		if len(*revisiones) != 0 {
+			t.Errorf("no debe guardarse la revisión: %+v", *revisiones)
+		}
+	})
+}
+
+func TestDiffLineas(t *testing.T) {
+	casos := []struct {
+		nombre         string
+		antes, despues string
+		esperado       string
+	}{
+		{"sin cambios", "a\nb", "a\nb", "=a =b"},
+		{"línea agregada", "a\nc", "a\nb\nc", "=a +b =c"},
+		{"línea eliminada", "a\nb\nc", "a\nc", "=a -b =c"},
+		{"línea reemplazada", "a\nb", "a\nx", "=a -b +x"},
+		{"desde vacío", "", "a", "+a"},
+		{"hasta vacío", "a", "", "-a"},
+		{"fines de línea de Windows", "a\r\nb", "a\nb", "=a =b"},
+	}
+	signo := map[string]string{DiffIgual: "=", DiffAgregado: "+", DiffEliminado: "-"}
+	for _, caso := range casos {
+		t.Run(caso.nombre, func(t *testing.T) {
+			var partes []string
+			for _, l := range diffLineas(caso.antes, caso.despues) {
+				partes = append(partes, signo[l.Tipo]+l.Texto)
+			}
+			if obtenido := strings.Join(partes, " "); obtenido != caso.esperado {
+				t.Errorf("diff = %q, se esperaba %q", obtenido, caso.esperado)
+			}
+		})
+	}
+}
+
+func contieneValor(vars []interface{}, valor interface{}) bool {
+	for _, v := range vars {
+		if v == valor {
+			return true
+		}
+	}
+	return false
+}
+
+func TestImagenPortadaSoloAceptaImagenesPublicas(t *testing.T) {
+	casos := []struct {
+		nombre  string
+		archivo *models.Archivo
+		valida  bool
+	}{
+		{"imagen pública", &models.Archivo{Carpeta: "images", Visibilidad: models.VisibilidadArchivoPublico}, true},
+		{"imagen privada", &models.Archivo{Carpeta: "images", Visibilidad: models.VisibilidadArchivoPrivado}, false},
+		{"documento público", &models.Archivo{Carpeta: "documents", Visibilidad: models.VisibilidadArchivoPublico}, false},
+		{"archivo inexistente", nil, false},
+	}
+	for _, caso := range casos {
+		t.Run(caso.nombre, func(t *testing.T) {
+			filas := map[string]interface{}{}
+			if caso.archivo != nil {
+				caso.archivo.ID = 8
+				filas["Archivo"] = caso.archivo
+			}
+			db := bdSimulada(t, filas)
+			servicio := NewNoticiaService(repositories.NewNoticiaRepository(db), repositories.NewUsuarioRepository(db), repositories.NewArchivoRepository(db), "", time.Minute)
+
+			noticia := noticiaEn(models.EstadoNoticiaBorrador)
+			id := uint(8)
+			err := servicio.aplicarNoticiaRequest(noticia, NoticiaRequest{ImagenPortadaID: &id})
+			if !caso.valida {
+				if !errors.Is(err, ErrImagenPortadaInvalida) {
+					t.Fatalf("error = %v, se esperaba ErrImagenPortadaInvalida", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if noticia.ImagenPortadaID == nil || *noticia.ImagenPortadaID != 8 {
+				t.Errorf("imagen_portada_id = %v, se esperaba 8", noticia.ImagenPortadaID)
+			}
+
+			cero := uint(0)
+			if err := servicio.aplicarNoticiaRequest(noticia, NoticiaRequest{ImagenPortadaID: &cero}); err != nil {
+				t.Fatal(err)
+			}
+			if noticia.ImagenPortadaID != nil {
+				t.Errorf("0 debería quitar la portada, quedó %v", *noticia.ImagenPortadaID)
+			}
+		})
+	}
+}
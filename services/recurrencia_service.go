@@ -0,0 +1,434 @@
+package services
+
+import (
+	"ApiEscuela/models"
+	"ApiEscuela/repositories"
+	"context"
+	"errors"
+	"time"
+)
+
+// Alcances de edición de una ocurrencia
+const (
+	AlcanceEsta    = "esta"
+	AlcanceFuturas = "futuras"
+	AlcanceTodas   = "todas"
+)
+
+// horizontePorDef es el rango materializado cuando no se indica fecha límite
+const horizontePorDef = 180 * 24 * time.Hour
+
+var (
+	ErrPlantillaNoEncontrada  = errors.New("plantilla no encontrada")
+	ErrOcurrenciaNoEncontrada = errors.New("ocurrencia no encontrada")
+	ErrAlcanceInvalido        = errors.New("alcance inválido")
+)
+
+// RecurrenciaService gestiona las series de visitas; los asignados reciben el .ics de cada ocurrencia
+// que se genera o se cancela
+type RecurrenciaService struct {
+	plantillaRepo     *repositories.PlantillaProgramaVisitaRepository
+	calendarioService *CalendarioService
+	zona              *time.Location // Zona de las visitas, en la que se interpretan los UNTIL sin "Z"
+}
+
+func NewRecurrenciaService(plantillaRepo *repositories.PlantillaProgramaVisitaRepository, calendarioService *CalendarioService, zona *time.Location) *RecurrenciaService {
+	return &RecurrenciaService{plantillaRepo: plantillaRepo, calendarioService: calendarioService, zona: zona}
+}
+
+// ConContexto devuelve una copia del servicio cuyos repositorios usan ctx, para que las consultas queden
+// dentro de la traza de la petición
+func (s *RecurrenciaService) ConContexto(ctx context.Context) *RecurrenciaService {
+	copia := *s
+	copia.plantillaRepo = s.plantillaRepo.ConContexto(ctx)
+	copia.calendarioService = s.calendarioService.ConContexto(ctx)
+	return &copia
+}
+
+// PlantillaRequest representa los datos para crear una plantilla de visitas recurrentes
+type PlantillaRequest struct {
+	InstitucionID   uint      `json:"institucion_id"`
+	FechaInicio     time.Time `json:"fecha_inicio"`
+	DuracionMinutos int       `json:"duracion_minutos"`
+	Regla           string    `json:"regla"`
+	Excepciones     string    `json:"excepciones"`
+	ActividadIDs    []uint    `json:"actividad_ids"`
+	AutoridadIDs    []uint    `json:"autoridad_ids"`
+	EstudianteIDs   []uint    `json:"estudiante_universitario_ids"`
+}
+
+// EdicionOcurrenciaRequest contiene los cambios de una ocurrencia; los campos nulos no se modifican
+type EdicionOcurrenciaRequest struct {
+	Fecha           *time.Time `json:"fecha"`
+	DuracionMinutos *int       `json:"duracion_minutos"`
+	Regla           *string    `json:"regla"` // Solo para "futuras" y "todas"
+	ActividadIDs    []uint     `json:"actividad_ids"`
+	AutoridadIDs    []uint     `json:"autoridad_ids"`
+	EstudianteIDs   []uint     `json:"estudiante_universitario_ids"`
+}
+
+// CrearPlantilla valida la regla y guarda la plantilla con sus actividades y asignaciones
+func (s *RecurrenciaService) CrearPlantilla(req PlantillaRequest) (*models.PlantillaProgramaVisita, error) {
+	if req.InstitucionID == 0 {
+		return nil, errors.New("la institución es requerida")
+	}
+	if req.FechaInicio.IsZero() {
+		return nil, errors.New("la fecha de inicio es requerida")
+	}
+	if req.DuracionMinutos < 0 {
+		return nil, errors.New("la duración no puede ser negativa")
+	}
+	regla, err := ParseReglaRecurrencia(req.Regla, s.zona)
+	if err != nil {
+		return nil, err
+	}
+	excepciones, err := ParseExcepciones(req.Excepciones)
+	if err != nil {
+		return nil, err
+	}
+
+	plantilla := &models.PlantillaProgramaVisita{
+		InstitucionID:   req.InstitucionID,
+		FechaInicio:     req.FechaInicio,
+		DuracionMinutos: req.DuracionMinutos,
+		Regla:           regla.String(),
+		Excepciones:     JoinExcepciones(excepciones),
+	}
+	asignarRelacionesPlantilla(plantilla, req.ActividadIDs, req.AutoridadIDs, req.EstudianteIDs)
+
+	if err := s.plantillaRepo.CreatePlantilla(plantilla); err != nil {
+		return nil, err
+	}
+	return plantilla, nil
+}
+
+// ObtenerPlantilla obtiene una plantilla con sus relaciones
+func (s *RecurrenciaService) ObtenerPlantilla(id uint) (*models.PlantillaProgramaVisita, error) {
+	plantilla, err := s.plantillaRepo.GetPlantillaByID(id)
+	if err != nil {
+		return nil, ErrPlantillaNoEncontrada
+	}
+	return plantilla, nil
+}
+
+// PrevisualizarOcurrencias calcula las fechas de la serie hasta la fecha límite sin guardarlas
+func (s *RecurrenciaService) PrevisualizarOcurrencias(id uint, hasta *time.Time) ([]time.Time, error) {
+	plantilla, err := s.ObtenerPlantilla(id)
+	if err != nil {
+		return nil, err
+	}
+	return s.ocurrenciasPlantilla(plantilla, limiteOcurrencias(hasta))
+}
+
+// Materializar crea los ProgramaVisita pendientes de la serie hasta la fecha límite
+func (s *RecurrenciaService) Materializar(ctx context.Context, id uint, hasta *time.Time) ([]models.ProgramaVisita, error) {
+	plantilla, err := s.ObtenerPlantilla(id)
+	if err != nil {
+		return nil, err
+	}
+	return s.materializarPlantilla(ctx, plantilla, time.Time{}, limiteOcurrencias(hasta))
+}
+
+// EditarOcurrencia aplica cambios a una ocurrencia ("esta"), a la ocurrencia y las siguientes
+// ("futuras") o a la serie completa ("todas")
+func (s *RecurrenciaService) EditarOcurrencia(ctx context.Context, plantillaID, programaID uint, alcance string, req EdicionOcurrenciaRequest) (*models.PlantillaProgramaVisita, error) {
+	plantilla, err := s.ObtenerPlantilla(plantillaID)
+	if err != nil {
+		return nil, err
+	}
+	programa, err := s.plantillaRepo.GetProgramaDePlantilla(plantillaID, programaID)
+	if err != nil || programa.FechaOcurrencia == nil {
+		return nil, ErrOcurrenciaNoEncontrada
+	}
+
+	switch alcance {
+	case AlcanceEsta:
+		if req.Regla != nil {
+			return nil, errors.New("la regla solo puede cambiarse para 'futuras' o 'todas'")
+		}
+		if req.Fecha != nil {
+			duracion := programa.Fechafin.Sub(programa.Fecha)
+			programa.Fecha = *req.Fecha
+			programa.Fechafin = req.Fecha.Add(duracion)
+		}
+		if req.DuracionMinutos != nil {
+			programa.Fechafin = programa.Fecha.Add(time.Duration(*req.DuracionMinutos) * time.Minute)
+		}
+		programa.Excepcion = true
+		if err := s.plantillaRepo.ReemplazarAsignacionesPrograma(programa, req.ActividadIDs, req.AutoridadIDs, req.EstudianteIDs); err != nil {
+			return nil, err
+		}
+		s.notificarProgramas(ctx, []uint{programa.ID})
+		return s.ObtenerPlantilla(plantillaID)
+	case AlcanceFuturas:
+		return s.editarFuturas(ctx, plantilla, *programa.FechaOcurrencia, req)
+	case AlcanceTodas:
+		return s.editarTodas(ctx, plantilla, req)
+	default:
+		return nil, ErrAlcanceInvalido
+	}
+}
+
+// EliminarOcurrencia elimina una ocurrencia ("esta") o la ocurrencia y las siguientes ("futuras")
+func (s *RecurrenciaService) EliminarOcurrencia(ctx context.Context, plantillaID, programaID uint, alcance string) error {
+	plantilla, err := s.ObtenerPlantilla(plantillaID)
+	if err != nil {
+		return err
+	}
+	programa, err := s.plantillaRepo.GetProgramaDePlantilla(plantillaID, programaID)
+	if err != nil || programa.FechaOcurrencia == nil {
+		return ErrOcurrenciaNoEncontrada
+	}
+	fecha := *programa.FechaOcurrencia
+
+	switch alcance {
+	case AlcanceEsta:
+		excepciones, err := ParseExcepciones(plantilla.Excepciones)
+		if err != nil {
+			return err
+		}
+		excepciones[fecha.Format("2006-01-02")] = true
+		plantilla.Excepciones = JoinExcepciones(excepciones)
+		if err := s.plantillaRepo.EliminarOcurrencia(plantilla, programa.ID); err != nil {
+			return err
+		}
+		s.notificarProgramas(ctx, []uint{programa.ID})
+		return nil
+	case AlcanceFuturas:
+		regla, err := ParseReglaRecurrencia(plantilla.Regla, s.zona)
+		if err != nil {
+			return err
+		}
+		hasta := fecha.Add(-time.Second)
+		regla.Conteo = 0
+		regla.Hasta = &hasta
+		plantilla.Regla = regla.String()
+		eliminados, err := s.plantillaRepo.TruncarSerie(plantilla, fecha)
+		if err != nil {
+			return err
+		}
+		s.notificarProgramas(ctx, eliminados)
+		return nil
+	default:
+		return ErrAlcanceInvalido
+	}
+}
+
+// EliminarPlantilla elimina la serie y sus ocurrencias futuras; las pasadas se conservan como historial
+func (s *RecurrenciaService) EliminarPlantilla(ctx context.Context, id uint) error {
+	if _, err := s.ObtenerPlantilla(id); err != nil {
+		return err
+	}
+	eliminados, err := s.plantillaRepo.DeletePlantilla(id)
+	if err != nil {
+		return err
+	}
+	s.notificarProgramas(ctx, eliminados)
+	return nil
+}
+
+// editarFuturas divide la serie: la original termina antes de la ocurrencia y una nueva
+// plantilla con los cambios continúa desde ella
+func (s *RecurrenciaService) editarFuturas(ctx context.Context, original *models.PlantillaProgramaVisita, desde time.Time, req EdicionOcurrenciaRequest) (*models.PlantillaProgramaVisita, error) {
+	reglaOriginal, err := ParseReglaRecurrencia(original.Regla, s.zona)
+	if err != nil {
+		return nil, err
+	}
+	previas := reglaOriginal.Ocurrencias(original.FechaInicio, desde.Add(-time.Second), nil)
+	if len(previas) == 0 {
+		// Es la primera ocurrencia: equivale a editar toda la serie
+		return s.editarTodas(ctx, original, req)
+	}
+
+	reglaNueva := *reglaOriginal
+	if req.Regla != nil {
+		r, err := ParseReglaRecurrencia(*req.Regla, s.zona)
+		if err != nil {
+			return nil, err
+		}
+		reglaNueva = *r
+	} else if reglaOriginal.Conteo > 0 {
+		reglaNueva.Conteo = reglaOriginal.Conteo - len(previas)
+	}
+
+	excepciones, err := ParseExcepciones(original.Excepciones)
+	if err != nil {
+		return nil, err
+	}
+	excepcionesOriginal := map[string]bool{}
+	excepcionesNueva := map[string]bool{}
+	for f := range excepciones {
+		if f < desde.Format("2006-01-02") {
+			excepcionesOriginal[f] = true
+		} else {
+			excepcionesNueva[f] = true
+		}
+	}
+
+	// Límite de regeneración: hasta donde estaba materializada la serie original
+	limite := time.Now().Add(horizontePorDef)
+	if programas, err := s.plantillaRepo.GetProgramasByPlantilla(original.ID); err == nil && len(programas) > 0 {
+		if ultima := programas[len(programas)-1].FechaOcurrencia; ultima != nil && ultima.After(limite) {
+			limite = *ultima
+		}
+	}
+
+	hasta := desde.Add(-time.Second)
+	reglaOriginal.Conteo = 0
+	reglaOriginal.Hasta = &hasta
+	original.Regla = reglaOriginal.String()
+	original.Excepciones = JoinExcepciones(excepcionesOriginal)
+
+	nueva := &models.PlantillaProgramaVisita{
+		InstitucionID:     original.InstitucionID,
+		FechaInicio:       desde,
+		DuracionMinutos:   original.DuracionMinutos,
+		Regla:             reglaNueva.String(),
+		Excepciones:       JoinExcepciones(excepcionesNueva),
+		PlantillaOrigenID: &original.ID,
+	}
+	if req.Fecha != nil {
+		nueva.FechaInicio = *req.Fecha
+	}
+	if req.DuracionMinutos != nil {
+		nueva.DuracionMinutos = *req.DuracionMinutos
+	}
+	asignarRelacionesPlantilla(nueva,
+		valorOPredeterminado(req.ActividadIDs, original.ActividadIDs()),
+		valorOPredeterminado(req.AutoridadIDs, original.AutoridadIDs()),
+		valorOPredeterminado(req.EstudianteIDs, original.EstudianteIDs()))
+
+	eliminados, err := s.plantillaRepo.DividirSerie(original, nueva, desde)
+	if err != nil {
+		return nil, err
+	}
+	s.notificarProgramas(ctx, eliminados)
+	if _, err := s.materializarPlantilla(ctx, nueva, time.Time{}, limite); err != nil {
+		return nil, err
+	}
+	return s.ObtenerPlantilla(nueva.ID)
+}
+
+// editarTodas actualiza la plantilla completa y regenera sus ocurrencias futuras;
+// las visitas ya realizadas y las editadas individualmente se conservan
+func (s *RecurrenciaService) editarTodas(ctx context.Context, plantilla *models.PlantillaProgramaVisita, req EdicionOcurrenciaRequest) (*models.PlantillaProgramaVisita, error) {
+	if req.Regla != nil {
+		regla, err := ParseReglaRecurrencia(*req.Regla, s.zona)
+		if err != nil {
+			return nil, err
+		}
+		plantilla.Regla = regla.String()
+	}
+	if req.Fecha != nil {
+		plantilla.FechaInicio = *req.Fecha
+	}
+	if req.DuracionMinutos != nil {
+		plantilla.DuracionMinutos = *req.DuracionMinutos
+	}
+
+	ahora := time.Now()
+	limite := ahora.Add(horizontePorDef)
+	if programas, err := s.plantillaRepo.GetProgramasByPlantilla(plantilla.ID); err == nil && len(programas) > 0 {
+		if ultima := programas[len(programas)-1].FechaOcurrencia; ultima != nil && ultima.After(limite) {
+			limite = *ultima
+		}
+	}
+
+	eliminados, err := s.plantillaRepo.ReemplazarSerie(plantilla, ahora, req.ActividadIDs, req.AutoridadIDs, req.EstudianteIDs)
+	if err != nil {
+		return nil, err
+	}
+	s.notificarProgramas(ctx, eliminados)
+	actualizada, err := s.ObtenerPlantilla(plantilla.ID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.materializarPlantilla(ctx, actualizada, ahora, limite); err != nil {
+		return nil, err
+	}
+	return actualizada, nil
+}
+
+// materializarPlantilla crea los programas de las ocurrencias entre desde y limite aún no generadas y
+// envía la invitación a sus asignados. Se comparan por día porque la regla genera a lo sumo una
+// ocurrencia diaria y una edición de la serie puede haber cambiado la hora.
+func (s *RecurrenciaService) materializarPlantilla(ctx context.Context, plantilla *models.PlantillaProgramaVisita, desde, limite time.Time) ([]models.ProgramaVisita, error) {
+	fechas, err := s.ocurrenciasPlantilla(plantilla, limite)
+	if err != nil {
+		return nil, err
+	}
+	generadas, err := s.plantillaRepo.GetFechasOcurrencia(plantilla.ID)
+	if err != nil {
+		return nil, err
+	}
+	zona := plantilla.FechaInicio.Location()
+	existentes := make(map[string]bool, len(generadas))
+	for _, f := range generadas {
+		existentes[f.In(zona).Format("2006-01-02")] = true
+	}
+	pendientes := make([]time.Time, 0, len(fechas))
+	for _, f := range fechas {
+		if !f.Before(desde) && !existentes[f.Format("2006-01-02")] {
+			pendientes = append(pendientes, f)
+		}
+	}
+	if len(pendientes) == 0 {
+		return []models.ProgramaVisita{}, nil
+	}
+	programas, err := s.plantillaRepo.MaterializarOcurrencias(plantilla, pendientes)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]uint, len(programas))
+	for i, programa := range programas {
+		ids[i] = programa.ID
+	}
+	s.notificarProgramas(ctx, ids)
+	return programas, nil
+}
+
+// notificarProgramas envía a los asignados de cada programa el .ics nuevo, actualizado o de cancelación
+func (s *RecurrenciaService) notificarProgramas(ctx context.Context, programaIDs []uint) {
+	for _, id := range programaIDs {
+		s.calendarioService.NotificarCambioPrograma(ctx, id)
+	}
+}
+
+func (s *RecurrenciaService) ocurrenciasPlantilla(plantilla *models.PlantillaProgramaVisita, limite time.Time) ([]time.Time, error) {
+	regla, err := ParseReglaRecurrencia(plantilla.Regla, s.zona)
+	if err != nil {
+		return nil, err
+	}
+	excepciones, err := ParseExcepciones(plantilla.Excepciones)
+	if err != nil {
+		return nil, err
+	}
+	return regla.Ocurrencias(plantilla.FechaInicio, limite, excepciones), nil
+}
+
+func limiteOcurrencias(hasta *time.Time) time.Time {
+	if hasta != nil {
+		// Incluir el día completo indicado
+		return hasta.Add(24*time.Hour - time.Second)
+	}
+	return time.Now().Add(horizontePorDef)
+}
+
+func asignarRelacionesPlantilla(plantilla *models.PlantillaProgramaVisita, actividadIDs, autoridadIDs, estudianteIDs []uint) {
+	for _, id := range actividadIDs {
+		plantilla.Actividades = append(plantilla.Actividades, models.PlantillaVisitaActividad{ActividadID: id})
+	}
+	for _, id := range autoridadIDs {
+		plantilla.Autoridades = append(plantilla.Autoridades, models.PlantillaVisitaAutoridad{AutoridadUTEQID: id})
+	}
+	for _, id := range estudianteIDs {
+		plantilla.Estudiantes = append(plantilla.Estudiantes, models.PlantillaVisitaEstudiante{EstudianteUniversitarioID: id})
+	}
+}
+
+func valorOPredeterminado(valor, predeterminado []uint) []uint {
+	if valor != nil {
+		return valor
+	}
+	return predeterminado
+}
@@ -0,0 +1,364 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Frecuencias soportadas del subconjunto RRULE
+const (
+	FrecuenciaSemanal = "WEEKLY"
+	FrecuenciaMensual = "MONTHLY"
+)
+
+// maxPeriodosRecurrencia limita la expansión de una regla para evitar bucles infinitos
+const maxPeriodosRecurrencia = 2000
+
+var diasRRULE = map[string]time.Weekday{
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+	"SU": time.Sunday,
+}
+
+// DiaRegla representa un valor de BYDAY: día de la semana con ordinal opcional (ej. 2TU, -1FR)
+type DiaRegla struct {
+	Dia     time.Weekday
+	Ordinal int // 0 = todas las semanas del mes
+}
+
+// ReglaRecurrencia es el subconjunto de RRULE (RFC 5545) usado por las plantillas de visita
+type ReglaRecurrencia struct {
+	Frecuencia string
+	Intervalo  int
+	Conteo     int
+	Hasta      *time.Time
+	PorDia     []DiaRegla
+	PorDiaMes  []int
+}
+
+// ParseReglaRecurrencia interpreta una cadena RRULE (con o sin prefijo "RRULE:"). Un UNTIL sin "Z"
+// (solo fecha u hora flotante) se interpreta en zona, la de DTSTART, como indica RFC 5545.
+func ParseReglaRecurrencia(regla string, zona *time.Location) (*ReglaRecurrencia, error) {
+	regla = strings.TrimSpace(regla)
+	regla = strings.TrimPrefix(strings.ToUpper(regla), "RRULE:")
+	if regla == "" {
+		return nil, errors.New("la regla de recurrencia es requerida")
+	}
+
+	r := &ReglaRecurrencia{Intervalo: 1}
+	for _, parte := range strings.Split(regla, ";") {
+		if parte == "" {
+			continue
+		}
+		kv := strings.SplitN(parte, "=", 2)
+		if len(kv) != 2 || kv[1] == "" {
+			return nil, fmt.Errorf("parte de regla inválida: %s", parte)
+		}
+		clave, valor := kv[0], kv[1]
+		switch clave {
+		case "FREQ":
+			if valor != FrecuenciaSemanal && valor != FrecuenciaMensual {
+				return nil, fmt.Errorf("frecuencia no soportada: %s (use WEEKLY o MONTHLY)", valor)
+			}
+			r.Frecuencia = valor
+		case "INTERVAL":
+			n, err := strconv.Atoi(valor)
+			if err != nil || n <= 0 {
+				return nil, fmt.Errorf("INTERVAL inválido: %s", valor)
+			}
+			r.Intervalo = n
+		case "COUNT":
+			n, err := strconv.Atoi(valor)
+			if err != nil || n <= 0 {
+				return nil, fmt.Errorf("COUNT inválido: %s", valor)
+			}
+			r.Conteo = n
+		case "UNTIL":
+			hasta, err := parseFechaRRULE(valor, zona)
+			if err != nil {
+				return nil, fmt.Errorf("UNTIL inválido: %s", valor)
+			}
+			r.Hasta = &hasta
+		case "BYDAY":
+			for _, d := range strings.Split(valor, ",") {
+				dia, err := parseDiaRegla(d)
+				if err != nil {
+					return nil, err
+				}
+				r.PorDia = append(r.PorDia, dia)
+			}
+		case "BYMONTHDAY":
+			for _, d := range strings.Split(valor, ",") {
+				n, err := strconv.Atoi(d)
+				if err != nil || n == 0 || n < -31 || n > 31 {
+					return nil, fmt.Errorf("BYMONTHDAY inválido: %s", d)
+				}
+				r.PorDiaMes = append(r.PorDiaMes, n)
+			}
+		case "WKST":
+			if valor != "MO" {
+				return nil, errors.New("solo se soporta WKST=MO")
+			}
+		default:
+			return nil, fmt.Errorf("parte de regla no soportada: %s", clave)
+		}
+	}
+
+	if r.Frecuencia == "" {
+		return nil, errors.New("FREQ es requerido")
+	}
+	if r.Conteo > 0 && r.Hasta != nil {
+		return nil, errors.New("COUNT y UNTIL no pueden usarse juntos")
+	}
+	if r.Frecuencia == FrecuenciaSemanal {
+		if len(r.PorDiaMes) > 0 {
+			return nil, errors.New("BYMONTHDAY no se permite con FREQ=WEEKLY")
+		}
+		for _, d := range r.PorDia {
+			if d.Ordinal != 0 {
+				return nil, errors.New("BYDAY con ordinal solo se permite con FREQ=MONTHLY")
+			}
+		}
+	}
+	return r, nil
+}
+
+func parseDiaRegla(valor string) (DiaRegla, error) {
+	valor = strings.TrimSpace(valor)
+	if len(valor) < 2 {
+		return DiaRegla{}, fmt.Errorf("BYDAY inválido: %s", valor)
+	}
+	dia, ok := diasRRULE[valor[len(valor)-2:]]
+	if !ok {
+		return DiaRegla{}, fmt.Errorf("BYDAY inválido: %s", valor)
+	}
+	ordinal := 0
+	if prefijo := valor[:len(valor)-2]; prefijo != "" {
+		n, err := strconv.Atoi(prefijo)
+		if err != nil || n == 0 || n < -5 || n > 5 {
+			return DiaRegla{}, fmt.Errorf("BYDAY inválido: %s", valor)
+		}
+		ordinal = n
+	}
+	return DiaRegla{Dia: dia, Ordinal: ordinal}, nil
+}
+
+func parseFechaRRULE(valor string, zona *time.Location) (time.Time, error) {
+	if t, err := time.Parse("20060102T150405Z", valor); err == nil {
+		return t, nil
+	}
+	for _, layout := range []string{"20060102T150405", "20060102"} {
+		if t, err := time.ParseInLocation(layout, valor, zona); err == nil {
+			if layout == "20060102" {
+				// UNTIL con solo fecha incluye el día completo
+				t = t.Add(24*time.Hour - time.Second)
+			}
+			return t, nil
+		}
+	}
+	return time.Time{}, errors.New("formato de fecha inválido")
+}
+
+// String serializa la regla en formato RRULE
+func (r *ReglaRecurrencia) String() string {
+	partes := []string{"FREQ=" + r.Frecuencia}
+	if r.Intervalo > 1 {
+		partes = append(partes, "INTERVAL="+strconv.Itoa(r.Intervalo))
+	}
+	if r.Conteo > 0 {
+		partes = append(partes, "COUNT="+strconv.Itoa(r.Conteo))
+	}
+	if r.Hasta != nil {
+		partes = append(partes, "UNTIL="+r.Hasta.UTC().Format("20060102T150405Z"))
+	}
+	if len(r.PorDia) > 0 {
+		dias := make([]string, 0, len(r.PorDia))
+		for _, d := range r.PorDia {
+			codigo := ""
+			for k, v := range diasRRULE {
+				if v == d.Dia {
+					codigo = k
+				}
+			}
+			if d.Ordinal != 0 {
+				codigo = strconv.Itoa(d.Ordinal) + codigo
+			}
+			dias = append(dias, codigo)
+		}
+		partes = append(partes, "BYDAY="+strings.Join(dias, ","))
+	}
+	if len(r.PorDiaMes) > 0 {
+		dias := make([]string, 0, len(r.PorDiaMes))
+		for _, d := range r.PorDiaMes {
+			dias = append(dias, strconv.Itoa(d))
+		}
+		partes = append(partes, "BYMONTHDAY="+strings.Join(dias, ","))
+	}
+	return strings.Join(partes, ";")
+}
+
+// Ocurrencias expande la regla desde inicio (DTSTART) y devuelve las fechas hasta limite (inclusive).
+// COUNT se aplica antes de descartar excepciones, como indica RFC 5545 para EXDATE.
+func (r *ReglaRecurrencia) Ocurrencias(inicio, limite time.Time, excepciones map[string]bool) []time.Time {
+	var resultado []time.Time
+	generadas := 0
+
+	// emitir devuelve false cuando la serie terminó
+	emitir := func(candidatos []time.Time) bool {
+		for _, t := range candidatos {
+			if t.Before(inicio) {
+				continue
+			}
+			if r.Hasta != nil && t.After(*r.Hasta) {
+				return false
+			}
+			if r.Conteo > 0 && generadas >= r.Conteo {
+				return false
+			}
+			if t.After(limite) {
+				return false
+			}
+			generadas++
+			if !excepciones[t.Format("2006-01-02")] {
+				resultado = append(resultado, t)
+			}
+		}
+		return true
+	}
+
+	switch r.Frecuencia {
+	case FrecuenciaSemanal:
+		dias := r.PorDia
+		if len(dias) == 0 {
+			dias = []DiaRegla{{Dia: inicio.Weekday()}}
+		}
+		// Semana que inicia en lunes (WKST=MO)
+		desfase := (int(inicio.Weekday()) + 6) % 7
+		lunes := time.Date(inicio.Year(), inicio.Month(), inicio.Day()-desfase,
+			inicio.Hour(), inicio.Minute(), inicio.Second(), 0, inicio.Location())
+		for p := 0; p < maxPeriodosRecurrencia; p++ {
+			semana := lunes.AddDate(0, 0, 7*r.Intervalo*p)
+			candidatos := make([]time.Time, 0, len(dias))
+			for _, d := range dias {
+				candidatos = append(candidatos, semana.AddDate(0, 0, (int(d.Dia)+6)%7))
+			}
+			ordenarFechas(candidatos)
+			if !emitir(candidatos) {
+				break
+			}
+		}
+	case FrecuenciaMensual:
+		primerMes := time.Date(inicio.Year(), inicio.Month(), 1,
+			inicio.Hour(), inicio.Minute(), inicio.Second(), 0, inicio.Location())
+		for p := 0; p < maxPeriodosRecurrencia; p++ {
+			mes := primerMes.AddDate(0, r.Intervalo*p, 0)
+			if !emitir(r.candidatosMes(mes, inicio)) {
+				break
+			}
+		}
+	}
+	return resultado
+}
+
+// candidatosMes devuelve las fechas del mes (primer día en mes) que cumplen BYMONTHDAY/BYDAY.
+// Si la regla tiene ambos, BYDAY limita a BYMONTHDAY (RFC 5545): FREQ=MONTHLY;BYDAY=FR;BYMONTHDAY=13
+// genera solo los viernes 13.
+func (r *ReglaRecurrencia) candidatosMes(mes, inicio time.Time) []time.Time {
+	diasEnMes := mes.AddDate(0, 1, -1).Day()
+	vistos := map[int]bool{}
+	var dias []int
+
+	agregar := func(d int) {
+		if d >= 1 && d <= diasEnMes && !vistos[d] {
+			vistos[d] = true
+			dias = append(dias, d)
+		}
+	}
+
+	diasMes := map[int]bool{}
+	for _, d := range r.PorDiaMes {
+		if d < 0 {
+			d = diasEnMes + d + 1
+		}
+		diasMes[d] = true
+		if len(r.PorDia) == 0 {
+			agregar(d)
+		}
+	}
+	if len(r.PorDiaMes) > 0 {
+		// Con BYMONTHDAY, los días de BYDAY solo se agregan si también están en BYMONTHDAY
+		agregarDia := agregar
+		agregar = func(d int) {
+			if diasMes[d] {
+				agregarDia(d)
+			}
+		}
+	}
+	for _, dr := range r.PorDia {
+		var coincidencias []int
+		for d := 1; d <= diasEnMes; d++ {
+			if mes.AddDate(0, 0, d-1).Weekday() == dr.Dia {
+				coincidencias = append(coincidencias, d)
+			}
+		}
+		switch {
+		case dr.Ordinal == 0:
+			for _, d := range coincidencias {
+				agregar(d)
+			}
+		case dr.Ordinal > 0 && dr.Ordinal <= len(coincidencias):
+			agregar(coincidencias[dr.Ordinal-1])
+		case dr.Ordinal < 0 && -dr.Ordinal <= len(coincidencias):
+			agregar(coincidencias[len(coincidencias)+dr.Ordinal])
+		}
+	}
+	if len(r.PorDiaMes) == 0 && len(r.PorDia) == 0 {
+		// Los meses sin ese día (ej. 31) se omiten, como indica RFC 5545
+		agregar(inicio.Day())
+	}
+
+	candidatos := make([]time.Time, 0, len(dias))
+	for _, d := range dias {
+		candidatos = append(candidatos, mes.AddDate(0, 0, d-1))
+	}
+	ordenarFechas(candidatos)
+	return candidatos
+}
+
+func ordenarFechas(fechas []time.Time) {
+	sort.Slice(fechas, func(i, j int) bool { return fechas[i].Before(fechas[j]) })
+}
+
+// ParseExcepciones convierte la lista "YYYY-MM-DD,YYYY-MM-DD" en un conjunto
+func ParseExcepciones(excepciones string) (map[string]bool, error) {
+	conjunto := map[string]bool{}
+	for _, f := range strings.Split(excepciones, ",") {
+		f = strings.TrimSpace(f)
+		if f == "" {
+			continue
+		}
+		if _, err := time.Parse("2006-01-02", f); err != nil {
+			return nil, fmt.Errorf("excepción con formato inválido: %s (use YYYY-MM-DD)", f)
+		}
+		conjunto[f] = true
+	}
+	return conjunto, nil
+}
+
+// JoinExcepciones serializa un conjunto de excepciones ordenado
+func JoinExcepciones(conjunto map[string]bool) string {
+	fechas := make([]string, 0, len(conjunto))
+	for f := range conjunto {
+		fechas = append(fechas, f)
+	}
+	sort.Strings(fechas)
+	return strings.Join(fechas, ",")
+}
@@ -0,0 +1,120 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseReglaRecurrencia(t *testing.T) {
+	casos := []struct {
+		regla string
+		error string // Fragmento esperado del error; vacío si la regla es válida
+		texto string // Serialización esperada de una regla válida
+	}{
+		{"RRULE:FREQ=WEEKLY;BYDAY=MO,WE", "", "FREQ=WEEKLY;BYDAY=MO,WE"},
+		{"freq=monthly;interval=2;byday=2tu", "", "FREQ=MONTHLY;INTERVAL=2;BYDAY=2TU"},
+		{"FREQ=MONTHLY;BYMONTHDAY=-1;COUNT=3", "", "FREQ=MONTHLY;COUNT=3;BYMONTHDAY=-1"},
+		{"FREQ=WEEKLY;UNTIL=20260301", "", "FREQ=WEEKLY;UNTIL=20260301T235959Z"},
+		{"FREQ=WEEKLY;WKST=MO", "", "FREQ=WEEKLY"},
+		{"", "requerida", ""},
+		{"BYDAY=MO", "FREQ es requerido", ""},
+		{"FREQ=DAILY", "frecuencia no soportada", ""},
+		{"FREQ=WEEKLY;INTERVAL=0", "INTERVAL inválido", ""},
+		{"FREQ=WEEKLY;COUNT=2;UNTIL=20260301", "COUNT y UNTIL", ""},
+		{"FREQ=WEEKLY;BYDAY=XX", "BYDAY inválido", ""},
+		{"FREQ=WEEKLY;BYDAY=1MO", "ordinal", ""},
+		{"FREQ=WEEKLY;BYMONTHDAY=1", "BYMONTHDAY no se permite", ""},
+		{"FREQ=MONTHLY;BYMONTHDAY=32", "BYMONTHDAY inválido", ""},
+		{"FREQ=MONTHLY;BYDAY=6FR", "BYDAY inválido", ""},
+		{"FREQ=WEEKLY;WKST=SU", "WKST", ""},
+		{"FREQ=WEEKLY;BYSETPOS=1", "no soportada", ""},
+	}
+	for _, caso := range casos {
+		t.Run(caso.regla, func(t *testing.T) {
+			regla, err := ParseReglaRecurrencia(caso.regla, time.UTC)
+			if caso.error != "" {
+				if err == nil || !strings.Contains(err.Error(), caso.error) {
+					t.Fatalf("error = %v, se esperaba %q", err, caso.error)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if regla.String() != caso.texto {
+				t.Errorf("String() = %q, se esperaba %q", regla.String(), caso.texto)
+			}
+		})
+	}
+}
+
+func TestOcurrenciasDeLaRegla(t *testing.T) {
+	zona := time.FixedZone("ECT", -5*3600)
+	// fecha acepta "2006-01-02" (a las 09:00) o "2006-01-02 15:04"
+	fecha := func(dia string) time.Time {
+		if !strings.Contains(dia, " ") {
+			dia += " 09:00"
+		}
+		f, err := time.ParseInLocation("2006-01-02 15:04", dia, zona)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return f
+	}
+	limite := fecha("2026-12-31")
+
+	casos := []struct {
+		nombre      string
+		regla       string
+		inicio      string
+		excepciones string
+		esperadas   []string
+	}{
+		{"semanal sin BYDAY usa el día de inicio", "FREQ=WEEKLY;COUNT=3", "2026-01-07", "",
+			[]string{"2026-01-07", "2026-01-14", "2026-01-21"}},
+		{"semanal con varios días e intervalo", "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,FR;COUNT=4", "2026-01-07", "",
+			[]string{"2026-01-09", "2026-01-19", "2026-01-23", "2026-02-02"}},
+		{"mensual por ordinal", "FREQ=MONTHLY;BYDAY=2TU;COUNT=3", "2026-01-01", "",
+			[]string{"2026-01-13", "2026-02-10", "2026-03-10"}},
+		{"mensual último viernes", "FREQ=MONTHLY;BYDAY=-1FR;COUNT=2", "2026-01-01", "",
+			[]string{"2026-01-30", "2026-02-27"}},
+		{"mensual último día", "FREQ=MONTHLY;BYMONTHDAY=-1;COUNT=3", "2026-01-01", "",
+			[]string{"2026-01-31", "2026-02-28", "2026-03-31"}},
+		{"mensual omite los meses sin el día de inicio", "FREQ=MONTHLY;COUNT=3", "2026-01-31", "",
+			[]string{"2026-01-31", "2026-03-31", "2026-05-31"}},
+		{"BYDAY limita a BYMONTHDAY", "FREQ=MONTHLY;BYDAY=FR;BYMONTHDAY=13", "2026-01-01", "",
+			[]string{"2026-02-13", "2026-03-13", "2026-11-13"}},
+		{"UNTIL incluye el día completo", "FREQ=WEEKLY;UNTIL=20260121", "2026-01-07", "",
+			[]string{"2026-01-07", "2026-01-14", "2026-01-21"}},
+		{"UNTIL de solo fecha incluye la visita de la noche", "FREQ=WEEKLY;UNTIL=20260121", "2026-01-07 19:00", "",
+			[]string{"2026-01-07", "2026-01-14", "2026-01-21"}},
+		{"UNTIL flotante en la zona de inicio", "FREQ=WEEKLY;UNTIL=20260121T190000", "2026-01-07 19:00", "",
+			[]string{"2026-01-07", "2026-01-14", "2026-01-21"}},
+		{"COUNT cuenta las excepciones", "FREQ=WEEKLY;COUNT=3", "2026-01-07", "2026-01-14",
+			[]string{"2026-01-07", "2026-01-21"}},
+	}
+	for _, caso := range casos {
+		t.Run(caso.nombre, func(t *testing.T) {
+			regla, err := ParseReglaRecurrencia(caso.regla, zona)
+			if err != nil {
+				t.Fatal(err)
+			}
+			excepciones, err := ParseExcepciones(caso.excepciones)
+			if err != nil {
+				t.Fatal(err)
+			}
+			inicio := fecha(caso.inicio)
+			var obtenidas []string
+			for _, f := range regla.Ocurrencias(inicio, limite, excepciones) {
+				if f.Hour() != inicio.Hour() || f.Location() != zona {
+					t.Errorf("la ocurrencia %s no conserva la hora ni la zona de inicio", f)
+				}
+				obtenidas = append(obtenidas, f.Format("2006-01-02"))
+			}
+			if strings.Join(obtenidas, ",") != strings.Join(caso.esperadas, ",") {
+				t.Errorf("ocurrencias = %v, se esperaban %v", obtenidas, caso.esperadas)
+			}
+		})
+	}
+}
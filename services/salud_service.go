@@ -0,0 +1,166 @@
+package services
+
+import (
+	"ApiEscuela/repositories"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Estados de la verificación de salud
+const (
+	EstadoSaludOK        = "ok"
+	EstadoSaludDegradado = "degradado" // Falló una dependencia opcional; la API sigue atendiendo
+	EstadoSaludError     = "error"
+)
+
+var errTiempoAgotado = errors.New("tiempo de espera agotado")
+
+// EstadoComponente es el resultado de verificar una dependencia. Las que no son críticas (SMTP) no
+// sacan a la instancia del balanceador cuando fallan.
+type EstadoComponente struct {
+	Estado     string `json:"status"`
+	Critico    bool   `json:"critico"`
+	DuracionMS int64  `json:"duracion_ms"`
+	Detalle    string `json:"detalle,omitempty"`
+}
+
+// EstadoSalud es la respuesta de las verificaciones de vida y disponibilidad
+type EstadoSalud struct {
+	Estado      string                      `json:"status"`
+	Componentes map[string]EstadoComponente `json:"componentes,omitempty"`
+}
+
+// SaludService verifica las dependencias de la API para las sondas del orquestador
+type SaludService struct {
+	saludRepo     *repositories.SaludRepository
+	storage       Storage
+	modelos       []interface{}
+	direccionSMTP string        // host:puerto a verificar; vacío para omitir el SMTP
+	limite        time.Duration // Plazo de cada verificación
+}
+
+func NewSaludService(saludRepo *repositories.SaludRepository, storage Storage, modelos []interface{}, direccionSMTP string, limite time.Duration) *SaludService {
+	return &SaludService{saludRepo: saludRepo, storage: storage, modelos: modelos, direccionSMTP: direccionSMTP, limite: limite}
+}
+
+type verificacion struct {
+	nombre   string
+	critico  bool
+	detalle  string // Mensaje público si falla; el error completo solo va al log
+	ejecutar func(ctx context.Context) error
+}
+
+// Vivo responde la sonda de vida: si el proceso atiende peticiones, está vivo. No consulta dependencias
+// para que una caída de la base de datos no provoque reinicios en cadena.
+func (s *SaludService) Vivo() EstadoSalud {
+	return EstadoSalud{Estado: EstadoSaludOK}
+}
+
+// Listo verifica en paralelo la base de datos, las migraciones, el almacenamiento y, si se indicó una
+// dirección (SALUD_VERIFICAR_SMTP=true), el servidor SMTP. Cada verificación tiene SALUD_TIMEOUT (por defecto 2s).
+func (s *SaludService) Listo(ctx context.Context) EstadoSalud {
+	verificaciones := []verificacion{
+		{"database", true, "PostgreSQL no responde", s.saludRepo.Ping},
+		{"migraciones", true, "", s.verificarMigraciones},
+		{"storage", true, "No se puede escribir en el almacenamiento de archivos", s.verificarStorage},
+	}
+	if s.direccionSMTP != "" {
+		verificaciones = append(verificaciones, verificacion{"smtp", false, "El servidor SMTP no responde", s.verificarSMTP})
+	}
+
+	componentes := make(map[string]EstadoComponente, len(verificaciones))
+	var mu sync.Mutex
+	var grupo sync.WaitGroup
+	for _, v := range verificaciones {
+		grupo.Add(1)
+		go func() {
+			defer grupo.Done()
+			inicio := time.Now()
+			err := ejecutarConLimite(ctx, s.limite, v.ejecutar)
+			componente := EstadoComponente{Estado: EstadoSaludOK, Critico: v.critico, DuracionMS: time.Since(inicio).Milliseconds()}
+			if err != nil {
+				componente.Estado = EstadoSaludError
+				componente.Detalle = v.detalle
+				if componente.Detalle == "" || errors.Is(err, errTiempoAgotado) {
+					componente.Detalle = err.Error()
+				}
+				slog.WarnContext(ctx, "verificación de salud fallida", "componente", v.nombre, "error", err)
+			}
+			mu.Lock()
+			componentes[v.nombre] = componente
+			mu.Unlock()
+		}()
+	}
+	grupo.Wait()
+
+	estado := EstadoSaludOK
+	for _, componente := range componentes {
+		if componente.Estado == EstadoSaludOK {
+			continue
+		}
+		if componente.Critico {
+			estado = EstadoSaludError
+			break
+		}
+		estado = EstadoSaludDegradado
+	}
+	return EstadoSalud{Estado: estado, Componentes: componentes}
+}
+
+// ejecutarConLimite corta la espera al vencer el plazo aunque la verificación no acepte contexto
+// (el almacenamiento no lo recibe); la verificación termina por su cuenta en segundo plano
+func ejecutarConLimite(ctx context.Context, limite time.Duration, verificar func(context.Context) error) error {
+	ctx, cancelar := context.WithTimeout(ctx, limite)
+	defer cancelar()
+	resultado := make(chan error, 1)
+	go func() { resultado <- verificar(ctx) }()
+	select {
+	case err := <-resultado:
+		return err
+	case <-ctx.Done():
+		return errTiempoAgotado
+	}
+}
+
+// verificarMigraciones comprueba que existan las tablas de todos los modelos. El detalle lista las
+// tablas faltantes: son nombres de tablas, no datos, y ayudan a ver qué migración no corrió.
+func (s *SaludService) verificarMigraciones(ctx context.Context) error {
+	faltantes, err := s.saludRepo.TablasFaltantes(ctx, s.modelos)
+	if err != nil {
+		return fmt.Errorf("no se pudo consultar el esquema: %w", err)
+	}
+	if len(faltantes) > 0 {
+		return fmt.Errorf("faltan tablas: %s", strings.Join(faltantes, ", "))
+	}
+	return nil
+}
+
+// verificarStorage escribe y borra un objeto pequeño en salud/, una carpeta que el recolector de
+// huérfanos no revisa
+func (s *SaludService) verificarStorage(_ context.Context) error {
+	sufijo, err := generarTokenHex(8)
+	if err != nil {
+		return err
+	}
+	clave := ClaveArchivo("salud", sufijo)
+	if err := s.storage.Guardar(clave, strings.NewReader("ok"), 2, "text/plain"); err != nil {
+		return err
+	}
+	return s.storage.Eliminar(clave)
+}
+
+// verificarSMTP solo abre y cierra una conexión TCP; no inicia sesión ni envía nada
+func (s *SaludService) verificarSMTP(ctx context.Context) error {
+	var dialer net.Dialer
+	conexion, err := dialer.DialContext(ctx, "tcp", s.direccionSMTP)
+	if err != nil {
+		return err
+	}
+	return conexion.Close()
+}
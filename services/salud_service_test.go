@@ -0,0 +1,47 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestVerificarStorage(t *testing.T) {
+	directorio := t.TempDir()
+	servicio := &SaludService{storage: NewStorageLocal(directorio)}
+	if err := servicio.verificarStorage(context.Background()); err != nil {
+		t.Fatalf("almacenamiento escribible: %v", err)
+	}
+	restantes, _ := os.ReadDir(filepath.Join(directorio, "salud"))
+	if len(restantes) != 0 {
+		t.Errorf("quedaron %d objetos de prueba en salud/", len(restantes))
+	}
+
+	// Un archivo en lugar del directorio base impide escribir
+	bloqueado := filepath.Join(directorio, "bloqueado")
+	if err := os.WriteFile(bloqueado, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	servicio.storage = NewStorageLocal(bloqueado)
+	if err := servicio.verificarStorage(context.Background()); err == nil {
+		t.Error("se esperaba un error con un almacenamiento no escribible")
+	}
+}
+
+func TestEjecutarConLimite(t *testing.T) {
+	lenta := func(context.Context) error {
+		time.Sleep(200 * time.Millisecond)
+		return nil
+	}
+	if err := ejecutarConLimite(context.Background(), 10*time.Millisecond, lenta); !errors.Is(err, errTiempoAgotado) {
+		t.Errorf("verificación lenta: %v, se esperaba errTiempoAgotado", err)
+	}
+
+	fallida := func(context.Context) error { return errors.New("sin conexión") }
+	if err := ejecutarConLimite(context.Background(), time.Second, fallida); err == nil || err.Error() != "sin conexión" {
+		t.Errorf("se esperaba el error de la verificación, se obtuvo %v", err)
+	}
+}
@@ -0,0 +1,386 @@
+package services
+
+import (
+	"ApiEscuela/models"
+	"ApiEscuela/repositories"
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+var (
+	ErrReglaNoEncontrada    = errors.New("regla de asignación no encontrada")
+	ErrReglaSinCriterios    = errors.New("la regla debe tener al menos un criterio: tematica_id, institucion_id o palabra_clave")
+	ErrTematicaDudaInvalida = errors.New("la temática indicada no existe")
+)
+
+type SLADudasService struct {
+	dudasRepo        *repositories.DudasRepository
+	enrutamientoRepo *repositories.EnrutamientoDudasRepository
+	estudianteRepo   *repositories.EstudianteRepository
+	autoridadRepo    *repositories.AutoridadUTEQRepository
+	tematicaRepo     *repositories.TematicaRepository
+	institucionRepo  *repositories.InstitucionRepository
+	emailService     *EmailService
+	zona             *time.Location // Zona de los plazos en los correos
+	intervalo        time.Duration  // Periodo del monitor de plazos
+}
+
+func NewSLADudasService(
+	dudasRepo *repositories.DudasRepository,
+	enrutamientoRepo *repositories.EnrutamientoDudasRepository,
+	estudianteRepo *repositories.EstudianteRepository,
+	autoridadRepo *repositories.AutoridadUTEQRepository,
+	tematicaRepo *repositories.TematicaRepository,
+	institucionRepo *repositories.InstitucionRepository,
+	emailService *EmailService,
+	zona *time.Location,
+	intervalo time.Duration,
+) *SLADudasService {
+	return &SLADudasService{
+		dudasRepo:        dudasRepo,
+		enrutamientoRepo: enrutamientoRepo,
+		estudianteRepo:   estudianteRepo,
+		autoridadRepo:    autoridadRepo,
+		tematicaRepo:     tematicaRepo,
+		institucionRepo:  institucionRepo,
+		emailService:     emailService,
+		zona:             zona,
+		intervalo:        intervalo,
+	}
+}
+
+// ConContexto devuelve una copia del servicio cuyos repositorios usan ctx, para que las consultas queden
+// dentro de la traza de la petición
+func (s *SLADudasService) ConContexto(ctx context.Context) *SLADudasService {
+	copia := *s
+	copia.dudasRepo = s.dudasRepo.ConContexto(ctx)
+	copia.enrutamientoRepo = s.enrutamientoRepo.ConContexto(ctx)
+	copia.estudianteRepo = s.estudianteRepo.ConContexto(ctx)
+	copia.autoridadRepo = s.autoridadRepo.ConContexto(ctx)
+	copia.tematicaRepo = s.tematicaRepo.ConContexto(ctx)
+	copia.institucionRepo = s.institucionRepo.ConContexto(ctx)
+	return &copia
+}
+
+// ReglaAsignacionRequest representa los datos de una regla de asignación
+type ReglaAsignacionRequest struct {
+	Nombre          string `json:"nombre"`
+	Prioridad       *int   `json:"prioridad"`
+	TematicaID      *uint  `json:"tematica_id"`
+	InstitucionID   *uint  `json:"institucion_id"`
+	PalabraClave    string `json:"palabra_clave"`
+	AutoridadUTEQID uint   `json:"autoridad_uteq_id"`
+	Activa          *bool  `json:"activa"`
+}
+
+// ConfiguracionSLARequest representa los cambios a la configuración del SLA
+type ConfiguracionSLARequest struct {
+	HorasRespuesta    int   `json:"horas_respuesta"`
+	HorasRecordatorio int   `json:"horas_recordatorio"`
+	SupervisorID      *uint `json:"supervisor_id"`
+}
+
+// ReporteSLA contiene las métricas globales y por autoridad de un rango de fechas
+type ReporteSLA struct {
+	Desde        time.Time                  `json:"desde"`
+	Hasta        time.Time                  `json:"hasta"`
+	General      repositories.MetricasSLA   `json:"general"`
+	PorAutoridad []repositories.MetricasSLA `json:"por_autoridad"`
+}
+
+// RegistrarDuda fija el plazo de respuesta, asigna una autoridad según las reglas si la duda no tiene una,
+// guarda la duda y avisa a la autoridad asignada
+func (s *SLADudasService) RegistrarDuda(ctx context.Context, duda *models.Dudas) error {
+	if duda.TematicaID != nil {
+		if _, err := s.tematicaRepo.GetTematicaByID(*duda.TematicaID); err != nil {
+			return ErrTematicaDudaInvalida
+		}
+	}
+	configuracion, err := s.enrutamientoRepo.GetConfiguracionSLA()
+	if err != nil {
+		return err
+	}
+
+	if duda.FechaPregunta.IsZero() {
+		duda.FechaPregunta = time.Now()
+	}
+	vence := duda.FechaPregunta.Add(time.Duration(configuracion.HorasRespuesta) * time.Hour)
+	duda.VenceEn = &vence
+
+	if duda.AutoridadUTEQID == nil {
+		regla, err := s.reglaAplicable(duda)
+		if err != nil {
+			return err
+		}
+		if regla != nil {
+			duda.AutoridadUTEQID = &regla.AutoridadUTEQID
+		}
+	}
+	if duda.AutoridadUTEQID != nil {
+		asignada := duda.FechaPregunta
+		duda.AsignadaEn = &asignada
+	}
+
+	if err := s.dudasRepo.CreateDudas(duda); err != nil {
+		return err
+	}
+
+	if duda.AutoridadUTEQID != nil {
+		if autoridad, err := s.autoridadRepo.GetAutoridadUTEQByID(*duda.AutoridadUTEQID); err == nil && correoValido(autoridad.Persona.Correo) {
+			s.emailService.EnviarEnSegundoPlano(ctx, autoridad.Persona.Correo, "Nueva duda asignada",
+				fmt.Sprintf(`<p>Hola %s,</p><p>Se le asignó la duda #%d:</p><blockquote>%s</blockquote><p>Plazo de respuesta: %s</p>`,
+					html.EscapeString(autoridad.Persona.Nombre), duda.ID, html.EscapeString(duda.Pregunta), s.formatoPlazo(vence)))
+		}
+	}
+	return nil
+}
+
+// CrearRegla valida y guarda una regla de asignación
+func (s *SLADudasService) CrearRegla(req ReglaAsignacionRequest) (*models.ReglaAsignacionDuda, error) {
+	regla := &models.ReglaAsignacionDuda{Prioridad: 100, Activa: true}
+	if err := s.aplicarRegla(regla, req); err != nil {
+		return nil, err
+	}
+	if err := s.enrutamientoRepo.CreateRegla(regla); err != nil {
+		return nil, err
+	}
+	return s.enrutamientoRepo.GetReglaByID(regla.ID)
+}
+
+// ActualizarRegla reemplaza los datos de una regla de asignación
+func (s *SLADudasService) ActualizarRegla(id uint, req ReglaAsignacionRequest) (*models.ReglaAsignacionDuda, error) {
+	regla, err := s.enrutamientoRepo.GetReglaByID(id)
+	if err != nil {
+		return nil, ErrReglaNoEncontrada
+	}
+	if err := s.aplicarRegla(regla, req); err != nil {
+		return nil, err
+	}
+	if err := s.enrutamientoRepo.UpdateRegla(regla); err != nil {
+		return nil, err
+	}
+	return s.enrutamientoRepo.GetReglaByID(id)
+}
+
+// ActualizarConfiguracion cambia el SLA. Los plazos de las dudas ya registradas no se recalculan.
+func (s *SLADudasService) ActualizarConfiguracion(req ConfiguracionSLARequest) (*models.ConfiguracionSLADudas, error) {
+	if req.HorasRespuesta <= 0 {
+		return nil, errors.New("horas_respuesta debe ser mayor que cero")
+	}
+	if req.HorasRecordatorio < 0 || req.HorasRecordatorio >= req.HorasRespuesta {
+		return nil, errors.New("horas_recordatorio debe estar entre 0 y horas_respuesta")
+	}
+	if req.SupervisorID != nil {
+		if _, err := s.autoridadRepo.GetAutoridadUTEQByID(*req.SupervisorID); err != nil {
+			return nil, errors.New("el supervisor indicado no existe")
+		}
+	}
+
+	configuracion := &models.ConfiguracionSLADudas{
+		HorasRespuesta:    req.HorasRespuesta,
+		HorasRecordatorio: req.HorasRecordatorio,
+		SupervisorID:      req.SupervisorID,
+	}
+	if err := s.enrutamientoRepo.GuardarConfiguracionSLA(configuracion); err != nil {
+		return nil, err
+	}
+	return s.enrutamientoRepo.GetConfiguracionSLA()
+}
+
+// RevisarPlazos envía recordatorios de las dudas próximas a vencer y escala al supervisor las vencidas
+func (s *SLADudasService) RevisarPlazos(ahora time.Time) (recordatorios, escaladas int, err error) {
+	configuracion, err := s.enrutamientoRepo.GetConfiguracionSLA()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if configuracion.HorasRecordatorio > 0 {
+		limite := ahora.Add(time.Duration(configuracion.HorasRecordatorio) * time.Hour)
+		porRecordar, err := s.dudasRepo.GetDudasPorRecordar(repositories.VisorSistema, ahora, limite)
+		if err != nil {
+			return 0, 0, err
+		}
+		for _, duda := range porRecordar {
+			marcada, err := s.dudasRepo.MarcarRecordatorio(duda.ID, ahora)
+			if err != nil {
+				return recordatorios, 0, err
+			}
+			if !marcada || duda.AutoridadUTEQ == nil || !correoValido(duda.AutoridadUTEQ.Persona.Correo) {
+				continue
+			}
+			s.emailService.EnviarEnSegundoPlano(context.Background(), duda.AutoridadUTEQ.Persona.Correo, "Recordatorio: duda por vencer",
+				fmt.Sprintf(`<p>Hola %s,</p><p>La duda #%d vence el %s y aún no tiene respuesta:</p><blockquote>%s</blockquote>`,
+					html.EscapeString(duda.AutoridadUTEQ.Persona.Nombre), duda.ID, s.formatoPlazo(*duda.VenceEn), html.EscapeString(duda.Pregunta)))
+			recordatorios++
+		}
+	}
+
+	if configuracion.SupervisorID == nil || configuracion.Supervisor == nil {
+		return recordatorios, 0, nil
+	}
+	supervisor := configuracion.Supervisor
+	vencidas, err := s.dudasRepo.GetDudasVencidas(repositories.VisorSistema, ahora)
+	if err != nil {
+		return recordatorios, 0, err
+	}
+	for _, duda := range vencidas {
+		marcada, err := s.dudasRepo.MarcarEscalada(duda.ID, supervisor.ID, ahora)
+		if err != nil {
+			return recordatorios, escaladas, err
+		}
+		if !marcada {
+			continue
+		}
+		escaladas++
+
+		asignada := "sin asignar"
+		if duda.AutoridadUTEQ != nil {
+			asignada = duda.AutoridadUTEQ.Persona.Nombre
+		}
+		cuerpo := fmt.Sprintf(`<p>La duda #%d venció el %s sin respuesta y fue escalada.</p><p>Asignada a: %s</p><blockquote>%s</blockquote>`,
+			duda.ID, s.formatoPlazo(*duda.VenceEn), html.EscapeString(asignada), html.EscapeString(duda.Pregunta))
+		if correoValido(supervisor.Persona.Correo) {
+			s.emailService.EnviarEnSegundoPlano(context.Background(), supervisor.Persona.Correo, "Duda escalada por falta de respuesta", cuerpo)
+		}
+		if duda.AutoridadUTEQ != nil && duda.AutoridadUTEQ.ID != supervisor.ID && correoValido(duda.AutoridadUTEQ.Persona.Correo) {
+			s.emailService.EnviarEnSegundoPlano(context.Background(), duda.AutoridadUTEQ.Persona.Correo, "Duda escalada por falta de respuesta", cuerpo)
+		}
+	}
+	return recordatorios, escaladas, nil
+}
+
+// IniciarMonitor revisa los plazos periódicamente en segundo plano (DUDAS_SLA_INTERVALO, por defecto 15m).
+// Devuelve una función que detiene el monitor y espera a que termine la revisión en curso.
+func (s *SLADudasService) IniciarMonitor() func() {
+	detener := make(chan struct{})
+	terminado := make(chan struct{})
+	go func() {
+		defer close(terminado)
+		ticker := time.NewTicker(s.intervalo)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				recordatorios, escaladas, err := s.RevisarPlazos(time.Now())
+				if err != nil {
+					slog.Error("error al revisar plazos de dudas", "error", err)
+				} else if recordatorios > 0 || escaladas > 0 {
+					slog.Info("plazos de dudas revisados", "recordatorios", recordatorios, "escaladas", escaladas)
+				}
+			case <-detener:
+				return
+			}
+		}
+	}()
+	return func() {
+		close(detener)
+		<-terminado
+	}
+}
+
+// Metricas calcula el cumplimiento del SLA de las dudas creadas en el rango
+func (s *SLADudasService) Metricas(desde, hasta time.Time) (*ReporteSLA, error) {
+	ahora := time.Now()
+	general, err := s.dudasRepo.GetMetricasSLA(desde, hasta, ahora, false)
+	if err != nil {
+		return nil, err
+	}
+	porAutoridad, err := s.dudasRepo.GetMetricasSLA(desde, hasta, ahora, true)
+	if err != nil {
+		return nil, err
+	}
+
+	reporte := &ReporteSLA{Desde: desde, Hasta: hasta, PorAutoridad: porAutoridad}
+	if len(general) > 0 {
+		reporte.General = general[0]
+	}
+	reporte.General.Cumplimiento = porcentajeCumplimiento(reporte.General)
+	for i := range reporte.PorAutoridad {
+		reporte.PorAutoridad[i].Cumplimiento = porcentajeCumplimiento(reporte.PorAutoridad[i])
+	}
+	return reporte, nil
+}
+
+// reglaAplicable devuelve la primera regla activa cuyos criterios cumple la duda
+func (s *SLADudasService) reglaAplicable(duda *models.Dudas) (*models.ReglaAsignacionDuda, error) {
+	reglas, err := s.enrutamientoRepo.GetReglasActivas()
+	if err != nil || len(reglas) == 0 {
+		return nil, err
+	}
+	var institucionID uint
+	if estudiante, err := s.estudianteRepo.GetEstudianteByID(duda.EstudianteID); err == nil {
+		institucionID = estudiante.InstitucionID
+	}
+	pregunta := strings.ToLower(duda.Pregunta)
+
+	for i := range reglas {
+		regla := &reglas[i]
+		if regla.TematicaID != nil && (duda.TematicaID == nil || *duda.TematicaID != *regla.TematicaID) {
+			continue
+		}
+		if regla.InstitucionID != nil && *regla.InstitucionID != institucionID {
+			continue
+		}
+		if regla.PalabraClave != "" && !strings.Contains(pregunta, strings.ToLower(regla.PalabraClave)) {
+			continue
+		}
+		return regla, nil
+	}
+	return nil, nil
+}
+
+func (s *SLADudasService) aplicarRegla(regla *models.ReglaAsignacionDuda, req ReglaAsignacionRequest) error {
+	req.Nombre = strings.TrimSpace(req.Nombre)
+	req.PalabraClave = strings.TrimSpace(req.PalabraClave)
+	if req.Nombre == "" {
+		return errors.New("el nombre de la regla es requerido")
+	}
+	if req.TematicaID == nil && req.InstitucionID == nil && req.PalabraClave == "" {
+		return ErrReglaSinCriterios
+	}
+	if req.TematicaID != nil {
+		if _, err := s.tematicaRepo.GetTematicaByID(*req.TematicaID); err != nil {
+			return errors.New("la temática indicada no existe")
+		}
+	}
+	if req.InstitucionID != nil {
+		if _, err := s.institucionRepo.GetInstitucionByID(*req.InstitucionID); err != nil {
+			return errors.New("la institución indicada no existe")
+		}
+	}
+	if _, err := s.autoridadRepo.GetAutoridadUTEQByID(req.AutoridadUTEQID); err != nil {
+		return errors.New("la autoridad indicada no existe")
+	}
+
+	regla.Nombre = req.Nombre
+	regla.TematicaID = req.TematicaID
+	regla.InstitucionID = req.InstitucionID
+	regla.PalabraClave = req.PalabraClave
+	regla.AutoridadUTEQID = req.AutoridadUTEQID
+	if req.Prioridad != nil {
+		regla.Prioridad = *req.Prioridad
+	}
+	if req.Activa != nil {
+		regla.Activa = *req.Activa
+	}
+	return nil
+}
+
+// porcentajeCumplimiento es el porcentaje respondido a tiempo entre las dudas respondidas o ya vencidas
+func porcentajeCumplimiento(m repositories.MetricasSLA) *float64 {
+	evaluadas := m.Respondidas + m.VencidasSinRespuesta
+	if evaluadas == 0 {
+		return nil
+	}
+	porcentaje := float64(m.RespondidasATiempo) * 100 / float64(evaluadas)
+	return &porcentaje
+}
+
+func (s *SLADudasService) formatoPlazo(t time.Time) string {
+	return t.In(s.zona).Format("02/01/2006 15:04")
+}
@@ -0,0 +1,33 @@
+package services
+
+import "strings"
+
+// maxLongitudSlug limita el largo de los slugs generados
+const maxLongitudSlug = 80
+
+// generarSlug convierte un texto en un slug: minúsculas, sin tildes y con guiones entre palabras
+func generarSlug(texto string) string {
+	texto = reemplazoTildes.Replace(strings.ToLower(texto))
+	var b strings.Builder
+	guion := false
+	for _, r := range texto {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			if guion && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			guion = false
+			continue
+		}
+		guion = true
+	}
+	slug := b.String()
+	if len(slug) > maxLongitudSlug {
+		slug = slug[:maxLongitudSlug]
+		if i := strings.LastIndexByte(slug, '-'); i > maxLongitudSlug/2 {
+			slug = slug[:i]
+		}
+		slug = strings.TrimRight(slug, "-")
+	}
+	return slug
+}
@@ -0,0 +1,287 @@
+package services
+
+import (
+	"ApiEscuela/models"
+	"ApiEscuela/repositories"
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"net/mail"
+	"strings"
+	"time"
+)
+
+// duracionVisitaPorDef se usa cuando el personal aprueba una solicitud sin indicar la hora de fin
+const duracionVisitaPorDef = 2 * time.Hour
+
+// maxEstudiantesSolicitud limita el tamaño del grupo que se puede pedir desde el formulario público
+const maxEstudiantesSolicitud = 500
+
+var (
+	ErrSolicitudNoEncontrada = errors.New("solicitud de visita no encontrada")
+	ErrSolicitudYaRevisada   = errors.New("la solicitud ya fue revisada")
+	ErrRevisionNoPermitida   = errors.New("solo el personal de la UTEQ puede consultar, aprobar o rechazar solicitudes")
+)
+
+type SolicitudVisitaService struct {
+	solicitudRepo   *repositories.SolicitudVisitaRepository
+	institucionRepo *repositories.InstitucionRepository
+	tematicaRepo    *repositories.TematicaRepository
+	usuarioRepo     *repositories.UsuarioRepository
+	autoridadRepo   *repositories.AutoridadUTEQRepository
+	emailService    *EmailService
+}
+
+func NewSolicitudVisitaService(
+	solicitudRepo *repositories.SolicitudVisitaRepository,
+	institucionRepo *repositories.InstitucionRepository,
+	tematicaRepo *repositories.TematicaRepository,
+	usuarioRepo *repositories.UsuarioRepository,
+	autoridadRepo *repositories.AutoridadUTEQRepository,
+	emailService *EmailService,
+) *SolicitudVisitaService {
+	return &SolicitudVisitaService{
+		solicitudRepo:   solicitudRepo,
+		institucionRepo: institucionRepo,
+		tematicaRepo:    tematicaRepo,
+		usuarioRepo:     usuarioRepo,
+		autoridadRepo:   autoridadRepo,
+		emailService:    emailService,
+	}
+}
+
+// ConContexto devuelve una copia del servicio cuyos repositorios usan ctx, para que las consultas queden
+// dentro de la traza de la petición
+func (s *SolicitudVisitaService) ConContexto(ctx context.Context) *SolicitudVisitaService {
+	copia := *s
+	copia.solicitudRepo = s.solicitudRepo.ConContexto(ctx)
+	copia.institucionRepo = s.institucionRepo.ConContexto(ctx)
+	copia.tematicaRepo = s.tematicaRepo.ConContexto(ctx)
+	copia.usuarioRepo = s.usuarioRepo.ConContexto(ctx)
+	copia.autoridadRepo = s.autoridadRepo.ConContexto(ctx)
+	return &copia
+}
+
+// SolicitudVisitaRequest representa los datos del formulario público
+type SolicitudVisitaRequest struct {
+	InstitucionID     *uint      `json:"institucion_id"`
+	NombreInstitucion string     `json:"nombre_institucion"`
+	NombreContacto    string     `json:"nombre_contacto"`
+	CorreoContacto    string     `json:"correo_contacto"`
+	TelefonoContacto  string     `json:"telefono_contacto"`
+	FechaPreferida    time.Time  `json:"fecha_preferida"`
+	FechaAlternativa  *time.Time `json:"fecha_alternativa"`
+	NumeroEstudiantes int        `json:"numero_estudiantes"`
+	TematicaIDs       []uint     `json:"tematica_ids"`
+	Comentarios       string     `json:"comentarios"`
+}
+
+// AprobacionSolicitudRequest permite ajustar la fecha de la visita al aprobar; por defecto se usa la fecha preferida
+type AprobacionSolicitudRequest struct {
+	Fecha    *time.Time `json:"fecha"`
+	Fechafin *time.Time `json:"fechafin"`
+}
+
+// CrearSolicitud valida y registra una solicitud pública, y confirma la recepción al contacto
+func (s *SolicitudVisitaService) CrearSolicitud(ctx context.Context, req SolicitudVisitaRequest) (*models.SolicitudVisita, error) {
+	req.NombreContacto = strings.TrimSpace(req.NombreContacto)
+	req.CorreoContacto = strings.TrimSpace(req.CorreoContacto)
+	req.NombreInstitucion = strings.TrimSpace(req.NombreInstitucion)
+
+	if req.NombreContacto == "" {
+		return nil, errors.New("el nombre del contacto es requerido")
+	}
+	if !correoValido(req.CorreoContacto) {
+		return nil, errors.New("el correo del contacto no es válido")
+	}
+	if req.InstitucionID != nil {
+		if _, err := s.institucionRepo.GetInstitucionByID(*req.InstitucionID); err != nil {
+			return nil, errors.New("la institución indicada no existe")
+		}
+	} else if req.NombreInstitucion == "" {
+		return nil, errors.New("indique institucion_id o nombre_institucion")
+	}
+	if !req.FechaPreferida.After(time.Now()) {
+		return nil, errors.New("la fecha preferida debe ser futura")
+	}
+	if req.FechaAlternativa != nil && !req.FechaAlternativa.After(time.Now()) {
+		return nil, errors.New("la fecha alternativa debe ser futura")
+	}
+	if req.NumeroEstudiantes <= 0 || req.NumeroEstudiantes > maxEstudiantesSolicitud {
+		return nil, fmt.Errorf("el número de estudiantes debe estar entre 1 y %d", maxEstudiantesSolicitud)
+	}
+
+	codigo, err := generarTokenHex(16)
+	if err != nil {
+		return nil, err
+	}
+
+	solicitud := &models.SolicitudVisita{
+		CodigoSeguimiento: codigo,
+		InstitucionID:     req.InstitucionID,
+		NombreInstitucion: req.NombreInstitucion,
+		NombreContacto:    req.NombreContacto,
+		CorreoContacto:    req.CorreoContacto,
+		TelefonoContacto:  strings.TrimSpace(req.TelefonoContacto),
+		FechaPreferida:    req.FechaPreferida,
+		FechaAlternativa:  req.FechaAlternativa,
+		NumeroEstudiantes: req.NumeroEstudiantes,
+		Comentarios:       strings.TrimSpace(req.Comentarios),
+		Estado:            models.EstadoSolicitudPendiente,
+	}
+	vistas := map[uint]bool{}
+	for _, id := range req.TematicaIDs {
+		if vistas[id] {
+			continue
+		}
+		vistas[id] = true
+		if _, err := s.tematicaRepo.GetTematicaByID(id); err != nil {
+			return nil, fmt.Errorf("la temática %d no existe", id)
+		}
+		solicitud.Tematicas = append(solicitud.Tematicas, models.SolicitudVisitaTematica{TematicaID: id})
+	}
+
+	if err := s.solicitudRepo.CreateSolicitud(solicitud); err != nil {
+		return nil, err
+	}
+
+	s.emailService.EnviarEnSegundoPlano(ctx, solicitud.CorreoContacto, "Solicitud de visita recibida",
+		fmt.Sprintf(`<p>Hola %s,</p><p>Recibimos su solicitud de visita a la UTEQ para el %s.</p><p>Código de seguimiento: <strong>%s</strong></p><p>Le notificaremos cuando sea revisada.</p>`,
+			html.EscapeString(solicitud.NombreContacto), solicitud.FechaPreferida.Format("02/01/2006 15:04"), solicitud.CodigoSeguimiento))
+
+	return solicitud, nil
+}
+
+// ObtenerPorCodigo obtiene una solicitud por su código de seguimiento
+func (s *SolicitudVisitaService) ObtenerPorCodigo(codigo string) (*models.SolicitudVisita, error) {
+	solicitud, err := s.solicitudRepo.GetSolicitudByCodigo(codigo)
+	if err != nil {
+		return nil, ErrSolicitudNoEncontrada
+	}
+	return solicitud, nil
+}
+
+// Aprobar crea el programa de visita de una solicitud pendiente y notifica al contacto de la institución
+func (s *SolicitudVisitaService) Aprobar(ctx context.Context, id, revisorID uint, req AprobacionSolicitudRequest) (*models.SolicitudVisita, error) {
+	if err := s.VerificarRevisor(revisorID); err != nil {
+		return nil, err
+	}
+	solicitud, err := s.solicitudRepo.GetSolicitudByID(id)
+	if err != nil {
+		return nil, ErrSolicitudNoEncontrada
+	}
+	if solicitud.Estado != models.EstadoSolicitudPendiente {
+		return nil, ErrSolicitudYaRevisada
+	}
+
+	fecha := solicitud.FechaPreferida
+	if req.Fecha != nil {
+		fecha = *req.Fecha
+	}
+	fechafin := fecha.Add(duracionVisitaPorDef)
+	if req.Fechafin != nil {
+		if !req.Fechafin.After(fecha) {
+			return nil, errors.New("fechafin debe ser posterior a fecha")
+		}
+		fechafin = *req.Fechafin
+	}
+
+	institucion := &models.Institucion{Nombre: solicitud.NombreInstitucion, Contacto: solicitud.CorreoContacto}
+	if solicitud.InstitucionID != nil {
+		if institucion, err = s.institucionRepo.GetInstitucionByID(*solicitud.InstitucionID); err != nil {
+			return nil, errors.New("la institución de la solicitud ya no existe")
+		}
+	}
+
+	ahora := time.Now()
+	solicitud.Estado = models.EstadoSolicitudAprobada
+	solicitud.RevisadoPorID = &revisorID
+	solicitud.FechaRevision = &ahora
+	programa := &models.ProgramaVisita{Fecha: fecha, Fechafin: fechafin}
+	if err := s.solicitudRepo.AprobarSolicitud(solicitud, institucion, programa); err != nil {
+		if errors.Is(err, repositories.ErrSolicitudNoPendiente) {
+			return nil, ErrSolicitudYaRevisada
+		}
+		return nil, err
+	}
+
+	s.emailService.EnviarEnSegundoPlano(ctx, destinatarioSolicitud(solicitud, institucion), "Solicitud de visita aprobada",
+		fmt.Sprintf(`<p>Hola %s,</p><p>Su solicitud de visita a la UTEQ (código %s) fue <strong>aprobada</strong>.</p><p>Fecha: %s - %s</p><p>Número de estudiantes: %d</p>`,
+			html.EscapeString(solicitud.NombreContacto), solicitud.CodigoSeguimiento,
+			fecha.Format("02/01/2006 15:04"), fechafin.Format("15:04"), solicitud.NumeroEstudiantes))
+
+	return s.solicitudRepo.GetSolicitudByID(id)
+}
+
+// Rechazar marca una solicitud pendiente como rechazada y notifica al contacto
+func (s *SolicitudVisitaService) Rechazar(ctx context.Context, id, revisorID uint, motivo string) (*models.SolicitudVisita, error) {
+	motivo = strings.TrimSpace(motivo)
+	if motivo == "" {
+		return nil, errors.New("el motivo de rechazo es requerido")
+	}
+	if err := s.VerificarRevisor(revisorID); err != nil {
+		return nil, err
+	}
+	solicitud, err := s.solicitudRepo.GetSolicitudByID(id)
+	if err != nil {
+		return nil, ErrSolicitudNoEncontrada
+	}
+	if solicitud.Estado != models.EstadoSolicitudPendiente {
+		return nil, ErrSolicitudYaRevisada
+	}
+
+	ahora := time.Now()
+	solicitud.Estado = models.EstadoSolicitudRechazada
+	solicitud.MotivoRechazo = motivo
+	solicitud.RevisadoPorID = &revisorID
+	solicitud.FechaRevision = &ahora
+	if err := s.solicitudRepo.RechazarSolicitud(solicitud); err != nil {
+		if errors.Is(err, repositories.ErrSolicitudNoPendiente) {
+			return nil, ErrSolicitudYaRevisada
+		}
+		return nil, err
+	}
+
+	s.emailService.EnviarEnSegundoPlano(ctx, destinatarioSolicitud(solicitud, solicitud.Institucion), "Solicitud de visita no aprobada",
+		fmt.Sprintf(`<p>Hola %s,</p><p>Su solicitud de visita a la UTEQ (código %s) no pudo ser aprobada.</p><p>Motivo: %s</p>`,
+			html.EscapeString(solicitud.NombreContacto), solicitud.CodigoSeguimiento, html.EscapeString(motivo)))
+
+	return solicitud, nil
+}
+
+// VerificarRevisor comprueba que el usuario sea personal de la UTEQ: administrador o autoridad.
+// Solo el personal puede consultar las solicitudes, que incluyen los datos de contacto, y revisarlas.
+func (s *SolicitudVisitaService) VerificarRevisor(usuarioID uint) error {
+	if !esPersonalUTEQ(s.usuarioRepo, s.autoridadRepo, usuarioID) {
+		return ErrRevisionNoPermitida
+	}
+	return nil
+}
+
+// esPersonalUTEQ indica si el usuario es administrador o está registrado como autoridad UTEQ
+func esPersonalUTEQ(usuarioRepo *repositories.UsuarioRepository, autoridadRepo *repositories.AutoridadUTEQRepository, usuarioID uint) bool {
+	usuario, err := usuarioRepo.GetUsuarioByID(usuarioID)
+	if err != nil {
+		return false
+	}
+	if usuario.TipoUsuario.EsAdministrador() {
+		return true
+	}
+	_, err = autoridadRepo.GetAutoridadUTEQByPersona(usuario.PersonaID)
+	return err == nil
+}
+
+// destinatarioSolicitud usa el contacto registrado de la institución cuando es un correo válido;
+// si no, el correo que dejó quien llenó el formulario
+func destinatarioSolicitud(solicitud *models.SolicitudVisita, institucion *models.Institucion) string {
+	if institucion != nil && correoValido(strings.TrimSpace(institucion.Contacto)) {
+		return strings.TrimSpace(institucion.Contacto)
+	}
+	return solicitud.CorreoContacto
+}
+
+func correoValido(correo string) bool {
+	direccion, err := mail.ParseAddress(correo)
+	return err == nil && direccion.Address == correo
+}
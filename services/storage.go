@@ -0,0 +1,100 @@
+package services
+
+import (
+	"ApiEscuela/config"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"time"
+)
+
+var (
+	// ErrObjetoNoEncontrado indica que la clave no existe en el almacenamiento
+	ErrObjetoNoEncontrado = errors.New("objeto no encontrado en el almacenamiento")
+	// ErrObjetoExistente indica que Guardar no escribió porque la clave ya existe
+	ErrObjetoExistente = errors.New("el objeto ya existe en el almacenamiento")
+	errClaveInvalida   = errors.New("clave de almacenamiento inválida")
+)
+
+// CarpetasArchivos son las carpetas donde UploadHandler guarda los archivos subidos
+var CarpetasArchivos = []string{"images", "videos", "documents"}
+
+// Storage guarda los archivos subidos. Las claves tienen la forma carpeta/nombre (por ejemplo
+// images/1700000000_ab12.jpg) y son las mismas en todos los backends.
+type Storage interface {
+	// Guardar escribe el contenido completo de una clave nueva; tamano es la longitud exacta del contenido.
+	// Nunca reemplaza un objeto: si la clave ya existe devuelve ErrObjetoExistente.
+	Guardar(clave string, contenido io.Reader, tamano int64, contentType string) error
+	// Abrir lee longitud bytes desde la posición desde (longitud < 0 lee hasta el final)
+	Abrir(clave string, desde, longitud int64) (io.ReadCloser, error)
+	// Info devuelve el tamaño y la fecha de modificación, o ErrObjetoNoEncontrado
+	Info(clave string) (*InfoObjeto, error)
+	// Eliminar borra la clave; no falla si ya no existe
+	Eliminar(clave string) error
+	// Listar devuelve los objetos cuya clave está dentro de la carpeta, incluidas las subcarpetas
+	Listar(carpeta string) ([]ObjetoAlmacenado, error)
+}
+
+// InfoObjeto describe un archivo guardado
+type InfoObjeto struct {
+	Tamano       int64
+	ModificadoEn time.Time
+}
+
+// ObjetoAlmacenado es un elemento del listado de una carpeta
+type ObjetoAlmacenado struct {
+	Clave string
+	InfoObjeto
+}
+
+// MoverObjeto copia la clave a destino y luego elimina el original. Sirve para todos los backends,
+// aunque en S3 implica descargar y volver a subir el contenido. Si el destino ya existe con el mismo
+// tamaño se asume que una llamada anterior se interrumpió antes de eliminar el original.
+func MoverObjeto(storage Storage, origen, destino string) error {
+	info, err := storage.Info(origen)
+	if err != nil {
+		return err
+	}
+	contenido, err := storage.Abrir(origen, 0, -1)
+	if err != nil {
+		return err
+	}
+	err = storage.Guardar(destino, contenido, info.Tamano, "")
+	contenido.Close()
+	if errors.Is(err, ErrObjetoExistente) {
+		if existente, errInfo := storage.Info(destino); errInfo == nil && existente.Tamano == info.Tamano {
+			err = nil
+		}
+	}
+	if err != nil {
+		return err
+	}
+	return storage.Eliminar(origen)
+}
+
+// ClaveArchivo arma la clave de almacenamiento de un archivo subido
+func ClaveArchivo(carpeta, nombre string) string {
+	return path.Join(carpeta, nombre)
+}
+
+// NewStorage crea el backend indicado por ALMACENAMIENTO: "local" (en ALMACENAMIENTO_DIRECTORIO) o "s3"
+// (compatible con MinIO, configurado con las variables S3_*)
+func NewStorage(cfg config.Almacenamiento) (Storage, error) {
+	switch cfg.Backend {
+	case "local":
+		return NewStorageLocal(cfg.Directorio), nil
+	case "s3":
+		return NewStorageS3(ConfigS3{
+			Endpoint:  cfg.S3.Endpoint,
+			Region:    cfg.S3.Region,
+			Bucket:    cfg.S3.Bucket,
+			AccessKey: cfg.S3.AccessKey,
+			SecretKey: cfg.S3.SecretKey,
+			Prefijo:   cfg.S3.Prefijo,
+			PathStyle: cfg.S3.PathStyle,
+		})
+	default:
+		return nil, fmt.Errorf("ALMACENAMIENTO desconocido: %q (use local o s3)", cfg.Backend)
+	}
+}
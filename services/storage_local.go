@@ -0,0 +1,153 @@
+package services
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// StorageLocal guarda los archivos en un directorio del disco. No sigue enlaces simbólicos: un archivo
+// o carpeta enlazados se tratan como inexistentes.
+type StorageLocal struct {
+	raiz string
+}
+
+func NewStorageLocal(raiz string) *StorageLocal {
+	return &StorageLocal{raiz: raiz}
+}
+
+// Raiz es el directorio donde se guardan los archivos
+func (s *StorageLocal) Raiz() string {
+	return s.raiz
+}
+
+// Guardar crea el archivo sin sobrescribir uno existente
+func (s *StorageLocal) Guardar(clave string, contenido io.Reader, tamano int64, contentType string) error {
+	if !filepath.IsLocal(clave) {
+		return errClaveInvalida
+	}
+	ruta := filepath.Join(s.raiz, filepath.FromSlash(clave))
+	if err := os.MkdirAll(filepath.Dir(ruta), 0755); err != nil {
+		return err
+	}
+	destino, err := os.OpenFile(ruta, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if errors.Is(err, os.ErrExist) {
+		return ErrObjetoExistente
+	}
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(destino, contenido)
+	if cerrarErr := destino.Close(); err == nil {
+		err = cerrarErr
+	}
+	if err != nil {
+		os.Remove(ruta)
+	}
+	return err
+}
+
+func (s *StorageLocal) Abrir(clave string, desde, longitud int64) (io.ReadCloser, error) {
+	ruta, _, err := s.resolver(clave)
+	if err != nil {
+		return nil, err
+	}
+	archivo, err := os.Open(ruta)
+	if err != nil {
+		return nil, ErrObjetoNoEncontrado
+	}
+	if _, err := archivo.Seek(desde, io.SeekStart); err != nil {
+		archivo.Close()
+		return nil, err
+	}
+	if longitud < 0 {
+		return archivo, nil
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{io.LimitReader(archivo, longitud), archivo}, nil
+}
+
+func (s *StorageLocal) Info(clave string) (*InfoObjeto, error) {
+	_, info, err := s.resolver(clave)
+	if err != nil {
+		return nil, err
+	}
+	return &InfoObjeto{Tamano: info.Size(), ModificadoEn: info.ModTime()}, nil
+}
+
+func (s *StorageLocal) Eliminar(clave string) error {
+	ruta, _, err := s.resolver(clave)
+	if errors.Is(err, ErrObjetoNoEncontrado) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(ruta); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// Listar recorre la carpeta sin seguir enlaces simbólicos; si la carpeta no existe devuelve una lista vacía
+func (s *StorageLocal) Listar(carpeta string) ([]ObjetoAlmacenado, error) {
+	if !filepath.IsLocal(carpeta) {
+		return nil, errClaveInvalida
+	}
+	var objetos []ObjetoAlmacenado
+	err := filepath.WalkDir(filepath.Join(s.raiz, filepath.FromSlash(carpeta)), func(ruta string, entrada fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if !entrada.Type().IsRegular() {
+			return nil
+		}
+		info, err := entrada.Info()
+		if err != nil {
+			return err
+		}
+		relativa, err := filepath.Rel(s.raiz, ruta)
+		if err != nil {
+			return err
+		}
+		objetos = append(objetos, ObjetoAlmacenado{
+			Clave:      filepath.ToSlash(relativa),
+			InfoObjeto: InfoObjeto{Tamano: info.Size(), ModificadoEn: info.ModTime()},
+		})
+		return nil
+	})
+	return objetos, err
+}
+
+// resolver devuelve la ruta real del archivo de la clave dentro de la raíz. Rechaza las claves que salen
+// de la raíz y cualquier enlace simbólico, tanto en las carpetas como en el archivo.
+func (s *StorageLocal) resolver(clave string) (string, os.FileInfo, error) {
+	if !filepath.IsLocal(clave) {
+		return "", nil, ErrObjetoNoEncontrado
+	}
+	raizReal, err := filepath.EvalSymlinks(s.raiz)
+	if err != nil {
+		return "", nil, ErrObjetoNoEncontrado
+	}
+	if raizReal, err = filepath.Abs(raizReal); err != nil {
+		return "", nil, ErrObjetoNoEncontrado
+	}
+	esperada := filepath.Join(raizReal, filepath.FromSlash(clave))
+	real, err := filepath.EvalSymlinks(esperada)
+	if err != nil || real != esperada {
+		return "", nil, ErrObjetoNoEncontrado
+	}
+
+	info, err := os.Lstat(real)
+	if err != nil || !info.Mode().IsRegular() {
+		return "", nil, ErrObjetoNoEncontrado
+	}
+	return real, info, nil
+}
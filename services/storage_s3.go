@@ -0,0 +1,348 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// cargaSinFirmar evita leer dos veces el archivo para calcular su hash antes de enviarlo
+	cargaSinFirmar = "UNSIGNED-PAYLOAD"
+	// hashCargaVacia es el SHA-256 de un cuerpo vacío
+	hashCargaVacia = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+	formatoFechaS3 = "20060102T150405Z"
+
+	// plazoControlS3 limita las peticiones que no transfieren contenido (HEAD, DELETE y listados). Las
+	// subidas y descargas no tienen un plazo total, porque un video grande en una conexión lenta puede
+	// tardar más; solo se limita la espera de la respuesta.
+	plazoControlS3 = 30 * time.Second
+	// esperaRespuestaS3 es lo máximo que se espera el encabezado de la respuesta una vez enviada la petición
+	esperaRespuestaS3 = time.Minute
+)
+
+// ConfigS3 configura un almacenamiento compatible con S3 (AWS, MinIO, etc.)
+type ConfigS3 struct {
+	Endpoint  string // por ejemplo https://s3.us-east-1.amazonaws.com o http://minio:9000
+	Region    string // us-east-1 si se omite
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	Prefijo   string // se antepone a todas las claves, por ejemplo "apiescuela/"
+	PathStyle bool   // endpoint/bucket/clave en lugar de bucket.endpoint/clave
+}
+
+// StorageS3 guarda los archivos en un bucket compatible con S3, firmando las peticiones con AWS Signature V4
+type StorageS3 struct {
+	config   ConfigS3
+	endpoint *url.URL
+	cliente  *http.Client
+	ahora    func() time.Time
+}
+
+func NewStorageS3(config ConfigS3) (*StorageS3, error) {
+	if config.Endpoint == "" || config.Bucket == "" || config.AccessKey == "" || config.SecretKey == "" {
+		return nil, errors.New("el almacenamiento S3 requiere S3_ENDPOINT, S3_BUCKET, S3_ACCESS_KEY y S3_SECRET_KEY")
+	}
+	endpoint, err := url.Parse(strings.TrimRight(config.Endpoint, "/"))
+	if err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Host == "" {
+		return nil, fmt.Errorf("S3_ENDPOINT no es una URL válida: %q", config.Endpoint)
+	}
+	if config.Region == "" {
+		config.Region = "us-east-1"
+	}
+	transporte := http.DefaultTransport.(*http.Transport).Clone()
+	transporte.ResponseHeaderTimeout = esperaRespuestaS3
+	return &StorageS3{
+		config:   config,
+		endpoint: endpoint,
+		cliente:  &http.Client{Transport: transporte},
+		ahora:    time.Now,
+	}, nil
+}
+
+// Guardar usa una escritura condicional (If-None-Match: *) para no reemplazar un objeto existente
+func (s *StorageS3) Guardar(clave string, contenido io.Reader, tamano int64, contentType string) error {
+	req, err := s.peticion(context.Background(), http.MethodPut, clave, contenido)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = tamano
+	if tamano == 0 {
+		req.Body = http.NoBody
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	req.Header.Set("If-None-Match", "*")
+	res, err := s.enviar(req, cargaSinFirmar)
+	if err != nil {
+		return err
+	}
+	res.Body.Close()
+	return nil
+}
+
+func (s *StorageS3) Abrir(clave string, desde, longitud int64) (io.ReadCloser, error) {
+	if longitud == 0 {
+		return io.NopCloser(strings.NewReader("")), nil
+	}
+	req, err := s.peticion(context.Background(), http.MethodGet, clave, nil)
+	if err != nil {
+		return nil, err
+	}
+	if longitud > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", desde, desde+longitud-1))
+	} else if desde > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", desde))
+	}
+	res, err := s.enviar(req, hashCargaVacia)
+	if err != nil {
+		return nil, err
+	}
+	return res.Body, nil
+}
+
+func (s *StorageS3) Info(clave string) (*InfoObjeto, error) {
+	ctx, cancelar := context.WithTimeout(context.Background(), plazoControlS3)
+	defer cancelar()
+	req, err := s.peticion(ctx, http.MethodHead, clave, nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := s.enviar(req, hashCargaVacia)
+	if err != nil {
+		return nil, err
+	}
+	res.Body.Close()
+	modificado, _ := http.ParseTime(res.Header.Get("Last-Modified"))
+	return &InfoObjeto{Tamano: res.ContentLength, ModificadoEn: modificado}, nil
+}
+
+func (s *StorageS3) Eliminar(clave string) error {
+	ctx, cancelar := context.WithTimeout(context.Background(), plazoControlS3)
+	defer cancelar()
+	req, err := s.peticion(ctx, http.MethodDelete, clave, nil)
+	if err != nil {
+		return err
+	}
+	res, err := s.enviar(req, hashCargaVacia)
+	if errors.Is(err, ErrObjetoNoEncontrado) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	res.Body.Close()
+	return nil
+}
+
+// listadoS3 es la respuesta de ListObjectsV2
+type listadoS3 struct {
+	Contenidos []struct {
+		Clave        string    `xml:"Key"`
+		Tamano       int64     `xml:"Size"`
+		ModificadoEn time.Time `xml:"LastModified"`
+	} `xml:"Contents"`
+	Truncado       bool   `xml:"IsTruncated"`
+	SiguienteToken string `xml:"NextContinuationToken"`
+}
+
+// Listar pagina ListObjectsV2 con el prefijo de la carpeta
+func (s *StorageS3) Listar(carpeta string) ([]ObjetoAlmacenado, error) {
+	if carpeta == "" || path.Clean(carpeta) != carpeta || strings.HasPrefix(carpeta, "/") || strings.HasPrefix(carpeta, "..") {
+		return nil, errClaveInvalida
+	}
+	var objetos []ObjetoAlmacenado
+	token := ""
+	for {
+		listado, err := s.paginaListado(carpeta, token)
+		if err != nil {
+			return nil, err
+		}
+		for _, contenido := range listado.Contenidos {
+			objetos = append(objetos, ObjetoAlmacenado{
+				Clave:      strings.TrimPrefix(contenido.Clave, s.config.Prefijo),
+				InfoObjeto: InfoObjeto{Tamano: contenido.Tamano, ModificadoEn: contenido.ModificadoEn},
+			})
+		}
+		if !listado.Truncado || listado.SiguienteToken == "" {
+			return objetos, nil
+		}
+		token = listado.SiguienteToken
+	}
+}
+
+// paginaListado pide una página de ListObjectsV2; cada página tiene su propio plazo
+func (s *StorageS3) paginaListado(carpeta, token string) (*listadoS3, error) {
+	consulta := url.Values{"list-type": {"2"}, "prefix": {s.config.Prefijo + carpeta + "/"}}
+	if token != "" {
+		consulta.Set("continuation-token", token)
+	}
+	u := *s.endpoint
+	if s.config.PathStyle {
+		u.Path = strings.TrimRight(u.Path, "/") + "/" + s.config.Bucket
+	} else {
+		u.Host = s.config.Bucket + "." + u.Host
+		u.Path = strings.TrimRight(u.Path, "/") + "/"
+	}
+	u.RawPath = codificarRutaS3(u.Path)
+	u.RawQuery = strings.ReplaceAll(consulta.Encode(), "+", "%20")
+	ctx, cancelar := context.WithTimeout(context.Background(), plazoControlS3)
+	defer cancelar()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := s.enviar(req, hashCargaVacia)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	var listado listadoS3
+	if err := xml.NewDecoder(res.Body).Decode(&listado); err != nil {
+		return nil, fmt.Errorf("s3: respuesta de listado inválida: %w", err)
+	}
+	return &listado, nil
+}
+
+// peticion arma la URL del objeto según el estilo de direccionamiento configurado
+func (s *StorageS3) peticion(ctx context.Context, metodo, clave string, cuerpo io.Reader) (*http.Request, error) {
+	if clave == "" || path.Clean(clave) != clave || strings.HasPrefix(clave, "/") || strings.HasPrefix(clave, "..") {
+		return nil, errClaveInvalida
+	}
+	u := *s.endpoint
+	objeto := s.config.Prefijo + clave
+	if s.config.PathStyle {
+		u.Path = strings.TrimRight(u.Path, "/") + "/" + s.config.Bucket + "/" + objeto
+	} else {
+		u.Host = s.config.Bucket + "." + u.Host
+		u.Path = strings.TrimRight(u.Path, "/") + "/" + objeto
+	}
+	u.RawPath = codificarRutaS3(u.Path)
+	return http.NewRequestWithContext(ctx, metodo, u.String(), cuerpo)
+}
+
+// enviar firma y ejecuta la petición. Devuelve ErrObjetoNoEncontrado ante un 404, ErrObjetoExistente
+// cuando falla la condición de una escritura y un error con el código de S3 ante cualquier otra
+// respuesta que no sea 2xx.
+func (s *StorageS3) enviar(req *http.Request, hashCarga string) (*http.Response, error) {
+	firmarPeticionS3(req, hashCarga, s.config.Region, s.config.AccessKey, s.config.SecretKey, s.ahora())
+	res, err := s.cliente.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode >= 200 && res.StatusCode < 300 {
+		return res, nil
+	}
+	defer res.Body.Close()
+	if res.StatusCode == http.StatusNotFound {
+		return nil, ErrObjetoNoEncontrado
+	}
+	if res.StatusCode == http.StatusPreconditionFailed && req.Method == http.MethodPut {
+		return nil, ErrObjetoExistente
+	}
+	detalle, _ := io.ReadAll(io.LimitReader(res.Body, 512))
+	return nil, fmt.Errorf("s3: %s %s respondió %s: %s", req.Method, req.URL.Path, res.Status, strings.TrimSpace(string(detalle)))
+}
+
+// firmarPeticionS3 agrega los encabezados de AWS Signature V4 para el servicio s3. Se firman host, los
+// encabezados x-amz-*, Range y Content-Type.
+func firmarPeticionS3(req *http.Request, hashCarga, region, accessKey, secretKey string, ahora time.Time) {
+	fecha := ahora.UTC().Format(formatoFechaS3)
+	dia := fecha[:8]
+	req.Header.Set("X-Amz-Date", fecha)
+	req.Header.Set("X-Amz-Content-Sha256", hashCarga)
+
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	encabezados := map[string]string{"host": host}
+	for nombre, valores := range req.Header {
+		nombre = strings.ToLower(nombre)
+		if strings.HasPrefix(nombre, "x-amz-") || nombre == "range" || nombre == "content-type" {
+			encabezados[nombre] = strings.Join(strings.Fields(strings.Join(valores, ",")), " ")
+		}
+	}
+	nombres := make([]string, 0, len(encabezados))
+	for nombre := range encabezados {
+		nombres = append(nombres, nombre)
+	}
+	sort.Strings(nombres)
+	var canonicos strings.Builder
+	for _, nombre := range nombres {
+		canonicos.WriteString(nombre + ":" + encabezados[nombre] + "\n")
+	}
+	firmados := strings.Join(nombres, ";")
+
+	solicitudCanonica := strings.Join([]string{
+		req.Method,
+		codificarRutaS3(req.URL.Path),
+		consultaCanonicaS3(req.URL.Query()),
+		canonicos.String(),
+		firmados,
+		hashCarga,
+	}, "\n")
+	ambito := dia + "/" + region + "/s3/aws4_request"
+	hashSolicitud := sha256.Sum256([]byte(solicitudCanonica))
+	textoAFirmar := "AWS4-HMAC-SHA256\n" + fecha + "\n" + ambito + "\n" + hex.EncodeToString(hashSolicitud[:])
+
+	clave := hmacSHA256([]byte("AWS4"+secretKey), dia)
+	clave = hmacSHA256(clave, region)
+	clave = hmacSHA256(clave, "s3")
+	clave = hmacSHA256(clave, "aws4_request")
+	firma := hex.EncodeToString(hmacSHA256(clave, textoAFirmar))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s", accessKey, ambito, firmados, firma))
+}
+
+func hmacSHA256(clave []byte, texto string) []byte {
+	mac := hmac.New(sha256.New, clave)
+	mac.Write([]byte(texto))
+	return mac.Sum(nil)
+}
+
+// codificarRutaS3 codifica cada segmento de la ruta como exige SigV4: todo salvo A-Z a-z 0-9 - _ . ~
+func codificarRutaS3(ruta string) string {
+	segmentos := strings.Split(ruta, "/")
+	for i, segmento := range segmentos {
+		segmentos[i] = codificarS3(segmento)
+	}
+	return strings.Join(segmentos, "/")
+}
+
+func consultaCanonicaS3(valores url.Values) string {
+	pares := make([]string, 0, len(valores))
+	for nombre, lista := range valores {
+		for _, valor := range lista {
+			pares = append(pares, codificarS3(nombre)+"="+codificarS3(valor))
+		}
+	}
+	sort.Strings(pares)
+	return strings.Join(pares, "&")
+}
+
+func codificarS3(texto string) string {
+	var b strings.Builder
+	for _, c := range []byte(texto) {
+		if ('A' <= c && c <= 'Z') || ('a' <= c && c <= 'z') || ('0' <= c && c <= '9') || c == '-' || c == '_' || c == '.' || c == '~' {
+			b.WriteByte(c)
+		} else {
+			b.WriteString("%" + strings.ToUpper(strconv.FormatInt(int64(c), 16)))
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,359 @@
+package services
+
+import (
+	"ApiEscuela/config"
+	"ApiEscuela/models"
+	"ApiEscuela/repositories"
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// AlgoritmosChecksum son los algoritmos aceptados en la cabecera Upload-Checksum
+const AlgoritmosChecksum = "sha1,sha256,md5"
+
+var (
+	ErrSubidaNoEncontrada     = errors.New("subida no encontrada")
+	ErrDesplazamientoInvalido = errors.New("el desplazamiento no coincide con los bytes recibidos")
+	ErrParteExcedeTamano      = errors.New("la parte supera el tamaño declarado de la subida")
+	ErrAlgoritmoChecksum      = errors.New("algoritmo de checksum no soportado")
+	ErrChecksumNoCoincide     = errors.New("el checksum de la parte no coincide")
+	ErrHashSubidaNoCoincide   = errors.New("el SHA-256 del archivo completo no coincide con el declarado")
+)
+
+// SubidaService gestiona las subidas reanudables: recibe el archivo por partes, las guarda en el
+// almacenamiento y, con la última, arma el archivo y lo registra con ArchivoService.
+type SubidaService struct {
+	subidaRepo     *repositories.SubidaRepository
+	archivoService *ArchivoService
+	storage        Storage
+	archivos       config.Archivos // Límites por categoría y vencimiento de las subidas
+	intervalo      time.Duration   // Periodo de la limpieza de subidas vencidas
+}
+
+func NewSubidaService(subidaRepo *repositories.SubidaRepository, archivoService *ArchivoService, storage Storage, archivos config.Archivos, intervalo time.Duration) *SubidaService {
+	return &SubidaService{subidaRepo: subidaRepo, archivoService: archivoService, storage: storage, archivos: archivos, intervalo: intervalo}
+}
+
+// ConContexto devuelve una copia del servicio cuyos repositorios usan ctx, para que las consultas queden
+// dentro de la traza de la petición
+func (s *SubidaService) ConContexto(ctx context.Context) *SubidaService {
+	copia := *s
+	copia.subidaRepo = s.subidaRepo.ConContexto(ctx)
+	copia.archivoService = s.archivoService.ConContexto(ctx)
+	return &copia
+}
+
+// CrearSubidaRequest son los datos con los que se inicia una subida reanudable
+type CrearSubidaRequest struct {
+	NombreArchivo string
+	Tamano        int64
+	Visibilidad   string
+	SHA256        string // Opcional: hash esperado del archivo completo, en hexadecimal
+}
+
+// Crear inicia una subida. La extensión y el tamaño se validan de entrada para no recibir partes de
+// un archivo que después se rechazaría; el contenido se inspecciona al completarla.
+func (s *SubidaService) Crear(usuarioID uint, req CrearSubidaRequest) (*models.SubidaReanudable, error) {
+	nombre := filepath.Base(strings.TrimSpace(req.NombreArchivo))
+	categoria := CategoriaPorExtension(nombre)
+	if categoria == "" {
+		return nil, ErrTipoArchivoNoPermitido
+	}
+	if req.Tamano <= 0 {
+		return nil, errors.New("el tamaño de la subida debe ser mayor que cero")
+	}
+	if limite := LimiteSubida(s.archivos, categoria); req.Tamano > limite {
+		return nil, fmt.Errorf("%w (máximo %d MB para %s)", ErrArchivoDemasiadoGrande, limite/(1024*1024), carpetaCategoria(categoria))
+	}
+	visibilidad, err := validarVisibilidadArchivo(req.Visibilidad)
+	if err != nil {
+		return nil, err
+	}
+	hashEsperado := strings.ToLower(strings.TrimSpace(req.SHA256))
+	if hashEsperado != "" {
+		if decodificado, err := hex.DecodeString(hashEsperado); err != nil || len(decodificado) != sha256.Size {
+			return nil, errors.New("el sha256 declarado no es válido")
+		}
+	}
+
+	identificador, err := generarTokenHex(16)
+	if err != nil {
+		return nil, err
+	}
+	subida := &models.SubidaReanudable{
+		Identificador: identificador,
+		UsuarioID:     usuarioID,
+		NombreArchivo: nombre,
+		Visibilidad:   visibilidad,
+		Tamano:        req.Tamano,
+		SHA256:        hashEsperado,
+		ExpiraEn:      time.Now().Add(s.archivos.ExpiracionSubidas),
+	}
+	if err := s.subidaRepo.CreateSubida(subida); err != nil {
+		return nil, err
+	}
+	return subida, nil
+}
+
+// Obtener devuelve una subida del usuario. Las vencidas que no se completaron se consideran inexistentes.
+func (s *SubidaService) Obtener(identificador string, usuarioID uint) (*models.SubidaReanudable, error) {
+	subida, err := s.subidaRepo.GetSubidaByIdentificador(identificador)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrSubidaNoEncontrada
+		}
+		return nil, err
+	}
+	if subida.UsuarioID != usuarioID || (subida.ArchivoID == nil && time.Now().After(subida.ExpiraEn)) {
+		return nil, ErrSubidaNoEncontrada
+	}
+	return subida, nil
+}
+
+// AgregarParte guarda una parte que empieza en desplazamiento y extiende la expiración de la subida.
+// checksum es el valor de Upload-Checksum ("<algoritmo> <base64>"), opcional. Con la última parte se
+// arma el archivo y se registra; en ese caso también se devuelve el archivo creado.
+func (s *SubidaService) AgregarParte(identificador string, usuarioID uint, desplazamiento int64, contenido []byte, checksum string) (*models.SubidaReanudable, *models.Archivo, error) {
+	subida, err := s.Obtener(identificador, usuarioID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if subida.ArchivoID != nil || desplazamiento != subida.Desplazamiento {
+		return nil, nil, ErrDesplazamientoInvalido
+	}
+	tamano := int64(len(contenido))
+	if desplazamiento+tamano > subida.Tamano {
+		return nil, nil, ErrParteExcedeTamano
+	}
+	if checksum != "" {
+		if err := verificarChecksum(contenido, checksum); err != nil {
+			return nil, nil, err
+		}
+	}
+	if tamano == 0 {
+		return subida, nil, nil
+	}
+
+	// El sufijo aleatorio evita que dos peticiones simultáneas con el mismo desplazamiento pisen la
+	// parte de la otra; solo una de ellas logra avanzar la subida
+	sufijo, err := generarTokenHex(4)
+	if err != nil {
+		return nil, nil, err
+	}
+	parte := &models.ParteSubida{
+		SubidaID:       subida.ID,
+		Desplazamiento: desplazamiento,
+		Tamano:         tamano,
+		Clave:          path.Join("subidas", subida.Identificador, fmt.Sprintf("%d_%s", desplazamiento, sufijo)),
+	}
+	if err := s.storage.Guardar(parte.Clave, bytes.NewReader(contenido), tamano, "application/octet-stream"); err != nil {
+		return nil, nil, err
+	}
+	expiraEn := time.Now().Add(s.archivos.ExpiracionSubidas)
+	if err := s.subidaRepo.AgregarParte(subida, parte, expiraEn); err != nil {
+		s.storage.Eliminar(parte.Clave)
+		if errors.Is(err, repositories.ErrDesplazamientoCambiado) {
+			return nil, nil, ErrDesplazamientoInvalido
+		}
+		return nil, nil, err
+	}
+	subida.Desplazamiento += tamano
+	subida.ExpiraEn = expiraEn
+	subida.Partes = append(subida.Partes, *parte)
+
+	if subida.Desplazamiento < subida.Tamano {
+		return subida, nil, nil
+	}
+	archivo, err := s.completar(subida)
+	if err != nil {
+		return nil, nil, err
+	}
+	return subida, archivo, nil
+}
+
+// Cancelar elimina una subida y sus partes. Si ya se completó, el archivo registrado se conserva.
+func (s *SubidaService) Cancelar(identificador string, usuarioID uint) error {
+	subida, err := s.Obtener(identificador, usuarioID)
+	if err != nil {
+		return err
+	}
+	return s.descartar(subida)
+}
+
+// LimpiarExpiradas elimina las subidas vencidas y sus partes; devuelve cuántas se eliminaron
+func (s *SubidaService) LimpiarExpiradas(ahora time.Time) (int, error) {
+	subidas, err := s.subidaRepo.GetSubidasExpiradas(ahora)
+	if err != nil {
+		return 0, err
+	}
+	eliminadas := 0
+	for i := range subidas {
+		if err := s.descartar(&subidas[i]); err != nil {
+			return eliminadas, err
+		}
+		eliminadas++
+	}
+	return eliminadas, nil
+}
+
+// IniciarLimpieza elimina periódicamente las subidas vencidas en segundo plano
+// (SUBIDAS_LIMPIEZA_INTERVALO, por defecto 1h). Devuelve una función que detiene la limpieza
+// cuando termina de borrar las partes de la pasada actual.
+func (s *SubidaService) IniciarLimpieza() func() {
+	detener := make(chan struct{})
+	terminado := make(chan struct{})
+	go func() {
+		defer close(terminado)
+		ticker := time.NewTicker(s.intervalo)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				eliminadas, err := s.LimpiarExpiradas(time.Now())
+				if err != nil {
+					slog.Error("error al limpiar subidas vencidas", "error", err)
+				} else if eliminadas > 0 {
+					slog.Info("subidas vencidas eliminadas", "cantidad", eliminadas)
+				}
+			case <-detener:
+				return
+			}
+		}
+	}()
+	return func() {
+		close(detener)
+		<-terminado
+	}
+}
+
+// TamanoMaximoSubida es el mayor tamaño que acepta alguna categoría
+func (s *SubidaService) TamanoMaximoSubida() int64 {
+	var maximo int64
+	for _, categoria := range categoriasArchivo {
+		maximo = max(maximo, LimiteSubida(s.archivos, categoria))
+	}
+	return maximo
+}
+
+// completar une las partes en un archivo temporal, comprueba el SHA-256 declarado y registra el
+// archivo como una subida normal. Si algo falla, la subida se descarta.
+func (s *SubidaService) completar(subida *models.SubidaReanudable) (*models.Archivo, error) {
+	temporal, err := os.CreateTemp("", "subida-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(temporal.Name())
+
+	hash := sha256.New()
+	err = s.unirPartes(subida, io.MultiWriter(temporal, hash))
+	if cerrarErr := temporal.Close(); err == nil {
+		err = cerrarErr
+	}
+	if err == nil && subida.SHA256 != "" && hex.EncodeToString(hash.Sum(nil)) != subida.SHA256 {
+		err = ErrHashSubidaNoCoincide
+	}
+	var archivo *models.Archivo
+	if err == nil {
+		var entrante ArchivoEntrante
+		if entrante, err = ArchivoEnDisco(temporal.Name(), subida.NombreArchivo); err == nil {
+			archivo, err = s.archivoService.GuardarArchivo(subida.UsuarioID, entrante, subida.Visibilidad)
+		}
+	}
+	if err != nil {
+		s.descartar(subida)
+		return nil, err
+	}
+
+	s.eliminarPartes(subida)
+	if err := s.subidaRepo.CompletarSubida(subida.ID, archivo.ID); err != nil {
+		return nil, err
+	}
+	subida.ArchivoID = &archivo.ID
+	subida.Partes = nil
+	return archivo, nil
+}
+
+func (s *SubidaService) unirPartes(subida *models.SubidaReanudable, destino io.Writer) error {
+	var esperado int64
+	for _, parte := range subida.Partes {
+		if parte.Desplazamiento != esperado {
+			return fmt.Errorf("falta la parte que empieza en el byte %d", esperado)
+		}
+		contenido, err := s.storage.Abrir(parte.Clave, 0, -1)
+		if err != nil {
+			return err
+		}
+		copiados, err := io.Copy(destino, contenido)
+		contenido.Close()
+		if err != nil {
+			return err
+		}
+		if copiados != parte.Tamano {
+			return fmt.Errorf("la parte que empieza en el byte %d está incompleta", parte.Desplazamiento)
+		}
+		esperado += parte.Tamano
+	}
+	if esperado != subida.Tamano {
+		return fmt.Errorf("se recibieron %d de %d bytes", esperado, subida.Tamano)
+	}
+	return nil
+}
+
+func (s *SubidaService) descartar(subida *models.SubidaReanudable) error {
+	s.eliminarPartes(subida)
+	return s.subidaRepo.DeleteSubida(subida.ID)
+}
+
+// eliminarPartes borra las partes del almacenamiento; los errores se ignoran porque la limpieza de
+// subidas vencidas no las vuelve a encontrar una vez eliminado el registro
+func (s *SubidaService) eliminarPartes(subida *models.SubidaReanudable) {
+	for _, parte := range subida.Partes {
+		s.storage.Eliminar(parte.Clave)
+	}
+}
+
+// verificarChecksum compara el contenido con un valor de Upload-Checksum: "<algoritmo> <base64>"
+func verificarChecksum(contenido []byte, checksum string) error {
+	algoritmo, valor, ok := strings.Cut(strings.TrimSpace(checksum), " ")
+	if !ok {
+		return ErrAlgoritmoChecksum
+	}
+	var h hash.Hash
+	switch strings.ToLower(algoritmo) {
+	case "sha1":
+		h = sha1.New()
+	case "sha256":
+		h = sha256.New()
+	case "md5":
+		h = md5.New()
+	default:
+		return ErrAlgoritmoChecksum
+	}
+	esperado, err := base64.StdEncoding.DecodeString(strings.TrimSpace(valor))
+	if err != nil {
+		return ErrChecksumNoCoincide
+	}
+	h.Write(contenido)
+	if !bytes.Equal(h.Sum(nil), esperado) {
+		return ErrChecksumNoCoincide
+	}
+	return nil
+}
+
@@ -0,0 +1,57 @@
+package services
+
+import (
+	"ApiEscuela/models"
+	"bytes"
+	"crypto/sha1"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestVerificarChecksum(t *testing.T) {
+	contenido := []byte("parte del video")
+	suma := sha1.Sum(contenido)
+	valido := "sha1 " + base64.StdEncoding.EncodeToString(suma[:])
+
+	if err := verificarChecksum(contenido, valido); err != nil {
+		t.Errorf("checksum válido: %v", err)
+	}
+	if err := verificarChecksum([]byte("parte alterada"), valido); !errors.Is(err, ErrChecksumNoCoincide) {
+		t.Errorf("contenido alterado: %v, se esperaba ErrChecksumNoCoincide", err)
+	}
+	if err := verificarChecksum(contenido, "crc32 AAAAAA=="); !errors.Is(err, ErrAlgoritmoChecksum) {
+		t.Errorf("algoritmo desconocido: %v, se esperaba ErrAlgoritmoChecksum", err)
+	}
+}
+
+func TestUnirPartes(t *testing.T) {
+	storage := NewStorageLocal(t.TempDir())
+	servicio := &SubidaService{storage: storage}
+	subida := &models.SubidaReanudable{Identificador: "abc", Tamano: 11}
+	for _, parte := range []struct {
+		desplazamiento int64
+		contenido      string
+	}{{0, "hola "}, {5, "mundo!"}} {
+		clave := ClaveArchivo("subidas/abc", strings.TrimSpace(parte.contenido))
+		if err := storage.Guardar(clave, strings.NewReader(parte.contenido), int64(len(parte.contenido)), ""); err != nil {
+			t.Fatal(err)
+		}
+		subida.Partes = append(subida.Partes, models.ParteSubida{Desplazamiento: parte.desplazamiento, Tamano: int64(len(parte.contenido)), Clave: clave})
+	}
+
+	var unido bytes.Buffer
+	if err := servicio.unirPartes(subida, &unido); err != nil {
+		t.Fatal(err)
+	}
+	if unido.String() != "hola mundo!" {
+		t.Errorf("contenido unido %q", unido.String())
+	}
+
+	// Sin la primera parte queda un hueco y no se puede armar el archivo
+	subida.Partes = subida.Partes[1:]
+	if err := servicio.unirPartes(subida, &bytes.Buffer{}); err == nil {
+		t.Error("se esperaba un error por la parte faltante")
+	}
+}
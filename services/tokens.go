@@ -0,0 +1,15 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+)
+
+// generarTokenHex genera un token aleatorio de n bytes codificado en hexadecimal
+func generarTokenHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,76 @@
+package trazas
+
+import (
+	"ApiEscuela/logging"
+	"errors"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+const claveSpan = "trazas:span"
+
+// PluginGORM crea un span hijo por cada consulta, incluidas las de los Preload. Solo traza las
+// consultas hechas con un contexto que ya tiene un span: los handlers usan los repositorios y servicios
+// con ConContexto(c.UserContext()); las de los procesos en segundo plano se miden solo en /metrics.
+// Se instala con db.Use(trazas.PluginGORM{}).
+type PluginGORM struct{}
+
+func (PluginGORM) Name() string {
+	return "trazas"
+}
+
+// Initialize registra un callback antes y otro después de cada tipo de operación
+func (PluginGORM) Initialize(db *gorm.DB) error {
+	callbacks := db.Callback()
+	return errors.Join(
+		callbacks.Create().Before("gorm:create").Register("trazas:antes_create", iniciarSpan("create")),
+		callbacks.Create().After("gorm:create").Register("trazas:despues_create", terminarSpan),
+		callbacks.Query().Before("gorm:query").Register("trazas:antes_query", iniciarSpan("query")),
+		callbacks.Query().After("gorm:query").Register("trazas:despues_query", terminarSpan),
+		callbacks.Update().Before("gorm:update").Register("trazas:antes_update", iniciarSpan("update")),
+		callbacks.Update().After("gorm:update").Register("trazas:despues_update", terminarSpan),
+		callbacks.Delete().Before("gorm:delete").Register("trazas:antes_delete", iniciarSpan("delete")),
+		callbacks.Delete().After("gorm:delete").Register("trazas:despues_delete", terminarSpan),
+		callbacks.Row().Before("gorm:row").Register("trazas:antes_row", iniciarSpan("row")),
+		callbacks.Row().After("gorm:row").Register("trazas:despues_row", terminarSpan),
+		callbacks.Raw().Before("gorm:raw").Register("trazas:antes_raw", iniciarSpan("raw")),
+		callbacks.Raw().After("gorm:raw").Register("trazas:despues_raw", terminarSpan),
+	)
+}
+
+func iniciarSpan(operacion string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		ctx := db.Statement.Context
+		if ctx == nil || !trace.SpanFromContext(ctx).SpanContext().IsValid() {
+			return
+		}
+		_, span := Tracer().Start(ctx, "gorm."+operacion, trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(
+				attribute.String("db.system.name", "postgresql"),
+				attribute.String("db.operation.name", operacion),
+			))
+		db.InstanceSet(claveSpan, span)
+	}
+}
+
+func terminarSpan(db *gorm.DB) {
+	valor, ok := db.InstanceGet(claveSpan)
+	span, esSpan := valor.(trace.Span)
+	if !ok || !esSpan {
+		return
+	}
+	// La sentencia lleva marcadores ($1, $2...) y no los valores, así que no expone datos personales
+	span.SetAttributes(
+		attribute.String("db.collection.name", db.Statement.Table),
+		attribute.String("db.query.text", db.Statement.SQL.String()),
+		attribute.Int64("db.response.returned_rows", db.Statement.RowsAffected),
+	)
+	// Los errores de PostgreSQL pueden citar valores (la clave duplicada), por eso se ocultan
+	if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+		span.SetStatus(codes.Error, logging.OcultarTexto(db.Error.Error()))
+	}
+	span.End()
+}
@@ -0,0 +1,81 @@
+// Package trazas configura OpenTelemetry: el proveedor de trazas con su exportador, la propagación
+// W3C (traceparent) y el plugin de GORM que crea un span por consulta.
+package trazas
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentacion identifica a los spans creados por la API
+const instrumentacion = "ApiEscuela"
+
+// Configurar instala el proveedor de trazas según el exportador: "otlp" envía por OTLP/HTTP a
+// OTEL_EXPORTER_OTLP_ENDPOINT (o OTEL_EXPORTER_OTLP_TRACES_ENDPOINT), "stdout" escribe los spans en la
+// salida estándar para desarrollo y "" o "none" desactiva las trazas. El muestreo se configura con
+// OTEL_TRACES_SAMPLER y OTEL_TRACES_SAMPLER_ARG, y el nombre del servicio con OTEL_SERVICE_NAME.
+// Devuelve la función que envía los spans pendientes y cierra el exportador.
+func Configurar(ctx context.Context, exportador string) (func(context.Context) error, error) {
+	// La propagación se instala siempre, para respetar el traceparent del cliente aunque no se exporte
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var exportadorSpans sdktrace.SpanExporter
+	var err error
+	switch strings.ToLower(strings.TrimSpace(exportador)) {
+	case "", "none":
+		return func(context.Context) error { return nil }, nil
+	case "otlp":
+		exportadorSpans, err = otlptracehttp.New(ctx)
+	case "stdout", "console":
+		exportadorSpans, err = stdouttrace.New(stdouttrace.WithPrettyPrint())
+	default:
+		return nil, fmt.Errorf("exportador de trazas %q no soportado (otlp, stdout o none)", exportador)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	recurso, err := resource.New(ctx,
+		resource.WithAttributes(attribute.String("service.name", "apiescuela")),
+		resource.WithFromEnv(), // OTEL_SERVICE_NAME y OTEL_RESOURCE_ATTRIBUTES reemplazan al anterior
+		resource.WithTelemetrySDK(),
+		resource.WithHost(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	proveedor := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exportadorSpans),
+		sdktrace.WithResource(recurso),
+	)
+	otel.SetTracerProvider(proveedor)
+	return proveedor.Shutdown, nil
+}
+
+// Tracer devuelve el tracer de la API; sin Configurar, sus spans no se registran
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentacion)
+}
+
+// TraceID devuelve el identificador de la traza del contexto, o "" si no hay una
+func TraceID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	span := trace.SpanContextFromContext(ctx)
+	if !span.HasTraceID() {
+		return ""
+	}
+	return span.TraceID().String()
+}
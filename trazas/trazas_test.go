@@ -0,0 +1,71 @@
+package trazas_test
+
+import (
+	"ApiEscuela/middleware"
+	"ApiEscuela/trazas"
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestMiddlewareContinuaLaTrazaDelCliente(t *testing.T) {
+	if _, err := trazas.Configurar(context.Background(), "none"); err != nil {
+		t.Fatal(err)
+	}
+	exportador := tracetest.NewInMemoryExporter()
+	proveedor := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exportador))
+	otel.SetTracerProvider(proveedor)
+	t.Cleanup(func() { proveedor.Shutdown(context.Background()) })
+
+	const traza = "4bf92f3577b34da6a3ce929d0e0e4736"
+	var trazaEnHandler string
+	app := fiber.New()
+	app.Use(middleware.Trazas())
+	app.Get("/estudiantes/:id", func(c *fiber.Ctx) error {
+		trazaEnHandler = trazas.TraceID(c.UserContext())
+		return fiber.NewError(fiber.StatusServiceUnavailable, "sin base de datos")
+	})
+
+	req := httptest.NewRequest("GET", "/estudiantes/7", nil)
+	req.Header.Set("traceparent", "00-"+traza+"-00f067aa0ba902b7-01")
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusServiceUnavailable {
+		t.Errorf("estado %d, se esperaba 503", resp.StatusCode)
+	}
+	if encabezado := resp.Header.Get(middleware.EncabezadoTraceID); encabezado != traza {
+		t.Errorf("%s = %q, se esperaba %q", middleware.EncabezadoTraceID, encabezado, traza)
+	}
+	if trazaEnHandler != traza {
+		t.Errorf("trace_id en el handler %q, se esperaba %q", trazaEnHandler, traza)
+	}
+
+	spans := exportador.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("se esperaba 1 span, hay %d", len(spans))
+	}
+	span := spans[0]
+	if span.Name != "GET /estudiantes/:id" {
+		t.Errorf("nombre del span %q", span.Name)
+	}
+	if span.SpanContext.TraceID().String() != traza || span.Parent.SpanID().String() != "00f067aa0ba902b7" {
+		t.Error("el span no continúa la traza del traceparent")
+	}
+	estado := false
+	for _, atributo := range span.Attributes {
+		if atributo.Key == attribute.Key("http.response.status_code") && atributo.Value.AsInt64() == 503 {
+			estado = true
+		}
+	}
+	if !estado {
+		t.Errorf("falta http.response.status_code=503 en %v", span.Attributes)
+	}
+}